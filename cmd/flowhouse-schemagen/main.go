@@ -0,0 +1,39 @@
+// flowhouse-schemagen generates the code depending on the fields of flows from the flow schema. It is run by go
+// generate in pkg/models/flow.
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/bio-routing/flowhouse/pkg/schemagen"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	schemaPath = flag.String("schema", "pkg/models/flow/schema.yaml", "Flow schema path (YAML)")
+	root       = flag.String("root", ".", "Root of the repository the generated files are written to")
+)
+
+func main() {
+	flag.Parse()
+
+	s, err := schemagen.Load(*schemaPath)
+	if err != nil {
+		log.Fatalf("Unable to load schema: %v", err)
+	}
+
+	files, err := s.Generate()
+	if err != nil {
+		log.Fatalf("Unable to generate code: %v", err)
+	}
+
+	for _, f := range files {
+		err := os.WriteFile(filepath.Join(*root, f.Path), f.Content, 0644)
+		if err != nil {
+			log.Fatalf("Unable to write %s: %v", f.Path, err)
+		}
+	}
+}
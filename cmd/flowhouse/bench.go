@@ -0,0 +1,642 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/decodelog"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/packet"
+	"github.com/bio-routing/flowhouse/pkg/servers/ipfix"
+	"github.com/bio-routing/flowhouse/pkg/servers/sflow"
+	"github.com/pkg/errors"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxDatagramSize is the largest datagram the decoders accept
+	maxDatagramSize = 1500
+
+	// latencySamples is the number of latencies kept per worker to estimate percentiles
+	latencySamples = 1 << 16
+
+	syntheticSamples      = 7
+	syntheticHeaderLength = 128
+)
+
+// inserter inserts batches of flows, e.g. into ClickHouse
+type inserter interface {
+	InsertFlows(flows []*flow.Flow) error
+}
+
+// discard drops all flows when only decoding is benchmarked
+type discard struct{}
+
+func (discard) InsertFlows(flows []*flow.Flow) error {
+	return nil
+}
+
+// nopResolver resolves no interface names, so flows carry interface indexes
+type nopResolver struct{}
+
+func (nopResolver) Resolve(agent bnet.IP, ifID uint32) string {
+	return ""
+}
+
+// benchDatagram is a datagram replayed by the benchmark
+type benchDatagram struct {
+	protocol string
+	agent    bnet.IP
+	data     []byte
+}
+
+// bench measures the throughput of the decoders and of inserting flows
+func bench(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	pcapFile := fs.String("pcap", "", "Replay the sflow and IPFIX datagrams of a pcap file instead of synthetic sflow datagrams")
+	sflowPort := fs.Uint("sflow-port", 6343, "UDP port of sflow datagrams in the pcap file")
+	ipfixPort := fs.Uint("ipfix-port", 4739, "UDP port of IPFIX datagrams in the pcap file")
+	numDatagrams := fs.Int("datagrams", 1024, "Number of synthetic datagrams")
+	workers := fs.Int("workers", runtime.GOMAXPROCS(0), "Number of decoding workers, each replaying all datagrams")
+	duration := fs.Duration("duration", 10*time.Second, "Time to decode for")
+	insert := fs.Bool("insert", false, "Insert the flows into the flows table of the configured ClickHouse database")
+	insertWorkers := fs.Int("insert.workers", 1, "Number of concurrent inserts")
+	batchSize := fs.Int("batch-size", 10000, "Flows per insert")
+	cpuProfile := fs.String("cpu-profile", "", "Write a CPU profile to this file")
+	memProfile := fs.String("mem-profile", "", "Write an allocation profile to this file")
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	if *workers < 1 || *insertWorkers < 1 || *batchSize < 1 {
+		return fail(fmt.Errorf("workers, insert.workers and batch-size must be positive"))
+	}
+
+	input, err := benchInput(*pcapFile, uint16(*sflowPort), uint16(*ipfixPort), *numDatagrams)
+	if err != nil {
+		return fail(err)
+	}
+
+	if len(input) == 0 {
+		return fail(fmt.Errorf("No datagrams to replay"))
+	}
+
+	var ins inserter = discard{}
+	if *insert {
+		chgw, err := cf.connect()
+		if err != nil {
+			return fail(err)
+		}
+		defer chgw.Close()
+		ins = chgw
+	}
+
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			return fail(errors.Wrap(err, "Unable to create CPU profile"))
+		}
+		defer f.Close()
+
+		err = pprof.StartCPUProfile(f)
+		if err != nil {
+			return fail(errors.Wrap(err, "Unable to start CPU profile"))
+		}
+	}
+
+	b := &benchmark{
+		input:         input,
+		workers:       *workers,
+		insertWorkers: *insertWorkers,
+		batchSize:     *batchSize,
+		duration:      *duration,
+		inserter:      ins,
+	}
+	res := b.run()
+
+	if *cpuProfile != "" {
+		pprof.StopCPUProfile()
+	}
+
+	if *memProfile != "" {
+		err := writeAllocProfile(*memProfile)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	res.print(os.Stdout, *insert)
+	if res.insertsFailed > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func writeAllocProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "Unable to create allocation profile")
+	}
+	defer f.Close()
+
+	err = pprof.Lookup("allocs").WriteTo(f, 0)
+	if err != nil {
+		return errors.Wrap(err, "Unable to write allocation profile")
+	}
+
+	return nil
+}
+
+func benchInput(pcapFile string, sflowPort uint16, ipfixPort uint16, numDatagrams int) ([]*benchDatagram, error) {
+	if pcapFile == "" {
+		rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+		return syntheticDatagrams(rnd, numDatagrams), nil
+	}
+
+	f, err := os.Open(pcapFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to open pcap file")
+	}
+	defer f.Close()
+
+	return readPcap(f, sflowPort, ipfixPort)
+}
+
+// benchmark feeds its input through the decoders and passes the flows on in batches to be inserted
+type benchmark struct {
+	input         []*benchDatagram
+	workers       int
+	insertWorkers int
+	batchSize     int
+	duration      time.Duration
+	inserter      inserter
+}
+
+// benchResult are the measurements of a benchmark
+type benchResult struct {
+	elapsed       time.Duration
+	workers       int
+	insertWorkers int
+	datagrams     int
+	flows         int
+	batches       int
+	insertedFlows int
+	insertsFailed int
+	allocs        uint64
+	allocBytes    uint64
+	gcCycles      uint32
+	decodeLatency *latencies
+	insertLatency *latencies
+}
+
+func (b *benchmark) run() *benchResult {
+	res := &benchResult{
+		workers:       b.workers,
+		insertWorkers: b.insertWorkers,
+		decodeLatency: newLatencies(0),
+		insertLatency: newLatencies(0),
+	}
+
+	batches := make(chan []*flow.Flow, b.insertWorkers*2)
+	decoded := make([]*benchResult, b.workers)
+	inserted := make([]*benchResult, b.insertWorkers)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	deadline := start.Add(b.duration)
+
+	insertWG := sync.WaitGroup{}
+	for i := range inserted {
+		inserted[i] = &benchResult{insertLatency: newLatencies(int64(i))}
+		insertWG.Add(1)
+		go func(r *benchResult) {
+			defer insertWG.Done()
+			b.insert(batches, r)
+		}(inserted[i])
+	}
+
+	decodeWG := sync.WaitGroup{}
+	for i := range decoded {
+		decoded[i] = &benchResult{decodeLatency: newLatencies(int64(i))}
+		decodeWG.Add(1)
+		go func(r *benchResult) {
+			defer decodeWG.Done()
+			b.decode(deadline, batches, r)
+		}(decoded[i])
+	}
+
+	decodeWG.Wait()
+	close(batches)
+	insertWG.Wait()
+
+	res.elapsed = time.Since(start)
+	runtime.ReadMemStats(&after)
+	res.allocs = after.Mallocs - before.Mallocs
+	res.allocBytes = after.TotalAlloc - before.TotalAlloc
+	res.gcCycles = after.NumGC - before.NumGC
+
+	for _, r := range decoded {
+		res.datagrams += r.datagrams
+		res.flows += r.flows
+		res.decodeLatency.merge(r.decodeLatency)
+	}
+
+	for _, r := range inserted {
+		res.batches += r.batches
+		res.insertsFailed += r.insertsFailed
+		res.insertedFlows += r.insertedFlows
+		res.insertLatency.merge(r.insertLatency)
+	}
+
+	return res
+}
+
+// decode replays the input until deadline. Flows are passed on in batches of batchSize.
+func (b *benchmark) decode(deadline time.Time, batches chan<- []*flow.Flow, res *benchResult) {
+	batch := make([]*flow.Flow, 0, b.batchSize)
+	emit := func(fl *flow.Flow) {
+		res.flows++
+		batch = append(batch, fl)
+		if len(batch) == b.batchSize {
+			batches <- batch
+			batch = make([]*flow.Flow, 0, b.batchSize)
+		}
+	}
+
+	sfd := sflow.NewDecoder(nopResolver{}, true, emit)
+	ipd := ipfix.NewDecoder(nopResolver{}, func(flows []*flow.Flow) {
+		for _, fl := range flows {
+			emit(fl)
+		}
+	})
+
+	// the decoders work in place, so every datagram is copied first
+	buf := make([]byte, maxDatagramSize)
+	for {
+		for _, d := range b.input {
+			start := time.Now()
+			if !start.Before(deadline) {
+				if len(batch) > 0 {
+					batches <- batch
+				}
+				return
+			}
+
+			n := copy(buf, d.data)
+			if d.protocol == decodelog.ProtocolIPFIX {
+				ipd.Decode(d.agent, buf[:n])
+			} else {
+				sfd.Decode(d.agent, buf[:n])
+			}
+
+			res.decodeLatency.add(time.Since(start))
+			res.datagrams++
+		}
+	}
+}
+
+func (b *benchmark) insert(batches <-chan []*flow.Flow, res *benchResult) {
+	for flows := range batches {
+		start := time.Now()
+		err := b.inserter.InsertFlows(flows)
+		d := time.Since(start)
+		res.insertLatency.add(d)
+		res.batches++
+
+		if err != nil {
+			log.WithError(err).Error("Insert failed")
+			res.insertsFailed++
+		} else {
+			res.insertedFlows += len(flows)
+		}
+
+		flow.ReleaseAll(flows)
+	}
+}
+
+func (r *benchResult) print(w io.Writer, inserted bool) {
+	seconds := r.elapsed.Seconds()
+	fmt.Fprintf(w, "Decoded %d flows from %d datagrams in %v with %d workers\n", r.flows, r.datagrams, r.elapsed.Round(time.Millisecond), r.workers)
+	fmt.Fprintf(w, "  flows/s          %.0f\n", float64(r.flows)/seconds)
+	fmt.Fprintf(w, "  datagrams/s      %.0f\n", float64(r.datagrams)/seconds)
+	fmt.Fprintf(w, "  decode latency   %s\n", r.decodeLatency)
+
+	if inserted {
+		fmt.Fprintf(w, "Inserted %d flows in %d batches with %d workers, %d inserts failed\n", r.insertedFlows, r.batches, r.insertWorkers, r.insertsFailed)
+		fmt.Fprintf(w, "  flows/s          %.0f\n", float64(r.insertedFlows)/seconds)
+		fmt.Fprintf(w, "  insert latency   %s\n", r.insertLatency)
+	}
+
+	if r.flows > 0 {
+		fmt.Fprintf(w, "  allocs/flow      %.2f\n", float64(r.allocs)/float64(r.flows))
+		fmt.Fprintf(w, "  bytes/flow       %.0f\n", float64(r.allocBytes)/float64(r.flows))
+	}
+	fmt.Fprintf(w, "  GC cycles        %d\n", r.gcCycles)
+}
+
+// latencies keeps a uniform sample of durations to estimate their percentiles in bounded memory
+type latencies struct {
+	samples []time.Duration
+	n       int
+	max     time.Duration
+	rnd     *rand.Rand
+}
+
+func newLatencies(seed int64) *latencies {
+	return &latencies{
+		rnd: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.n++
+	if d > l.max {
+		l.max = d
+	}
+
+	if len(l.samples) < latencySamples {
+		l.samples = append(l.samples, d)
+		return
+	}
+
+	i := l.rnd.Intn(l.n)
+	if i < latencySamples {
+		l.samples[i] = d
+	}
+}
+
+func (l *latencies) merge(o *latencies) {
+	l.samples = append(l.samples, o.samples...)
+	l.n += o.n
+	if o.max > l.max {
+		l.max = o.max
+	}
+}
+
+// percentile gets the p-th percentile (0-100) of the sampled durations
+func (l *latencies) percentile(p float64) time.Duration {
+	if len(l.samples) == 0 {
+		return 0
+	}
+
+	sort.Slice(l.samples, func(i, j int) bool {
+		return l.samples[i] < l.samples[j]
+	})
+
+	i := int(p / 100 * float64(len(l.samples)-1))
+	return l.samples[i]
+}
+
+func (l *latencies) String() string {
+	return fmt.Sprintf("p50 %v  p90 %v  p99 %v  max %v", l.percentile(50), l.percentile(90), l.percentile(99), l.max)
+}
+
+// syntheticDatagrams generates sflow datagrams of an agent at 192.0.2.1 with samples of random flows between
+// documentation prefixes
+func syntheticDatagrams(rnd *rand.Rand, n int) []*benchDatagram {
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+
+	res := make([]*benchDatagram, n)
+	for i := range res {
+		res[i] = &benchDatagram{
+			protocol: decodelog.ProtocolSFlow,
+			agent:    agent,
+			data:     syntheticDatagram(rnd, agent, uint32(i)),
+		}
+	}
+
+	return res
+}
+
+// syntheticDatagram encodes an sflow v5 datagram with syntheticSamples flow samples
+func syntheticDatagram(rnd *rand.Rand, agent bnet.IP, seq uint32) []byte {
+	be := binary.BigEndian
+	b := make([]byte, 0, maxDatagramSize)
+	b = be.AppendUint32(b, 5)
+	b = be.AppendUint32(b, 1)
+	b = be.AppendUint32(b, agent.ToUint32())
+	b = be.AppendUint32(b, 0)
+	b = be.AppendUint32(b, seq)
+	b = be.AppendUint32(b, seq*1000)
+	b = be.AppendUint32(b, syntheticSamples)
+
+	for i := uint32(0); i < syntheticSamples; i++ {
+		ifIn := uint32(1 + rnd.Intn(4))
+		hdr := syntheticHeader(rnd)
+
+		// flow sample
+		b = be.AppendUint32(b, 1)
+		b = be.AppendUint32(b, uint32(32+8+16+len(hdr)))
+		b = be.AppendUint32(b, seq*syntheticSamples+i)
+		b = be.AppendUint32(b, ifIn)
+		b = be.AppendUint32(b, 1000)
+		b = be.AppendUint32(b, (seq*syntheticSamples+i)*1000)
+		b = be.AppendUint32(b, 0)
+		b = be.AppendUint32(b, ifIn)
+		b = be.AppendUint32(b, uint32(5+rnd.Intn(4)))
+		b = be.AppendUint32(b, 1)
+
+		// raw packet header record
+		b = be.AppendUint32(b, 1)
+		b = be.AppendUint32(b, uint32(16+len(hdr)))
+		b = be.AppendUint32(b, 1)
+		b = be.AppendUint32(b, uint32(len(hdr)+rnd.Intn(1390)))
+		b = be.AppendUint32(b, 4)
+		b = be.AppendUint32(b, uint32(len(hdr)))
+		b = append(b, hdr...)
+	}
+
+	return b
+}
+
+// syntheticHeader creates the first syntheticHeaderLength bytes of an ethernet frame carrying a TCP or UDP packet
+// from 198.51.100.0/24 to 203.0.113.0/24
+func syntheticHeader(rnd *rand.Rand) []byte {
+	be := binary.BigEndian
+	hdr := make([]byte, syntheticHeaderLength)
+
+	copy(hdr[0:6], []byte{0x02, 0, 0, 0, 0, 1})
+	copy(hdr[6:12], []byte{0x02, 0, 0, 0, 0, 2})
+	be.PutUint16(hdr[12:], packet.EtherTypeIPv4)
+
+	ip := hdr[14:34]
+	ip[0] = 0x45
+	be.PutUint16(ip[2:], uint16(syntheticHeaderLength-14))
+	ip[8] = 64
+	copy(ip[12:16], []byte{198, 51, 100, uint8(rnd.Intn(256))})
+	copy(ip[16:20], []byte{203, 0, 113, uint8(rnd.Intn(256))})
+
+	l4 := hdr[34:]
+	be.PutUint16(l4[0:], uint16(1024+rnd.Intn(64512)))
+	if rnd.Intn(4) == 0 {
+		ip[9] = packet.UDP
+		be.PutUint16(l4[2:], []uint16{53, 123, 443, 4500}[rnd.Intn(4)])
+		be.PutUint16(l4[4:], uint16(syntheticHeaderLength-34))
+	} else {
+		ip[9] = packet.TCP
+		be.PutUint16(l4[2:], []uint16{22, 80, 443, 8080}[rnd.Intn(4)])
+		l4[12] = 0x50
+		l4[13] = 0x18
+	}
+
+	return hdr
+}
+
+const (
+	pcapMagic           = 0xa1b2c3d4
+	pcapMagicNanosecond = 0xa1b23c4d
+	linkTypeEthernet    = 1
+	linkTypeRaw         = 101
+	etherTypeDot1Q      = 0x8100
+)
+
+// readPcap reads the sflow and IPFIX datagrams of a pcap file, telling them apart by their UDP destination port.
+// Datagrams are attributed to the source address of their IP packet.
+func readPcap(r io.Reader, sflowPort uint16, ipfixPort uint16) ([]*benchDatagram, error) {
+	hdr := make([]byte, 24)
+	_, err := io.ReadFull(r, hdr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read pcap header")
+	}
+
+	var bo binary.ByteOrder = binary.LittleEndian
+	switch binary.LittleEndian.Uint32(hdr) {
+	case pcapMagic, pcapMagicNanosecond:
+	default:
+		bo = binary.BigEndian
+		magic := bo.Uint32(hdr)
+		if magic != pcapMagic && magic != pcapMagicNanosecond {
+			return nil, fmt.Errorf("Not a pcap file")
+		}
+	}
+
+	linkType := bo.Uint32(hdr[20:])
+	if linkType != linkTypeEthernet && linkType != linkTypeRaw {
+		return nil, fmt.Errorf("Unsupported link type %d", linkType)
+	}
+
+	res := make([]*benchDatagram, 0)
+	rec := make([]byte, 16)
+	for {
+		_, err := io.ReadFull(r, rec)
+		if err == io.EOF {
+			return res, nil
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to read pcap record header")
+		}
+
+		pkt := make([]byte, bo.Uint32(rec[8:]))
+		_, err = io.ReadFull(r, pkt)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to read pcap record")
+		}
+
+		if linkType == linkTypeEthernet {
+			pkt = ethernetPayload(pkt)
+		}
+
+		src, dstPort, payload := udpPayload(pkt)
+		if payload == nil || len(payload) > maxDatagramSize {
+			continue
+		}
+
+		agent, err := bnet.IPFromBytes(src)
+		if err != nil {
+			continue
+		}
+
+		d := &benchDatagram{
+			agent: agent,
+			data:  payload,
+		}
+
+		switch dstPort {
+		case sflowPort:
+			d.protocol = decodelog.ProtocolSFlow
+		case ipfixPort:
+			d.protocol = decodelog.ProtocolIPFIX
+		default:
+			continue
+		}
+
+		res = append(res, d)
+	}
+}
+
+// ethernetPayload gets the IP packet of a frame, nil if it carries none
+func ethernetPayload(frame []byte) []byte {
+	if len(frame) < 14 {
+		return nil
+	}
+
+	ethType := binary.BigEndian.Uint16(frame[12:])
+	frame = frame[14:]
+	if ethType == etherTypeDot1Q && len(frame) >= 4 {
+		ethType = binary.BigEndian.Uint16(frame[2:])
+		frame = frame[4:]
+	}
+
+	if ethType != packet.EtherTypeIPv4 && ethType != packet.EtherTypeIPv6 {
+		return nil
+	}
+
+	return frame
+}
+
+// udpPayload gets source address, destination port and payload of an unfragmented UDP packet. payload is nil for
+// any other packet.
+func udpPayload(pkt []byte) (src []byte, dstPort uint16, payload []byte) {
+	if len(pkt) == 0 {
+		return nil, 0, nil
+	}
+
+	var udp []byte
+	switch pkt[0] >> 4 {
+	case 4:
+		ihl := int(pkt[0]&0x0f) * 4
+		if len(pkt) < 20 || ihl < 20 || len(pkt) < ihl || pkt[9] != packet.UDP {
+			return nil, 0, nil
+		}
+
+		// more fragments flag or fragment offset
+		if binary.BigEndian.Uint16(pkt[6:])&0x3fff != 0 {
+			return nil, 0, nil
+		}
+
+		src, udp = pkt[12:16], pkt[ihl:]
+	case 6:
+		if len(pkt) < 40 || pkt[6] != packet.UDP {
+			return nil, 0, nil
+		}
+
+		src, udp = pkt[8:24], pkt[40:]
+	default:
+		return nil, 0, nil
+	}
+
+	if len(udp) < 8 {
+		return nil, 0, nil
+	}
+
+	length := int(binary.BigEndian.Uint16(udp[4:]))
+	if length < 8 || length > len(udp) {
+		return nil, 0, nil
+	}
+
+	return src, binary.BigEndian.Uint16(udp[2:]), udp[8:length]
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/decodelog"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/packet"
+	"github.com/bio-routing/flowhouse/pkg/servers/sflow"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestSyntheticDatagrams(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	input := syntheticDatagrams(rnd, 4)
+
+	flows := make([]*flow.Flow, 0)
+	dec := sflow.NewDecoder(nopResolver{}, false, func(fl *flow.Flow) {
+		flows = append(flows, fl)
+	})
+
+	for _, d := range input {
+		assert.Equal(t, decodelog.ProtocolSFlow, d.protocol)
+		assert.LessOrEqual(t, len(d.data), maxDatagramSize)
+		dec.Decode(d.agent, append([]byte(nil), d.data...))
+	}
+
+	assert.Len(t, flows, 4*syntheticSamples)
+	for _, fl := range flows {
+		assert.Equal(t, netip.MustParseAddr("192.0.2.1"), fl.Agent)
+		assert.True(t, netip.MustParsePrefix("198.51.100.0/24").Contains(fl.SrcAddr), fl.SrcAddr.String())
+		assert.True(t, netip.MustParsePrefix("203.0.113.0/24").Contains(fl.DstAddr), fl.DstAddr.String())
+		assert.Contains(t, []uint8{packet.TCP, packet.UDP}, fl.Protocol)
+		assert.Contains(t, []uint16{22, 53, 80, 123, 443, 4500, 8080}, fl.DstPort)
+		assert.Equal(t, uint64(1000), fl.Samplerate)
+	}
+}
+
+func TestReadPcap(t *testing.T) {
+	agent := netip.MustParseAddr("192.0.2.7").As4()
+	tests := []struct {
+		name     string
+		linkType uint32
+		frames   [][]byte
+		expected []*benchDatagram
+		wantFail bool
+	}{
+		{
+			name:     "Ethernet with VLAN",
+			linkType: linkTypeEthernet,
+			frames: [][]byte{
+				testFrame(true, testIPv4(agent, testUDP(6343, []byte{1, 2, 3, 4}))),
+				testFrame(false, testIPv4(agent, testUDP(4739, []byte{5, 6}))),
+				testFrame(false, testIPv4(agent, testUDP(53, []byte{7}))),
+			},
+			expected: []*benchDatagram{
+				{
+					protocol: decodelog.ProtocolSFlow,
+					agent:    bnet.IPv4FromOctets(192, 0, 2, 7),
+					data:     []byte{1, 2, 3, 4},
+				},
+				{
+					protocol: decodelog.ProtocolIPFIX,
+					agent:    bnet.IPv4FromOctets(192, 0, 2, 7),
+					data:     []byte{5, 6},
+				},
+			},
+		},
+		{
+			name:     "Raw IP",
+			linkType: linkTypeRaw,
+			frames: [][]byte{
+				testIPv4(agent, testUDP(6343, []byte{1, 2, 3, 4})),
+			},
+			expected: []*benchDatagram{
+				{
+					protocol: decodelog.ProtocolSFlow,
+					agent:    bnet.IPv4FromOctets(192, 0, 2, 7),
+					data:     []byte{1, 2, 3, 4},
+				},
+			},
+		},
+		{
+			name:     "Unsupported link type",
+			linkType: 113,
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		res, err := readPcap(bytes.NewReader(testPcap(test.linkType, test.frames)), 6343, 4739)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected failure for %q: %v", test.name, err)
+			continue
+		}
+
+		assert.Equal(t, test.expected, res, test.name)
+	}
+}
+
+func TestLatencies(t *testing.T) {
+	l := newLatencies(1)
+	for i := 1; i <= 2*latencySamples; i++ {
+		l.add(time.Duration(i) * time.Microsecond)
+	}
+
+	assert.Equal(t, latencySamples, len(l.samples))
+	assert.Equal(t, 2*latencySamples*int(time.Microsecond), int(l.max))
+	assert.InDelta(t, latencySamples*int(time.Microsecond), int(l.percentile(50)), float64(latencySamples/20*int(time.Microsecond)))
+
+	o := newLatencies(2)
+	o.add(time.Hour)
+	l.merge(o)
+	assert.Equal(t, time.Hour, l.max)
+	assert.Equal(t, 2*latencySamples+1, l.n)
+}
+
+func testPcap(linkType uint32, frames [][]byte) []byte {
+	le := binary.LittleEndian
+	b := le.AppendUint32(nil, pcapMagic)
+	b = le.AppendUint16(b, 2)
+	b = le.AppendUint16(b, 4)
+	b = append(b, make([]byte, 8)...)
+	b = le.AppendUint32(b, 65535)
+	b = le.AppendUint32(b, linkType)
+
+	for _, f := range frames {
+		b = append(b, make([]byte, 8)...)
+		b = le.AppendUint32(b, uint32(len(f)))
+		b = le.AppendUint32(b, uint32(len(f)))
+		b = append(b, f...)
+	}
+
+	return b
+}
+
+func testFrame(vlan bool, ip []byte) []byte {
+	f := make([]byte, 12)
+	if vlan {
+		f = binary.BigEndian.AppendUint16(f, etherTypeDot1Q)
+		f = binary.BigEndian.AppendUint16(f, 100)
+	}
+
+	f = binary.BigEndian.AppendUint16(f, packet.EtherTypeIPv4)
+	return append(f, ip...)
+}
+
+func testIPv4(src [4]byte, udp []byte) []byte {
+	ip := make([]byte, 20)
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+len(udp)))
+	ip[9] = packet.UDP
+	copy(ip[12:16], src[:])
+	return append(ip, udp...)
+}
+
+func testUDP(dstPort uint16, payload []byte) []byte {
+	udp := make([]byte, 8)
+	binary.BigEndian.PutUint16(udp[0:], 50000)
+	binary.BigEndian.PutUint16(udp[2:], dstPort)
+	binary.BigEndian.PutUint16(udp[4:], uint16(8+len(payload)))
+	return append(udp, payload...)
+}
+
+type countingInserter struct {
+	flows int
+}
+
+func (c *countingInserter) InsertFlows(flows []*flow.Flow) error {
+	c.flows += len(flows)
+	return nil
+}
+
+func TestBenchmarkRun(t *testing.T) {
+	ins := &countingInserter{}
+	b := &benchmark{
+		input:         syntheticDatagrams(rand.New(rand.NewSource(1)), 16),
+		workers:       2,
+		insertWorkers: 1,
+		batchSize:     100,
+		duration:      50 * time.Millisecond,
+		inserter:      ins,
+	}
+	res := b.run()
+
+	assert.Greater(t, res.datagrams, 0)
+	assert.Equal(t, res.datagrams*syntheticSamples, res.flows)
+	assert.Equal(t, res.flows, res.insertedFlows)
+	assert.Equal(t, res.flows, ins.flows)
+	assert.Equal(t, 0, res.insertsFailed)
+	assert.Equal(t, res.datagrams, res.decodeLatency.n)
+	assert.Equal(t, res.batches, res.insertLatency.n)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/billing"
+	"github.com/pkg/errors"
+)
+
+// billingReport writes the 95th percentile billing report of a month as CSV or JSON
+func billingReport(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	month := fs.String("month", billing.PreviousMonth(time.Now()).Format(billing.MonthFormat), "Month to report (YYYY-MM)")
+	by := fs.String("by", billing.ByInterface, "Group by interface, customer, service or traffic_class")
+	format := fs.String("format", "csv", "Output format (csv or json)")
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	m, err := time.Parse(billing.MonthFormat, *month)
+	if err != nil {
+		return fail(errors.Wrapf(err, "Invalid month %q", *month))
+	}
+
+	chgw, err := cf.connect()
+	if err != nil {
+		return fail(err)
+	}
+	defer chgw.Close()
+
+	entries, err := billing.New(chgw).Report(*by, m)
+	if err != nil {
+		return fail(errors.Wrap(err, "Unable to create report"))
+	}
+
+	if *format == "json" {
+		err = json.NewEncoder(os.Stdout).Encode(entries)
+	} else {
+		err = billing.WriteCSV(os.Stdout, *by, entries)
+	}
+
+	if err != nil {
+		return fail(errors.Wrap(err, "Unable to write report"))
+	}
+
+	return 0
+}
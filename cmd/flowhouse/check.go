@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bio-routing/flowhouse/cmd/flowhouse/config"
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+)
+
+// checkConfig checks the config file and ClickHouse dicts and prints all problems found
+func checkConfig(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	fp := cf.configFilePath
+	cfg, err := config.ReadConfig(fp, cf.overrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fp, err)
+		return 1
+	}
+
+	var db config.DictDescriber
+	if cfg.Clickhouse != nil {
+		chgw, err := clickhousegw.Connect(cfg.Clickhouse)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: Unable to connect to clickhouse: %v\n", fp, err)
+			return 1
+		}
+		defer chgw.Close()
+		db = chgw
+	}
+
+	problems := cfg.Check(db)
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fp, p)
+	}
+
+	if len(problems) > 0 {
+		return 1
+	}
+
+	fmt.Printf("%s: OK\n", fp)
+	return 0
+}
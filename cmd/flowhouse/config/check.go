@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/bio-routing/flowhouse/pkg/source"
+	"github.com/pkg/errors"
+)
+
+// DictDescriber describes dictionaries in ClickHouse
+type DictDescriber interface {
+	GetDictFields(dictName string) ([]string, error)
+}
+
+// Check validates and loads the config and additionally checks that listen and relay addresses are valid, local source
+// files exist and dicts are well formed and (if db is not nil) exist in ClickHouse. It returns all problems found.
+func (c *Config) Check(db DictDescriber) []error {
+	res := make([]error, 0)
+
+	err := c.Validate()
+	if err != nil {
+		res = append(res, err)
+	}
+
+	err = c.load()
+	if err != nil {
+		res = append(res, err)
+	}
+
+	res = append(res, c.checkAddresses()...)
+	res = append(res, c.checkFiles()...)
+	res = append(res, c.checkDicts(db)...)
+
+	return res
+}
+
+func (c *Config) checkAddresses() []error {
+	res := make([]error, 0)
+	for _, x := range []struct {
+		name    string
+		network string
+		addr    string
+	}{
+		{name: "listen_sflow", network: "udp", addr: c.ListenSFlow},
+		{name: "listen_ipfix", network: "udp", addr: c.ListenIPFIX},
+		{name: "listen_http", network: "tcp", addr: c.ListenHTTP},
+	} {
+		if x.addr == "" {
+			continue
+		}
+
+		err := checkAddress(x.network, x.addr)
+		if err != nil {
+			res = append(res, errors.Wrapf(err, "Invalid %s", x.name))
+		}
+	}
+
+	if c.Relay != nil {
+		for _, target := range append(append([]string{}, c.Relay.SFlow...), c.Relay.IPFIX...) {
+			err := checkAddress("udp", target)
+			if err != nil {
+				res = append(res, errors.Wrap(err, "Invalid relay target"))
+			}
+		}
+	}
+
+	return res
+}
+
+func checkAddress(network string, addr string) error {
+	var err error
+	switch network {
+	case "udp":
+		_, err = net.ResolveUDPAddr(network, addr)
+	default:
+		_, err = net.ResolveTCPAddr(network, addr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Unable to resolve %q: %v", addr, err)
+	}
+
+	return nil
+}
+
+type sourceFile struct {
+	name string
+	path string
+}
+
+func (c *Config) checkFiles() []error {
+	files := make([]sourceFile, 0)
+	if c.RPKI != nil {
+		files = append(files, sourceFile{name: "rpki.vrp_source", path: c.RPKI.VRPSource})
+	}
+
+	if c.Bogons != nil && !c.DisableBogons {
+		files = append(files, sourceFile{name: "bogons.list_source", path: c.Bogons.ListSource})
+	}
+
+	if c.ThreatIntel != nil {
+		for _, f := range c.ThreatIntel.Feeds {
+			files = append(files, sourceFile{name: fmt.Sprintf("threat_intel feed %q", f.Name), path: f.Source})
+		}
+	}
+
+	res := make([]error, 0)
+	for _, f := range files {
+		if f.path == "" || source.IsURL(f.path) {
+			continue
+		}
+
+		_, err := os.Stat(f.path)
+		if err != nil {
+			res = append(res, fmt.Errorf("Invalid %s: %v", f.name, err))
+		}
+	}
+
+	return res
+}
+
+func (c *Config) checkDicts(db DictDescriber) []error {
+	res := make([]error, 0)
+	for _, d := range c.Dicts {
+		err := frontend.Dicts{d}.Validate()
+		if err != nil {
+			res = append(res, err)
+			continue
+		}
+
+		if db == nil {
+			continue
+		}
+
+		attrs, err := db.GetDictFields(d.Dict)
+		if err != nil || len(attrs) == 0 {
+			res = append(res, fmt.Errorf("Dict %q for field %q not found in ClickHouse", d.Dict, d.Field))
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/bio-routing/flowhouse/pkg/rpki"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDicts map[string][]string
+
+func (f fakeDicts) GetDictFields(dictName string) ([]string, error) {
+	attrs, exists := f[dictName]
+	if !exists {
+		return nil, fmt.Errorf("not found")
+	}
+
+	return attrs, nil
+}
+
+func TestCheck(t *testing.T) {
+	db := fakeDicts{
+		"asns": {"name"},
+	}
+
+	tests := []struct {
+		name     string
+		cfg      *Config
+		expected int
+	}{
+		{
+			name: "Valid",
+			cfg: &Config{
+				Clickhouse:  &clickhousegw.ClickhouseConfig{},
+				ListenSFlow: ":6343",
+				ListenHTTP:  "127.0.0.1:9991",
+				Dicts: frontend.Dicts{
+					{Field: "src_asn", Dict: "asns", Expr: "tuple(%s)"},
+				},
+			},
+			expected: 0,
+		},
+		{
+			name:     "Missing clickhouse",
+			cfg:      &Config{},
+			expected: 1,
+		},
+		{
+			name: "Invalid addresses",
+			cfg: &Config{
+				Clickhouse:  &clickhousegw.ClickhouseConfig{},
+				ListenSFlow: "6343",
+				ListenIPFIX: ":99999",
+			},
+			expected: 2,
+		},
+		{
+			name: "Invalid router and missing VRP file",
+			cfg: &Config{
+				Clickhouse: &clickhousegw.ClickhouseConfig{},
+				Routers: []*Router{
+					{Name: "rtr1", Address: "not an address"},
+				},
+				RPKI: &rpki.Config{
+					VRPSource: "/nonexistent/vrps.json",
+				},
+			},
+			expected: 2,
+		},
+		{
+			name: "Bad dicts",
+			cfg: &Config{
+				Clickhouse: &clickhousegw.ClickhouseConfig{},
+				Dicts: frontend.Dicts{
+					{Field: "src_asn", Dict: "asns", Expr: "tuple(%s, %s)"},
+					{Field: "dst_asn", Dict: "unknown", Expr: "tuple(%s)"},
+					{Field: "int_in", Dict: "asns", Expr: "tuple(%s, %s)", Keys: []string{"agent", "int_in"}},
+				},
+			},
+			expected: 2,
+		},
+	}
+
+	for _, test := range tests {
+		problems := test.cfg.Check(db)
+		assert.Equal(t, test.expected, len(problems), test.name)
+	}
+}
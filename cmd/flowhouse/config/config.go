@@ -2,10 +2,37 @@ package config
 
 import (
 	"io/ioutil"
+	"os"
 
 	"github.com/bio-routing/bio-rd/routingtable/vrf"
+	"github.com/bio-routing/flowhouse/pkg/alerting"
+	"github.com/bio-routing/flowhouse/pkg/anomaly"
+	"github.com/bio-routing/flowhouse/pkg/anonymizer"
+	"github.com/bio-routing/flowhouse/pkg/appid"
+	"github.com/bio-routing/flowhouse/pkg/bogon"
 	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/ddos"
+	"github.com/bio-routing/flowhouse/pkg/federation"
+	"github.com/bio-routing/flowhouse/pkg/forecast"
 	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/bio-routing/flowhouse/pkg/heartbeats"
+	"github.com/bio-routing/flowhouse/pkg/hostcounters"
+	"github.com/bio-routing/flowhouse/pkg/inventory"
+	"github.com/bio-routing/flowhouse/pkg/logging"
+	"github.com/bio-routing/flowhouse/pkg/ratelimit"
+	"github.com/bio-routing/flowhouse/pkg/rdns"
+	"github.com/bio-routing/flowhouse/pkg/relay"
+	"github.com/bio-routing/flowhouse/pkg/reports"
+	"github.com/bio-routing/flowhouse/pkg/ringbuffer"
+	"github.com/bio-routing/flowhouse/pkg/rpki"
+	"github.com/bio-routing/flowhouse/pkg/scandetect"
+	"github.com/bio-routing/flowhouse/pkg/secrets"
+	"github.com/bio-routing/flowhouse/pkg/servers/capture"
+	"github.com/bio-routing/flowhouse/pkg/servers/ipfix"
+	"github.com/bio-routing/flowhouse/pkg/tagger"
+	"github.com/bio-routing/flowhouse/pkg/threatintel"
+	"github.com/bio-routing/flowhouse/pkg/timestamps"
+	"github.com/bio-routing/flowhouse/pkg/writers"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 
@@ -26,10 +53,52 @@ type Config struct {
 	ListenSFlow        string                         `yaml:"listen_sflow"`
 	ListenIPFIX        string                         `yaml:"listen_ipfix"`
 	ListenHTTP         string                         `yaml:"listen_http"`
+	HTTPAuth           *frontend.AuthConfig           `yaml:"http_auth"`
+	QueryLimits        *frontend.QueryLimitConfig     `yaml:"query_limits"`
+	Downsampling       *frontend.DownsampleConfig     `yaml:"downsampling"`
+	Names              *frontend.NamesConfig          `yaml:"names"`
+	Geo                *frontend.GeoConfig            `yaml:"geo"`
+	UI                 *frontend.UIConfig             `yaml:"ui"`
+	I18n               *frontend.I18nConfig           `yaml:"i18n"`
 	Dicts              frontend.Dicts                 `yaml:"dicts"`
+	DictsFile          string                         `yaml:"dicts_file"`
+	Joins              frontend.Joins                 `yaml:"joins"`
+	ComputedFields     frontend.ComputedFields        `yaml:"computed_fields"`
 	Clickhouse         *clickhousegw.ClickhouseConfig `yaml:"clickhouse"`
 	Routers            []*Router                      `yaml:"routers"`
 	DisableIPAnnotator bool                           `yaml:"disable_ip_annotator"`
+	DecodeTunnels      bool                           `yaml:"decode_tunnels"`
+	RDNS               *rdns.Config                   `yaml:"rdns"`
+	Tagging            *tagger.Config                 `yaml:"tagging"`
+	RPKI               *rpki.Config                   `yaml:"rpki"`
+	DisableBogons      bool                           `yaml:"disable_bogons"`
+	Bogons             *bogon.Config                  `yaml:"bogons"`
+	ThreatIntel        *threatintel.Config            `yaml:"threat_intel"`
+	Anonymization      *anonymizer.Config             `yaml:"anonymization"`
+	Applications       *appid.Config                  `yaml:"applications"`
+	Capture            *capture.Config                `yaml:"capture"`
+	Relay              *relay.Config                  `yaml:"relay"`
+	RateLimits         *ratelimit.Config              `yaml:"rate_limits"`
+	IPFIXFields        ipfix.Fields                   `yaml:"ipfix_fields"`
+	RawFields          bool                           `yaml:"raw_fields"`
+	DecodeErrorLogSize int                            `yaml:"decode_error_log_size"`
+	SamplingIntervals  int                            `yaml:"sampling_report_intervals"`
+	Timestamps         *timestamps.Config             `yaml:"timestamps"`
+	Alerting           *alerting.Config               `yaml:"alerting"`
+	DDoS               *ddos.Config                   `yaml:"ddos"`
+	Anomaly            *anomaly.Config                `yaml:"anomaly_detection"`
+	Forecasting        *forecast.Config               `yaml:"forecasting"`
+	ScanDetection      *scandetect.Config             `yaml:"scan_detection"`
+	ScheduledQueries   *reports.Config                `yaml:"scheduled_queries"`
+	InsertWriters      *writers.Config                `yaml:"insert_writers"`
+	IngestQueue        *ringbuffer.Config             `yaml:"ingest_queue"`
+	Heartbeats         *heartbeats.Config             `yaml:"heartbeats"`
+	HostCounters       *hostcounters.Config           `yaml:"host_counters"`
+	Discovery          *inventory.DiscoveryConfig     `yaml:"discovery"`
+	Federation         *federation.Config             `yaml:"federation"`
+	Secrets            *secrets.Config                `yaml:"secrets"`
+	Logging            *logging.Config                `yaml:"logging"`
+	secrets            *secrets.Resolver
 }
 
 type SNMPConfig struct {
@@ -38,6 +107,7 @@ type SNMPConfig struct {
 	User              string `yaml:"user"`
 	AuthPassphrase    string `yaml:"auth-key"`
 	PrivacyPassphrase string `yaml:"privacy-passphrase"`
+	ExportInterfaces  bool   `yaml:"export_interfaces"`
 }
 
 func (c *Config) load() error {
@@ -80,6 +150,8 @@ func (c *Config) GetDefaultVRF() uint64 {
 // Router represents a router
 type Router struct {
 	Name         string `yaml:"name"`
+	Site         string `yaml:"site"`
+	Role         string `yaml:"role"`
 	Address      string `yaml:"address"`
 	address      bnet.IP
 	RISInstances []string `yaml:"ris_instances"`
@@ -117,8 +189,9 @@ func (r *Router) load() error {
 	return nil
 }
 
-// GetConfig gets the configuration
-func GetConfig(fp string) (*Config, error) {
+// ReadConfig reads and parses the configuration, applies environment variables and overrides and resolves secret
+// references without validating it
+func ReadConfig(fp string, overrides Overrides) (*Config, error) {
 	fc, err := ioutil.ReadFile(fp)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to read file")
@@ -130,6 +203,37 @@ func GetConfig(fp string) (*Config, error) {
 		return nil, errors.Wrap(err, "Unable to unmarshal")
 	}
 
+	err = c.applyOverrides(os.Environ(), overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.DictsFile != "" {
+		if len(c.Dicts) > 0 {
+			return nil, errors.New("dicts and dicts_file are mutually exclusive")
+		}
+
+		c.Dicts, err = DictsFile(c.DictsFile).readDicts()
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to read dicts_file")
+		}
+	}
+
+	err = c.resolveSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetConfig gets the configuration
+func GetConfig(fp string, overrides Overrides) (*Config, error) {
+	c, err := ReadConfig(fp, overrides)
+	if err != nil {
+		return nil, err
+	}
+
 	err = c.Validate()
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to validate config")
@@ -140,9 +244,59 @@ func GetConfig(fp string) (*Config, error) {
 }
 
 func (c *Config) Validate() error {
+	if c.Clickhouse == nil {
+		return errors.New("clickhouse must be configured")
+	}
+
 	if c.Clickhouse.Sharded && c.Clickhouse.Cluster == "" {
 		return errors.New("cluster must be set when Clickhouse is replicated")
 	}
+
+	err := c.ComputedFields.Validate()
+	if err != nil {
+		return errors.Wrap(err, "Invalid computed fields")
+	}
+
+	if c.Names != nil {
+		err = c.Names.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid names")
+		}
+	}
+
+	if c.Geo != nil {
+		err = c.Geo.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid geo config")
+		}
+	}
+
+	if c.UI != nil {
+		err = c.UI.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid UI config")
+		}
+	}
+
+	if c.I18n != nil {
+		err = c.I18n.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid i18n config")
+		}
+	}
+
+	if c.Logging != nil {
+		err = c.Logging.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid logging config")
+		}
+	}
+
+	// raw and extra exporter fields are stored as exported and may carry addresses or URLs
+	if c.Anonymization != nil && (c.RawFields || len(c.IPFIXFields) > 0) {
+		return errors.New("raw_fields and ipfix_fields can not be used with anonymization")
+	}
+
 	return nil
 }
 
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/anonymizer"
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/servers/ipfix"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAnonymization(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *Config
+		wantErr bool
+	}{
+		{
+			name: "Anonymization",
+			cfg:  &Config{Anonymization: &anonymizer.Config{}},
+		},
+		{
+			name: "Raw fields",
+			cfg:  &Config{RawFields: true, IPFIXFields: ipfix.Fields{{Element: 94, Column: "app_description"}}},
+		},
+		{
+			name:    "Anonymization with raw fields",
+			cfg:     &Config{Anonymization: &anonymizer.Config{}, RawFields: true},
+			wantErr: true,
+		},
+		{
+			name:    "Anonymization with IPFIX fields",
+			cfg:     &Config{Anonymization: &anonymizer.Config{}, IPFIXFields: ipfix.Fields{{Element: 94, Column: "app_description"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test.cfg.Clickhouse = &clickhousegw.ClickhouseConfig{}
+		err := test.cfg.Validate()
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// DictsFile holds the dicts managed through /admin/dicts as YAML list in the format of the dicts key of the config
+type DictsFile string
+
+// readDicts reads the dicts of the file. A missing file holds no dicts yet.
+func (f DictsFile) readDicts() (frontend.Dicts, error) {
+	fc, err := ioutil.ReadFile(string(f))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return frontend.Dicts{}, nil
+		}
+
+		return nil, errors.Wrap(err, "Unable to read file")
+	}
+
+	res := frontend.Dicts{}
+	err = yaml.Unmarshal(fc, &res)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to unmarshal")
+	}
+
+	return res, nil
+}
+
+// SaveDicts replaces the dicts of the file. It is written to a temporary file first, so readers never see a partial
+// file.
+func (f DictsFile) SaveDicts(d frontend.Dicts) error {
+	b, err := yaml.Marshal(d)
+	if err != nil {
+		return errors.Wrap(err, "Unable to marshal")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(string(f)), filepath.Base(string(f))+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "Unable to create temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "Unable to write temporary file")
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return errors.Wrap(err, "Unable to close temporary file")
+	}
+
+	err = os.Rename(tmp.Name(), string(f))
+	if err != nil {
+		return errors.Wrap(err, "Unable to replace file")
+	}
+
+	return nil
+}
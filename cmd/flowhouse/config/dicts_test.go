@@ -0,0 +1,60 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDictsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flowhouse")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := DictsFile(filepath.Join(dir, "dicts.yaml"))
+	dicts, err := f.readDicts()
+	assert.NoError(t, err)
+	assert.Equal(t, frontend.Dicts{}, dicts)
+
+	saved := frontend.Dicts{
+		{Field: "int_in", Dict: "interfaces_dict", Expr: "tuple(IPv6NumToString(%s), %s)", Keys: []string{"agent", "int_in"}},
+		{Field: "src_asn", Dict: "asn_dict", Expr: "tuple(%s)"},
+	}
+	assert.NoError(t, f.SaveDicts(saved))
+
+	dicts, err = f.readDicts()
+	assert.NoError(t, err)
+	assert.Equal(t, saved, dicts)
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+}
+
+func TestReadConfigDictsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flowhouse")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dictsFile := filepath.Join(dir, "dicts.yaml")
+	assert.NoError(t, DictsFile(dictsFile).SaveDicts(frontend.Dicts{{Field: "src_asn", Dict: "asn_dict", Expr: "tuple(%s)"}}))
+
+	cfgFile := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, ioutil.WriteFile(cfgFile, []byte("dicts_file: "+dictsFile+"\n"), 0644))
+
+	c, err := ReadConfig(cfgFile, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, frontend.Dicts{{Field: "src_asn", Dict: "asn_dict", Expr: "tuple(%s)"}}, c.Dicts)
+
+	assert.NoError(t, ioutil.WriteFile(cfgFile, []byte("dicts_file: "+dictsFile+"\ndicts:\n- field: dst_asn\n  dict: asn_dict\n  expr: tuple(%s)\n"), 0644))
+	_, err = ReadConfig(cfgFile, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EnvPrefix is the prefix of environment variables overriding config keys, e.g. FLOWHOUSE_CLICKHOUSE_PASSWORD
+const EnvPrefix = "FLOWHOUSE_"
+
+// Overrides are config values given as key=value with the key being the dot separated path of YAML keys, e.g.
+// clickhouse.password=secret. It implements flag.Value so it can be given multiple times on the command line.
+type Overrides []string
+
+// String implements flag.Value
+func (o *Overrides) String() string {
+	return strings.Join(*o, ",")
+}
+
+// Set implements flag.Value
+func (o *Overrides) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("Invalid override %q, expected key=value", s)
+	}
+
+	*o = append(*o, s)
+	return nil
+}
+
+// applyOverrides sets config values from environment variables (given as KEY=value) and then from overrides, so
+// overrides take precedence over the environment which takes precedence over the config file. Only keys of scalar
+// values and string lists (comma separated) outside of lists can be overridden.
+func (c *Config) applyOverrides(env []string, overrides Overrides) error {
+	paths := make(map[string][]string)
+	collectPaths(reflect.TypeOf(c).Elem(), nil, paths)
+
+	for _, e := range env {
+		if !strings.HasPrefix(e, EnvPrefix) {
+			continue
+		}
+
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		path, exists := paths[strings.TrimPrefix(parts[0], EnvPrefix)]
+		if !exists {
+			return fmt.Errorf("Unknown config key in environment variable %s", parts[0])
+		}
+
+		err := setPath(reflect.ValueOf(c).Elem(), path, parts[1])
+		if err != nil {
+			return errors.Wrapf(err, "Unable to apply environment variable %s", parts[0])
+		}
+	}
+
+	for _, o := range overrides {
+		parts := strings.SplitN(o, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("Invalid override %q, expected key=value", o)
+		}
+
+		err := setPath(reflect.ValueOf(c).Elem(), strings.Split(parts[0], "."), parts[1])
+		if err != nil {
+			return errors.Wrapf(err, "Unable to apply override %q", parts[0])
+		}
+	}
+
+	return nil
+}
+
+// collectPaths maps the environment variable names of all overridable keys of t to their YAML paths
+func collectPaths(t reflect.Type, prefix []string, paths map[string][]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := yamlKey(f)
+		if key == "" {
+			continue
+		}
+
+		path := append(append([]string{}, prefix...), key)
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct {
+			collectPaths(ft, path, paths)
+			continue
+		}
+
+		if isSettable(ft) {
+			paths[envName(path)] = path
+		}
+	}
+}
+
+// envName returns the environment variable name (without prefix) of a path. Dashes are not allowed in variable names
+// and are replaced by underscores.
+func envName(path []string) string {
+	return strings.ToUpper(strings.Replace(strings.Join(path, "_"), "-", "_", -1))
+}
+
+func yamlKey(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+
+	key := strings.Split(f.Tag.Get("yaml"), ",")[0]
+	if key == "-" {
+		return ""
+	}
+
+	return key
+}
+
+func isSettable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+
+	return false
+}
+
+// setPath sets the value at path below v, allocating nil structs on the way
+func setPath(v reflect.Value, path []string, value string) error {
+	for i, key := range path {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		if v.Kind() != reflect.Struct {
+			return fmt.Errorf("%q is not a section", strings.Join(path[:i], "."))
+		}
+
+		field, found := fieldByYAMLKey(v, key)
+		if !found {
+			return fmt.Errorf("Unknown key %q", strings.Join(path[:i+1], "."))
+		}
+		v = field
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if !isSettable(v.Type()) {
+		return fmt.Errorf("%q can not be overridden", strings.Join(path, "."))
+	}
+
+	return setValue(v, value)
+}
+
+func fieldByYAMLKey(v reflect.Value, key string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		if yamlKey(v.Type().Field(i)) == key {
+			return v.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("Invalid bool %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("Invalid integer %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("Invalid unsigned integer %q", value)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("Invalid number %q", value)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		items := make([]string, 0)
+		for _, x := range strings.Split(value, ",") {
+			if x = strings.TrimSpace(x); x != "" {
+				items = append(items, x)
+			}
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	}
+
+	return nil
+}
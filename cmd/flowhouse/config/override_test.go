@@ -0,0 +1,91 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       []string
+		overrides Overrides
+		expected  *Config
+		wantFail  bool
+	}{
+		{
+			name: "Environment",
+			env:  []string{"PATH=/bin", "FLOWHOUSE_CLICKHOUSE_PASSWORD=secret", "FLOWHOUSE_LISTEN_HTTP=:8080", "FLOWHOUSE_DECODE_TUNNELS=true"},
+			expected: &Config{
+				ListenHTTP:    ":8080",
+				DecodeTunnels: true,
+				Clickhouse: &clickhousegw.ClickhouseConfig{
+					Address:  "clickhouse:9000",
+					Password: "secret",
+				},
+			},
+		},
+		{
+			name:      "Overrides take precedence",
+			env:       []string{"FLOWHOUSE_CLICKHOUSE_PASSWORD=secret"},
+			overrides: Overrides{"clickhouse.password=other", "ris_timeout=30"},
+			expected: &Config{
+				RISTimeout: 30,
+				Clickhouse: &clickhousegw.ClickhouseConfig{
+					Address:  "clickhouse:9000",
+					Password: "other",
+				},
+			},
+		},
+		{
+			name:     "Unknown environment variable",
+			env:      []string{"FLOWHOUSE_CLICKHOUSE_PASSWROD=secret"},
+			wantFail: true,
+		},
+		{
+			name:      "Unknown key",
+			overrides: Overrides{"clickhouse.passwrod=secret"},
+			wantFail:  true,
+		},
+		{
+			name:      "Invalid value",
+			overrides: Overrides{"ris_timeout=soon"},
+			wantFail:  true,
+		},
+		{
+			name:      "List",
+			overrides: Overrides{"routers=foo"},
+			wantFail:  true,
+		},
+	}
+
+	for _, test := range tests {
+		c := &Config{
+			Clickhouse: &clickhousegw.ClickhouseConfig{
+				Address: "clickhouse:9000",
+			},
+		}
+
+		err := c.applyOverrides(test.env, test.overrides)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, c, test.name)
+	}
+}
+
+func TestApplyOverridesAllocatesSections(t *testing.T) {
+	c := &Config{}
+	err := c.applyOverrides([]string{"FLOWHOUSE_RELAY_SFLOW=192.0.2.1:6343, 192.0.2.2:6343"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.1:6343", "192.0.2.2:6343"}, c.Relay.SFlow)
+
+	err = c.applyOverrides([]string{"FLOWHOUSE_SNMP_AUTH_KEY=secret"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", c.SNMP.AuthPassphrase)
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"reflect"
+
+	"github.com/bio-routing/flowhouse/pkg/secrets"
+	"github.com/pkg/errors"
+)
+
+// resolveSecrets replaces all secret references in string values by the referenced secrets
+func (c *Config) resolveSecrets() error {
+	c.secrets = secrets.New(c.Secrets)
+
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		// the Vault token is resolved by the resolver itself
+		if v.Type().Field(i).Name == "Secrets" {
+			continue
+		}
+
+		err := resolveValue(c.secrets, v.Field(i), yamlKey(v.Type().Field(i)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resolveValue(r *secrets.Resolver, v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return resolveValue(r, v.Elem(), path)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			key := yamlKey(v.Type().Field(i))
+			if key == "" {
+				continue
+			}
+
+			err := resolveValue(r, v.Field(i), path+"."+key)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			err := resolveValue(r, v.Index(i), path)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		if !secrets.IsReference(v.String()) {
+			return nil
+		}
+
+		s, err := r.Resolve(v.String())
+		if err != nil {
+			return errors.Wrapf(err, "Unable to resolve secret for %s", path)
+		}
+		v.SetString(s)
+	}
+
+	return nil
+}
+
+// SecretsChanged checks if any of the referenced secrets changed since the config was read
+func (c *Config) SecretsChanged() (bool, error) {
+	if c.secrets == nil {
+		return false, nil
+	}
+
+	return c.secrets.Changed()
+}
+
+// GetSecretsRefreshInterval gets the interval in seconds secrets should be checked for rotation (0 if disabled)
+func (c *Config) GetSecretsRefreshInterval() uint64 {
+	if c.Secrets == nil {
+		return 0
+	}
+
+	return c.Secrets.RefreshInterval
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/alerting"
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	os.Setenv("FLOWHOUSE_TEST_PASSWORD", "secret")
+	defer os.Unsetenv("FLOWHOUSE_TEST_PASSWORD")
+
+	c := &Config{
+		Clickhouse: &clickhousegw.ClickhouseConfig{
+			User:     "flowhouse",
+			Password: "${env:FLOWHOUSE_TEST_PASSWORD}",
+		},
+		Alerting: &alerting.Config{
+			Email: []*alerting.EmailConfig{
+				{Name: "noc", Password: "${env:FLOWHOUSE_TEST_PASSWORD}"},
+			},
+		},
+	}
+
+	err := c.resolveSecrets()
+	assert.NoError(t, err)
+	assert.Equal(t, "flowhouse", c.Clickhouse.User)
+	assert.Equal(t, "secret", c.Clickhouse.Password)
+	assert.Equal(t, "secret", c.Alerting.Email[0].Password)
+
+	changed, err := c.SecretsChanged()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	c.Clickhouse.Password = "${env:FLOWHOUSE_TEST_UNSET}"
+	err = c.resolveSecrets()
+	assert.Error(t, err)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hash"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+)
+
+// dedupFlag adds the flag selecting how repeated inserts of the same data are deduplicated
+func dedupFlag(fs *flag.FlagSet) *string {
+	return fs.String("dedup", clickhousegw.DedupToken, "Deduplicate inserts by a token derived from the input (token), by their blocks (blocks) or not at all (off)")
+}
+
+// batchDedup gets the deduplication of the seq-th batch whose input was written to h. Tokens depend on the position
+// of the batch as well, so equal batches of an input are all inserted.
+func batchDedup(mode string, seq int, h hash.Hash) clickhousegw.Dedup {
+	d := clickhousegw.Dedup{Mode: mode}
+	if mode == clickhousegw.DedupToken {
+		d.Token = fmt.Sprintf("%d-%x", seq, h.Sum(nil))
+	}
+
+	return d
+}
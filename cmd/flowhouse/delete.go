@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/retention"
+)
+
+// deleteFlows deletes the flows of agents, customers or a time range and writes the deletion to the audit log
+func deleteFlows(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	start := fs.String("start", "", "Delete flows from this time on (RFC 3339, default unbounded)")
+	end := fs.String("end", "", "Delete flows before this time (RFC 3339, default unbounded)")
+	agents := fs.String("agent", "", "Comma separated agents whose flows are deleted")
+	customers := fs.String("customer", "", "Comma separated customers whose flows are deleted")
+	dryRun := fs.Bool("dry-run", false, "Only count the flows that would be deleted")
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	req := &retention.Request{
+		Agents:    splitList(*agents),
+		Customers: splitList(*customers),
+		DryRun:    *dryRun,
+	}
+
+	for _, x := range []struct {
+		name  string
+		value string
+		t     **time.Time
+	}{
+		{name: "start", value: *start, t: &req.Start},
+		{name: "end", value: *end, t: &req.End},
+	} {
+		if x.value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, x.value)
+		if err != nil {
+			return fail(fmt.Errorf("Invalid %s %q", x.name, x.value))
+		}
+		*x.t = &t
+	}
+
+	chgw, err := cf.connect()
+	if err != nil {
+		return fail(err)
+	}
+	defer chgw.Close()
+
+	res, err := retention.Delete(context.Background(), chgw, req, actor())
+	if err != nil {
+		return fail(err)
+	}
+
+	if res.DryRun {
+		fmt.Printf("%d flows would be deleted\n", res.Rows)
+		return 0
+	}
+
+	fmt.Printf("%d flows deleted, %d partitions dropped\n", res.Rows, res.DroppedPartitions)
+	if res.Mutation {
+		fmt.Println("The remaining flows are deleted by a mutation in the background, see system.mutations")
+	}
+
+	return 0
+}
+
+// splitList splits a comma separated list, ignoring empty elements
+func splitList(s string) []string {
+	res := make([]string, 0)
+	for _, x := range strings.Split(s, ",") {
+		x = strings.TrimSpace(x)
+		if x != "" {
+			res = append(res, x)
+		}
+	}
+
+	return res
+}
+
+// actor gets the name of the user running the command for the audit log
+func actor() string {
+	u, err := user.Current()
+	if err != nil {
+		return "cli"
+	}
+
+	return "cli:" + u.Username
+}
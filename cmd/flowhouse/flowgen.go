@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/packet"
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// flowgen inserts random flows between documentation prefixes at a fixed rate
+func flowgen(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	rate := fs.Int("rate", 1000, "Flows per second")
+	duration := fs.Duration("duration", time.Minute, "Time to generate flows for")
+	agent := fs.String("agent", "192.0.2.1", "Agent address of the flows")
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	agentAddr, err := netip.ParseAddr(*agent)
+	if err != nil {
+		return fail(errors.Wrap(err, "Invalid agent"))
+	}
+
+	chgw, err := cf.connect()
+	if err != nil {
+		return fail(err)
+	}
+	defer chgw.Close()
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+
+	total := 0
+	stop := time.Now().Add(*duration)
+	for now := time.Now(); now.Before(stop); now = <-t.C {
+		flows := make([]*flow.Flow, *rate)
+		for i := range flows {
+			flows[i] = generateFlow(rnd, agentAddr, now)
+		}
+
+		err := chgw.InsertFlows(flows)
+		if err != nil {
+			return fail(errors.Wrap(err, "Insert failed"))
+		}
+		flow.ReleaseAll(flows)
+
+		total += len(flows)
+		log.WithField("flows", total).Debug("Generated flows")
+	}
+
+	fmt.Printf("Generated %d flows\n", total)
+	return 0
+}
+
+// generateFlow creates a flow from 198.51.100.0/24 to 203.0.113.0/24 with a random protocol, ports and size
+func generateFlow(rnd *rand.Rand, agent netip.Addr, now time.Time) *flow.Flow {
+	src := netip.AddrFrom4([4]byte{198, 51, 100, uint8(rnd.Intn(256))})
+	dst := netip.AddrFrom4([4]byte{203, 0, 113, uint8(rnd.Intn(256))})
+
+	fl := flow.New()
+	*fl = flow.Flow{
+		Agent:      agent,
+		IntIn:      "eth0",
+		IntOut:     "eth1",
+		SrcAddr:    src,
+		DstAddr:    dst,
+		NextHop:    netip.AddrFrom4([4]byte{192, 0, 2, 254}),
+		SrcPfx:     netip.PrefixFrom(netip.AddrFrom4([4]byte{198, 51, 100, 0}), 24),
+		DstPfx:     netip.PrefixFrom(netip.AddrFrom4([4]byte{203, 0, 113, 0}), 24),
+		SrcAs:      64496,
+		DstAs:      64497 + uint32(rnd.Intn(4)),
+		Family:     4,
+		Timestamp:  now.Unix(),
+		ReceivedAt: now.Unix(),
+		Packets:    uint64(1 + rnd.Intn(10)),
+		Samplerate: 1000,
+	}
+	fl.Size = fl.Packets * uint64(64+rnd.Intn(1437))
+
+	switch rnd.Intn(10) {
+	case 0:
+		fl.Protocol = packet.ICMP
+	case 1, 2, 3:
+		fl.Protocol = packet.UDP
+		fl.SrcPort = uint16(1024 + rnd.Intn(64512))
+		fl.DstPort = []uint16{53, 123, 443, 4500}[rnd.Intn(4)]
+	default:
+		fl.Protocol = packet.TCP
+		fl.SrcPort = uint16(1024 + rnd.Intn(64512))
+		fl.DstPort = []uint16{22, 80, 443, 8080}[rnd.Intn(4)]
+		fl.TCPFlags = 0x18
+	}
+
+	return fl
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/pkg/errors"
+)
+
+const importBatchSize = 10000
+
+// importFlows inserts flows written by export into the flows table. Importing a file again inserts nothing unless
+// deduplication is off.
+func importFlows(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	dedup := dedupFlag(fs)
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	err := clickhousegw.ValidateDedupMode(*dedup)
+	if err != nil {
+		return fail(err)
+	}
+
+	in := os.Stdin
+	if fs.NArg() > 0 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return fail(errors.Wrap(err, "Unable to open input file"))
+		}
+		defer f.Close()
+		in = f
+	}
+
+	chgw, err := cf.connect()
+	if err != nil {
+		return fail(err)
+	}
+	defer chgw.Close()
+
+	n, err := importRows(chgw, in, *dedup)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("Imported %d flows\n", n)
+	return 0
+}
+
+// rowInserter inserts rows into a table
+type rowInserter interface {
+	GetColumns(table string) ([]*clickhousegw.Column, error)
+	InsertRowsDedup(table string, columns []string, rows [][]interface{}, d clickhousegw.Dedup) error
+}
+
+// importRows reads JSON lines from r and inserts them in batches deduplicated as given by dedup. All lines must have
+// the same columns.
+func importRows(db rowInserter, r io.Reader, dedup string) (int, error) {
+	tableColumns, err := db.GetColumns(flowsTable)
+	if err != nil {
+		return 0, errors.Wrap(err, "Unable to get columns")
+	}
+
+	types := make(map[string]string, len(tableColumns))
+	for _, c := range tableColumns {
+		types[c.Name] = c.Type
+	}
+
+	var columns []string
+	batch := make([][]interface{}, 0, importBatchSize)
+	count := 0
+	seq := 0
+	h := sha256.New()
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		row := make(map[string]string)
+		err := dec.Decode(&row)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return count, errors.Wrapf(err, "Unable to decode flow %d", line)
+		}
+
+		if columns == nil {
+			columns = make([]string, 0, len(row))
+			for c := range row {
+				if _, exists := types[c]; !exists {
+					return count, fmt.Errorf("Unknown column %q in flow %d", c, line)
+				}
+				columns = append(columns, c)
+			}
+			sort.Strings(columns)
+		}
+
+		if len(row) != len(columns) {
+			return count, fmt.Errorf("Flow %d has %d columns, expected %d", line, len(row), len(columns))
+		}
+
+		values := make([]interface{}, len(columns))
+		for i, c := range columns {
+			s, exists := row[c]
+			if !exists {
+				return count, fmt.Errorf("Column %q missing in flow %d", c, line)
+			}
+
+			values[i], err = clickhousegw.ParseValue(types[c], s)
+			if err != nil {
+				return count, errors.Wrapf(err, "Invalid column %q in flow %d", c, line)
+			}
+
+			fmt.Fprintf(h, "%s=%q,", c, s)
+		}
+		h.Write([]byte{'\n'})
+
+		batch = append(batch, values)
+		if len(batch) == importBatchSize {
+			err := db.InsertRowsDedup(flowsTable, columns, batch, batchDedup(dedup, seq, h))
+			if err != nil {
+				return count, errors.Wrap(err, "Insert failed")
+			}
+
+			count += len(batch)
+			batch = batch[:0]
+			seq++
+			h.Reset()
+		}
+	}
+
+	if len(batch) > 0 {
+		err := db.InsertRowsDedup(flowsTable, columns, batch, batchDedup(dedup, seq, h))
+		if err != nil {
+			return count, errors.Wrap(err, "Insert failed")
+		}
+
+		count += len(batch)
+	}
+
+	return count, nil
+}
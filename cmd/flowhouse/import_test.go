@@ -0,0 +1,167 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRowInserter struct {
+	columns  []string
+	inserted [][]interface{}
+	dedups   []clickhousegw.Dedup
+}
+
+func (m *mockRowInserter) GetColumns(table string) ([]*clickhousegw.Column, error) {
+	return []*clickhousegw.Column{
+		{Name: "agent", Type: "IPv6"},
+		{Name: "timestamp", Type: "DateTime"},
+		{Name: "size", Type: "UInt64"},
+	}, nil
+}
+
+func (m *mockRowInserter) InsertRowsDedup(table string, columns []string, rows [][]interface{}, d clickhousegw.Dedup) error {
+	m.columns = columns
+	m.inserted = append(m.inserted, rows...)
+	m.dedups = append(m.dedups, d)
+	return nil
+}
+
+func TestImportRows(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		wantCount      int
+		wantColumns    []string
+		wantFirstValue []interface{}
+		wantFail       bool
+	}{
+		{
+			name: "Two flows",
+			input: `{"agent":"192.0.2.1","size":"100","timestamp":"2020-01-01T00:00:00Z"}
+{"agent":"192.0.2.2","size":"200","timestamp":"2020-01-01T00:00:01Z"}
+`,
+			wantCount:      2,
+			wantColumns:    []string{"agent", "size", "timestamp"},
+			wantFirstValue: []interface{}{"192.0.2.1", uint64(100), time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "Unknown column",
+			input:    `{"agent":"192.0.2.1","foo":"bar"}`,
+			wantFail: true,
+		},
+		{
+			name: "Missing column",
+			input: `{"agent":"192.0.2.1","size":"100"}
+{"agent":"192.0.2.2"}
+`,
+			wantFail: true,
+		},
+		{
+			name:     "Invalid value",
+			input:    `{"size":"-1"}`,
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		m := &mockRowInserter{}
+		n, err := importRows(m, strings.NewReader(test.input), clickhousegw.DedupToken)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		if !assert.NoError(t, err, test.name) {
+			continue
+		}
+
+		assert.Equal(t, test.wantCount, n, test.name)
+		assert.Equal(t, test.wantColumns, m.columns, test.name)
+		assert.Equal(t, test.wantFirstValue[0], clickhousegw.FormatValue(m.inserted[0][0]), test.name)
+		assert.Equal(t, test.wantFirstValue[1:], m.inserted[0][1:], test.name)
+	}
+}
+
+func TestImportRowsDedup(t *testing.T) {
+	input := `{"agent":"192.0.2.1","size":"100","timestamp":"2020-01-01T00:00:00Z"}
+`
+	other := `{"agent":"192.0.2.1","size":"200","timestamp":"2020-01-01T00:00:00Z"}
+`
+
+	first := &mockRowInserter{}
+	_, err := importRows(first, strings.NewReader(input), clickhousegw.DedupToken)
+	assert.NoError(t, err)
+
+	again := &mockRowInserter{}
+	_, err = importRows(again, strings.NewReader(input), clickhousegw.DedupToken)
+	assert.NoError(t, err)
+
+	changed := &mockRowInserter{}
+	_, err = importRows(changed, strings.NewReader(other), clickhousegw.DedupToken)
+	assert.NoError(t, err)
+
+	off := &mockRowInserter{}
+	_, err = importRows(off, strings.NewReader(input), clickhousegw.DedupOff)
+	assert.NoError(t, err)
+
+	if assert.Len(t, first.dedups, 1) && assert.Len(t, again.dedups, 1) && assert.Len(t, changed.dedups, 1) {
+		assert.Equal(t, clickhousegw.DedupToken, first.dedups[0].Mode)
+		assert.True(t, strings.HasPrefix(first.dedups[0].Token, "0-"))
+		assert.Equal(t, first.dedups[0], again.dedups[0], "same input")
+		assert.NotEqual(t, first.dedups[0].Token, changed.dedups[0].Token, "changed input")
+	}
+
+	assert.Equal(t, []clickhousegw.Dedup{{Mode: clickhousegw.DedupOff}}, off.dedups)
+}
+
+func TestParseTimeRange(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantFail  bool
+	}{
+		{
+			name:      "Defaults",
+			wantStart: now.Add(-time.Hour),
+			wantEnd:   now,
+		},
+		{
+			name:      "Start only",
+			start:     "2020-01-01T00:00:00Z",
+			wantStart: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   now,
+		},
+		{
+			name:     "Invalid end",
+			end:      "yesterday",
+			wantFail: true,
+		},
+		{
+			name:     "End before start",
+			start:    "2020-01-01T11:00:00Z",
+			end:      "2020-01-01T10:00:00Z",
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		from, to, err := parseTimeRange(test.start, test.end, now)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.True(t, test.wantStart.Equal(from), test.name)
+		assert.True(t, test.wantEnd.Equal(to), test.name)
+	}
+}
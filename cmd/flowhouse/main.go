@@ -2,62 +2,143 @@ package main
 
 import (
 	"flag"
-	"sync"
-	"time"
+	"fmt"
+	"os"
+	"strings"
 
 	"github.com/bio-routing/flowhouse/cmd/flowhouse/config"
-	"github.com/bio-routing/flowhouse/pkg/flowhouse"
-
-	log "github.com/sirupsen/logrus"
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/logging"
+	"github.com/bio-routing/flowhouse/pkg/version"
 )
 
-var (
-	configFilePath = flag.String("config.file", "config.yaml", "Config file path (YAML)")
-	debug          = flag.Bool("debug", false, "Enable debug logging")
-)
+// command is a subcommand of flowhouse. run gets the arguments following the subcommand and returns the exit code.
+type command struct {
+	name        string
+	args        string
+	description string
+	run         func(c *command, args []string) int
+}
+
+var commands = []*command{
+	{name: "serve", description: "Run the collector and web frontend (default)", run: serve},
+	{name: "migrate", description: "Create missing tables and dictionaries in ClickHouse", run: migrate},
+	{name: "query", args: "<sql>", description: "Run a SQL query against ClickHouse and print the result as TSV", run: query},
+	{name: "export", description: "Export flows of a time range as JSON lines", run: export},
+	{name: "delete", description: "Delete flows by agent, customer or time range", run: deleteFlows},
+	{name: "import", args: "[file]", description: "Import flows written by export (from stdin if no file is given)", run: importFlows},
+	{name: "check-config", description: "Check the config file and the dicts in ClickHouse", run: checkConfig},
+	{name: "reload-dicts", description: "Reload dicts from their sources and print their state", run: reloadDicts},
+	{name: "flowgen", description: "Insert generated flows for testing and demos", run: flowgen},
+	{name: "bench", description: "Measure decoding and insert throughput with synthetic or recorded datagrams", run: bench},
+	{name: "billing", description: "Print the 95th percentile billing report of a month", run: billingReport},
+	{name: "replay", args: "<pcap file>", description: "Insert the flows of the sflow and IPFIX datagrams of a pcap file", run: replay},
+	{name: "version", description: "Print version and build information", run: printVersion},
+}
 
 func main() {
-	flag.Parse()
+	args := os.Args[1:]
+
+	// without a command (or with flags only) flowhouse serves as it did before there were commands
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		usage()
+		os.Exit(0)
+	}
 
-	if *debug {
-		log.SetLevel(log.DebugLevel)
-		log.Debug("logLevel: DEBUG")
-	} else {
-		log.SetLevel(log.InfoLevel)
+	for _, c := range commands {
+		if c.name == name {
+			os.Exit(c.run(c, args))
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: flowhouse <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", c.name, c.description)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nRun flowhouse <command> -h for the flags of a command.\n")
+}
+
+// commonFlags are the flags all commands have
+type commonFlags struct {
+	configFilePath string
+	debug          bool
+	overrides      config.Overrides
+}
+
+// flagSet creates the flag set of a command with the common flags
+func (c *command) flagSet() (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	cf := &commonFlags{}
+	fs.StringVar(&cf.configFilePath, "config.file", "config.yaml", "Config file path (YAML)")
+	fs.BoolVar(&cf.debug, "debug", false, "Enable debug logging")
+	fs.Var(&cf.overrides, "set", "Override a config value as key=value, e.g. clickhouse.password=secret (repeatable)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s\n\n%s\n\nFlags:\n", strings.TrimSpace("flowhouse "+c.name+" [flags] "+c.args), c.description)
+		fs.PrintDefaults()
+	}
+
+	return fs, cf
+}
+
+// parse parses the arguments of a command. If parsing failed or help was requested, ok is false and code the exit code.
+func parse(fs *flag.FlagSet, cf *commonFlags, args []string) (code int, ok bool) {
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {
+		return 0, false
 	}
 
-	cfg, err := config.GetConfig(*configFilePath)
 	if err != nil {
-		log.WithError(err).Fatal("Unable to get config")
+		return 2, false
 	}
 
-	fhcfg := &flowhouse.Config{
-		ChCfg:              cfg.Clickhouse,
-		SNMP:               cfg.SNMP,
-		RISTimeout:         time.Duration(cfg.RISTimeout) * time.Second,
-		ListenSflow:        cfg.ListenSFlow,
-		ListenIPFIX:        cfg.ListenIPFIX,
-		ListenHTTP:         cfg.ListenHTTP,
-		DefaultVRF:         cfg.GetDefaultVRF(),
-		Dicts:              cfg.Dicts,
-		DisableIPAnnotator: cfg.DisableIPAnnotator,
+	logging.Configure(nil, cf.debug)
+	return 0, true
+}
+
+// getConfig reads and validates the config file and configures logging
+func (cf *commonFlags) getConfig() (*config.Config, error) {
+	cfg, err := config.GetConfig(cf.configFilePath, cf.overrides)
+	if err != nil {
+		return nil, err
 	}
 
-	fh, err := flowhouse.New(fhcfg)
+	err = logging.Configure(cfg.Logging, cf.debug)
 	if err != nil {
-		log.WithError(err).Fatal("Unable to create flowhouse instance")
+		return nil, err
 	}
 
-	for _, rtr := range cfg.Routers {
-		fh.AddAgent(rtr.Name, rtr.GetAddress(), rtr.RISInstances, rtr.GetVRFs())
+	return cfg, nil
+}
+
+// connect reads the config file and connects to ClickHouse without changing the schema
+func (cf *commonFlags) connect() (*clickhousegw.ClickHouseGateway, error) {
+	cfg, err := cf.getConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		fh.Run()
-	}()
+	return clickhousegw.Connect(cfg.Clickhouse)
+}
+
+func printVersion(c *command, args []string) int {
+	fmt.Println(version.Get())
+	return 0
+}
 
-	wg.Wait()
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "%v\n", err)
+	return 1
 }
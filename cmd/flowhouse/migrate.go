@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/pkg/errors"
+)
+
+// migrate creates all tables and dictionaries flowhouse uses if they don't exist
+func migrate(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	cfg, err := cf.getConfig()
+	if err != nil {
+		return fail(err)
+	}
+
+	chgw, err := clickhousegw.New(cfg.Clickhouse)
+	if err != nil {
+		return fail(errors.Wrap(err, "Unable to create flows schema"))
+	}
+	defer chgw.Close()
+
+	steps := []struct {
+		name string
+		f    func() error
+	}{
+		{name: "agents", f: chgw.CreateAgentsSchemaIfNotExists},
+		{name: "annotations", f: chgw.CreateAnnotationsSchemaIfNotExists},
+		{name: "sessions", f: chgw.CreateSessionsSchemaIfNotExists},
+	}
+
+	if cfg.SNMP != nil && cfg.SNMP.ExportInterfaces {
+		steps = append(steps, struct {
+			name string
+			f    func() error
+		}{name: "interfaces", f: chgw.CreateInterfacesSchemaIfNotExists})
+	}
+
+	if cfg.Heartbeats != nil {
+		steps = append(steps, struct {
+			name string
+			f    func() error
+		}{name: "heartbeats", f: chgw.CreateHeartbeatsSchemaIfNotExists})
+	}
+
+	if cfg.HostCounters != nil {
+		steps = append(steps, struct {
+			name string
+			f    func() error
+		}{name: "host counters", f: chgw.CreateHostCountersSchemaIfNotExists})
+	}
+
+	for _, s := range steps {
+		err := s.f()
+		if err != nil {
+			return fail(errors.Wrapf(err, "Unable to create %s schema", s.name))
+		}
+	}
+
+	fmt.Println("Schema is up to date")
+	return 0
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/pkg/errors"
+)
+
+const flowsTable = "flows"
+
+// query runs a SQL query and prints the result as tab separated values with a header line
+func query(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	q := strings.Join(fs.Args(), " ")
+	if q == "" {
+		fs.Usage()
+		return 2
+	}
+
+	chgw, err := cf.connect()
+	if err != nil {
+		return fail(err)
+	}
+	defer chgw.Close()
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	err = scanRows(chgw, q, func(columns []string) error {
+		_, err := fmt.Fprintln(w, strings.Join(columns, "\t"))
+		return err
+	}, func(columns []string, values []string) error {
+		_, err := fmt.Fprintln(w, strings.Join(values, "\t"))
+		return err
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	return 0
+}
+
+// export writes the flows of a time range as one JSON object per line mapping column names to values
+func export(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	start := fs.String("start", "", "Start of the time range (RFC 3339, default one hour ago)")
+	end := fs.String("end", "", "End of the time range (RFC 3339, default now)")
+	where := fs.String("where", "", "Additional SQL condition, e.g. \"agent = toIPv6('192.0.2.1')\"")
+	output := fs.String("output", "", "Output file (default stdout)")
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	from, to, err := parseTimeRange(*start, *end, time.Now())
+	if err != nil {
+		return fail(err)
+	}
+
+	chgw, err := cf.connect()
+	if err != nil {
+		return fail(err)
+	}
+	defer chgw.Close()
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			return fail(errors.Wrap(err, "Unable to create output file"))
+		}
+		defer out.Close()
+	}
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	q := fmt.Sprintf("SELECT * FROM %s WHERE timestamp >= toDateTime(%d) AND timestamp < toDateTime(%d)", flowsTable, from.Unix(), to.Unix())
+	if *where != "" {
+		q += " AND (" + *where + ")"
+	}
+	q += " ORDER BY timestamp"
+
+	enc := json.NewEncoder(w)
+	err = scanRows(chgw, q, nil, func(columns []string, values []string) error {
+		row := make(map[string]string, len(columns))
+		for i, c := range columns {
+			row[c] = values[i]
+		}
+
+		return enc.Encode(row)
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	return 0
+}
+
+// parseTimeRange parses start and end, defaulting to the hour before now
+func parseTimeRange(start string, end string, now time.Time) (time.Time, time.Time, error) {
+	from, to := now.Add(-time.Hour), now
+	for _, x := range []struct {
+		name  string
+		value string
+		t     *time.Time
+	}{
+		{name: "start", value: start, t: &from},
+		{name: "end", value: end, t: &to},
+	} {
+		if x.value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, x.value)
+		if err != nil {
+			return from, to, fmt.Errorf("Invalid %s %q", x.name, x.value)
+		}
+		*x.t = t
+	}
+
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("Start must be before end")
+	}
+
+	return from, to, nil
+}
+
+// scanRows runs q and calls header with the column names and row with the formatted values of each row
+func scanRows(chgw *clickhousegw.ClickHouseGateway, q string, header func(columns []string) error, row func(columns []string, values []string) error) error {
+	rows, err := chgw.Query(q)
+	if err != nil {
+		return errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return errors.Wrap(err, "Unable to get columns")
+	}
+
+	if header != nil {
+		err = header(columns)
+		if err != nil {
+			return err
+		}
+	}
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	formatted := make([]string, len(columns))
+	for rows.Next() {
+		err := rows.Scan(valuePtrs...)
+		if err != nil {
+			return errors.Wrap(err, "Scan failed")
+		}
+
+		for i, v := range values {
+			formatted[i] = clickhousegw.FormatValue(v)
+		}
+
+		err = row(columns, formatted)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+)
+
+// reloadDicts reloads the configured dicts (or the one given by -dict) from their sources and prints their state
+func reloadDicts(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	dict := fs.String("dict", "", "Reload only this dict")
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	cfg, err := cf.getConfig()
+	if err != nil {
+		return fail(err)
+	}
+
+	names := cfg.Dicts.DictNames()
+	if *dict != "" {
+		names = []string{*dict}
+	}
+
+	chgw, err := clickhousegw.Connect(cfg.Clickhouse)
+	if err != nil {
+		return fail(err)
+	}
+	defer chgw.Close()
+
+	code := 0
+	for _, name := range names {
+		r := chgw.ReloadDict(name)
+		if r.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", r.Dict, r.Error)
+			code = 1
+			continue
+		}
+
+		fmt.Printf("%s: %s, %d elements\n", r.Dict, r.Status, r.Elements)
+	}
+
+	return code
+}
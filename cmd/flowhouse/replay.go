@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/decodelog"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/servers/ipfix"
+	"github.com/bio-routing/flowhouse/pkg/servers/sflow"
+	"github.com/pkg/errors"
+)
+
+// flowInserter inserts deduplicated batches of flows
+type flowInserter interface {
+	InsertFlowsDedup(flows []*flow.Flow, d clickhousegw.Dedup) error
+}
+
+// replay decodes the sflow and IPFIX datagrams of a pcap file once and inserts their flows. Replaying a file again
+// inserts nothing unless deduplication is off.
+func replay(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	sflowPort := fs.Uint("sflow-port", 6343, "UDP port of sflow datagrams in the pcap file")
+	ipfixPort := fs.Uint("ipfix-port", 4739, "UDP port of IPFIX datagrams in the pcap file")
+	batchSize := fs.Int("batch-size", 1000, "Datagrams per insert")
+	dedup := dedupFlag(fs)
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	if fs.NArg() != 1 {
+		return fail(fmt.Errorf("Expected one pcap file"))
+	}
+
+	if *batchSize < 1 {
+		return fail(fmt.Errorf("batch-size must be positive"))
+	}
+
+	err := clickhousegw.ValidateDedupMode(*dedup)
+	if err != nil {
+		return fail(err)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fail(errors.Wrap(err, "Unable to open pcap file"))
+	}
+	defer f.Close()
+
+	datagrams, err := readPcap(f, uint16(*sflowPort), uint16(*ipfixPort))
+	if err != nil {
+		return fail(err)
+	}
+
+	chgw, err := cf.connect()
+	if err != nil {
+		return fail(err)
+	}
+	defer chgw.Close()
+
+	n, err := replayDatagrams(chgw, datagrams, *batchSize, *dedup)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("Replayed %d datagrams, inserted %d flows\n", len(datagrams), n)
+	return 0
+}
+
+// replayDatagrams decodes datagrams and inserts the flows of every batchSize datagrams deduplicated as given by dedup.
+// Tokens are derived from the datagrams as the flows of a replay differ, e.g. by their receive time.
+func replayDatagrams(db flowInserter, datagrams []*benchDatagram, batchSize int, dedup string) (int, error) {
+	var flows []*flow.Flow
+	sfd := sflow.NewDecoder(nopResolver{}, true, func(fl *flow.Flow) {
+		flows = append(flows, fl)
+	})
+	ipd := ipfix.NewDecoder(nopResolver{}, func(fls []*flow.Flow) {
+		flows = append(flows, fls...)
+	})
+
+	count := 0
+	h := sha256.New()
+	for seq := 0; seq*batchSize < len(datagrams); seq++ {
+		end := (seq + 1) * batchSize
+		if end > len(datagrams) {
+			end = len(datagrams)
+		}
+
+		h.Reset()
+		for _, d := range datagrams[seq*batchSize : end] {
+			// the decoders work in place, so the datagram is hashed first
+			fmt.Fprintf(h, "%s %s %d\n", d.protocol, d.agent.String(), len(d.data))
+			h.Write(d.data)
+
+			if d.protocol == decodelog.ProtocolIPFIX {
+				ipd.Decode(d.agent, d.data)
+			} else {
+				sfd.Decode(d.agent, d.data)
+			}
+		}
+
+		if len(flows) == 0 {
+			continue
+		}
+
+		err := db.InsertFlowsDedup(flows, batchDedup(dedup, seq, h))
+		if err != nil {
+			return count, errors.Wrap(err, "Insert failed")
+		}
+
+		count += len(flows)
+		flow.ReleaseAll(flows)
+		flows = flows[:0]
+	}
+
+	return count, nil
+}
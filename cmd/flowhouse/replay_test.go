@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockFlowInserter struct {
+	flows  int
+	dedups []clickhousegw.Dedup
+}
+
+func (m *mockFlowInserter) InsertFlowsDedup(flows []*flow.Flow, d clickhousegw.Dedup) error {
+	m.flows += len(flows)
+	m.dedups = append(m.dedups, d)
+	return nil
+}
+
+func TestReplayDatagrams(t *testing.T) {
+	replayed := func(dedup string) *mockFlowInserter {
+		m := &mockFlowInserter{}
+		n, err := replayDatagrams(m, syntheticDatagrams(rand.New(rand.NewSource(1)), 5), 2, dedup)
+		assert.NoError(t, err)
+		assert.Equal(t, m.flows, n)
+		return m
+	}
+
+	first := replayed(clickhousegw.DedupToken)
+	again := replayed(clickhousegw.DedupToken)
+	assert.Equal(t, 5*syntheticSamples, first.flows)
+	if assert.Len(t, first.dedups, 3) {
+		assert.Equal(t, first.dedups, again.dedups, "tokens of a replay")
+		assert.NotEqual(t, first.dedups[0].Token, first.dedups[1].Token, "tokens of batches")
+	}
+
+	off := replayed(clickhousegw.DedupOff)
+	assert.Equal(t, []clickhousegw.Dedup{{Mode: clickhousegw.DedupOff}, {Mode: clickhousegw.DedupOff}, {Mode: clickhousegw.DedupOff}}, off.dedups)
+}
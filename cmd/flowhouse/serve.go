@@ -0,0 +1,269 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bio-routing/flowhouse/cmd/flowhouse/config"
+	"github.com/bio-routing/flowhouse/pkg/flowhouse"
+	"github.com/bio-routing/flowhouse/pkg/flowsink"
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/bio-routing/flowhouse/pkg/sdnotify"
+	"github.com/bio-routing/flowhouse/pkg/version"
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// serve runs flowhouse until it is killed
+func serve(c *command, args []string) int {
+	fs, cf := c.flagSet()
+	printFlows := fs.String("print-flows", "", "Write each flow as JSON line to this file before inserting it (- for stdout)")
+	printFields := fs.String("print-flows.fields", "", "Comma separated fields written by -print-flows (default all)")
+	if code, ok := parse(fs, cf, args); !ok {
+		return code
+	}
+
+	if fs.NArg() > 0 {
+		log.Errorf("Unexpected arguments %v (commands go before flags, e.g. flowhouse check-config -config.file config.yaml)", fs.Args())
+		return 2
+	}
+
+	cfg, err := cf.getConfig()
+	if err != nil {
+		log.WithError(err).Error("Unable to get config")
+		return 1
+	}
+
+	log.WithField("version", version.Get().String()).Info("Starting flowhouse")
+	version.RegisterMetric()
+
+	l := &configLoader{
+		flags: cf,
+		cfg:   cfg,
+	}
+
+	fhcfg := flowhouseConfig(cfg)
+	fhcfg.Loader = l.load
+
+	if *printFlows != "" {
+		sink, err := newFlowSink(*printFlows, *printFields)
+		if err != nil {
+			log.WithError(err).Error("Unable to create flow sink")
+			return 1
+		}
+		fhcfg.FlowSink = sink
+	}
+
+	fh, err := flowhouse.New(fhcfg)
+	if err != nil {
+		log.WithError(err).Error("Unable to create flowhouse instance")
+		return 1
+	}
+
+	for _, rtr := range cfg.Routers {
+		fh.AddAgent(rtr.Name, rtr.Site, rtr.Role, rtr.GetAddress(), rtr.RISInstances, rtr.GetVRFs())
+	}
+
+	go reloadOnSIGHUP(fh)
+	go notifySystemd(fh)
+	if interval := cfg.GetSecretsRefreshInterval(); interval > 0 {
+		go l.reloadOnSecretRotation(fh, time.Duration(interval)*time.Second)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fh.Run()
+	}()
+
+	wg.Wait()
+	return 0
+}
+
+func flowhouseConfig(cfg *config.Config) *flowhouse.Config {
+	var dictStore frontend.DictStore
+	if cfg.DictsFile != "" {
+		dictStore = config.DictsFile(cfg.DictsFile)
+	}
+
+	return &flowhouse.Config{
+		ChCfg:              cfg.Clickhouse,
+		SNMP:               cfg.SNMP,
+		RISTimeout:         time.Duration(cfg.RISTimeout) * time.Second,
+		ListenSflow:        cfg.ListenSFlow,
+		ListenIPFIX:        cfg.ListenIPFIX,
+		ListenHTTP:         cfg.ListenHTTP,
+		HTTPAuth:           cfg.HTTPAuth,
+		QueryLimits:        cfg.QueryLimits,
+		Downsampling:       cfg.Downsampling,
+		Names:              cfg.Names,
+		Geo:                cfg.Geo,
+		UI:                 cfg.UI,
+		I18n:               cfg.I18n,
+		DefaultVRF:         cfg.GetDefaultVRF(),
+		Dicts:              cfg.Dicts,
+		DictStore:          dictStore,
+		Joins:              cfg.Joins,
+		ComputedFields:     cfg.ComputedFields,
+		DisableIPAnnotator: cfg.DisableIPAnnotator,
+		DecodeTunnels:      cfg.DecodeTunnels,
+		RDNS:               cfg.RDNS,
+		Tagging:            cfg.Tagging,
+		RPKI:               cfg.RPKI,
+		DisableBogons:      cfg.DisableBogons,
+		Bogons:             cfg.Bogons,
+		ThreatIntel:        cfg.ThreatIntel,
+		Anonymization:      cfg.Anonymization,
+		Applications:       cfg.Applications,
+		Capture:            cfg.Capture,
+		Relay:              cfg.Relay,
+		RateLimits:         cfg.RateLimits,
+		IPFIXFields:        cfg.IPFIXFields,
+		RawFields:          cfg.RawFields,
+		DecodeErrorLogSize: cfg.DecodeErrorLogSize,
+		SamplingIntervals:  cfg.SamplingIntervals,
+		Timestamps:         cfg.Timestamps,
+		Alerting:           cfg.Alerting,
+		DDoS:               cfg.DDoS,
+		Anomaly:            cfg.Anomaly,
+		Forecasting:        cfg.Forecasting,
+		ScanDetection:      cfg.ScanDetection,
+		ScheduledQueries:   cfg.ScheduledQueries,
+		Writers:            cfg.InsertWriters,
+		Queue:              cfg.IngestQueue,
+		Heartbeats:         cfg.Heartbeats,
+		HostCounters:       cfg.HostCounters,
+		Discovery:          cfg.Discovery,
+		Federation:         cfg.Federation,
+	}
+}
+
+// newFlowSink creates a sink writing fields (comma separated, all if empty) to path or stdout if path is -
+func newFlowSink(path string, fields string) (*flowsink.Sink, error) {
+	var fieldNames []string
+	if fields != "" {
+		fieldNames = strings.Split(fields, ",")
+	}
+
+	w := os.Stdout
+	if path != "-" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to open file")
+		}
+		w = f
+	}
+
+	return flowsink.New(w, fieldNames)
+}
+
+// configLoader reads the config file on reload and keeps the config read last to check its secrets for rotation
+type configLoader struct {
+	flags *commonFlags
+	cfg   *config.Config
+	mu    sync.Mutex
+}
+
+func (l *configLoader) load() (*flowhouse.Config, error) {
+	cfg, err := l.flags.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	l.cfg = cfg
+	l.mu.Unlock()
+
+	return flowhouseConfig(cfg), nil
+}
+
+// reloadOnSecretRotation periodically checks the referenced secrets and reloads if any of them changed
+func (l *configLoader) reloadOnSecretRotation(fh *flowhouse.Flowhouse, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for range t.C {
+		l.mu.Lock()
+		cfg := l.cfg
+		l.mu.Unlock()
+
+		changed, err := cfg.SecretsChanged()
+		if err != nil {
+			log.WithError(err).Error("Unable to check secrets for rotation")
+			continue
+		}
+
+		if !changed {
+			continue
+		}
+
+		log.Info("Secrets rotated, reloading configuration")
+		err = fh.Reload()
+		if err != nil {
+			log.WithError(err).Error("Unable to reload configuration")
+		}
+	}
+}
+
+func reloadOnSIGHUP(fh *flowhouse.Flowhouse) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+
+	for range ch {
+		log.Info("Received SIGHUP, reloading configuration")
+		err := fh.Reload()
+		if err != nil {
+			log.WithError(err).Error("Unable to reload configuration")
+		}
+	}
+}
+
+// notifySystemd signals readiness to systemd once flowhouse is healthy and then keeps resetting the watchdog (if
+// WatchdogSec is set) as long as it stays healthy
+func notifySystemd(fh *flowhouse.Flowhouse) {
+	if !sdnotify.Enabled() {
+		return
+	}
+
+	for {
+		err := fh.Healthy()
+		if err == nil {
+			break
+		}
+
+		log.WithError(err).Warning("Not ready yet")
+		time.Sleep(time.Second)
+	}
+
+	err := sdnotify.Notify(sdnotify.Ready)
+	if err != nil {
+		log.WithError(err).Error("Unable to notify systemd")
+	}
+
+	timeout := sdnotify.WatchdogTimeout()
+	if timeout == 0 {
+		return
+	}
+
+	t := time.NewTicker(timeout / 2)
+	defer t.Stop()
+
+	for range t.C {
+		err := fh.Healthy()
+		if err != nil {
+			log.WithError(err).Error("Unhealthy, not resetting watchdog")
+			continue
+		}
+
+		err = sdnotify.Notify(sdnotify.Watchdog)
+		if err != nil {
+			log.WithError(err).Error("Unable to notify systemd")
+		}
+	}
+}
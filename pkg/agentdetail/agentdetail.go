@@ -0,0 +1,199 @@
+// Package agentdetail combines everything known about one agent, its inventory entry, traffic and export health, for
+// a per device drill-down
+package agentdetail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/decodelog"
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/bio-routing/flowhouse/pkg/heartbeats"
+	"github.com/bio-routing/flowhouse/pkg/inventory"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultWindow = 300
+	defaultTop    = 10
+
+	// gapsRange is the time range gaps of the heartbeats are reported for
+	gapsRange = 24 * time.Hour
+)
+
+// Inventory knows the agents, e.g. the agent inventory
+type Inventory interface {
+	Get(addr netip.Addr) *inventory.AgentView
+}
+
+// Traffic queries the traffic of an agent, e.g. the frontend
+type Traffic interface {
+	AgentTraffic(ctx context.Context, agent netip.Addr, start time.Time, end time.Time, top int) (*frontend.AgentTraffic, error)
+}
+
+// DecodeErrors records datagrams that failed to decode, e.g. the decode log
+type DecodeErrors interface {
+	Entries(agent *bnet.IP) []*decodelog.Entry
+}
+
+// Gaps finds gaps in the heartbeats, e.g. the heartbeat monitor
+type Gaps interface {
+	Gaps(start time.Time, end time.Time) ([]*heartbeats.Gap, error)
+}
+
+// Detail is everything known about an agent
+type Detail struct {
+	Agent   *inventory.AgentView   `json:"agent"`
+	Traffic *frontend.AgentTraffic `json:"traffic"`
+	Health  *Health                `json:"health"`
+}
+
+// Health is the export health of an agent
+type Health struct {
+	// DecodeErrors is the number of recorded datagrams of the agent that failed to decode
+	DecodeErrors    int        `json:"decode_errors"`
+	LastDecodeError string     `json:"last_decode_error,omitempty"`
+	LastDecodeTime  *time.Time `json:"last_decode_error_time,omitempty"`
+
+	// Gaps are the gaps in the heartbeats of the agent over the last 24 hours, omitted without heartbeats
+	Gaps []*heartbeats.Gap `json:"gaps,omitempty"`
+}
+
+// AgentDetail serves the details of agents
+type AgentDetail struct {
+	inventory    Inventory
+	traffic      Traffic
+	decodeErrors DecodeErrors
+	gaps         Gaps
+}
+
+// New creates an agent detail service. gaps may be nil if heartbeats are disabled.
+func New(inv Inventory, traffic Traffic, decodeErrors DecodeErrors, gaps Gaps) *AgentDetail {
+	return &AgentDetail{
+		inventory:    inv,
+		traffic:      traffic,
+		decodeErrors: decodeErrors,
+		gaps:         gaps,
+	}
+}
+
+// Get gets the details of agent with its traffic over the window before now, nil if the agent is unknown
+func (a *AgentDetail) Get(ctx context.Context, agent netip.Addr, now time.Time, window time.Duration, top int) (*Detail, error) {
+	v := a.inventory.Get(agent)
+	if v == nil {
+		return nil, nil
+	}
+
+	traffic, err := a.traffic.AgentTraffic(ctx, agent, now.Add(-window), now, top)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Detail{
+		Agent:   v,
+		Traffic: traffic,
+		Health:  a.health(agent, now),
+	}, nil
+}
+
+func (a *AgentDetail) health(agent netip.Addr, now time.Time) *Health {
+	h := &Health{}
+
+	addr := flow.AddrToBNet(agent.Unmap())
+	entries := a.decodeErrors.Entries(&addr)
+	h.DecodeErrors = len(entries)
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		h.LastDecodeError = last.Error
+		h.LastDecodeTime = &last.Time
+	}
+
+	if a.gaps == nil {
+		return h
+	}
+
+	gaps, err := a.gaps.Gaps(now.Add(-gapsRange), now)
+	if err != nil {
+		log.WithError(err).WithField("agent", agent.String()).Warning("Unable to get gaps of heartbeats")
+		return h
+	}
+
+	h.Gaps = make([]*heartbeats.Gap, 0)
+	for _, g := range gaps {
+		if g.Agent == agent.Unmap().String() {
+			h.Gaps = append(h.Gaps, g)
+		}
+	}
+
+	return h
+}
+
+// Handler handles requests for /agents/detail. It takes the agent, the window in seconds the traffic is summed up
+// over (default 300) and the number of top conversations (top, default 10).
+func (a *AgentDetail) Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	agent, window, top, err := parseParameters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d, err := a.Get(r.Context(), agent, time.Now(), window, top)
+	if err != nil {
+		log.WithError(err).WithField("agent", agent.String()).Error("Unable to get agent details")
+		http.Error(w, "Unable to get agent details", http.StatusInternalServerError)
+		return
+	}
+
+	if d == nil {
+		http.Error(w, "Unknown agent", http.StatusNotFound)
+		return
+	}
+
+	j, err := json.Marshal(d)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func parseParameters(r *http.Request) (netip.Addr, time.Duration, int, error) {
+	q := r.URL.Query()
+	agent, err := netip.ParseAddr(q.Get("agent"))
+	if err != nil {
+		return netip.Addr{}, 0, 0, fmt.Errorf("Invalid agent %q", q.Get("agent"))
+	}
+
+	window := uint64(defaultWindow)
+	if s := q.Get("window"); s != "" {
+		window, err = strconv.ParseUint(s, 10, 32)
+		if err != nil || window == 0 {
+			return netip.Addr{}, 0, 0, fmt.Errorf("Invalid window %q", s)
+		}
+	}
+
+	top := defaultTop
+	if s := q.Get("top"); s != "" {
+		top, err = strconv.Atoi(s)
+		if err != nil || top <= 0 {
+			return netip.Addr{}, 0, 0, fmt.Errorf("Invalid top %q", s)
+		}
+	}
+
+	return agent.Unmap(), time.Duration(window) * time.Second, top, nil
+}
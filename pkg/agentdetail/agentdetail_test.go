@@ -0,0 +1,147 @@
+package agentdetail
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/decodelog"
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/bio-routing/flowhouse/pkg/heartbeats"
+	"github.com/bio-routing/flowhouse/pkg/inventory"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+type mockInventory struct{}
+
+func (m *mockInventory) Get(addr netip.Addr) *inventory.AgentView {
+	if addr != netip.MustParseAddr("192.0.2.1") {
+		return nil
+	}
+
+	return &inventory.AgentView{
+		Address: "192.0.2.1",
+		Name:    "core01.pop01",
+		Status:  "active",
+	}
+}
+
+type mockTraffic struct {
+	start time.Time
+	end   time.Time
+	top   int
+}
+
+func (m *mockTraffic) AgentTraffic(ctx context.Context, agent netip.Addr, start time.Time, end time.Time, top int) (*frontend.AgentTraffic, error) {
+	m.start, m.end, m.top = start, end, top
+	return &frontend.AgentTraffic{
+		Start: start,
+		End:   end,
+		Mbps:  42,
+	}, nil
+}
+
+type mockDecodeErrors struct{}
+
+func (m *mockDecodeErrors) Entries(agent *bnet.IP) []*decodelog.Entry {
+	if *agent != bnet.IPv4FromOctets(192, 0, 2, 1) {
+		return nil
+	}
+
+	return []*decodelog.Entry{
+		{Time: time.Unix(1700000000, 0), Error: "Short datagram"},
+		{Time: time.Unix(1700000060, 0), Error: "Unknown template"},
+	}
+}
+
+type mockGaps struct{}
+
+func (m *mockGaps) Gaps(start time.Time, end time.Time) ([]*heartbeats.Gap, error) {
+	return []*heartbeats.Gap{
+		{Kind: heartbeats.GapNoTraffic, Listener: "sflow", Agent: "192.0.2.1"},
+		{Kind: heartbeats.GapNoTraffic, Listener: "sflow", Agent: "192.0.2.2"},
+		{Kind: heartbeats.GapNoHeartbeat, Listener: "sflow"},
+	}, nil
+}
+
+func TestGet(t *testing.T) {
+	now := time.Unix(1700000300, 0)
+	traffic := &mockTraffic{}
+	a := New(&mockInventory{}, traffic, &mockDecodeErrors{}, &mockGaps{})
+
+	d, err := a.Get(context.Background(), netip.MustParseAddr("192.0.2.1"), now, 5*time.Minute, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "core01.pop01", d.Agent.Name)
+	assert.Equal(t, float64(42), d.Traffic.Mbps)
+	assert.Equal(t, now.Add(-5*time.Minute), traffic.start)
+	assert.Equal(t, now, traffic.end)
+	assert.Equal(t, 3, traffic.top)
+
+	assert.Equal(t, 2, d.Health.DecodeErrors)
+	assert.Equal(t, "Unknown template", d.Health.LastDecodeError)
+	assert.Equal(t, []*heartbeats.Gap{
+		{Kind: heartbeats.GapNoTraffic, Listener: "sflow", Agent: "192.0.2.1"},
+	}, d.Health.Gaps)
+
+	d, err = a.Get(context.Background(), netip.MustParseAddr("192.0.2.9"), now, 5*time.Minute, 3)
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+
+	d, err = New(&mockInventory{}, traffic, &mockDecodeErrors{}, nil).Get(context.Background(), netip.MustParseAddr("192.0.2.1"), now, time.Minute, 3)
+	assert.NoError(t, err)
+	assert.Nil(t, d.Health.Gaps)
+}
+
+func TestHandler(t *testing.T) {
+	a := New(&mockInventory{}, &mockTraffic{}, &mockDecodeErrors{}, nil)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{
+			name:           "Known agent",
+			query:          "agent=192.0.2.1&window=60&top=5",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "IPv4-mapped agent",
+			query:          "agent=::ffff:192.0.2.1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unknown agent",
+			query:          "agent=192.0.2.9",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid agent",
+			query:          "agent=core01",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid window",
+			query:          "agent=192.0.2.1&window=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		a.Handler(w, httptest.NewRequest(http.MethodGet, "/agents/detail?"+test.query, nil))
+		assert.Equal(t, test.expectedStatus, w.Code, test.name)
+
+		if test.expectedStatus == http.StatusOK {
+			d := &Detail{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), d), test.name)
+			assert.Equal(t, "192.0.2.1", d.Agent.Address, test.name)
+		}
+	}
+}
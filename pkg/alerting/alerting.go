@@ -0,0 +1,429 @@
+// Package alerting evaluates threshold rules against the flows table and notifies about firing and resolved alerts
+package alerting
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// MetricBPS is the traffic rate in bits per second
+	MetricBPS = "bps"
+
+	// MetricPPS is the packet rate in packets per second
+	MetricPPS = "pps"
+
+	// StateInactive means the rules condition is not met
+	StateInactive = "inactive"
+
+	// StatePending means the condition is met but not yet for the configured duration
+	StatePending = "pending"
+
+	// StateFiring means the condition is met for at least the configured duration
+	StateFiring = "firing"
+
+	// StateResolved is sent to notifiers once a firing alert is no longer met
+	StateResolved = "resolved"
+
+	defaultInterval = 60
+
+	// ingestDelaySeconds accounts for aggregation and insert batching. Flows newer than this are not considered
+	// as they might not have been written yet.
+	ingestDelaySeconds = 30
+)
+
+// Querier runs queries against the flows database
+type Querier interface {
+	Query(q string) (*sql.Rows, error)
+	GetDatabaseName() string
+}
+
+// Config is the alerting configuration
+type Config struct {
+	// Interval is the time between two evaluations of all rules in seconds
+	Interval      uint64                `yaml:"interval"`
+	Rules         []*Rule               `yaml:"rules"`
+	Webhooks      []*WebhookConfig      `yaml:"webhooks"`
+	Email         []*EmailConfig        `yaml:"email"`
+	Alertmanagers []*AlertmanagerConfig `yaml:"alertmanagers"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Interval == 0 {
+		c.Interval = defaultInterval
+	}
+}
+
+// Rule is an alerting rule, e.g. "traffic to AS64500 > 5 Gbps for 5 minutes"
+type Rule struct {
+	Name string `yaml:"name"`
+
+	// Filter is an SQL condition on the flows table selecting the traffic of interest (e.g. "dst_asn = 64500").
+	// An empty filter matches all flows.
+	Filter string `yaml:"filter"`
+
+	// Metric is either bps (default) or pps
+	Metric string `yaml:"metric"`
+
+	// Operator is either > (default) or <
+	Operator  string  `yaml:"operator"`
+	Threshold float64 `yaml:"threshold"`
+
+	// Window is the time range in seconds the rate is averaged over (default: the evaluation interval)
+	Window uint64 `yaml:"window"`
+
+	// For is the time in seconds the condition has to be met before the alert fires
+	For uint64 `yaml:"for"`
+
+	// Notify lists the names of the notifiers to use. All notifiers are used if empty.
+	Notify []string `yaml:"notify"`
+}
+
+func (r *Rule) loadDefaults(interval uint64) {
+	if r.Metric == "" {
+		r.Metric = MetricBPS
+	}
+
+	if r.Operator == "" {
+		r.Operator = ">"
+	}
+
+	if r.Window == 0 {
+		r.Window = interval
+	}
+}
+
+func (r *Rule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("Rule without name")
+	}
+
+	if r.Metric != MetricBPS && r.Metric != MetricPPS {
+		return fmt.Errorf("Rule %q: unknown metric %q", r.Name, r.Metric)
+	}
+
+	if r.Operator != ">" && r.Operator != "<" {
+		return fmt.Errorf("Rule %q: unknown operator %q", r.Name, r.Operator)
+	}
+
+	return nil
+}
+
+// query returns the SQL query calculating the rules metric for the window ending at end
+func (r *Rule) query(database string, end time.Time) string {
+	to := end.Unix() - ingestDelaySeconds
+	from := to - int64(r.Window)
+
+	expr := "sum(size * samplerate) * 8"
+	if r.Metric == MetricPPS {
+		expr = "sum(packets * samplerate)"
+	}
+
+	conditions := fmt.Sprintf("timestamp >= toDateTime(%d) AND timestamp < toDateTime(%d)", from, to)
+	if r.Filter != "" {
+		conditions += fmt.Sprintf(" AND (%s)", r.Filter)
+	}
+
+	return fmt.Sprintf("SELECT toFloat64(%s) / %d FROM %s.flows WHERE %s", expr, r.Window, database, conditions)
+}
+
+func (r *Rule) conditionMet(value float64) bool {
+	if r.Operator == "<" {
+		return value < r.Threshold
+	}
+
+	return value > r.Threshold
+}
+
+// Alert is the notification about a rule changing into or out of the firing state
+type Alert struct {
+	Rule      string    `json:"rule"`
+	State     string    `json:"state"`
+	Metric    string    `json:"metric"`
+	Operator  string    `json:"operator"`
+	Threshold float64   `json:"threshold"`
+	Value     float64   `json:"value"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+}
+
+// Summary returns a human readable description of the alert
+func (a *Alert) Summary() string {
+	return fmt.Sprintf("[%s] %s: %s %s %g (current value: %g)", a.State, a.Rule, a.Metric, a.Operator, a.Threshold, a.Value)
+}
+
+// RuleStatus is the state of a rule as reported by the API
+type RuleStatus struct {
+	Name        string    `json:"name"`
+	State       string    `json:"state"`
+	Value       float64   `json:"value"`
+	ActiveSince time.Time `json:"active_since"`
+	LastEval    time.Time `json:"last_evaluation"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+type ruleState struct {
+	rule        *Rule
+	state       string
+	value       float64
+	activeSince time.Time
+	lastEval    time.Time
+	lastErr     error
+}
+
+// Notifier delivers alerts
+type Notifier interface {
+	Name() string
+	Notify(a *Alert) error
+}
+
+// Manager evaluates the alerting rules periodically
+type Manager struct {
+	cfg       *Config
+	db        Querier
+	rules     []*ruleState
+	notifiers []Notifier
+	mu        sync.RWMutex
+	stopCh    chan struct{}
+}
+
+// New creates a new alert manager and starts evaluating the rules
+func New(cfg *Config, db Querier) (*Manager, error) {
+	cfg.loadDefaults()
+
+	m := &Manager{
+		cfg:    cfg,
+		db:     db,
+		rules:  make([]*ruleState, 0, len(cfg.Rules)),
+		stopCh: make(chan struct{}),
+	}
+
+	for _, wh := range cfg.Webhooks {
+		m.notifiers = append(m.notifiers, newWebhook(wh))
+	}
+
+	for _, e := range cfg.Email {
+		m.notifiers = append(m.notifiers, newEmail(e))
+	}
+
+	for _, am := range cfg.Alertmanagers {
+		m.notifiers = append(m.notifiers, newAlertmanager(am))
+	}
+
+	for _, r := range cfg.Rules {
+		r.loadDefaults(cfg.Interval)
+		err := r.validate()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, n := range r.Notify {
+			if m.getNotifier(n) == nil {
+				return nil, fmt.Errorf("Rule %q: unknown notifier %q", r.Name, n)
+			}
+		}
+
+		m.rules = append(m.rules, &ruleState{
+			rule:  r,
+			state: StateInactive,
+		})
+	}
+
+	go m.evaluator()
+	return m, nil
+}
+
+// Stop stops evaluating rules
+func (m *Manager) Stop() {
+	close(m.stopCh)
+}
+
+func (m *Manager) getNotifier(name string) Notifier {
+	for _, n := range m.notifiers {
+		if n.Name() == name {
+			return n
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) evaluator() {
+	t := time.NewTicker(time.Duration(m.cfg.Interval) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case now := <-t.C:
+			for _, rs := range m.rules {
+				m.evaluate(rs, now)
+			}
+		}
+	}
+}
+
+func (m *Manager) evaluate(rs *ruleState, now time.Time) {
+	value, err := m.queryValue(rs.rule.query(m.db.GetDatabaseName(), now))
+	if err != nil {
+		log.WithError(err).Errorf("Unable to evaluate alerting rule %q", rs.rule.Name)
+
+		m.mu.Lock()
+		rs.lastEval = now
+		rs.lastErr = err
+		m.mu.Unlock()
+		return
+	}
+
+	a := m.update(rs, value, now)
+	if a != nil {
+		m.notify(rs.rule, a)
+	}
+}
+
+func (m *Manager) queryValue(q string) (float64, error) {
+	rows, err := m.db.Query(q)
+	if err != nil {
+		return 0, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	value := float64(0)
+	if rows.Next() {
+		err = rows.Scan(&value)
+		if err != nil {
+			return 0, errors.Wrap(err, "Scan failed")
+		}
+	}
+
+	return value, rows.Err()
+}
+
+// update moves a rule through its states and returns an alert if the rule started or stopped firing
+func (m *Manager) update(rs *ruleState, value float64, now time.Time) *Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rs.value = value
+	rs.lastEval = now
+	rs.lastErr = nil
+
+	if !rs.rule.conditionMet(value) {
+		wasFiring := rs.state == StateFiring
+		rs.state = StateInactive
+		if !wasFiring {
+			return nil
+		}
+
+		a := rs.alert(StateResolved)
+		a.EndsAt = now
+		return a
+	}
+
+	if rs.state == StateInactive {
+		rs.state = StatePending
+		rs.activeSince = now
+	}
+
+	if rs.state == StatePending && now.Sub(rs.activeSince) >= time.Duration(rs.rule.For)*time.Second {
+		rs.state = StateFiring
+		return rs.alert(StateFiring)
+	}
+
+	return nil
+}
+
+func (rs *ruleState) alert(state string) *Alert {
+	return &Alert{
+		Rule:      rs.rule.Name,
+		State:     state,
+		Metric:    rs.rule.Metric,
+		Operator:  rs.rule.Operator,
+		Threshold: rs.rule.Threshold,
+		Value:     rs.value,
+		StartsAt:  rs.activeSince,
+	}
+}
+
+func (m *Manager) notify(r *Rule, a *Alert) {
+	log.Warning(a.Summary())
+
+	for _, n := range m.notifiers {
+		if !r.notifies(n.Name()) {
+			continue
+		}
+
+		err := n.Notify(a)
+		if err != nil {
+			log.WithError(err).Errorf("Unable to send alert %q via %q", a.Rule, n.Name())
+		}
+	}
+}
+
+func (r *Rule) notifies(notifier string) bool {
+	if len(r.Notify) == 0 {
+		return true
+	}
+
+	for _, n := range r.Notify {
+		if n == notifier {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Status returns the state of all rules
+func (m *Manager) Status() []*RuleStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make([]*RuleStatus, 0, len(m.rules))
+	for _, rs := range m.rules {
+		s := &RuleStatus{
+			Name:     rs.rule.Name,
+			State:    rs.state,
+			Value:    rs.value,
+			LastEval: rs.lastEval,
+		}
+
+		if rs.state != StateInactive {
+			s.ActiveSince = rs.activeSince
+		}
+
+		if rs.lastErr != nil {
+			s.LastError = rs.lastErr.Error()
+		}
+
+		res = append(res, s)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
+
+// Handler serves the state of all rules as JSON
+func (m *Manager) Handler(w http.ResponseWriter, r *http.Request) {
+	j, err := json.Marshal(m.Status())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
@@ -0,0 +1,134 @@
+package alerting
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleQuery(t *testing.T) {
+	r := &Rule{
+		Name:   "test",
+		Filter: "dst_asn = 64500",
+	}
+	r.loadDefaults(60)
+
+	end := time.Unix(1000, 0)
+	assert.Equal(t, "SELECT toFloat64(sum(size * samplerate) * 8) / 60 FROM flowhouse.flows WHERE timestamp >= toDateTime(910) AND timestamp < toDateTime(970) AND (dst_asn = 64500)", r.query("flowhouse", end))
+
+	r.Metric = MetricPPS
+	r.Filter = ""
+	assert.Equal(t, "SELECT toFloat64(sum(packets * samplerate)) / 60 FROM flowhouse.flows WHERE timestamp >= toDateTime(910) AND timestamp < toDateTime(970)", r.query("flowhouse", end))
+}
+
+func TestUpdate(t *testing.T) {
+	r := &Rule{
+		Name:      "test",
+		Threshold: 100,
+		For:       300,
+	}
+	r.loadDefaults(60)
+
+	m := &Manager{}
+	rs := &ruleState{
+		rule:  r,
+		state: StateInactive,
+	}
+
+	start := time.Unix(1000, 0)
+	tests := []struct {
+		offset   int64
+		value    float64
+		state    string
+		expected string
+	}{
+		{offset: 0, value: 50, state: StateInactive},
+		{offset: 60, value: 150, state: StatePending},
+		{offset: 120, value: 150, state: StatePending},
+		{offset: 360, value: 150, state: StateFiring, expected: StateFiring},
+		{offset: 420, value: 150, state: StateFiring},
+		{offset: 480, value: 80, state: StateInactive, expected: StateResolved},
+		{offset: 540, value: 80, state: StateInactive},
+	}
+
+	for _, test := range tests {
+		now := start.Add(time.Duration(test.offset) * time.Second)
+		a := m.update(rs, test.value, now)
+		assert.Equal(t, test.state, rs.state, "offset %d", test.offset)
+
+		if test.expected == "" {
+			assert.Nil(t, a, "offset %d", test.offset)
+			continue
+		}
+
+		assert.Equal(t, test.expected, a.State, "offset %d", test.offset)
+		assert.Equal(t, start.Add(60*time.Second), a.StartsAt)
+		assert.Equal(t, test.value, a.Value)
+	}
+}
+
+func TestNewInvalidRule(t *testing.T) {
+	_, err := New(&Config{
+		Rules: []*Rule{
+			{
+				Name:   "test",
+				Metric: "foo",
+			},
+		},
+	}, nil)
+	assert.Error(t, err)
+
+	_, err = New(&Config{
+		Rules: []*Rule{
+			{
+				Name:   "test",
+				Notify: []string{"noc"},
+			},
+		},
+	}, nil)
+	assert.Error(t, err)
+}
+
+func TestNotifiers(t *testing.T) {
+	var received []byte
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		received, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	a := &Alert{
+		Rule:      "test",
+		State:     StateResolved,
+		Metric:    MetricBPS,
+		Operator:  ">",
+		Threshold: 100,
+		Value:     50,
+		StartsAt:  time.Unix(1000, 0).UTC(),
+		EndsAt:    time.Unix(2000, 0).UTC(),
+	}
+
+	err := newWebhook(&WebhookConfig{Name: "wh", URL: srv.URL + "/hook"}).Notify(a)
+	assert.NoError(t, err)
+	assert.Equal(t, "/hook", path)
+
+	var wa Alert
+	assert.NoError(t, json.Unmarshal(received, &wa))
+	assert.Equal(t, *a, wa)
+
+	err = newAlertmanager(&AlertmanagerConfig{Name: "am", URL: srv.URL + "/"}).Notify(a)
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/v2/alerts", path)
+
+	var ama []*alertmanagerAlert
+	assert.NoError(t, json.Unmarshal(received, &ama))
+	assert.Len(t, ama, 1)
+	assert.Equal(t, "test", ama[0].Labels["alertname"])
+	assert.Equal(t, a.EndsAt, *ama[0].EndsAt)
+}
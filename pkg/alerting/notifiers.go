@@ -0,0 +1,160 @@
+package alerting
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/httpjson"
+	"github.com/pkg/errors"
+)
+
+const notifyTimeout = 10 * time.Second
+
+// WebhookConfig configures a notifier POSTing alerts as JSON to an URL
+type WebhookConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// EmailConfig configures a notifier sending alerts via SMTP
+type EmailConfig struct {
+	Name       string   `yaml:"name"`
+	SMTPServer string   `yaml:"smtp_server"`
+	Username   string   `yaml:"username"`
+	Password   string   `yaml:"password"`
+	From       string   `yaml:"from"`
+	To         []string `yaml:"to"`
+}
+
+// AlertmanagerConfig configures a notifier pushing alerts to a Prometheus Alertmanager
+type AlertmanagerConfig struct {
+	Name string `yaml:"name"`
+
+	// URL is the Alertmanagers base URL, e.g. http://alertmanager:9093
+	URL string `yaml:"url"`
+}
+
+type webhook struct {
+	cfg    *WebhookConfig
+	client *http.Client
+}
+
+func newWebhook(cfg *WebhookConfig) *webhook {
+	return &webhook{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: notifyTimeout,
+		},
+	}
+}
+
+func (w *webhook) Name() string {
+	return w.cfg.Name
+}
+
+func (w *webhook) Notify(a *Alert) error {
+	return httpjson.Post(w.client, w.cfg.URL, a)
+}
+
+type email struct {
+	cfg *EmailConfig
+}
+
+func newEmail(cfg *EmailConfig) *email {
+	return &email{
+		cfg: cfg,
+	}
+}
+
+func (e *email) Name() string {
+	return e.cfg.Name
+}
+
+func (e *email) Notify(a *Alert) error {
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		host := strings.Split(e.cfg.SMTPServer, ":")[0]
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, host)
+	}
+
+	err := smtp.SendMail(e.cfg.SMTPServer, auth, e.cfg.From, e.cfg.To, e.message(a))
+	if err != nil {
+		return errors.Wrap(err, "Unable to send mail")
+	}
+
+	return nil
+}
+
+func (e *email) message(a *Alert) []byte {
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(buf, "Subject: %s\r\n", a.Summary())
+	fmt.Fprintf(buf, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(buf, "Rule: %s\r\n", a.Rule)
+	fmt.Fprintf(buf, "State: %s\r\n", a.State)
+	fmt.Fprintf(buf, "Condition: %s %s %g\r\n", a.Metric, a.Operator, a.Threshold)
+	fmt.Fprintf(buf, "Value: %g\r\n", a.Value)
+	fmt.Fprintf(buf, "Active since: %s\r\n", a.StartsAt.Format(time.RFC3339))
+	if !a.EndsAt.IsZero() {
+		fmt.Fprintf(buf, "Resolved at: %s\r\n", a.EndsAt.Format(time.RFC3339))
+	}
+
+	return buf.Bytes()
+}
+
+type alertmanager struct {
+	cfg    *AlertmanagerConfig
+	client *http.Client
+}
+
+func newAlertmanager(cfg *AlertmanagerConfig) *alertmanager {
+	return &alertmanager{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: notifyTimeout,
+		},
+	}
+}
+
+func (am *alertmanager) Name() string {
+	return am.cfg.Name
+}
+
+// alertmanagerAlert is an alert as expected by Alertmanagers v2 API
+type alertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      *time.Time        `json:"endsAt,omitempty"`
+}
+
+func newAlertmanagerAlert(a *Alert) *alertmanagerAlert {
+	ama := &alertmanagerAlert{
+		Labels: map[string]string{
+			"alertname": a.Rule,
+			"source":    "flowhouse",
+			"metric":    a.Metric,
+		},
+		Annotations: map[string]string{
+			"summary": a.Summary(),
+			"value":   fmt.Sprintf("%g", a.Value),
+		},
+		StartsAt: a.StartsAt,
+	}
+
+	if a.State == StateResolved {
+		ama.EndsAt = &a.EndsAt
+	}
+
+	return ama
+}
+
+func (am *alertmanager) Notify(a *Alert) error {
+	url := strings.TrimSuffix(am.cfg.URL, "/") + "/api/v2/alerts"
+	return httpjson.Post(am.client, url, []*alertmanagerAlert{newAlertmanagerAlert(a)})
+}
@@ -0,0 +1,231 @@
+// Package annotations keeps events like maintenances that explain traffic changes in query results
+package annotations
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/annotation"
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Store persists annotations
+type Store interface {
+	InsertAnnotations(annotations []*annotation.Annotation) error
+	DeleteAnnotations(annotations []*annotation.Annotation) error
+	GetAnnotations() ([]*annotation.Annotation, error)
+}
+
+// Manager keeps track of all annotations
+type Manager struct {
+	store       Store
+	annotations map[uint64]*annotation.Annotation
+	lastID      uint64
+	mu          sync.RWMutex
+}
+
+// New creates a new annotation manager and loads previously stored annotations
+func New(store Store) (*Manager, error) {
+	m := &Manager{
+		store:       store,
+		annotations: make(map[uint64]*annotation.Annotation),
+	}
+
+	annotations, err := store.GetAnnotations()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load annotations")
+	}
+
+	for _, a := range annotations {
+		m.annotations[a.ID] = a
+		if a.ID > m.lastID {
+			m.lastID = a.ID
+		}
+	}
+
+	return m, nil
+}
+
+// Add validates and stores a new annotation. The ID is assigned by the manager.
+func (m *Manager) Add(a *annotation.Annotation) error {
+	if a.Title == "" {
+		return fmt.Errorf("Title is missing")
+	}
+
+	if a.Start.IsZero() || a.End.IsZero() {
+		return fmt.Errorf("Start or end is missing")
+	}
+
+	if a.End.Before(a.Start) {
+		return fmt.Errorf("End is before start")
+	}
+
+	agents := make([]string, 0, len(a.Agents))
+	for _, x := range a.Agents {
+		addr := net.ParseIP(x)
+		if addr == nil {
+			return fmt.Errorf("Invalid agent %q", x)
+		}
+
+		agents = append(agents, addr.String())
+	}
+	a.Agents = agents
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a.ID = m.lastID + 1
+	err := m.store.InsertAnnotations([]*annotation.Annotation{a})
+	if err != nil {
+		return errors.Wrap(err, "Unable to store annotation")
+	}
+
+	m.lastID = a.ID
+	m.annotations[a.ID] = a
+	return nil
+}
+
+// Delete deletes an annotation
+func (m *Manager) Delete(id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, exists := m.annotations[id]
+	if !exists {
+		return fmt.Errorf("Annotation %d not found", id)
+	}
+
+	err := m.store.DeleteAnnotations([]*annotation.Annotation{a})
+	if err != nil {
+		return errors.Wrap(err, "Unable to delete annotation")
+	}
+
+	delete(m.annotations, id)
+	return nil
+}
+
+// Get returns all annotations overlapping with the time range that apply to any of the agents (all if empty), ordered
+// by start
+func (m *Manager) Get(start time.Time, end time.Time, agents []string) []*annotation.Annotation {
+	normalized := make([]string, 0, len(agents))
+	for _, x := range agents {
+		if addr := net.ParseIP(x); addr != nil {
+			x = addr.String()
+		}
+		normalized = append(normalized, x)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make([]*annotation.Annotation, 0)
+	for _, a := range m.annotations {
+		if !a.Overlaps(start, end) || !a.AppliesTo(normalized) {
+			continue
+		}
+
+		res = append(res, a)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].Start.Equal(res[j].Start) {
+			return res[i].Start.Before(res[j].Start)
+		}
+
+		return res[i].ID < res[j].ID
+	})
+
+	return res
+}
+
+// Handler handles requests for /annotations. GET lists annotations (optionally filtered by start and end as unix
+// timestamps and agent), POST creates an annotation and DELETE deletes the annotation given by id.
+func (m *Manager) Handler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		start, end, err := parseRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, m.Get(start, end, r.URL.Query()["agent"]))
+	case http.MethodPost:
+		a := &annotation.Annotation{}
+		err := json.NewDecoder(r.Body).Decode(a)
+		if err != nil {
+			http.Error(w, "Unable to decode annotation", http.StatusBadRequest)
+			return
+		}
+
+		err = m.Add(a)
+		if err != nil {
+			log.WithError(err).Error("Unable to add annotation")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, a)
+	case http.MethodDelete:
+		id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid id", http.StatusBadRequest)
+			return
+		}
+
+		err = m.Delete(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func parseRange(r *http.Request) (time.Time, time.Time, error) {
+	start, end := time.Unix(0, 0), time.Unix(math.MaxInt32, 0)
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{
+		{name: "start", t: &start},
+		{name: "end", t: &end},
+	} {
+		s := r.URL.Query().Get(p.name)
+		if s == "" {
+			continue
+		}
+
+		ts, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return start, end, fmt.Errorf("Invalid %s %q", p.name, s)
+		}
+		*p.t = time.Unix(ts, 0)
+	}
+
+	return start, end, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	j, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(j)
+}
@@ -0,0 +1,119 @@
+package annotations
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/annotation"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockStore struct {
+	annotations []*annotation.Annotation
+	deleted     []*annotation.Annotation
+}
+
+func (m *mockStore) InsertAnnotations(annotations []*annotation.Annotation) error {
+	m.annotations = append(m.annotations, annotations...)
+	return nil
+}
+
+func (m *mockStore) DeleteAnnotations(annotations []*annotation.Annotation) error {
+	m.deleted = append(m.deleted, annotations...)
+	return nil
+}
+
+func (m *mockStore) GetAnnotations() ([]*annotation.Annotation, error) {
+	return m.annotations, nil
+}
+
+func TestManager(t *testing.T) {
+	ts := func(s int64) time.Time {
+		return time.Unix(s, 0)
+	}
+
+	store := &mockStore{
+		annotations: []*annotation.Annotation{
+			{ID: 7, Title: "Fiber cut", Start: ts(1000), End: ts(2000)},
+		},
+	}
+
+	m, err := New(store)
+	assert.NoError(t, err)
+
+	a := &annotation.Annotation{
+		Title:  "Maintenance on edge2",
+		Start:  ts(1500),
+		End:    ts(3000),
+		Agents: []string{"::ffff:192.0.2.2"},
+	}
+	assert.NoError(t, m.Add(a))
+	assert.Equal(t, uint64(8), a.ID)
+	assert.Equal(t, []string{"192.0.2.2"}, a.Agents)
+	assert.Len(t, store.annotations, 2)
+
+	tests := []struct {
+		name     string
+		start    int64
+		end      int64
+		agents   []string
+		expected []uint64
+	}{
+		{name: "All", start: 0, end: 5000, expected: []uint64{7, 8}},
+		{name: "Before", start: 0, end: 999, expected: []uint64{}},
+		{name: "Overlapping end", start: 2500, end: 5000, expected: []uint64{8}},
+		{name: "Other agent", start: 0, end: 5000, agents: []string{"192.0.2.1"}, expected: []uint64{7}},
+		{name: "Agent", start: 0, end: 5000, agents: []string{"::ffff:192.0.2.2"}, expected: []uint64{7, 8}},
+	}
+
+	for _, test := range tests {
+		ids := make([]uint64, 0)
+		for _, x := range m.Get(ts(test.start), ts(test.end), test.agents) {
+			ids = append(ids, x.ID)
+		}
+
+		assert.Equal(t, test.expected, ids, test.name)
+	}
+
+	assert.NoError(t, m.Delete(7))
+	assert.Len(t, store.deleted, 1)
+	assert.Error(t, m.Delete(7))
+	assert.Len(t, m.Get(ts(0), ts(5000), nil), 1)
+}
+
+func TestAddInvalid(t *testing.T) {
+	m, err := New(&mockStore{})
+	assert.NoError(t, err)
+
+	start := time.Unix(1000, 0)
+	for _, a := range []*annotation.Annotation{
+		{Start: start, End: start},
+		{Title: "x", End: start},
+		{Title: "x", Start: start, End: start.Add(-time.Second)},
+		{Title: "x", Start: start, End: start, Agents: []string{"edge2"}},
+	} {
+		assert.Error(t, m.Add(a))
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m, err := New(&mockStore{})
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	m.Handler(rec, httptest.NewRequest(http.MethodPost, "/annotations", bytes.NewBufferString(
+		`{"title": "Maintenance on edge2", "start": "2021-03-01T00:00:00Z", "end": "2021-03-01T02:00:00Z"}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = httptest.NewRecorder()
+	m.Handler(rec, httptest.NewRequest(http.MethodGet, "/annotations?start=1614556800&end=1614560400", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"title":"Maintenance on edge2"`)
+
+	rec = httptest.NewRecorder()
+	m.Handler(rec, httptest.NewRequest(http.MethodDelete, "/annotations?id=1", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
@@ -0,0 +1,302 @@
+// Package anomaly learns the normal traffic rate per agent and interface and flags significant deviations
+package anomaly
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DirectionIn is traffic entering the agent through the interface
+	DirectionIn = "in"
+
+	// DirectionOut is traffic leaving the agent through the interface
+	DirectionOut = "out"
+
+	defaultWindow       = 60
+	defaultAlpha        = 0.05
+	defaultThreshold    = 3
+	defaultWarmup       = 30
+	defaultMaxAnomalies = 1000
+
+	// slotsPerWeek is the number of seasonal baselines (one per hour of the week)
+	slotsPerWeek = 7 * 24
+)
+
+// Config is the anomaly detectors configuration
+type Config struct {
+	// Window is the interval in seconds traffic is summed up over before it is compared to the baseline
+	Window uint64 `yaml:"window"`
+
+	// Alpha is the smoothing factor of the exponentially weighted moving average (0 < alpha <= 1)
+	Alpha float64 `yaml:"alpha"`
+
+	// Threshold is the deviation from the baseline in standard deviations considered an anomaly
+	Threshold float64 `yaml:"threshold"`
+
+	// Warmup is the number of samples a baseline needs before deviations are reported
+	Warmup uint64 `yaml:"warmup"`
+
+	// Seasonal keeps a separate baseline per hour of the week
+	Seasonal bool `yaml:"seasonal"`
+
+	// MaxAnomalies is the number of anomalies kept for the API
+	MaxAnomalies int `yaml:"max_anomalies"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Window == 0 {
+		c.Window = defaultWindow
+	}
+
+	if c.Alpha == 0 {
+		c.Alpha = defaultAlpha
+	}
+
+	if c.Threshold == 0 {
+		c.Threshold = defaultThreshold
+	}
+
+	if c.Warmup == 0 {
+		c.Warmup = defaultWarmup
+	}
+
+	if c.MaxAnomalies == 0 {
+		c.MaxAnomalies = defaultMaxAnomalies
+	}
+}
+
+// Anomaly is a significant deviation of the traffic rate of an interface from its baseline
+type Anomaly struct {
+	Time      time.Time `json:"time"`
+	Agent     string    `json:"agent"`
+	Interface string    `json:"interface"`
+	Direction string    `json:"direction"`
+	BPS       float64   `json:"bps"`
+	Baseline  float64   `json:"baseline_bps"`
+	StdDev    float64   `json:"stddev_bps"`
+	Score     float64   `json:"score"`
+}
+
+type seriesKey struct {
+	agent     netip.Addr
+	intf      string
+	direction string
+}
+
+// baseline is an exponentially weighted moving average and variance
+type baseline struct {
+	mean     float64
+	variance float64
+	samples  uint64
+}
+
+func (b *baseline) update(v float64, alpha float64) {
+	if b.samples == 0 {
+		b.mean = v
+		b.samples++
+		return
+	}
+
+	diff := v - b.mean
+	incr := alpha * diff
+	b.mean += incr
+	b.variance = (1 - alpha) * (b.variance + diff*incr)
+	b.samples++
+}
+
+type series struct {
+	baselines []baseline
+}
+
+// Detector compares the traffic per agent and interface with its learned baseline
+type Detector struct {
+	cfg         *Config
+	windowStart int64
+	bytes       map[seriesKey]uint64
+	series      map[seriesKey]*series
+	anomalies   []*Anomaly
+	mu          sync.RWMutex
+}
+
+// New creates a new anomaly detector
+func New(cfg *Config) *Detector {
+	cfg.loadDefaults()
+
+	return &Detector{
+		cfg:    cfg,
+		bytes:  make(map[seriesKey]uint64),
+		series: make(map[seriesKey]*series),
+	}
+}
+
+// Observe adds flows to the current window
+func (d *Detector) Observe(flows []*flow.Flow) {
+	d.observe(flows, time.Now())
+}
+
+func (d *Detector) observe(flows []*flow.Flow, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	windowStart := now.Unix() - now.Unix()%int64(d.cfg.Window)
+	if windowStart > d.windowStart {
+		if d.windowStart != 0 {
+			d.evaluate(time.Unix(d.windowStart, 0))
+		}
+
+		d.windowStart = windowStart
+		d.bytes = make(map[seriesKey]uint64)
+	}
+
+	for _, fl := range flows {
+		size := fl.Size * fl.Samplerate
+		if fl.IntIn != "" {
+			d.bytes[seriesKey{agent: fl.Agent, intf: fl.IntIn, direction: DirectionIn}] += size
+		}
+
+		if fl.IntOut != "" {
+			d.bytes[seriesKey{agent: fl.Agent, intf: fl.IntOut, direction: DirectionOut}] += size
+		}
+	}
+}
+
+func (d *Detector) slot(ts time.Time) int {
+	if !d.cfg.Seasonal {
+		return 0
+	}
+
+	ts = ts.UTC()
+	return int(ts.Weekday())*24 + ts.Hour()
+}
+
+// evaluate compares the finished window with the baselines and updates them. It has to be called with d.mu held.
+func (d *Detector) evaluate(windowStart time.Time) {
+	slot := d.slot(windowStart)
+
+	// series without traffic in this window are updated with 0 so drops are detected as well
+	for k := range d.series {
+		if _, exists := d.bytes[k]; !exists {
+			d.bytes[k] = 0
+		}
+	}
+
+	for k, bytes := range d.bytes {
+		s, exists := d.series[k]
+		if !exists {
+			n := 1
+			if d.cfg.Seasonal {
+				n = slotsPerWeek
+			}
+
+			s = &series{
+				baselines: make([]baseline, n),
+			}
+			d.series[k] = s
+		}
+
+		bps := float64(bytes) * 8 / float64(d.cfg.Window)
+		b := &s.baselines[slot]
+		if b.samples >= d.cfg.Warmup {
+			stddev := math.Sqrt(b.variance)
+			if stddev > 0 {
+				score := (bps - b.mean) / stddev
+				if math.Abs(score) > d.cfg.Threshold {
+					d.addAnomaly(&Anomaly{
+						Time:      windowStart,
+						Agent:     k.agent.String(),
+						Interface: k.intf,
+						Direction: k.direction,
+						BPS:       bps,
+						Baseline:  b.mean,
+						StdDev:    stddev,
+						Score:     score,
+					})
+				}
+			}
+		}
+
+		b.update(bps, d.cfg.Alpha)
+	}
+}
+
+func (d *Detector) addAnomaly(a *Anomaly) {
+	log.WithFields(log.Fields{
+		"agent":     a.Agent,
+		"interface": a.Interface,
+		"direction": a.Direction,
+		"bps":       a.BPS,
+		"baseline":  a.Baseline,
+		"score":     a.Score,
+	}).Info("Traffic anomaly detected")
+
+	d.anomalies = append(d.anomalies, a)
+	if len(d.anomalies) > d.cfg.MaxAnomalies {
+		d.anomalies = d.anomalies[len(d.anomalies)-d.cfg.MaxAnomalies:]
+	}
+}
+
+// Anomalies returns the anomalies detected between start and end (both inclusive, zero means unbounded)
+func (d *Detector) Anomalies(start time.Time, end time.Time) []*Anomaly {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	res := make([]*Anomaly, 0)
+	for _, a := range d.anomalies {
+		if !start.IsZero() && a.Time.Before(start) {
+			continue
+		}
+
+		if !end.IsZero() && a.Time.After(end) {
+			continue
+		}
+
+		c := *a
+		res = append(res, &c)
+	}
+
+	return res
+}
+
+// Handler serves the detected anomalies as JSON. The time range can be restricted with `start` and `end` (unix time).
+func (d *Detector) Handler(w http.ResponseWriter, r *http.Request) {
+	var start, end time.Time
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{
+		{name: "start", t: &start},
+		{name: "end", t: &end},
+	} {
+		v := r.URL.Query().Get(p.name)
+		if v == "" {
+			continue
+		}
+
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid "+p.name, http.StatusBadRequest)
+			return
+		}
+
+		*p.t = time.Unix(ts, 0)
+	}
+
+	j, err := json.Marshal(d.Anomalies(start, end))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
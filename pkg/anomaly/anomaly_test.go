@@ -0,0 +1,83 @@
+package anomaly
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseline(t *testing.T) {
+	b := &baseline{}
+	for i := 0; i < 100; i++ {
+		b.update(100, 0.1)
+	}
+
+	assert.Equal(t, float64(100), b.mean)
+	assert.Equal(t, float64(0), b.variance)
+	assert.Equal(t, uint64(100), b.samples)
+}
+
+func TestDetector(t *testing.T) {
+	d := New(&Config{
+		Window: 10,
+		Alpha:  0.1,
+		Warmup: 5,
+	})
+
+	agent := netip.AddrFrom4([4]byte{192, 0, 2, 1})
+	traffic := func(size uint64) []*flow.Flow {
+		return []*flow.Flow{
+			{
+				Agent:      agent,
+				IntIn:      "et-0/0/0",
+				IntOut:     "et-0/0/1",
+				Size:       size,
+				Samplerate: 1,
+			},
+		}
+	}
+
+	start := time.Unix(1000, 0)
+	now := start
+	for i := 0; i < 20; i++ {
+		// 1000 +/- 100 bytes per window
+		size := uint64(1000)
+		if i%2 == 0 {
+			size = 1100
+		} else {
+			size = 900
+		}
+
+		d.observe(traffic(size), now)
+		now = now.Add(10 * time.Second)
+	}
+	assert.Len(t, d.Anomalies(time.Time{}, time.Time{}), 0)
+
+	spike := now
+	d.observe(traffic(10000), now)
+	now = now.Add(10 * time.Second)
+	d.observe(nil, now)
+
+	res := d.Anomalies(time.Time{}, time.Time{})
+	assert.Len(t, res, 2)
+	for _, a := range res {
+		assert.Equal(t, spike, a.Time)
+		assert.Equal(t, "192.0.2.1", a.Agent)
+		assert.Equal(t, float64(8000), a.BPS)
+		assert.True(t, a.Score > 3)
+	}
+
+	assert.Len(t, d.Anomalies(spike.Add(time.Second), time.Time{}), 0)
+}
+
+func TestSlot(t *testing.T) {
+	d := New(&Config{Seasonal: true})
+	ts := time.Date(2021, 3, 2, 13, 30, 0, 0, time.UTC) // Tuesday
+	assert.Equal(t, 2*24+13, d.slot(ts))
+
+	d = New(&Config{})
+	assert.Equal(t, 0, d.slot(ts))
+}
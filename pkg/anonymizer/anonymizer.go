@@ -0,0 +1,145 @@
+package anonymizer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+)
+
+const (
+	// ModeTruncate zeroes the host part of addresses and the device part of MAC addresses
+	ModeTruncate = "truncate"
+
+	// ModePseudonymize replaces addresses and MAC addresses by a keyed hash of them
+	ModePseudonymize = "pseudonymize"
+
+	// ouiMask keeps the OUI of the 48 bit MAC addresses of flows, which names the vendor but not the device
+	ouiMask = 0xffffff000000
+	macMask = 0xffffffffffff
+
+	defaultIPv4PrefixLength = 24
+	defaultIPv6PrefixLength = 48
+)
+
+// Config is the anonymizers configuration
+type Config struct {
+	Mode             string `yaml:"mode"`
+	IPv4PrefixLength uint8  `yaml:"ipv4_prefix_length"`
+	IPv6PrefixLength uint8  `yaml:"ipv6_prefix_length"`
+	Key              string `yaml:"key"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Mode == "" {
+		c.Mode = ModeTruncate
+	}
+
+	if c.IPv4PrefixLength == 0 {
+		c.IPv4PrefixLength = defaultIPv4PrefixLength
+	}
+
+	if c.IPv6PrefixLength == 0 {
+		c.IPv6PrefixLength = defaultIPv6PrefixLength
+	}
+}
+
+// Anonymizer anonymizes the end host addresses and MAC addresses of flows
+type Anonymizer struct {
+	cfg *Config
+	key []byte
+}
+
+// New creates a new anonymizer
+func New(cfg *Config) (*Anonymizer, error) {
+	cfg.loadDefaults()
+
+	switch cfg.Mode {
+	case ModeTruncate:
+		if cfg.IPv4PrefixLength > 32 || cfg.IPv6PrefixLength > 128 {
+			return nil, fmt.Errorf("Invalid prefix length")
+		}
+	case ModePseudonymize:
+		if cfg.Key == "" {
+			return nil, fmt.Errorf("Mode %q requires a key", ModePseudonymize)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown mode %q", cfg.Mode)
+	}
+
+	return &Anonymizer{
+		cfg: cfg,
+		key: []byte(cfg.Key),
+	}, nil
+}
+
+// Annotate anonymizes source and destination addresses (outer and inner) and MAC addresses of a flow.
+// Host names are removed as they would reveal the addresses.
+func (a *Anonymizer) Annotate(fl *flow.Flow) {
+	fl.SrcAddr = a.Anonymize(fl.SrcAddr)
+	fl.DstAddr = a.Anonymize(fl.DstAddr)
+	fl.InnerSrcAddr = a.Anonymize(fl.InnerSrcAddr)
+	fl.InnerDstAddr = a.Anonymize(fl.InnerDstAddr)
+	fl.SrcMAC = a.AnonymizeMAC(fl.SrcMAC)
+	fl.DstMAC = a.AnonymizeMAC(fl.DstMAC)
+	fl.SrcHostname = ""
+	fl.DstHostname = ""
+}
+
+// AnonymizeMAC returns the anonymized form of the MAC address mac (in the lower 48 bits)
+func (a *Anonymizer) AnonymizeMAC(mac uint64) uint64 {
+	// unset MAC addresses (e.g. of IPFIX flows without them) are left alone
+	if mac == 0 {
+		return mac
+	}
+
+	if a.cfg.Mode == ModePseudonymize {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], mac)
+
+		h := hmac.New(sha256.New, a.key)
+		h.Write(b[2:])
+		return binary.BigEndian.Uint64(h.Sum(nil)[:8]) & macMask
+	}
+
+	return mac & ouiMask
+}
+
+// Anonymize returns the anonymized form of addr
+func (a *Anonymizer) Anonymize(addr netip.Addr) netip.Addr {
+	// unset addresses (e.g. inner addresses of non tunneled flows) are left alone
+	if !addr.IsValid() {
+		return addr
+	}
+
+	if a.cfg.Mode == ModePseudonymize {
+		return a.pseudonymize(addr)
+	}
+
+	return a.truncate(addr)
+}
+
+func (a *Anonymizer) truncate(addr netip.Addr) netip.Addr {
+	pfxLen := a.cfg.IPv6PrefixLength
+	if addr.Is4() {
+		pfxLen = a.cfg.IPv4PrefixLength
+	}
+
+	pfx, _ := addr.Prefix(int(pfxLen))
+	return pfx.Addr()
+}
+
+func (a *Anonymizer) pseudonymize(addr netip.Addr) netip.Addr {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write(addr.AsSlice())
+	sum := mac.Sum(nil)
+
+	if addr.Is4() {
+		return netip.AddrFrom4([4]byte(sum[:4]))
+	}
+
+	return netip.AddrFrom16([16]byte(sum[:16]))
+}
@@ -0,0 +1,142 @@
+package anonymizer
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		addr     string
+		expected string
+	}{
+		{
+			name:     "IPv4 default",
+			cfg:      &Config{},
+			addr:     "192.0.2.123",
+			expected: "192.0.2.0",
+		},
+		{
+			name:     "IPv6 default",
+			cfg:      &Config{},
+			addr:     "2001:db8:1:2::1",
+			expected: "2001:db8:1::",
+		},
+		{
+			name: "IPv4 custom length",
+			cfg: &Config{
+				IPv4PrefixLength: 16,
+			},
+			addr:     "192.0.2.123",
+			expected: "192.0.0.0",
+		},
+	}
+
+	for _, test := range tests {
+		a, err := New(test.cfg)
+		if err != nil {
+			t.Fatalf("Unable to create anonymizer for %s: %v", test.name, err)
+		}
+
+		assert.Equal(t, mustIP(t, test.expected), a.Anonymize(mustIP(t, test.addr)), test.name)
+	}
+}
+
+func TestPseudonymize(t *testing.T) {
+	a, err := New(&Config{
+		Mode: ModePseudonymize,
+		Key:  "secret",
+	})
+	if err != nil {
+		t.Fatalf("Unable to create anonymizer: %v", err)
+	}
+
+	b, err := New(&Config{
+		Mode: ModePseudonymize,
+		Key:  "other-secret",
+	})
+	if err != nil {
+		t.Fatalf("Unable to create anonymizer: %v", err)
+	}
+
+	v4 := mustIP(t, "192.0.2.1")
+	p := a.Anonymize(v4)
+	assert.True(t, p.Is4())
+	assert.NotEqual(t, v4, p)
+	assert.Equal(t, p, a.Anonymize(v4), "pseudonyms have to be stable")
+	assert.NotEqual(t, p, b.Anonymize(v4), "pseudonyms have to depend on the key")
+
+	v6 := mustIP(t, "2001:db8::1")
+	p = a.Anonymize(v6)
+	assert.False(t, p.Is4())
+	assert.NotEqual(t, v6, p)
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New(&Config{Mode: ModePseudonymize})
+	assert.Error(t, err)
+
+	_, err = New(&Config{Mode: "foo"})
+	assert.Error(t, err)
+}
+
+func TestAnnotate(t *testing.T) {
+	a, err := New(&Config{})
+	if err != nil {
+		t.Fatalf("Unable to create anonymizer: %v", err)
+	}
+
+	fl := &flow.Flow{
+		SrcAddr:     mustIP(t, "192.0.2.1"),
+		DstAddr:     mustIP(t, "198.51.100.200"),
+		SrcHostname: "host.example.com",
+	}
+	a.Annotate(fl)
+
+	assert.Equal(t, mustIP(t, "192.0.2.0"), fl.SrcAddr)
+	assert.Equal(t, mustIP(t, "198.51.100.0"), fl.DstAddr)
+	assert.Equal(t, netip.Addr{}, fl.InnerSrcAddr)
+	assert.Equal(t, "", fl.SrcHostname)
+}
+
+func mustIP(t *testing.T, s string) netip.Addr {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		t.Fatalf("Unable to parse address %q: %v", s, err)
+	}
+
+	return addr
+}
+
+func TestAnonymizeMAC(t *testing.T) {
+	truncate, err := New(&Config{})
+	if err != nil {
+		t.Fatalf("Unable to create anonymizer: %v", err)
+	}
+
+	pseudonymize, err := New(&Config{Mode: ModePseudonymize, Key: "secret"})
+	if err != nil {
+		t.Fatalf("Unable to create anonymizer: %v", err)
+	}
+
+	mac := uint64(0x204e71041cb9)
+	assert.Equal(t, uint64(0x204e71000000), truncate.AnonymizeMAC(mac), "OUI is kept")
+	assert.Equal(t, uint64(0), truncate.AnonymizeMAC(0), "unset")
+
+	p := pseudonymize.AnonymizeMAC(mac)
+	assert.NotEqual(t, mac, p)
+	assert.Zero(t, p>>48, "48 bit")
+	assert.Equal(t, p, pseudonymize.AnonymizeMAC(mac), "stable")
+	assert.NotEqual(t, p, pseudonymize.AnonymizeMAC(mac+1))
+	assert.Equal(t, uint64(0), pseudonymize.AnonymizeMAC(0), "unset")
+
+	fl := &flow.Flow{SrcMAC: mac, DstMAC: 0x80711f7f0294}
+	truncate.Annotate(fl)
+	assert.Equal(t, uint64(0x204e71000000), fl.SrcMAC)
+	assert.Equal(t, uint64(0x80711f000000), fl.DstMAC)
+}
@@ -0,0 +1,85 @@
+// Package appid names the applications identified by exporters, e.g. Cisco NBAR2 or Juniper AppID. Exporters give
+// applications as an application ID (RFC 6759) of a classification engine ID and a selector ID, some add the name of
+// the application.
+package appid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+)
+
+// maxSelectorLength is the longest selector ID formatted as number, longer ones are formatted as hex
+const maxSelectorLength = 8
+
+// Config maps application IDs to names
+type Config struct {
+	// Names maps application IDs given as <engine>:<selector>, e.g. 13:453, to application names
+	Names map[string]string `yaml:"names"`
+}
+
+// Mapper replaces the application IDs of flows by their names
+type Mapper struct {
+	names map[string]string
+}
+
+// New creates a new mapper
+func New(cfg *Config) (*Mapper, error) {
+	for id, name := range cfg.Names {
+		if !isID(id) {
+			return nil, fmt.Errorf("Invalid application ID %q", id)
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("Application ID %q has no name", id)
+		}
+	}
+
+	return &Mapper{
+		names: cfg.Names,
+	}, nil
+}
+
+// Annotate replaces the application ID of a flow by its name. Flows naming their application already are kept.
+func (m *Mapper) Annotate(fl *flow.Flow) {
+	if name, exists := m.names[fl.Application]; exists {
+		fl.Application = name
+	}
+}
+
+// FormatID formats an application ID in network byte order as <engine>:<selector>
+func FormatID(id []byte) string {
+	if len(id) == 0 {
+		return ""
+	}
+
+	engine := strconv.Itoa(int(id[0]))
+	selector := id[1:]
+	if len(selector) > maxSelectorLength {
+		return fmt.Sprintf("%s:%x", engine, selector)
+	}
+
+	s := uint64(0)
+	for _, b := range selector {
+		s = s<<8 | uint64(b)
+	}
+
+	return engine + ":" + strconv.FormatUint(s, 10)
+}
+
+func isID(id string) bool {
+	parts := strings.Split(id, ":")
+	if len(parts) != 2 {
+		return false
+	}
+
+	_, err := strconv.ParseUint(parts[0], 10, 8)
+	if err != nil {
+		return false
+	}
+
+	_, err = strconv.ParseUint(parts[1], 10, 64)
+	return err == nil
+}
@@ -0,0 +1,97 @@
+package appid
+
+import (
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatID(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       []byte
+		expected string
+	}{
+		{
+			name:     "NBAR2",
+			id:       []byte{13, 0, 1, 0xc5},
+			expected: "13:453",
+		},
+		{
+			name:     "IANA L4 port",
+			id:       []byte{3, 0x01, 0xbb},
+			expected: "3:443",
+		},
+		{
+			name:     "Engine only",
+			id:       []byte{20},
+			expected: "20:0",
+		},
+		{
+			name:     "Long selector",
+			id:       []byte{20, 0, 0, 0, 9, 0, 0, 0, 0, 1},
+			expected: "20:000000090000000001",
+		},
+		{
+			name:     "Empty",
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, FormatID(test.id), test.name)
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&Config{Names: map[string]string{"13:453": "https"}})
+	assert.NoError(t, err)
+
+	_, err = New(&Config{Names: map[string]string{"https": "https"}})
+	assert.Error(t, err)
+
+	_, err = New(&Config{Names: map[string]string{"300:1": "https"}})
+	assert.Error(t, err)
+
+	_, err = New(&Config{Names: map[string]string{"13:453": ""}})
+	assert.Error(t, err)
+}
+
+func TestAnnotate(t *testing.T) {
+	m, err := New(&Config{Names: map[string]string{"13:453": "https"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		name        string
+		application string
+		expected    string
+	}{
+		{
+			name:        "Mapped ID",
+			application: "13:453",
+			expected:    "https",
+		},
+		{
+			name:        "Unmapped ID",
+			application: "13:80",
+			expected:    "13:80",
+		},
+		{
+			name:        "Exported name",
+			application: "ssl",
+			expected:    "ssl",
+		},
+		{
+			name: "No application",
+		},
+	}
+
+	for _, test := range tests {
+		fl := &flow.Flow{Application: test.application}
+		m.Annotate(fl)
+		assert.Equal(t, test.expected, fl.Application, test.name)
+	}
+}
@@ -0,0 +1,330 @@
+// Package billing calculates monthly 95th percentile traffic rates for invoicing
+package billing
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ByInterface bills per agent interface. In is traffic received on the interface, out is traffic sent through it.
+	ByInterface = "interface"
+
+	// ByCustomer bills per customer tag
+	ByCustomer = "customer"
+
+	// ByService bills per service tag
+	ByService = "service"
+
+	// ByTrafficClass bills per traffic class tag
+	ByTrafficClass = "traffic_class"
+
+	// BucketSeconds is the size of the buckets the percentile is calculated over
+	BucketSeconds = 300
+
+	// MonthFormat is the format of the month parameter
+	MonthFormat = "2006-01"
+)
+
+// Querier runs queries against the flows database
+type Querier interface {
+	Query(q string) (*sql.Rows, error)
+	GetDatabaseName() string
+}
+
+// Entry is the billing result of one interface or tag
+type Entry struct {
+	Agent     string `json:"agent,omitempty"`
+	Interface string `json:"interface,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+
+	// In95Mbps and Out95Mbps are the 95th percentiles per direction (interfaces only)
+	In95Mbps  float64 `json:"in_95th_mbps"`
+	Out95Mbps float64 `json:"out_95th_mbps"`
+
+	// Total95Mbps is the 95th percentile of the sum of both directions
+	Total95Mbps float64 `json:"total_95th_mbps"`
+
+	// BillableMbps is the greater of in and out for interfaces and the total for tags
+	BillableMbps float64 `json:"billable_mbps"`
+
+	InBytes    uint64 `json:"in_bytes"`
+	OutBytes   uint64 `json:"out_bytes"`
+	TotalBytes uint64 `json:"total_bytes"`
+}
+
+// Reporter creates billing reports
+type Reporter struct {
+	db Querier
+}
+
+// New creates a new billing reporter
+func New(db Querier) *Reporter {
+	return &Reporter{
+		db: db,
+	}
+}
+
+type sample struct {
+	direction string
+	agent     string
+	key       string
+	bucket    time.Time
+	bytes     uint64
+}
+
+// Report calculates the 95th percentiles of the given month (UTC) grouped by interface or tag
+func (r *Reporter) Report(by string, month time.Time) ([]*Entry, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	q, err := r.query(by, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(q)
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	samples := make([]sample, 0)
+	for rows.Next() {
+		var s sample
+		err := rows.Scan(&s.direction, &s.agent, &s.key, &s.bucket, &s.bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		samples = append(samples, s)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+
+	return buildReport(by, samples, start, end), nil
+}
+
+func (r *Reporter) query(by string, start time.Time, end time.Time) (string, error) {
+	cond := fmt.Sprintf("timestamp >= toDateTime(%d) AND timestamp < toDateTime(%d)", start.Unix(), end.Unix())
+	sel := "SELECT '%s' AS d, %s AS a, %s AS k, toStartOfFiveMinute(timestamp) AS t, sum(size * samplerate) AS bytes FROM %s.flows WHERE %s AND k != '' GROUP BY a, k, t"
+	db := r.db.GetDatabaseName()
+
+	switch by {
+	case ByInterface:
+		return fmt.Sprintf(sel, "in", "IPv6NumToString(agent)", "int_in", db, cond) + " UNION ALL " +
+			fmt.Sprintf(sel, "out", "IPv6NumToString(agent)", "int_out", db, cond), nil
+	case ByCustomer, ByService, ByTrafficClass:
+		return fmt.Sprintf(sel, "total", "''", by, db, cond), nil
+	}
+
+	return "", fmt.Errorf("Unsupported grouping %q", by)
+}
+
+// buildReport calculates the percentiles. Buckets without traffic count as 0.
+func buildReport(by string, samples []sample, start time.Time, end time.Time) []*Entry {
+	type key struct {
+		agent string
+		key   string
+	}
+
+	type series struct {
+		in  map[int64]uint64
+		out map[int64]uint64
+	}
+
+	data := make(map[key]*series)
+	for _, s := range samples {
+		k := key{agent: s.agent, key: s.key}
+		if _, exists := data[k]; !exists {
+			data[k] = &series{
+				in:  make(map[int64]uint64),
+				out: make(map[int64]uint64),
+			}
+		}
+
+		if s.direction == "out" {
+			data[k].out[s.bucket.Unix()] += s.bytes
+			continue
+		}
+
+		data[k].in[s.bucket.Unix()] += s.bytes
+	}
+
+	buckets := int(end.Sub(start).Seconds()) / BucketSeconds
+	res := make([]*Entry, 0, len(data))
+	for k, s := range data {
+		in := make([]float64, 0, buckets)
+		out := make([]float64, 0, buckets)
+		total := make([]float64, 0, buckets)
+		e := &Entry{}
+		for ts := start.Unix(); ts < end.Unix(); ts += BucketSeconds {
+			in = append(in, toMbps(s.in[ts]))
+			out = append(out, toMbps(s.out[ts]))
+			total = append(total, toMbps(s.in[ts]+s.out[ts]))
+			e.InBytes += s.in[ts]
+			e.OutBytes += s.out[ts]
+		}
+
+		e.TotalBytes = e.InBytes + e.OutBytes
+		e.Total95Mbps = Percentile95(total)
+		if by == ByInterface {
+			e.Agent = formatAddr(k.agent)
+			e.Interface = k.key
+			e.In95Mbps = Percentile95(in)
+			e.Out95Mbps = Percentile95(out)
+			e.BillableMbps = math.Max(e.In95Mbps, e.Out95Mbps)
+		} else {
+			e.Tag = k.key
+			e.BillableMbps = e.Total95Mbps
+		}
+
+		res = append(res, e)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Agent != res[j].Agent {
+			return res[i].Agent < res[j].Agent
+		}
+
+		if res[i].Interface != res[j].Interface {
+			return res[i].Interface < res[j].Interface
+		}
+
+		return res[i].Tag < res[j].Tag
+	})
+
+	return res
+}
+
+func toMbps(bytes uint64) float64 {
+	return float64(bytes) * 8 / BucketSeconds / 1000000
+}
+
+func formatAddr(s string) string {
+	if addr := net.ParseIP(s); addr != nil {
+		return addr.String()
+	}
+
+	return s
+}
+
+// Percentile95 returns the 95th percentile of values: the highest value after discarding the top 5%
+func Percentile95(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	idx := int(math.Ceil(float64(len(sorted))*0.95)) - 1
+	if idx < 0 {
+		idx = 0
+	}
+
+	return sorted[idx]
+}
+
+// WriteCSV writes a report as CSV
+func WriteCSV(w io.Writer, by string, entries []*Entry) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"tag", "total_95th_mbps", "billable_mbps", "total_bytes"}
+	if by == ByInterface {
+		header = []string{"agent", "interface", "in_95th_mbps", "out_95th_mbps", "total_95th_mbps", "billable_mbps", "in_bytes", "out_bytes"}
+	}
+
+	err := cw.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		rec := []string{e.Tag, formatFloat(e.Total95Mbps), formatFloat(e.BillableMbps), fmt.Sprintf("%d", e.TotalBytes)}
+		if by == ByInterface {
+			rec = []string{e.Agent, e.Interface, formatFloat(e.In95Mbps), formatFloat(e.Out95Mbps), formatFloat(e.Total95Mbps),
+				formatFloat(e.BillableMbps), fmt.Sprintf("%d", e.InBytes), fmt.Sprintf("%d", e.OutBytes)}
+		}
+
+		err := cw.Write(rec)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(f float64) string {
+	return fmt.Sprintf("%.3f", f)
+}
+
+// PreviousMonth gets the first of the month before the month of now (UTC). Going back from the first of the month
+// keeps days missing in the previous month, e.g. March 31, from normalizing into the month of now.
+func PreviousMonth(now time.Time) time.Time {
+	y, m, _ := now.UTC().Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+}
+
+// Handler serves a billing report. Parameters: month (YYYY-MM, default: previous month), by (default: interface)
+// and format (json or csv, default: json).
+func (r *Reporter) Handler(w http.ResponseWriter, req *http.Request) {
+	by := req.URL.Query().Get("by")
+	if by == "" {
+		by = ByInterface
+	}
+
+	month := PreviousMonth(time.Now())
+	if m := req.URL.Query().Get("month"); m != "" {
+		var err error
+		month, err = time.Parse(MonthFormat, m)
+		if err != nil {
+			http.Error(w, "Invalid month", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := r.Report(by, month)
+	if err != nil {
+		log.WithError(err).Error("Unable to create billing report")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if strings.ToLower(req.URL.Query().Get("format")) == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=billing-%s-%s.csv", by, month.Format(MonthFormat)))
+		err = WriteCSV(w, by, entries)
+		if err != nil {
+			log.WithError(err).Error("Unable to write CSV")
+		}
+		return
+	}
+
+	j, err := json.Marshal(entries)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
@@ -0,0 +1,133 @@
+package billing
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPercentile95(t *testing.T) {
+	values := make([]float64, 0, 100)
+	for i := 100; i > 0; i-- {
+		values = append(values, float64(i))
+	}
+
+	assert.Equal(t, float64(95), Percentile95(values))
+	assert.Equal(t, float64(100), values[0], "input must not be modified")
+	assert.Equal(t, float64(0), Percentile95(nil))
+	assert.Equal(t, float64(7), Percentile95([]float64{7}))
+}
+
+func TestBuildReport(t *testing.T) {
+	start := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	// 8064 buckets in February 2021, traffic in 500 of them: 5% (403) of the buckets are discarded
+	samples := make([]sample, 0)
+	for i := 0; i < 500; i++ {
+		bytes := uint64(37500000) // 1 Mbps
+		if i < 300 {
+			bytes = 375000000 // 10 Mbps
+		}
+
+		samples = append(samples, sample{
+			direction: "in",
+			agent:     "::ffff:192.0.2.1",
+			key:       "et-0/0/0",
+			bucket:    start.Add(time.Duration(i*BucketSeconds) * time.Second),
+			bytes:     bytes,
+		})
+	}
+
+	samples = append(samples, sample{
+		direction: "out",
+		agent:     "::ffff:192.0.2.1",
+		key:       "et-0/0/0",
+		bucket:    start,
+		bytes:     100,
+	})
+
+	res := buildReport(ByInterface, samples, start, end)
+	assert.Len(t, res, 1)
+	assert.Equal(t, "192.0.2.1", res[0].Agent)
+	assert.Equal(t, "et-0/0/0", res[0].Interface)
+	assert.Equal(t, float64(1), res[0].In95Mbps)
+	assert.Equal(t, float64(0), res[0].Out95Mbps)
+	assert.Equal(t, float64(1), res[0].BillableMbps)
+	assert.Equal(t, uint64(300*375000000+200*37500000), res[0].InBytes)
+	assert.Equal(t, uint64(100), res[0].OutBytes)
+
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, WriteCSV(buf, ByInterface, res))
+	assert.Equal(t, "agent,interface,in_95th_mbps,out_95th_mbps,total_95th_mbps,billable_mbps,in_bytes,out_bytes\n192.0.2.1,et-0/0/0,1.000,0.000,1.000,1.000,120000000000,100\n", buf.String())
+}
+
+func TestBuildReportTags(t *testing.T) {
+	start := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	res := buildReport(ByCustomer, []sample{
+		{direction: "total", key: "acme", bucket: start, bytes: 1000},
+	}, start, start.AddDate(0, 1, 0))
+
+	assert.Len(t, res, 1)
+	assert.Equal(t, "acme", res[0].Tag)
+	assert.Equal(t, "", res[0].Agent)
+	assert.Equal(t, float64(0), res[0].BillableMbps)
+	assert.Equal(t, uint64(1000), res[0].TotalBytes)
+}
+
+type fakeDB struct{}
+
+func (f fakeDB) Query(q string) (*sql.Rows, error) {
+	return nil, nil
+}
+
+func (f fakeDB) GetDatabaseName() string {
+	return "flowhouse"
+}
+
+func TestQuery(t *testing.T) {
+	r := New(fakeDB{})
+	start := time.Unix(1000, 0)
+	q, err := r.query(ByCustomer, start, start.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 'total' AS d, '' AS a, customer AS k, toStartOfFiveMinute(timestamp) AS t, sum(size * samplerate) AS bytes FROM flowhouse.flows WHERE timestamp >= toDateTime(1000) AND timestamp < toDateTime(4600) AND k != '' GROUP BY a, k, t", q)
+
+	_, err = r.query("src_ip_addr", start, start)
+	assert.Error(t, err)
+}
+
+func TestPreviousMonth(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{
+			name:     "Middle of month",
+			now:      time.Date(2021, 3, 15, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Day missing in previous month",
+			now:      time.Date(2021, 3, 31, 23, 59, 59, 0, time.UTC),
+			expected: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "January",
+			now:      time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Other time zone",
+			now:      time.Date(2021, 4, 1, 1, 0, 0, 0, time.FixedZone("CEST", 2*3600)),
+			expected: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, PreviousMonth(test.now), test.name)
+	}
+}
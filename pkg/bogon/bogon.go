@@ -0,0 +1,227 @@
+package bogon
+
+import (
+	"bufio"
+	"io"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/prefixset"
+	"github.com/bio-routing/flowhouse/pkg/source"
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultRefreshInterval = 86400
+
+// defaultBogons are the special purpose and reserved ranges (RFC6890 and friends) which should never show up as source
+// or destination of traffic on the public internet
+var defaultBogons = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/8",
+	"100::/64",
+	"2001:2::/48",
+	"2001:10::/28",
+	"2001:db8::/32",
+	"3fff::/20",
+	"fc00::/7",
+	"fe80::/10",
+	"fec0::/10",
+	"ff00::/8",
+}
+
+// Config is the bogon classifiers configuration
+type Config struct {
+	// DisableDefaults disables the built-in list of reserved ranges
+	DisableDefaults bool `yaml:"disable_defaults"`
+
+	// Prefixes are additional prefixes to be considered bogons
+	Prefixes []string `yaml:"prefixes"`
+
+	// ListSource is a file path or HTTP(S) URL of a list of prefixes (one per line, # starts a comment),
+	// e.g. a full bogon list. It is reloaded every RefreshInterval seconds.
+	ListSource      string `yaml:"list_source"`
+	RefreshInterval uint64 `yaml:"refresh_interval"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.RefreshInterval == 0 {
+		c.RefreshInterval = defaultRefreshInterval
+	}
+}
+
+// Classifier flags flows from or to bogon address space
+type Classifier struct {
+	cfg    *Config
+	static []netip.Prefix
+	set    *prefixset.Set
+	mu     sync.RWMutex
+	stopCh chan struct{}
+}
+
+// New creates a new bogon classifier
+func New(cfg *Config) (*Classifier, error) {
+	cfg.loadDefaults()
+
+	c := &Classifier{
+		cfg:    cfg,
+		stopCh: make(chan struct{}),
+	}
+
+	if !cfg.DisableDefaults {
+		pfxs, err := parsePrefixes(defaultBogons)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid built-in bogon")
+		}
+		c.static = append(c.static, pfxs...)
+	}
+
+	pfxs, err := parsePrefixes(cfg.Prefixes)
+	if err != nil {
+		return nil, err
+	}
+	c.static = append(c.static, pfxs...)
+
+	if cfg.ListSource == "" {
+		c.set = c.newSet(nil)
+		return c, nil
+	}
+
+	err = c.load()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load bogon list")
+	}
+
+	go c.refresher()
+	return c, nil
+}
+
+// Stop stops the periodic refresh of the bogon list
+func (c *Classifier) Stop() {
+	close(c.stopCh)
+}
+
+func (c *Classifier) refresher() {
+	t := time.NewTicker(time.Duration(c.cfg.RefreshInterval) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-t.C:
+			err := c.load()
+			if err != nil {
+				log.WithError(err).Error("Unable to refresh bogon list")
+			}
+		}
+	}
+}
+
+func (c *Classifier) load() error {
+	rc, err := source.Open(c.cfg.ListSource)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	pfxs, err := parseList(rc)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to parse %q", c.cfg.ListSource)
+	}
+
+	s := c.newSet(pfxs)
+
+	c.mu.Lock()
+	c.set = s
+	c.mu.Unlock()
+
+	log.Infof("Loaded %d bogon prefixes", s.Len())
+	return nil
+}
+
+func (c *Classifier) newSet(pfxs []netip.Prefix) *prefixset.Set {
+	s := prefixset.New()
+	for _, pfx := range c.static {
+		s.Add(pfx)
+	}
+
+	for _, pfx := range pfxs {
+		s.Add(pfx)
+	}
+
+	return s
+}
+
+func parsePrefixes(list []string) ([]netip.Prefix, error) {
+	res := make([]netip.Prefix, 0, len(list))
+	for _, p := range list {
+		pfx, err := netip.ParsePrefix(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to parse prefix %q", p)
+		}
+
+		res = append(res, pfx)
+	}
+
+	return res, nil
+}
+
+func parseList(r io.Reader) ([]netip.Prefix, error) {
+	list := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		list = append(list, line)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "Read failed")
+	}
+
+	return parsePrefixes(list)
+}
+
+// IsBogon checks if addr is in bogon address space
+func (c *Classifier) IsBogon(addr netip.Addr) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.set.Contains(addr)
+}
+
+// Annotate flags the flows source and destination address if they are bogons
+func (c *Classifier) Annotate(fl *flow.Flow) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	fl.SrcBogon = c.set.Contains(fl.SrcAddr)
+	fl.DstBogon = c.set.Contains(fl.DstAddr)
+}
@@ -0,0 +1,100 @@
+package bogon
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotate(t *testing.T) {
+	c, err := New(&Config{
+		Prefixes: []string{"198.51.0.0/16"},
+	})
+	if err != nil {
+		t.Fatalf("Unable to create classifier: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		src         string
+		dst         string
+		expectedSrc bool
+		expectedDst bool
+	}{
+		{
+			name:        "Private source",
+			src:         "10.1.2.3",
+			dst:         "8.8.8.8",
+			expectedSrc: true,
+		},
+		{
+			name:        "Additional prefix",
+			src:         "8.8.8.8",
+			dst:         "198.51.1.1",
+			expectedDst: true,
+		},
+		{
+			name: "Public addresses",
+			src:  "8.8.8.8",
+			dst:  "2a00:1450::1",
+		},
+		{
+			name:        "IPv6 documentation and link local",
+			src:         "2001:db8::1",
+			dst:         "fe80::1",
+			expectedSrc: true,
+			expectedDst: true,
+		},
+	}
+
+	for _, test := range tests {
+		fl := &flow.Flow{
+			SrcAddr: mustIP(t, test.src),
+			DstAddr: mustIP(t, test.dst),
+		}
+
+		c.Annotate(fl)
+		assert.Equal(t, test.expectedSrc, fl.SrcBogon, test.name)
+		assert.Equal(t, test.expectedDst, fl.DstBogon, test.name)
+	}
+}
+
+func TestListSource(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "bogons.txt")
+	err := os.WriteFile(p, []byte("# full bogons\n41.62.0.0/16\n\n2c0f:fe51::/32 # unallocated\n"), 0644)
+	if err != nil {
+		t.Fatalf("Unable to write list: %v", err)
+	}
+
+	c, err := New(&Config{
+		DisableDefaults: true,
+		ListSource:      p,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create classifier: %v", err)
+	}
+	defer c.Stop()
+
+	assert.True(t, c.IsBogon(mustIP(t, "41.62.1.1")))
+	assert.True(t, c.IsBogon(mustIP(t, "2c0f:fe51::1")))
+	assert.False(t, c.IsBogon(mustIP(t, "10.0.0.1")))
+}
+
+func TestParseListInvalid(t *testing.T) {
+	_, err := parseList(strings.NewReader("not-a-prefix\n"))
+	assert.Error(t, err)
+}
+
+func mustIP(t *testing.T, s string) netip.Addr {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		t.Fatalf("Unable to parse address %q: %v", s, err)
+	}
+
+	return addr
+}
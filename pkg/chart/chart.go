@@ -0,0 +1,311 @@
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultWidth is the width of charts without a width in pixels
+	DefaultWidth = 800
+
+	// DefaultHeight is the height of charts without a height in pixels
+	DefaultHeight = 400
+
+	maxLegendEntries = 10
+	legendRowHeight  = 12
+	minXLabelSpacing = 90
+	yTicks           = 5
+)
+
+var (
+	background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	foreground = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	gridColor  = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+
+	// palette are the series colors of the web frontend (Google Charts)
+	palette = []color.RGBA{
+		{0x33, 0x66, 0xcc, 0xff},
+		{0xdc, 0x39, 0x12, 0xff},
+		{0xff, 0x99, 0x00, 0xff},
+		{0x10, 0x96, 0x18, 0xff},
+		{0x99, 0x00, 0x99, 0xff},
+		{0x00, 0x99, 0xc6, 0xff},
+		{0xdd, 0x44, 0x77, 0xff},
+		{0x66, 0xaa, 0x00, 0xff},
+		{0xb8, 0x2e, 0x2e, 0xff},
+		{0x31, 0x63, 0x95, 0xff},
+	}
+
+	timeSteps = []time.Duration{
+		time.Minute,
+		5 * time.Minute,
+		10 * time.Minute,
+		15 * time.Minute,
+		30 * time.Minute,
+		time.Hour,
+		3 * time.Hour,
+		6 * time.Hour,
+		12 * time.Hour,
+		24 * time.Hour,
+		7 * 24 * time.Hour,
+	}
+)
+
+// Series is a line of a chart. Values has a value for each timestamp of the chart.
+type Series struct {
+	Name   string
+	Values []float64
+}
+
+// Chart is a line chart of series over time
+type Chart struct {
+	Title      string
+	Unit       string
+	Width      int
+	Height     int
+	Timestamps []time.Time
+	Series     []Series
+}
+
+type anchor int
+
+const (
+	anchorStart anchor = iota
+	anchorMiddle
+	anchorEnd
+)
+
+type point struct {
+	x, y float64
+}
+
+// canvas is what a chart is drawn on. y of text is the vertical middle of the text.
+type canvas interface {
+	rect(x, y, w, h float64, c color.RGBA)
+	line(points []point, c color.RGBA)
+	text(x, y float64, s string, c color.RGBA, a anchor)
+}
+
+func (c *Chart) size() (float64, float64) {
+	w, h := c.Width, c.Height
+	if w <= 0 {
+		w = DefaultWidth
+	}
+
+	if h <= 0 {
+		h = DefaultHeight
+	}
+
+	return float64(w), float64(h)
+}
+
+// draw lays out and draws the chart
+func (c *Chart) draw(cv canvas) {
+	width, height := c.size()
+	cv.rect(0, 0, width, height, background)
+
+	top := 10.0
+	if c.Title != "" {
+		cv.text(width/2, 10, c.Title, foreground, anchorMiddle)
+		top = 30
+	}
+
+	if c.Unit != "" {
+		cv.text(10, top, c.Unit, foreground, anchorStart)
+		top += 12
+	}
+
+	legend := c.legend()
+	bottom := height - 24 - float64(len(legend))*legendRowHeight
+
+	maxValue := c.maxValue()
+	step, yMax := niceScale(maxValue, yTicks)
+
+	ticks := int(math.Round(yMax / step))
+	labelWidth := 0.0
+	for i := 0; i <= ticks; i++ {
+		labelWidth = math.Max(labelWidth, textWidth(formatValue(float64(i)*step)))
+	}
+
+	left := 10 + labelWidth + 6
+	right := width - 15
+	plotWidth, plotHeight := right-left, bottom-top
+	if plotWidth <= 0 || plotHeight <= 0 {
+		return
+	}
+
+	y := func(v float64) float64 {
+		return bottom - v/yMax*plotHeight
+	}
+
+	for i := 0; i <= ticks; i++ {
+		v := float64(i) * step
+		cv.line([]point{{left, y(v)}, {right, y(v)}}, gridColor)
+		cv.text(left-6, y(v), formatValue(v), foreground, anchorEnd)
+	}
+
+	cv.line([]point{{left, top}, {left, bottom}, {right, bottom}}, foreground)
+
+	for i, l := range legend {
+		ly := bottom + 30 + float64(i)*legendRowHeight
+		if l.color != nil {
+			cv.rect(left, ly-2, 12, 4, *l.color)
+		}
+		cv.text(left+18, ly, l.text, foreground, anchorStart)
+	}
+
+	if len(c.Timestamps) == 0 {
+		cv.text(left+plotWidth/2, top+plotHeight/2, "No data", foreground, anchorMiddle)
+		return
+	}
+
+	first, last := c.Timestamps[0], c.Timestamps[len(c.Timestamps)-1]
+	span := last.Sub(first)
+	x := func(ts time.Time) float64 {
+		if span <= 0 {
+			return left + plotWidth/2
+		}
+
+		return left + float64(ts.Sub(first))/float64(span)*plotWidth
+	}
+
+	for _, ts := range timeTicks(first, last, int(plotWidth/minXLabelSpacing)) {
+		cv.line([]point{{x(ts), bottom}, {x(ts), bottom + 4}}, foreground)
+		cv.text(x(ts), bottom+12, formatTime(ts, span), foreground, anchorMiddle)
+	}
+
+	for i, s := range c.Series {
+		col := palette[i%len(palette)]
+		points := make([]point, 0, len(c.Timestamps))
+		for j, ts := range c.Timestamps {
+			v := 0.0
+			if j < len(s.Values) {
+				v = s.Values[j]
+			}
+
+			points = append(points, point{x(ts), y(v)})
+		}
+
+		if len(points) == 1 {
+			cv.rect(points[0].x-2, points[0].y-2, 4, 4, col)
+			continue
+		}
+
+		cv.line(points, col)
+	}
+}
+
+type legendEntry struct {
+	color *color.RGBA
+	text  string
+}
+
+func (c *Chart) legend() []legendEntry {
+	res := make([]legendEntry, 0)
+	for i, s := range c.Series {
+		if i == maxLegendEntries {
+			res = append(res, legendEntry{text: fmt.Sprintf("+%d more", len(c.Series)-maxLegendEntries)})
+			break
+		}
+
+		col := palette[i%len(palette)]
+		res = append(res, legendEntry{color: &col, text: s.Name})
+	}
+
+	return res
+}
+
+func (c *Chart) maxValue() float64 {
+	res := 0.0
+	for _, s := range c.Series {
+		for _, v := range s.Values {
+			res = math.Max(res, v)
+		}
+	}
+
+	return res
+}
+
+// niceScale gets a step of 1, 2 or 5 times a power of 10 for about n ticks from 0 to max and the top of the scale
+func niceScale(max float64, n int) (step float64, top float64) {
+	if max <= 0 {
+		return 1, 1
+	}
+
+	raw := max / float64(n)
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step = magnitude * 10
+	for _, m := range []float64{1, 2, 5} {
+		if m*magnitude >= raw {
+			step = m * magnitude
+			break
+		}
+	}
+
+	return step, math.Ceil(max/step) * step
+}
+
+// timeTicks gets at most n ticks between first and last aligned to a step in the time zone of first
+func timeTicks(first time.Time, last time.Time, n int) []time.Time {
+	span := last.Sub(first)
+	if span <= 0 || n < 1 {
+		return []time.Time{first}
+	}
+
+	step := timeSteps[len(timeSteps)-1]
+	for _, s := range timeSteps {
+		if span/s <= time.Duration(n) {
+			step = s
+			break
+		}
+	}
+
+	_, offset := first.Zone()
+	off := time.Duration(offset) * time.Second
+	ts := first.Add(off).Truncate(step).Add(-off)
+	if ts.Before(first) {
+		ts = ts.Add(step)
+	}
+
+	res := make([]time.Time, 0)
+	for ; !ts.After(last); ts = ts.Add(step) {
+		res = append(res, ts)
+	}
+
+	return res
+}
+
+func formatTime(ts time.Time, span time.Duration) string {
+	switch {
+	case span > 7*24*time.Hour:
+		return ts.Format("2006-01-02")
+	case span > 24*time.Hour:
+		return ts.Format("01-02 15:04")
+	}
+
+	return ts.Format("15:04")
+}
+
+// formatValue formats axis labels with SI prefixes, e.g. 1.5k for 1500
+func formatValue(v float64) string {
+	v = math.Round(v*1e6) / 1e6
+	for _, p := range []struct {
+		factor float64
+		suffix string
+	}{
+		{1e12, "T"},
+		{1e9, "G"},
+		{1e6, "M"},
+		{1e3, "k"},
+	} {
+		if math.Abs(v) >= p.factor {
+			return strconv.FormatFloat(math.Round(v/p.factor*1e6)/1e6, 'f', -1, 64) + p.suffix
+		}
+	}
+
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,133 @@
+package chart
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testChart() *Chart {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := &Chart{
+		Title:  "Traffic <rtr01>",
+		Unit:   "Mbps",
+		Width:  400,
+		Height: 200,
+	}
+
+	for i := 0; i < 60; i++ {
+		c.Timestamps = append(c.Timestamps, start.Add(time.Duration(i)*time.Minute))
+	}
+
+	for _, name := range []string{"agent=rtr01", "agent=rtr02"} {
+		s := Series{Name: name}
+		for i := range c.Timestamps {
+			s.Values = append(s.Values, float64(i*len(name)))
+		}
+		c.Series = append(c.Series, s)
+	}
+
+	return c
+}
+
+func TestNiceScale(t *testing.T) {
+	tests := []struct {
+		max  float64
+		step float64
+		top  float64
+	}{
+		{max: 0, step: 1, top: 1},
+		{max: 9, step: 2, top: 10},
+		{max: 100, step: 20, top: 100},
+		{max: 1234, step: 500, top: 1500},
+	}
+
+	for _, test := range tests {
+		step, top := niceScale(test.max, 5)
+		assert.Equal(t, test.step, step, "step for %v", test.max)
+		assert.Equal(t, test.top, top, "top for %v", test.max)
+	}
+}
+
+func TestTimeTicks(t *testing.T) {
+	first := time.Date(2024, 1, 1, 12, 3, 0, 0, time.UTC)
+	ticks := timeTicks(first, first.Add(time.Hour), 4)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 1, 1, 12, 15, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 12, 45, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC),
+	}, ticks)
+
+	// days start at midnight of the time zone
+	loc := time.FixedZone("UTC+2", 2*3600)
+	first = time.Date(2024, 1, 1, 12, 0, 0, 0, loc)
+	ticks = timeTicks(first, first.Add(72*time.Hour), 4)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, loc), ticks[0])
+	assert.Len(t, ticks, 3)
+}
+
+func TestFormatValue(t *testing.T) {
+	assert.Equal(t, "0", formatValue(0))
+	assert.Equal(t, "0.3", formatValue(0.30000000000000004))
+	assert.Equal(t, "500", formatValue(500))
+	assert.Equal(t, "1.5k", formatValue(1500))
+	assert.Equal(t, "2G", formatValue(2e9))
+}
+
+func TestSVG(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := testChart().SVG(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	svg := buf.String()
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="400" height="200"`))
+	assert.Contains(t, svg, "Traffic &lt;rtr01&gt;")
+	assert.Contains(t, svg, ">agent=rtr02</text>")
+	assert.Contains(t, svg, ">12:30</text>")
+	assert.Equal(t, 2, strings.Count(svg, `stroke="#3366cc"`)+strings.Count(svg, `stroke="#dc3912"`))
+}
+
+func TestPNG(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := testChart().PNG(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatalf("Unable to decode PNG: %v", err)
+	}
+
+	assert.Equal(t, 400, img.Bounds().Dx())
+	assert.Equal(t, 200, img.Bounds().Dy())
+
+	colors := make(map[uint32]bool)
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			colors[(r>>8)<<16|(g>>8)<<8|b>>8] = true
+		}
+	}
+
+	assert.True(t, colors[0x3366cc], "first series drawn")
+	assert.True(t, colors[0xdc3912], "second series drawn")
+}
+
+func TestNoData(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := (&Chart{}).SVG(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assert.Contains(t, buf.String(), ">No data</text>")
+	assert.Contains(t, buf.String(), `width="800" height="400"`)
+}
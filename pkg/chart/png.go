@@ -0,0 +1,102 @@
+package chart
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// pngCanvas rasterizes onto an image using the bitmap font for text
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+// PNG writes the chart as PNG image
+func (c *Chart) PNG(w io.Writer) error {
+	width, height := c.size()
+	cv := &pngCanvas{
+		img: image.NewRGBA(image.Rect(0, 0, int(width), int(height))),
+	}
+
+	c.draw(cv)
+	return png.Encode(w, cv.img)
+}
+
+func (cv *pngCanvas) rect(x, y, w, h float64, c color.RGBA) {
+	for py := int(math.Round(y)); py < int(math.Round(y+h)); py++ {
+		for px := int(math.Round(x)); px < int(math.Round(x+w)); px++ {
+			cv.img.SetRGBA(px, py, c)
+		}
+	}
+}
+
+func (cv *pngCanvas) line(points []point, c color.RGBA) {
+	for i := 1; i < len(points); i++ {
+		cv.segment(points[i-1], points[i], c)
+	}
+}
+
+// segment draws a line from a to b with Bresenham's algorithm
+func (cv *pngCanvas) segment(a, b point, c color.RGBA) {
+	x0, y0 := int(math.Round(a.x)), int(math.Round(a.y))
+	x1, y1 := int(math.Round(b.x)), int(math.Round(b.y))
+
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	e := dx + dy
+	for {
+		cv.img.SetRGBA(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func (cv *pngCanvas) text(x, y float64, s string, c color.RGBA, a anchor) {
+	switch a {
+	case anchorMiddle:
+		x -= textWidth(s) / 2
+	case anchorEnd:
+		x -= textWidth(s)
+	}
+
+	left := int(math.Round(x))
+	top := int(math.Round(y)) - glyphHeight/2
+	for i, r := range []rune(s) {
+		g := glyph(r)
+		for col := 0; col < glyphWidth; col++ {
+			for row := 0; row < glyphHeight; row++ {
+				if g[col]&(1<<row) != 0 {
+					cv.img.SetRGBA(left+i*glyphAdvance+col, top+row, c)
+				}
+			}
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
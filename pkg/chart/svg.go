@@ -0,0 +1,61 @@
+package chart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// svgCanvas draws as SVG elements. The monospace font size matches the advance of the bitmap font used for PNGs.
+type svgCanvas struct {
+	buf *bytes.Buffer
+}
+
+// SVG writes the chart as SVG image
+func (c *Chart) SVG(w io.Writer) error {
+	width, height := c.size()
+	cv := &svgCanvas{
+		buf: bytes.NewBuffer(nil),
+	}
+
+	fmt.Fprintf(cv.buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g" font-family="monospace" font-size="10">`, width, height, width, height)
+	c.draw(cv)
+	cv.buf.WriteString("</svg>\n")
+
+	_, err := w.Write(cv.buf.Bytes())
+	return err
+}
+
+func (cv *svgCanvas) rect(x, y, w, h float64, c color.RGBA) {
+	fmt.Fprintf(cv.buf, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`, x, y, w, h, hexColor(c))
+}
+
+func (cv *svgCanvas) line(points []point, c color.RGBA) {
+	coords := make([]string, len(points))
+	for i, p := range points {
+		coords[i] = fmt.Sprintf("%.1f,%.1f", p.x, p.y)
+	}
+
+	fmt.Fprintf(cv.buf, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`, strings.Join(coords, " "), hexColor(c))
+}
+
+func (cv *svgCanvas) text(x, y float64, s string, c color.RGBA, a anchor) {
+	textAnchor := "start"
+	switch a {
+	case anchorMiddle:
+		textAnchor = "middle"
+	case anchorEnd:
+		textAnchor = "end"
+	}
+
+	fmt.Fprintf(cv.buf, `<text x="%g" y="%g" dominant-baseline="middle" text-anchor="%s" fill="%s">`, x, y, textAnchor, hexColor(c))
+	xml.EscapeText(cv.buf, []byte(s))
+	cv.buf.WriteString("</text>")
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
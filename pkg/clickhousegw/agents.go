@@ -0,0 +1,140 @@
+package clickhousegw
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/agent"
+	"github.com/pkg/errors"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+const (
+	agentsTableName = "agents"
+
+	// AgentsDictName is the name of the dictionary mapping agent addresses to names, sites and roles
+	AgentsDictName = "agents_dict"
+)
+
+// CreateAgentsSchemaIfNotExists creates the agents table and dictionary
+func (c *ClickHouseGateway) CreateAgentsSchemaIfNotExists() error {
+	_, err := c.db.Exec(c.getCreateAgentsTableDDL())
+	if err != nil {
+		return errors.Wrap(err, "Unable to create agents table")
+	}
+
+	_, err = c.db.Exec(c.getCreateAgentsDictDDL())
+	if err != nil {
+		return errors.Wrap(err, "Unable to create agents dictionary")
+	}
+
+	return nil
+}
+
+func (c *ClickHouseGateway) getCreateAgentsTableDDL() string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.%s (
+			agent   IPv6,
+			name    String,
+			site    String,
+			role    String,
+			updated DateTime
+		) ENGINE = ReplacingMergeTree(updated)
+		ORDER BY (agent)
+	`, c.cfg.Database, agentsTableName)
+}
+
+func (c *ClickHouseGateway) getCreateAgentsDictDDL() string {
+	return fmt.Sprintf(`
+		CREATE DICTIONARY IF NOT EXISTS %s.%s (
+			agent String,
+			name  String,
+			site  String,
+			role  String
+		)
+		PRIMARY KEY agent
+		%s
+		LIFETIME(MIN 60 MAX 300)
+		LAYOUT(COMPLEX_KEY_HASHED())
+	`, c.cfg.Database, AgentsDictName, c.dictSource(fmt.Sprintf("SELECT IPv6NumToString(agent) AS agent, name, site, role FROM %s.%s FINAL", c.cfg.Database, agentsTableName)))
+}
+
+// InsertAgents inserts or updates agents
+func (c *ClickHouseGateway) InsertAgents(agents []*agent.Agent) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Begin failed")
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s.%s (
+		agent,
+		name,
+		site,
+		role,
+		updated
+	) VALUES (?, ?, ?, ?, ?)`, c.cfg.Database, agentsTableName))
+	if err != nil {
+		return errors.Wrap(err, "Prepare failed")
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, a := range agents {
+		_, err := stmt.Exec(
+			a.Address.ToNetIP(),
+			a.Name,
+			a.Site,
+			a.Role,
+			now,
+		)
+		if err != nil {
+			return errors.Wrap(err, "Exec failed")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Commit failed")
+	}
+
+	return nil
+}
+
+// GetAgents gets all stored agents
+func (c *ClickHouseGateway) GetAgents() ([]*agent.Agent, error) {
+	rows, err := c.db.Query(fmt.Sprintf("SELECT agent, name, site, role FROM %s.%s FINAL", c.cfg.Database, agentsTableName))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	result := make([]*agent.Agent, 0)
+	for rows.Next() {
+		var addr net.IP
+		a := &agent.Agent{}
+		err := rows.Scan(&addr, &a.Name, &a.Site, &a.Role)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		a.Address, err = bnet.IPFromBytes(normalizeIP(addr))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid agent address %q", addr)
+		}
+
+		result = append(result, a)
+	}
+
+	return result, nil
+}
+
+// normalizeIP converts IPv4 mapped IPv6 addresses into their 4 byte representation
+func normalizeIP(addr net.IP) net.IP {
+	if addr4 := addr.To4(); addr4 != nil {
+		return addr4
+	}
+
+	return addr
+}
@@ -0,0 +1,122 @@
+package clickhousegw
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/annotation"
+	"github.com/pkg/errors"
+)
+
+const (
+	annotationsTableName = "annotations"
+)
+
+// CreateAnnotationsSchemaIfNotExists creates the annotations table
+func (c *ClickHouseGateway) CreateAnnotationsSchemaIfNotExists() error {
+	_, err := c.db.Exec(c.getCreateAnnotationsTableDDL())
+	if err != nil {
+		return errors.Wrap(err, "Unable to create annotations table")
+	}
+
+	return nil
+}
+
+func (c *ClickHouseGateway) getCreateAnnotationsTableDDL() string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.%s (
+			id          UInt64,
+			start       DateTime,
+			end         DateTime,
+			title       String,
+			description String,
+			agents      Array(String),
+			deleted     UInt8,
+			updated     DateTime
+		) ENGINE = ReplacingMergeTree(updated)
+		ORDER BY (id)
+	`, c.cfg.Database, annotationsTableName)
+}
+
+// InsertAnnotations inserts or updates annotations
+func (c *ClickHouseGateway) InsertAnnotations(annotations []*annotation.Annotation) error {
+	return c.insertAnnotations(annotations, false)
+}
+
+// DeleteAnnotations marks annotations as deleted
+func (c *ClickHouseGateway) DeleteAnnotations(annotations []*annotation.Annotation) error {
+	return c.insertAnnotations(annotations, true)
+}
+
+func (c *ClickHouseGateway) insertAnnotations(annotations []*annotation.Annotation, deleted bool) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Begin failed")
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s.%s (
+		id,
+		start,
+		end,
+		title,
+		description,
+		agents,
+		deleted,
+		updated
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, c.cfg.Database, annotationsTableName))
+	if err != nil {
+		return errors.Wrap(err, "Prepare failed")
+	}
+	defer stmt.Close()
+
+	del := uint8(0)
+	if deleted {
+		del = 1
+	}
+
+	now := time.Now()
+	for _, a := range annotations {
+		_, err := stmt.Exec(
+			a.ID,
+			a.Start,
+			a.End,
+			a.Title,
+			a.Description,
+			a.Agents,
+			del,
+			now,
+		)
+		if err != nil {
+			return errors.Wrap(err, "Exec failed")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Commit failed")
+	}
+
+	return nil
+}
+
+// GetAnnotations gets all stored annotations that are not deleted
+func (c *ClickHouseGateway) GetAnnotations() ([]*annotation.Annotation, error) {
+	rows, err := c.db.Query(fmt.Sprintf("SELECT id, start, end, title, description, agents FROM %s.%s FINAL WHERE deleted = 0", c.cfg.Database, annotationsTableName))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	result := make([]*annotation.Annotation, 0)
+	for rows.Next() {
+		a := &annotation.Annotation{}
+		err := rows.Scan(&a.ID, &a.Start, &a.End, &a.Title, &a.Description, &a.Agents)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		result = append(result, a)
+	}
+
+	return result, nil
+}
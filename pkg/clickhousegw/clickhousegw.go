@@ -1,26 +1,37 @@
 package clickhousegw
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"net"
 	"strings"
 	"time"
 
 	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/requestid"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
 	"github.com/ClickHouse/clickhouse-go"
-
-	bnet "github.com/bio-routing/bio-rd/net"
 )
 
-const tableName = "flows"
+const (
+	tableName = "flows"
+
+	// enrichmentCodec compresses the dictionaries of enrichment columns
+	enrichmentCodec = "CODEC(ZSTD(1))"
+
+	rawKeysColumn   = "raw_keys"
+	rawValuesColumn = "raw_values"
+	rawColumn       = "raw"
+)
 
 // ClickHouseGateway is a wrapper for Clickhouse
 type ClickHouseGateway struct {
-	cfg *ClickhouseConfig
-	db  *sql.DB
+	cfg         *ClickhouseConfig
+	db          *sql.DB
+	replicas    *replicas
+	dictSchemas *dictSchemas
 }
 
 // ClickhouseConfig represents a clickhouse client config
@@ -33,13 +44,77 @@ type ClickhouseConfig struct {
 	Sharded  bool   `yaml:"sharded"`
 	Cluster  string `yaml:"cluster"`
 	Secure   bool   `yaml:"secure"`
+
+	// Replicas are endpoints long range and export queries are routed to while they are healthy
+	Replicas []*ReplicaConfig `yaml:"replicas"`
+
+	// AnalyticsMinRange is the queried time range in seconds from which queries go to a replica
+	AnalyticsMinRange uint64 `yaml:"analytics_min_range"`
+
+	// Retention keeps rollups of the flows for longer than the raw flows if set
+	Retention *RetentionConfig `yaml:"retention"`
+
+	// DictSchemaTTL is the number of seconds the attributes of dictionaries are cached (default 60)
+	DictSchemaTTL uint64 `yaml:"dict_schema_ttl"`
+
+	// DictNamedCollection is the named collection the dictionaries of flowhouse (interfaces_dict, agents_dict) read
+	// their tables with, so no credentials are put into their DDL
+	DictNamedCollection string `yaml:"dict_named_collection"`
+
+	// DictUser is a user without password, e.g. one limited to localhost, the dictionaries read their tables with
+	// instead of User and Password, which are put into their DDL in plain text otherwise
+	DictUser string `yaml:"dict_user"`
+
+	// DeduplicationWindow is the number of inserted blocks the flows table remembers to deduplicate inserts if set
+	DeduplicationWindow uint64 `yaml:"deduplication_window"`
+
+	// ExtraColumns are string columns appended to the flows table taking the values of flow.Flow.Extra in this order.
+	// They are set from the configured exporter fields, not the clickhouse config.
+	ExtraColumns []string `yaml:"-"`
+
+	// RawColumn adds the raw map column taking the values of flow.Flow.Raw. It is set if raw exporter fields are
+	// enabled, not by the clickhouse config.
+	RawColumn bool `yaml:"-"`
 }
 
-// New instantiates a new ClickHouseGateway
+// New instantiates a new ClickHouseGateway, creates the flows schema if necessary and connects the analytics replicas
 func New(cfg *ClickhouseConfig) (*ClickHouseGateway, error) {
-	dsn := fmt.Sprintf("tcp://%s?username=%s&password=%s&database=%s&read_timeout=10&write_timeout=20&secure=%t",
-		cfg.Address, cfg.User, cfg.Password, cfg.Database, cfg.Secure)
-	c, err := sql.Open("clickhouse", dsn)
+	chgw, err := Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = chgw.createFlowsSchemaIfNotExists()
+	if err != nil {
+		chgw.Close()
+		return nil, errors.Wrap(err, "Unable to create flows schema")
+	}
+
+	chgw.replicas, err = connectReplicas(cfg)
+	if err != nil {
+		chgw.Close()
+		return nil, err
+	}
+
+	return chgw, nil
+}
+
+// Connect instantiates a new ClickHouseGateway without touching the schema
+func Connect(cfg *ClickhouseConfig) (*ClickHouseGateway, error) {
+	if cfg.Retention != nil {
+		cfg.Retention.loadDefaults()
+	}
+
+	if cfg.DictSchemaTTL == 0 {
+		cfg.DictSchemaTTL = defaultDictSchemaTTL
+	}
+
+	err := validateExtraColumns(cfg.ExtraColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := sql.Open("clickhouse", dsn(cfg.Address, cfg.User, cfg.Password, cfg.Database, cfg.Secure))
 	if err != nil {
 		return nil, errors.Wrap(err, "sql.Open failed")
 	}
@@ -53,17 +128,21 @@ func New(cfg *ClickhouseConfig) (*ClickHouseGateway, error) {
 		return nil, errors.Wrap(err, "c.Ping failed")
 	}
 
-	chgw := &ClickHouseGateway{
-		cfg: cfg,
-		db:  c,
-	}
+	return &ClickHouseGateway{
+		cfg:         cfg,
+		db:          c,
+		dictSchemas: newDictSchemas(time.Duration(cfg.DictSchemaTTL) * time.Second),
+	}, nil
+}
 
-	err = chgw.createFlowsSchemaIfNotExists()
-	if err != nil {
-		return nil, errors.Wrap(err, "Unable to create flows schema")
-	}
+func dsn(address string, user string, password string, database string, secure bool) string {
+	return fmt.Sprintf("tcp://%s?username=%s&password=%s&database=%s&read_timeout=10&write_timeout=20&secure=%t",
+		address, user, password, database, secure)
+}
 
-	return chgw, nil
+// Ping checks that ClickHouse is reachable
+func (c *ClickHouseGateway) Ping() error {
+	return c.db.Ping()
 }
 
 func (c *ClickHouseGateway) createFlowsSchemaIfNotExists() error {
@@ -81,49 +160,235 @@ func (c *ClickHouseGateway) createFlowsSchemaIfNotExists() error {
 		return errors.Wrap(err, "Query failed")
 	}
 
+	err = c.addMissingColumns()
+	if err != nil {
+		return err
+	}
+
+	if c.cfg.DeduplicationWindow > 0 {
+		err = c.setDeduplicationWindow()
+		if err != nil {
+			return errors.Wrap(err, "Unable to set deduplication window")
+		}
+	}
+
+	if c.cfg.Retention != nil {
+		return c.createRollupsIfNotExists()
+	}
+
 	return nil
 }
 
 func (c *ClickHouseGateway) getCreateTableSchemaDDL(isBaseTable bool, zookeeperPathPrefix int64) string {
 	tableDDl := `
 		CREATE TABLE IF NOT EXISTS %s%s (
-			agent           IPv6,
-			int_in          String,
-			int_out         String,
-			src_ip_addr     IPv6,
-			dst_ip_addr     IPv6,
-			src_ip_pfx_addr IPv6,
-			src_ip_pfx_len  UInt8,
-			dst_ip_pfx_addr IPv6,
-			dst_ip_pfx_len  UInt8,
-			nexthop         IPv6,
-			next_asn        UInt32,
-			src_asn         UInt32,
-			dst_asn         UInt32,
-			ip_protocol     UInt8,
-			src_port        UInt16,
-			dst_port        UInt16,
-			timestamp       DateTime,
-			size            UInt64,
-			packets         UInt64,
-			samplerate      UInt64
+%s%s
 		) ENGINE = %s
 		PARTITION BY toStartOfTenMinutes(timestamp)
 		ORDER BY (timestamp)
 		%s
 		SETTINGS index_granularity = 8192
 	`
-	ttl := "TTL timestamp + INTERVAL 14 DAY"
+	ttl := c.rawTTL()
 	onClusterStatement := ""
 	if c.cfg.Sharded {
 		onClusterStatement = " ON CLUSTER " + c.cfg.Cluster
 	}
 
 	if isBaseTable {
-		return fmt.Sprintf(tableDDl, c.getBaseTableName(), onClusterStatement, c.getBaseTableEngineDDL(zookeeperPathPrefix), ttl)
+		return fmt.Sprintf(tableDDl, c.getBaseTableName(), onClusterStatement, flowColumnsDDL, c.enrichmentColumnsDDL(true), c.getBaseTableEngineDDL(zookeeperPathPrefix), ttl)
 	} else {
-		return fmt.Sprintf(tableDDl, tableName, onClusterStatement, c.getDistributedTableDDl(), "")
+		return fmt.Sprintf(tableDDl, tableName, onClusterStatement, flowColumnsDDL, c.enrichmentColumnsDDL(false), c.getDistributedTableDDl(), "")
+	}
+}
+
+// validateExtraColumns checks that extra columns do not shadow columns of the flows table
+func validateExtraColumns(names []string) error {
+	existing := make(map[string]struct{}, len(flowColumns))
+	for _, col := range flowColumns {
+		existing[col.name] = struct{}{}
+	}
+
+	for _, name := range []string{rawKeysColumn, rawValuesColumn, rawColumn} {
+		existing[name] = struct{}{}
+	}
+
+	for _, name := range names {
+		if _, exists := existing[name]; exists {
+			return fmt.Errorf("Extra column %q already exists in the flows table", name)
+		}
+		existing[name] = struct{}{}
+	}
+
+	return nil
+}
+
+// stringColumns gets the enrichment columns followed by the extra columns
+func (c *ClickHouseGateway) stringColumns() []string {
+	return append(append([]string{}, enrichmentColumns...), c.cfg.ExtraColumns...)
+}
+
+// appendedColumn is a column appended to the columns of the flows table and its type in the base or distributed table
+type appendedColumn struct {
+	name string
+	typ  func(isBaseTable bool) string
+}
+
+// appendedColumns gets the enrichment and extra columns followed by the raw columns if enabled
+func (c *ClickHouseGateway) appendedColumns() []appendedColumn {
+	res := make([]appendedColumn, 0, len(enrichmentColumns)+len(c.cfg.ExtraColumns)+3)
+	for _, col := range c.stringColumns() {
+		res = append(res, appendedColumn{name: col, typ: enrichmentColumnType})
+	}
+
+	if c.cfg.RawColumn {
+		res = append(res,
+			appendedColumn{name: rawKeysColumn, typ: rawArrayColumnType},
+			appendedColumn{name: rawValuesColumn, typ: rawArrayColumnType},
+			appendedColumn{name: rawColumn, typ: rawMapColumnType},
+		)
+	}
+
+	return res
+}
+
+// enrichmentColumnsDDL gets the definitions of the enrichment, extra and raw columns to append to the columns of the
+// flows table. Codecs only apply to tables storing data, so the distributed table gets none.
+func (c *ClickHouseGateway) enrichmentColumnsDDL(isBaseTable bool) string {
+	res := ""
+	for _, col := range c.appendedColumns() {
+		res += fmt.Sprintf(",\n\t\t\t%-15s %s", col.name, col.typ(isBaseTable))
+	}
+
+	return res
+}
+
+func enrichmentColumnType(isBaseTable bool) string {
+	if isBaseTable {
+		return "LowCardinality(String) " + enrichmentCodec
+	}
+
+	return "LowCardinality(String)"
+}
+
+// rawArrayColumnType is the type of the keys and values of raw exporter fields. The driver is unable to write maps,
+// so they are stored as two arrays of the same length.
+func rawArrayColumnType(isBaseTable bool) string {
+	if isBaseTable {
+		return "Array(String) " + enrichmentCodec
+	}
+
+	return "Array(String)"
+}
+
+// rawMapColumnType makes the raw exporter fields queryable as map, e.g. raw['461'] or mapKeys(raw)
+func rawMapColumnType(isBaseTable bool) string {
+	return fmt.Sprintf("Map(String, String) ALIAS CAST((%s, %s), 'Map(String, String)')", rawKeysColumn, rawValuesColumn)
+}
+
+// columnType is a column of the flows table and its type
+type columnType struct {
+	name string
+	typ  string
+}
+
+type flowsTable struct {
+	// database and name locate the table in system.columns, ddlName is how DDL refers to it
+	database string
+	name     string
+	ddlName  string
+	isBase   bool
+}
+
+// flowsTables gets the tables of flows, the base table first as it has to have a column before the distributed table
+// reads it
+func (c *ClickHouseGateway) flowsTables() []flowsTable {
+	if !c.cfg.Sharded {
+		return []flowsTable{{database: c.cfg.Database, name: tableName, ddlName: tableName, isBase: true}}
 	}
+
+	return []flowsTable{
+		{database: "_" + c.cfg.Database, name: tableName + "_base", ddlName: c.getBaseTableName(), isBase: true},
+		{database: c.cfg.Database, name: tableName, ddlName: tableName},
+	}
+}
+
+// allColumns gets the columns of the flows table: the generated flow columns followed by the appended ones
+func (c *ClickHouseGateway) allColumns() []appendedColumn {
+	appended := c.appendedColumns()
+	res := make([]appendedColumn, 0, len(flowColumnTypes)+len(appended))
+	for _, col := range flowColumnTypes {
+		typ := col.typ
+		res = append(res, appendedColumn{name: col.name, typ: func(isBaseTable bool) string { return typ }})
+	}
+
+	return append(res, appended...)
+}
+
+// missingColumns gets the columns of the flows table not within existing
+func (c *ClickHouseGateway) missingColumns(existing []string) []appendedColumn {
+	exists := make(map[string]struct{}, len(existing))
+	for _, name := range existing {
+		exists[name] = struct{}{}
+	}
+
+	res := make([]appendedColumn, 0)
+	for _, col := range c.allColumns() {
+		if _, ok := exists[col.name]; !ok {
+			res = append(res, col)
+		}
+	}
+
+	return res
+}
+
+// addMissingColumns adds the columns introduced after the flows tables were created: flow columns of newer versions
+// as well as enrichment, extra and raw columns
+func (c *ClickHouseGateway) addMissingColumns() error {
+	onClusterStatement := ""
+	if c.cfg.Sharded {
+		onClusterStatement = " ON CLUSTER " + c.cfg.Cluster
+	}
+
+	for _, t := range c.flowsTables() {
+		existing, err := c.columnNames(t.database, t.name)
+		if err != nil {
+			return errors.Wrapf(err, "Unable to get columns of %s", t.ddlName)
+		}
+
+		for _, col := range c.missingColumns(existing) {
+			_, err := c.db.Exec(fmt.Sprintf("ALTER TABLE %s%s ADD COLUMN IF NOT EXISTS %s %s", t.ddlName, onClusterStatement, col.name, col.typ(t.isBase)))
+			if err != nil {
+				return errors.Wrapf(err, "Unable to add column %s to %s", col.name, t.ddlName)
+			}
+
+			log.Infof("Added column %s to %s", col.name, t.ddlName)
+		}
+	}
+
+	return nil
+}
+
+// columnNames gets the names of the columns of a table
+func (c *ClickHouseGateway) columnNames(database string, table string) ([]string, error) {
+	rows, err := c.db.Query(fmt.Sprintf("SELECT name FROM system.columns WHERE database = '%s' AND table = '%s'", escapeString(database), escapeString(table)))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	res := make([]string, 0)
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		res = append(res, name)
+	}
+
+	return res, rows.Err()
 }
 
 func (c *ClickHouseGateway) getBaseTableName() string {
@@ -155,86 +420,95 @@ func (c *ClickHouseGateway) getDistributedTableDDl() string {
 		"rand()")
 }
 
-// InsertFlows inserts flows into clickhouse
+// InsertFlows inserts flows into clickhouse. The flows are written as one columnar block through the native
+// interface of the driver instead of row by row through database/sql.
 func (c *ClickHouseGateway) InsertFlows(flows []*flow.Flow) error {
-	tx, err := c.db.Begin()
+	return c.InsertFlowsDedup(flows, Dedup{})
+}
+
+// InsertFlowsDedup inserts flows into clickhouse deduplicated as given by d
+func (c *ClickHouseGateway) InsertFlowsDedup(flows []*flow.Flow, d Dedup) error {
+	settings, err := d.settings(c.cfg.Sharded)
 	if err != nil {
-		return errors.Wrap(err, "Begin failed")
+		return err
 	}
 
-	stmt, err := tx.Prepare(`INSERT INTO flows (
-		agent, 
-		int_in, 
-		int_out, 
-		src_ip_addr, 
-		dst_ip_addr, 
-		src_ip_pfx_addr, 
-		src_ip_pfx_len, 
-		dst_ip_pfx_addr, 
-		dst_ip_pfx_len, 
-		nexthop, 
-		next_asn, 
-		src_asn, 
-		dst_asn, 
-		ip_protocol, 
-		src_port, 
-		dst_port, 
-		timestamp, 
-		size, 
-		packets, 
-		samplerate
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? , ?, ?, ?)`)
-	defer stmt.Close()
+	start := time.Now()
+	conn, err := c.db.Conn(context.Background())
 	if err != nil {
-		return errors.Wrap(err, "Prepare failed")
+		return errors.Wrap(err, "Unable to get connection")
 	}
+	defer conn.Close()
 
-	for _, fl := range flows {
-		_, err := stmt.Exec(
-			fl.Agent.ToNetIP(),
-			fl.IntIn,
-			fl.IntOut,
-			fl.SrcAddr.ToNetIP(),
-			fl.DstAddr.ToNetIP(),
-			addrToNetIP(fl.SrcPfx.Addr()),
-			fl.SrcPfx.Pfxlen(),
-			addrToNetIP(fl.DstPfx.Addr()),
-			fl.DstPfx.Pfxlen(),
-			fl.NextHop.ToNetIP(),
-			fl.NextAs,
-			fl.SrcAs,
-			fl.DstAs,
-			fl.Protocol,
-			fl.SrcPort,
-			fl.DstPort,
-			fl.Timestamp,
-			fl.Size,
-			fl.Packets,
-			fl.Samplerate,
-		)
-		if err != nil {
-			return errors.Wrap(err, "Exec failed")
+	err = conn.Raw(func(dc interface{}) error {
+		ch, ok := dc.(clickhouse.Clickhouse)
+		if !ok {
+			return fmt.Errorf("Unexpected driver connection %T", dc)
+		}
+
+		columns := withExtraColumns(flowColumns, c.cfg.ExtraColumns)
+		if c.cfg.RawColumn {
+			columns = withRawColumns(columns)
 		}
-	}
 
-	err = tx.Commit()
+		return insertBlock(ch, insertQuery(columns)+settings, columns, flows)
+	})
 	if err != nil {
-		return errors.Wrap(err, "Commit failed")
+		return err
 	}
 
+	log.WithFields(logrus.Fields{
+		"flows":    len(flows),
+		"duration": time.Since(start),
+	}).Debug("Inserted flows")
 	return nil
 }
 
-func addrToNetIP(addr *bnet.IP) net.IP {
-	if addr == nil {
-		return net.IP([]byte{0, 0, 0, 0})
+func insertBlock(ch clickhouse.Clickhouse, query string, columns []flowColumn, flows []*flow.Flow) error {
+	_, err := ch.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Begin failed")
+	}
+
+	_, err = ch.Prepare(query)
+	if err != nil {
+		ch.Rollback()
+		return errors.Wrap(err, "Prepare failed")
+	}
+
+	block, err := ch.Block()
+	if err != nil {
+		ch.Rollback()
+		return errors.Wrap(err, "Unable to get block")
+	}
+
+	if len(block.Columns) != len(columns) {
+		ch.Rollback()
+		return fmt.Errorf("Block has %d columns, expected %d", len(block.Columns), len(columns))
+	}
+
+	block.Reserve()
+	block.NumRows = uint64(len(flows))
+	err = writeFlows(block, columns, flows)
+	if err != nil {
+		ch.Rollback()
+		return err
 	}
 
-	return addr.ToNetIP()
+	err = ch.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Commit failed")
+	}
+
+	return nil
 }
 
-// Close closes the database handler
+// Close closes the database handler and the replicas
 func (c *ClickHouseGateway) Close() {
+	if c.replicas != nil {
+		c.replicas.close()
+	}
+
 	c.db.Close()
 }
 
@@ -265,12 +539,28 @@ func (c *ClickHouseGateway) GetColumnValues(columnName string) ([]string, error)
 	return result, nil
 }
 
-// GetDictValues gets all values of a certain dicts attribute
-func (c *ClickHouseGateway) GetDictValues(dictName string, attr string) ([]string, error) {
+// GetDictValues gets all values of a certain dicts attribute. With a scope only values of rows within the scope are
+// returned.
+func (c *ClickHouseGateway) GetDictValues(dictName string, attr string, scope ValueScope) ([]string, error) {
 	dictName = strings.Replace(dictName, " ", "", -1)
 	attr = strings.Replace(attr, " ", "", -1)
 
-	query := fmt.Sprintf("SELECT %s FROM dictionary(%s) GROUP BY %s", attr, dictName, attr)
+	where := ""
+	if len(scope) > 0 {
+		attrs, err := c.DescribeDict(dictName)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to describe dict")
+		}
+
+		conditions := scope.conditions(attrs)
+		if len(conditions) == 0 {
+			return []string{}, nil
+		}
+
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM dictionary(%s)%s GROUP BY %s", attr, dictName, where, attr)
 	res, err := c.db.Query(query)
 	defer res.Close()
 
@@ -293,27 +583,6 @@ func (c *ClickHouseGateway) GetDictValues(dictName string, attr string) ([]strin
 	return result, nil
 }
 
-// GetDictFields gets the names of all fields in a dictionary
-func (c *ClickHouseGateway) GetDictFields(dictName string) ([]string, error) {
-	dictName = strings.Replace(dictName, " ", "", -1)
-
-	query := fmt.Sprintf("SELECT attribute.names FROM system.dictionaries WHERE name = '%s';", dictName)
-	res, err := c.db.Query(query)
-	defer res.Close()
-	if err != nil {
-		return nil, errors.Wrap(err, "Exec failed")
-	}
-
-	result := make([]string, 0)
-	res.Next()
-	err = res.Scan(&result)
-	if err != nil {
-		return nil, err
-	}
-
-	return result, nil
-}
-
 // DescribeTable gets the names of all fields of a table
 func (c *ClickHouseGateway) DescribeTable(tableName string) ([]string, error) {
 	tableName = strings.Replace(tableName, " ", "", -1)
@@ -349,5 +618,31 @@ func (c *ClickHouseGateway) GetDatabaseName() string {
 
 // Query executs an SQL query
 func (c *ClickHouseGateway) Query(q string) (*sql.Rows, error) {
-	return c.db.Query(q)
+	return c.QueryContext(context.Background(), q)
+}
+
+// QueryContext executes an SQL query which is canceled with ctx. Queries are tagged with the request ID of ctx and
+// a query ID derived from it (see RunningQueries). Queries with an analytical ctx (see Route) run on a healthy replica
+// if there is one.
+func (c *ClickHouseGateway) QueryContext(ctx context.Context, q string) (*sql.Rows, error) {
+	id := requestid.FromContext(ctx)
+	queryID := ""
+	if id != "" {
+		queryID = QueryID(id, q)
+		q = tagQuery(q, id, queryID)
+	}
+
+	db, endpoint := c.queryDB(ctx)
+	queriesRouted.WithLabelValues(endpoint).Inc()
+
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, q)
+	log.WithFields(logrus.Fields{
+		"duration":   time.Since(start),
+		"request_id": id,
+		"query_id":   queryID,
+		"endpoint":   endpoint,
+	}).Debug("Query executed")
+
+	return rows, err
 }
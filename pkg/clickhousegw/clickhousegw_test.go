@@ -56,7 +56,46 @@ func TestClickHouseGateway_getCreateTableSchemaDDL(t *testing.T) {
 			timestamp       DateTime,
 			size            UInt64,
 			packets         UInt64,
-			samplerate      UInt64
+			samplerate      UInt64,
+			src_hostname    LowCardinality(String),
+			dst_hostname    LowCardinality(String),
+			customer        LowCardinality(String),
+			service         LowCardinality(String),
+			traffic_class   LowCardinality(String),
+			tcp_flags       UInt8,
+			dscp            UInt8,
+			icmp_type       UInt8,
+			icmp_code       UInt8,
+			src_vlan        UInt16,
+			dst_vlan        UInt16,
+			src_mac_addr    UInt64,
+			dst_mac_addr    UInt64,
+			tunnel_type     LowCardinality(String),
+			tunnel_id       UInt32,
+			inner_src_ip_addr IPv6,
+			inner_dst_ip_addr IPv6,
+			inner_ip_protocol UInt8,
+			inner_src_port  UInt16,
+			inner_dst_port  UInt16,
+			direction       LowCardinality(String),
+			observation_domain_id UInt32,
+			observation_point_id UInt64,
+			flow_start      DateTime64(3),
+			flow_end        DateTime64(3),
+			duration_ms     UInt64,
+			src_rpki_state  LowCardinality(String),
+			dst_rpki_state  LowCardinality(String),
+			src_bogon       UInt8,
+			dst_bogon       UInt8,
+			src_threat_feed LowCardinality(String),
+			dst_threat_feed LowCardinality(String),
+			received_at     DateTime,
+			exported_at     DateTime,
+			agent_name      LowCardinality(String) CODEC(ZSTD(1)),
+			agent_site      LowCardinality(String) CODEC(ZSTD(1)),
+			agent_role      LowCardinality(String) CODEC(ZSTD(1)),
+			export_protocol LowCardinality(String) CODEC(ZSTD(1)),
+			application     LowCardinality(String) CODEC(ZSTD(1))
 		) ENGINE = MergeTree()
 		PARTITION BY toStartOfTenMinutes(timestamp)
 		ORDER BY (timestamp)
@@ -98,7 +137,46 @@ func TestClickHouseGateway_getCreateTableSchemaDDL(t *testing.T) {
 			timestamp       DateTime,
 			size            UInt64,
 			packets         UInt64,
-			samplerate      UInt64
+			samplerate      UInt64,
+			src_hostname    LowCardinality(String),
+			dst_hostname    LowCardinality(String),
+			customer        LowCardinality(String),
+			service         LowCardinality(String),
+			traffic_class   LowCardinality(String),
+			tcp_flags       UInt8,
+			dscp            UInt8,
+			icmp_type       UInt8,
+			icmp_code       UInt8,
+			src_vlan        UInt16,
+			dst_vlan        UInt16,
+			src_mac_addr    UInt64,
+			dst_mac_addr    UInt64,
+			tunnel_type     LowCardinality(String),
+			tunnel_id       UInt32,
+			inner_src_ip_addr IPv6,
+			inner_dst_ip_addr IPv6,
+			inner_ip_protocol UInt8,
+			inner_src_port  UInt16,
+			inner_dst_port  UInt16,
+			direction       LowCardinality(String),
+			observation_domain_id UInt32,
+			observation_point_id UInt64,
+			flow_start      DateTime64(3),
+			flow_end        DateTime64(3),
+			duration_ms     UInt64,
+			src_rpki_state  LowCardinality(String),
+			dst_rpki_state  LowCardinality(String),
+			src_bogon       UInt8,
+			dst_bogon       UInt8,
+			src_threat_feed LowCardinality(String),
+			dst_threat_feed LowCardinality(String),
+			received_at     DateTime,
+			exported_at     DateTime,
+			agent_name      LowCardinality(String) CODEC(ZSTD(1)),
+			agent_site      LowCardinality(String) CODEC(ZSTD(1)),
+			agent_role      LowCardinality(String) CODEC(ZSTD(1)),
+			export_protocol LowCardinality(String) CODEC(ZSTD(1)),
+			application     LowCardinality(String) CODEC(ZSTD(1))
 		) ENGINE = ReplicatedMergeTree('/clickhouse/tables/{shard}/test/flows_%d', '{replica}')
 		PARTITION BY toStartOfTenMinutes(timestamp)
 		ORDER BY (timestamp)
@@ -140,7 +218,46 @@ func TestClickHouseGateway_getCreateTableSchemaDDL(t *testing.T) {
 			timestamp       DateTime,
 			size            UInt64,
 			packets         UInt64,
-			samplerate      UInt64
+			samplerate      UInt64,
+			src_hostname    LowCardinality(String),
+			dst_hostname    LowCardinality(String),
+			customer        LowCardinality(String),
+			service         LowCardinality(String),
+			traffic_class   LowCardinality(String),
+			tcp_flags       UInt8,
+			dscp            UInt8,
+			icmp_type       UInt8,
+			icmp_code       UInt8,
+			src_vlan        UInt16,
+			dst_vlan        UInt16,
+			src_mac_addr    UInt64,
+			dst_mac_addr    UInt64,
+			tunnel_type     LowCardinality(String),
+			tunnel_id       UInt32,
+			inner_src_ip_addr IPv6,
+			inner_dst_ip_addr IPv6,
+			inner_ip_protocol UInt8,
+			inner_src_port  UInt16,
+			inner_dst_port  UInt16,
+			direction       LowCardinality(String),
+			observation_domain_id UInt32,
+			observation_point_id UInt64,
+			flow_start      DateTime64(3),
+			flow_end        DateTime64(3),
+			duration_ms     UInt64,
+			src_rpki_state  LowCardinality(String),
+			dst_rpki_state  LowCardinality(String),
+			src_bogon       UInt8,
+			dst_bogon       UInt8,
+			src_threat_feed LowCardinality(String),
+			dst_threat_feed LowCardinality(String),
+			received_at     DateTime,
+			exported_at     DateTime,
+			agent_name      LowCardinality(String),
+			agent_site      LowCardinality(String),
+			agent_role      LowCardinality(String),
+			export_protocol LowCardinality(String),
+			application     LowCardinality(String)
 		) ENGINE = Distributed(test_cluster, _test, flows_base, rand())
 		PARTITION BY toStartOfTenMinutes(timestamp)
 		ORDER BY (timestamp)
@@ -0,0 +1,221 @@
+package clickhousegw
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/pkg/errors"
+)
+
+// blockWriter appends values to the columns of a block, e.g. a *data.Block of the ClickHouse driver
+type blockWriter interface {
+	WriteUInt8(c int, v uint8) error
+	WriteUInt16(c int, v uint16) error
+	WriteUInt32(c int, v uint32) error
+	WriteUInt64(c int, v uint64) error
+	WriteInt64(c int, v int64) error
+	WriteString(c int, v string) error
+	WriteArray(c int, v interface{}) error
+}
+
+// flowColumn is a column of the flows table and how to get its value from a flow
+type flowColumn struct {
+	name  string
+	write func(w blockWriter, c int, fl *flow.Flow) error
+}
+
+// insertFlowsQuery inserts all flowColumns. The driver appends VALUES itself and expects the data as a block.
+var insertFlowsQuery = insertQuery(flowColumns)
+
+func insertQuery(columns []flowColumn) string {
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		names = append(names, col.name)
+	}
+
+	return "INSERT INTO " + tableName + " (" + strings.Join(names, ", ") + ")"
+}
+
+// withExtraColumns appends string columns taking the values of fl.Extra in the order of names
+func withExtraColumns(columns []flowColumn, names []string) []flowColumn {
+	if len(names) == 0 {
+		return columns
+	}
+
+	res := make([]flowColumn, 0, len(columns)+len(names))
+	res = append(res, columns...)
+	for i, name := range names {
+		i := i
+		res = append(res, stringColumn(name, func(fl *flow.Flow) string {
+			if i < len(fl.Extra) {
+				return fl.Extra[i]
+			}
+
+			return ""
+		}))
+	}
+
+	return res
+}
+
+// withRawColumns appends the keys and values of fl.Raw as two arrays. Keys are sorted, so equal maps are stored
+// equally.
+func withRawColumns(columns []flowColumn) []flowColumn {
+	res := make([]flowColumn, 0, len(columns)+2)
+	res = append(res, columns...)
+	return append(res,
+		flowColumn{
+			name: rawKeysColumn,
+			write: func(w blockWriter, c int, fl *flow.Flow) error {
+				return w.WriteArray(c, rawKeys(fl.Raw))
+			},
+		},
+		flowColumn{
+			name: rawValuesColumn,
+			write: func(w blockWriter, c int, fl *flow.Flow) error {
+				keys := rawKeys(fl.Raw)
+				values := make([]string, 0, len(keys))
+				for _, k := range keys {
+					values = append(values, fl.Raw[k])
+				}
+
+				return w.WriteArray(c, values)
+			},
+		},
+	)
+}
+
+func rawKeys(raw map[string]string) []string {
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// writeFlows writes flows to w column by column
+func writeFlows(w blockWriter, columns []flowColumn, flows []*flow.Flow) error {
+	for c, col := range columns {
+		for _, fl := range flows {
+			err := col.write(w, c, fl)
+			if err != nil {
+				return errors.Wrapf(err, "Unable to write column %s", col.name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// addrColumn is an IPv6 column. IPv6 values are 16 raw bytes on the wire which are written as two little endian
+// 64 bit integers, saving the net.IP the driver would need.
+func addrColumn(name string, get func(fl *flow.Flow) netip.Addr) flowColumn {
+	return flowColumn{
+		name: name,
+		write: func(w blockWriter, c int, fl *flow.Flow) error {
+			var b [flow.AddrSize]byte
+			flow.PutAddr(b[:], get(fl))
+
+			err := w.WriteUInt64(c, binary.LittleEndian.Uint64(b[:8]))
+			if err != nil {
+				return err
+			}
+
+			return w.WriteUInt64(c, binary.LittleEndian.Uint64(b[8:]))
+		},
+	}
+}
+
+func stringColumn(name string, get func(fl *flow.Flow) string) flowColumn {
+	return flowColumn{
+		name: name,
+		write: func(w blockWriter, c int, fl *flow.Flow) error {
+			return w.WriteString(c, get(fl))
+		},
+	}
+}
+
+func uint8Column(name string, get func(fl *flow.Flow) uint8) flowColumn {
+	return flowColumn{
+		name: name,
+		write: func(w blockWriter, c int, fl *flow.Flow) error {
+			return w.WriteUInt8(c, get(fl))
+		},
+	}
+}
+
+func boolColumn(name string, get func(fl *flow.Flow) bool) flowColumn {
+	return uint8Column(name, func(fl *flow.Flow) uint8 {
+		if get(fl) {
+			return 1
+		}
+
+		return 0
+	})
+}
+
+func uint16Column(name string, get func(fl *flow.Flow) uint16) flowColumn {
+	return flowColumn{
+		name: name,
+		write: func(w blockWriter, c int, fl *flow.Flow) error {
+			return w.WriteUInt16(c, get(fl))
+		},
+	}
+}
+
+func uint32Column(name string, get func(fl *flow.Flow) uint32) flowColumn {
+	return flowColumn{
+		name: name,
+		write: func(w blockWriter, c int, fl *flow.Flow) error {
+			return w.WriteUInt32(c, get(fl))
+		},
+	}
+}
+
+func uint64Column(name string, get func(fl *flow.Flow) uint64) flowColumn {
+	return flowColumn{
+		name: name,
+		write: func(w blockWriter, c int, fl *flow.Flow) error {
+			return w.WriteUInt64(c, get(fl))
+		},
+	}
+}
+
+// dateTimeColumn is a DateTime column of unix time in seconds
+func dateTimeColumn(name string, get func(fl *flow.Flow) int64) flowColumn {
+	return uint32Column(name, func(fl *flow.Flow) uint32 {
+		return uint32(get(fl))
+	})
+}
+
+// dateTime64Column is a DateTime64(3) column of unix time in milliseconds
+func dateTime64Column(name string, get func(fl *flow.Flow) int64) flowColumn {
+	return flowColumn{
+		name: name,
+		write: func(w blockWriter, c int, fl *flow.Flow) error {
+			return w.WriteInt64(c, get(fl))
+		},
+	}
+}
+
+// pfxAddr gets the address of pfx, 0.0.0.0 if it is unset
+func pfxAddr(pfx netip.Prefix) netip.Addr {
+	if !pfx.IsValid() {
+		return netip.IPv4Unspecified()
+	}
+
+	return pfx.Addr()
+}
+
+func pfxlen(pfx netip.Prefix) uint8 {
+	if !pfx.IsValid() {
+		return 0
+	}
+
+	return uint8(pfx.Bits())
+}
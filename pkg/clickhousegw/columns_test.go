@@ -0,0 +1,246 @@
+package clickhousegw
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingWriter keeps the little endian encoding of the values written to each column, like the driver does
+type recordingWriter struct {
+	columns map[int][]byte
+	strings map[int][]string
+	arrays  map[int][]interface{}
+}
+
+func newRecordingWriter() *recordingWriter {
+	return &recordingWriter{
+		columns: make(map[int][]byte),
+		strings: make(map[int][]string),
+		arrays:  make(map[int][]interface{}),
+	}
+}
+
+func (w *recordingWriter) WriteUInt8(c int, v uint8) error {
+	w.columns[c] = append(w.columns[c], v)
+	return nil
+}
+
+func (w *recordingWriter) WriteUInt16(c int, v uint16) error {
+	w.columns[c] = binary.LittleEndian.AppendUint16(w.columns[c], v)
+	return nil
+}
+
+func (w *recordingWriter) WriteUInt32(c int, v uint32) error {
+	w.columns[c] = binary.LittleEndian.AppendUint32(w.columns[c], v)
+	return nil
+}
+
+func (w *recordingWriter) WriteUInt64(c int, v uint64) error {
+	w.columns[c] = binary.LittleEndian.AppendUint64(w.columns[c], v)
+	return nil
+}
+
+func (w *recordingWriter) WriteInt64(c int, v int64) error {
+	return w.WriteUInt64(c, uint64(v))
+}
+
+func (w *recordingWriter) WriteString(c int, v string) error {
+	w.strings[c] = append(w.strings[c], v)
+	return nil
+}
+
+func (w *recordingWriter) WriteArray(c int, v interface{}) error {
+	w.arrays[c] = append(w.arrays[c], v)
+	return nil
+}
+
+func columnIndex(t *testing.T, name string) int {
+	for i, col := range flowColumns {
+		if col.name == name {
+			return i
+		}
+	}
+
+	t.Fatalf("Column %s not found", name)
+	return -1
+}
+
+func TestWriteFlows(t *testing.T) {
+	flows := []*flow.Flow{
+		{
+			Agent:     netip.MustParseAddr("192.0.2.1"),
+			SrcAddr:   netip.MustParseAddr("2001:db8::1"),
+			SrcPfx:    netip.MustParsePrefix("2001:db8::/32"),
+			SrcPort:   443,
+			Timestamp: 1700000000,
+			FlowStart: 1700000000123,
+			DstBogon:  true,
+			IntIn:     "et-0/0/0",
+		},
+		{
+			Agent:   netip.MustParseAddr("192.0.2.2"),
+			SrcPort: 80,
+		},
+	}
+
+	w := newRecordingWriter()
+	err := writeFlows(w, flowColumns, flows)
+	if err != nil {
+		t.Fatalf("writeFlows failed: %v", err)
+	}
+
+	agent := netip.MustParseAddr("192.0.2.1").As16()
+	agent2 := netip.MustParseAddr("192.0.2.2").As16()
+	assert.Equal(t, append(agent[:], agent2[:]...), w.columns[columnIndex(t, "agent")], "agent")
+
+	src := netip.MustParseAddr("2001:db8::1").As16()
+	unspecified := netip.IPv4Unspecified().As16()
+	assert.Equal(t, append(src[:], unspecified[:]...), w.columns[columnIndex(t, "src_ip_addr")], "src_ip_addr")
+
+	pfx := netip.MustParseAddr("2001:db8::").As16()
+	assert.Equal(t, append(pfx[:], unspecified[:]...), w.columns[columnIndex(t, "src_ip_pfx_addr")], "src_ip_pfx_addr")
+	assert.Equal(t, []byte{32, 0}, w.columns[columnIndex(t, "src_ip_pfx_len")], "src_ip_pfx_len")
+
+	assert.Equal(t, []byte{0xbb, 0x01, 80, 0}, w.columns[columnIndex(t, "src_port")], "src_port")
+	assert.Equal(t, binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, 1700000000), 0), w.columns[columnIndex(t, "timestamp")], "timestamp")
+	assert.Equal(t, binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, 1700000000123), 0), w.columns[columnIndex(t, "flow_start")], "flow_start")
+	assert.Equal(t, []byte{1, 0}, w.columns[columnIndex(t, "dst_bogon")], "dst_bogon")
+	assert.Equal(t, []string{"et-0/0/0", ""}, w.strings[columnIndex(t, "int_in")], "int_in")
+}
+
+func TestFlowColumns(t *testing.T) {
+	c := &ClickHouseGateway{
+		cfg: &ClickhouseConfig{
+			Database: "test",
+		},
+	}
+	ddl := c.getCreateTableSchemaDDL(true, 0)
+
+	seen := make(map[string]struct{})
+	for _, col := range flowColumns {
+		_, dup := seen[col.name]
+		assert.False(t, dup, "duplicate column %s", col.name)
+		seen[col.name] = struct{}{}
+
+		assert.True(t, strings.Contains(ddl, "\t"+col.name+" "), "column %s not in DDL", col.name)
+	}
+
+	assert.True(t, strings.HasPrefix(insertFlowsQuery, "INSERT INTO flows (agent, int_in, int_out, "))
+	assert.False(t, strings.Contains(insertFlowsQuery, "VALUES"))
+}
+
+func TestExtraColumns(t *testing.T) {
+	c := &ClickHouseGateway{
+		cfg: &ClickhouseConfig{
+			Database:     "test",
+			ExtraColumns: []string{"interface_name", "app_id"},
+		},
+	}
+	assert.True(t, strings.Contains(c.getCreateTableSchemaDDL(true, 0), "\tapp_id          LowCardinality(String) "+enrichmentCodec))
+
+	columns := withExtraColumns(flowColumns, c.cfg.ExtraColumns)
+	assert.True(t, strings.HasSuffix(insertQuery(columns), ", application, interface_name, app_id)"))
+
+	flows := []*flow.Flow{
+		{Extra: []string{"et-0/0/0", "42"}},
+		{},
+	}
+
+	w := newRecordingWriter()
+	err := writeFlows(w, columns, flows)
+	if err != nil {
+		t.Fatalf("writeFlows failed: %v", err)
+	}
+
+	assert.Equal(t, []string{"et-0/0/0", ""}, w.strings[len(flowColumns)])
+	assert.Equal(t, []string{"42", ""}, w.strings[len(flowColumns)+1])
+}
+
+func TestValidateExtraColumns(t *testing.T) {
+	assert.NoError(t, validateExtraColumns([]string{"interface_name"}))
+	assert.Error(t, validateExtraColumns([]string{"int_in"}))
+	assert.Error(t, validateExtraColumns([]string{"agent_site"}))
+	assert.Error(t, validateExtraColumns([]string{"app_id", "app_id"}))
+	assert.Error(t, validateExtraColumns([]string{"raw"}))
+}
+
+func TestRawColumns(t *testing.T) {
+	c := &ClickHouseGateway{
+		cfg: &ClickhouseConfig{
+			Database: "test",
+		},
+	}
+	assert.False(t, strings.Contains(c.getCreateTableSchemaDDL(true, 0), rawKeysColumn))
+
+	c.cfg.RawColumn = true
+	ddl := c.getCreateTableSchemaDDL(true, 0)
+	assert.True(t, strings.Contains(ddl, "\traw_keys        Array(String) "+enrichmentCodec))
+	assert.True(t, strings.Contains(ddl, "\traw_values      Array(String) "+enrichmentCodec))
+	assert.True(t, strings.Contains(ddl, "\traw             Map(String, String) ALIAS CAST((raw_keys, raw_values), 'Map(String, String)')"))
+	assert.True(t, strings.Contains(c.getCreateTableSchemaDDL(false, 0), "\traw_keys        Array(String),"))
+
+	columns := withRawColumns(flowColumns)
+	assert.True(t, strings.HasSuffix(insertQuery(columns), ", application, raw_keys, raw_values)"))
+
+	flows := []*flow.Flow{
+		{Raw: map[string]string{"461": "/index.html", "9/12235": "42"}},
+		{},
+	}
+
+	w := newRecordingWriter()
+	err := writeFlows(w, columns, flows)
+	if err != nil {
+		t.Fatalf("writeFlows failed: %v", err)
+	}
+
+	assert.Equal(t, []interface{}{[]string{"461", "9/12235"}, []string{}}, w.arrays[len(flowColumns)])
+	assert.Equal(t, []interface{}{[]string{"/index.html", "42"}, []string{}}, w.arrays[len(flowColumns)+1])
+}
+
+func TestMissingColumns(t *testing.T) {
+	// baseline are the columns of the flows table of the first releases
+	baseline := []string{
+		"agent", "int_in", "int_out", "src_ip_addr", "dst_ip_addr", "src_ip_pfx_addr", "src_ip_pfx_len", "dst_ip_pfx_addr",
+		"dst_ip_pfx_len", "nexthop", "next_asn", "src_asn", "dst_asn", "ip_protocol", "src_port", "dst_port", "timestamp",
+		"size", "packets", "samplerate",
+	}
+
+	c := &ClickHouseGateway{cfg: &ClickhouseConfig{ExtraColumns: []string{"url"}, RawColumn: true}}
+	missing := c.missingColumns(baseline)
+
+	names := make([]string, 0, len(missing))
+	types := make(map[string]string, len(missing))
+	for _, col := range missing {
+		names = append(names, col.name)
+		types[col.name] = col.typ(true)
+	}
+
+	assert.Equal(t, len(c.allColumns())-len(baseline), len(missing))
+	for _, name := range baseline {
+		assert.NotContains(t, names, name)
+	}
+
+	for _, name := range []string{"src_hostname", "dst_hostname", "tcp_flags", "dscp", "src_mac_addr", "flow_start", "agent_name", "url", rawColumn} {
+		assert.Contains(t, names, name)
+	}
+	assert.Equal(t, "LowCardinality(String)", types["src_hostname"])
+	assert.Equal(t, "UInt8", types["tcp_flags"])
+	assert.Equal(t, "DateTime64(3)", types["flow_start"])
+	assert.Equal(t, enrichmentColumnType(true), types["agent_name"])
+
+	all := append(append([]string{}, baseline...), names...)
+	assert.Empty(t, c.missingColumns(all), "up to date")
+}
+
+func TestFlowsTables(t *testing.T) {
+	c := &ClickHouseGateway{cfg: &ClickhouseConfig{Database: "flowhouse", Sharded: true, Cluster: "c1"}}
+	assert.Equal(t, []flowsTable{
+		{database: "_flowhouse", name: "flows_base", ddlName: "_flowhouse.flows_base", isBase: true},
+		{database: "flowhouse", name: "flows", ddlName: "flows"},
+	}, c.flowsTables())
+}
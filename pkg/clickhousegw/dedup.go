@@ -0,0 +1,79 @@
+package clickhousegw
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DedupToken drops inserts whose token was inserted before (insert_deduplication_token, ClickHouse 22.2 or later).
+	// Data inserted again is dropped even if it differs, e.g. by the receive time of replayed flows.
+	DedupToken = "token"
+
+	// DedupBlocks drops inserts of blocks equal to a block inserted before
+	DedupBlocks = "blocks"
+
+	// DedupOff inserts all blocks even if they were inserted before
+	DedupOff = "off"
+)
+
+var dedupTokenRegexp = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// Dedup is how an insert is deduplicated. The zero value keeps the settings of the server.
+type Dedup struct {
+	Mode string
+
+	// Token identifies the inserted data in DedupToken mode
+	Token string
+}
+
+// ValidateDedupMode checks that mode is a deduplication mode
+func ValidateDedupMode(mode string) error {
+	switch mode {
+	case DedupToken, DedupBlocks, DedupOff:
+		return nil
+	}
+
+	return fmt.Errorf("Invalid deduplication mode %q (token, blocks or off)", mode)
+}
+
+// settings gets the SETTINGS clause of an insert, empty for the zero value. Inserts into the distributed table of a
+// sharded cluster are forwarded to the shards synchronously, otherwise the shards deduplicate without the token.
+func (d Dedup) settings(sharded bool) (string, error) {
+	var settings []string
+	switch d.Mode {
+	case "":
+		return "", nil
+	case DedupOff:
+		settings = append(settings, "insert_deduplicate = 0")
+	case DedupBlocks:
+		settings = append(settings, "insert_deduplicate = 1")
+	case DedupToken:
+		if !dedupTokenRegexp.MatchString(d.Token) {
+			return "", fmt.Errorf("Invalid deduplication token %q", d.Token)
+		}
+
+		settings = append(settings, "insert_deduplicate = 1", fmt.Sprintf("insert_deduplication_token = '%s'", d.Token))
+	default:
+		return "", ValidateDedupMode(d.Mode)
+	}
+
+	if sharded && d.Mode != DedupOff {
+		settings = append(settings, "insert_distributed_sync = 1")
+	}
+
+	return " SETTINGS " + strings.Join(settings, ", "), nil
+}
+
+// setDeduplicationWindow sets the number of blocks the flows base table remembers to deduplicate inserts.
+// Replicated tables deduplicate by default, MergeTree tables only with a window.
+func (c *ClickHouseGateway) setDeduplicationWindow() error {
+	if c.cfg.Sharded {
+		_, err := c.db.Exec(fmt.Sprintf("ALTER TABLE %s ON CLUSTER %s MODIFY SETTING replicated_deduplication_window = %d", c.getBaseTableName(), c.cfg.Cluster, c.cfg.DeduplicationWindow))
+		return err
+	}
+
+	_, err := c.db.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY SETTING non_replicated_deduplication_window = %d", c.getBaseTableName(), c.cfg.DeduplicationWindow))
+	return err
+}
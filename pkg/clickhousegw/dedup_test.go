@@ -0,0 +1,77 @@
+package clickhousegw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		dedup    Dedup
+		sharded  bool
+		expected string
+		wantErr  bool
+	}{
+		{
+			name: "Server defaults",
+		},
+		{
+			name:     "Token",
+			dedup:    Dedup{Mode: DedupToken, Token: "0-abc"},
+			expected: " SETTINGS insert_deduplicate = 1, insert_deduplication_token = '0-abc'",
+		},
+		{
+			name:     "Token sharded",
+			dedup:    Dedup{Mode: DedupToken, Token: "0-abc"},
+			sharded:  true,
+			expected: " SETTINGS insert_deduplicate = 1, insert_deduplication_token = '0-abc', insert_distributed_sync = 1",
+		},
+		{
+			name:     "Blocks",
+			dedup:    Dedup{Mode: DedupBlocks},
+			expected: " SETTINGS insert_deduplicate = 1",
+		},
+		{
+			name:     "Off sharded",
+			dedup:    Dedup{Mode: DedupOff},
+			sharded:  true,
+			expected: " SETTINGS insert_deduplicate = 0",
+		},
+		{
+			name:    "Invalid token",
+			dedup:   Dedup{Mode: DedupToken, Token: "x' , insert_deduplicate = 0, y = '"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing token",
+			dedup:   Dedup{Mode: DedupToken},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown mode",
+			dedup:   Dedup{Mode: "always"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		settings, err := test.dedup.settings(test.sharded)
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, settings, test.name)
+	}
+}
+
+func TestValidateDedupMode(t *testing.T) {
+	assert.NoError(t, ValidateDedupMode(DedupToken))
+	assert.NoError(t, ValidateDedupMode(DedupBlocks))
+	assert.NoError(t, ValidateDedupMode(DedupOff))
+	assert.Error(t, ValidateDedupMode(""))
+	assert.Error(t, ValidateDedupMode("on"))
+}
@@ -0,0 +1,271 @@
+package clickhousegw
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultDictSchemaTTL is the number of seconds dictionary schemas are cached by default
+const defaultDictSchemaTTL = 60
+
+var dictNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)?$`)
+
+// dictSource gets the SOURCE clause of a dictionary of flowhouse reading its data with query. The DDL of dictionaries,
+// including the credentials of the source, is readable e.g. by SHOW CREATE DICTIONARY, so a named collection or a user
+// without password is preferred over the credentials of the gateway.
+func (c *ClickHouseGateway) dictSource(query string) string {
+	if c.cfg.DictNamedCollection != "" {
+		return fmt.Sprintf("SOURCE(CLICKHOUSE(NAME %s QUERY '%s'))", c.cfg.DictNamedCollection, escapeString(query))
+	}
+
+	if c.cfg.DictUser != "" {
+		return fmt.Sprintf("SOURCE(CLICKHOUSE(USER '%s' QUERY '%s'))", escapeString(c.cfg.DictUser), escapeString(query))
+	}
+
+	return fmt.Sprintf("SOURCE(CLICKHOUSE(USER '%s' PASSWORD '%s' QUERY '%s'))", escapeString(c.cfg.User), escapeString(c.cfg.Password), escapeString(query))
+}
+
+// DictAttribute is an attribute of a dictionary
+type DictAttribute struct {
+	Name string `json:"name"`
+
+	// Type is the ClickHouse type, e.g. String or UInt32
+	Type string `json:"type"`
+}
+
+// IsNumeric checks if the attribute holds integers or floats
+func (a *DictAttribute) IsNumeric() bool {
+	t := strings.TrimPrefix(a.Type, "Nullable(")
+	return strings.HasPrefix(t, "UInt") || strings.HasPrefix(t, "Int") || strings.HasPrefix(t, "Float")
+}
+
+// ValueScope restricts the values listed from dicts to the rows whose attributes have one of the given values, e.g.
+// the customers a tenant may see
+type ValueScope map[string][]string
+
+// conditions gets the conditions of the scope on the attributes of a dict, sorted by attribute. Attributes of the
+// scope the dict does not have are skipped. A dict without any of them is not within the scope, so no conditions are
+// returned then.
+func (s ValueScope) conditions(attrs []*DictAttribute) []string {
+	res := make([]string, 0, len(s))
+	for _, a := range attrs {
+		allowed, exists := s[a.Name]
+		if !exists {
+			continue
+		}
+
+		values := make([]string, 0, len(allowed))
+		for _, v := range allowed {
+			values = append(values, fmt.Sprintf("'%s'", escapeString(v)))
+		}
+
+		if len(values) == 0 {
+			res = append(res, "0")
+			continue
+		}
+
+		res = append(res, fmt.Sprintf("toString(%s) IN (%s)", a.Name, strings.Join(values, ", ")))
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// dictSchemas caches the attributes of dictionaries, so rendering the index view does not query
+// system.dictionaries for every dict bound to a field. Failed lookups are not cached.
+type dictSchemas struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*dictSchema
+}
+
+type dictSchema struct {
+	attrs   []*DictAttribute
+	expires time.Time
+}
+
+func newDictSchemas(ttl time.Duration) *dictSchemas {
+	return &dictSchemas{
+		ttl:     ttl,
+		entries: make(map[string]*dictSchema),
+	}
+}
+
+// get gets the cached attributes of dictName or loads them if they are missing or expired
+func (s *dictSchemas) get(dictName string, load func() ([]*DictAttribute, error)) ([]*DictAttribute, error) {
+	if s == nil {
+		return load()
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	e, exists := s.entries[dictName]
+	s.mu.Unlock()
+	if exists && now.Before(e.expires) {
+		return e.attrs, nil
+	}
+
+	attrs, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.entries[dictName] = &dictSchema{
+		attrs:   attrs,
+		expires: now.Add(s.ttl),
+	}
+	s.mu.Unlock()
+
+	return attrs, nil
+}
+
+// forget drops the cached attributes of dictName
+func (s *dictSchemas) forget(dictName string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range []string{dictName, dictName[strings.Index(dictName, ".")+1:]} {
+		delete(s.entries, name)
+	}
+}
+
+// DescribeDict gets the attributes of a dictionary with their types. Schemas are cached for dict_schema_ttl seconds
+// (default 60).
+func (c *ClickHouseGateway) DescribeDict(dictName string) ([]*DictAttribute, error) {
+	dictName = strings.Replace(dictName, " ", "", -1)
+
+	return c.dictSchemas.get(dictName, func() ([]*DictAttribute, error) {
+		return c.describeDict(dictName)
+	})
+}
+
+func (c *ClickHouseGateway) describeDict(dictName string) ([]*DictAttribute, error) {
+	query := fmt.Sprintf("SELECT attribute.names, attribute.types FROM system.dictionaries WHERE name = '%s'", dictName)
+	res, err := c.db.Query(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Exec failed")
+	}
+	defer res.Close()
+
+	if !res.Next() {
+		return nil, fmt.Errorf("Dict %q not found", dictName)
+	}
+
+	names := make([]string, 0)
+	types := make([]string, 0)
+	err = res.Scan(&names, &types)
+	if err != nil {
+		return nil, errors.Wrap(err, "Scan failed")
+	}
+
+	return dictAttributes(names, types)
+}
+
+func dictAttributes(names []string, types []string) ([]*DictAttribute, error) {
+	if len(names) != len(types) {
+		return nil, fmt.Errorf("Got %d attribute names but %d types", len(names), len(types))
+	}
+
+	res := make([]*DictAttribute, 0, len(names))
+	for i := range names {
+		res = append(res, &DictAttribute{
+			Name: names[i],
+			Type: types[i],
+		})
+	}
+
+	return res, nil
+}
+
+// GetDictFields gets the names of all fields in a dictionary
+func (c *ClickHouseGateway) GetDictFields(dictName string) ([]string, error) {
+	attrs, err := c.DescribeDict(dictName)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		res = append(res, a.Name)
+	}
+
+	return res, nil
+}
+
+// DictReload is the state of a dictionary after reloading it
+type DictReload struct {
+	Dict string `json:"dict"`
+
+	// Status is the status of the dictionary in system.dictionaries, e.g. LOADED or FAILED
+	Status   string `json:"status"`
+	Elements uint64 `json:"elements"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ReloadDict reloads a dictionary from its source right away instead of waiting for its lifetime to expire and gets
+// its state afterwards. Names without database are qualified with the flowhouse database.
+func (c *ClickHouseGateway) ReloadDict(dictName string) *DictReload {
+	if !dictNamePattern.MatchString(dictName) {
+		return &DictReload{Dict: dictName, Error: "Invalid dict name"}
+	}
+
+	if !strings.Contains(dictName, ".") {
+		dictName = c.cfg.Database + "." + dictName
+	}
+
+	res := &DictReload{Dict: dictName}
+	_, err := c.db.Exec(c.reloadDictQuery(dictName))
+	c.dictSchemas.forget(dictName)
+	if err != nil {
+		res.Error = errors.Wrap(err, "Reload failed").Error()
+	}
+
+	parts := strings.SplitN(dictName, ".", 2)
+	rows, err := c.db.Query(fmt.Sprintf("SELECT toString(status), element_count, last_exception FROM system.dictionaries WHERE database = '%s' AND name = '%s'", parts[0], parts[1]))
+	if err != nil {
+		if res.Error == "" {
+			res.Error = errors.Wrap(err, "Unable to get status").Error()
+		}
+		return res
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if res.Error == "" {
+			res.Error = "Dict not found"
+		}
+		return res
+	}
+
+	lastException := ""
+	err = rows.Scan(&res.Status, &res.Elements, &lastException)
+	if err != nil && res.Error == "" {
+		res.Error = errors.Wrap(err, "Scan failed").Error()
+	}
+
+	if res.Error == "" {
+		res.Error = lastException
+	}
+
+	return res
+}
+
+func (c *ClickHouseGateway) reloadDictQuery(dictName string) string {
+	onClusterStatement := ""
+	if c.cfg.Sharded {
+		onClusterStatement = " ON CLUSTER " + c.cfg.Cluster
+	}
+
+	return fmt.Sprintf("SYSTEM RELOAD DICTIONARY%s %s", onClusterStatement, dictName)
+}
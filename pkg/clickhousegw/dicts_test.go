@@ -0,0 +1,153 @@
+package clickhousegw
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDictSchemas(t *testing.T) {
+	s := newDictSchemas(time.Minute)
+
+	loads := 0
+	attrs := []*DictAttribute{{Name: "name", Type: "String"}}
+	load := func() ([]*DictAttribute, error) {
+		loads++
+		return attrs, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		res, err := s.get("interfaces_dict", load)
+		assert.NoError(t, err)
+		assert.Equal(t, attrs, res)
+	}
+	assert.Equal(t, 1, loads)
+
+	s.entries["interfaces_dict"].expires = time.Now().Add(-time.Second)
+	_, err := s.get("interfaces_dict", load)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, loads)
+
+	_, err = s.get("sites_dict", func() ([]*DictAttribute, error) {
+		return nil, fmt.Errorf("Dict not found")
+	})
+	assert.Error(t, err)
+	assert.NotContains(t, s.entries, "sites_dict")
+
+	var none *dictSchemas
+	_, err = none.get("interfaces_dict", load)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, loads)
+}
+
+func TestDictAttributes(t *testing.T) {
+	res, err := dictAttributes([]string{"name", "speed", "site_id"}, []string{"String", "UInt64", "Nullable(Int32)"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*DictAttribute{
+		{Name: "name", Type: "String"},
+		{Name: "speed", Type: "UInt64"},
+		{Name: "site_id", Type: "Nullable(Int32)"},
+	}, res)
+
+	assert.False(t, res[0].IsNumeric())
+	assert.True(t, res[1].IsNumeric())
+	assert.True(t, res[2].IsNumeric())
+
+	_, err = dictAttributes([]string{"name"}, nil)
+	assert.Error(t, err)
+}
+
+func TestReloadDictQuery(t *testing.T) {
+	c := &ClickHouseGateway{cfg: &ClickhouseConfig{Database: "flows"}}
+	assert.Equal(t, "SYSTEM RELOAD DICTIONARY flows.interfaces_dict", c.reloadDictQuery("flows.interfaces_dict"))
+
+	c.cfg.Sharded = true
+	c.cfg.Cluster = "flows_cluster"
+	assert.Equal(t, "SYSTEM RELOAD DICTIONARY ON CLUSTER flows_cluster flows.interfaces_dict", c.reloadDictQuery("flows.interfaces_dict"))
+
+	for _, name := range []string{"", "interfaces_dict; DROP TABLE flows", "a.b.c"} {
+		assert.Equal(t, "Invalid dict name", c.ReloadDict(name).Error, name)
+	}
+}
+
+func TestDictSchemasForget(t *testing.T) {
+	s := newDictSchemas(time.Minute)
+	load := func() ([]*DictAttribute, error) {
+		return []*DictAttribute{{Name: "name", Type: "String"}}, nil
+	}
+
+	for _, name := range []string{"interfaces_dict", "flows.interfaces_dict", "sites"} {
+		_, err := s.get(name, load)
+		assert.NoError(t, err)
+	}
+
+	s.forget("flows.interfaces_dict")
+	assert.Equal(t, 1, len(s.entries))
+	assert.Contains(t, s.entries, "sites")
+}
+
+func TestValueScopeConditions(t *testing.T) {
+	attrs := []*DictAttribute{{Name: "name", Type: "String"}, {Name: "customer", Type: "String"}, {Name: "site_id", Type: "UInt64"}}
+
+	tests := []struct {
+		name     string
+		scope    ValueScope
+		expected []string
+	}{
+		{
+			name:     "One attribute",
+			scope:    ValueScope{"customer": {"acme", "o'neil"}},
+			expected: []string{`toString(customer) IN ('acme', 'o\'neil')`},
+		},
+		{
+			name:     "Several attributes",
+			scope:    ValueScope{"customer": {"acme"}, "site_id": {"7"}, "region": {"eu"}},
+			expected: []string{"toString(customer) IN ('acme')", "toString(site_id) IN ('7')"},
+		},
+		{
+			name:     "No values allowed",
+			scope:    ValueScope{"customer": {}},
+			expected: []string{"0"},
+		},
+		{
+			name:     "Dict out of scope",
+			scope:    ValueScope{"tenant": {"acme"}},
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.scope.conditions(attrs), test.name)
+	}
+}
+
+func TestDictSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *ClickhouseConfig
+		expected string
+	}{
+		{
+			name:     "Credentials",
+			cfg:      &ClickhouseConfig{User: "flowhouse", Password: "it's secret"},
+			expected: `SOURCE(CLICKHOUSE(USER 'flowhouse' PASSWORD 'it\'s secret' QUERY 'SELECT name FROM flows.agents'))`,
+		},
+		{
+			name:     "User without password",
+			cfg:      &ClickhouseConfig{User: "flowhouse", Password: "secret", DictUser: "dicts"},
+			expected: `SOURCE(CLICKHOUSE(USER 'dicts' QUERY 'SELECT name FROM flows.agents'))`,
+		},
+		{
+			name:     "Named collection",
+			cfg:      &ClickhouseConfig{User: "flowhouse", Password: "secret", DictUser: "dicts", DictNamedCollection: "flowhouse_dicts"},
+			expected: `SOURCE(CLICKHOUSE(NAME flowhouse_dicts QUERY 'SELECT name FROM flows.agents'))`,
+		},
+	}
+
+	for _, test := range tests {
+		c := &ClickHouseGateway{cfg: test.cfg}
+		assert.Equal(t, test.expected, c.dictSource("SELECT name FROM flows.agents"), test.name)
+	}
+}
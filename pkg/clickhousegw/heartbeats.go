@@ -0,0 +1,116 @@
+package clickhousegw
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/heartbeat"
+	"github.com/pkg/errors"
+)
+
+const (
+	heartbeatsTableName = "heartbeats"
+)
+
+// CreateHeartbeatsSchemaIfNotExists creates the heartbeats table
+func (c *ClickHouseGateway) CreateHeartbeatsSchemaIfNotExists() error {
+	_, err := c.db.Exec(c.getCreateHeartbeatsTableDDL())
+	if err != nil {
+		return errors.Wrap(err, "Unable to create heartbeats table")
+	}
+
+	return nil
+}
+
+func (c *ClickHouseGateway) getCreateHeartbeatsTableDDL() string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.%s (
+			timestamp DateTime,
+			instance  String,
+			listener  String,
+			agent     IPv6,
+			flows     UInt64
+		) ENGINE = MergeTree()
+		PARTITION BY toYYYYMM(timestamp)
+		ORDER BY (instance, listener, agent, timestamp)
+	`, c.cfg.Database, heartbeatsTableName)
+}
+
+// InsertHeartbeats inserts heartbeats
+func (c *ClickHouseGateway) InsertHeartbeats(heartbeats []*heartbeat.Heartbeat) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Begin failed")
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s.%s (
+		timestamp,
+		instance,
+		listener,
+		agent,
+		flows
+	) VALUES (?, ?, ?, ?, ?)`, c.cfg.Database, heartbeatsTableName))
+	if err != nil {
+		return errors.Wrap(err, "Prepare failed")
+	}
+	defer stmt.Close()
+
+	for _, h := range heartbeats {
+		agent := h.Agent.As16()
+		_, err := stmt.Exec(
+			h.Timestamp,
+			h.Instance,
+			h.Listener,
+			net.IP(agent[:]),
+			h.Flows,
+		)
+		if err != nil {
+			return errors.Wrap(err, "Exec failed")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Commit failed")
+	}
+
+	return nil
+}
+
+// GetHeartbeats gets the heartbeats from start to end ordered by instance, listener, agent and time
+func (c *ClickHouseGateway) GetHeartbeats(start time.Time, end time.Time) ([]*heartbeat.Heartbeat, error) {
+	rows, err := c.db.Query(fmt.Sprintf(`SELECT timestamp, instance, listener, agent, flows FROM %s.%s
+		WHERE timestamp >= toDateTime(%d) AND timestamp <= toDateTime(%d)
+		ORDER BY instance, listener, agent, timestamp`, c.cfg.Database, heartbeatsTableName, start.Unix(), end.Unix()))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	result := make([]*heartbeat.Heartbeat, 0)
+	for rows.Next() {
+		var addr net.IP
+		h := &heartbeat.Heartbeat{}
+		err := rows.Scan(&h.Timestamp, &h.Instance, &h.Listener, &addr, &h.Flows)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		agent, ok := netip.AddrFromSlice(addr.To16())
+		if !ok {
+			return nil, fmt.Errorf("Invalid agent address %q", addr)
+		}
+
+		// listener heartbeats keep the unspecified IPv6 address, agents are unmapped like everywhere else
+		if agent != netip.IPv6Unspecified() {
+			agent = agent.Unmap()
+		}
+		h.Agent = agent
+
+		result = append(result, h)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,193 @@
+package clickhousegw
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/bio-routing/flowhouse/pkg/models/hostcounter"
+	"github.com/pkg/errors"
+)
+
+const (
+	hostCountersTableName = "host_counters"
+)
+
+var hostCountersColumns = []string{
+	"timestamp",
+	"agent",
+	"source_index",
+	"parent_index",
+	"hostname",
+	"uuid",
+	"machine_type",
+	"os_name",
+	"os_release",
+	"load_one",
+	"load_five",
+	"load_fifteen",
+	"proc_run",
+	"proc_total",
+	"cpu_num",
+	"cpu_speed",
+	"uptime",
+	"cpu_user",
+	"cpu_nice",
+	"cpu_system",
+	"cpu_idle",
+	"cpu_wio",
+	"cpu_intr",
+	"cpu_sintr",
+	"interrupts",
+	"contexts",
+	"mem_total",
+	"mem_free",
+	"mem_shared",
+	"mem_buffers",
+	"mem_cached",
+	"swap_total",
+	"swap_free",
+	"page_in",
+	"page_out",
+	"swap_in",
+	"swap_out",
+	"virt_state",
+	"virt_cpu_time",
+	"virt_cpus",
+	"virt_memory",
+	"virt_max_memory",
+}
+
+// CreateHostCountersSchemaIfNotExists creates the host counters table
+func (c *ClickHouseGateway) CreateHostCountersSchemaIfNotExists() error {
+	_, err := c.db.Exec(c.getCreateHostCountersTableDDL())
+	if err != nil {
+		return errors.Wrap(err, "Unable to create host counters table")
+	}
+
+	return nil
+}
+
+func (c *ClickHouseGateway) getCreateHostCountersTableDDL() string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.%s (
+			timestamp       DateTime,
+			agent           IPv6,
+			source_index    UInt32,
+			parent_index    UInt32,
+			hostname        String,
+			uuid            String,
+			machine_type    LowCardinality(String),
+			os_name         LowCardinality(String),
+			os_release      LowCardinality(String),
+			load_one        Float32,
+			load_five       Float32,
+			load_fifteen    Float32,
+			proc_run        UInt32,
+			proc_total      UInt32,
+			cpu_num         UInt32,
+			cpu_speed       UInt32,
+			uptime          UInt32,
+			cpu_user        UInt32,
+			cpu_nice        UInt32,
+			cpu_system      UInt32,
+			cpu_idle        UInt32,
+			cpu_wio         UInt32,
+			cpu_intr        UInt32,
+			cpu_sintr       UInt32,
+			interrupts      UInt32,
+			contexts        UInt32,
+			mem_total       UInt64,
+			mem_free        UInt64,
+			mem_shared      UInt64,
+			mem_buffers     UInt64,
+			mem_cached      UInt64,
+			swap_total      UInt64,
+			swap_free       UInt64,
+			page_in         UInt32,
+			page_out        UInt32,
+			swap_in         UInt32,
+			swap_out        UInt32,
+			virt_state      LowCardinality(String),
+			virt_cpu_time   UInt32,
+			virt_cpus       UInt32,
+			virt_memory     UInt64,
+			virt_max_memory UInt64
+		) ENGINE = MergeTree()
+		PARTITION BY toYYYYMM(timestamp)
+		ORDER BY (agent, hostname, timestamp)
+	`, c.cfg.Database, hostCountersTableName)
+}
+
+// InsertHostCounters inserts host counter samples
+func (c *ClickHouseGateway) InsertHostCounters(samples []*hostcounter.Sample) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Begin failed")
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s.%s (%s) VALUES (%s)`, c.cfg.Database, hostCountersTableName,
+		strings.Join(hostCountersColumns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(hostCountersColumns)), ", ")))
+	if err != nil {
+		return errors.Wrap(err, "Prepare failed")
+	}
+	defer stmt.Close()
+
+	for _, s := range samples {
+		agent := s.Agent.As16()
+		_, err := stmt.Exec(
+			s.Timestamp,
+			net.IP(agent[:]),
+			s.SourceIndex,
+			s.ParentIndex,
+			s.Hostname,
+			s.UUID,
+			s.MachineType,
+			s.OSName,
+			s.OSRelease,
+			s.LoadOne,
+			s.LoadFive,
+			s.LoadFifteen,
+			s.ProcRun,
+			s.ProcTotal,
+			s.CPUNum,
+			s.CPUSpeed,
+			s.Uptime,
+			s.CPUUser,
+			s.CPUNice,
+			s.CPUSystem,
+			s.CPUIdle,
+			s.CPUWio,
+			s.CPUIntr,
+			s.CPUSoftIntr,
+			s.Interrupts,
+			s.Contexts,
+			s.MemTotal,
+			s.MemFree,
+			s.MemShared,
+			s.MemBuffers,
+			s.MemCached,
+			s.SwapTotal,
+			s.SwapFree,
+			s.PageIn,
+			s.PageOut,
+			s.SwapIn,
+			s.SwapOut,
+			s.VirtState,
+			s.VirtCPUTime,
+			s.VirtCPUs,
+			s.VirtMemory,
+			s.VirtMaxMemory,
+		)
+		if err != nil {
+			return errors.Wrap(err, "Exec failed")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Commit failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package clickhousegw
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostCountersColumns(t *testing.T) {
+	c := &ClickHouseGateway{cfg: &ClickhouseConfig{Database: "flows"}}
+	ddl := c.getCreateHostCountersTableDDL()
+
+	assert.True(t, strings.Contains(ddl, "flows.host_counters"))
+	for _, col := range hostCountersColumns {
+		assert.True(t, strings.Contains(ddl, "\t"+col+" "), "column %s not in DDL", col)
+	}
+}
@@ -0,0 +1,105 @@
+package clickhousegw
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/netif"
+	"github.com/pkg/errors"
+)
+
+const (
+	interfacesTableName = "interfaces"
+
+	// InterfacesDictName is the name of the dictionary mapping (agent, interface name) to interface meta data
+	InterfacesDictName = "interfaces_dict"
+)
+
+// CreateInterfacesSchemaIfNotExists creates the interfaces table and dictionary
+func (c *ClickHouseGateway) CreateInterfacesSchemaIfNotExists() error {
+	_, err := c.db.Exec(c.getCreateInterfacesTableDDL())
+	if err != nil {
+		return errors.Wrap(err, "Unable to create interfaces table")
+	}
+
+	_, err = c.db.Exec(c.getCreateInterfacesDictDDL())
+	if err != nil {
+		return errors.Wrap(err, "Unable to create interfaces dictionary")
+	}
+
+	return nil
+}
+
+func (c *ClickHouseGateway) getCreateInterfacesTableDDL() string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.%s (
+			agent       IPv6,
+			ifindex     UInt32,
+			name        String,
+			description String,
+			speed       UInt64,
+			updated     DateTime
+		) ENGINE = ReplacingMergeTree(updated)
+		ORDER BY (agent, name)
+		TTL updated + INTERVAL 1 DAY
+	`, c.cfg.Database, interfacesTableName)
+}
+
+func (c *ClickHouseGateway) getCreateInterfacesDictDDL() string {
+	return fmt.Sprintf(`
+		CREATE DICTIONARY IF NOT EXISTS %s.%s (
+			agent       String,
+			name        String,
+			ifindex     UInt32,
+			description String,
+			speed       UInt64
+		)
+		PRIMARY KEY agent, name
+		%s
+		LIFETIME(MIN 60 MAX 300)
+		LAYOUT(COMPLEX_KEY_HASHED())
+	`, c.cfg.Database, InterfacesDictName, c.dictSource(fmt.Sprintf("SELECT IPv6NumToString(agent) AS agent, name, ifindex, description, speed FROM %s.%s FINAL", c.cfg.Database, interfacesTableName)))
+}
+
+// InsertInterfaces inserts the current state of interfaces into clickhouse
+func (c *ClickHouseGateway) InsertInterfaces(interfaces []*netif.Interface) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Begin failed")
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s.%s (
+		agent,
+		ifindex,
+		name,
+		description,
+		speed,
+		updated
+	) VALUES (?, ?, ?, ?, ?, ?)`, c.cfg.Database, interfacesTableName))
+	if err != nil {
+		return errors.Wrap(err, "Prepare failed")
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, ifa := range interfaces {
+		_, err := stmt.Exec(
+			ifa.Agent.ToNetIP(),
+			ifa.Index,
+			ifa.Name,
+			ifa.Description,
+			ifa.Speed,
+			now,
+		)
+		if err != nil {
+			return errors.Wrap(err, "Exec failed")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Commit failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,5 @@
+package clickhousegw
+
+import "github.com/bio-routing/flowhouse/pkg/logging"
+
+var log = logging.Component(logging.Gateway)
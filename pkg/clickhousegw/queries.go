@@ -0,0 +1,114 @@
+package clickhousegw
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+
+	"github.com/bio-routing/flowhouse/pkg/requestid"
+	"github.com/pkg/errors"
+)
+
+// queryTag is the comment tagged queries start with. ClickHouse keeps comments in system.processes and
+// system.query_log, which makes up for the driver not supporting to set the query_id.
+const queryTag = "/* request_id: %s query_id: %s */ "
+
+var (
+	tagPattern     = regexp.MustCompile(`^/\* request_id: ([A-Za-z0-9._-]+) query_id: ([A-Za-z0-9._-]+) \*/ `)
+	queryIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}-[0-9a-f]{8}$`)
+)
+
+// RunningQuery is a query of the frontend running in ClickHouse
+type RunningQuery struct {
+	QueryID           string  `json:"query_id"`
+	RequestID         string  `json:"request_id"`
+	ClickHouseQueryID string  `json:"clickhouse_query_id"`
+	User              string  `json:"user"`
+	Elapsed           float64 `json:"elapsed"`
+	ReadRows          uint64  `json:"read_rows"`
+	ReadBytes         uint64  `json:"read_bytes"`
+	MemoryUsage       int64   `json:"memory_usage"`
+	Query             string  `json:"query"`
+}
+
+// QueryID gets the ID of query q of request requestID. It is deterministic, so a request running the same query
+// again (e.g. a retried scheduled query) gets the same ID.
+func QueryID(requestID string, q string) string {
+	h := fnv.New32a()
+	h.Write([]byte(q))
+
+	return fmt.Sprintf("%s-%08x", requestID, h.Sum32())
+}
+
+// ValidQueryID checks whether id is a query ID as generated by QueryID
+func ValidQueryID(id string) bool {
+	return queryIDPattern.MatchString(id)
+}
+
+func tagQuery(q string, requestID string, queryID string) string {
+	return fmt.Sprintf(queryTag, requestID, queryID) + q
+}
+
+// RunningQueries gets the tagged queries currently running
+func (c *ClickHouseGateway) RunningQueries() ([]*RunningQuery, error) {
+	// the query is not tagged itself, so it does not show up in its result
+	rows, err := c.db.Query("SELECT query_id, user, elapsed, read_rows, read_bytes, memory_usage, query FROM system.processes WHERE startsWith(query, '/* request_id: ') ORDER BY elapsed DESC")
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	res := make([]*RunningQuery, 0)
+	for rows.Next() {
+		q := &RunningQuery{}
+		err := rows.Scan(&q.ClickHouseQueryID, &q.User, &q.Elapsed, &q.ReadRows, &q.ReadBytes, &q.MemoryUsage, &q.Query)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		m := tagPattern.FindStringSubmatch(q.Query)
+		if m == nil {
+			continue
+		}
+
+		q.RequestID, q.QueryID = m[1], m[2]
+		q.Query = q.Query[len(m[0]):]
+		res = append(res, q)
+	}
+
+	return res, nil
+}
+
+// KillQuery kills the running query with the ID queryID and returns how many queries were killed
+func (c *ClickHouseGateway) KillQuery(queryID string) (int, error) {
+	if !ValidQueryID(queryID) {
+		return 0, fmt.Errorf("Invalid query ID %q", queryID)
+	}
+
+	return c.kill(fmt.Sprintf("position(query, ' query_id: %s */ ') > 0", queryID))
+}
+
+// KillRequest kills all running queries of request requestID and returns how many queries were killed
+func (c *ClickHouseGateway) KillRequest(requestID string) (int, error) {
+	if !requestid.Valid(requestID) {
+		return 0, fmt.Errorf("Invalid request ID %q", requestID)
+	}
+
+	return c.kill(fmt.Sprintf("startsWith(query, '/* request_id: %s query_id: ')", requestID))
+}
+
+// kill kills the queries of system.processes matching cond. The IDs in cond are validated, so they need no quoting.
+func (c *ClickHouseGateway) kill(cond string) (int, error) {
+	rows, err := c.db.Query(fmt.Sprintf("KILL QUERY WHERE %s ASYNC", cond))
+	if err != nil {
+		return 0, errors.Wrap(err, "Kill failed")
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		n++
+	}
+
+	return n, errors.Wrap(rows.Err(), "Kill failed")
+}
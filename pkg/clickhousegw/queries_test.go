@@ -0,0 +1,39 @@
+package clickhousegw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryID(t *testing.T) {
+	id := QueryID("3f2a9c1e8b7d6a50", "SELECT 1")
+	assert.Regexp(t, `^3f2a9c1e8b7d6a50-[0-9a-f]{8}$`, id)
+	assert.Equal(t, id, QueryID("3f2a9c1e8b7d6a50", "SELECT 1"))
+	assert.NotEqual(t, id, QueryID("3f2a9c1e8b7d6a50", "SELECT 2"))
+	assert.True(t, ValidQueryID(id))
+}
+
+func TestValidQueryID(t *testing.T) {
+	tests := []struct {
+		id       string
+		expected bool
+	}{
+		{id: "client-42-0123abcd", expected: true},
+		{id: "client-42", expected: false},
+		{id: "-0123abcd", expected: false},
+		{id: "x' OR 1=1 -- -0123abcd", expected: false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ValidQueryID(test.id), test.id)
+	}
+}
+
+func TestTagQuery(t *testing.T) {
+	q := tagQuery("SELECT 1", "abc", "abc-0123abcd")
+	assert.Equal(t, "/* request_id: abc query_id: abc-0123abcd */ SELECT 1", q)
+
+	m := tagPattern.FindStringSubmatch(q)
+	assert.Equal(t, []string{"/* request_id: abc query_id: abc-0123abcd */ ", "abc", "abc-0123abcd"}, m)
+}
@@ -0,0 +1,211 @@
+package clickhousegw
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultAnalyticsMinRange   = 86400
+	defaultHealthCheckInterval = 10
+	healthCheckTimeout         = 5 * time.Second
+
+	endpointPrimary = "primary"
+)
+
+var (
+	replicaHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "flowhouse",
+		Subsystem: "clickhouse",
+		Name:      "replica_healthy",
+		Help:      "Whether an analytics replica passed its last health check",
+	}, []string{"address"})
+	queriesRouted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "clickhouse",
+		Name:      "queries",
+		Help:      "Queries per endpoint, primary or the address of an analytics replica",
+	}, []string{"endpoint"})
+)
+
+// ReplicaConfig configures a ClickHouse endpoint heavy analytical queries are sent to. User, password and secure
+// default to the settings of the primary.
+type ReplicaConfig struct {
+	Address  string `yaml:"address"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Secure   *bool  `yaml:"secure"`
+
+	// HealthCheckInterval is the time in seconds between health checks, queries only go to healthy replicas
+	HealthCheckInterval uint64 `yaml:"health_check_interval"`
+}
+
+type analyticsKey struct{}
+
+// Analytical marks ctx so the queries run with it go to a healthy analytics replica if there is one
+func Analytical(ctx context.Context) context.Context {
+	return context.WithValue(ctx, analyticsKey{}, true)
+}
+
+func isAnalytical(ctx context.Context) bool {
+	v, _ := ctx.Value(analyticsKey{}).(bool)
+	return v
+}
+
+type replica struct {
+	cfg     *ReplicaConfig
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// replicas are the analytics replicas of a gateway
+type replicas struct {
+	replicas []*replica
+	next     atomic.Uint64
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// connectReplicas opens the replicas of cfg and starts checking their health. Replicas that are down at startup are
+// used once they pass a health check.
+func connectReplicas(cfg *ClickhouseConfig) (*replicas, error) {
+	if cfg.AnalyticsMinRange == 0 {
+		cfg.AnalyticsMinRange = defaultAnalyticsMinRange
+	}
+
+	rs := &replicas{
+		stopCh: make(chan struct{}),
+	}
+
+	for _, rc := range cfg.Replicas {
+		if rc.Address == "" {
+			rs.close()
+			return nil, fmt.Errorf("Replica address is missing")
+		}
+
+		if rc.User == "" {
+			rc.User = cfg.User
+			rc.Password = cfg.Password
+		}
+
+		if rc.Secure == nil {
+			rc.Secure = &cfg.Secure
+		}
+
+		if rc.HealthCheckInterval == 0 {
+			rc.HealthCheckInterval = defaultHealthCheckInterval
+		}
+
+		db, err := sql.Open("clickhouse", dsn(rc.Address, rc.User, rc.Password, cfg.Database, *rc.Secure))
+		if err != nil {
+			rs.close()
+			return nil, fmt.Errorf("Unable to open replica %s: %v", rc.Address, err)
+		}
+
+		r := &replica{
+			cfg: rc,
+			db:  db,
+		}
+		r.check()
+		rs.replicas = append(rs.replicas, r)
+	}
+
+	for _, r := range rs.replicas {
+		rs.wg.Add(1)
+		go rs.healthChecker(r)
+	}
+
+	return rs, nil
+}
+
+func (rs *replicas) healthChecker(r *replica) {
+	defer rs.wg.Done()
+
+	t := time.NewTicker(time.Duration(r.cfg.HealthCheckInterval) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-rs.stopCh:
+			return
+		case <-t.C:
+			r.check()
+		}
+	}
+}
+
+func (r *replica) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	err := r.db.PingContext(ctx)
+	if err != nil && r.healthy.Load() {
+		log.WithError(err).WithField("address", r.cfg.Address).Warning("Analytics replica failed health check")
+	}
+
+	if err == nil && !r.healthy.Load() {
+		log.WithField("address", r.cfg.Address).Info("Analytics replica is healthy")
+	}
+
+	r.healthy.Store(err == nil)
+	v := 0.0
+	if err == nil {
+		v = 1
+	}
+	replicaHealthy.WithLabelValues(r.cfg.Address).Set(v)
+}
+
+// pick gets the next healthy replica in turns, nil if there is none
+func (rs *replicas) pick() *replica {
+	n := len(rs.replicas)
+	start := rs.next.Add(1)
+	for i := 0; i < n; i++ {
+		r := rs.replicas[(start+uint64(i))%uint64(n)]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func (rs *replicas) close() {
+	close(rs.stopCh)
+	rs.wg.Wait()
+
+	for _, r := range rs.replicas {
+		r.db.Close()
+	}
+}
+
+// Route marks ctx as analytical if the query spans at least analytics_min_range seconds or is an export, and
+// replicas are configured
+func (c *ClickHouseGateway) Route(ctx context.Context, rangeSeconds int64, export bool) context.Context {
+	if c.replicas == nil || len(c.replicas.replicas) == 0 {
+		return ctx
+	}
+
+	if export || rangeSeconds >= int64(c.cfg.AnalyticsMinRange) {
+		return Analytical(ctx)
+	}
+
+	return ctx
+}
+
+// queryDB gets the database queries with ctx are run on and the name of its endpoint
+func (c *ClickHouseGateway) queryDB(ctx context.Context) (*sql.DB, string) {
+	if c.replicas != nil && isAnalytical(ctx) {
+		if r := c.replicas.pick(); r != nil {
+			return r.db, r.cfg.Address
+		}
+	}
+
+	return c.db, endpointPrimary
+}
@@ -0,0 +1,91 @@
+package clickhousegw
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoute(t *testing.T) {
+	tests := []struct {
+		name         string
+		replicas     *replicas
+		rangeSeconds int64
+		export       bool
+		expected     bool
+	}{
+		{
+			name:         "No replicas",
+			rangeSeconds: 7 * 86400,
+			export:       true,
+		},
+		{
+			name:         "Short range",
+			replicas:     &replicas{replicas: []*replica{{}}},
+			rangeSeconds: 3600,
+		},
+		{
+			name:         "Long range",
+			replicas:     &replicas{replicas: []*replica{{}}},
+			rangeSeconds: 86400,
+			expected:     true,
+		},
+		{
+			name:         "Export",
+			replicas:     &replicas{replicas: []*replica{{}}},
+			rangeSeconds: 60,
+			export:       true,
+			expected:     true,
+		},
+	}
+
+	for _, test := range tests {
+		c := &ClickHouseGateway{
+			cfg: &ClickhouseConfig{
+				AnalyticsMinRange: 86400,
+			},
+			replicas: test.replicas,
+		}
+
+		ctx := c.Route(context.Background(), test.rangeSeconds, test.export)
+		assert.Equal(t, test.expected, isAnalytical(ctx), test.name)
+	}
+}
+
+func TestQueryDB(t *testing.T) {
+	primary := &sql.DB{}
+	r1 := &replica{cfg: &ReplicaConfig{Address: "r1:9000"}, db: &sql.DB{}}
+	r2 := &replica{cfg: &ReplicaConfig{Address: "r2:9000"}, db: &sql.DB{}}
+	c := &ClickHouseGateway{
+		db: primary,
+		replicas: &replicas{
+			replicas: []*replica{r1, r2},
+		},
+	}
+
+	db, endpoint := c.queryDB(Analytical(context.Background()))
+	assert.Same(t, primary, db, "no healthy replica")
+	assert.Equal(t, endpointPrimary, endpoint)
+
+	r1.healthy.Store(true)
+	r2.healthy.Store(true)
+	endpoints := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		_, endpoint := c.queryDB(Analytical(context.Background()))
+		endpoints[endpoint]++
+	}
+	assert.Equal(t, map[string]int{"r1:9000": 2, "r2:9000": 2}, endpoints, "in turns")
+
+	r1.healthy.Store(false)
+	for i := 0; i < 2; i++ {
+		db, endpoint = c.queryDB(Analytical(context.Background()))
+		assert.Same(t, r2.db, db)
+		assert.Equal(t, "r2:9000", endpoint)
+	}
+
+	db, endpoint = c.queryDB(context.Background())
+	assert.Same(t, primary, db, "not analytical")
+	assert.Equal(t, endpointPrimary, endpoint)
+}
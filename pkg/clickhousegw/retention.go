@@ -0,0 +1,211 @@
+package clickhousegw
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FlowDeletion selects the flows to delete. A zero start or end leaves the time range open on that side, agents and
+// customers restrict the deletion to their flows if set.
+type FlowDeletion struct {
+	Start     time.Time
+	End       time.Time
+	Agents    []netip.Addr
+	Customers []string
+}
+
+// FlowDeletionResult is the outcome of a deletion
+type FlowDeletionResult struct {
+	// Rows is the number of flows matching the deletion
+	Rows uint64 `json:"rows"`
+
+	// DroppedPartitions is the number of partitions dropped as a whole
+	DroppedPartitions int `json:"dropped_partitions"`
+
+	// Mutation is set if the remaining flows are deleted by an ALTER TABLE ... DELETE mutation, which ClickHouse
+	// applies in the background
+	Mutation bool `json:"mutation"`
+}
+
+// condition gets the SQL condition matching the flows of d
+func (d *FlowDeletion) condition() string {
+	conditions := make([]string, 0)
+	if !d.Start.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("timestamp >= toDateTime(%d)", d.Start.Unix()))
+	}
+
+	if !d.End.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("timestamp < toDateTime(%d)", d.End.Unix()))
+	}
+
+	if len(d.Agents) > 0 {
+		agents := make([]string, 0, len(d.Agents))
+		for _, a := range d.Agents {
+			agents = append(agents, fmt.Sprintf("toIPv6('%s')", a.String()))
+		}
+		conditions = append(conditions, fmt.Sprintf("agent IN (%s)", strings.Join(agents, ", ")))
+	}
+
+	if len(d.Customers) > 0 {
+		customers := make([]string, 0, len(d.Customers))
+		for _, c := range d.Customers {
+			customers = append(customers, "'"+escapeString(c)+"'")
+		}
+		conditions = append(conditions, fmt.Sprintf("customer IN (%s)", strings.Join(customers, ", ")))
+	}
+
+	if len(conditions) == 0 {
+		return "1"
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+// timeRangeOnly checks if d selects flows by time only, so whole partitions within its range can be dropped
+func (d *FlowDeletion) timeRangeOnly() bool {
+	return len(d.Agents) == 0 && len(d.Customers) == 0
+}
+
+func escapeString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// CountFlows counts the flows matching d
+func (c *ClickHouseGateway) CountFlows(ctx context.Context, d *FlowDeletion) (uint64, error) {
+	rows, err := c.db.QueryContext(ctx, fmt.Sprintf("SELECT count() FROM %s.%s WHERE %s", c.cfg.Database, tableName, d.condition()))
+	if err != nil {
+		return 0, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	n := uint64(0)
+	if rows.Next() {
+		err := rows.Scan(&n)
+		if err != nil {
+			return 0, errors.Wrap(err, "Scan failed")
+		}
+	}
+
+	return n, nil
+}
+
+// DeleteFlows deletes the flows matching d and their rollups. Partitions holding only matching flows are dropped,
+// which frees their space immediately. The remaining flows are deleted by a mutation. Partitions are not dropped on
+// sharded setups as their parts are spread over the shards.
+func (c *ClickHouseGateway) DeleteFlows(ctx context.Context, d *FlowDeletion) (*FlowDeletionResult, error) {
+	n, err := c.CountFlows(ctx, d)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to count flows")
+	}
+
+	res := &FlowDeletionResult{
+		Rows: n,
+	}
+
+	// rollups outlive the raw flows, so they are deleted even if no raw flows are left
+	if c.cfg.Retention != nil {
+		_, err = c.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s DELETE WHERE %s", RollupTableName, d.condition()))
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to delete rollups")
+		}
+		res.Mutation = true
+	}
+
+	if n == 0 {
+		return res, nil
+	}
+
+	if d.timeRangeOnly() && !c.cfg.Sharded {
+		res.DroppedPartitions, err = c.dropPartitions(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.DroppedPartitions > 0 {
+			n, err = c.CountFlows(ctx, d)
+			if err != nil {
+				return nil, errors.Wrap(err, "Unable to count remaining flows")
+			}
+		}
+	}
+
+	if n == 0 {
+		return res, nil
+	}
+
+	onClusterStatement := ""
+	if c.cfg.Sharded {
+		onClusterStatement = " ON CLUSTER " + c.cfg.Cluster
+	}
+
+	_, err = c.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s%s DELETE WHERE %s", c.getBaseTableName(), onClusterStatement, d.condition()))
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to delete flows")
+	}
+	res.Mutation = true
+
+	return res, nil
+}
+
+// dropPartitions drops the partitions whose flows all lie within the time range of d
+func (c *ClickHouseGateway) dropPartitions(ctx context.Context, d *FlowDeletion) (int, error) {
+	// a partition spans several parts, so the time range of all its parts counts, not of one of them
+	rows, err := c.db.QueryContext(ctx, fmt.Sprintf("SELECT partition_id, toUnixTimestamp(min(min_time)), toUnixTimestamp(max(max_time)) FROM system.parts WHERE database = '%s' AND table = '%s' AND active GROUP BY partition_id",
+		escapeString(c.cfg.Database), tableName))
+	if err != nil {
+		return 0, errors.Wrap(err, "Unable to get partitions")
+	}
+
+	ranges := make([]partitionRange, 0)
+	for rows.Next() {
+		var r partitionRange
+		err := rows.Scan(&r.id, &r.minTime, &r.maxTime)
+		if err != nil {
+			rows.Close()
+			return 0, errors.Wrap(err, "Scan failed")
+		}
+
+		ranges = append(ranges, r)
+	}
+	rows.Close()
+
+	partitions := coveredPartitions(ranges, d)
+	for i, p := range partitions {
+		_, err := c.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s.%s DROP PARTITION ID '%s'", c.cfg.Database, tableName, escapeString(p)))
+		if err != nil {
+			return i, errors.Wrapf(err, "Unable to drop partition %s", p)
+		}
+	}
+
+	return len(partitions), nil
+}
+
+// partitionRange is the time range of the flows of a partition (unix time in seconds)
+type partitionRange struct {
+	id      string
+	minTime uint32
+	maxTime uint32
+}
+
+// coveredPartitions gets the IDs of the partitions whose flows all lie within the time range of d
+func coveredPartitions(ranges []partitionRange, d *FlowDeletion) []string {
+	res := make([]string, 0)
+	for _, r := range ranges {
+		if !d.Start.IsZero() && int64(r.minTime) < d.Start.Unix() {
+			continue
+		}
+
+		if !d.End.IsZero() && int64(r.maxTime) >= d.End.Unix() {
+			continue
+		}
+
+		res = append(res, r.id)
+	}
+
+	return res
+}
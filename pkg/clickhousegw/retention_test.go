@@ -0,0 +1,84 @@
+package clickhousegw
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlowDeletionCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		d        *FlowDeletion
+		expected string
+	}{
+		{
+			name:     "Empty",
+			d:        &FlowDeletion{},
+			expected: "1",
+		},
+		{
+			name: "Time range",
+			d: &FlowDeletion{
+				Start: time.Unix(1700000000, 0),
+				End:   time.Unix(1700003600, 0),
+			},
+			expected: "timestamp >= toDateTime(1700000000) AND timestamp < toDateTime(1700003600)",
+		},
+		{
+			name: "Agents and customers",
+			d: &FlowDeletion{
+				End:       time.Unix(1700003600, 0),
+				Agents:    []netip.Addr{netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1")},
+				Customers: []string{"acme", "o'reilly"},
+			},
+			expected: "timestamp < toDateTime(1700003600) AND agent IN (toIPv6('192.0.2.1'), toIPv6('2001:db8::1')) AND customer IN ('acme', 'o\\'reilly')",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.d.condition(), test.name)
+	}
+}
+
+func TestCoveredPartitions(t *testing.T) {
+	// partitions of ten minutes from 12:00, 12:10 and 12:20
+	ranges := []partitionRange{
+		{id: "1700049600", minTime: 1700049600, maxTime: 1700050199},
+		{id: "1700050200", minTime: 1700050200, maxTime: 1700050799},
+		{id: "1700050800", minTime: 1700050800, maxTime: 1700051399},
+	}
+
+	tests := []struct {
+		name     string
+		d        *FlowDeletion
+		expected []string
+	}{
+		{
+			name:     "Whole partitions",
+			d:        &FlowDeletion{Start: time.Unix(1700049600, 0), End: time.Unix(1700050800, 0)},
+			expected: []string{"1700049600", "1700050200"},
+		},
+		{
+			name:     "Partially covered partitions",
+			d:        &FlowDeletion{Start: time.Unix(1700049900, 0), End: time.Unix(1700051100, 0)},
+			expected: []string{"1700050200"},
+		},
+		{
+			name:     "Open start",
+			d:        &FlowDeletion{End: time.Unix(1700050500, 0)},
+			expected: []string{"1700049600"},
+		},
+		{
+			name:     "Within one partition",
+			d:        &FlowDeletion{Start: time.Unix(1700049700, 0), End: time.Unix(1700049800, 0)},
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, coveredPartitions(ranges, test.d), test.name)
+	}
+}
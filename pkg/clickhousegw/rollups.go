@@ -0,0 +1,209 @@
+package clickhousegw
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// RollupTableName is the table the rollups of the flows are kept in
+	RollupTableName = "flows_rollup"
+
+	rollupViewName        = "flows_rollup_mv"
+	defaultRawDays        = 14
+	defaultRollupDays     = 365
+	defaultRollupInterval = 300
+)
+
+// RetentionConfig configures how long raw flows and their rollups are kept
+type RetentionConfig struct {
+	// RawDays is the number of days raw flows are kept (default 14)
+	RawDays uint64 `yaml:"raw_days"`
+
+	// RollupDays is the number of days the rollups are kept (default 365)
+	RollupDays uint64 `yaml:"rollup_days"`
+
+	// RollupInterval is the resolution of the rollups in seconds (default 300). It is fixed once the rollups exist.
+	RollupInterval uint64 `yaml:"rollup_interval"`
+}
+
+func (c *RetentionConfig) loadDefaults() {
+	if c.RawDays == 0 {
+		c.RawDays = defaultRawDays
+	}
+
+	if c.RollupDays == 0 {
+		c.RollupDays = defaultRollupDays
+	}
+
+	if c.RollupInterval == 0 {
+		c.RollupInterval = defaultRollupInterval
+	}
+}
+
+type rollupColumn struct {
+	name string
+	typ  string
+}
+
+// rollupColumns are the columns of the flows table the rollups keep. Traffic is summed up per interval and
+// combination of their values, so columns with many distinct values like addresses and ports are left out.
+var rollupColumns = []rollupColumn{
+	{name: "agent", typ: "IPv6"},
+	{name: "int_in", typ: "String"},
+	{name: "int_out", typ: "String"},
+	{name: "src_asn", typ: "UInt32"},
+	{name: "dst_asn", typ: "UInt32"},
+	{name: "next_asn", typ: "UInt32"},
+	{name: "ip_protocol", typ: "UInt8"},
+	{name: "direction", typ: "LowCardinality(String)"},
+	{name: "customer", typ: "LowCardinality(String)"},
+	{name: "service", typ: "LowCardinality(String)"},
+	{name: "traffic_class", typ: "LowCardinality(String)"},
+}
+
+func init() {
+	for _, col := range enrichmentColumns {
+		rollupColumns = append(rollupColumns, rollupColumn{name: col, typ: enrichmentColumnType(true)})
+	}
+}
+
+// Rollups describes the rollups of the flows for the query planner
+type Rollups struct {
+	// Interval is the resolution of the rollups in seconds
+	Interval uint64
+
+	// RawDays is the number of days raw flows are kept
+	RawDays uint64
+}
+
+// HasColumn checks whether the rollups keep a column of the flows table
+func (r *Rollups) HasColumn(name string) bool {
+	for _, col := range rollupColumns {
+		if col.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Needed checks whether raw flows from start on may have expired at now, so only the rollups cover start
+func (r *Rollups) Needed(start time.Time, now time.Time) bool {
+	return start.Before(now.Add(-time.Duration(r.RawDays) * 24 * time.Hour))
+}
+
+// Rollups gets the rollups of the flows, nil if retention is not configured
+func (c *ClickHouseGateway) Rollups() *Rollups {
+	if c == nil || c.cfg.Retention == nil {
+		return nil
+	}
+
+	return &Rollups{
+		Interval: c.cfg.Retention.RollupInterval,
+		RawDays:  c.cfg.Retention.RawDays,
+	}
+}
+
+// rawTTL gets the TTL of the flows table
+func (c *ClickHouseGateway) rawTTL() string {
+	days := uint64(defaultRawDays)
+	if c.cfg.Retention != nil {
+		days = c.cfg.Retention.RawDays
+	}
+
+	return fmt.Sprintf("TTL timestamp + INTERVAL %d DAY", days)
+}
+
+// createRollupsIfNotExists creates the rollup table and the view filling it and applies the configured raw retention
+// to an existing flows table
+func (c *ClickHouseGateway) createRollupsIfNotExists() error {
+	if c.cfg.Sharded {
+		return fmt.Errorf("Rollups are not supported on sharded setups")
+	}
+
+	err := c.updateRawTTL()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Exec(c.getCreateRollupTableDDL())
+	if err != nil {
+		return errors.Wrap(err, "Unable to create rollup table")
+	}
+
+	_, err = c.db.Exec(c.getCreateRollupViewDDL())
+	if err != nil {
+		return errors.Wrap(err, "Unable to create rollup view")
+	}
+
+	return nil
+}
+
+// updateRawTTL changes the TTL of the flows table if it differs from raw_days
+func (c *ClickHouseGateway) updateRawTTL() error {
+	rows, err := c.db.Query(fmt.Sprintf("SELECT engine_full FROM system.tables WHERE database = '%s' AND name = '%s'", escapeString(c.cfg.Database), tableName))
+	if err != nil {
+		return errors.Wrap(err, "Unable to get flows table")
+	}
+
+	engine := ""
+	if rows.Next() {
+		err = rows.Scan(&engine)
+	}
+	rows.Close()
+	if err != nil {
+		return errors.Wrap(err, "Scan failed")
+	}
+
+	if strings.Contains(engine, fmt.Sprintf("TTL timestamp + toIntervalDay(%d)", c.cfg.Retention.RawDays)) {
+		return nil
+	}
+
+	_, err = c.db.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY %s", tableName, c.rawTTL()))
+	if err != nil {
+		return errors.Wrap(err, "Unable to change TTL of flows table")
+	}
+
+	log.WithField("raw_days", c.cfg.Retention.RawDays).Info("Changed TTL of the flows table")
+	return nil
+}
+
+func (c *ClickHouseGateway) getCreateRollupTableDDL() string {
+	columns := make([]string, 0, len(rollupColumns))
+	orderBy := make([]string, 0, len(rollupColumns))
+	for _, col := range rollupColumns {
+		columns = append(columns, fmt.Sprintf("%s %s", col.name, col.typ))
+		orderBy = append(orderBy, col.name)
+	}
+
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			timestamp DateTime,
+			%s,
+			bytes UInt64,
+			packets UInt64,
+			flows UInt64
+		) ENGINE = SummingMergeTree((bytes, packets, flows))
+		PARTITION BY toYYYYMMDD(timestamp)
+		ORDER BY (timestamp, %s)
+		TTL timestamp + INTERVAL %d DAY`,
+		RollupTableName, strings.Join(columns, ",\n\t\t\t"), strings.Join(orderBy, ", "), c.cfg.Retention.RollupDays)
+}
+
+func (c *ClickHouseGateway) getCreateRollupViewDDL() string {
+	names := make([]string, 0, len(rollupColumns))
+	for _, col := range rollupColumns {
+		names = append(names, col.name)
+	}
+	columns := strings.Join(names, ", ")
+
+	// the interval start is aliased in a subquery as it would shadow the timestamp column otherwise
+	return fmt.Sprintf(`CREATE MATERIALIZED VIEW IF NOT EXISTS %s TO %s AS
+		SELECT t AS timestamp, %s, sum(size * samplerate) AS bytes, sum(packets * samplerate) AS packets, count() AS flows
+		FROM (SELECT toStartOfInterval(timestamp, INTERVAL %d second) AS t, %s, size, packets, samplerate FROM %s)
+		GROUP BY t, %s`,
+		rollupViewName, RollupTableName, columns, c.cfg.Retention.RollupInterval, columns, tableName, columns)
+}
@@ -0,0 +1,50 @@
+package clickhousegw
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollupsDDL(t *testing.T) {
+	cfg := &RetentionConfig{}
+	cfg.loadDefaults()
+	c := &ClickHouseGateway{
+		cfg: &ClickhouseConfig{
+			Retention: cfg,
+		},
+	}
+
+	assert.Equal(t, "TTL timestamp + INTERVAL 14 DAY", c.rawTTL())
+
+	table := c.getCreateRollupTableDDL()
+	assert.True(t, strings.Contains(table, "ENGINE = SummingMergeTree((bytes, packets, flows))"), table)
+	assert.True(t, strings.Contains(table, "ORDER BY (timestamp, agent, int_in, int_out, src_asn"), table)
+	assert.True(t, strings.Contains(table, "TTL timestamp + INTERVAL 365 DAY"), table)
+
+	view := c.getCreateRollupViewDDL()
+	assert.True(t, strings.Contains(view, "TO flows_rollup"), view)
+	assert.True(t, strings.Contains(view, "toStartOfInterval(timestamp, INTERVAL 300 second) AS t"), view)
+	assert.True(t, strings.Contains(view, "agent_role, export_protocol, application, size, packets, samplerate FROM flows"), view)
+}
+
+func TestRollups(t *testing.T) {
+	c := &ClickHouseGateway{
+		cfg: &ClickhouseConfig{},
+	}
+	assert.Nil(t, c.Rollups())
+	assert.Equal(t, "TTL timestamp + INTERVAL 14 DAY", c.rawTTL())
+
+	c.cfg.Retention = &RetentionConfig{RawDays: 7, RollupInterval: 60}
+	r := c.Rollups()
+	assert.Equal(t, &Rollups{Interval: 60, RawDays: 7}, r)
+	assert.True(t, r.HasColumn("customer"))
+	assert.True(t, r.HasColumn("agent_site"))
+	assert.False(t, r.HasColumn("src_ip_addr"))
+
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.True(t, r.Needed(now.Add(-8*24*time.Hour), now))
+	assert.False(t, r.Needed(now.Add(-6*24*time.Hour), now))
+}
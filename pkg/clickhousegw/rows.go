@@ -0,0 +1,190 @@
+package clickhousegw
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Column is a column of a table
+type Column struct {
+	Name string
+	Type string
+}
+
+// GetColumns gets the columns of a table in their order
+func (c *ClickHouseGateway) GetColumns(table string) ([]*Column, error) {
+	table = strings.Replace(table, " ", "", -1)
+
+	rows, err := c.db.Query(fmt.Sprintf("SELECT name, type FROM system.columns WHERE database = '%s' AND table = '%s' ORDER BY position", c.cfg.Database, table))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	res := make([]*Column, 0)
+	for rows.Next() {
+		col := &Column{}
+		err := rows.Scan(&col.Name, &col.Type)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		res = append(res, col)
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("Table %q not found", table)
+	}
+
+	return res, nil
+}
+
+// InsertRows inserts rows of values (in the order of columns) into a table
+func (c *ClickHouseGateway) InsertRows(table string, columns []string, rows [][]interface{}) error {
+	return c.InsertRowsDedup(table, columns, rows, Dedup{})
+}
+
+// InsertRowsDedup inserts rows of values (in the order of columns) into a table deduplicated as given by d
+func (c *ClickHouseGateway) InsertRowsDedup(table string, columns []string, rows [][]interface{}, d Dedup) error {
+	settings, err := d.settings(c.cfg.Sharded)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Begin failed")
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (%s)%s VALUES (%s)", table, strings.Join(columns, ", "), settings, placeholders))
+	if err != nil {
+		return errors.Wrap(err, "Prepare failed")
+	}
+
+	for _, row := range rows {
+		_, err := stmt.Exec(row...)
+		if err != nil {
+			return errors.Wrap(err, "Exec failed")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Commit failed")
+	}
+
+	return nil
+}
+
+// FormatValue formats a value scanned from a query result
+func FormatValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case net.IP:
+		return x.String()
+	case time.Time:
+		return x.UTC().Format(time.RFC3339Nano)
+	case []byte:
+		return string(x)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// ParseValue parses a value formatted by FormatValue into the type expected by the driver for a column of type chType
+func ParseValue(chType string, s string) (interface{}, error) {
+	t := unwrapType(chType)
+	switch {
+	case t == "String" || strings.HasPrefix(t, "FixedString("):
+		return s, nil
+	case t == "IPv4" || t == "IPv6":
+		addr := net.ParseIP(s)
+		if addr == nil {
+			return nil, fmt.Errorf("Invalid address %q", s)
+		}
+
+		return addr, nil
+	case t == "DateTime" || strings.HasPrefix(t, "DateTime(") || strings.HasPrefix(t, "DateTime64("):
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid time %q", s)
+		}
+
+		return ts, nil
+	case strings.HasPrefix(t, "UInt"):
+		bits, err := strconv.Atoi(strings.TrimPrefix(t, "UInt"))
+		if err != nil {
+			break
+		}
+
+		n, err := strconv.ParseUint(s, 10, bits)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s %q", t, s)
+		}
+
+		switch bits {
+		case 8:
+			return uint8(n), nil
+		case 16:
+			return uint16(n), nil
+		case 32:
+			return uint32(n), nil
+		case 64:
+			return n, nil
+		}
+	case strings.HasPrefix(t, "Int"):
+		bits, err := strconv.Atoi(strings.TrimPrefix(t, "Int"))
+		if err != nil {
+			break
+		}
+
+		n, err := strconv.ParseInt(s, 10, bits)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s %q", t, s)
+		}
+
+		switch bits {
+		case 8:
+			return int8(n), nil
+		case 16:
+			return int16(n), nil
+		case 32:
+			return int32(n), nil
+		case 64:
+			return n, nil
+		}
+	case t == "Float32" || t == "Float64":
+		bits := 64
+		if t == "Float32" {
+			bits = 32
+		}
+
+		n, err := strconv.ParseFloat(s, bits)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s %q", t, s)
+		}
+
+		if bits == 32 {
+			return float32(n), nil
+		}
+		return n, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported type %q", chType)
+}
+
+// unwrapType removes LowCardinality() from a type
+func unwrapType(t string) string {
+	if strings.HasPrefix(t, "LowCardinality(") && strings.HasSuffix(t, ")") {
+		return strings.TrimSuffix(strings.TrimPrefix(t, "LowCardinality("), ")")
+	}
+
+	return t
+}
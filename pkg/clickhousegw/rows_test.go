@@ -0,0 +1,91 @@
+package clickhousegw
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		chType   string
+		value    string
+		expected interface{}
+		wantFail bool
+	}{
+		{
+			name:     "String",
+			chType:   "LowCardinality(String)",
+			value:    "web",
+			expected: "web",
+		},
+		{
+			name:     "IPv6",
+			chType:   "IPv6",
+			value:    "2001:db8::1",
+			expected: net.ParseIP("2001:db8::1"),
+		},
+		{
+			name:     "Invalid IPv6",
+			chType:   "IPv6",
+			value:    "foo",
+			wantFail: true,
+		},
+		{
+			name:     "UInt16",
+			chType:   "UInt16",
+			value:    "443",
+			expected: uint16(443),
+		},
+		{
+			name:     "UInt8 overflow",
+			chType:   "UInt8",
+			value:    "256",
+			wantFail: true,
+		},
+		{
+			name:     "DateTime64",
+			chType:   "DateTime64(3)",
+			value:    "2021-03-01T12:00:00.123Z",
+			expected: time.Date(2021, 3, 1, 12, 0, 0, 123000000, time.UTC),
+		},
+		{
+			name:     "Float64",
+			chType:   "Float64",
+			value:    "0.5",
+			expected: float64(0.5),
+		},
+		{
+			name:     "Unsupported",
+			chType:   "Array(String)",
+			value:    "[]",
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		v, err := ParseValue(test.chType, test.value)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, v, test.name)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	ts := time.Date(2021, 3, 1, 12, 0, 0, 123000000, time.UTC)
+	assert.Equal(t, "2021-03-01T12:00:00.123Z", FormatValue(ts))
+	assert.Equal(t, "192.0.2.1", FormatValue(net.ParseIP("192.0.2.1")))
+	assert.Equal(t, "443", FormatValue(uint16(443)))
+	assert.Equal(t, "", FormatValue(nil))
+
+	v, err := ParseValue("DateTime", FormatValue(ts))
+	assert.NoError(t, err)
+	assert.Equal(t, ts, v)
+}
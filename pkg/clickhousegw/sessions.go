@@ -0,0 +1,95 @@
+package clickhousegw
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/preferences"
+	"github.com/pkg/errors"
+)
+
+const (
+	sessionsTableName = "sessions"
+)
+
+// CreateSessionsSchemaIfNotExists creates the sessions table. Sessions not updated for a year are removed.
+func (c *ClickHouseGateway) CreateSessionsSchemaIfNotExists() error {
+	_, err := c.db.Exec(c.getCreateSessionsTableDDL())
+	if err != nil {
+		return errors.Wrap(err, "Unable to create sessions table")
+	}
+
+	return nil
+}
+
+func (c *ClickHouseGateway) getCreateSessionsTableDDL() string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.%s (
+			id          String,
+			preferences String,
+			updated     DateTime
+		) ENGINE = ReplacingMergeTree(updated)
+		ORDER BY (id)
+		TTL updated + INTERVAL 1 YEAR
+	`, c.cfg.Database, sessionsTableName)
+}
+
+// InsertSession inserts or updates the preferences of a session
+func (c *ClickHouseGateway) InsertSession(id string, p *preferences.Preferences) error {
+	j, err := json.Marshal(p)
+	if err != nil {
+		return errors.Wrap(err, "Unable to marshal preferences")
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Begin failed")
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s.%s (id, preferences, updated) VALUES (?, ?, ?)", c.cfg.Database, sessionsTableName))
+	if err != nil {
+		return errors.Wrap(err, "Prepare failed")
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(id, string(j), time.Now())
+	if err != nil {
+		return errors.Wrap(err, "Exec failed")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Commit failed")
+	}
+
+	return nil
+}
+
+// GetSessions gets the preferences of all stored sessions by session ID
+func (c *ClickHouseGateway) GetSessions() (map[string]*preferences.Preferences, error) {
+	rows, err := c.db.Query(fmt.Sprintf("SELECT id, preferences FROM %s.%s FINAL", c.cfg.Database, sessionsTableName))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	result := make(map[string]*preferences.Preferences)
+	for rows.Next() {
+		var id, j string
+		err := rows.Scan(&id, &j)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		p := &preferences.Preferences{}
+		err = json.Unmarshal([]byte(j), p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to unmarshal preferences of session %q", id)
+		}
+
+		result[id] = p
+	}
+
+	return result, nil
+}
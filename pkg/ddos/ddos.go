@@ -0,0 +1,401 @@
+// Package ddos detects volumetric attacks by rolling up the ingested flows per destination address
+package ddos
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/packet"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// SignatureTotal matches all traffic towards a destination
+	SignatureTotal = "total"
+
+	// SignatureUDPAmplification matches UDP traffic from ports of commonly abused reflection services
+	SignatureUDPAmplification = "udp_amplification"
+
+	// SignatureSYNFlood matches TCP packets with SYN but without ACK set
+	SignatureSYNFlood = "syn_flood"
+
+	defaultWindow        = 10
+	defaultExpireWindows = 6
+	defaultMaxIncidents  = 1000
+
+	// maxTrackedSources limits the memory used for counting unique sources per destination and window
+	maxTrackedSources = 65536
+
+	tcpFlagSYN = 0x02
+	tcpFlagACK = 0x10
+)
+
+// defaultAmplificationPorts are source ports of services commonly abused for reflection/amplification attacks
+var defaultAmplificationPorts = []uint16{
+	17,    // QOTD
+	19,    // chargen
+	53,    // DNS
+	111,   // portmap
+	123,   // NTP
+	137,   // NetBIOS
+	161,   // SNMP
+	389,   // CLDAP
+	1900,  // SSDP
+	3283,  // ARD
+	3702,  // WS-Discovery
+	5353,  // mDNS
+	11211, // memcached
+}
+
+// Config is the DDoS detectors configuration
+type Config struct {
+	// Window is the rollup interval in seconds. Rates are averaged over one window.
+	Window uint64 `yaml:"window"`
+
+	// ExpireWindows is the number of windows without exceeded threshold after which an incident ends
+	ExpireWindows uint64 `yaml:"expire_windows"`
+
+	// MaxIncidents is the number of incidents kept for the incidents API
+	MaxIncidents int `yaml:"max_incidents"`
+
+	// AmplificationPorts overrides the list of UDP source ports considered amplification traffic
+	AmplificationPorts []uint16 `yaml:"amplification_ports"`
+
+	Total            *Thresholds `yaml:"total"`
+	UDPAmplification *Thresholds `yaml:"udp_amplification"`
+	SYNFlood         *Thresholds `yaml:"syn_flood"`
+}
+
+// Thresholds are the limits per destination. A threshold of 0 is not checked.
+type Thresholds struct {
+	BPS           float64 `yaml:"bps"`
+	PPS           float64 `yaml:"pps"`
+	UniqueSources int     `yaml:"unique_sources"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Window == 0 {
+		c.Window = defaultWindow
+	}
+
+	if c.ExpireWindows == 0 {
+		c.ExpireWindows = defaultExpireWindows
+	}
+
+	if c.MaxIncidents == 0 {
+		c.MaxIncidents = defaultMaxIncidents
+	}
+
+	if len(c.AmplificationPorts) == 0 {
+		c.AmplificationPorts = defaultAmplificationPorts
+	}
+}
+
+// Incident is a detected attack on a destination
+type Incident struct {
+	ID                uint64     `json:"id"`
+	Destination       string     `json:"destination"`
+	Signature         string     `json:"signature"`
+	Reasons           []string   `json:"reasons"`
+	Start             time.Time  `json:"start"`
+	LastSeen          time.Time  `json:"last_seen"`
+	End               *time.Time `json:"end,omitempty"`
+	PeakBPS           float64    `json:"peak_bps"`
+	PeakPPS           float64    `json:"peak_pps"`
+	PeakUniqueSources int        `json:"peak_unique_sources"`
+}
+
+// Active checks if an incident is still ongoing
+func (i *Incident) Active() bool {
+	return i.End == nil
+}
+
+type counters struct {
+	bytes   uint64
+	packets uint64
+	sources map[netip.Addr]struct{}
+}
+
+func newCounters() *counters {
+	return &counters{
+		sources: make(map[netip.Addr]struct{}),
+	}
+}
+
+func (c *counters) add(fl *flow.Flow) {
+	c.bytes += fl.Size * fl.Samplerate
+	c.packets += fl.Packets * fl.Samplerate
+
+	if len(c.sources) < maxTrackedSources {
+		c.sources[fl.SrcAddr] = struct{}{}
+	}
+}
+
+type incidentKey struct {
+	dst       netip.Addr
+	signature string
+}
+
+// Detector rolls up flows per destination and window and raises incidents when thresholds are exceeded
+type Detector struct {
+	cfg         *Config
+	thresholds  map[string]*Thresholds
+	ampPorts    map[uint16]struct{}
+	windowStart int64
+	rollup      map[incidentKey]*counters
+	active      map[incidentKey]*Incident
+	incidents   []*Incident
+	lastID      uint64
+	mu          sync.RWMutex
+}
+
+// New creates a new DDoS detector
+func New(cfg *Config) (*Detector, error) {
+	cfg.loadDefaults()
+
+	d := &Detector{
+		cfg:        cfg,
+		thresholds: make(map[string]*Thresholds),
+		ampPorts:   make(map[uint16]struct{}),
+		rollup:     make(map[incidentKey]*counters),
+		active:     make(map[incidentKey]*Incident),
+	}
+
+	for sig, t := range map[string]*Thresholds{
+		SignatureTotal:            cfg.Total,
+		SignatureUDPAmplification: cfg.UDPAmplification,
+		SignatureSYNFlood:         cfg.SYNFlood,
+	} {
+		if t != nil {
+			d.thresholds[sig] = t
+		}
+	}
+
+	if len(d.thresholds) == 0 {
+		return nil, fmt.Errorf("No thresholds configured")
+	}
+
+	for _, p := range cfg.AmplificationPorts {
+		d.ampPorts[p] = struct{}{}
+	}
+
+	return d, nil
+}
+
+// Observe adds flows to the current window
+func (d *Detector) Observe(flows []*flow.Flow) {
+	d.observe(flows, time.Now())
+}
+
+func (d *Detector) observe(flows []*flow.Flow, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	windowStart := now.Unix() - now.Unix()%int64(d.cfg.Window)
+	if windowStart > d.windowStart {
+		if d.windowStart != 0 {
+			d.evaluate(time.Unix(d.windowStart+int64(d.cfg.Window), 0))
+		}
+
+		d.windowStart = windowStart
+		d.rollup = make(map[incidentKey]*counters)
+	}
+
+	for _, fl := range flows {
+		for _, sig := range d.signatures(fl) {
+			if _, enabled := d.thresholds[sig]; !enabled {
+				continue
+			}
+
+			k := incidentKey{
+				dst:       fl.DstAddr,
+				signature: sig,
+			}
+
+			c, exists := d.rollup[k]
+			if !exists {
+				c = newCounters()
+				d.rollup[k] = c
+			}
+
+			c.add(fl)
+		}
+	}
+}
+
+// signatures returns the signatures a flow matches
+func (d *Detector) signatures(fl *flow.Flow) []string {
+	res := []string{SignatureTotal}
+
+	switch fl.Protocol {
+	case packet.UDP:
+		if _, ok := d.ampPorts[fl.SrcPort]; ok {
+			res = append(res, SignatureUDPAmplification)
+		}
+	case packet.TCP:
+		if fl.TCPFlags&tcpFlagSYN != 0 && fl.TCPFlags&tcpFlagACK == 0 {
+			res = append(res, SignatureSYNFlood)
+		}
+	}
+
+	return res
+}
+
+// evaluate checks the finished window against the thresholds. It has to be called with d.mu held.
+func (d *Detector) evaluate(windowEnd time.Time) {
+	window := float64(d.cfg.Window)
+	for k, c := range d.rollup {
+		bps := float64(c.bytes) * 8 / window
+		pps := float64(c.packets) / window
+		reasons := d.thresholds[k.signature].exceeded(bps, pps, len(c.sources))
+		if len(reasons) == 0 {
+			continue
+		}
+
+		inc, exists := d.active[k]
+		if !exists {
+			d.lastID++
+			inc = &Incident{
+				ID:          d.lastID,
+				Destination: k.dst.String(),
+				Signature:   k.signature,
+				Start:       windowEnd.Add(-time.Duration(d.cfg.Window) * time.Second),
+			}
+			d.active[k] = inc
+			d.addIncident(inc)
+
+			log.WithFields(log.Fields{
+				"incident":    inc.ID,
+				"destination": inc.Destination,
+				"signature":   inc.Signature,
+				"reasons":     reasons,
+				"bps":         bps,
+				"pps":         pps,
+				"sources":     len(c.sources),
+			}).Warning("DDoS incident started")
+		}
+
+		inc.LastSeen = windowEnd
+		inc.Reasons = mergeReasons(inc.Reasons, reasons)
+		if bps > inc.PeakBPS {
+			inc.PeakBPS = bps
+		}
+
+		if pps > inc.PeakPPS {
+			inc.PeakPPS = pps
+		}
+
+		if len(c.sources) > inc.PeakUniqueSources {
+			inc.PeakUniqueSources = len(c.sources)
+		}
+	}
+
+	expiry := time.Duration(d.cfg.ExpireWindows*d.cfg.Window) * time.Second
+	for k, inc := range d.active {
+		if windowEnd.Sub(inc.LastSeen) < expiry {
+			continue
+		}
+
+		end := inc.LastSeen
+		inc.End = &end
+		delete(d.active, k)
+
+		log.WithFields(log.Fields{
+			"incident":    inc.ID,
+			"destination": inc.Destination,
+			"signature":   inc.Signature,
+		}).Info("DDoS incident ended")
+	}
+}
+
+func (d *Detector) addIncident(inc *Incident) {
+	d.incidents = append(d.incidents, inc)
+	if len(d.incidents) <= d.cfg.MaxIncidents {
+		return
+	}
+
+	// drop the oldest finished incident
+	for i, x := range d.incidents {
+		if !x.Active() {
+			d.incidents = append(d.incidents[:i], d.incidents[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *Thresholds) exceeded(bps float64, pps float64, sources int) []string {
+	res := make([]string, 0)
+	if t.BPS > 0 && bps > t.BPS {
+		res = append(res, "bps")
+	}
+
+	if t.PPS > 0 && pps > t.PPS {
+		res = append(res, "pps")
+	}
+
+	if t.UniqueSources > 0 && sources > t.UniqueSources {
+		res = append(res, "unique_sources")
+	}
+
+	return res
+}
+
+func mergeReasons(a []string, b []string) []string {
+	for _, x := range b {
+		found := false
+		for _, y := range a {
+			if x == y {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			a = append(a, x)
+		}
+	}
+
+	return a
+}
+
+// Incidents returns copies of all known incidents, most recent first. If activeOnly is set, finished incidents are omitted.
+func (d *Detector) Incidents(activeOnly bool) []*Incident {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	res := make([]*Incident, 0, len(d.incidents))
+	for _, inc := range d.incidents {
+		if activeOnly && !inc.Active() {
+			continue
+		}
+
+		c := *inc
+		c.Reasons = append([]string{}, inc.Reasons...)
+		res = append(res, &c)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID > res[j].ID
+	})
+
+	return res
+}
+
+// Handler serves the incidents as JSON. With ?active=true only ongoing incidents are returned.
+func (d *Detector) Handler(w http.ResponseWriter, r *http.Request) {
+	j, err := json.Marshal(d.Incidents(r.URL.Query().Get("active") == "true"))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
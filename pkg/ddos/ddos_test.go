@@ -0,0 +1,110 @@
+package ddos
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatures(t *testing.T) {
+	d, err := New(&Config{
+		Total: &Thresholds{},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		fl       *flow.Flow
+		expected []string
+	}{
+		{
+			name:     "NTP reflection",
+			fl:       &flow.Flow{Protocol: packet.UDP, SrcPort: 123, DstPort: 40000},
+			expected: []string{SignatureTotal, SignatureUDPAmplification},
+		},
+		{
+			name:     "Regular UDP",
+			fl:       &flow.Flow{Protocol: packet.UDP, SrcPort: 40000, DstPort: 123},
+			expected: []string{SignatureTotal},
+		},
+		{
+			name:     "SYN",
+			fl:       &flow.Flow{Protocol: packet.TCP, TCPFlags: tcpFlagSYN},
+			expected: []string{SignatureTotal, SignatureSYNFlood},
+		},
+		{
+			name:     "SYN ACK",
+			fl:       &flow.Flow{Protocol: packet.TCP, TCPFlags: tcpFlagSYN | tcpFlagACK},
+			expected: []string{SignatureTotal},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, d.signatures(test.fl), test.name)
+	}
+}
+
+func TestIncidentLifecycle(t *testing.T) {
+	d, err := New(&Config{
+		Window:        10,
+		ExpireWindows: 2,
+		UDPAmplification: &Thresholds{
+			PPS:           1000,
+			UniqueSources: 2,
+		},
+	})
+	assert.NoError(t, err)
+
+	victim := netip.AddrFrom4([4]byte{192, 0, 2, 1})
+	attack := func(sources int) []*flow.Flow {
+		res := make([]*flow.Flow, 0, sources)
+		for i := 0; i < sources; i++ {
+			res = append(res, &flow.Flow{
+				SrcAddr:    netip.AddrFrom4([4]byte{198, 51, 100, uint8(i)}),
+				DstAddr:    victim,
+				Protocol:   packet.UDP,
+				SrcPort:    53,
+				Packets:    1,
+				Size:       1500,
+				Samplerate: 10000,
+			})
+		}
+		return res
+	}
+
+	start := time.Unix(1000, 0)
+	d.observe(attack(3), start)
+	assert.Len(t, d.Incidents(false), 0)
+
+	// the first window is evaluated when the next one starts
+	d.observe(attack(1), start.Add(10*time.Second))
+	incidents := d.Incidents(true)
+	assert.Len(t, incidents, 1)
+	assert.Equal(t, "192.0.2.1", incidents[0].Destination)
+	assert.Equal(t, SignatureUDPAmplification, incidents[0].Signature)
+	assert.Equal(t, []string{"pps", "unique_sources"}, incidents[0].Reasons)
+	assert.Equal(t, float64(3000), incidents[0].PeakPPS)
+	assert.Equal(t, 3, incidents[0].PeakUniqueSources)
+	assert.Equal(t, start, incidents[0].Start)
+
+	// 1000pps does not exceed the threshold, the incident expires after two quiet windows
+	d.observe(nil, start.Add(20*time.Second))
+	assert.Len(t, d.Incidents(true), 1)
+
+	d.observe(nil, start.Add(30*time.Second))
+	d.observe(nil, start.Add(40*time.Second))
+	assert.Len(t, d.Incidents(true), 0)
+
+	incidents = d.Incidents(false)
+	assert.Len(t, incidents, 1)
+	assert.Equal(t, start.Add(10*time.Second), *incidents[0].End)
+}
+
+func TestNewWithoutThresholds(t *testing.T) {
+	_, err := New(&Config{})
+	assert.Error(t, err)
+}
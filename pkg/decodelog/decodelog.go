@@ -0,0 +1,190 @@
+// Package decodelog keeps the most recent datagrams that failed to decode for offline debugging
+package decodelog
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultSize is the default number of datagrams kept per agent
+	DefaultSize = 16
+
+	// ProtocolSFlow denotes sFlow datagrams
+	ProtocolSFlow = "sflow"
+
+	// ProtocolIPFIX denotes IPFIX datagrams
+	ProtocolIPFIX = "ipfix"
+)
+
+var decodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "flowhouse",
+	Subsystem: "decoder",
+	Name:      "failed_datagrams",
+	Help:      "Datagrams that failed to decode",
+}, []string{"protocol", "agent"})
+
+// Entry is a datagram that failed to decode
+type Entry struct {
+	Time     time.Time
+	Protocol string
+	Agent    bnet.IP
+	SrcPort  uint16
+	Data     []byte
+	Error    string
+}
+
+type ring struct {
+	entries []*Entry
+	next    int
+}
+
+func (r *ring) add(e *Entry) {
+	if len(r.entries) < cap(r.entries) {
+		r.entries = append(r.entries, e)
+		return
+	}
+
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+}
+
+// list returns the entries oldest first
+func (r *ring) list() []*Entry {
+	res := make([]*Entry, 0, len(r.entries))
+	res = append(res, r.entries[r.next:]...)
+	res = append(res, r.entries[:r.next]...)
+	return res
+}
+
+// Log keeps a bounded ring of failed datagrams per agent
+type Log struct {
+	size  int
+	rings map[bnet.IP]*ring
+	mu    sync.Mutex
+}
+
+// New creates a new log keeping size datagrams per agent
+func New(size int) *Log {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	return &Log{
+		size:  size,
+		rings: make(map[bnet.IP]*ring),
+	}
+}
+
+// Add records a datagram from agent that failed to decode. data is copied.
+func (l *Log) Add(protocol string, agent bnet.IP, srcPort uint16, data []byte, err error) {
+	decodeErrors.WithLabelValues(protocol, agent.String()).Inc()
+
+	e := &Entry{
+		Time:     time.Now(),
+		Protocol: protocol,
+		Agent:    agent,
+		SrcPort:  srcPort,
+		Data:     make([]byte, len(data)),
+	}
+	copy(e.Data, data)
+
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, exists := l.rings[agent]
+	if !exists {
+		r = &ring{
+			entries: make([]*Entry, 0, l.size),
+		}
+		l.rings[agent] = r
+	}
+
+	r.add(e)
+}
+
+// Entries returns all recorded entries (optionally only those of agent) ordered by time
+func (l *Log) Entries(agent *bnet.IP) []*Entry {
+	l.mu.Lock()
+	res := make([]*Entry, 0)
+	for a, r := range l.rings {
+		if agent != nil && a != *agent {
+			continue
+		}
+
+		res = append(res, r.list()...)
+	}
+	l.mu.Unlock()
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Time.Before(res[j].Time)
+	})
+
+	return res
+}
+
+type entryView struct {
+	Time     time.Time `json:"time"`
+	Protocol string    `json:"protocol"`
+	Agent    string    `json:"agent"`
+	Length   int       `json:"length"`
+	Error    string    `json:"error"`
+}
+
+// Handler serves the recorded datagrams as pcap file (or as JSON summary with format=json).
+// The agent parameter restricts the result to a single agent.
+func (l *Log) Handler(w http.ResponseWriter, r *http.Request) {
+	var agent *bnet.IP
+	if a := r.URL.Query().Get("agent"); a != "" {
+		addr, err := bnet.IPFromString(a)
+		if err != nil {
+			http.Error(w, "Invalid agent", http.StatusBadRequest)
+			return
+		}
+		agent = &addr
+	}
+
+	entries := l.Entries(agent)
+	if r.URL.Query().Get("format") == "json" {
+		views := make([]entryView, 0, len(entries))
+		for _, e := range entries {
+			views = append(views, entryView{
+				Time:     e.Time,
+				Protocol: e.Protocol,
+				Agent:    e.Agent.String(),
+				Length:   len(e.Data),
+				Error:    e.Error,
+			})
+		}
+
+		j, err := json.Marshal(views)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(j)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"decode_errors.pcap\"")
+	err := writePcap(w, entries)
+	if err != nil {
+		log.WithError(err).Warning("Unable to write pcap")
+	}
+}
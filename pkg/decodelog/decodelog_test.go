@@ -0,0 +1,66 @@
+package decodelog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestRing(t *testing.T) {
+	l := New(2)
+	a := bnet.IPv4FromOctets(192, 0, 2, 1)
+	b := bnet.IPv4FromOctets(192, 0, 2, 2)
+
+	for i := 0; i < 3; i++ {
+		l.Add(ProtocolSFlow, a, 1234, []byte{byte(i)}, fmt.Errorf("error %d", i))
+	}
+	l.Add(ProtocolIPFIX, b, 1234, []byte{42}, nil)
+
+	entries := l.Entries(&a)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, []byte{1}, entries[0].Data)
+	assert.Equal(t, []byte{2}, entries[1].Data)
+	assert.Equal(t, "error 2", entries[1].Error)
+
+	assert.Len(t, l.Entries(nil), 3)
+}
+
+func TestAddCopiesData(t *testing.T) {
+	l := New(1)
+	a := bnet.IPv4FromOctets(192, 0, 2, 1)
+
+	data := []byte{1, 2, 3}
+	l.Add(ProtocolSFlow, a, 1234, data, nil)
+	data[0] = 42
+
+	assert.Equal(t, []byte{1, 2, 3}, l.Entries(nil)[0].Data)
+}
+
+func TestPcapHandler(t *testing.T) {
+	l := New(4)
+	l.Add(ProtocolSFlow, bnet.IPv4FromOctets(192, 0, 2, 1), 50000, []byte{0, 0, 0, 5}, nil)
+
+	rec := httptest.NewRecorder()
+	l.Handler(rec, httptest.NewRequest(http.MethodGet, "/debug/decode_errors", nil))
+
+	data := rec.Body.Bytes()
+	assert.Equal(t, uint32(pcapMagic), binary.LittleEndian.Uint32(data[0:]))
+	assert.Equal(t, uint32(linkTypeRaw), binary.LittleEndian.Uint32(data[20:]))
+
+	// record header followed by IPv4 + UDP header + payload
+	assert.Equal(t, uint32(20+8+4), binary.LittleEndian.Uint32(data[24+8:]))
+	pkt := data[24+16:]
+	assert.Equal(t, byte(0x45), pkt[0])
+	assert.Equal(t, []byte{192, 0, 2, 1}, pkt[12:16])
+	assert.Equal(t, uint16(0), ipv4Checksum(pkt[:20]), "checksum has to verify")
+	assert.Equal(t, uint16(50000), binary.BigEndian.Uint16(pkt[20:]))
+	assert.Equal(t, uint16(sflowPort), binary.BigEndian.Uint16(pkt[22:]))
+	assert.True(t, bytes.Equal([]byte{0, 0, 0, 5}, pkt[28:]))
+}
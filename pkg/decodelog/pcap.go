@@ -0,0 +1,105 @@
+package decodelog
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	pcapMagic      = 0xa1b2c3d4
+	pcapSnapLength = 65535
+	linkTypeRaw    = 101
+
+	sflowPort = 6343
+	ipfixPort = 4739
+
+	udpProtocol = 17
+)
+
+// writePcap writes entries as pcap file. As only the UDP payload is known the IP and UDP headers are synthesized
+// (agent as source, the protocols well known port as destination) so tools like Wireshark dissect the datagrams.
+func writePcap(w io.Writer, entries []*Entry) error {
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(hdr[4:], 2)
+	binary.LittleEndian.PutUint16(hdr[6:], 4)
+	binary.LittleEndian.PutUint32(hdr[16:], pcapSnapLength)
+	binary.LittleEndian.PutUint32(hdr[20:], linkTypeRaw)
+
+	_, err := w.Write(hdr)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		pkt := synthesizePacket(e)
+
+		rec := make([]byte, 16)
+		binary.LittleEndian.PutUint32(rec[0:], uint32(e.Time.Unix()))
+		binary.LittleEndian.PutUint32(rec[4:], uint32(e.Time.Nanosecond()/1000))
+		binary.LittleEndian.PutUint32(rec[8:], uint32(len(pkt)))
+		binary.LittleEndian.PutUint32(rec[12:], uint32(len(pkt)))
+
+		_, err = w.Write(rec)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(pkt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func synthesizePacket(e *Entry) []byte {
+	dstPort := uint16(ipfixPort)
+	if e.Protocol == ProtocolSFlow {
+		dstPort = sflowPort
+	}
+
+	udp := make([]byte, 8)
+	binary.BigEndian.PutUint16(udp[0:], e.SrcPort)
+	binary.BigEndian.PutUint16(udp[2:], dstPort)
+	binary.BigEndian.PutUint16(udp[4:], uint16(8+len(e.Data)))
+
+	var ip []byte
+	if e.Agent.IsIPv4() {
+		ip = make([]byte, 20)
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:], uint16(20+8+len(e.Data)))
+		ip[8] = 64
+		ip[9] = udpProtocol
+		copy(ip[12:16], e.Agent.Bytes())
+		// destination is left at 0.0.0.0 as the local address is unknown
+		binary.BigEndian.PutUint16(ip[10:], ipv4Checksum(ip))
+	} else {
+		ip = make([]byte, 40)
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:], uint16(8+len(e.Data)))
+		ip[6] = udpProtocol
+		ip[7] = 64
+		copy(ip[8:24], e.Agent.Bytes())
+	}
+
+	pkt := make([]byte, 0, len(ip)+len(udp)+len(e.Data))
+	pkt = append(pkt, ip...)
+	pkt = append(pkt, udp...)
+	pkt = append(pkt, e.Data...)
+	return pkt
+}
+
+func ipv4Checksum(hdr []byte) uint16 {
+	sum := uint32(0)
+	for i := 0; i < len(hdr); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(hdr[i:]))
+	}
+
+	for sum > 0xffff {
+		sum = (sum & 0xffff) + sum>>16
+	}
+
+	return ^uint16(sum)
+}
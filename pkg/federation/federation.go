@@ -0,0 +1,370 @@
+// Package federation fans queries out to several flowhouse instances, e.g. one per region, and merges their results
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// StatusOK is the status of a backend that answered the query
+	StatusOK = "ok"
+
+	// StatusFailed is the status of a backend that failed or timed out
+	StatusFailed = "failed"
+
+	// splitParameter prefixes each series with the name of its backend if set to true
+	splitParameter = "split_by_backend"
+
+	defaultTimeout = 30
+)
+
+// Config configures the federation
+type Config struct {
+	// Timeout is the time in seconds a backend may take to answer a query
+	Timeout uint64 `yaml:"timeout"`
+
+	// Local is the name this instance is queried under, it is not queried if empty
+	Local string `yaml:"local"`
+
+	// Backends are the remote flowhouse instances
+	Backends []*BackendConfig `yaml:"backends"`
+}
+
+// BackendConfig configures a remote flowhouse instance
+type BackendConfig struct {
+	Name string `yaml:"name"`
+
+	// URL is the base URL of the instance, e.g. http://flowhouse-eu:9991
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Timeout == 0 {
+		c.Timeout = defaultTimeout
+	}
+}
+
+func (c *Config) validate() error {
+	names := make(map[string]struct{})
+	if c.Local != "" {
+		names[c.Local] = struct{}{}
+	}
+
+	for _, b := range c.Backends {
+		if b.Name == "" {
+			return fmt.Errorf("Backend name is missing")
+		}
+
+		if _, exists := names[b.Name]; exists {
+			return fmt.Errorf("Duplicate backend %q", b.Name)
+		}
+		names[b.Name] = struct{}{}
+
+		u, err := url.Parse(b.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("Backend %q: invalid URL %q", b.Name, b.URL)
+		}
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("No backends configured")
+	}
+
+	return nil
+}
+
+// Querier runs a query given as parameters of /query, e.g. the local frontend
+type Querier interface {
+	RunQuery(ctx context.Context, fields url.Values) (*frontend.QueryResult, error)
+}
+
+// Backend is a flowhouse instance queries are fanned out to
+type Backend interface {
+	Name() string
+	Querier
+}
+
+// Result is a merged query result with the status of each backend
+type Result struct {
+	*frontend.QueryResult
+
+	// Partial is set if some backends failed and the result only covers the others
+	Partial  bool             `json:"partial"`
+	Backends []*BackendStatus `json:"backends"`
+}
+
+// BackendStatus is the outcome of a query on a backend
+type BackendStatus struct {
+	Name            string  `json:"name"`
+	Status          string  `json:"status"`
+	Error           string  `json:"error,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Series          int     `json:"series"`
+}
+
+// Federation queries all backends
+type Federation struct {
+	cfg      *Config
+	backends []Backend
+}
+
+// New creates a federation of the configured backends and local if cfg.Local is set
+func New(cfg *Config, local Querier) (*Federation, error) {
+	cfg.loadDefaults()
+	err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Federation{
+		cfg:      cfg,
+		backends: make([]Backend, 0, len(cfg.Backends)+1),
+	}
+
+	if cfg.Local != "" {
+		f.backends = append(f.backends, &localBackend{
+			name:    cfg.Local,
+			querier: local,
+		})
+	}
+
+	for _, b := range cfg.Backends {
+		f.backends = append(f.backends, newRemoteBackend(b))
+	}
+
+	return f, nil
+}
+
+// Query runs a query on all backends in parallel and merges the results. Series with the same name are summed up
+// unless split_by_backend is set.
+func (f *Federation) Query(ctx context.Context, fields url.Values) *Result {
+	split := fields.Get(splitParameter) == "true"
+	share := frontend.ShareRequested(fields)
+	fields = cloneValues(fields)
+	fields.Del(splitParameter)
+
+	// shares of the results of single backends can not be merged, so the merged rates are normalized
+	fields.Del("normalize")
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(f.cfg.Timeout)*time.Second)
+	defer cancel()
+
+	results := make([]*frontend.QueryResult, len(f.backends))
+	statuses := make([]*BackendStatus, len(f.backends))
+	wg := sync.WaitGroup{}
+	for i, b := range f.backends {
+		wg.Add(1)
+		go func(i int, b Backend) {
+			defer wg.Done()
+
+			start := time.Now()
+			res, err := b.RunQuery(ctx, fields)
+			statuses[i] = &BackendStatus{
+				Name:            b.Name(),
+				Status:          StatusOK,
+				DurationSeconds: time.Since(start).Seconds(),
+			}
+
+			if err != nil {
+				statuses[i].Status = StatusFailed
+				statuses[i].Error = err.Error()
+				log.WithError(err).WithField("backend", b.Name()).Warning("Federated query failed")
+				return
+			}
+
+			statuses[i].Series = len(res.Series)
+			results[i] = res
+		}(i, b)
+	}
+	wg.Wait()
+
+	res := &Result{
+		Backends: statuses,
+	}
+
+	m := newMerger()
+	for i, r := range results {
+		if r == nil {
+			res.Partial = true
+			continue
+		}
+
+		prefix := ""
+		if split {
+			prefix = "backend=" + f.backends[i].Name() + ";"
+		}
+		m.add(r, prefix)
+	}
+	res.QueryResult = m.result()
+	if share {
+		res.QueryResult.Share()
+	}
+	res.QueryResult.Describe()
+
+	return res
+}
+
+func cloneValues(v url.Values) url.Values {
+	res := make(url.Values, len(v))
+	for k, x := range v {
+		res[k] = append([]string(nil), x...)
+	}
+
+	return res
+}
+
+// Handler handles requests for /federation/query. It takes the parameters of /query and returns the merged result
+// with the status of each backend as JSON. It fails only if all backends failed.
+func (f *Federation) Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	res := f.Query(r.Context(), r.URL.Query())
+
+	status := http.StatusOK
+	if res.Partial && len(res.Backends) == countFailed(res.Backends) {
+		status = http.StatusBadGateway
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(j)
+}
+
+func countFailed(statuses []*BackendStatus) int {
+	n := 0
+	for _, s := range statuses {
+		if s.Status == StatusFailed {
+			n++
+		}
+	}
+
+	return n
+}
+
+type localBackend struct {
+	name    string
+	querier Querier
+}
+
+func (b *localBackend) Name() string {
+	return b.name
+}
+
+func (b *localBackend) RunQuery(ctx context.Context, fields url.Values) (*frontend.QueryResult, error) {
+	return b.querier.RunQuery(ctx, fields)
+}
+
+// merger sums up the series of several results by name and timestamp
+type merger struct {
+	unit        string
+	resolution  uint64
+	downsampled string
+	timestamps  map[int64]time.Time
+	series      map[string]map[int64]uint64
+}
+
+func newMerger() *merger {
+	return &merger{
+		unit:       "Mbps",
+		timestamps: make(map[int64]time.Time),
+		series:     make(map[string]map[int64]uint64),
+	}
+}
+
+func (m *merger) add(r *frontend.QueryResult, prefix string) {
+	if r.Unit != "" {
+		m.unit = r.Unit
+	}
+
+	if r.Resolution > m.resolution {
+		m.resolution = r.Resolution
+	}
+
+	if r.Downsampled != "" {
+		m.downsampled = r.Downsampled
+	}
+
+	for _, ts := range r.Timestamps {
+		m.timestamps[ts.Unix()] = ts
+	}
+
+	for _, s := range r.Series {
+		name := prefix + s.Name
+		values, exists := m.series[name]
+		if !exists {
+			values = make(map[int64]uint64)
+			m.series[name] = values
+		}
+
+		for i, v := range s.Values {
+			if i < len(r.Timestamps) {
+				values[r.Timestamps[i].Unix()] += v
+			}
+		}
+	}
+}
+
+func (m *merger) result() *frontend.QueryResult {
+	keys := make([]int64, 0, len(m.timestamps))
+	for k := range m.timestamps {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	names := make([]string, 0, len(m.series))
+	for name := range m.series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := &frontend.QueryResult{
+		Unit:        m.unit,
+		Resolution:  m.resolution,
+		Downsampled: m.downsampled,
+		Timestamps:  make([]time.Time, 0, len(keys)),
+		Series:      make([]*frontend.QuerySeries, 0, len(names)),
+	}
+
+	for _, k := range keys {
+		res.Timestamps = append(res.Timestamps, m.timestamps[k])
+	}
+
+	for _, name := range names {
+		s := &frontend.QuerySeries{
+			Name:   name,
+			Values: make([]uint64, 0, len(keys)),
+		}
+
+		for _, k := range keys {
+			s.Values = append(s.Values, m.series[name][k])
+		}
+
+		res.Series = append(res.Series, s)
+	}
+
+	return res
+}
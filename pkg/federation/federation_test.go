@@ -0,0 +1,206 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockQuerier struct {
+	res    *frontend.QueryResult
+	err    error
+	fields url.Values
+}
+
+func (m *mockQuerier) RunQuery(ctx context.Context, fields url.Values) (*frontend.QueryResult, error) {
+	m.fields = fields
+	return m.res, m.err
+}
+
+func csvBackend(t *testing.T, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/query", r.URL.Path)
+		assert.Equal(t, "", r.URL.Query().Get(splitParameter))
+		assert.Equal(t, "eu", r.URL.Query().Get("agent_site"))
+		fmt.Fprint(w, body)
+	}))
+}
+
+func TestQuery(t *testing.T) {
+	t1 := time.Unix(1700000000, 0).UTC()
+	t2 := time.Unix(1700000060, 0).UTC()
+	t3 := time.Unix(1700000120, 0).UTC()
+
+	remote := csvBackend(t, "timestamp,Protocol=6,Protocol=17\n"+
+		t2.Format(time.RFC3339)+",10,1\n"+
+		t3.Format(time.RFC3339)+",20,2\n")
+	defer remote.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unable to process query", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	local := &mockQuerier{
+		res: &frontend.QueryResult{
+			Unit:       "Mbps",
+			Timestamps: []time.Time{t1, t2},
+			Series: []*frontend.QuerySeries{
+				{Name: "Protocol=6", Values: []uint64{5, 7}},
+			},
+		},
+	}
+
+	f, err := New(&Config{
+		Local: "local",
+		Backends: []*BackendConfig{
+			{Name: "remote", URL: remote.URL},
+			{Name: "broken", URL: failing.URL + "/"},
+		},
+	}, local)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	fields := url.Values{"agent_site": []string{"eu"}}
+	res := f.Query(context.Background(), fields)
+	assert.True(t, res.Partial)
+	assert.Equal(t, []time.Time{t1, t2, t3}, res.Timestamps)
+	assert.Equal(t, []*frontend.QuerySeries{
+		{Name: "Protocol=17", Values: []uint64{0, 1, 2}, Meta: &frontend.SeriesMeta{Unit: "bps", Scale: 1000000, Prefix: "M", Decimals: 2}},
+		{Name: "Protocol=6", Values: []uint64{5, 17, 20}, Meta: &frontend.SeriesMeta{Unit: "bps", Scale: 1000000, Prefix: "M", Decimals: 1}},
+	}, res.Series)
+	assert.Equal(t, fields, local.fields)
+
+	assert.Len(t, res.Backends, 3)
+	assert.Equal(t, StatusOK, res.Backends[0].Status)
+	assert.Equal(t, 1, res.Backends[0].Series)
+	assert.Equal(t, StatusOK, res.Backends[1].Status)
+	assert.Equal(t, 2, res.Backends[1].Series)
+	assert.Equal(t, StatusFailed, res.Backends[2].Status)
+	assert.True(t, strings.Contains(res.Backends[2].Error, "500"), res.Backends[2].Error)
+
+	fields.Set("normalize", "share")
+	res = f.Query(context.Background(), fields)
+	assert.Equal(t, "bp", res.Unit)
+	assert.Equal(t, []uint64{0, 556, 909}, res.Series[0].Values)
+	assert.Equal(t, "", local.fields.Get("normalize"))
+	fields.Del("normalize")
+
+	fields.Set(splitParameter, "true")
+	res = f.Query(context.Background(), fields)
+	names := make([]string, 0)
+	for _, s := range res.Series {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"backend=local;Protocol=6", "backend=remote;Protocol=17", "backend=remote;Protocol=6"}, names)
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected *frontend.QueryResult
+		wantFail bool
+	}{
+		{
+			name:  "Empty",
+			input: "",
+			expected: &frontend.QueryResult{
+				Unit: "Mbps",
+			},
+		},
+		{
+			name:  "Series",
+			input: "timestamp,a,b\n2023-11-14T22:13:20Z,1,2\n",
+			expected: &frontend.QueryResult{
+				Unit:       "Mbps",
+				Timestamps: []time.Time{time.Unix(1700000000, 0).UTC()},
+				Series: []*frontend.QuerySeries{
+					{Name: "a", Values: []uint64{1}},
+					{Name: "b", Values: []uint64{2}},
+				},
+			},
+		},
+		{
+			name:     "Unexpected header",
+			input:    "time,a\n",
+			wantFail: true,
+		},
+		{
+			name:     "Invalid value",
+			input:    "timestamp,a\n2023-11-14T22:13:20Z,x\n",
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		res, err := parseCSV(strings.NewReader(test.input))
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected failure for %q: %v", test.name, err)
+			continue
+		}
+
+		assert.Equal(t, test.expected, res, test.name)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		wantFail bool
+	}{
+		{
+			name: "Local only",
+			cfg:  &Config{Local: "fra"},
+		},
+		{
+			name:     "No backends",
+			cfg:      &Config{},
+			wantFail: true,
+		},
+		{
+			name: "Duplicate name",
+			cfg: &Config{
+				Local: "fra",
+				Backends: []*BackendConfig{
+					{Name: "fra", URL: "http://fra:9991"},
+				},
+			},
+			wantFail: true,
+		},
+		{
+			name: "Invalid URL",
+			cfg: &Config{
+				Backends: []*BackendConfig{
+					{Name: "ams", URL: "ams:9991"},
+				},
+			},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := New(test.cfg, &mockQuerier{})
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+	}
+}
@@ -0,0 +1,132 @@
+package federation
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/bio-routing/flowhouse/pkg/requestid"
+	"github.com/pkg/errors"
+)
+
+// maxErrorBodySize limits how much of the body of a failed response ends up in the backend status
+const maxErrorBodySize = 512
+
+// remoteBackend queries the /query endpoint of another flowhouse instance
+type remoteBackend struct {
+	cfg    *BackendConfig
+	client *http.Client
+}
+
+func newRemoteBackend(cfg *BackendConfig) *remoteBackend {
+	return &remoteBackend{
+		cfg:    cfg,
+		client: &http.Client{},
+	}
+}
+
+func (b *remoteBackend) Name() string {
+	return b.cfg.Name
+}
+
+func (b *remoteBackend) RunQuery(ctx context.Context, fields url.Values) (*frontend.QueryResult, error) {
+	params := cloneValues(fields)
+	params.Del("format")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(b.cfg.URL, "/")+"/query?"+params.Encode(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create request")
+	}
+
+	withRequestID(ctx, req)
+	if b.cfg.Username != "" {
+		req.SetBasicAuth(b.cfg.Username, b.cfg.Password)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, fmt.Errorf("Unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return parseCSV(resp.Body)
+}
+
+// withRequestID passes the request ID of ctx on to a backend so its logs can be correlated
+func withRequestID(ctx context.Context, req *http.Request) {
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+}
+
+// parseCSV parses a /query result with a timestamp column followed by a column per series
+func parseCSV(r io.Reader) (*frontend.QueryResult, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return &frontend.QueryResult{
+			Unit: "Mbps",
+		}, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read header")
+	}
+
+	if len(header) == 0 || header[0] != "timestamp" {
+		return nil, fmt.Errorf("Unexpected header %q", strings.Join(header, ","))
+	}
+
+	res := &frontend.QueryResult{
+		Unit:       "Mbps",
+		Timestamps: make([]time.Time, 0),
+		Series:     make([]*frontend.QuerySeries, 0, len(header)-1),
+	}
+
+	for _, name := range header[1:] {
+		res.Series = append(res.Series, &frontend.QuerySeries{
+			Name:   name,
+			Values: make([]uint64, 0),
+		})
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to read row")
+		}
+
+		ts, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid timestamp %q", record[0])
+		}
+		res.Timestamps = append(res.Timestamps, ts)
+
+		for i, s := range res.Series {
+			v, err := strconv.ParseUint(record[i+1], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid value %q of %s", record[i+1], s.Name)
+			}
+
+			s.Values = append(s.Values, v)
+		}
+	}
+
+	return res, nil
+}
@@ -3,18 +3,54 @@ package flowhouse
 import (
 	"net/http"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bio-routing/bio-rd/util/grpc/clientmanager"
 	"github.com/bio-routing/flowhouse/cmd/flowhouse/config"
+	"github.com/bio-routing/flowhouse/pkg/agentdetail"
+	"github.com/bio-routing/flowhouse/pkg/alerting"
+	"github.com/bio-routing/flowhouse/pkg/annotations"
+	"github.com/bio-routing/flowhouse/pkg/anomaly"
+	"github.com/bio-routing/flowhouse/pkg/anonymizer"
+	"github.com/bio-routing/flowhouse/pkg/appid"
+	"github.com/bio-routing/flowhouse/pkg/billing"
+	"github.com/bio-routing/flowhouse/pkg/bogon"
 	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/ddos"
+	"github.com/bio-routing/flowhouse/pkg/decodelog"
+	"github.com/bio-routing/flowhouse/pkg/federation"
+	"github.com/bio-routing/flowhouse/pkg/flowsink"
+	"github.com/bio-routing/flowhouse/pkg/forecast"
 	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/bio-routing/flowhouse/pkg/heartbeats"
+	"github.com/bio-routing/flowhouse/pkg/hostcounters"
 	"github.com/bio-routing/flowhouse/pkg/intfmapper"
+	"github.com/bio-routing/flowhouse/pkg/inventory"
 	"github.com/bio-routing/flowhouse/pkg/ipannotator"
+	"github.com/bio-routing/flowhouse/pkg/models/agent"
 	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/models/hostcounter"
+	"github.com/bio-routing/flowhouse/pkg/ratelimit"
+	"github.com/bio-routing/flowhouse/pkg/rdns"
+	"github.com/bio-routing/flowhouse/pkg/relay"
+	"github.com/bio-routing/flowhouse/pkg/reports"
+	"github.com/bio-routing/flowhouse/pkg/retention"
+	"github.com/bio-routing/flowhouse/pkg/ringbuffer"
 	"github.com/bio-routing/flowhouse/pkg/routemirror"
+	"github.com/bio-routing/flowhouse/pkg/rpki"
+	"github.com/bio-routing/flowhouse/pkg/samplingreport"
+	"github.com/bio-routing/flowhouse/pkg/scandetect"
+	"github.com/bio-routing/flowhouse/pkg/servers/capture"
 	"github.com/bio-routing/flowhouse/pkg/servers/ipfix"
 	"github.com/bio-routing/flowhouse/pkg/servers/sflow"
+	"github.com/bio-routing/flowhouse/pkg/sessions"
+	"github.com/bio-routing/flowhouse/pkg/tagger"
+	"github.com/bio-routing/flowhouse/pkg/threatintel"
+	"github.com/bio-routing/flowhouse/pkg/timestamps"
+	"github.com/bio-routing/flowhouse/pkg/version"
+	"github.com/bio-routing/flowhouse/pkg/writers"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
@@ -24,6 +60,13 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	interfaceExportInterval = time.Minute * 2
+
+	// listenerCapture names the capture server in the heartbeats
+	listenerCapture = "capture"
+)
+
 // Flowhouse is an clickhouse based sflow collector
 type Flowhouse struct {
 	cfg               *Config
@@ -31,11 +74,39 @@ type Flowhouse struct {
 	routeMirror       *routemirror.RouteMirror
 	grpcClientManager *clientmanager.ClientManager
 	ipa               *ipannotator.IPAnnotator
+	enrichment        *enrichment
+	ddos              *ddos.Detector
+	anomalies         *anomaly.Detector
+	scans             *scandetect.Detector
+	sflowRelay        *relay.Relay
+	ipfixRelay        *relay.Relay
+	limiter           *ratelimit.Limiter
 	sfs               *sflow.SflowServer
 	ifxs              *ipfix.IPFIXServer
+	capture           *capture.Server
+	decodeLog         *decodelog.Log
+	samplingReport    *samplingreport.Report
 	chgw              *clickhousegw.ClickHouseGateway
+	writers           *writers.Pool
+	inventory         *inventory.Inventory
+	annotations       *annotations.Manager
+	sessions          *sessions.Manager
+	alerting          *alerting.Manager
+	forecaster        *forecast.Forecaster
+	reports           *reports.Manager
 	fe                *frontend.Frontend
-	flowsRX           chan []*flow.Flow
+	queue             *ringbuffer.RingBuffer
+	heartbeats        *heartbeats.Monitor
+	hostCounters      *hostcounters.Collector
+	federation        *federation.Federation
+
+	// httpListening and batchStartedAt (unix nanoseconds, 0 while idle) are checked by Healthy
+	httpListening  atomic.Bool
+	batchStartedAt atomic.Int64
+
+	// stagesMu protects the stages and servers replaced by Reload
+	stagesMu sync.RWMutex
+	reloadMu sync.Mutex
 }
 
 // Config is flow house instances configuration
@@ -46,9 +117,54 @@ type Config struct {
 	ListenSflow        string
 	ListenIPFIX        string
 	ListenHTTP         string
+	HTTPAuth           *frontend.AuthConfig
+	QueryLimits        *frontend.QueryLimitConfig
+	Downsampling       *frontend.DownsampleConfig
+	Names              *frontend.NamesConfig
+	Geo                *frontend.GeoConfig
+	UI                 *frontend.UIConfig
+	I18n               *frontend.I18nConfig
 	DefaultVRF         uint64
 	Dicts              frontend.Dicts
+	DictStore          frontend.DictStore
+	Joins              frontend.Joins
+	ComputedFields     frontend.ComputedFields
 	DisableIPAnnotator bool
+	DecodeTunnels      bool
+	RDNS               *rdns.Config
+	Tagging            *tagger.Config
+	RPKI               *rpki.Config
+	DisableBogons      bool
+	Bogons             *bogon.Config
+	ThreatIntel        *threatintel.Config
+	Anonymization      *anonymizer.Config
+	Applications       *appid.Config
+	Capture            *capture.Config
+	Relay              *relay.Config
+	RateLimits         *ratelimit.Config
+	IPFIXFields        ipfix.Fields
+	RawFields          bool
+	DecodeErrorLogSize int
+	SamplingIntervals  int
+	Timestamps         *timestamps.Config
+	Alerting           *alerting.Config
+	DDoS               *ddos.Config
+	Anomaly            *anomaly.Config
+	ScanDetection      *scandetect.Config
+	Forecasting        *forecast.Config
+	ScheduledQueries   *reports.Config
+	Writers            *writers.Config
+	Queue              *ringbuffer.Config
+	Heartbeats         *heartbeats.Config
+	HostCounters       *hostcounters.Config
+	Discovery          *inventory.DiscoveryConfig
+	Federation         *federation.Config
+
+	// FlowSink gets all flows right before they are inserted if set
+	FlowSink *flowsink.Sink
+
+	// Loader reads the current configuration on reload
+	Loader func() (*Config, error)
 }
 
 // ClickhouseConfig represents a clickhouse client config
@@ -67,37 +183,288 @@ func New(cfg *Config) (*Flowhouse, error) {
 		ifMapper:          intfmapper.New(),
 		routeMirror:       routemirror.New(),
 		grpcClientManager: clientmanager.New(),
-		flowsRX:           make(chan []*flow.Flow, 1024),
+		decodeLog:         decodelog.New(cfg.DecodeErrorLogSize),
+		samplingReport:    samplingreport.New(cfg.SamplingIntervals),
+	}
+
+	qcfg := cfg.Queue
+	if qcfg == nil {
+		qcfg = &ringbuffer.Config{}
+	}
+
+	q, err := ringbuffer.New(qcfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create ingest queue")
+	}
+	fh.queue = q
+
+	e, err := newEnrichment(cfg)
+	if err != nil {
+		return nil, err
 	}
+	fh.enrichment = e
 
 	if !cfg.DisableIPAnnotator {
 		fh.ipa = ipannotator.New(fh.routeMirror)
 	}
 
-	sfs, err := sflow.New(fh.cfg.ListenSflow, runtime.NumCPU(), fh.flowsRX, fh.ifMapper)
+	if cfg.DDoS != nil {
+		d, err := ddos.New(cfg.DDoS)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create DDoS detector")
+		}
+		fh.ddos = d
+	}
+
+	if cfg.Anomaly != nil {
+		fh.anomalies = anomaly.New(cfg.Anomaly)
+	}
+
+	if cfg.ScanDetection != nil {
+		fh.scans = scandetect.New(cfg.ScanDetection)
+	}
+
+	if cfg.Relay != nil {
+		fh.sflowRelay, err = relay.New(cfg.Relay, "sflow", cfg.Relay.SFlow)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create sflow relay")
+		}
+
+		fh.ipfixRelay, err = relay.New(cfg.Relay, "ipfix", cfg.Relay.IPFIX)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create IPFIX relay")
+		}
+	}
+
+	if cfg.RateLimits != nil {
+		fh.limiter, err = ratelimit.New(cfg.RateLimits)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid rate limits")
+		}
+	}
+
+	if cfg.IPFIXFields != nil {
+		err := cfg.IPFIXFields.Validate()
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid IPFIX fields")
+		}
+		cfg.ChCfg.ExtraColumns = cfg.IPFIXFields.Columns()
+	}
+
+	cfg.ChCfg.RawColumn = cfg.RawFields
+
+	chgw, err := clickhousegw.New(fh.cfg.ChCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create clickhouse wrapper")
+	}
+	fh.chgw = chgw
+
+	if cfg.Heartbeats != nil {
+		err = fh.chgw.CreateHeartbeatsSchemaIfNotExists()
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create heartbeats schema")
+		}
+
+		hm, err := heartbeats.New(cfg.Heartbeats, fh.chgw)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to start heartbeats")
+		}
+		fh.heartbeats = hm
+	}
+
+	if cfg.HostCounters != nil {
+		err = fh.chgw.CreateHostCountersSchemaIfNotExists()
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create host counters schema")
+		}
+
+		fh.hostCounters = hostcounters.New(cfg.HostCounters, fh.chgw)
+	}
+
+	sfs, err := sflow.New(fh.cfg.ListenSflow, runtime.NumCPU(), fh.output(decodelog.ProtocolSFlow), fh.hostOutput(), fh.ifMapper, fh.cfg.DecodeTunnels, fh.sflowRelay, fh.decodeLog, fh.samplingReport, fh.limiter)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to start sflow server")
 	}
 	fh.sfs = sfs
 
-	ifxs, err := ipfix.New(fh.cfg.ListenIPFIX, runtime.NumCPU(), fh.flowsRX, fh.ifMapper)
+	ifxs, err := ipfix.New(fh.cfg.ListenIPFIX, runtime.NumCPU(), fh.output(decodelog.ProtocolIPFIX), fh.ifMapper, fh.ipfixRelay, fh.decodeLog, fh.limiter, fh.cfg.IPFIXFields, fh.cfg.RawFields)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to start IPFIX server")
 	}
 	fh.ifxs = ifxs
 
-	chgw, err := clickhousegw.New(fh.cfg.ChCfg)
+	if cfg.Capture != nil {
+		cs, err := capture.New(cfg.Capture, fh.output(listenerCapture))
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to start capture server")
+		}
+		fh.capture = cs
+	}
+
+	wcfg := cfg.Writers
+	if wcfg == nil {
+		wcfg = &writers.Config{}
+	}
+
+	wp, err := writers.New(wcfg, fh.connectWriter, func(flows []*flow.Flow, d time.Duration, err error) {
+		observeInsert(flows, countByAgent(flows), d, err)
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, "Unable to create clickhouse wrapper")
+		return nil, errors.Wrap(err, "Unable to start writers")
+	}
+	fh.writers = wp
+
+	err = fh.chgw.CreateAgentsSchemaIfNotExists()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create agents schema")
+	}
+
+	inv, err := inventory.New(fh.chgw)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create inventory")
+	}
+	fh.inventory = inv
+
+	if cfg.Discovery != nil {
+		err := fh.inventory.EnableDiscovery(cfg.Discovery)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid discovery")
+		}
+	}
+
+	err = fh.chgw.CreateAnnotationsSchemaIfNotExists()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create annotations schema")
+	}
+
+	an, err := annotations.New(fh.chgw)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create annotation manager")
+	}
+	fh.annotations = an
+
+	err = fh.chgw.CreateSessionsSchemaIfNotExists()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create sessions schema")
+	}
+
+	sm, err := sessions.New(fh.chgw)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create session manager")
+	}
+	fh.sessions = sm
+
+	if cfg.SNMP != nil && cfg.SNMP.ExportInterfaces {
+		err = fh.chgw.CreateInterfacesSchemaIfNotExists()
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create interfaces schema")
+		}
+	}
+
+	if cfg.Alerting != nil {
+		am, err := alerting.New(cfg.Alerting, fh.chgw)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create alert manager")
+		}
+		fh.alerting = am
+	}
+
+	forecastCfg := cfg.Forecasting
+	if forecastCfg == nil {
+		forecastCfg = &forecast.Config{}
+	}
+
+	fc, err := forecast.New(forecastCfg, fh.chgw, fh.ifMapper)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create forecaster")
+	}
+	fh.forecaster = fc
+
+	fh.fe = frontend.New(fh.chgw, cfg.Dicts, cfg.ComputedFields, fh.annotations, fh.sessions)
+	if cfg.QueryLimits != nil {
+		err := fh.fe.LimitQueries(cfg.QueryLimits)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid query limits")
+		}
+	}
+
+	if cfg.Downsampling != nil {
+		err := fh.fe.SetDownsampling(cfg.Downsampling)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid downsampling")
+		}
+	}
+
+	if cfg.DictStore != nil {
+		fh.fe.SetDictStore(cfg.DictStore)
+	}
+
+	if cfg.Joins != nil {
+		err := fh.fe.SetJoins(cfg.Joins)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid joins")
+		}
+	}
+
+	if cfg.Names != nil {
+		err := fh.fe.SetNames(cfg.Names)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid names")
+		}
+	}
+
+	if cfg.Geo != nil {
+		err := fh.fe.SetGeo(cfg.Geo)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid geo config")
+		}
+	}
+
+	if cfg.UI != nil {
+		err := fh.fe.SetUI(cfg.UI)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid UI config")
+		}
+	}
+
+	if cfg.I18n != nil {
+		err := fh.fe.SetI18n(cfg.I18n)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid i18n config")
+		}
+	}
+
+	if cfg.Federation != nil {
+		fed, err := federation.New(cfg.Federation, fh.fe)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create federation")
+		}
+		fh.federation = fed
+	}
+
+	if cfg.ScheduledQueries != nil {
+		rm, err := reports.New(cfg.ScheduledQueries, fh.fe)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create scheduled queries")
+		}
+		fh.reports = rm
 	}
-	fh.chgw = chgw
 
-	fh.fe = frontend.New(fh.chgw, cfg.Dicts)
 	return fh, nil
 }
 
 // AddAgent adds an agent
-func (f *Flowhouse) AddAgent(name string, addr bnet.IP, risAddrs []string, vrfs []uint64) {
+func (f *Flowhouse) AddAgent(name string, site string, role string, addr bnet.IP, risAddrs []string, vrfs []uint64) {
+	err := f.inventory.Add(&agent.Agent{
+		Address: addr,
+		Name:    name,
+		Site:    site,
+		Role:    role,
+	})
+	if err != nil {
+		log.WithError(err).Errorf("Unable to add agent %q to inventory", name)
+	}
+
 	if f.cfg.SNMP != nil {
 		f.ifMapper.AddDevice(addr, f.cfg.SNMP)
 	}
@@ -121,35 +488,268 @@ func (f *Flowhouse) AddAgent(name string, addr bnet.IP, risAddrs []string, vrfs
 // Run runs flowhouse
 func (f *Flowhouse) Run() {
 	f.installHTTPHandlers(f.fe)
-	go http.ListenAndServe(f.cfg.ListenHTTP, nil)
-	log.WithField("address", f.cfg.ListenHTTP).Info("Listening for HTTP requests")
+	err := f.fe.Start(f.cfg.ListenHTTP)
+	if err != nil {
+		log.WithError(err).Error("Unable to listen for HTTP requests")
+	} else {
+		f.httpListening.Store(true)
+		log.WithField("address", f.cfg.ListenHTTP).Info("Listening for HTTP requests")
+	}
+
+	if f.cfg.SNMP != nil && f.cfg.SNMP.ExportInterfaces {
+		go f.interfaceExporter()
+	}
 
+	observeQueueCapacity(f.queue.Capacity())
 	for {
-		flows := <-f.flowsRX
+		flows := f.queue.Get()
+		f.batchStartedAt.Store(time.Now().UnixNano())
+		counts := countByAgent(flows)
+		queuedBatches, queuedFlows := f.queue.Len()
+		observeReceived(flows, counts, queuedBatches, queuedFlows)
+		f.inventory.Observe(flows)
 
-		if f.ipa != nil {
-			for _, fl := range flows {
-				fl.VRFIn = f.cfg.DefaultVRF
-				fl.VRFOut = f.cfg.DefaultVRF
+		f.stagesMu.RLock()
+		f.annotate(flows)
+		f.stagesMu.RUnlock()
+		flowsEnriched.Add(float64(len(flows)))
 
-				err := f.ipa.Annotate(fl)
-				if err != nil {
-					log.WithError(err).Info("Annotating failed")
-				}
+		if f.cfg.FlowSink != nil {
+			err := f.cfg.FlowSink.Write(flows)
+			if err != nil {
+				log.WithError(err).Error("Unable to write flows to sink")
 			}
 		}
 
-		err := f.chgw.InsertFlows(flows)
+		// the writers take ownership of the flows
+		f.writers.Write(flows)
+		f.batchStartedAt.Store(0)
+	}
+}
+
+// output gets the output of the server receiving flows as listener, counted in the heartbeats if enabled. Flows of
+// agents beyond their rate limit are dropped.
+func (f *Flowhouse) output(listener string) func(flows []*flow.Flow) {
+	output := f.queue.Put
+	if f.limiter != nil {
+		output = f.limiter.Output(output)
+	}
+
+	if f.heartbeats == nil {
+		return output
+	}
+
+	return f.heartbeats.Output(listener, output)
+}
+
+// hostOutput gets the output of host sflow counter samples, nil if they are not stored
+func (f *Flowhouse) hostOutput() func(samples []*hostcounter.Sample) {
+	if f.hostCounters == nil {
+		return nil
+	}
+
+	return f.hostCounters.Add
+}
+
+// connectWriter opens a connection of its own for a writer, to address instead of the configured server if set
+func (f *Flowhouse) connectWriter(address string) (writers.Inserter, error) {
+	cfg := *f.cfg.ChCfg
+	if address != "" {
+		cfg.Address = address
+	}
+
+	return clickhousegw.Connect(&cfg)
+}
+
+// annotate runs all enrichment stages and detectors on flows. It has to be called with f.stagesMu held.
+func (f *Flowhouse) annotate(flows []*flow.Flow) {
+	e := f.enrichment
+	for _, fl := range flows {
+		e.timestamps.Annotate(fl)
+	}
+
+	if f.ipa != nil {
+		for _, fl := range flows {
+			fl.VRFIn = f.cfg.DefaultVRF
+			fl.VRFOut = f.cfg.DefaultVRF
+
+			err := f.ipa.Annotate(fl)
+			if err != nil {
+				log.WithError(err).Info("Annotating failed")
+			}
+		}
+	}
+
+	for _, fl := range flows {
+		f.inventory.Annotate(fl)
+	}
+
+	if e.rdns != nil {
+		for _, fl := range flows {
+			e.rdns.Annotate(fl)
+		}
+	}
+
+	if e.tagger != nil {
+		for _, fl := range flows {
+			e.tagger.Annotate(fl)
+		}
+	}
+
+	if e.appNames != nil {
+		for _, fl := range flows {
+			e.appNames.Annotate(fl)
+		}
+	}
+
+	if e.rpki != nil {
+		for _, fl := range flows {
+			e.rpki.Annotate(fl)
+		}
+	}
+
+	if e.bogons != nil {
+		for _, fl := range flows {
+			e.bogons.Annotate(fl)
+		}
+	}
+
+	if e.threatIntel != nil {
+		for _, fl := range flows {
+			e.threatIntel.Annotate(fl)
+		}
+	}
+
+	if f.ddos != nil {
+		f.ddos.Observe(flows)
+	}
+
+	if f.anomalies != nil {
+		f.anomalies.Observe(flows)
+	}
+
+	if f.scans != nil {
+		f.scans.Observe(flows)
+	}
+
+	// anonymization has to be the last stage as all others rely on the real addresses
+	if e.anonymizer != nil {
+		for _, fl := range flows {
+			e.anonymizer.Annotate(fl)
+		}
+	}
+}
+
+// interfaceExporter periodically writes the interfaces known to the IntfMapper into clickhouse
+func (f *Flowhouse) interfaceExporter() {
+	t := time.NewTicker(interfaceExportInterval)
+	defer t.Stop()
+
+	for {
+		<-t.C
+
+		err := f.chgw.InsertInterfaces(f.ifMapper.GetInterfaces())
 		if err != nil {
-			log.WithError(err).Error("Insert failed")
+			log.WithError(err).Error("Unable to export interfaces")
 		}
 	}
 }
 
+// installHTTPHandlers adds the middleware and the routes not served by the frontend itself
 func (f *Flowhouse) installHTTPHandlers(fe *frontend.Frontend) {
-	http.HandleFunc("/", fe.IndexHandler)
-	http.HandleFunc("/flowhouse.js", fe.FlowhouseJSHandler)
-	http.HandleFunc("/query", fe.QueryHandler)
-	http.HandleFunc("/dict_values/", fe.GetDictValues)
-	http.Handle("/metrics", promhttp.Handler())
+	fe.Use(frontend.RequestID, frontend.Recovery, frontend.Logging, frontend.Metrics)
+	if f.cfg.HTTPAuth != nil {
+		fe.Use(frontend.BasicAuth(f.cfg.HTTPAuth), frontend.AdminOnly)
+	}
+	fe.Use(frontend.Gzip)
+
+	fe.HandleFunc("/billing", billing.New(f.chgw).Handler)
+	fe.HandleFunc("/forecast", f.forecaster.Handler)
+	fe.HandleFunc("/agents", f.inventory.Handler)
+	fe.HandleFunc("/agents/discovered", f.inventory.DiscoveriesHandler)
+	fe.HandleFunc("/agents/detail", f.agentDetail().Handler)
+	fe.HandleFunc("/agents/sampling", f.samplingReport.Handler)
+	fe.HandleFunc("/annotations", f.annotations.Handler)
+	fe.HandleFunc("/preferences", f.sessions.Handler)
+	fe.HandleFunc("/debug/decode_errors", f.decodeLog.Handler)
+	fe.HandleFunc("/ipfix/templates", f.ipfixTemplatesHandler)
+	fe.HandleFunc("/threat_intel/feeds", f.threatIntelHandler)
+	if f.ddos != nil {
+		fe.HandleFunc("/ddos/incidents", f.ddos.Handler)
+	}
+	if f.anomalies != nil {
+		fe.HandleFunc("/anomalies", f.anomalies.Handler)
+	}
+	if f.scans != nil {
+		fe.HandleFunc("/scans/events", f.scans.Handler)
+	}
+	if f.heartbeats != nil {
+		fe.HandleFunc("/heartbeats/gaps", f.heartbeats.Handler)
+	}
+	if f.federation != nil {
+		fe.HandleFunc("/federation/query", f.federation.Handler)
+	}
+	fe.HandleFunc("/alerts", f.alertsHandler)
+	if f.reports != nil {
+		fe.HandleFunc("/scheduled_queries", f.reports.Handler)
+	}
+	fe.HandleFunc("/version", version.Handler)
+	fe.Handle("/metrics", promhttp.Handler())
+
+	// the admin routes reload the config, delete flows, kill queries and change dicts, so they are only served to
+	// authenticated admins
+	if f.cfg.HTTPAuth == nil {
+		log.Warning("http_auth is not configured, the admin routes are disabled")
+		return
+	}
+
+	fe.AdminRoutes()
+	fe.HandleFunc(frontend.AdminPrefix+"reload", f.ReloadHandler)
+	fe.HandleFunc(frontend.AdminPrefix+"retention/delete", retention.Handler(f.chgw))
+}
+
+// agentDetail combines the inventory, the traffic in the frontend, the decode log and the heartbeats if enabled
+func (f *Flowhouse) agentDetail() *agentdetail.AgentDetail {
+	var gaps agentdetail.Gaps
+	if f.heartbeats != nil {
+		gaps = f.heartbeats
+	}
+
+	return agentdetail.New(f.inventory, f.fe, f.decodeLog, gaps)
+}
+
+// ipfixTemplatesHandler, threatIntelHandler and alertsHandler dispatch to the current instances as they are replaced on
+// reload
+func (f *Flowhouse) ipfixTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	f.stagesMu.RLock()
+	ifxs := f.ifxs
+	f.stagesMu.RUnlock()
+
+	ifxs.TemplatesHandler(w, r)
+}
+
+func (f *Flowhouse) threatIntelHandler(w http.ResponseWriter, r *http.Request) {
+	f.stagesMu.RLock()
+	m := f.enrichment.threatIntel
+	f.stagesMu.RUnlock()
+
+	if m == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	m.Handler(w, r)
+}
+
+func (f *Flowhouse) alertsHandler(w http.ResponseWriter, r *http.Request) {
+	f.stagesMu.RLock()
+	am := f.alerting
+	f.stagesMu.RUnlock()
+
+	if am == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	am.Handler(w, r)
 }
@@ -0,0 +1,41 @@
+package flowhouse
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxBatchDuration is the time enriching and inserting a batch may take before the pipeline is considered stuck. The
+// ClickHouse write timeout is 20s.
+const maxBatchDuration = time.Minute
+
+// Healthy checks that the HTTP listener is up, ClickHouse is reachable and the pipeline is not stuck on a batch. The
+// sflow and IPFIX listeners are bound by New and Reload.
+func (f *Flowhouse) Healthy() error {
+	if !f.httpListening.Load() {
+		return fmt.Errorf("Not listening for HTTP requests")
+	}
+
+	err := f.chgw.Ping()
+	if err != nil {
+		return errors.Wrap(err, "ClickHouse unreachable")
+	}
+
+	return f.checkPipeline(time.Now())
+}
+
+func (f *Flowhouse) checkPipeline(now time.Time) error {
+	started := f.batchStartedAt.Load()
+	if started == 0 {
+		return nil
+	}
+
+	d := now.Sub(time.Unix(0, started))
+	if d > maxBatchDuration {
+		return fmt.Errorf("Pipeline stuck on a batch for %v", d.Truncate(time.Second))
+	}
+
+	return nil
+}
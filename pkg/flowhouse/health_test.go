@@ -0,0 +1,46 @@
+package flowhouse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPipeline(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		started  time.Time
+		wantFail bool
+	}{
+		{
+			name: "Idle",
+		},
+		{
+			name:    "Busy",
+			started: now.Add(-time.Second),
+		},
+		{
+			name:     "Stuck",
+			started:  now.Add(-2 * maxBatchDuration),
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		f := &Flowhouse{}
+		if !test.started.IsZero() {
+			f.batchStartedAt.Store(test.started.UnixNano())
+		}
+
+		err := f.checkPipeline(now)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+	}
+}
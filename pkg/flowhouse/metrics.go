@@ -0,0 +1,121 @@
+package flowhouse
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	flowsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "received_flows",
+		Help:      "Flows received from the sflow, IPFIX and capture servers",
+	}, []string{"agent"})
+	flowsEnriched = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "enriched_flows",
+		Help:      "Flows that passed all enrichment stages",
+	})
+	flowsInserted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "inserted_flows",
+		Help:      "Flows inserted into ClickHouse",
+	}, []string{"agent"})
+	flowsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "dropped_flows",
+		Help:      "Flows lost as their insert failed",
+	})
+	insertErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "insert_errors",
+		Help:      "Failed inserts into ClickHouse",
+	})
+	insertDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "insert_duration_seconds",
+		Help:      "Duration of inserts into ClickHouse",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+	batchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "batch_size_flows",
+		Help:      "Number of flows per batch",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+	})
+	queueLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "queue_length_batches",
+		Help:      "Batches waiting to be enriched and inserted",
+	})
+	queueCapacity = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "queue_capacity_batches",
+		Help:      "Batches the queue can hold before it drops batches",
+	})
+	queueLengthFlows = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "queue_length_flows",
+		Help:      "Flows waiting to be enriched and inserted",
+	})
+	queueCapacityFlows = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "queue_capacity_flows",
+		Help:      "Flows the queue can hold before it drops batches",
+	})
+)
+
+// countByAgent counts flows per agent
+func countByAgent(flows []*flow.Flow) map[netip.Addr]int {
+	res := make(map[netip.Addr]int)
+	for _, fl := range flows {
+		res[fl.Agent]++
+	}
+
+	return res
+}
+
+// observeQueueCapacity records how many batches and flows the queue holds
+func observeQueueCapacity(batches int, flows int) {
+	queueCapacity.Set(float64(batches))
+	queueCapacityFlows.Set(float64(flows))
+}
+
+// observeReceived records a batch taken from the queue of which queued batches and flows are still waiting
+func observeReceived(flows []*flow.Flow, counts map[netip.Addr]int, queuedBatches int, queuedFlows int) {
+	queueLength.Set(float64(queuedBatches))
+	queueLengthFlows.Set(float64(queuedFlows))
+	batchSize.Observe(float64(len(flows)))
+	for agent, n := range counts {
+		flowsReceived.WithLabelValues(agent.String()).Add(float64(n))
+	}
+}
+
+// observeInsert records the result of inserting flows that took d
+func observeInsert(flows []*flow.Flow, counts map[netip.Addr]int, d time.Duration, err error) {
+	insertDuration.Observe(d.Seconds())
+	if err != nil {
+		insertErrors.Inc()
+		flowsDropped.Add(float64(len(flows)))
+		return
+	}
+
+	for agent, n := range counts {
+		flowsInserted.WithLabelValues(agent.String()).Add(float64(n))
+	}
+}
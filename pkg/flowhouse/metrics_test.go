@@ -0,0 +1,34 @@
+package flowhouse
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveInsert(t *testing.T) {
+	a := netip.AddrFrom4([4]byte{192, 0, 2, 1})
+	b := netip.AddrFrom4([4]byte{192, 0, 2, 2})
+	flows := []*flow.Flow{{Agent: a}, {Agent: b}, {Agent: a}}
+
+	counts := countByAgent(flows)
+	assert.Equal(t, map[netip.Addr]int{a: 2, b: 1}, counts)
+
+	insertedA := testutil.ToFloat64(flowsInserted.WithLabelValues(a.String()))
+	dropped := testutil.ToFloat64(flowsDropped)
+	errs := testutil.ToFloat64(insertErrors)
+
+	observeInsert(flows, counts, time.Millisecond, nil)
+	assert.Equal(t, insertedA+2, testutil.ToFloat64(flowsInserted.WithLabelValues(a.String())))
+	assert.Equal(t, dropped, testutil.ToFloat64(flowsDropped))
+
+	observeInsert(flows, counts, time.Millisecond, fmt.Errorf("Connection refused"))
+	assert.Equal(t, insertedA+2, testutil.ToFloat64(flowsInserted.WithLabelValues(a.String())))
+	assert.Equal(t, dropped+3, testutil.ToFloat64(flowsDropped))
+	assert.Equal(t, errs+1, testutil.ToFloat64(insertErrors))
+}
@@ -0,0 +1,239 @@
+package flowhouse
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"github.com/bio-routing/flowhouse/pkg/alerting"
+	"github.com/bio-routing/flowhouse/pkg/anonymizer"
+	"github.com/bio-routing/flowhouse/pkg/appid"
+	"github.com/bio-routing/flowhouse/pkg/bogon"
+	"github.com/bio-routing/flowhouse/pkg/decodelog"
+	"github.com/bio-routing/flowhouse/pkg/rdns"
+	"github.com/bio-routing/flowhouse/pkg/rpki"
+	"github.com/bio-routing/flowhouse/pkg/servers/ipfix"
+	"github.com/bio-routing/flowhouse/pkg/servers/sflow"
+	"github.com/bio-routing/flowhouse/pkg/tagger"
+	"github.com/bio-routing/flowhouse/pkg/threatintel"
+	"github.com/bio-routing/flowhouse/pkg/timestamps"
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// enrichment are the reloadable stages annotating flows before they are inserted
+type enrichment struct {
+	timestamps  *timestamps.Policy
+	rdns        *rdns.Resolver
+	tagger      *tagger.Tagger
+	rpki        *rpki.Validator
+	bogons      *bogon.Classifier
+	threatIntel *threatintel.Matcher
+	anonymizer  *anonymizer.Anonymizer
+	appNames    *appid.Mapper
+}
+
+func newEnrichment(cfg *Config) (*enrichment, error) {
+	e := &enrichment{}
+
+	timestampCfg := cfg.Timestamps
+	if timestampCfg == nil {
+		timestampCfg = &timestamps.Config{}
+	}
+
+	tp, err := timestamps.New(timestampCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create timestamp policy")
+	}
+	e.timestamps = tp
+
+	if cfg.Tagging != nil {
+		t, err := tagger.New(cfg.Tagging)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create tagger")
+		}
+		e.tagger = t
+	}
+
+	if cfg.Applications != nil {
+		m, err := appid.New(cfg.Applications)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create application mapper")
+		}
+		e.appNames = m
+	}
+
+	if cfg.Anonymization != nil {
+		a, err := anonymizer.New(cfg.Anonymization)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create anonymizer")
+		}
+		e.anonymizer = a
+	}
+
+	// the remaining stages start background workers and have to be stopped if a later one fails
+	if cfg.RPKI != nil {
+		v, err := rpki.New(cfg.RPKI)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create RPKI validator")
+		}
+		e.rpki = v
+	}
+
+	if !cfg.DisableBogons {
+		bogonCfg := cfg.Bogons
+		if bogonCfg == nil {
+			bogonCfg = &bogon.Config{}
+		}
+
+		bc, err := bogon.New(bogonCfg)
+		if err != nil {
+			e.stop()
+			return nil, errors.Wrap(err, "Unable to create bogon classifier")
+		}
+		e.bogons = bc
+	}
+
+	if cfg.ThreatIntel != nil {
+		m, err := threatintel.New(cfg.ThreatIntel)
+		if err != nil {
+			e.stop()
+			return nil, errors.Wrap(err, "Unable to create threat intelligence matcher")
+		}
+		e.threatIntel = m
+	}
+
+	if cfg.RDNS != nil {
+		e.rdns = rdns.New(cfg.RDNS)
+	}
+
+	return e, nil
+}
+
+// stop stops the background workers of all stages
+func (e *enrichment) stop() {
+	if e.rdns != nil {
+		e.rdns.Stop()
+	}
+
+	if e.rpki != nil {
+		e.rpki.Stop()
+	}
+
+	if e.bogons != nil {
+		e.bogons.Stop()
+	}
+
+	if e.threatIntel != nil {
+		e.threatIntel.Stop()
+	}
+}
+
+// Reload reads the configuration using the configured loader and applies dicts, computed fields, enrichment stages
+// (tagging, application names, reverse DNS, RPKI, bogons, threat intelligence, anonymization, timestamps), alert rules and the sFlow and
+// IPFIX listen addresses without interrupting ingestion. All parts are created before any is replaced, so a failing
+// reload keeps the running configuration. Other settings require a restart.
+func (f *Flowhouse) Reload() error {
+	if f.cfg.Loader == nil {
+		return fmt.Errorf("No config loader set")
+	}
+
+	f.reloadMu.Lock()
+	defer f.reloadMu.Unlock()
+
+	cfg, err := f.cfg.Loader()
+	if err != nil {
+		return errors.Wrap(err, "Unable to load config")
+	}
+
+	e, err := newEnrichment(cfg)
+	if err != nil {
+		return err
+	}
+
+	var am *alerting.Manager
+	if cfg.Alerting != nil {
+		am, err = alerting.New(cfg.Alerting, f.chgw)
+		if err != nil {
+			e.stop()
+			return errors.Wrap(err, "Unable to create alert manager")
+		}
+	}
+
+	var sfs *sflow.SflowServer
+	if cfg.ListenSflow != f.cfg.ListenSflow {
+		sfs, err = sflow.New(cfg.ListenSflow, runtime.NumCPU(), f.output(decodelog.ProtocolSFlow), f.hostOutput(), f.ifMapper, f.cfg.DecodeTunnels, f.sflowRelay, f.decodeLog, f.samplingReport, f.limiter)
+		if err != nil {
+			e.stop()
+			stopAlerting(am)
+			return errors.Wrap(err, "Unable to start sflow server")
+		}
+	}
+
+	var ifxs *ipfix.IPFIXServer
+	if cfg.ListenIPFIX != f.cfg.ListenIPFIX {
+		ifxs, err = ipfix.New(cfg.ListenIPFIX, runtime.NumCPU(), f.output(decodelog.ProtocolIPFIX), f.ifMapper, f.ipfixRelay, f.decodeLog, f.limiter, f.cfg.IPFIXFields, f.cfg.RawFields)
+		if err != nil {
+			e.stop()
+			stopAlerting(am)
+			if sfs != nil {
+				sfs.Stop()
+			}
+			return errors.Wrap(err, "Unable to start IPFIX server")
+		}
+	}
+
+	f.stagesMu.Lock()
+	oldEnrichment, oldAlerting, oldSfs, oldIfxs := f.enrichment, f.alerting, f.sfs, f.ifxs
+	f.enrichment = e
+	f.alerting = am
+	if sfs != nil {
+		f.sfs = sfs
+		f.cfg.ListenSflow = cfg.ListenSflow
+	}
+	if ifxs != nil {
+		f.ifxs = ifxs
+		f.cfg.ListenIPFIX = cfg.ListenIPFIX
+	}
+	f.stagesMu.Unlock()
+
+	oldEnrichment.stop()
+	stopAlerting(oldAlerting)
+	if sfs != nil {
+		oldSfs.Stop()
+		log.WithField("address", cfg.ListenSflow).Info("Moved sflow server")
+	}
+	if ifxs != nil {
+		oldIfxs.Stop()
+		log.WithField("address", cfg.ListenIPFIX).Info("Moved IPFIX server")
+	}
+
+	f.fe.Reconfigure(cfg.Dicts, cfg.ComputedFields)
+
+	log.Info("Configuration reloaded")
+	return nil
+}
+
+func stopAlerting(am *alerting.Manager) {
+	if am != nil {
+		am.Stop()
+	}
+}
+
+// ReloadHandler reloads the configuration on POST requests
+func (f *Flowhouse) ReloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := f.Reload()
+	if err != nil {
+		log.WithError(err).Error("Reload failed")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
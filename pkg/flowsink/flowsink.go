@@ -0,0 +1,101 @@
+// Package flowsink writes flows as JSON lines for debugging decoders and enrichment
+package flowsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/pkg/errors"
+)
+
+// field extracts a value of a flow
+type field func(fl *flow.Flow) interface{}
+
+// Sink writes flows as JSON objects, one per line
+type Sink struct {
+	w      io.Writer
+	fields []string
+	mu     sync.Mutex
+}
+
+// New creates a sink writing the given fields (all if empty) to w
+func New(w io.Writer, fieldNames []string) (*Sink, error) {
+	for _, name := range fieldNames {
+		if _, exists := fields[name]; !exists {
+			return nil, fmt.Errorf("Unknown field %q (known fields: %s)", name, strings.Join(FieldNames(), ", "))
+		}
+	}
+
+	if len(fieldNames) == 0 {
+		fieldNames = FieldNames()
+	}
+
+	return &Sink{
+		w:      w,
+		fields: fieldNames,
+	}, nil
+}
+
+// FieldNames returns the names of all fields in alphabetical order
+func FieldNames() []string {
+	res := make([]string, 0, len(fields))
+	for name := range fields {
+		res = append(res, name)
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// Write writes flows
+func (s *Sink) Write(flows []*flow.Flow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enc := json.NewEncoder(s.w)
+	for _, fl := range flows {
+		obj := make(map[string]interface{}, len(s.fields))
+		for _, name := range s.fields {
+			obj[name] = fields[name](fl)
+		}
+
+		err := enc.Encode(obj)
+		if err != nil {
+			return errors.Wrap(err, "Write failed")
+		}
+	}
+
+	return nil
+}
+
+func ipString(addr netip.Addr) string {
+	if !addr.IsValid() {
+		return ""
+	}
+
+	return addr.String()
+}
+
+func pfxString(pfx netip.Prefix) string {
+	if !pfx.IsValid() {
+		return ""
+	}
+
+	return pfx.String()
+}
+
+func macString(mac uint64) string {
+	hw := make(net.HardwareAddr, 6)
+	for i := range hw {
+		hw[i] = byte(mac >> uint(8*(5-i)))
+	}
+
+	return hw.String()
+}
@@ -0,0 +1,64 @@
+package flowsink
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	fl := &flow.Flow{
+		Agent:    netip.AddrFrom4([4]byte{192, 0, 2, 1}),
+		SrcAddr:  netip.AddrFrom4([4]byte{198, 51, 100, 1}),
+		SrcPfx:   netip.PrefixFrom(netip.AddrFrom4([4]byte{198, 51, 100, 0}), 24),
+		SrcMAC:   0x0200c0000201,
+		DstPort:  443,
+		Protocol: 6,
+		Size:     1500,
+	}
+
+	tests := []struct {
+		name     string
+		fields   []string
+		expected string
+		wantFail bool
+	}{
+		{
+			name:     "Selected fields",
+			fields:   []string{"agent", "src_ip_pfx", "src_mac", "dst_ip_addr", "dst_port", "size"},
+			expected: `{"agent":"192.0.2.1","dst_ip_addr":"","dst_port":443,"size":1500,"src_ip_pfx":"198.51.100.0/24","src_mac":"02:00:c0:00:02:01"}` + "\n",
+		},
+		{
+			name:     "Unknown field",
+			fields:   []string{"src_addr"},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		s, err := New(buf, test.fields)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		if !assert.NoError(t, err, test.name) {
+			continue
+		}
+
+		assert.NoError(t, s.Write([]*flow.Flow{fl}), test.name)
+		assert.Equal(t, test.expected, buf.String(), test.name)
+	}
+}
+
+func TestAllFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s, err := New(buf, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, FieldNames(), s.fields)
+	assert.NoError(t, s.Write([]*flow.Flow{{}}))
+}
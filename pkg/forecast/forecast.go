@@ -0,0 +1,470 @@
+// Package forecast projects when interfaces will reach a utilization threshold based on their traffic history
+package forecast
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/netif"
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ModelLinear is a linear regression over the daily peaks
+	ModelLinear = "linear"
+
+	// ModelHoltWinters is additive Holt-Winters smoothing with weekly seasonality over the daily peaks
+	ModelHoltWinters = "holt_winters"
+
+	defaultModel       = ModelLinear
+	defaultHistoryDays = 90
+	defaultHorizonDays = 365
+	defaultThreshold   = 0.8
+
+	// minHistoryDays is the number of days of traffic required for a forecast
+	minHistoryDays = 7
+
+	weekDays = 7
+
+	hwAlpha = 0.3
+	hwBeta  = 0.1
+	hwGamma = 0.3
+)
+
+// Querier runs queries against the flows database
+type Querier interface {
+	Query(q string) (*sql.Rows, error)
+	GetDatabaseName() string
+}
+
+// InterfaceSource provides interfaces with their speed
+type InterfaceSource interface {
+	GetInterfaces() []*netif.Interface
+}
+
+// Config is the forecasting configuration. Model, history, horizon and thresholds are defaults that can be
+// overridden per request.
+type Config struct {
+	// Model is the forecasting model (linear or holt_winters)
+	Model string `yaml:"model"`
+
+	// HistoryDays is the number of days of traffic the model is fitted to
+	HistoryDays int `yaml:"history_days"`
+
+	// HorizonDays is the number of days projected into the future
+	HorizonDays int `yaml:"horizon_days"`
+
+	// Thresholds are the utilizations (e.g. 0.8 for 80%) to project the dates for
+	Thresholds []float64 `yaml:"thresholds"`
+
+	// Capacities override or complement the interface speeds learned via SNMP, e.g. for links limited below port speed
+	Capacities []*Capacity `yaml:"capacities"`
+}
+
+// Capacity is the capacity of an interface
+type Capacity struct {
+	Agent     string `yaml:"agent"`
+	Interface string `yaml:"interface"`
+	BPS       uint64 `yaml:"bps"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Model == "" {
+		c.Model = defaultModel
+	}
+
+	if c.HistoryDays == 0 {
+		c.HistoryDays = defaultHistoryDays
+	}
+
+	if c.HorizonDays == 0 {
+		c.HorizonDays = defaultHorizonDays
+	}
+
+	if len(c.Thresholds) == 0 {
+		c.Thresholds = []float64{defaultThreshold}
+	}
+}
+
+// Forecast is the projection for one interface and direction
+type Forecast struct {
+	Agent     string `json:"agent"`
+	Interface string `json:"interface"`
+	Direction string `json:"direction"`
+
+	// CapacityBPS is the interface speed, 0 if unknown
+	CapacityBPS uint64 `json:"capacity_bps"`
+
+	// PeakBPS is the peak rate (5 minute average) of the last day of the history
+	PeakBPS float64 `json:"peak_bps"`
+
+	// Utilization is PeakBPS relative to the capacity
+	Utilization float64 `json:"utilization"`
+
+	// GrowthBPSPerDay is the trend of the daily peak rate
+	GrowthBPSPerDay float64 `json:"growth_bps_per_day"`
+
+	// Thresholds are the projections per configured utilization threshold (empty if the capacity is unknown)
+	Thresholds []*Threshold `json:"thresholds"`
+}
+
+// Threshold is the projection for one utilization threshold
+type Threshold struct {
+	Utilization float64 `json:"utilization"`
+
+	// Date is the first day the predicted peak rate reaches the threshold. Date and DaysLeft are absent if this does
+	// not happen within the horizon.
+	Date     *time.Time `json:"date,omitempty"`
+	DaysLeft *int       `json:"days_left,omitempty"`
+}
+
+// Forecaster creates capacity forecasts
+type Forecaster struct {
+	cfg        *Config
+	db         Querier
+	interfaces InterfaceSource
+	capacities map[interfaceKey]uint64
+}
+
+// New creates a new forecaster
+func New(cfg *Config, db Querier, interfaces InterfaceSource) (*Forecaster, error) {
+	cfg.loadDefaults()
+	if cfg.Model != ModelLinear && cfg.Model != ModelHoltWinters {
+		return nil, fmt.Errorf("Unknown model %q", cfg.Model)
+	}
+
+	f := &Forecaster{
+		cfg:        cfg,
+		db:         db,
+		interfaces: interfaces,
+		capacities: make(map[interfaceKey]uint64),
+	}
+
+	for _, c := range cfg.Capacities {
+		addr := net.ParseIP(c.Agent)
+		if addr == nil {
+			return nil, fmt.Errorf("Invalid agent %q", c.Agent)
+		}
+
+		f.capacities[interfaceKey{agent: addr.String(), name: c.Interface}] = c.BPS
+	}
+
+	return f, nil
+}
+
+// Options control a forecast
+type Options struct {
+	Model       string
+	HistoryDays int
+	HorizonDays int
+	Thresholds  []float64
+	Agent       string
+	Interface   string
+}
+
+func (f *Forecaster) parseOptions(v url.Values) (*Options, error) {
+	o := &Options{
+		Model:       f.cfg.Model,
+		HistoryDays: f.cfg.HistoryDays,
+		HorizonDays: f.cfg.HorizonDays,
+		Thresholds:  f.cfg.Thresholds,
+		Agent:       v.Get("agent"),
+		Interface:   v.Get("interface"),
+	}
+
+	if m := v.Get("model"); m != "" {
+		o.Model = m
+	}
+
+	if o.Model != ModelLinear && o.Model != ModelHoltWinters {
+		return nil, fmt.Errorf("Unknown model %q", o.Model)
+	}
+
+	for _, p := range []struct {
+		name string
+		v    *int
+	}{
+		{name: "history", v: &o.HistoryDays},
+		{name: "horizon", v: &o.HorizonDays},
+	} {
+		s := v.Get(p.name)
+		if s == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("Invalid %s %q", p.name, s)
+		}
+		*p.v = n
+	}
+
+	if len(v["threshold"]) > 0 {
+		o.Thresholds = make([]float64, 0, len(v["threshold"]))
+		for _, s := range v["threshold"] {
+			t, err := strconv.ParseFloat(s, 64)
+			if err != nil || t <= 0 {
+				return nil, fmt.Errorf("Invalid threshold %q", s)
+			}
+			o.Thresholds = append(o.Thresholds, t)
+		}
+	}
+
+	if o.Agent != "" && net.ParseIP(o.Agent) == nil {
+		return nil, fmt.Errorf("Invalid agent %q", o.Agent)
+	}
+
+	return o, nil
+}
+
+type seriesKey struct {
+	agent     string
+	intf      string
+	direction string
+}
+
+// Forecast creates forecasts for all interfaces with traffic in the history
+func (f *Forecaster) Forecast(o *Options, now time.Time) ([]*Forecast, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	start := today.AddDate(0, 0, -o.HistoryDays)
+
+	rows, err := f.db.Query(f.query(o, start, today))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	peaks := make(map[seriesKey]map[int64]float64)
+	for rows.Next() {
+		var k seriesKey
+		var day time.Time
+		var bytes uint64
+		err := rows.Scan(&k.direction, &k.agent, &k.intf, &day, &bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		k.agent = formatAddr(k.agent)
+		if _, exists := peaks[k]; !exists {
+			peaks[k] = make(map[int64]float64)
+		}
+
+		peaks[k][day.Unix()] = float64(bytes) * 8 / 300
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+
+	speeds := f.speeds()
+	res := make([]*Forecast, 0, len(peaks))
+	for k, p := range peaks {
+		values := make([]float64, 0, o.HistoryDays)
+		for d := start; d.Before(today); d = d.AddDate(0, 0, 1) {
+			values = append(values, p[d.Unix()])
+		}
+
+		fc := project(values, speeds[interfaceKey{agent: k.agent, name: k.intf}], o, today)
+		if fc == nil {
+			continue
+		}
+
+		fc.Agent = k.agent
+		fc.Interface = k.intf
+		fc.Direction = k.direction
+		res = append(res, fc)
+	}
+
+	// the interfaces running out of capacity first come first
+	sort.Slice(res, func(i, j int) bool {
+		di, dj := res[i].daysLeft(), res[j].daysLeft()
+		if (di == nil) != (dj == nil) {
+			return di != nil
+		}
+
+		if di != nil && *di != *dj {
+			return *di < *dj
+		}
+
+		return res[i].Utilization > res[j].Utilization
+	})
+
+	return res, nil
+}
+
+// daysLeft returns the days left until the first threshold is reached
+func (fc *Forecast) daysLeft() *int {
+	var res *int
+	for _, t := range fc.Thresholds {
+		if t.DaysLeft != nil && (res == nil || *t.DaysLeft < *res) {
+			res = t.DaysLeft
+		}
+	}
+
+	return res
+}
+
+// query returns the daily peaks of the 5 minute averages per interface and direction
+func (f *Forecaster) query(o *Options, start time.Time, end time.Time) string {
+	cond := fmt.Sprintf("timestamp >= toDateTime(%d) AND timestamp < toDateTime(%d)", start.Unix(), end.Unix())
+	if o.Agent != "" {
+		cond += fmt.Sprintf(" AND agent = toIPv6('%s')", o.Agent)
+	}
+
+	sel := "SELECT d, a, k, toStartOfDay(t) AS day, max(bytes) FROM (SELECT '%s' AS d, IPv6NumToString(agent) AS a, %s AS k, toStartOfFiveMinute(timestamp) AS t, sum(size * samplerate) AS bytes FROM %s.flows WHERE %s AND k != ''%s GROUP BY a, k, t) GROUP BY d, a, k, day"
+	db := f.db.GetDatabaseName()
+
+	intfCond := func(field string) string {
+		if o.Interface == "" {
+			return ""
+		}
+
+		return fmt.Sprintf(" AND %s = '%s'", field, escape(o.Interface))
+	}
+
+	return fmt.Sprintf(sel, "in", "int_in", db, cond, intfCond("int_in")) + " UNION ALL " +
+		fmt.Sprintf(sel, "out", "int_out", db, cond, intfCond("int_out"))
+}
+
+func escape(s string) string {
+	res := make([]rune, 0, len(s))
+	for _, c := range s {
+		if c == '\'' || c == '\\' {
+			res = append(res, '\\')
+		}
+		res = append(res, c)
+	}
+
+	return string(res)
+}
+
+// project fits the model to the daily peaks and searches the first day each threshold is reached
+func project(values []float64, capacity uint64, o *Options, today time.Time) *Forecast {
+	days := 0
+	for _, v := range values {
+		if v > 0 {
+			days++
+		}
+	}
+
+	if days < minHistoryDays {
+		return nil
+	}
+
+	fc := &Forecast{
+		CapacityBPS: capacity,
+		PeakBPS:     values[len(values)-1],
+		Thresholds:  make([]*Threshold, 0, len(o.Thresholds)),
+	}
+
+	var m Model
+	switch o.Model {
+	case ModelHoltWinters:
+		hw := FitHoltWinters(values, weekDays, hwAlpha, hwBeta, hwGamma)
+		fc.GrowthBPSPerDay = hw.Slope()
+		m = hw
+	default:
+		l := FitLinear(values)
+		fc.GrowthBPSPerDay = l.Slope()
+		m = l
+	}
+
+	if capacity == 0 {
+		return fc
+	}
+
+	fc.Utilization = fc.PeakBPS / float64(capacity)
+
+	for _, t := range o.Thresholds {
+		fc.Thresholds = append(fc.Thresholds, projectThreshold(m, t, capacity, o.HorizonDays, today))
+	}
+
+	return fc
+}
+
+func projectThreshold(m Model, utilization float64, capacity uint64, horizon int, today time.Time) *Threshold {
+	t := &Threshold{
+		Utilization: utilization,
+	}
+
+	limit := utilization * float64(capacity)
+	for n := 1; n <= horizon; n++ {
+		if m.Predict(n) < limit {
+			continue
+		}
+
+		// the first predicted day is today (the history ends yesterday)
+		d := today.AddDate(0, 0, n-1)
+		left := n - 1
+		t.Date = &d
+		t.DaysLeft = &left
+		break
+	}
+
+	return t
+}
+
+type interfaceKey struct {
+	agent string
+	name  string
+}
+
+// speeds returns the capacity per interface: the configured capacity if present, the SNMP speed otherwise
+func (f *Forecaster) speeds() map[interfaceKey]uint64 {
+	res := make(map[interfaceKey]uint64)
+	if f.interfaces != nil {
+		for _, ifa := range f.interfaces.GetInterfaces() {
+			res[interfaceKey{agent: ifa.Agent.String(), name: ifa.Name}] = ifa.Speed
+		}
+	}
+
+	for k, bps := range f.capacities {
+		res[k] = bps
+	}
+
+	return res
+}
+
+func formatAddr(s string) string {
+	if addr := net.ParseIP(s); addr != nil {
+		return addr.String()
+	}
+
+	return s
+}
+
+// Handler serves forecasts as JSON. Parameters: model (linear or holt_winters), history and horizon (days),
+// threshold (utilization, can be repeated), agent and interface.
+func (f *Forecaster) Handler(w http.ResponseWriter, r *http.Request) {
+	o, err := f.parseOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := f.Forecast(o, time.Now().UTC())
+	if err != nil {
+		log.WithError(err).Error("Unable to create forecast")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
@@ -0,0 +1,140 @@
+package forecast
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFitLinear(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    []float64
+		slope     float64
+		predicted float64
+	}{
+		{
+			name:      "Growing",
+			values:    []float64{10, 12, 14, 16},
+			slope:     2,
+			predicted: 20,
+		},
+		{
+			name:      "Constant",
+			values:    []float64{5},
+			slope:     0,
+			predicted: 5,
+		},
+		{
+			name:      "Empty",
+			values:    nil,
+			slope:     0,
+			predicted: 0,
+		},
+	}
+
+	for _, test := range tests {
+		l := FitLinear(test.values)
+		assert.InDelta(t, test.slope, l.Slope(), 0.0001, test.name)
+		assert.InDelta(t, test.predicted, l.Predict(2), 0.0001, test.name)
+	}
+}
+
+func TestFitHoltWinters(t *testing.T) {
+	// weekly pattern (low weekends) on top of a growth of 1 per day
+	values := make([]float64, 0, 8*weekDays)
+	for i := 0; i < 8*weekDays; i++ {
+		v := 100 + float64(i)
+		if i%weekDays >= 5 {
+			v -= 30
+		}
+		values = append(values, v)
+	}
+
+	hw := FitHoltWinters(values, weekDays, hwAlpha, hwBeta, hwGamma)
+	assert.InDelta(t, 1, hw.Slope(), 0.1)
+
+	// the next day is a monday, the one after the next saturday a weekend day
+	assert.InDelta(t, 100+float64(len(values)), hw.Predict(1), 2)
+	assert.InDelta(t, 100+float64(len(values)+5)-30, hw.Predict(6), 2)
+
+	// without two full seasons only level and trend are used
+	short := FitHoltWinters([]float64{1, 2, 3, 4}, weekDays, hwAlpha, hwBeta, hwGamma)
+	assert.InDelta(t, 5, short.Predict(1), 0.0001)
+}
+
+func TestProject(t *testing.T) {
+	today := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// 100 Mbps growing by 10 Mbps per day on a 1 Gbps interface
+	values := make([]float64, 0, 30)
+	for i := 0; i < 30; i++ {
+		values = append(values, 100000000+float64(i)*10000000)
+	}
+
+	o := &Options{
+		Model:       ModelLinear,
+		HorizonDays: 60,
+		Thresholds:  []float64{0.5, 0.8, 1},
+	}
+
+	fc := project(values, 1000000000, o, today)
+	assert.InDelta(t, 0.39, fc.Utilization, 0.0001)
+	assert.InDelta(t, 10000000, fc.GrowthBPSPerDay, 0.1)
+	assert.Len(t, fc.Thresholds, 3)
+
+	// 500 Mbps are predicted 11 days after the last value
+	assert.Equal(t, 10, *fc.Thresholds[0].DaysLeft)
+	assert.Equal(t, today.AddDate(0, 0, 10), *fc.Thresholds[0].Date)
+	assert.Equal(t, 40, *fc.Thresholds[1].DaysLeft)
+	assert.Nil(t, fc.Thresholds[2].DaysLeft)
+	assert.Equal(t, 10, *fc.daysLeft())
+
+	// unknown capacity
+	fc = project(values, 0, o, today)
+	assert.Empty(t, fc.Thresholds)
+
+	// not enough history
+	assert.Nil(t, project(make([]float64, 30), 1000000000, o, today))
+}
+
+func TestParseOptions(t *testing.T) {
+	f, err := New(&Config{}, nil, nil)
+	assert.NoError(t, err)
+
+	o, err := f.parseOptions(url.Values{})
+	assert.NoError(t, err)
+	assert.Equal(t, ModelLinear, o.Model)
+	assert.Equal(t, defaultHistoryDays, o.HistoryDays)
+	assert.Equal(t, []float64{defaultThreshold}, o.Thresholds)
+
+	o, err = f.parseOptions(url.Values{"model": {"holt_winters"}, "history": {"30"}, "threshold": {"0.7", "0.9"}})
+	assert.NoError(t, err)
+	assert.Equal(t, ModelHoltWinters, o.Model)
+	assert.Equal(t, 30, o.HistoryDays)
+	assert.Equal(t, []float64{0.7, 0.9}, o.Thresholds)
+
+	for _, v := range []url.Values{
+		{"model": {"arima"}},
+		{"horizon": {"-1"}},
+		{"threshold": {"x"}},
+		{"agent": {"foo"}},
+	} {
+		_, err := f.parseOptions(v)
+		assert.Error(t, err, v.Encode())
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&Config{Model: "arima"}, nil, nil)
+	assert.Error(t, err)
+
+	f, err := New(&Config{Capacities: []*Capacity{{Agent: "192.0.2.1", Interface: "ae0", BPS: 10}}}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), f.speeds()[interfaceKey{agent: "192.0.2.1", name: "ae0"}])
+
+	_, err = New(&Config{Capacities: []*Capacity{{Agent: "foo"}}}, nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package forecast
+
+import "math"
+
+// Model predicts future values of a daily series
+type Model interface {
+	// Predict returns the value predicted for n steps after the last value of the series
+	Predict(n int) float64
+}
+
+// Linear is a least squares linear regression
+type Linear struct {
+	intercept float64
+	slope     float64
+	len       int
+}
+
+// FitLinear fits a line through values (one per step)
+func FitLinear(values []float64) *Linear {
+	n := float64(len(values))
+	if n == 0 {
+		return &Linear{}
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range values {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	l := &Linear{
+		len: len(values),
+	}
+
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		l.intercept = sumY / n
+		return l
+	}
+
+	l.slope = (n*sumXY - sumX*sumY) / denom
+	l.intercept = (sumY - l.slope*sumX) / n
+	return l
+}
+
+// Predict returns the value predicted for n steps after the last value
+func (l *Linear) Predict(n int) float64 {
+	return l.intercept + l.slope*float64(l.len-1+n)
+}
+
+// Slope returns the change per step
+func (l *Linear) Slope() float64 {
+	return l.slope
+}
+
+// HoltWinters is additive triple exponential smoothing (level, trend and season)
+type HoltWinters struct {
+	level  float64
+	trend  float64
+	season []float64
+	len    int
+}
+
+// FitHoltWinters applies Holt-Winters smoothing to values. The smoothing factors alpha (level), beta (trend) and gamma
+// (season) are between 0 and 1. Seasonality is skipped if fewer than two full seasons are available.
+func FitHoltWinters(values []float64, seasonLength int, alpha float64, beta float64, gamma float64) *HoltWinters {
+	hw := &HoltWinters{
+		len: len(values),
+	}
+
+	if len(values) == 0 {
+		return hw
+	}
+
+	if seasonLength <= 1 || len(values) < 2*seasonLength {
+		seasonLength = 1
+	}
+
+	hw.season = make([]float64, seasonLength)
+	if seasonLength > 1 {
+		// initial trend: average change between the first two seasons, initial season: deviation from the first seasons mean
+		first := mean(values[:seasonLength])
+		second := mean(values[seasonLength : 2*seasonLength])
+		hw.level = first
+		hw.trend = (second - first) / float64(seasonLength)
+		for i := 0; i < seasonLength; i++ {
+			hw.season[i] = values[i] - first
+		}
+	} else {
+		hw.level = values[0]
+		if len(values) > 1 {
+			hw.trend = values[1] - values[0]
+		}
+	}
+
+	for i := 1; i < len(values); i++ {
+		s := hw.season[i%seasonLength]
+		lastLevel := hw.level
+		hw.level = alpha*(values[i]-s) + (1-alpha)*(hw.level+hw.trend)
+		hw.trend = beta*(hw.level-lastLevel) + (1-beta)*hw.trend
+		if seasonLength > 1 {
+			hw.season[i%seasonLength] = gamma*(values[i]-hw.level) + (1-gamma)*s
+		}
+	}
+
+	return hw
+}
+
+// Predict returns the value predicted for n steps after the last value
+func (hw *HoltWinters) Predict(n int) float64 {
+	if hw.len == 0 {
+		return 0
+	}
+
+	s := 0.0
+	if len(hw.season) > 1 {
+		s = hw.season[(hw.len-1+n)%len(hw.season)]
+	}
+
+	return hw.level + float64(n)*hw.trend + s
+}
+
+// Slope returns the change per step
+func (hw *HoltWinters) Slope() float64 {
+	return hw.trend
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
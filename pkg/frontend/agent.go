@@ -0,0 +1,113 @@
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AgentTraffic is the traffic an agent exported over a time range
+type AgentTraffic struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Mbps  float64   `json:"mbps"`
+
+	// Interfaces are all interfaces flows were received on or sent through
+	Interfaces    []*AgentInterface `json:"interfaces"`
+	Conversations []*Conversation   `json:"conversations"`
+
+	// SampleRates are the distinct sample rates of the flows
+	SampleRates []uint64 `json:"sample_rates"`
+}
+
+// AgentInterface is the traffic of an interface of an agent. In is traffic received on the interface, out is traffic
+// sent through it.
+type AgentInterface struct {
+	Name     string  `json:"name"`
+	InBytes  uint64  `json:"in_bytes"`
+	OutBytes uint64  `json:"out_bytes"`
+	InMbps   float64 `json:"in_mbps"`
+	OutMbps  float64 `json:"out_mbps"`
+}
+
+// AgentTraffic gets the interfaces, rates and top conversations (by IP) of an agent between start and end
+func (fe *Frontend) AgentTraffic(ctx context.Context, agent netip.Addr, start time.Time, end time.Time, top int) (*AgentTraffic, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("Empty time range")
+	}
+
+	seconds := end.Unix() - start.Unix()
+	res := &AgentTraffic{
+		Start: start.UTC(),
+		End:   end.UTC(),
+	}
+
+	agentCondition := "agent = " + formatIPCondition(agent.Unmap().String())
+	condition := fmt.Sprintf("timestamp BETWEEN toDateTime(%d) AND toDateTime(%d) AND %s", start.Unix(), end.Unix(), agentCondition)
+	rows, err := fe.chgw.QueryContext(ctx, agentInterfacesQuery(fe.chgw.GetDatabaseName(), condition))
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to get interfaces")
+	}
+
+	res.Interfaces = make([]*AgentInterface, 0)
+	for rows.Next() {
+		i := &AgentInterface{}
+		err := rows.Scan(&i.Name, &i.InBytes, &i.OutBytes)
+		if err != nil {
+			rows.Close()
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		i.InMbps = float64(i.InBytes) * 8 / float64(seconds) / 1000000
+		i.OutMbps = float64(i.OutBytes) * 8 / float64(seconds) / 1000000
+		res.Mbps += i.InMbps
+		res.Interfaces = append(res.Interfaces, i)
+	}
+	rows.Close()
+
+	rows, err = fe.chgw.QueryContext(ctx, fmt.Sprintf("SELECT DISTINCT samplerate FROM %s.flows WHERE %s ORDER BY samplerate", fe.chgw.GetDatabaseName(), condition))
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to get sample rates")
+	}
+
+	res.SampleRates = make([]uint64, 0)
+	for rows.Next() {
+		var r uint64
+		err := rows.Scan(&r)
+		if err != nil {
+			rows.Close()
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		res.SampleRates = append(res.SampleRates, r)
+	}
+	rows.Close()
+
+	res.Conversations, err = fe.conversations(ctx, conversationsByIP, start.Unix(), end.Unix(), []string{agentCondition}, top)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to get conversations")
+	}
+
+	return res, nil
+}
+
+// agentInterfacesQuery sums up the traffic per interface, counting each flow as in on int_in and out on int_out
+func agentInterfacesQuery(database string, condition string) string {
+	parts := make([]string, 0, 2)
+	for _, x := range []struct {
+		column string
+		in     string
+		out    string
+	}{
+		{column: "int_in", in: "sum(size * samplerate)", out: "toUInt64(0)"},
+		{column: "int_out", in: "toUInt64(0)", out: "sum(size * samplerate)"},
+	} {
+		parts = append(parts, fmt.Sprintf("SELECT %s AS i, %s AS in_bytes, %s AS out_bytes FROM %s.flows WHERE %s GROUP BY i", x.column, x.in, x.out, database, condition))
+	}
+
+	return fmt.Sprintf("SELECT i, sum(in_bytes), sum(out_bytes) FROM (%s) GROUP BY i ORDER BY i", strings.Join(parts, " UNION ALL "))
+}
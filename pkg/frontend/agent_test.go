@@ -0,0 +1,14 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentInterfacesQuery(t *testing.T) {
+	assert.Equal(t, "SELECT i, sum(in_bytes), sum(out_bytes) FROM ("+
+		"SELECT int_in AS i, sum(size * samplerate) AS in_bytes, toUInt64(0) AS out_bytes FROM db.flows WHERE agent = x GROUP BY i UNION ALL "+
+		"SELECT int_out AS i, toUInt64(0) AS in_bytes, sum(size * samplerate) AS out_bytes FROM db.flows WHERE agent = x GROUP BY i"+
+		") GROUP BY i ORDER BY i", agentInterfacesQuery("db", "agent = x"))
+}
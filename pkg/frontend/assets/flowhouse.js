@@ -1,17 +1,19 @@
 var filtersCount = 0;
 
 $(document).ready(function() {
-  var start = formatTimestamp(new Date(((new Date() / 1000) - 900 - new Date().getTimezoneOffset() * 60)* 1000));
+  var start = timeBeforeNow(preferences.time_range);
   if ($("#time_start").val() == "") {
     $("#time_start").val(start);
   }
 
-  var end = formatTimestamp(new Date(((new Date() / 1000) - new Date().getTimezoneOffset() * 60)* 1000));
+  var end = timeBeforeNow(0);
   if ($("#time_end").val() == "") {
     $("#time_end").val(end);
   }
 
   $("#filterPlus").click(addFilter);
+  $("#saveDefaults").click(saveDefaults);
+  $("#exportXLSX").click(exportXLSX);
   $("form").on('submit', submitQuery);
 
   google.charts.load('current', {
@@ -39,6 +41,7 @@ function addFilter() {
     const fieldName = $(this).val();
     const filterNum = $(this).attr("id").match(/\d+/)[0];
     $filterValue.attr("name", fieldName);
+    $filterValue.attr("type", $(this).find("option:selected").data("numeric") ? "number" : "text");
     loadValues(filterNum, fieldName);
   });
 
@@ -61,6 +64,9 @@ function parseParams(str) {
 function populateFields() {
   var query = location.href.split("#")[1];
   if (!query) {
+    for (var i = 0; i < preferences.breakdowns.length; i++) {
+      $("#breakdown option[value=" + preferences.breakdowns[i] + "]").attr('selected', 'selected');
+    }
     return;
   }
 
@@ -90,7 +96,7 @@ function populateFields() {
       continue;
     }
 
-    if (k.match(/^filter_field/)) {
+    if (k.match(/^filter_field/) || k == "tz") {
       continue;
     }
 
@@ -290,6 +296,45 @@ function formatTimestamp(date) {
   return date.toISOString().substr(0, 16)
 }
 
+// timeBeforeNow returns the time seconds ago formatted for the time inputs in the preferred timezone
+function timeBeforeNow(seconds) {
+  var t = new Date(new Date() - seconds * 1000);
+  if (preferences.timezone) {
+    // the sv-SE locale formats as "YYYY-MM-DD HH:MM:SS"
+    return t.toLocaleString("sv-SE", {timeZone: preferences.timezone}).replace(" ", "T").substr(0, 16);
+  }
+
+  return formatTimestamp(new Date(t - t.getTimezoneOffset() * 60 * 1000));
+}
+
+function saveDefaults() {
+  var timeRange = (new Date($("#time_end").val()) - new Date($("#time_start").val())) / 1000;
+  if (timeRange > 0) {
+    preferences.time_range = timeRange;
+  }
+  preferences.breakdowns = $("#breakdown").val() || [];
+
+  $.ajax({
+    url: "/preferences",
+    type: "PUT",
+    contentType: "application/json",
+    data: JSON.stringify(preferences),
+    error: function(xhr) {
+      alert("Unable to save defaults: " + xhr.responseText);
+    }
+  });
+}
+
+function exportXLSX() {
+  var query = location.href.split("#")[1]
+  if (!query) {
+    alert("Run a query first");
+    return;
+  }
+
+  location.href = "/query?" + query + "&format=xlsx";
+}
+
 function loadValues(filterNum, field) {
     return $.getJSON("/dict_values/"+field, function(data) {
         $("#filter_value\\[" + filterNum + "\\]").autocomplete({
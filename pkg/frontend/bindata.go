@@ -79,7 +79,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _assetsFlowhouseJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\x59\x6f\x73\xdb\x36\xd2\x7f\xaf\x4f\xb1\xa5\xdd\x12\xac\x19\x5a\x72\xd2\x66\x22\x55\xcf\x33\xad\x12\x37\xbe\x49\x9c\x5e\xed\xf6\x5e\xc8\x3a\x17\x22\x57\x12\x6c\x12\x60\x01\x50\x92\x9b\xfa\xbb\xdf\x00\xe0\x5f\x59\xf6\x25\xd7\x7b\x79\xf1\x4c\x4d\x2f\x76\x7f\xbb\xd8\x5d\xec\x2e\xd0\x35\x95\xb0\x60\xa9\x46\xa9\x26\xa2\xe0\x1a\xc6\xd0\x1f\xf5\x7a\x87\x24\x11\x71\x91\x21\xd7\x41\x24\x91\x26\x77\x64\x51\xf0\x58\x33\xc1\x49\x00\x1f\x7b\x00\x46\x4e\x69\x2a\x8d\xc0\x42\xc8\x8c\xea\x4b\x96\xa1\xd2\x34\xcb\x09\xc7\x0d\xbc\xa6\x1a\x09\x69\x3e\x03\x38\x86\x41\xbf\xdf\x0f\xe0\x19\xbc\xea\xf7\xe1\x19\x34\x4b\xd1\x12\xad\xf4\x1f\x82\xe3\x87\xc5\x42\xa1\x26\x01\x7c\x0d\xdf\xf6\x83\xaf\x9d\x4c\x30\xea\x01\xb0\x05\x90\x43\xe2\x1d\x68\x96\xe1\xb5\x55\xed\x05\xd1\x9a\xa6\x24\x80\xf1\x18\x3c\xcf\xd9\x05\xb0\x97\xc7\x7e\x5b\x98\xfb\x5e\x69\x3d\xf2\xe4\x3f\xb0\xfd\x2f\x59\x8d\x3c\xf9\x37\x36\x37\x1c\xc8\x93\xc6\x5e\xb3\xee\xa2\xf4\x53\x5a\x28\x2f\x88\xe2\x94\xc5\xb7\x84\x26\xc9\xa9\xa5\x5a\xce\x43\xe2\x99\xdd\x78\x41\x24\x38\xf1\x55\x31\xcf\x98\xf6\x43\x70\x1f\x7f\x2f\x50\xde\x05\x23\x03\xb6\x14\x62\x99\x62\x14\xaf\xa8\xd4\x2a\x4a\x05\x4d\x88\x1f\x17\x52\x22\x37\xec\xd6\x20\x3f\xa7\xf1\x2d\x5d\xa2\xf2\x87\x30\xf5\x63\x21\xd1\x72\xfb\x33\x63\x90\x55\x06\x3d\x80\x0d\xe3\x89\xd8\x44\x82\xaf\xa8\x5a\xc5\x2b\xca\x97\x68\x3c\x5a\x26\x0a\x90\x6a\x77\x5d\x85\x0a\xf5\x07\xfe\x4e\xd0\x64\x42\xd3\x74\x4e\xe3\x5b\x92\x48\xba\x99\xac\x3a\x01\xfa\x1c\x91\x1e\x40\x2e\xf2\x22\xa5\x1a\x4f\x19\xa6\x89\x22\xc1\xa8\x67\xac\xec\xd5\xa6\xd4\x8e\x2a\x6d\x8a\x05\x57\xba\xcc\xfb\x4b\xcc\x72\x23\x0b\xe3\x96\x9b\x2b\xa2\x17\x44\x2b\x9d\xa5\xc4\x9c\x82\x3c\xa5\x31\x92\xe3\xeb\xeb\xf3\x5f\xde\x5f\x5f\x1f\x2f\xc3\xce\xc1\xa9\x42\x50\x02\x98\x20\xd1\x3c\x47\x9e\x90\x2e\xa2\x33\xd8\x19\x70\xe8\x96\xac\xd5\x56\xfd\x6f\xa5\xf4\xf5\xc2\x90\xae\xae\xa6\x87\x1f\xdb\x3a\xee\xaf\xae\x66\xbf\x59\x45\x1d\xf9\x5f\x69\x5a\x60\x57\x7e\x6d\x48\x9f\x2a\xff\x33\x66\x62\xbd\x03\x20\x2d\xed\x51\x04\xb3\xd7\x96\xf1\x91\x8b\xfe\x6e\x91\x68\x1c\x8d\x69\x72\x4e\x33\xa7\x43\xaf\x98\x2a\x4f\xc1\xa8\xc3\x64\xe0\xce\x8b\xac\xc5\x44\xb5\x96\xc4\x63\xe6\x4c\x64\x54\xc7\x2b\x72\x7c\x95\x1c\x1d\x07\xd3\xfe\xcc\x49\xb6\x1d\x50\x32\x73\x9a\xa1\x17\x36\x2a\x4b\x1d\x26\xcd\x2d\x9b\x22\xb5\xa2\x5d\xae\xfb\xce\xc6\x9c\x57\xca\x93\xf6\x60\x63\x95\x85\x71\x2a\x14\x2a\x4d\xfc\x48\x8a\x8d\x6f\xd2\xc4\x48\x91\x36\x5e\xdb\x7f\x47\x47\xa3\xde\x7d\xaf\x95\x99\x39\x95\x0a\x7f\xa2\x92\x66\x8a\x28\x2d\x1d\xbc\x44\x5d\x48\x0e\x4a\xcb\x48\xe5\x29\xd3\xc4\xff\xca\x42\x27\x45\xdc\xf2\x71\x6e\xa5\x42\xb0\xbf\xbb\x0e\x9f\xde\xe2\x5d\x08\x36\x09\x66\x30\x76\x1c\x15\xd4\xd8\x37\xce\xcc\x49\x82\xb1\x48\xf0\x97\x9f\xcf\x26\x22\xcb\x05\xc7\x32\x85\xc1\x71\x2b\x03\x61\x64\x2d\x48\x93\xfd\x57\x47\x26\xf1\x7d\xf0\x4b\xe6\xd2\x54\x27\x63\x37\x1d\xc2\x47\xb3\xf1\xfb\xf6\x26\x77\x8e\x67\xdd\x41\x7e\x37\x55\x09\xc6\x90\x8a\x98\x1a\xce\x68\x25\x71\x51\x1a\xea\x1d\x78\xc1\x74\x30\xab\x8a\xe8\x17\x96\xb7\xda\xa6\x53\xdb\xa9\xe7\x76\xfd\xcd\xef\x85\x05\x52\x30\x76\x84\x96\x03\x0d\xf7\x42\x48\x20\x86\x9b\xd9\x46\x07\x0c\xbe\xdb\x11\x8c\x52\xe4\x4b\xbd\x1a\x01\x3b\x3a\xaa\xb4\xd9\x76\x51\x21\xd6\x9c\x53\x36\x6b\xf9\x74\x54\x73\xde\xc2\x18\xb0\x4e\x51\x43\x59\xc3\x18\x1e\xba\x9b\xe0\x74\x30\x73\x29\xe2\xb6\x78\x6b\x9b\xc2\x5c\x22\xbd\x4d\xc4\x86\xd7\xdd\xc1\xd5\x95\x9a\x0e\x22\x37\xfa\xa7\x36\x32\x63\x0f\x8e\x60\x0d\x47\xe0\xcd\xbc\xf2\xbc\xf8\x0a\x53\x8c\x35\x26\x7e\x08\xcd\x77\x69\xa0\xcd\x10\xcd\x78\x81\xee\xef\x7b\xfb\xdf\x1d\x13\xda\xed\xb3\x63\xc3\x83\xbe\xba\xfe\x6c\x58\xdb\xe1\x1e\x82\x36\x8d\xef\x71\xc8\x5d\x38\x91\x9f\xa6\x62\xa3\x76\xe1\x6a\xf2\x27\xc3\x55\xc5\xe5\x9f\xed\xea\x7b\x1c\x34\xb8\x7b\x45\xdd\xe8\x84\x69\x72\xc6\x13\xdc\xc2\xb8\xdd\x65\x46\x75\x5b\xdf\x29\xe8\x26\x5c\x2d\xa1\x23\xf0\xae\xae\x66\xa5\xa9\xb7\x9f\x2f\xa6\x25\x5b\x2e\x51\x12\xcf\x15\x60\xef\x21\x42\xd5\x09\x9e\x52\xbc\x2e\x1b\x6f\xfb\xcc\xb6\xc6\x86\xf2\xc0\xe2\x1a\xb9\x8e\x72\x69\x7f\xbf\xc6\x05\x2d\x52\x4d\x5c\xfe\x1e\x1f\xc3\xaf\x34\x65\x89\x69\xa3\x7e\x15\x00\x1f\xe6\x62\x5b\xf7\x9a\x8a\x6a\xab\xbb\x5f\x47\xc9\x6f\x75\x82\x2e\xe3\x99\x1d\x46\x6d\x7d\x3c\xe3\x9a\x54\xe4\x10\x06\xfd\x7a\xb2\x62\xea\x9c\x9e\x93\x96\x44\x00\x7f\xfe\xd9\x41\xf8\x0e\x06\xbb\xa4\xff\xb3\xe3\x59\xbf\x8a\x2e\x4d\x51\x6a\xe2\x9d\xf1\x58\x48\x89\xb1\x06\xff\x52\xe4\xe0\x8c\x1b\x42\x9e\x22\x55\x08\xc8\x35\x4a\xa0\xa6\x1a\xb2\x04\x18\xd7\xb8\x44\x09\x73\xd4\x1b\x44\x0e\x03\xa0\x3c\x71\xa8\x91\xd7\x2d\x8c\x0b\x9a\x2a\xac\xeb\x94\x2b\x93\xce\x05\x66\x56\xf3\x83\x48\xa1\x64\x34\x65\x7f\x94\x3d\xa3\xe4\x38\x1a\x83\xff\x55\x65\xf4\xd8\x87\x23\x40\xfe\xa0\x7e\x54\xeb\x56\xb0\x53\x42\x61\x0c\xde\x81\x89\xb8\x83\xeb\x75\xcd\xe9\xc4\xb9\x9e\xa4\x3e\xaf\x2c\x7f\x4a\x55\x3e\x8c\xe8\x0d\xdd\x12\xb7\xa6\xef\x72\x1c\x82\xf7\xe3\x9b\x4b\x2f\xb4\x84\x42\xa6\x43\xf0\x8e\x2d\xc0\xff\x1b\x5b\xed\x97\x5b\x4b\xa8\xa6\x97\x4e\x40\xe3\x56\x97\x12\xaa\x88\x63\x54\x6a\x58\x8f\x98\x44\x1a\xc6\xd0\x5c\x44\x74\xa1\x42\xd8\xae\x64\x73\x66\x8d\x79\x76\xdd\x14\x8b\x82\x27\xb8\x60\x1c\x93\x66\xdd\x9d\x12\x3b\x61\x5e\x27\x6c\x6d\x0e\x13\x6e\x35\xf1\xce\x85\x55\x0f\x0b\x51\x98\x9a\x54\x73\x57\xdb\xab\x09\xf7\xbd\x8a\xcc\x13\x94\xce\x87\x56\xa1\x93\xb9\x77\x46\xa3\x94\x42\xb6\x4c\xee\xd8\xb8\xd7\x82\xed\x4a\x46\x12\x55\x2e\xb8\xc2\x4b\xdc\xea\xa0\xae\xa8\xf7\x41\x27\x74\x0f\x15\x5b\xe0\x5c\xa2\x49\xb1\x9f\x68\x4e\x23\x7b\x7c\xdc\xa2\xa9\x15\x19\x09\x82\xaa\x5f\x3a\xd7\xc0\x74\xf6\x58\x5f\x34\x38\x91\x95\xdc\xd3\x12\x6b\x81\x1b\x27\x70\xd3\x16\x30\x9d\xb1\x92\xb9\x69\x64\x5c\x4c\x6e\x4c\x3c\xfa\xed\x38\x94\x22\xb5\x31\x6d\xe7\x9a\xba\xda\xc6\x9d\xde\xd4\x2c\xb6\x00\xc0\x17\x3b\x60\x4e\xc5\x2e\xb5\x04\xaa\x8a\xc9\x36\x78\x10\xc5\xea\x77\xa3\x07\xc6\xb0\x6d\xba\x99\xcd\xe8\xd2\x67\xe5\xed\x64\xcd\x54\x61\x8e\xae\x3b\x23\x54\x4a\x7a\x77\x29\x5e\x9b\xd4\xa5\xf3\x14\x89\x8d\xc8\xa8\xf4\xb6\xeb\xd9\x26\x2e\xce\x28\xa6\x2e\x34\x8d\x6f\x31\x19\x82\x96\x05\xba\x5c\xd1\x4c\xa7\x38\x04\xdf\x9c\x6a\x78\x3f\xcf\x95\xdf\xa2\x9b\x54\xb8\xd0\x77\x86\xa1\xda\xd7\x42\x70\x7d\xc1\xfe\xc0\x21\x9c\xbc\x08\x4b\xda\x5c\xa4\x1d\x4c\x53\x56\x53\x93\x81\xfe\xc1\xf3\xe7\xcf\xfd\x76\x6a\xae\xbe\xdf\x32\xd5\xa0\x55\xda\xcd\xa5\xd6\x0f\xdb\xc4\x3d\xaa\x77\x60\xc3\xc6\xff\x9a\xa6\x2c\x1e\xba\x4a\xd3\x90\x1f\x9a\xd5\x36\x7f\xf0\xa2\x8a\x42\xb5\xba\x94\x2c\x49\x19\x47\xb5\x57\xe5\xe2\xb9\xf9\x69\x69\x8d\xcd\x70\x3d\x84\x41\x7f\x17\x27\x63\x5c\xc8\x1f\x9f\x04\xc3\x57\xe6\xc7\xdf\x95\x74\x2f\x03\x43\xf0\xdf\xbe\x1d\x66\xd9\x50\xa9\xc6\x27\x9f\xe1\x8e\xd6\x1e\x4f\x3a\x99\x56\xea\x59\x77\x63\x90\x31\x6e\x6f\x29\x43\xe8\x87\x3b\x61\x79\x8f\x4b\x3a\x67\x5a\x41\x8e\x12\x14\xc6\x82\x27\xff\x8b\xd2\x7f\x35\x16\x2b\x64\xcb\x95\x1e\x82\x8a\x25\x22\x8f\xdc\x9f\xf0\x35\xf4\xa3\x97\x8e\xc1\x96\xea\xef\x25\xd2\x46\xd9\x86\x25\x7a\x35\x04\xff\x55\xff\x4b\x3f\x84\x92\x58\x01\xf9\x2f\x0d\xb5\xb2\x54\xe4\x43\xf0\xbf\x69\x08\x73\x1a\xdf\x2e\xa5\xe9\x32\x13\x67\x6f\xb3\x01\xa5\xa5\xb8\x35\x41\x3f\x88\xe3\xb8\xb5\x03\x47\xff\x87\xd3\x39\xd8\xb7\x87\x07\xa0\xfe\xc1\xc2\xfe\x2b\x51\xac\x6b\xd4\x10\xa6\xfe\xc1\xc9\xe0\xd5\xb7\xa7\xcf\xcd\x25\xe1\xe0\xc5\xe4\xfb\xd3\x6f\xfa\xf6\xf3\xf4\x74\x32\xe8\xbf\x2c\x3f\xbf\x79\x79\x72\x62\x3f\x5f\x4d\x4e\x5e\xfe\xd0\xf7\x67\x0e\x84\x72\x96\xd9\xa2\xd7\xd8\x6c\x2f\x05\x45\xde\x4d\x9e\xa4\x90\x25\x9b\x99\x8d\x2a\x2a\x52\xc5\xf8\x72\x08\xbe\x28\x74\xa7\x1e\xa5\xb8\x44\x9e\x34\x98\xb9\x50\xcc\x89\xfb\x5c\x70\xec\xf0\x6a\x21\x52\xcd\xf2\x56\xf5\xfa\x8b\x89\x50\xad\xaa\x95\xd8\x58\xd7\x4d\x44\x82\x6e\x3b\x1d\x1b\x19\xaf\xfc\x7f\xe2\x28\xb9\x60\x75\x15\x2e\xe7\x14\x94\xac\x9d\xc5\xfd\x21\x7c\xb4\x82\xaf\xa9\x5a\x95\x36\x4e\x5f\x84\xf0\x62\xd6\xa8\x1d\xec\xe3\x39\x09\xe1\xa4\xc5\x73\xf2\x08\x4e\x9b\xe7\xf9\x23\x38\x6d\x5d\x2f\xf6\xf1\x0c\x42\x18\xcc\xaa\x5c\x32\xfd\x6e\x54\x0d\x0a\x36\xeb\x61\x6c\x9f\x3e\xf7\x76\x3e\x73\x22\xdc\x14\x52\x3d\x1a\x47\x4b\xd4\x6f\x52\x34\x9f\x3f\xdc\x9d\x25\xc4\xaf\x87\x1c\xdf\x3d\x8b\xda\xbf\x23\x33\x7b\x12\x37\xc2\x95\xfd\xb1\xfd\x3a\x16\x17\x4a\x8b\xec\x9d\x4d\x8a\xd7\xcc\xde\xa1\x1f\x85\xb7\xac\xd7\x2e\x81\xdc\x55\x77\x47\x3a\x62\x9c\xa3\x7c\x7b\xf9\xfe\x1d\x8c\xc1\xf7\x47\xe6\xca\x32\x49\x91\x4a\xa0\xfc\x0e\x70\xcb\x94\x66\x7c\x59\xa6\x60\x63\x82\x3d\x2d\x30\xae\xcc\x8b\x1c\x61\xd4\x66\x28\x32\xdb\xd6\xed\xa0\xb4\x44\x7d\x5e\x64\x73\x94\x1f\x16\x13\xb7\x42\xda\x3b\xd2\x66\x2e\x68\xef\x23\x96\x48\x35\x96\x5b\x21\xbe\x5d\x77\xe6\xdb\xcf\x28\x4e\xa9\x52\xef\x98\xd2\x11\x4d\x92\x6a\x3d\x04\xf7\xf1\x4c\x65\xcd\xf7\x5c\xc8\x04\x65\x75\xcf\x2f\xd5\xcd\x45\x72\xf7\x94\x3a\xb3\xee\x97\x8f\x54\x66\x9e\x4b\x51\xdb\x01\x70\xe0\x06\xc0\x72\x73\x9d\xb9\xcf\x21\x4b\xb1\x79\x0a\x57\xfa\x9d\x97\x3d\xeb\xb4\x09\xa6\xe9\x53\x32\x49\x23\x53\x72\x47\xca\xa4\x66\xb4\x53\xd1\x60\x5c\x46\x65\x4a\x18\x3c\x83\x41\x00\x5f\x96\x84\x72\xe4\x9c\xed\x87\xb1\x85\xda\xc4\xfe\xa4\x9f\x6f\xfd\xb6\x79\x29\x9d\x63\xfa\xc9\xe6\xd5\xdc\x76\x58\x9f\x08\xae\xd1\x5e\x54\xab\xf8\xbb\xb8\xbf\x33\x5c\x84\x55\x77\x41\xb1\x29\x1f\x83\x27\x2b\x96\x26\xa4\xb6\xed\x91\xf5\x5a\x47\xb9\x6e\x03\xd5\xe1\x90\x62\x53\xbd\x1c\x35\x0f\x82\xae\xee\xd8\xfb\x7d\x33\xfb\x5a\xec\x24\x79\x63\x2e\xed\x26\x93\x90\xa3\x24\x7e\x26\x0a\x85\x62\x8d\xd2\x0f\xe1\xc1\xdb\xa6\x6d\x61\x6c\xb9\x4a\x4d\x17\xbb\xb0\xa0\xc4\x1e\xd8\x10\x3a\x87\x35\x84\xb6\xc6\x7a\x72\xaf\xbf\x9e\x52\x5d\xe8\xc7\x34\x4b\x54\xa8\xdf\x56\xea\xf7\x2a\x7e\xa0\xeb\x3e\x28\x93\xa1\xbe\x7b\xba\xf3\xd3\x76\x99\x75\xe2\xde\xd2\xd0\xe1\x32\x72\x3b\xcf\x97\xbb\xff\x8f\x28\xa1\x1a\x3b\x0f\xb5\x86\x10\x69\x71\x76\xf1\xe1\x42\x4b\xc6\x97\x24\x88\x54\x31\x57\x5a\x92\x7e\x08\x83\x6f\xbb\xb7\xb6\x27\x5e\xa2\xbb\xf7\x68\x38\x34\xf9\xf4\xb7\x8b\x0f\xe7\xc4\x3b\x4e\x58\xac\xdd\x5b\x8e\x3a\xf6\x8e\x2c\x77\xcb\x7f\xcd\xe5\xcf\xfd\x7b\xf4\xfd\xa7\x7a\x62\xaf\x9f\x7f\x68\xa1\x45\x2c\xb2\x3c\x45\x8d\xa4\x7d\x5f\x02\x50\xa2\x90\x31\x0e\x9d\xeb\x9b\xfb\x52\xed\xf2\x51\xef\xfe\x5f\x01\x00\x00\xff\xff\xba\xa9\xa0\x28\x3e\x1c\x00\x00")
+var _assetsFlowhouseJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xed\x19\xfd\x57\xdb\x38\xf2\x77\xfe\x0a\xad\xe9\xd6\x4e\x49\x43\xa0\x5f\xaf\x61\xd9\x7d\x6d\x28\x5b\xf6\x01\xed\x15\x7a\xb7\x77\xc0\xb1\x8e\xad\x24\x06\xc7\xf2\x5a\x72\x80\xb6\xfc\xef\x37\x33\x92\x6c\xd9\x09\x1c\x7d\xb7\x3f\x1e\x7d\x7d\x88\xd1\x7c\x69\x66\x34\x33\x1a\xcf\xc3\x82\x8d\x93\x54\xf1\x42\x0e\x45\x99\x29\xb6\xcd\xfa\x5b\x2b\x2b\x8f\x82\x58\x44\xe5\x8c\x67\xaa\xd3\x2b\x78\x18\xdf\x04\xe3\x32\x8b\x54\x22\xb2\xa0\xc3\xbe\xae\x30\x36\x07\x3a\xa9\xc2\x02\x09\x54\x32\xe3\x6f\xf9\x58\x14\xfc\x50\x5c\x05\x79\xc1\xc7\xbc\xe0\x59\xc4\x65\x0f\x77\xce\x8b\x30\x9b\xf0\xce\x16\x10\x25\x63\x16\x3c\x0a\xbc\x55\x02\x13\xb5\xd7\xe9\xcd\xc3\x14\x78\x6e\x6f\x33\xcf\xd3\xac\x19\x5b\x8a\x43\x6b\x62\x73\xbb\x62\x14\xe0\x59\xbc\x20\xbe\xbf\x28\x09\xd0\xfe\x8b\x9c\x1a\x03\x56\xb5\x0c\xdc\xd7\xc6\xf9\x98\x96\x12\x30\xa2\x34\x89\x2e\x83\x30\x8e\x77\x09\x4a\x98\x88\x24\xc3\x39\xdf\xe1\xe3\xb0\x4c\x55\x8d\xe6\x02\x2b\x4c\x7e\x9d\x8b\x42\xfd\xbe\x7f\xf4\x7b\x85\x57\x83\x2c\x16\x9c\x65\x06\xdb\x60\x6c\x5f\x96\xa3\x59\xa2\xfc\x2e\xd3\x8b\xbf\x95\xbc\xb8\x01\x34\xc0\x9b\x08\x31\x49\x79\x2f\x9a\x82\x59\x64\x2f\x15\x61\x1c\xf8\x51\x59\x80\xe5\x11\x9d\x0e\xe8\xe7\x61\x74\x19\x4e\xb8\xf4\x07\xec\xc4\x8f\xc0\x42\x84\xed\x9f\xe1\x01\x49\x18\x83\xff\x57\x49\x16\x8b\x2b\x90\x36\x0d\xe5\x14\x10\xc0\x5b\x60\x55\xeb\x6f\x16\x58\x6b\x35\x05\x4a\xae\x3e\x64\xfb\x20\x75\x18\xa6\xe9\x08\xe4\x04\x71\x11\x5e\x0d\xa7\x0d\x27\x7d\x0f\x09\xa0\xe7\x22\x2f\xd3\x50\xf1\xdd\x84\xa7\xb1\x0c\x00\x88\x5a\xae\x54\xaa\x54\x86\x37\x3a\x45\x22\x93\xca\x84\xef\x31\x9f\xe5\x48\x0b\xaa\xd7\x6e\xb3\x40\x30\xe6\x54\xcd\xc0\xff\x10\xcc\x00\x88\x78\xb0\x7e\x7e\x7e\xf8\xf9\xe0\xfc\x7c\x7d\xd2\x6d\xc4\x7f\xe5\x28\x03\x04\xca\x30\xcf\x21\x2a\x82\x26\x47\xad\xb0\x56\xe0\x91\xde\x22\xad\x49\xfc\x1f\x86\xfa\x7c\x8c\xa0\xd3\xd3\x93\x47\x5f\x5d\x19\xb7\xa7\xa7\x67\x7f\x90\xa0\x06\xfd\xdf\xc3\xb4\xe4\x4d\xfa\x39\x82\x1e\x4a\xff\x89\xcf\xc4\xbc\xc5\xa0\x20\xd8\x9d\x1c\xf0\xac\x8e\xf2\x3d\xed\xfd\xf6\x5d\xaf\x0d\x0d\x38\x87\xe1\x4c\xcb\x50\xd3\x44\x9a\x5b\xb5\xd5\x40\x42\x76\x87\xe5\xcc\x41\x0a\x95\x2a\x02\x2f\xc1\x3b\x36\x0b\x55\x34\x0d\xd6\x4f\xe3\xb5\xf5\xce\x49\xff\x4c\x53\xba\x06\x30\xc8\x19\x88\xf1\xba\xb5\xc8\xce\x9d\x98\xea\x26\x47\x4c\x2b\x6b\x0c\xd1\x1c\x78\x22\x47\xfd\x07\x92\xa7\x3c\x52\x1c\x05\xc7\xa1\x0a\x81\x2d\xe4\xb4\x22\x89\x20\x03\xfc\xc2\xf0\x8f\x11\x2f\x3c\x36\x60\x9e\xe2\xd7\x90\x67\xb4\x0c\xbc\x4a\x24\x40\x06\xd5\x61\xda\x9a\xdc\x36\x8c\xa7\x2d\x6f\xae\xf3\x82\xf1\xac\x66\x51\x2a\x24\x97\x2a\xf0\x7b\x85\xb8\xf2\x31\x14\x91\x2a\x70\xf9\xb9\x3e\x5a\x5b\x83\xf0\x5f\x71\xa2\x3f\x0f\x0b\xc9\x3f\x86\x45\x38\x93\x90\x0c\x0b\xcd\xbe\xe0\xaa\x2c\x32\x48\xc5\x45\x4f\xe6\x69\x02\xdc\x1f\x13\xeb\xb8\x8c\x1c\x3f\xe6\x44\xd5\x65\xf4\xbb\xe9\xd4\x93\x4b\x7e\xd3\x65\x14\x68\x67\xe0\x32\xc2\xb0\xac\xb6\x7d\x74\x58\x1e\xc4\x3c\x12\x31\xff\xfc\x69\x6f\x28\x66\xb9\xc8\xb8\xb9\x26\x4c\x63\x4b\x64\x81\xb4\xc4\xa4\xbe\x61\xa7\x6b\x78\xb9\x7c\xe6\x1b\x64\xa3\xaa\xa6\xa1\x43\x43\x9a\xc2\x83\xdf\xba\x87\x6c\xa5\x80\xaa\xd8\xfc\x89\x99\x0f\x84\xa4\x22\x0a\x11\xb3\x37\x85\x3a\x63\x14\xf5\x56\xbd\xce\xc9\xc6\x99\x4d\xfc\x3f\x10\xae\x3d\x26\x24\x53\x16\x20\x87\x84\x6a\x1b\xfc\xfa\x89\xb9\x35\x6a\x04\xf5\xed\x12\x12\x60\x06\x49\x94\x67\x13\x35\x05\x94\xb5\x35\x4b\xad\x93\x41\x85\xc3\x74\x60\x9d\xd0\x51\xb7\x3d\xb6\x76\x07\xab\x93\xe4\x0c\xf6\xbc\x33\xcf\x04\xbf\x6f\x23\x11\x52\x73\xbd\x36\x86\xb9\x75\xcc\xd3\xa8\x6f\x74\x8e\x77\x7f\x96\x74\x60\x09\xea\x13\xc0\x71\x34\x62\x2f\x3b\x5f\x93\x70\xd9\xb9\xa8\x7c\x5a\x8e\x15\x26\xa8\xed\xf8\x7e\xab\xc2\xbc\x04\x4c\x5e\x5d\x57\x84\xcc\x01\xb2\x18\x16\x01\x07\x37\xe8\x50\xd6\xae\xb8\xa4\x82\x5b\xd9\xc5\x7b\xb0\x59\xe7\x0f\x37\x1f\x45\xb2\x4a\xb2\x92\xbb\xe6\x6c\xa9\xe0\xb6\x13\x0d\x1d\x16\xfa\x8c\xf9\x77\xb3\xa5\xee\x61\x91\x69\xdd\x54\xdc\xcd\xb2\xcd\x4e\xe4\xbb\xa9\xb8\x92\x6d\x76\x15\xf8\xc1\xec\x6c\xa2\xfd\xb7\x5b\x89\xd6\x3b\xec\xdb\x37\x66\x44\x7d\x71\x84\x2c\xe5\x33\xa7\xd6\x10\xc8\xf6\xb2\x98\x5f\x83\xbf\x9d\xf2\xbb\x55\xf5\x4f\xad\x4a\x87\xbe\x73\x88\xc0\x89\x50\x6a\x8c\xde\x97\xdf\x4f\xa6\x8a\x64\x32\x01\x81\x9e\xae\x4c\xde\x22\x07\x5b\x22\xef\x13\x3c\x37\x1d\x89\x9b\x68\x9c\x7e\xca\x64\x19\x3e\x87\x08\xee\xc1\x7d\xc6\xdf\xa6\x71\x0b\x74\x30\xaf\xaf\x33\xa8\x08\x49\x8c\xfd\x85\x6f\xbd\xe1\xb3\x91\xb8\xae\x8a\xb0\x85\x52\xd9\xf3\x2b\x97\xf9\x4e\x89\x6c\x22\xee\x51\xb3\x4d\x49\x1d\x96\x81\x05\x77\xd9\x46\xdd\xc2\x26\xf2\x30\x3c\x0c\x1c\x0a\x72\xa0\xcb\xe1\x27\xb6\xd1\x06\xfd\x0c\x1c\xe0\xc7\x7a\x37\x4c\x79\x01\x29\x72\x2f\x83\xfe\x0f\x1a\x40\xc5\xfc\x63\x91\x33\xad\xdc\x80\xe5\x29\x0f\x25\x87\x4e\x1a\x6c\xc9\x42\x4c\xe1\x49\xcc\x12\xf8\x0b\xac\xce\x46\x5c\x5d\x71\x9e\x81\x88\x10\x3a\x6d\xe2\xda\xf3\x9a\xd9\x7c\x1c\xa6\x92\x57\x49\x4b\xe7\x76\x6d\x02\x6c\x62\xe1\xf4\x12\xea\x2d\x30\xfd\x62\x0a\x9d\xc1\x58\xdb\x66\xfe\x63\xab\xf4\xb6\x0f\xee\x82\x0c\xda\x4e\x26\x76\x9f\x08\x1b\x79\x1f\x24\x40\xce\xc7\xec\x4b\xec\x56\x9a\xea\x34\xfc\x5c\xb5\x98\xdf\x57\x4b\x96\x96\x92\x56\x8a\x7e\xd4\x0b\x2f\xc2\xeb\x40\xef\x61\x1b\x02\x8d\xc4\xaf\xef\x8e\xbd\x2e\x01\xca\x22\x85\xbf\xd7\x89\xc1\x2f\xa8\x2b\xad\xf4\x1e\x36\x23\xc7\x9a\x80\x3a\x0f\x0d\x95\x65\x04\x55\x44\x0e\xaa\xde\x3b\x28\x10\xb1\x8b\x0f\x2d\x55\x42\x64\x5c\x4f\x8b\xfa\xce\xa2\x7a\xb4\x8f\xd7\xb9\x84\xa0\x87\xce\x87\xc7\xf5\xbe\xbe\x25\xd4\x7a\x9f\xc7\xc9\x1c\x2f\x13\x88\x0a\xbc\x43\x41\xe2\xa1\x6e\x94\x98\xa0\x2a\x6c\x7b\xbc\x0a\x70\xbb\x62\xc1\xc0\xbc\xd0\x36\x24\x81\x9a\xe6\x56\x2b\xcd\x8b\x42\x14\x8e\xca\x0d\x1d\x97\x6a\x00\x18\xd0\x24\x48\x70\xb2\xe4\xc7\x00\xe8\x54\xe9\xf5\xb6\xd3\x70\xdd\xa2\x60\x62\x0c\x37\x14\x43\xec\x63\x98\x87\x3d\xba\x3e\x7a\x13\x73\xc5\x2c\xe8\x74\x6c\xf1\xd4\xa6\x61\x27\x67\x77\x15\x49\xe4\x43\x6d\xe1\xb2\xfa\x58\x11\x5c\x68\x82\x0b\x97\x00\xcb\xa4\xa5\xb9\x70\x7b\x05\xf4\xc9\x05\xfa\xa3\xef\xfa\xc1\x90\x54\xca\xb8\xc6\xc5\xbc\xea\xf2\x3d\xb9\xa8\x50\x28\x01\xb0\x1f\x5a\xcc\xb4\x88\x36\xd4\x30\xb2\xc9\xe4\xba\xb3\xe0\x45\xfb\xbb\x96\x03\x04\xd7\x75\x69\xa3\x88\x36\x36\x33\xcf\xb6\x79\x22\x4b\xbc\xba\xfa\x8e\x84\x45\x11\xde\x1c\x8b\x1d\x0c\xdd\x70\x94\xf2\x80\x3c\xb2\x65\xac\xad\x0b\x38\xfa\x45\x2b\x95\xc8\x23\x05\x2f\x3c\x1e\x0f\x98\x2a\x4a\xae\x63\x45\x25\x2a\x85\x98\xf7\xf1\x56\xb3\x83\x51\x2e\x7d\x07\x8e\xa1\x70\xa4\x6e\x10\xc1\x9e\x6b\x0c\x65\xe9\x08\x52\xc7\x80\x6d\x3e\xef\x1a\xd8\x48\xa4\x0d\x9e\x98\x56\x53\x8c\x40\x7f\xf5\xd9\xb3\x67\xbe\x1b\x9a\xd3\x37\xd7\x89\xac\xb9\x59\xe9\xc7\x50\x9c\xfd\xae\x0b\x5c\x22\xba\xc5\xb6\x5b\xdb\x5f\x81\x49\xa2\x81\xce\x34\x35\x78\x51\x2d\x57\xfd\x8d\xe7\xd6\x0b\x76\x77\x52\x24\x71\x0a\x17\x56\x2e\x15\x39\x7e\x86\xff\x1c\xa9\x11\xbe\x08\x80\x4f\xbf\xcd\x67\x96\x64\xa2\xf8\xf5\x5e\x66\xfc\x35\xfe\xf3\xdb\x94\x98\x9f\x43\xe0\xe9\xbf\x7f\x3f\x98\xcd\x06\x52\xd6\x36\xf9\x0e\x73\x38\x67\xdc\x6c\x44\x9a\x91\x33\x6f\xfa\x00\xd4\xa5\xa7\xd5\x80\xf5\xbb\x2d\xb7\x1c\xf0\x49\x38\x4a\x94\x64\x39\x54\x1e\x09\xad\x65\x16\xff\xdf\x4b\x7f\xa9\x2f\xa6\x3c\x99\x4c\x41\x3f\x19\x15\x50\xd5\x7b\xfa\x4f\xf6\x84\xf5\x7b\xaf\x34\x02\xa5\xea\x37\xd0\x94\xd7\xc2\xae\x92\x58\x4d\x41\xd4\xeb\xfe\x8f\xd0\x7b\x1b\xa0\x65\xe4\xbf\x42\xa8\xd5\x54\xe4\x00\x79\x51\x03\x70\xc2\x33\x29\xb0\xca\x0c\xb5\xbe\xf5\x01\xe0\xb9\x2a\x2e\xd1\xe9\xab\x51\x14\x39\x27\xd0\xf0\x7f\x68\x99\x1b\xcb\xce\xb0\xc0\x14\xdc\x40\x3f\x86\x0b\x99\x46\xe2\xb8\x6b\x75\x73\xe3\xf5\xcb\xdd\x67\xf8\x62\x58\x7d\x3e\x7c\xb3\xfb\xa2\x4f\xcb\xdd\xdd\xe1\x46\xff\x95\x59\xbe\x78\xb5\xb9\x49\xcb\xd7\xc3\xcd\x57\x6f\xfb\xfe\x99\x66\x12\x66\xc9\x8c\x92\x5e\xad\x33\xbd\x10\xca\xbc\x19\x3c\x71\x59\x18\x34\xec\x8d\x2c\x14\x1a\xaa\x24\x9b\x80\x66\xa2\x54\x8d\x7c\x94\x42\x53\x95\xc5\x35\xcf\x5c\xc8\x44\x93\xfb\x19\x74\x3c\x0d\x5c\x25\x44\xaa\x92\xdc\xc9\x5e\xff\x63\x20\xd8\x5d\x39\x15\x57\x64\xba\x21\xb4\x5b\xfa\x38\x0d\x1d\x21\x40\x8d\xfd\x37\x35\x24\x17\x49\x95\x85\x4d\x9f\x02\x2d\x9d\x1b\xc5\x7d\x58\x12\xe1\x4e\x28\xa7\x46\xc7\x93\xe7\x5d\xf6\xfc\xac\x16\xbb\xb1\x0c\x67\xb3\xcb\x36\x1d\x9c\xcd\x3b\xf8\xb8\x38\xcf\xee\xe0\xe3\xca\x7a\xbe\x0c\x67\x03\x1a\xeb\x33\x1b\x4b\x58\xef\xb6\x6c\xa3\x40\x51\x0f\x85\x2b\xe3\x57\xcb\x2b\x1f\xde\x08\xdd\x85\xd8\xa1\x78\x6f\xc2\xd5\xbb\x94\xe3\xf2\xed\xcd\x1e\x0e\x5e\x6d\x93\xe3\x77\x74\xab\x8f\x7f\xf7\xb0\xf7\x0c\x74\x0b\x67\xea\xa3\x3b\x36\x8c\x4a\xa9\xc4\x6c\x9f\x82\x62\x27\xa1\x07\xf5\x9d\xec\x09\xf5\x5c\x07\x90\x7e\xf7\xb6\xa8\x7b\x49\x96\xf1\xe2\xfd\xf1\xc1\x3e\xf0\xf1\xfd\x2d\x7c\xb2\x0c\xa1\xb7\x87\xa6\x3e\xbb\x61\x1c\x92\x30\x3c\xf0\x26\x26\x04\x6b\x15\xe8\xb6\x00\x85\x51\xaf\xa7\x01\x5b\x2e\x42\x39\xa3\xb2\x4e\x8d\x12\xe8\x75\x48\x43\xb3\x0f\xe3\xa1\xde\x09\xdc\x13\x29\xec\x0b\xdc\x73\x40\xa2\x81\x27\x93\x39\x4a\xe0\xd3\xbe\x56\x9f\x96\xbd\x28\x0d\xa5\xdc\x07\xdd\x7a\xf0\xb6\xb4\xfb\x70\x1f\x69\xf1\x54\xce\xea\xf5\x48\x14\xd0\x0b\xda\x47\xbf\x11\x37\x12\xf1\xcd\x7d\xe2\x70\xdf\x37\x93\x35\xec\xe7\x52\xae\xa8\x01\xdc\xd0\x0d\xa0\x39\x5c\xa3\xef\xd3\x9c\x0b\xe8\x4f\xee\xe1\x5b\xf8\x8d\x91\x27\x19\x6d\xc8\xd3\xf4\x3e\x9a\xb8\xa6\x31\xd8\x3d\x89\xa1\xd9\x6b\x65\x34\xe0\xa1\x9d\x70\x02\xcd\xdf\x53\xb6\xd1\x61\x3f\x1a\x80\x69\x39\xcf\x96\xb3\xa1\x44\x8d\xbe\xdf\xec\xe7\xd7\xbe\xab\x5e\x1a\x8e\x78\xfa\x60\xf5\x2a\x6c\x6a\xd6\x87\x90\x49\x38\x3d\x54\xad\xff\xb5\xdf\xf7\x11\x2b\x48\xec\x5b\x50\x5c\x99\x29\xf9\x70\x9a\xa4\x71\x50\xe9\x76\xc7\x7e\x25\xc3\xec\x93\xa3\x1a\x18\x40\x61\xc7\x48\xf5\x14\x53\xe7\x1d\x7a\xdf\xd7\xbd\x2f\xf1\x8e\xe3\x77\xf8\x68\xc7\x48\xe2\x70\x0d\x02\x7f\x26\x4a\xc9\xc5\x9c\x17\x10\x41\x0b\x03\x59\x2a\x61\x50\xc1\x52\xac\x62\x47\xc4\x34\xa0\x0b\xdb\x65\x8d\xcb\xda\x65\xae\xc4\xaa\x73\xaf\x56\xf7\x89\x2e\xd5\x5d\x92\xa1\xd9\xe7\xea\xbd\x15\xbf\x54\xf0\x82\xac\xdb\x8e\x09\x86\xea\xed\xa9\xef\x8f\x6b\x32\x32\xe2\xd2\xd4\xd0\xc0\x42\xba\xd6\xcc\x55\x77\x82\xd8\x16\x43\x95\x9b\xe5\x98\xb1\x78\x63\xba\x8c\x80\x9e\x12\x7b\x47\x1f\x8e\xe0\x91\x95\x4d\x02\x78\xd6\x97\x23\x28\xd6\x41\x1f\xb2\xea\x4b\x7a\xb5\x41\xbe\x69\x7c\x88\x33\xb4\x92\xa9\x29\xa7\x1d\xd3\xd3\x49\x16\x4e\x84\x11\xa9\x78\x4c\x17\xb3\x42\x49\xb2\xbc\x84\x0e\x30\xc9\x08\xa4\x47\xab\x70\xe9\x69\xf3\x0b\x94\xc8\x5a\xe7\xe6\x47\x3f\xc3\xba\x7e\xe1\xdb\x7c\x0e\xaf\x15\x1e\x54\x8b\x0e\xd8\xd0\x6a\xf1\x84\x6a\x76\x35\x69\x69\x7f\xb5\x44\x69\xd6\x6b\x78\x34\x50\x47\xce\x9f\x1e\xbd\xa3\x99\x01\x24\x39\x7d\x00\x38\x8c\x64\xde\x3f\xe1\xe7\xe9\xc1\xc1\xd3\x9d\x1d\x06\xbd\xf4\xc1\xc1\xe0\xe8\xc8\x73\x67\x24\x0a\x6c\xb7\x4f\x64\xc6\x7c\x1e\xb1\xf2\xba\xec\x2b\x8a\xfa\x17\x88\x1a\xb0\x65\x0a\xdc\xd6\xdf\xab\x3c\x06\xe8\xde\xb1\xd7\xb2\x7c\x15\x0f\x76\xfe\xd1\x72\x65\x75\x74\x05\x47\xa7\xba\x72\x6c\x78\x7f\x18\x8f\x21\x0e\xc1\x24\x4f\xd8\xcb\xbe\xb5\x46\x2b\x30\xdc\x2f\x98\xce\xf8\x04\xd5\xfb\x64\x3e\x13\xd6\x12\x96\x7d\x6e\x45\x83\x2f\x22\x34\xbe\xfc\x02\xce\x3a\x09\xb7\x9e\xa8\xb9\xff\x5c\xbf\x71\x97\x7f\x54\x36\x1f\x7f\x09\x5b\x5b\x82\xdd\x31\x8f\x37\x5f\x05\x9d\x41\xb4\xf9\x1e\xfc\xed\x1b\x3d\xca\xdb\xd3\x1c\x33\xbc\x71\x98\x99\x19\x8d\x19\xf3\x7c\xfc\x6c\xc7\x3c\x91\xce\x90\x66\x9a\x03\x57\x0d\xde\x1d\xd4\x36\xac\x5f\x48\x91\x79\xf5\xbc\x67\xc0\x7e\x3b\xfa\x70\x08\xd9\x1a\x43\x20\x19\xdf\xb8\x21\xd7\x79\xc0\x2c\xc5\xcc\xf1\x3e\x67\x54\x64\x95\x20\xef\xb0\xd8\xb8\x07\x64\xb3\x35\xb6\x30\x5a\x71\xde\xf7\x2d\xdf\xd6\x5f\x9d\xff\x82\xc1\x98\xd1\xed\x53\x99\xb1\xd0\x30\x19\x27\x85\x54\xcd\x61\x61\x15\xac\x0b\xb3\xbc\xf6\x94\x0c\xc7\xb7\x8f\x75\x2c\x6f\x5f\xa7\xf2\xda\x6b\xea\x7e\xcf\x17\xba\xe6\xa8\x0e\x9c\x0a\x21\x8f\x76\x0f\xbc\xf5\x38\x89\x94\x1e\x17\xcb\x75\x6f\x8d\xb0\x9d\x14\x5d\xcf\x97\xea\xd1\xd5\xd2\x11\xb3\xfd\xbc\x59\x4d\x98\xc3\x52\x89\x48\xcc\x72\xe8\x2e\x78\xe0\x8e\x64\xa0\x53\x16\x65\x11\x41\x5c\x50\x76\xaf\x47\x32\x55\x56\x87\x63\xfd\x07\xc5\x41\xf4\xc6\x81\x21\x00\x00")
 
 func assetsFlowhouseJsBytes() ([]byte, error) {
 	return bindataRead(
@@ -94,12 +94,12 @@ func assetsFlowhouseJs() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "assets/flowhouse.js", size: 7230, mode: os.FileMode(436), modTime: time.Unix(1740341436, 0)}
+	info := bindataFileInfo{name: "assets/flowhouse.js", size: 8577, mode: os.FileMode(436), modTime: time.Unix(1791961461, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _assetsIndexHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcc\x58\x5f\x6f\xdb\x36\x10\x7f\xef\xa7\xb8\xb1\x2f\x1b\x56\x8a\x76\xd2\x00\x5b\x26\xf9\x61\xdd\xba\x97\xae\xe8\xb6\xf4\x69\x18\x0c\x4a\x3c\x5b\x4c\xf8\x47\x23\x29\x3b\x5e\x90\xef\x3e\x50\x92\x6d\x49\xb1\x53\x07\x30\x86\xb5\x40\x24\x1d\x8f\xbf\xfb\xf1\xfe\x90\x67\xa6\x5f\x09\x5b\x84\x4d\x85\x50\x06\xad\x66\xaf\xd2\xf8\x00\xc5\xcd\x32\x23\x68\xc8\xec\x15\x40\x5a\x22\x17\xf1\x05\x20\xd5\x18\x38\x14\x25\x77\x1e\x43\x46\xea\xb0\xa0\xdf\x91\xfe\x90\xe1\x1a\x33\xb2\x92\xb8\xae\xac\x0b\x04\x0a\x6b\x02\x9a\x90\x91\xb5\x14\xa1\xcc\x04\xae\x64\x81\xb4\xf9\x78\x03\xd2\xc8\x20\xb9\xa2\xbe\xe0\x0a\xb3\xe9\x1b\xf0\xa5\x93\xe6\x8e\x06\x4b\x17\x32\x64\xc6\x6e\xa1\x95\x34\x77\xe0\x50\x65\xc4\x87\x8d\x42\x5f\x22\x06\x02\xa5\xc3\x45\x46\xca\x10\x2a\x7f\xcd\x98\x0f\xbc\xb8\xab\x78\x28\x93\xdc\xda\xe0\x83\xe3\x55\x21\x4c\x52\x58\xcd\x76\x02\xf6\x36\xb9\x4a\x2e\x58\xe1\xfd\x5e\x96\x68\x69\x92\xc2\x7b\x02\x2f\x30\x56\x58\x81\xc9\xed\xdf\x35\xba\x4d\x63\xa1\x96\x6c\x9a\x4c\x2f\x92\x29\x0b\x25\x6a\xf4\x2c\xe7\x1e\x59\xab\x40\x6b\xd9\xe0\x77\xf0\x41\x06\x85\xb3\xf7\xca\xae\x4b\x5b\x7b\x4c\x59\x2b\x68\x07\x1b\x8b\xed\x3b\xc0\xeb\xa2\xf6\xc1\xea\xb9\xc2\x25\x1a\x01\x0f\x9d\x18\x40\xf3\x7b\x5a\xa2\x5c\x96\xe1\x1a\xde\x4e\x26\xd5\xfd\x0f\xbb\x21\xbb\x42\xb7\x50\x76\x4d\x37\xd7\xc0\xeb\x60\xb7\x23\x8f\xdd\x33\x09\x3c\x57\x48\xbd\x86\xd0\x47\xac\xb8\x10\xd2\x2c\xaf\x61\x92\x5c\x5c\x39\xd4\xc3\x69\x29\x6b\x79\x01\x40\x4c\x07\xb6\xcd\x87\x34\xb7\x62\xd3\x31\x37\x7c\x05\x85\xe2\xde\x67\xc4\xf0\x55\xce\x1d\xb4\x0f\x2a\xb8\xbb\x03\x1f\x64\x71\xb7\xa1\xc1\x56\x90\x2f\x5b\xd1\x42\xe1\x3d\xd5\x82\x1a\xbb\x76\xbc\x82\x8a\x4e\xc8\x76\xe1\x29\x1f\x42\xd1\xdc\x71\x23\xa0\xb0\x8a\x7a\x4d\x2f\x9b\x17\x2d\xe8\x05\x68\x47\x27\xdb\xd0\xbc\x26\x7d\xa7\xf2\x8e\x16\x33\x7c\xd5\xbd\x0a\xb9\x63\x18\xb3\x92\x4b\x83\x8e\x2e\x54\x2d\xc5\xde\x70\x4f\xc7\xd9\xf5\x4e\x3e\x9e\xdd\x99\x8f\xe4\x0d\x82\x88\x5f\xb9\xb2\xc5\x5d\x5c\x9c\x8a\x71\x01\x2f\x05\x46\x27\x54\x74\xda\x43\x19\xe2\x74\x3a\xb4\x75\xce\x40\x0d\x20\x5d\x58\xa7\xc7\x22\x89\x4a\x78\x0c\x43\x71\x6f\x60\x8b\x1c\xe7\xd2\xa5\xb3\x75\x45\xc6\xba\x31\xc1\x9b\x7c\x9a\xdd\x48\x8d\xf0\xf5\xe7\x9b\x77\xdf\xa4\xac\x13\x3d\xd5\x3d\xea\x90\xc3\x3a\x85\x55\x07\x75\xa2\x55\x9e\xa3\x82\x85\x75\x19\x09\x52\xe3\xdc\x07\xee\x02\x99\xfd\x11\x1f\x29\x6b\x46\x8f\xcc\x94\xa6\xaa\x03\xc4\x3d\x2a\x23\x82\x07\x8c\xd3\xa9\xb2\x05\x57\xa4\xdb\x6f\x7a\x80\x03\x17\xc4\x38\x3b\xab\x40\xd3\x69\x13\x09\x90\x62\x68\xfd\xd0\x72\x98\x90\xab\x33\xae\x13\x8d\x20\xb3\x9f\x8d\x38\xcb\x1a\x23\xd8\x89\x2b\x6c\xec\x9e\xbe\xbe\x83\xe2\x94\x9d\x37\xe5\x5e\xc3\x8d\xad\x20\x96\xa9\xff\x4f\x72\xae\xef\x55\x53\xeb\x1c\x5d\xe7\x20\x5b\x35\x24\x76\xbe\xdd\x7d\x3f\xeb\x5b\x2d\x4d\x46\xe2\x93\xdf\x67\x64\x3a\x99\x4c\x26\x04\x56\x5c\xd5\x98\x91\xab\xc9\xe4\x18\x09\xaf\xb9\x52\x03\xe0\x80\xf7\x01\xe2\x1f\xaa\xeb\x80\x87\xc3\x14\xff\xbd\x2b\xad\xf5\xd2\x2c\x21\x58\x0b\x9a\x9b\x0d\xe8\x66\x73\xe1\x8b\x05\x16\x01\x72\x67\xd7\x1e\x1d\x54\xe8\x22\x2c\x37\x05\x26\x87\x29\xb0\x86\xc3\x19\xb3\x61\x20\x3f\x4b\x6a\xbc\x97\x2a\xa0\xfb\x42\x56\xc4\xd8\x2d\x1a\x45\x7f\x08\xea\xc8\x5a\xce\x97\x4f\x79\x1d\x82\x35\x5d\x42\xb5\x1f\xa4\x47\xea\x93\xaa\xf7\x29\x94\x07\x03\x79\x30\xb4\x72\x52\x73\xb7\x69\xde\xbd\x8e\xc9\x44\x66\xdf\xa6\xac\x9d\xfd\x7f\x2b\xd0\x1f\x1d\xf2\x3b\x61\xd7\xe6\x84\x40\xe4\x5b\xdd\x43\xb1\x38\xcd\xed\xa7\x3a\x3e\x56\x11\xaa\x98\xf3\x6d\xbd\xee\x4c\x93\x21\x93\xe3\xf5\x0b\x6d\x2f\x45\x5b\x18\x02\xba\x56\x41\x56\x0a\xc1\xcb\x7f\x30\x23\x0f\x0f\x90\x34\x6b\xff\xc9\xae\xcd\x07\x34\xf0\xf8\xb8\xa3\xf2\xf0\x40\xc1\x71\xb3\x44\x48\xde\x47\x9f\xfe\x12\x7d\xe8\xe1\xf1\xf1\x08\x55\x80\xd4\x56\xa1\xf1\x34\x34\x5b\x7e\x0b\xff\xa1\x39\x17\x9e\xc1\x7d\x16\xb2\x05\x95\xd6\x6c\x37\x9c\x08\xf9\x91\x6b\x6c\x10\xfb\xf8\x29\x6b\x15\xfb\x66\x62\xfb\xf8\x1c\x5f\xb6\x25\x7c\xf2\xa4\x94\xb5\x9e\x3c\x12\xd5\xa3\x67\xe8\x79\x72\xbb\xbf\xaf\xfb\x3a\xd7\x32\xec\x36\xe2\xdf\x6b\x03\xbf\xc5\xb6\xbb\x4d\x8d\x6e\x74\xd4\x48\x1d\x44\x4e\xd9\xb0\xe5\x1a\x90\x1a\x7e\x68\x2e\xcd\xa8\x1b\xfc\x1e\x74\xd3\x9d\xc6\xa6\xbb\x69\x50\xd5\x92\x4e\x27\x50\x05\x7a\x09\xd5\x3d\x7d\x3b\xee\x25\x23\xb9\xf8\x3b\x2a\xcc\x85\x5c\x91\xd9\xc8\x03\x7b\x95\xfe\x4f\x80\x91\x5a\xca\x22\x8f\x11\xc3\x43\x7c\x77\x88\x4d\x1b\x1f\x7f\x9e\x79\x99\x4b\x25\xc3\xe6\xba\x94\x42\xe0\x60\x13\xbb\x41\x5d\x29\x1e\x70\xec\xb3\x51\x39\xf7\x66\xcc\x9d\x5d\xff\x39\x9f\x7f\xfc\xfc\xeb\x7c\xfe\xd7\x93\x0a\x1e\x77\xce\xd1\x41\x87\x76\xa0\xae\xc0\x7b\xb0\x4d\x90\xf6\xc0\x5d\xed\x1f\x19\x3c\x52\xf8\x64\xf6\xea\x25\xf5\xfb\xa5\xca\x3d\xb9\x66\x5f\x5e\xad\xcf\x96\x5c\xbf\x42\x9f\x51\x3c\x56\x95\x87\xeb\xeb\xc4\xc0\xf4\x8b\x2d\x36\x2d\xc7\x7d\x3d\x8c\x50\xb3\xf4\x5e\x84\x7a\x91\x1d\x0d\x9d\x95\xee\xc1\x33\xba\x0b\x43\x67\x71\x98\xbc\xa8\xed\x0a\x9f\x66\xd2\xf6\xfc\xf6\x58\x58\x23\xc6\x27\x38\x3d\x76\x82\x1f\xe0\xfe\xb4\x16\xc7\x82\x6d\xd1\xfa\xc2\xc9\x2a\x80\x77\xc5\xf1\xab\x86\xee\x52\xe1\x32\x99\x26\xd3\xe6\xee\xe2\xd6\x13\x98\xa5\xac\x9d\x3b\x7b\x09\xd4\xfe\xd6\x62\x7f\x53\x71\xeb\xc9\x69\x60\x2f\xb8\x6f\xb9\x1d\x5f\xb7\x9c\x6c\x64\xbd\x5e\x27\x4b\x1f\x78\x90\x45\x83\xdc\xec\x98\x9e\x29\xcb\x05\xba\xd3\x61\x0a\x61\x6e\x7d\x52\x28\x5b\x8b\x85\xe2\x0e\x1b\x2c\x7e\xcb\xef\x99\x92\xb9\x67\x9f\x78\xc5\x3f\x71\xe7\x91\x5d\x25\x97\xc9\x84\x55\x3c\xfe\x77\x1e\x4f\x23\xcb\x16\xdb\x7b\x87\xa7\xba\x29\x6b\xef\x48\x52\xd6\x5c\xaf\xfd\x1b\x00\x00\xff\xff\x41\x6b\x92\x22\x6e\x13\x00\x00")
+var _assetsIndexHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xcd\x58\x5b\x6f\xdb\x36\x14\x7e\xcf\xaf\x60\x95\x97\x0d\xab\x24\x3b\x69\x80\x2d\xb5\xfd\xb0\x5e\xf6\xd2\x15\xd9\x9a\x02\x05\x86\xc1\xa0\xc4\x63\x9b\x31\x25\x6a\x24\xe5\x4b\x03\xff\xf7\x1d\x52\x92\x75\xb1\xe5\xb8\x43\x0a\x2c\x41\x22\x5e\x0e\x3f\x9e\x3b\x79\x38\x7a\xc1\x64\x6c\xb6\x19\x90\x85\x49\xc4\xe4\x62\x64\x3f\x44\xd0\x74\x3e\xf6\x1e\x1f\x49\xf0\x01\x5b\x39\x9d\x03\xd9\xed\xbc\xc9\x05\x21\xa3\x05\x50\x66\x1b\xd8\x4c\xc0\x50\x12\x2f\xa8\xd2\x60\xc6\x5e\x6e\x66\xfe\xcf\x5e\x73\x2a\xa5\x09\x8c\xbd\x15\x87\x75\x26\x95\xf1\x48\x2c\x53\x03\x29\x92\xae\x39\x33\x8b\x31\x83\x15\x8f\xc1\x77\x9d\x97\x84\xa7\xdc\x70\x2a\x7c\x1d\x53\x01\xe3\xe1\x4b\xa2\x17\x8a\xa7\x4b\xdf\x48\x7f\xc6\xcd\x38\x95\x15\xb4\xc0\x51\xa2\x40\x8c\x3d\x6d\xb6\x02\xf4\x02\x00\xb1\x17\x0a\x66\x63\x6f\x61\x4c\xa6\x6f\xc3\x50\x1b\x1a\x2f\x33\x6a\x16\x41\x24\xa5\xd1\x46\xd1\x2c\x66\x69\x10\xcb\x24\xdc\x0f\x84\xaf\x82\x9b\xe0\x2a\x8c\xb5\xae\xc7\x82\x84\x23\x95\xd6\x1e\xf9\x86\xcd\x62\xc9\x20\x78\xf8\x27\x07\xb5\x75\x3b\xe4\x3c\x1c\x06\xc3\xab\x60\x18\x9a\x05\x24\x80\xf0\x54\x43\x58\x10\xf8\x39\x77\xf8\x25\xbc\xe1\x46\xc0\xe4\xbd\x90\xeb\x85\xcc\x35\x8c\xc2\x62\xa0\x98\x74\x3b\x16\x6d\x42\x2e\xe3\x5c\x1b\x99\x4c\x05\xcc\x21\x65\xe4\xb1\x1c\x26\x24\xa1\x1b\x7f\x01\x7c\xbe\x30\xb7\xe4\xd5\x60\x90\x6d\x5e\xef\xa7\xe4\x0a\xd4\x0c\xb1\xfd\xed\x2d\xa1\xb9\x91\xd5\xcc\xae\xfc\x06\x86\x46\x02\x7c\x9d\x10\xd3\x44\xcc\x28\x63\x3c\x9d\xdf\x92\x41\x70\x75\xa3\x20\xe9\x2e\x8b\x24\xdb\x06\x4e\x34\x9f\x51\xb5\x7c\x49\x1a\x1d\x12\xcc\xa4\x4a\x7c\x6b\x69\x25\x45\x03\x34\x42\x83\xcc\x95\xcc\x53\x86\x93\x42\xaa\x5b\x72\x79\x75\x75\x55\xf3\x5a\x0d\x32\xc6\x0e\xd9\x6c\xc0\x47\x73\x5f\x58\x61\x4f\x43\x5f\x5f\x5f\x93\x17\x3c\xb1\x6e\x47\x53\xd3\x06\x1c\x85\x85\x62\xb1\x69\xfd\x39\xac\x1c\x7a\x64\xe5\x22\xb1\xa0\x5a\x8f\xbd\x62\x4b\x1b\x00\x77\x68\x6b\x50\x90\xc6\xa0\x83\x7b\x3b\x5a\x45\x02\xae\x48\xe9\xaa\x5a\x80\xcd\x88\x2a\x52\x7c\x0a\x5e\xb5\xe1\xf1\x72\x8b\x0e\x9c\x11\xe4\xda\x0d\xcd\x04\x6c\xfc\x84\xf9\xa9\x5c\xa3\xb7\x91\xcc\x1f\x78\x95\x81\x47\xb4\x0d\xe5\x47\x8a\xa2\xa1\x51\x22\x34\x90\x7f\xed\x1a\xb8\xf2\x8a\x24\x0a\x57\x95\x2e\x78\xe9\x35\x9d\x87\x96\x6c\x85\x88\x50\x36\x19\xdf\x73\x68\x6d\x42\x79\x0a\xca\x9f\x89\x9c\xb3\x7a\xe3\x06\x8d\x92\xeb\xfd\x78\x77\x75\xb9\xbd\x65\x3e\x05\xfc\x60\x2f\x12\x32\x5e\x92\xbd\x49\x34\x67\x60\x95\x90\xf9\xc3\x06\x4a\x1b\xa7\xa4\xf1\x0b\xe5\xb4\xc8\x90\xd0\x3a\x4f\x77\x88\x83\x60\x98\x60\xda\xc3\x8d\x89\x0a\xd9\x39\x9e\xf5\x83\xcc\xeb\xd2\xda\x40\x76\x71\x33\x41\x8b\xde\x13\xef\x9e\x27\xe0\xa1\x1d\xc9\x0f\xd8\xe7\xb3\x8e\x91\x71\xf2\xab\x15\x71\xb7\x3b\xb0\x7f\x6b\x0a\x84\xb6\xad\xcf\xf7\x6f\x6c\x07\x6d\xb5\xdb\xfd\x38\x0a\xcb\x8d\x2e\x1e\x1f\xfd\x53\xd0\x87\x1c\xf2\x34\xcb\x0d\xb1\xa9\x18\x53\x0b\x67\x0c\x52\xaf\xcc\xa0\xe6\xab\x47\x56\x54\xe4\x50\xa4\xe4\x1e\x44\xaf\xd8\xb3\x60\xe4\x10\xbe\xd7\xca\xc7\x69\xd0\xde\x47\x69\xac\x2a\x69\x04\x82\xa0\xba\x91\x33\xdc\x7e\x8a\xc9\x16\xb3\x7b\xa9\xda\x4f\xae\x83\x1c\xa0\x26\x2c\x5d\x0f\x46\x53\x58\x46\x0d\x58\x20\x1f\xbd\x89\x8a\xbd\xd0\x35\x74\xcb\xc2\x55\x6a\x49\xfc\xa1\x73\x34\xc2\x59\x9b\x8f\x63\x82\x85\x28\xd9\x33\x4a\x8c\x3a\xae\xe4\x7d\x87\xcd\xe7\x92\xd6\xc2\x9e\x29\xab\xe3\xe0\x7c\x49\x8f\x0e\x8f\xc2\xef\x11\x5b\x97\xe4\x1e\x13\x9e\xcd\x4a\xba\x54\x4c\x19\x10\xdf\xd1\x23\x9b\xfa\x4d\xf3\x24\x02\x55\xaa\x4a\x66\x25\x23\xa5\x96\xf7\xfd\x93\x5a\xc6\x4b\xc0\xd8\xb3\x5f\xba\xc1\xef\x00\x7f\xf6\xf1\x77\x33\x18\xf4\x31\xa1\x13\x2a\x44\x0b\xd8\xc0\x06\xb9\xc2\x7f\x7e\x92\x1b\x60\x3d\x0b\x09\x29\x14\xf7\x66\x21\xa5\xc6\x73\x97\x18\x29\x71\xeb\x74\x8b\x7c\xd8\xac\x4a\x67\x33\x88\x0d\x89\x50\x47\x1a\x30\xb9\xe2\x99\x8e\xe0\x14\xc3\x3f\xf0\x8e\x45\x7a\x79\xc6\x59\x6e\x9e\xd1\x43\x5a\xe3\xcf\xe8\x2e\xef\xb9\x30\xd6\x5c\x4f\x7a\x8a\xb5\xe7\xcc\x11\x6b\xef\x4c\xf6\x9f\xd5\xc7\xa2\xdc\x18\x99\x96\x4e\x56\x74\xbc\x06\x53\x77\x22\xaf\xdd\x2a\x32\x29\xc1\x3f\x3f\x53\x3c\xa1\x6a\xeb\xda\x78\xcb\x4a\xec\xb9\xf8\xd3\x28\x2c\x56\xff\x3f\xc3\xf7\x57\x05\x74\xc9\xe4\x3a\x3d\xd3\x24\x51\x45\xaf\x9f\x54\x6e\x9f\x01\xce\x35\x81\x8d\x31\x10\x36\x16\x8a\x68\x8e\x6a\x56\x5b\x9c\xf4\x47\x37\x29\x2e\xd1\x7e\x01\x83\x01\x9e\x0b\xc3\x33\x01\x78\x73\xf9\x5a\x1e\xae\x4e\xfe\xb7\x88\xf2\x01\xd2\xfa\xa6\x67\x63\xd4\x27\x78\x21\xc3\x42\x28\x78\x6f\xb5\xfb\x9b\xd5\xa6\xee\x8b\x3f\xc7\xac\xcc\x8c\xd3\x39\x71\x47\x43\x55\x4e\xd9\x93\xe4\x04\xee\x49\xc8\x02\x94\xa3\x1b\x36\xae\x03\x1f\x69\x79\x27\x6d\xe2\x8f\xc2\x82\xb0\xb9\x4d\xcf\xcd\xa0\xf6\xa7\x8a\xe1\xb3\x17\x61\x9a\x71\x9a\xec\xb1\x6a\xef\xa9\xfb\x3c\x5e\xde\xcc\xfa\x3a\x8f\x12\x6e\x9a\xd7\x24\xf4\xe5\x3f\xf3\x94\xfc\x61\xab\x2e\xeb\xcb\x85\x93\x94\x74\x07\x58\xbd\xd1\xad\xe9\x0a\xde\xc2\x8c\xa2\xa7\x1c\xc6\xb7\x06\xf4\x2e\xd6\x89\x70\xe2\x6a\xb8\x8a\x87\xcf\x78\x47\xc4\x62\x82\x14\x8a\x02\x46\xea\x80\x21\xf6\x7e\x6f\x4f\xf3\xd2\x01\xa2\x2d\x61\xc5\x4e\x5e\x65\x4f\x7b\xa1\x42\x06\x08\xd5\xe4\x6d\x3d\xd5\x97\x41\xfa\xa5\x80\x8d\x2d\x85\xbe\x7c\xf8\xf4\xe5\xbf\xc8\x60\xc3\x41\x48\xca\x9c\x20\x0a\x34\xb2\x41\xe4\xcc\xf5\x10\xcb\x10\x57\xd9\x5a\x1e\xdf\x6d\x62\xf4\xbe\xb5\x54\x4b\xac\xa8\x97\x4d\x29\xde\x39\x06\x48\xc1\x41\x9f\x04\xc7\x6d\x8d\xa3\xad\xaa\xa0\xe5\x26\xed\x4e\x82\xf5\x4d\xa7\x60\xf9\x85\x24\xae\x80\xb2\xf5\xaf\xab\xa1\xc4\xdc\x1f\x0e\x48\x66\xb0\xa4\xca\x36\xfe\xab\x6e\xb9\x63\xb5\x65\x9f\x34\xcc\x14\x7b\xde\xa4\xe3\x93\x35\x49\xb3\x1a\xef\x90\x8d\x42\xcb\x47\x87\xc3\x8b\x1e\x1f\x77\x88\xae\x20\xb5\x2f\x25\x9a\x47\x5c\x70\xb3\xbd\xad\x0a\x80\xfa\x80\xb9\x87\x24\x13\x78\x77\xec\x96\x4c\x9d\x04\xdb\x58\x31\xc5\xfe\x5f\xd3\xe9\xc7\xcf\xbf\x4f\xa7\x7f\x1f\xba\x7c\xa7\xb8\xb3\x0a\x3a\x76\x3a\x94\x29\xb7\x01\xeb\x8c\x54\x03\x97\xd9\xb8\x67\xb2\x27\x15\x97\xc5\xca\xb9\x19\xf5\xa9\x5c\x7a\x76\x16\x3d\x91\x3f\xcb\x52\xf0\xde\xbe\x85\x61\x69\x88\xf7\x74\xea\x17\x91\x64\xa9\xca\x61\x6f\x5f\xea\x95\xe4\x1f\xf3\x04\x14\x8f\xf7\x2b\xd2\xa2\x8f\x77\x4d\x95\x43\x4d\xdd\x93\x9c\x4f\x66\xd8\x66\x42\x3e\x55\xd9\xf5\x24\xe1\xe3\xe9\xf4\x4c\xab\x37\x73\xab\xbd\xc1\xf6\x1b\xb2\x6d\x7e\xa7\xd7\x86\xf9\x1b\x6e\xd3\x99\x7a\x56\x76\x8f\x26\xbe\xd2\xc6\xe5\x8e\xed\xc8\x80\x44\xae\xe0\xd0\x4d\x4f\x26\xc5\x89\xdf\x9b\x78\x0f\x79\x3f\x0c\xf4\xee\x40\x95\x11\x74\xac\x78\x66\x88\x56\x71\xff\x93\x62\xf9\x78\x78\x1d\x0c\xf1\xd7\xbe\x51\x3e\xd8\x27\x4a\xb4\xbc\x5b\x3b\xf9\x16\xa8\xfa\x75\xb2\x7e\x91\x44\xb4\xf3\xc0\xbe\xe1\x5d\xf5\xa1\xfb\xac\x7a\xf6\x26\xeb\xf5\x3a\x98\xe3\x4e\x86\xc7\x0e\xd9\xa5\x63\x1d\xda\x13\x08\xd4\xf9\x30\xc8\xd8\x83\x0e\x62\x21\x73\x36\x13\x54\x81\xc3\xa2\x0f\x74\x13\x0a\x1e\xe9\xf0\x8e\x66\xf4\xce\x3e\x5d\x87\x37\xc1\x75\x30\x08\x33\x6a\x7f\xb1\xff\x14\xb3\x93\x95\x7d\xe2\xaa\xdf\x60\xc8\x98\x74\x5e\x65\x30\x4a\x5f\x9f\x64\x31\x9c\x55\x8f\x76\x87\x1b\xa1\x8f\x49\xb6\x9d\x5c\x8c\x42\xf7\x20\xff\x2f\x04\xc1\xa2\xf1\xa0\x17\x00\x00")
 
 func assetsIndexHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -114,7 +114,7 @@ func assetsIndexHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "assets/index.html", size: 4974, mode: os.FileMode(436), modTime: time.Unix(1740344273, 0)}
+	info := bindataFileInfo{name: "assets/index.html", size: 6048, mode: os.FileMode(436), modTime: time.Unix(1791961461, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
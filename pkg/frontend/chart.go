@@ -0,0 +1,98 @@
+package frontend
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/bio-routing/flowhouse/pkg/chart"
+)
+
+const (
+	minChartSize = 100
+	maxChartSize = 4000
+)
+
+// chartHandler renders the result of a query (parameters as for /query) as PNG or SVG chart
+func (fe *Frontend) chartHandler(w http.ResponseWriter, r *http.Request) {
+	l := requestLogger(r)
+	c, format, err := parseChartOptions(r.URL.Query())
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := fe.processQuery(r.Context(), r.URL.Query(), l)
+	if err != nil {
+		l.WithError(err).Error("Unable to process query")
+		httpError(w, r, "Unable to process query", http.StatusInternalServerError)
+		return
+	}
+
+	if res == nil {
+		res = newResult()
+	}
+
+	res.toChart(c)
+
+	buf := bytes.NewBuffer(nil)
+	contentType := "image/png"
+	if format == "svg" {
+		contentType = "image/svg+xml"
+		err = c.SVG(buf)
+	} else {
+		err = c.PNG(buf)
+	}
+
+	if err != nil {
+		l.WithError(err).Error("Unable to render chart")
+		httpError(w, r, "Unable to render chart", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
+
+// parseChartOptions gets the size, title and format (png or svg) of a chart
+func parseChartOptions(fields url.Values) (*chart.Chart, string, error) {
+	c := &chart.Chart{
+		Title:  fields.Get("title"),
+		Unit:   "Mbps",
+		Width:  chart.DefaultWidth,
+		Height: chart.DefaultHeight,
+	}
+
+	format := fields.Get("format")
+	switch format {
+	case "":
+		format = "png"
+	case "png", "svg":
+	default:
+		return nil, "", fmt.Errorf("Unknown format %q", format)
+	}
+
+	for _, o := range []struct {
+		name  string
+		value *int
+	}{
+		{"width", &c.Width},
+		{"height", &c.Height},
+	} {
+		v := fields.Get(o.name)
+		if v == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(v)
+		if err != nil || n < minChartSize || n > maxChartSize {
+			return nil, "", fmt.Errorf("Invalid %s %q (must be %d to %d)", o.name, v, minChartSize, maxChartSize)
+		}
+
+		*o.value = n
+	}
+
+	return c, format, nil
+}
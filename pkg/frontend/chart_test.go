@@ -0,0 +1,92 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/chart"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChartOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantFail bool
+		format   string
+		width    int
+		height   int
+		title    string
+	}{
+		{
+			name:   "Defaults",
+			query:  "breakdown=agent",
+			format: "png",
+			width:  chart.DefaultWidth,
+			height: chart.DefaultHeight,
+		},
+		{
+			name:   "SVG with size and title",
+			query:  "format=svg&width=1200&height=300&title=Transit",
+			format: "svg",
+			width:  1200,
+			height: 300,
+			title:  "Transit",
+		},
+		{
+			name:     "Unknown format",
+			query:    "format=gif",
+			wantFail: true,
+		},
+		{
+			name:     "Too large",
+			query:    "width=10000",
+			wantFail: true,
+		},
+		{
+			name:     "Invalid height",
+			query:    "height=abc",
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		fields, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatalf("Invalid query %q: %v", test.query, err)
+		}
+
+		c, format, err := parseChartOptions(fields)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error for test %q: %v", test.name, err)
+			continue
+		}
+
+		assert.Equal(t, test.format, format, test.name)
+		assert.Equal(t, test.width, c.Width, test.name)
+		assert.Equal(t, test.height, c.Height, test.name)
+		assert.Equal(t, test.title, c.Title, test.name)
+	}
+}
+
+func TestResultToChart(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	res := newResult()
+	res.add(ts, "agent=rtr02", 20)
+	res.add(ts.Add(time.Minute), "agent=rtr01", 10)
+
+	c := &chart.Chart{}
+	res.toChart(c)
+
+	assert.Equal(t, []time.Time{ts, ts.Add(time.Minute)}, c.Timestamps)
+	assert.Equal(t, []chart.Series{
+		{Name: "agent=rtr01", Values: []float64{0, 10}},
+		{Name: "agent=rtr02", Values: []float64{20, 0}},
+	}, c.Series)
+}
@@ -0,0 +1,101 @@
+package frontend
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var computedFieldNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// reservedFieldNames are columns and aliases used by the query builder that computed fields must not shadow
+var reservedFieldNames = []string{"t", "mbps", "timestamp", "size", "packets", "samplerate", "flow_start", "duration_ms"}
+
+// ComputedField is a virtual field calculated by a ClickHouse expression over the flows table
+type ComputedField struct {
+	Name       string `yaml:"name"`
+	Label      string `yaml:"label"`
+	ShortLabel string `yaml:"short_label"`
+	Expr       string `yaml:"expr"`
+}
+
+// ComputedFields is a slice of computed fields
+type ComputedFields []*ComputedField
+
+// Validate checks the computed fields for invalid or conflicting names and missing expressions
+func (c ComputedFields) Validate() error {
+	names := make(map[string]struct{})
+	for _, f := range fields {
+		names[f.Name] = struct{}{}
+	}
+
+	for _, n := range reservedFieldNames {
+		names[n] = struct{}{}
+	}
+
+	for _, n := range geoFieldNames {
+		names[n] = struct{}{}
+	}
+
+	for _, cf := range c {
+		if !computedFieldNameRegex.MatchString(cf.Name) || strings.Contains(cf.Name, "__") {
+			return fmt.Errorf("Invalid computed field name %q", cf.Name)
+		}
+
+		if _, exists := names[cf.Name]; exists {
+			return fmt.Errorf("Computed field %q conflicts with an existing field", cf.Name)
+		}
+		names[cf.Name] = struct{}{}
+
+		if cf.Expr == "" {
+			return fmt.Errorf("Computed field %q has no expression", cf.Name)
+		}
+	}
+
+	return nil
+}
+
+func (c ComputedFields) get(name string) *ComputedField {
+	for _, cf := range c {
+		if cf.Name == name {
+			return cf
+		}
+	}
+
+	return nil
+}
+
+// fieldExpr returns the expression of a computed field or the field name itself
+func (fe *Frontend) fieldExpr(name string) string {
+	if cf := fe.getComputedFields().get(name); cf != nil {
+		return "(" + cf.Expr + ")"
+	}
+
+	return name
+}
+
+// catalog returns the built in and the computed fields
+func (fe *Frontend) catalog() []fieldDescription {
+	computedFields := fe.getComputedFields()
+	res := make([]fieldDescription, 0, len(fields)+len(computedFields))
+	res = append(res, fields...)
+	for _, cf := range computedFields {
+		label := cf.Label
+		if label == "" {
+			label = cf.Name
+		}
+
+		shortLabel := cf.ShortLabel
+		if shortLabel == "" {
+			shortLabel = label
+		}
+
+		res = append(res, fieldDescription{
+			Name:       cf.Name,
+			Label:      label,
+			ShortLabel: shortLabel,
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,84 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputedFieldsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   ComputedFields
+		wantFail bool
+	}{
+		{
+			name: "Valid",
+			fields: ComputedFields{
+				{Name: "app", Expr: "multiIf(dst_port = 443, 'https', 'other')"},
+			},
+		},
+		{
+			name:     "Invalid name",
+			fields:   ComputedFields{{Name: "App", Expr: "1"}},
+			wantFail: true,
+		},
+		{
+			name:     "Dict separator",
+			fields:   ComputedFields{{Name: "app__name", Expr: "1"}},
+			wantFail: true,
+		},
+		{
+			name:     "Built in field",
+			fields:   ComputedFields{{Name: "dst_port", Expr: "1"}},
+			wantFail: true,
+		},
+		{
+			name:     "Reserved",
+			fields:   ComputedFields{{Name: "mbps", Expr: "1"}},
+			wantFail: true,
+		},
+		{
+			name:     "Duplicate",
+			fields:   ComputedFields{{Name: "app", Expr: "1"}, {Name: "app", Expr: "2"}},
+			wantFail: true,
+		},
+		{
+			name:     "No expression",
+			fields:   ComputedFields{{Name: "app"}},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.fields.Validate()
+		assert.Equal(t, test.wantFail, err != nil, test.name)
+	}
+}
+
+func TestComputedFieldResolution(t *testing.T) {
+	fe := &Frontend{
+		computedFields: ComputedFields{
+			{Name: "app", Label: "Application", Expr: "multiIf(dst_port = 443, 'https', 'other')"},
+		},
+		dictCfgs: Dicts{
+			{Field: "app", Dict: "flowhouse.apps", Expr: "%s"},
+		},
+	}
+
+	s, err := fe.resolveDictIfNecessary("app")
+	assert.NoError(t, err)
+	assert.Equal(t, "(multiIf(dst_port = 443, 'https', 'other'))", s)
+
+	s, err = fe.resolveDictIfNecessary("app__owner")
+	assert.NoError(t, err)
+	assert.Equal(t, "dictGet('flowhouse.apps', 'owner', (multiIf(dst_port = 443, 'https', 'other')))", s)
+
+	s, err = fe.resolveDictIfNecessary("dst_port")
+	assert.NoError(t, err)
+	assert.Equal(t, "dst_port", s)
+
+	catalog := fe.catalog()
+	assert.Equal(t, fieldDescription{Name: "app", Label: "Application", ShortLabel: "Application"}, catalog[len(catalog)-1])
+	assert.Equal(t, "Application", getReadableLabel("app", catalog))
+}
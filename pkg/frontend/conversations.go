@@ -0,0 +1,232 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	conversationsByIP     = "ip"
+	conversationsByASN    = "asn"
+	conversationsBySocket = "socket"
+
+	defaultConversationsTop = 100
+
+	// maxConversationRows limits the number of directed pairs fetched before pairing
+	maxConversationRows = 100000
+)
+
+// conversationKeys are the source and destination expressions per conversation type
+var conversationKeys = map[string][2]string{
+	conversationsByIP:     {"IPv6NumToString(src_ip_addr)", "IPv6NumToString(dst_ip_addr)"},
+	conversationsByASN:    {"toString(src_asn)", "toString(dst_asn)"},
+	conversationsBySocket: {"concat(IPv6NumToString(src_ip_addr), ' ', toString(src_port))", "concat(IPv6NumToString(dst_ip_addr), ' ', toString(dst_port))"},
+}
+
+// Conversation is the traffic between two endpoints in both directions. A is the lower of both endpoints.
+type Conversation struct {
+	A        string `json:"a"`
+	B        string `json:"b"`
+	Protocol uint8  `json:"protocol,omitempty"`
+
+	ABBytes   uint64 `json:"a_to_b_bytes"`
+	BABytes   uint64 `json:"b_to_a_bytes"`
+	ABPackets uint64 `json:"a_to_b_packets"`
+	BAPackets uint64 `json:"b_to_a_packets"`
+
+	ABMbps float64 `json:"a_to_b_mbps"`
+	BAMbps float64 `json:"b_to_a_mbps"`
+}
+
+type conversationRow struct {
+	src      string
+	dst      string
+	protocol uint8
+	bytes    uint64
+	packets  uint64
+}
+
+// conversationsHandler serves the top conversations between IPs (by=ip, default), ASNs (by=asn) or
+// sockets (by=socket, per IP protocol) with the volumes of both directions as JSON
+func (fe *Frontend) conversationsHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := fe.processConversationsQuery(r.Context(), r.URL.Query())
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Unable to process conversations query")
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func (fe *Frontend) processConversationsQuery(ctx context.Context, fields url.Values) ([]*Conversation, error) {
+	by := fields.Get("by")
+	if by == "" {
+		by = conversationsByIP
+	}
+
+	if _, ok := conversationKeys[by]; !ok {
+		return nil, fmt.Errorf("Invalid by %q", by)
+	}
+
+	top := defaultConversationsTop
+	if t := fields.Get("top"); t != "" {
+		var err error
+		top, err = strconv.Atoi(t)
+		if err != nil || top <= 0 {
+			return nil, fmt.Errorf("Invalid top %q", t)
+		}
+	}
+
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if end <= start {
+		return nil, fmt.Errorf("Empty time range")
+	}
+
+	return fe.conversations(ctx, by, start, end, fe.getFieldConditions(fields), top)
+}
+
+// conversations gets the top conversations matching conditions between start and end
+func (fe *Frontend) conversations(ctx context.Context, by string, start int64, end int64, conditions []string, top int) ([]*Conversation, error) {
+	rows, err := fe.chgw.QueryContext(ctx, fe.conversationsQuery(by, start, end, conditions))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	data := make([]conversationRow, 0)
+	for rows.Next() {
+		var r conversationRow
+		err := rows.Scan(&r.src, &r.dst, &r.protocol, &r.bytes, &r.packets)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		data = append(data, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+
+	return buildConversations(by, data, top, end-start), nil
+}
+
+func (fe *Frontend) conversationsQuery(by string, start int64, end int64, conditions []string) string {
+	conditions = append([]string{fmt.Sprintf("timestamp BETWEEN toDateTime(%d) AND toDateTime(%d)", start, end)}, conditions...)
+
+	protocol := "toUInt8(0)"
+	if by == conversationsBySocket {
+		protocol = "ip_protocol"
+	}
+
+	keys := conversationKeys[by]
+	return fmt.Sprintf("SELECT %s AS s, %s AS d, %s AS p, sum(size * samplerate) AS bytes, sum(packets * samplerate) AS pkts FROM %s.flows WHERE %s GROUP BY s, d, p ORDER BY bytes DESC LIMIT %d",
+		keys[0], keys[1], protocol, fe.chgw.GetDatabaseName(), strings.Join(conditions, " AND "), maxConversationRows)
+}
+
+// buildConversations pairs A->B with B->A and returns the top conversations by total volume
+func buildConversations(by string, data []conversationRow, top int, seconds int64) []*Conversation {
+	type key struct {
+		a        string
+		b        string
+		protocol uint8
+	}
+
+	convs := make(map[key]*Conversation)
+	for _, r := range data {
+		src := formatEndpoint(by, r.src)
+		dst := formatEndpoint(by, r.dst)
+
+		reverse := dst < src
+		k := key{a: src, b: dst, protocol: r.protocol}
+		if reverse {
+			k.a, k.b = dst, src
+		}
+
+		c, exists := convs[k]
+		if !exists {
+			c = &Conversation{
+				A:        k.a,
+				B:        k.b,
+				Protocol: k.protocol,
+			}
+			convs[k] = c
+		}
+
+		if reverse {
+			c.BABytes += r.bytes
+			c.BAPackets += r.packets
+			continue
+		}
+
+		c.ABBytes += r.bytes
+		c.ABPackets += r.packets
+	}
+
+	res := make([]*Conversation, 0, len(convs))
+	for _, c := range convs {
+		c.ABMbps = float64(c.ABBytes) * 8 / float64(seconds) / 1000000
+		c.BAMbps = float64(c.BABytes) * 8 / float64(seconds) / 1000000
+		res = append(res, c)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		ti, tj := res[i].ABBytes+res[i].BABytes, res[j].ABBytes+res[j].BABytes
+		if ti != tj {
+			return ti > tj
+		}
+
+		if res[i].A != res[j].A {
+			return res[i].A < res[j].A
+		}
+
+		return res[i].B < res[j].B
+	})
+
+	if len(res) > top {
+		res = res[:top]
+	}
+
+	return res
+}
+
+// formatEndpoint unmaps IPv4 addresses and formats sockets as host:port
+func formatEndpoint(by string, s string) string {
+	switch by {
+	case conversationsByIP:
+		if addr := net.ParseIP(s); addr != nil {
+			return addr.String()
+		}
+	case conversationsBySocket:
+		parts := strings.SplitN(s, " ", 2)
+		if len(parts) != 2 {
+			return s
+		}
+
+		return net.JoinHostPort(formatEndpoint(conversationsByIP, parts[0]), parts[1])
+	}
+
+	return s
+}
@@ -0,0 +1,48 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildConversations(t *testing.T) {
+	data := []conversationRow{
+		{src: "::ffff:192.0.2.2", dst: "::ffff:192.0.2.1", bytes: 3750000, packets: 10},
+		{src: "::ffff:192.0.2.1", dst: "::ffff:192.0.2.2", bytes: 375000, packets: 5},
+		{src: "2001:db8::1", dst: "2001:db8::2", bytes: 100, packets: 1},
+		{src: "2001:db8::3", dst: "2001:db8::4", bytes: 10, packets: 1},
+	}
+
+	res := buildConversations(conversationsByIP, data, 2, 300)
+	assert.Len(t, res, 2)
+	assert.Equal(t, &Conversation{
+		A:         "192.0.2.1",
+		B:         "192.0.2.2",
+		ABBytes:   375000,
+		BABytes:   3750000,
+		ABPackets: 5,
+		BAPackets: 10,
+		ABMbps:    0.01,
+		BAMbps:    0.1,
+	}, res[0])
+	assert.Equal(t, "2001:db8::1", res[1].A)
+	assert.Equal(t, uint64(0), res[1].BABytes)
+}
+
+func TestBuildConversationsSocket(t *testing.T) {
+	data := []conversationRow{
+		{src: "2001:db8::1 443", dst: "::ffff:192.0.2.1 50000", protocol: 6, bytes: 100},
+		{src: "::ffff:192.0.2.1 50000", dst: "2001:db8::1 443", protocol: 6, bytes: 10},
+		{src: "::ffff:192.0.2.1 50000", dst: "2001:db8::1 443", protocol: 17, bytes: 1},
+	}
+
+	res := buildConversations(conversationsBySocket, data, 10, 1)
+	assert.Len(t, res, 2)
+	assert.Equal(t, "192.0.2.1:50000", res[0].A)
+	assert.Equal(t, "[2001:db8::1]:443", res[0].B)
+	assert.Equal(t, uint8(6), res[0].Protocol)
+	assert.Equal(t, uint64(10), res[0].ABBytes)
+	assert.Equal(t, uint64(100), res[0].BABytes)
+	assert.Equal(t, uint8(17), res[1].Protocol)
+}
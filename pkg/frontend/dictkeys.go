@@ -0,0 +1,105 @@
+package frontend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dictKey is a key passed to a dict lookup
+type dictKey struct {
+	expr string
+
+	// ip is set if expr is an IPv6 column of the flows table
+	ip bool
+}
+
+// isDictKeyType checks if keys can be cast to t
+func isDictKeyType(t string) bool {
+	switch t {
+	case "String", "IPv4", "IPv6", "Float32", "Float64":
+		return true
+	}
+
+	for _, prefix := range []string{"UInt", "Int"} {
+		if strings.HasPrefix(t, prefix) {
+			switch strings.TrimPrefix(t, prefix) {
+			case "8", "16", "32", "64":
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// castDictKey casts k to the dict key type t. Addresses are stored as IPv6, so IPv4 keys are taken from the mapped
+// address and String keys are formatted like IPv6NumToString does for the agents and interfaces dicts.
+func castDictKey(k dictKey, t string) string {
+	switch t {
+	case "String":
+		if k.ip {
+			return fmt.Sprintf("IPv6NumToString(%s)", k.expr)
+		}
+
+		return fmt.Sprintf("toString(%s)", k.expr)
+	case "IPv6":
+		if k.ip {
+			return k.expr
+		}
+
+		return fmt.Sprintf("toIPv6(%s)", k.expr)
+	case "IPv4":
+		if k.ip {
+			return fmt.Sprintf("toIPv4(replaceOne(IPv6NumToString(%s), '::ffff:', ''))", k.expr)
+		}
+
+		return fmt.Sprintf("toIPv4(%s)", k.expr)
+	}
+
+	return fmt.Sprintf("to%s(%s)", t, k.expr)
+}
+
+// keyExpr gets the key of a lookup in d. With key types the keys are cast to them and passed as tuple, otherwise
+// they are formatted into expr.
+func (d *Dict) keyExpr(keys []dictKey) string {
+	if len(d.KeyTypes) == 0 {
+		params := make([]interface{}, 0, len(keys))
+		for _, k := range keys {
+			params = append(params, k.expr)
+		}
+
+		return fmt.Sprintf(d.Expr, params...)
+	}
+
+	casts := make([]string, 0, len(keys))
+	for i, k := range keys {
+		casts = append(casts, castDictKey(k, d.KeyTypes[i]))
+	}
+
+	return fmt.Sprintf("tuple(%s)", strings.Join(casts, ", "))
+}
+
+// validateKeyTypes checks that there is one supported key type per key (or one for the parent field without keys)
+// and no expr to format the keys with
+func (d *Dict) validateKeyTypes() error {
+	if d.Expr != "" {
+		return fmt.Errorf("Dict %q for field %q: expr and key_types are mutually exclusive", d.Dict, d.Field)
+	}
+
+	want := len(d.Keys)
+	if want == 0 {
+		want = 1
+	}
+
+	if len(d.KeyTypes) != want {
+		return fmt.Errorf("Dict %q for field %q: %d key types, expected %d", d.Dict, d.Field, len(d.KeyTypes), want)
+	}
+
+	for _, t := range d.KeyTypes {
+		if !isDictKeyType(t) {
+			return fmt.Errorf("Dict %q for field %q: unsupported key type %q", d.Dict, d.Field, t)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCastDictKey(t *testing.T) {
+	tests := []struct {
+		key      dictKey
+		keyType  string
+		expected string
+	}{
+		{key: dictKey{expr: "agent", ip: true}, keyType: "String", expected: "IPv6NumToString(agent)"},
+		{key: dictKey{expr: "int_in"}, keyType: "String", expected: "toString(int_in)"},
+		{key: dictKey{expr: "src_asn"}, keyType: "UInt32", expected: "toUInt32(src_asn)"},
+		{key: dictKey{expr: "dst_ip_addr", ip: true}, keyType: "IPv6", expected: "dst_ip_addr"},
+		{key: dictKey{expr: "dst_ip_addr", ip: true}, keyType: "IPv4", expected: "toIPv4(replaceOne(IPv6NumToString(dst_ip_addr), '::ffff:', ''))"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, castDictKey(test.key, test.keyType), test.expected)
+	}
+}
+
+func TestResolveTypedDictKeys(t *testing.T) {
+	fe := &Frontend{
+		dictCfgs: Dicts{
+			{Field: "int_in", Dict: "flowhouse.interfaces_dict", Keys: []string{"agent", "int_in"}, KeyTypes: []string{"String", "String"}},
+			{Field: "int_in__site_id", Dict: "flowhouse.sites", KeyTypes: []string{"UInt64"}},
+			{Field: "agent", Dict: "flowhouse.agents_dict", KeyTypes: []string{"String"}},
+		},
+	}
+
+	s, err := fe.resolveDictIfNecessary("int_in__site_id__region")
+	assert.NoError(t, err)
+	assert.Equal(t, "dictGet('flowhouse.sites', 'region', tuple(toUInt64(dictGet('flowhouse.interfaces_dict', 'site_id', tuple(IPv6NumToString(agent), toString(int_in))))))", s)
+
+	s, err = fe.resolveDictIfNecessary("agent__name")
+	assert.NoError(t, err)
+	assert.Equal(t, "dictGet('flowhouse.agents_dict', 'name', tuple(IPv6NumToString(agent)))", s)
+}
+
+func TestValidateKeyTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		dict     *Dict
+		wantFail bool
+	}{
+		{
+			name: "Keys",
+			dict: &Dict{Field: "int_in", Dict: "interfaces", Keys: []string{"agent", "int_in"}, KeyTypes: []string{"String", "String"}},
+		},
+		{
+			name: "Parent field",
+			dict: &Dict{Field: "src_asn", Dict: "asns", KeyTypes: []string{"UInt32"}},
+		},
+		{
+			name:     "Expr",
+			dict:     &Dict{Field: "src_asn", Dict: "asns", Expr: "tuple(%s)", KeyTypes: []string{"UInt32"}},
+			wantFail: true,
+		},
+		{
+			name:     "Missing key type",
+			dict:     &Dict{Field: "int_in", Dict: "interfaces", Keys: []string{"agent", "int_in"}, KeyTypes: []string{"String"}},
+			wantFail: true,
+		},
+		{
+			name:     "Unsupported key type",
+			dict:     &Dict{Field: "src_asn", Dict: "asns", KeyTypes: []string{"UInt128"}},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		err := Dicts{test.dict}.Validate()
+		assert.Equal(t, test.wantFail, err != nil, test.name)
+	}
+}
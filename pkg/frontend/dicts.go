@@ -0,0 +1,242 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/requestid"
+	"github.com/pkg/errors"
+)
+
+// DictStore persists the dicts managed through /admin/dicts
+type DictStore interface {
+	SaveDicts(d Dicts) error
+}
+
+// SetDictStore enables changing dicts through /admin/dicts. Changes are saved to s before they are applied.
+func (fe *Frontend) SetDictStore(s DictStore) {
+	fe.dictStore = s
+}
+
+// dictsHandler handles requests for /admin/dicts. GET lists the dicts, POST adds the dict given as JSON body, PUT
+// replaces and DELETE removes the dict bound to field by the query parameters field and dict. Changes are validated,
+// saved to the dict store and applied to queries right away. The dicts after the change are returned.
+func (fe *Frontend) dictsHandler(w http.ResponseWriter, r *http.Request) {
+	l := requestLogger(r)
+
+	res := fe.getDictCfgs()
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut, http.MethodDelete:
+		if fe.dictStore == nil {
+			httpError(w, r, "No dict store configured", http.StatusNotImplemented)
+			return
+		}
+
+		var d *Dict
+		if r.Method != http.MethodDelete {
+			d = &Dict{}
+			err := json.NewDecoder(r.Body).Decode(d)
+			if err != nil {
+				httpError(w, r, "Invalid dict", http.StatusBadRequest)
+				return
+			}
+		}
+
+		field, dict := r.URL.Query().Get("field"), r.URL.Query().Get("dict")
+		dicts, code, err := fe.changeDicts(r.Method, field, dict, d)
+		if err != nil {
+			l.WithError(err).Error("Unable to change dicts")
+			httpError(w, r, err.Error(), code)
+			return
+		}
+
+		actor, _, _ := r.BasicAuth()
+		entry := audit.WithField("actor", actor).WithField("method", r.Method).WithField("field", field).WithField("dict", dict)
+		if id := requestid.FromContext(r.Context()); id != "" {
+			entry = entry.WithField("request_id", id)
+		}
+		if d != nil {
+			entry = entry.WithField("new_field", d.Field).WithField("new_dict", d.Dict)
+		}
+		entry.Info("Changed dicts")
+
+		res = dicts
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if res == nil {
+		res = Dicts{}
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+// changeDicts adds d (POST), replaces the dict bound to field by d (PUT) or removes it (DELETE). It returns the new
+// dicts or an error with the HTTP status code to reply with.
+func (fe *Frontend) changeDicts(method string, field string, dict string, d *Dict) (Dicts, int, error) {
+	fe.dictsMu.Lock()
+	defer fe.dictsMu.Unlock()
+
+	current := fe.getDictCfgs()
+	i := current.index(field, dict)
+	if method != http.MethodPost && i < 0 {
+		return nil, http.StatusNotFound, fmt.Errorf("Dict %q for field %q not found", dict, field)
+	}
+
+	res := make(Dicts, 0, len(current)+1)
+	switch method {
+	case http.MethodPost:
+		res = append(append(res, current...), d)
+	case http.MethodPut:
+		res = append(append(append(res, current[:i]...), d), current[i+1:]...)
+	case http.MethodDelete:
+		res = append(append(res, current[:i]...), current[i+1:]...)
+	}
+
+	if d != nil {
+		for _, x := range res {
+			if x != d && x.Field == d.Field && x.Dict == d.Dict {
+				return nil, http.StatusConflict, fmt.Errorf("Dict %q for field %q exists already", d.Dict, d.Field)
+			}
+		}
+	}
+
+	err := fe.checkDicts(res, d)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	err = fe.dictStore.SaveDicts(res)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Unable to save dicts")
+	}
+
+	fe.mu.Lock()
+	fe.dictCfgs = res
+	fe.mu.Unlock()
+
+	return res, http.StatusOK, nil
+}
+
+// checkDicts checks that the dicts are well formed, bound to known fields or the columns of other dicts and use
+// known fields as keys. If the frontend has a gateway, changed must exist in ClickHouse.
+func (fe *Frontend) checkDicts(dicts Dicts, changed *Dict) error {
+	err := dicts.Validate()
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]struct{})
+	for _, f := range fe.catalog() {
+		known[f.Name] = struct{}{}
+	}
+
+	for _, x := range dicts {
+		if i := strings.LastIndex(x.Field, "__"); i > 0 {
+			if len(dicts.getDicts(x.Field[:i])) == 0 {
+				return fmt.Errorf("Dict %q for field %q: no dict bound to %q", x.Dict, x.Field, x.Field[:i])
+			}
+		} else if _, exists := known[x.Field]; !exists {
+			return fmt.Errorf("Dict %q for field %q: unknown field", x.Dict, x.Field)
+		}
+
+		for _, k := range x.Keys {
+			if _, exists := known[k]; !exists {
+				return fmt.Errorf("Dict %q for field %q: unknown key %q", x.Dict, x.Field, k)
+			}
+		}
+	}
+
+	if changed == nil || fe.chgw == nil {
+		return nil
+	}
+
+	attrs, err := fe.chgw.GetDictFields(changed.Dict)
+	if err != nil || len(attrs) == 0 {
+		return fmt.Errorf("Dict %q for field %q not found in ClickHouse", changed.Dict, changed.Field)
+	}
+
+	return nil
+}
+
+// index gets the position of the dict bound to field or -1
+func (d Dicts) index(field string, dict string) int {
+	for i, x := range d {
+		if x.Field == field && x.Dict == dict {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// DictNames gets the distinct names of the dicts
+func (d Dicts) DictNames() []string {
+	seen := make(map[string]struct{})
+	res := make([]string, 0)
+	for _, x := range d {
+		if _, exists := seen[x.Dict]; exists {
+			continue
+		}
+
+		seen[x.Dict] = struct{}{}
+		res = append(res, x.Dict)
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// reloadDictsHandler handles POST requests for /admin/dicts/reload. It reloads the dict given by the dict parameter or
+// all dicts bound to fields from their sources and returns their state afterwards.
+func (fe *Frontend) reloadDictsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	names := fe.getDictCfgs().DictNames()
+	if dict := r.URL.Query().Get("dict"); dict != "" {
+		i := sort.SearchStrings(names, dict)
+		if i == len(names) || names[i] != dict {
+			httpError(w, r, fmt.Sprintf("Dict %q not bound to any field", dict), http.StatusNotFound)
+			return
+		}
+
+		names = []string{dict}
+	}
+
+	l := requestLogger(r)
+	res := make([]*clickhousegw.DictReload, 0, len(names))
+	for _, name := range names {
+		dr := fe.chgw.ReloadDict(name)
+		if dr.Error != "" {
+			l.WithField("dict", dr.Dict).WithField("error", dr.Error).Error("Unable to reload dict")
+		}
+
+		res = append(res, dr)
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
@@ -0,0 +1,169 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockDictStore struct {
+	saved Dicts
+	err   error
+}
+
+func (m *mockDictStore) SaveDicts(d Dicts) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.saved = d
+	return nil
+}
+
+func TestDictsHandler(t *testing.T) {
+	interfaces := &Dict{Field: "int_in", Dict: "interfaces_dict", Expr: "tuple(IPv6NumToString(%s), %s)", Keys: []string{"agent", "int_in"}}
+	sites := &Dict{Field: "int_in__site_id", Dict: "sites_dict", Expr: "tuple(%s)"}
+
+	tests := []struct {
+		name     string
+		method   string
+		query    string
+		body     string
+		expected Dicts
+		code     int
+	}{
+		{
+			name:     "List",
+			method:   http.MethodGet,
+			expected: Dicts{interfaces, sites},
+			code:     http.StatusOK,
+		},
+		{
+			name:     "Add",
+			method:   http.MethodPost,
+			body:     `{"field": "src_asn", "dict": "asn_dict", "expr": "tuple(%s)"}`,
+			expected: Dicts{interfaces, sites, {Field: "src_asn", Dict: "asn_dict", Expr: "tuple(%s)"}},
+			code:     http.StatusOK,
+		},
+		{
+			name:   "Add existing",
+			method: http.MethodPost,
+			body:   `{"field": "int_in", "dict": "interfaces_dict", "expr": "tuple(%s)"}`,
+			code:   http.StatusConflict,
+		},
+		{
+			name:   "Add unknown field",
+			method: http.MethodPost,
+			body:   `{"field": "foo", "dict": "asn_dict", "expr": "tuple(%s)"}`,
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "Add unknown key",
+			method: http.MethodPost,
+			body:   `{"field": "int_out", "dict": "interfaces_dict", "expr": "tuple(%s, %s)", "keys": ["router", "int_out"]}`,
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "Add malformed",
+			method: http.MethodPost,
+			body:   `{"field": "src_asn", "dict": "asn_dict", "expr": "tuple(%s, %s)"}`,
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:     "Replace",
+			method:   http.MethodPut,
+			query:    "field=int_in__site_id&dict=sites_dict",
+			body:     `{"field": "int_in__site_id", "dict": "sites_v2_dict", "expr": "tuple(%s)"}`,
+			expected: Dicts{interfaces, {Field: "int_in__site_id", Dict: "sites_v2_dict", Expr: "tuple(%s)"}},
+			code:     http.StatusOK,
+		},
+		{
+			name:   "Replace missing",
+			method: http.MethodPut,
+			query:  "field=src_asn&dict=asn_dict",
+			body:   `{"field": "src_asn", "dict": "asn_dict", "expr": "tuple(%s)"}`,
+			code:   http.StatusNotFound,
+		},
+		{
+			name:     "Delete",
+			method:   http.MethodDelete,
+			query:    "field=int_in__site_id&dict=sites_dict",
+			expected: Dicts{interfaces},
+			code:     http.StatusOK,
+		},
+		{
+			name:   "Delete parent of chained dict",
+			method: http.MethodDelete,
+			query:  "field=int_in&dict=interfaces_dict",
+			code:   http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		store := &mockDictStore{}
+		fe := &Frontend{
+			dictCfgs:  Dicts{interfaces, sites},
+			dictStore: store,
+		}
+
+		r := httptest.NewRequest(test.method, "/admin/dicts?"+test.query, strings.NewReader(test.body))
+		rec := httptest.NewRecorder()
+		fe.dictsHandler(rec, r)
+
+		assert.Equal(t, test.code, rec.Code, test.name)
+		if test.code != http.StatusOK {
+			assert.Equal(t, Dicts{interfaces, sites}, fe.getDictCfgs(), test.name)
+			assert.Nil(t, store.saved, test.name)
+			continue
+		}
+
+		res := Dicts{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res), test.name)
+		assert.Equal(t, test.expected, res, test.name)
+		assert.Equal(t, test.expected, fe.getDictCfgs(), test.name)
+		if test.method != http.MethodGet {
+			assert.Equal(t, test.expected, store.saved, test.name)
+		}
+	}
+}
+
+func TestDictsHandlerStore(t *testing.T) {
+	fe := &Frontend{}
+	rec := httptest.NewRecorder()
+	fe.dictsHandler(rec, httptest.NewRequest(http.MethodPost, "/admin/dicts", strings.NewReader(`{"field": "src_asn", "dict": "asn_dict", "expr": "tuple(%s)"}`)))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+
+	fe.SetDictStore(&mockDictStore{err: fmt.Errorf("disk full")})
+	rec = httptest.NewRecorder()
+	fe.dictsHandler(rec, httptest.NewRequest(http.MethodPost, "/admin/dicts", strings.NewReader(`{"field": "src_asn", "dict": "asn_dict", "expr": "tuple(%s)"}`)))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Nil(t, fe.getDictCfgs())
+}
+
+func TestDictNames(t *testing.T) {
+	d := Dicts{
+		{Field: "int_in", Dict: "interfaces_dict"},
+		{Field: "int_out", Dict: "interfaces_dict"},
+		{Field: "int_in__site_id", Dict: "sites"},
+		{Field: "agent", Dict: "agents_dict"},
+	}
+
+	assert.Equal(t, []string{"agents_dict", "interfaces_dict", "sites"}, d.DictNames())
+}
+
+func TestReloadDictsHandler(t *testing.T) {
+	fe := &Frontend{dictCfgs: Dicts{{Field: "int_in", Dict: "interfaces_dict"}}}
+
+	rec := httptest.NewRecorder()
+	fe.reloadDictsHandler(rec, httptest.NewRequest(http.MethodGet, "/admin/dicts/reload", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	fe.reloadDictsHandler(rec, httptest.NewRequest(http.MethodPost, "/admin/dicts/reload?dict=sites", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,245 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultDiffOffset = 86400
+	defaultDiffTop    = 20
+	maxDiffTop        = 1000
+)
+
+// QueryDiff compares the traffic per breakdown key of a time range with a compared time range
+type QueryDiff struct {
+	Start        time.Time    `json:"start"`
+	End          time.Time    `json:"end"`
+	CompareStart time.Time    `json:"compare_start"`
+	CompareEnd   time.Time    `json:"compare_end"`
+	Unit         string       `json:"unit"`
+	Entries      []*DiffEntry `json:"entries"`
+}
+
+// DiffEntry is the average rate of a breakdown key in both time ranges
+type DiffEntry struct {
+	Key         string  `json:"key"`
+	Mbps        float64 `json:"mbps"`
+	CompareMbps float64 `json:"compare_mbps"`
+	DeltaMbps   float64 `json:"delta_mbps"`
+
+	// ChangePercent is the change compared to the compared time range (null if there was no traffic)
+	ChangePercent *float64 `json:"change_percent"`
+}
+
+// diffHandler serves the change of the average rate per breakdown key between two time ranges as JSON. It takes the
+// parameters of /query. The compared range is given by compare_start and compare_end or is the queried range moved
+// back by offset seconds (default one day). The top keys with the largest changes either way are returned.
+func (fe *Frontend) diffHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := fe.processDiffQuery(r.Context(), r.URL.Query())
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Unable to process diff query")
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func (fe *Frontend) processDiffQuery(ctx context.Context, fields url.Values) (*QueryDiff, error) {
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	compareStart, compareEnd, err := getCompareRange(fields, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range [][2]int64{{start, end}, {compareStart, compareEnd}} {
+		if r[1] <= r[0] {
+			return nil, fmt.Errorf("Empty time range")
+		}
+	}
+
+	top := defaultDiffTop
+	if t := fields.Get("top"); t != "" {
+		top, err = strconv.Atoi(t)
+		if err != nil || top <= 0 || top > maxDiffTop {
+			return nil, fmt.Errorf("Invalid top %q", t)
+		}
+	}
+
+	current, err := fe.diffTotals(ctx, fields, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	compared, err := fe.diffTotals(ctx, fields, compareStart, compareEnd)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to query compared time range")
+	}
+
+	return &QueryDiff{
+		Start:        time.Unix(start, 0).UTC(),
+		End:          time.Unix(end, 0).UTC(),
+		CompareStart: time.Unix(compareStart, 0).UTC(),
+		CompareEnd:   time.Unix(compareEnd, 0).UTC(),
+		Unit:         "Mbps",
+		Entries:      buildDiff(current, compared, top),
+	}, nil
+}
+
+// getCompareRange returns the time range given by compare_start and compare_end or the range moved back by offset
+func getCompareRange(fields url.Values, start int64, end int64) (int64, int64, error) {
+	if fields.Get("compare_start") != "" || fields.Get("compare_end") != "" {
+		compare := url.Values{}
+		for from, to := range map[string]string{"compare_start": "time_start", "compare_end": "time_end", "tz": "tz"} {
+			if v, exists := fields[from]; exists {
+				compare[to] = v
+			}
+		}
+
+		s, e, err := getTimeRange(compare)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "Invalid compared time range")
+		}
+
+		return s, e, nil
+	}
+
+	offset := int64(defaultDiffOffset)
+	if o := fields.Get("offset"); o != "" {
+		var err error
+		offset, err = strconv.ParseInt(o, 10, 64)
+		if err != nil || offset <= 0 {
+			return 0, 0, fmt.Errorf("Invalid offset %q", o)
+		}
+	}
+
+	return start - offset, end - offset, nil
+}
+
+// diffTotals gets the average rate per breakdown key between start and end
+func (fe *Frontend) diffTotals(ctx context.Context, fields url.Values, start int64, end int64) (map[string]float64, error) {
+	p, err := fe.planQueryRange(fields, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := diffQuery(p, fe.chgw.GetDatabaseName(), end-start)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := fe.chgw.QueryContext(ctx, q)
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to get columns")
+	}
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range columns {
+		valuePtrs[i] = &values[i]
+	}
+
+	catalog := fe.catalog()
+	names := fe.getNames()
+	res := make(map[string]float64)
+	for rows.Next() {
+		err := rows.Scan(valuePtrs...)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		mbps, ok := values[len(columns)-1].(float64)
+		if !ok {
+			return nil, fmt.Errorf("expected float64 for the last column")
+		}
+
+		res[formatKey(columns[:len(columns)-1], values[:len(columns)-1], catalog, names)] += mbps
+	}
+
+	return res, rows.Err()
+}
+
+// diffQuery sums the rates of the time slots of p up per breakdown key and averages them over seconds
+func diffQuery(p *queryPlan, db string, seconds int64) (string, error) {
+	if len(p.groupBy) < 2 {
+		return "", fmt.Errorf("No breakdown field could be resolved")
+	}
+
+	keys := p.groupBy[1:]
+
+	return fmt.Sprintf("SELECT %s, sum(mbps) * %d / %d AS avg_mbps FROM (%s) GROUP BY %s ORDER BY avg_mbps DESC LIMIT 10000",
+		strings.Join(keys, ", "), p.slot, seconds, p.sql(db, ""), strings.Join(keys, ", ")), nil
+}
+
+// buildDiff gets the top keys by magnitude of change. Keys with equal changes are ordered by name.
+func buildDiff(current map[string]float64, compared map[string]float64, top int) []*DiffEntry {
+	res := make([]*DiffEntry, 0, len(current))
+	for k, mbps := range current {
+		res = append(res, newDiffEntry(k, mbps, compared[k]))
+	}
+
+	for k, mbps := range compared {
+		if _, exists := current[k]; !exists {
+			res = append(res, newDiffEntry(k, 0, mbps))
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		a, b := math.Abs(res[i].DeltaMbps), math.Abs(res[j].DeltaMbps)
+		if a != b {
+			return a > b
+		}
+
+		return res[i].Key < res[j].Key
+	})
+
+	if len(res) > top {
+		res = res[:top]
+	}
+
+	return res
+}
+
+func newDiffEntry(key string, mbps float64, compareMbps float64) *DiffEntry {
+	e := &DiffEntry{
+		Key:         key,
+		Mbps:        mbps,
+		CompareMbps: compareMbps,
+		DeltaMbps:   mbps - compareMbps,
+	}
+
+	if compareMbps > 0 {
+		change := e.DeltaMbps / compareMbps * 100
+		e.ChangePercent = &change
+	}
+
+	return e
+}
@@ -0,0 +1,128 @@
+package frontend
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffQuery(t *testing.T) {
+	fe := &Frontend{}
+	fields, err := url.ParseQuery("breakdown=src_asn&breakdown=ip_protocol&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00")
+	if err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+
+	p, err := fe.planQueryRange(fields, 1704060000, 1704063600)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	q, err := diffQuery(p, "db", 3600)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT src_asn, ip_protocol, sum(mbps) * 10 / 3600 AS avg_mbps FROM ("+
+		"SELECT timestamp as t, src_asn as src_asn, ip_protocol as ip_protocol, sum(size * samplerate) * 8 / 10 / 1000000 AS mbps FROM db.flows WHERE t BETWEEN toDateTime(1704060000) AND toDateTime(1704063600) GROUP BY t, src_asn, ip_protocol "+
+		") GROUP BY src_asn, ip_protocol ORDER BY avg_mbps DESC LIMIT 10000", q)
+
+	_, err = diffQuery(newQueryPlan(), "db", 3600)
+	assert.Error(t, err)
+}
+
+func TestBuildDiff(t *testing.T) {
+	current := map[string]float64{"a": 10, "b": 50, "c": 5}
+	compared := map[string]float64{"a": 40, "b": 20, "d": 30}
+
+	res := buildDiff(current, compared, 3)
+	assert.Equal(t, 3, len(res))
+
+	keys := make([]string, 0)
+	for _, e := range res {
+		keys = append(keys, e.Key)
+	}
+	assert.Equal(t, []string{"a", "b", "d"}, keys)
+
+	assert.Equal(t, float64(-30), res[0].DeltaMbps)
+	assert.Equal(t, float64(-75), *res[0].ChangePercent)
+	assert.Equal(t, float64(150), *res[1].ChangePercent)
+	assert.Equal(t, float64(0), res[2].Mbps)
+
+	res = buildDiff(current, compared, 10)
+	assert.Equal(t, "c", res[3].Key)
+	assert.Nil(t, res[3].ChangePercent)
+}
+
+func TestGetCompareRange(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedStart int64
+		expectedEnd   int64
+		wantFail      bool
+	}{
+		{
+			name:          "Default offset",
+			query:         "",
+			expectedStart: 1704067200 - 86400,
+			expectedEnd:   1704070800 - 86400,
+		},
+		{
+			name:          "Offset",
+			query:         "offset=3600",
+			expectedStart: 1704063600,
+			expectedEnd:   1704067200,
+		},
+		{
+			name:          "Explicit range",
+			query:         "compare_start=2023-12-01T00:00&compare_end=2023-12-01T02:00",
+			expectedStart: 1701388800,
+			expectedEnd:   1701396000,
+		},
+		{
+			name:     "Invalid offset",
+			query:    "offset=-1",
+			wantFail: true,
+		},
+		{
+			name:     "Incomplete range",
+			query:    "compare_start=2023-12-01T00:00",
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		fields, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatalf("Invalid query for test %q: %v", test.name, err)
+		}
+
+		start, end, err := getCompareRange(fields, 1704067200, 1704070800)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expectedStart, start, test.name)
+		assert.Equal(t, test.expectedEnd, end, test.name)
+	}
+}
+
+func TestProcessDiffQueryValidation(t *testing.T) {
+	fe := &Frontend{}
+	for _, query := range []string{
+		"breakdown=src_asn&time_start=2024-01-01T01:00&time_end=2024-01-01T00:00",
+		"breakdown=src_asn&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&compare_start=2023-12-01T02:00&compare_end=2023-12-01T00:00",
+		"breakdown=src_asn&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&top=0",
+		"breakdown=src_asn&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&offset=x",
+	} {
+		fields, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatalf("Invalid query %q: %v", query, err)
+		}
+
+		_, err = fe.processDiffQuery(context.Background(), fields)
+		assert.Error(t, err, query)
+	}
+}
@@ -0,0 +1,179 @@
+package frontend
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// downsampleAvg merges time slots into their average
+	downsampleAvg = "avg"
+
+	// downsampleMax merges time slots into their maximum, which keeps peaks visible
+	downsampleMax = "max"
+)
+
+// DownsampleConfig limits the number of points per series of query results
+type DownsampleConfig struct {
+	// MaxPoints is the number of time slots per series above which results are downsampled
+	MaxPoints int `yaml:"max_points"`
+
+	// Method merges time slots, avg (default) or max
+	Method string `yaml:"method"`
+}
+
+func (c *DownsampleConfig) loadDefaults() {
+	if c.Method == "" {
+		c.Method = downsampleAvg
+	}
+}
+
+// Validate validates the downsampling config
+func (c *DownsampleConfig) Validate() error {
+	if c.MaxPoints < 1 {
+		return fmt.Errorf("max_points must be at least 1")
+	}
+
+	if !isDownsampleMethod(c.Method) {
+		return fmt.Errorf("Invalid method %q", c.Method)
+	}
+
+	return nil
+}
+
+func isDownsampleMethod(m string) bool {
+	return m == downsampleAvg || m == downsampleMax
+}
+
+// SetDownsampling downsamples query results with more than cfg.MaxPoints time slots per series
+func (fe *Frontend) SetDownsampling(cfg *DownsampleConfig) error {
+	cfg.loadDefaults()
+	err := cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	fe.downsampling = cfg
+	return nil
+}
+
+// downsampling merges the time slots of a result so no series has more than maxPoints values
+type downsampling struct {
+	method    string
+	maxPoints int
+}
+
+// getDownsampling gets the downsampling of a query. The max_points parameter lowers the configured maximum (or sets one
+// if none is configured), downsample selects the method. nil if there is no maximum.
+func (fe *Frontend) getDownsampling(fields url.Values) (*downsampling, error) {
+	d := &downsampling{
+		method: downsampleAvg,
+	}
+
+	if fe.downsampling != nil {
+		d.method = fe.downsampling.Method
+		d.maxPoints = fe.downsampling.MaxPoints
+	}
+
+	if m := fields.Get("downsample"); m != "" {
+		if !isDownsampleMethod(m) {
+			return nil, fmt.Errorf("Invalid downsample %q", m)
+		}
+		d.method = m
+	}
+
+	if mp := fields.Get("max_points"); mp != "" {
+		maxPoints, err := strconv.Atoi(mp)
+		if err != nil || maxPoints < 1 {
+			return nil, fmt.Errorf("Invalid max_points %q", mp)
+		}
+
+		if d.maxPoints == 0 || maxPoints < d.maxPoints {
+			d.maxPoints = maxPoints
+		}
+	}
+
+	if d.maxPoints == 0 {
+		return nil, nil
+	}
+
+	return d, nil
+}
+
+// downsample merges the time slots of r into slots of a multiple of its resolution, aligned like those of queries,
+// until there are no more than d.maxPoints. Averages are taken over the time slots r has, a series without a value in
+// one of them counts as 0.
+func (r *result) downsample(d *downsampling) {
+	timestamps := r.getTimestampsSorted()
+	if len(timestamps) <= d.maxPoints || r.resolution == 0 {
+		return
+	}
+
+	factor := uint64((len(timestamps) + d.maxPoints - 1) / d.maxPoints)
+	for countSlots(timestamps, r.resolution*factor) > d.maxPoints {
+		factor++
+	}
+	resolution := int64(r.resolution * factor)
+
+	loc := timestamps[0].Location()
+	data := make(map[time.Time]map[string]uint64)
+	var slot time.Time
+	var slotTimestamps []time.Time
+	for i, ts := range timestamps {
+		slotStart := ts.Unix() - ts.Unix()%resolution
+		if i == 0 || slotStart != slot.Unix() {
+			if i > 0 {
+				data[slot] = r.merge(slotTimestamps, d.method)
+			}
+
+			slot = time.Unix(slotStart, 0).In(loc)
+			slotTimestamps = slotTimestamps[:0]
+		}
+
+		slotTimestamps = append(slotTimestamps, ts)
+	}
+	data[slot] = r.merge(slotTimestamps, d.method)
+
+	r.data = data
+	r.resolution = uint64(resolution)
+	r.downsampled = d.method
+}
+
+// merge gets the values of all keys merged over timestamps
+func (r *result) merge(timestamps []time.Time, method string) map[string]uint64 {
+	res := make(map[string]uint64, len(r.keys))
+	for k := range r.keys {
+		sum := uint64(0)
+		for _, ts := range timestamps {
+			v := r.data[ts][k]
+			sum += v
+			if method == downsampleMax && v > res[k] {
+				res[k] = v
+			}
+		}
+
+		if method == downsampleAvg {
+			res[k] = uint64(math.Round(float64(sum) / float64(len(timestamps))))
+		}
+	}
+
+	return res
+}
+
+// countSlots counts the time slots of resolution seconds the sorted timestamps fall into
+func countSlots(timestamps []time.Time, resolution uint64) int {
+	n := 0
+	last := int64(-1)
+	for _, ts := range timestamps {
+		slot := ts.Unix() - ts.Unix()%int64(resolution)
+		if slot != last {
+			n++
+			last = slot
+		}
+	}
+
+	return n
+}
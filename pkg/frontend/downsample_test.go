@@ -0,0 +1,144 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownsampleConfig(t *testing.T) {
+	fe := &Frontend{}
+	assert.Error(t, fe.SetDownsampling(&DownsampleConfig{}))
+	assert.Error(t, fe.SetDownsampling(&DownsampleConfig{MaxPoints: 100, Method: "median"}))
+
+	assert.NoError(t, fe.SetDownsampling(&DownsampleConfig{MaxPoints: 100}))
+	assert.Equal(t, downsampleAvg, fe.downsampling.Method)
+}
+
+func TestGetDownsampling(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *DownsampleConfig
+		query    string
+		expected *downsampling
+		wantFail bool
+	}{
+		{
+			name: "Not configured",
+		},
+		{
+			name:     "Configured",
+			cfg:      &DownsampleConfig{MaxPoints: 500, Method: downsampleAvg},
+			expected: &downsampling{method: downsampleAvg, maxPoints: 500},
+		},
+		{
+			name:     "Fewer points and other method",
+			cfg:      &DownsampleConfig{MaxPoints: 500, Method: downsampleAvg},
+			query:    "max_points=100&downsample=max",
+			expected: &downsampling{method: downsampleMax, maxPoints: 100},
+		},
+		{
+			name:     "More points than configured",
+			cfg:      &DownsampleConfig{MaxPoints: 500, Method: downsampleAvg},
+			query:    "max_points=1000",
+			expected: &downsampling{method: downsampleAvg, maxPoints: 500},
+		},
+		{
+			name:     "Not configured with max_points",
+			query:    "max_points=1000",
+			expected: &downsampling{method: downsampleAvg, maxPoints: 1000},
+		},
+		{
+			name:     "Invalid max_points",
+			query:    "max_points=0",
+			wantFail: true,
+		},
+		{
+			name:     "Invalid method",
+			query:    "downsample=min",
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		fe := &Frontend{downsampling: test.cfg}
+		fields, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatalf("Invalid query %q: %v", test.query, err)
+		}
+
+		d, err := fe.getDownsampling(fields)
+		assert.Equal(t, test.wantFail, err != nil, test.name)
+		assert.Equal(t, test.expected, d, test.name)
+	}
+}
+
+func TestResultDownsample(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	newTestResult := func() *result {
+		res := newResult()
+		res.resolution = 10
+		for i, v := range []uint64{10, 20, 30, 40, 50, 60} {
+			res.add(start.Add(time.Duration(i*10)*time.Second), "agent=rtr01", v)
+		}
+		res.add(start.Add(50*time.Second), "agent=rtr02", 8)
+
+		return res
+	}
+
+	tests := []struct {
+		name        string
+		d           *downsampling
+		resolution  uint64
+		downsampled string
+		timestamps  []time.Time
+		values      [][]uint64
+	}{
+		{
+			name:       "Few enough points",
+			d:          &downsampling{method: downsampleAvg, maxPoints: 6},
+			resolution: 10,
+			timestamps: []time.Time{start, start.Add(10 * time.Second), start.Add(20 * time.Second), start.Add(30 * time.Second), start.Add(40 * time.Second), start.Add(50 * time.Second)},
+			values:     [][]uint64{{10, 20, 30, 40, 50, 60}, {0, 0, 0, 0, 0, 8}},
+		},
+		{
+			name:        "Average",
+			d:           &downsampling{method: downsampleAvg, maxPoints: 3},
+			resolution:  20,
+			downsampled: downsampleAvg,
+			timestamps:  []time.Time{start, start.Add(20 * time.Second), start.Add(40 * time.Second)},
+			values:      [][]uint64{{15, 35, 55}, {0, 0, 4}},
+		},
+		{
+			name:        "Maximum",
+			d:           &downsampling{method: downsampleMax, maxPoints: 2},
+			resolution:  30,
+			downsampled: downsampleMax,
+			timestamps:  []time.Time{start, start.Add(30 * time.Second)},
+			values:      [][]uint64{{30, 60}, {0, 8}},
+		},
+		{
+			name:        "Single point",
+			d:           &downsampling{method: downsampleMax, maxPoints: 1},
+			resolution:  60,
+			downsampled: downsampleMax,
+			timestamps:  []time.Time{start},
+			values:      [][]uint64{{60}, {8}},
+		},
+	}
+
+	for _, test := range tests {
+		res := newTestResult()
+		res.downsample(test.d)
+
+		r := res.export()
+		assert.Equal(t, test.resolution, r.Resolution, test.name)
+		assert.Equal(t, test.downsampled, r.Downsampled, test.name)
+		assert.Equal(t, test.timestamps, r.Timestamps, test.name)
+		for i, s := range r.Series {
+			assert.Equal(t, test.values[i], s.Values, test.name)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package frontend
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	// fillZero adds the time slots of the range a series has no flows in as 0
+	fillZero = "zero"
+
+	// maxFillSlots limits the number of time slots filled per series
+	maxFillSlots = 100000
+)
+
+// getFill checks the fill parameter, which is either unset or zero
+func getFill(fields url.Values) (bool, error) {
+	switch f := fields.Get("fill"); f {
+	case "":
+		return false, nil
+	case fillZero:
+		return true, nil
+	default:
+		return false, fmt.Errorf("Invalid fill %q", f)
+	}
+}
+
+// fill adds every time slot between start and end to r with a value of 0 for each series without a value in it, so
+// charts do not interpolate across gaps. Time slots are aligned to slot seconds like those of the query.
+func (r *result) fill(start int64, end int64, slot uint64) error {
+	if slot == 0 {
+		return fmt.Errorf("Invalid slot size 0")
+	}
+
+	s := int64(slot)
+	first := start - start%s
+	if first < start {
+		first += s
+	}
+
+	if end >= first && (end-first)/s >= maxFillSlots {
+		return fmt.Errorf("Time range too long to fill, more than %d time slots", maxFillSlots)
+	}
+
+	loc := time.UTC
+	existing := make(map[int64]time.Time, len(r.data))
+	for ts := range r.data {
+		existing[ts.Unix()] = ts
+		loc = ts.Location()
+	}
+
+	for t := first; t <= end; t += s {
+		ts, exists := existing[t]
+		if !exists {
+			ts = time.Unix(t, 0).In(loc)
+			r.data[ts] = make(map[string]uint64)
+		}
+
+		for k := range r.keys {
+			if _, exists := r.data[ts][k]; !exists {
+				r.data[ts][k] = 0
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFill(t *testing.T) {
+	fill, err := getFill(url.Values{})
+	assert.NoError(t, err)
+	assert.False(t, fill)
+
+	fill, err = getFill(url.Values{"fill": []string{"zero"}})
+	assert.NoError(t, err)
+	assert.True(t, fill)
+
+	_, err = getFill(url.Values{"fill": []string{"linear"}})
+	assert.Error(t, err)
+}
+
+func TestResultFill(t *testing.T) {
+	loc := time.FixedZone("CET", 3600)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, loc)
+	res := newResult()
+	res.add(start.Add(10*time.Second), "agent=rtr01", 10)
+	res.add(start.Add(30*time.Second), "agent=rtr02", 6)
+
+	err := res.fill(start.Unix()+5, start.Unix()+40, 10)
+	assert.NoError(t, err)
+
+	r := res.export()
+	assert.Equal(t, []time.Time{
+		start.Add(10 * time.Second),
+		start.Add(20 * time.Second),
+		start.Add(30 * time.Second),
+		start.Add(40 * time.Second),
+	}, r.Timestamps)
+	assert.Equal(t, []uint64{10, 0, 0, 0}, r.Series[0].Values)
+	assert.Equal(t, []uint64{0, 0, 6, 0}, r.Series[1].Values)
+	assert.Len(t, res.data[start.Add(20*time.Second)], 2)
+
+	assert.Error(t, newResult().fill(0, 10*maxFillSlots, 10))
+	assert.Error(t, newResult().fill(0, 10, 0))
+}
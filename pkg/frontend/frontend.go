@@ -2,6 +2,7 @@ package frontend
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -11,112 +12,67 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/models/annotation"
+	"github.com/bio-routing/flowhouse/pkg/models/preferences"
+	"github.com/bio-routing/flowhouse/pkg/requestid"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
 	bnet "github.com/bio-routing/bio-rd/net"
-	log "github.com/sirupsen/logrus"
 )
 
-var (
-	fields []struct {
-		Name       string
-		Label      string
-		ShortLabel string
-	}
-)
+const defaultAttributionBucketSeconds = 60
 
-func init() {
-	fields = []struct {
-		Name       string
-		Label      string
-		ShortLabel string
-	}{
-		{
-			Name:       "agent",
-			Label:      "Agent",
-			ShortLabel: "A.",
-		},
-		{
-			Name:       "int_in",
-			Label:      "Interface In",
-			ShortLabel: "Int.In",
-		},
-		{
-			Name:       "int_out",
-			Label:      "Interface Out",
-			ShortLabel: "Int.Out",
-		},
-		{
-			Name:       "src_ip_addr",
-			Label:      "Source IP",
-			ShortLabel: "Src.IP",
-		},
-		{
-			Name:       "src_ip_pfx",
-			Label:      "Source IP Prefix",
-			ShortLabel: "Src.IP.Pfx",
-		},
-		{
-			Name:       "dst_ip_addr",
-			Label:      "Destination IP",
-			ShortLabel: "Dst.IP",
-		},
-		{
-			Name:       "dst_ip_pfx",
-			Label:      "Destination IP Prefix",
-			ShortLabel: "Dst.IP.Pfx",
-		},
-		{
-			Name:       "nexthop",
-			Label:      "Nexthop",
-			ShortLabel: "Nexthop",
-		},
-		{
-			Name:       "next_asn",
-			Label:      "Next ASN",
-			ShortLabel: "Next ASN",
-		},
-		{
-			Name:       "src_asn",
-			Label:      "Source ASN",
-			ShortLabel: "Src.AS",
-		},
-		{
-			Name:       "dst_asn",
-			Label:      "Destination ASN",
-			ShortLabel: "Dst.AS",
-		},
-		{
-			Name:       "ip_protocol",
-			Label:      "IP Protocol",
-			ShortLabel: "IP.Proto",
-		},
-		{
-			Name:       "src_port",
-			Label:      "Source Port",
-			ShortLabel: "Src.Port",
-		},
-		{
-			Name:       "dst_port",
-			Label:      "Destination Port",
-			ShortLabel: "Dst.Port",
-		},
-	}
+type fieldDescription struct {
+	Name       string
+	Label      string
+	ShortLabel string
 }
 
 // Frontend is a web frontend service
 type Frontend struct {
-	chgw     *clickhousegw.ClickHouseGateway
-	dictCfgs Dicts
+	chgw           *clickhousegw.ClickHouseGateway
+	rollups        *clickhousegw.Rollups
+	dictCfgs       Dicts
+	computedFields ComputedFields
+	geoFields      ComputedFields
+	annotations    Annotations
+	preferences    Preferences
+	names          *names
+	ui             *UIConfig
+	i18n           *I18nConfig
+	dictStore      DictStore
+	joins          Joins
+	downsampling   *DownsampleConfig
+	mu             sync.RWMutex
+	dictsMu        sync.Mutex
+
+	mux        *http.ServeMux
+	middleware []Middleware
+	server     *http.Server
+	limiter    *queryLimiter
+}
+
+// Annotations provides the annotations overlaid on query results
+type Annotations interface {
+	Get(start time.Time, end time.Time, agents []string) []*annotation.Annotation
+}
+
+// Preferences provides the preferences of the user of a request
+type Preferences interface {
+	Get(r *http.Request) *preferences.Preferences
 }
 
 // IndexView is the index template data structure
 type IndexView struct {
 	FieldGroups  []*FieldGroup
 	BreakDownLen int
+	Preferences  *preferences.Preferences
+	Language     string
 }
 
 type FieldGroup struct {
@@ -128,46 +84,163 @@ type FieldGroup struct {
 type Field struct {
 	Name  string
 	Label string
+
+	// Type is the ClickHouse type of dict columns, the UI offers numeric inputs for numeric ones
+	Type    string
+	Numeric bool
 }
 
-// Dict connects a fields with a dict
+// Dict connects a fields with a dict. Field can also be a column derived from another dict (e.g. int_in__site_id) to
+// chain lookups. Several dicts can be bound to the same field.
 type Dict struct {
-	Field string   `yaml:"field"`
-	Dict  string   `yaml:"dict"`
-	Expr  string   `yaml:"expr"`
-	Keys  []string `yaml:"keys"`
+	Field string   `yaml:"field" json:"field"`
+	Dict  string   `yaml:"dict" json:"dict"`
+	Expr  string   `yaml:"expr" json:"expr"`
+	Keys  []string `yaml:"keys,omitempty" json:"keys,omitempty"`
+
+	// KeyTypes are the ClickHouse types of the keys of the dict. If set, the keys are cast to them and passed as
+	// tuple instead of being formatted into Expr.
+	KeyTypes []string `yaml:"key_types,omitempty" json:"key_types,omitempty"`
+
+	// Defaults are the values of columns for keys missing in the dict instead of the default of the column type
+	Defaults map[string]string `yaml:"defaults,omitempty" json:"defaults,omitempty"`
+
+	// Unknown groups keys missing in the dict under this value. Columns become strings then.
+	Unknown string `yaml:"unknown,omitempty" json:"unknown,omitempty"`
+}
+
+// lookup gets the expression looking up column of key in the dict named dictName. Defaults are cast to attrType if it
+// is known.
+func (d *Dict) lookup(dictName string, column string, key string, attrType string) string {
+	if d.Unknown != "" {
+		return fmt.Sprintf("if(dictHas('%s', %s), toString(dictGet('%s', '%s', %s)), '%s')", dictName, key, dictName, column, key, d.Unknown)
+	}
+
+	v, exists := d.Defaults[column]
+	if !exists {
+		return fmt.Sprintf("dictGet('%s', '%s', %s)", dictName, column, key)
+	}
+
+	def := fmt.Sprintf("'%s'", v)
+	if attrType != "" {
+		def = fmt.Sprintf("CAST(%s, '%s')", def, attrType)
+	}
+
+	return fmt.Sprintf("dictGetOrDefault('%s', '%s', %s, %s)", dictName, column, key, def)
 }
 
-func (d Dicts) getDict(field string) *Dict {
+// maxDictChainDepth limits the number of chained lookups listed in the index view
+const maxDictChainDepth = 4
+
+func (d Dicts) getDicts(field string) []*Dict {
+	res := make([]*Dict, 0)
 	for _, x := range d {
 		if x.Field == field {
-			return x
+			res = append(res, x)
 		}
 	}
 
-	return nil
+	return res
 }
 
 // Dicts is a slice of dicts
 type Dicts []*Dict
 
+// Validate checks that every dict has a field, a name and key types or an expression with one placeholder per key (or
+// a single one for the parent field without keys)
+func (d Dicts) Validate() error {
+	for _, x := range d {
+		if x.Field == "" || x.Dict == "" {
+			return fmt.Errorf("Dict %q for field %q: field and dict must be set", x.Dict, x.Field)
+		}
+
+		want := len(x.Keys)
+		if want == 0 {
+			want = 1
+		}
+
+		if len(x.KeyTypes) > 0 {
+			err := x.validateKeyTypes()
+			if err != nil {
+				return err
+			}
+		} else if n := strings.Count(x.Expr, "%s"); n != want {
+			return fmt.Errorf("Dict %q for field %q: expr %q has %d placeholders, expected %d", x.Dict, x.Field, x.Expr, n, want)
+		}
+
+		for column, v := range x.Defaults {
+			if strings.ContainsAny(v, `'\`) {
+				return fmt.Errorf("Dict %q for field %q: invalid default %q of column %q", x.Dict, x.Field, v, column)
+			}
+		}
+
+		if strings.ContainsAny(x.Unknown, `'\`) {
+			return fmt.Errorf("Dict %q for field %q: invalid unknown value %q", x.Dict, x.Field, x.Unknown)
+		}
+	}
+
+	return nil
+}
+
 // New creates a new frontend
-func New(chgw *clickhousegw.ClickHouseGateway, dictCfgs Dicts) *Frontend {
-	return &Frontend{
-		chgw:     chgw,
-		dictCfgs: dictCfgs,
+func New(chgw *clickhousegw.ClickHouseGateway, dictCfgs Dicts, computedFields ComputedFields, annotations Annotations, prefs Preferences) *Frontend {
+	fe := &Frontend{
+		chgw:           chgw,
+		rollups:        chgw.Rollups(),
+		dictCfgs:       dictCfgs,
+		computedFields: computedFields,
+		annotations:    annotations,
+		preferences:    prefs,
+		mux:            http.NewServeMux(),
 	}
+
+	fe.routes()
+	return fe
+}
+
+// Reconfigure replaces the dicts and computed fields. Queries already being built keep using the previous ones.
+func (fe *Frontend) Reconfigure(dictCfgs Dicts, computedFields ComputedFields) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.dictCfgs = dictCfgs
+	fe.computedFields = computedFields
+}
+
+func (fe *Frontend) getDictCfgs() Dicts {
+	fe.mu.RLock()
+	defer fe.mu.RUnlock()
+
+	return fe.dictCfgs
+}
+
+// getComputedFields returns the geo fields, if configured, and the computed fields
+func (fe *Frontend) getComputedFields() ComputedFields {
+	fe.mu.RLock()
+	defer fe.mu.RUnlock()
+
+	if len(fe.geoFields) == 0 {
+		return fe.computedFields
+	}
+
+	return append(append(make(ComputedFields, 0, len(fe.geoFields)+len(fe.computedFields)), fe.geoFields...), fe.computedFields...)
 }
 
-// IndexHandler handles requests for /
-func (fe *Frontend) IndexHandler(w http.ResponseWriter, r *http.Request) {
+// indexHandler handles requests for /
+func (fe *Frontend) indexHandler(w http.ResponseWriter, r *http.Request) {
 	templateAsset, err := assetsIndexHtml()
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	t, err := template.New("index.html").Parse(string(templateAsset.bytes))
+	prefs := preferences.Defaults()
+	if fe.preferences != nil {
+		prefs = fe.preferences.Get(r)
+	}
+	lang, locale := fe.language(r, prefs)
+
+	t, err := template.New("index.html").Funcs(template.FuncMap{"T": locale.translate}).Parse(string(templateAsset.bytes))
 	if err != nil {
 		log.WithError(err).Error("Unable to parse template")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -180,6 +253,10 @@ func (fe *Frontend) IndexHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	translateIndexView(indexData, locale)
+
+	indexData.Preferences = fe.defaultPreferences(prefs)
+	indexData.Language = lang
 
 	buf := bytes.NewBuffer(nil)
 	err = t.Execute(buf, indexData)
@@ -192,8 +269,8 @@ func (fe *Frontend) IndexHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(buf.Bytes())
 }
 
-// FlowhouseJSHandler gets flowhouse.js file
-func (fe *Frontend) FlowhouseJSHandler(w http.ResponseWriter, r *http.Request) {
+// flowhouseJSHandler gets flowhouse.js file
+func (fe *Frontend) flowhouseJSHandler(w http.ResponseWriter, r *http.Request) {
 	jsAsset, err := assetsFlowhouseJs()
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
@@ -203,42 +280,182 @@ func (fe *Frontend) FlowhouseJSHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsAsset.bytes)
 }
 
-// QueryHandler handles query requests
-func (fe *Frontend) QueryHandler(w http.ResponseWriter, r *http.Request) {
-	res, err := fe.processQuery(r)
+// queryHandler handles query requests
+func (fe *Frontend) queryHandler(w http.ResponseWriter, r *http.Request) {
+	l := requestLogger(r)
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "csv" && format != "xlsx" && format != "json" {
+		httpError(w, r, fmt.Sprintf("Unknown format %q", format), http.StatusBadRequest)
+		return
+	}
+
+	res, err := fe.processQuery(r.Context(), r.URL.Query(), l)
 	if err != nil {
-		log.WithError(err).Error("Unable to process query")
-		w.WriteHeader(http.StatusInternalServerError)
+		l.WithError(err).Error("Unable to process query")
+		httpError(w, r, "Unable to process query", http.StatusInternalServerError)
 		return
 	}
 
 	if res == nil {
-		log.WithError(err).Error("Query returned a nil result")
-		w.WriteHeader(http.StatusInternalServerError)
+		l.WithError(err).Error("Query returned a nil result")
+		httpError(w, r, "Query returned no result", http.StatusInternalServerError)
 		return
 	}
 
-	err = res.csv(w)
+	if format == "xlsx" {
+		fe.writeXLSX(w, r, l, res)
+		return
+	}
+
+	fe.setAnnotationsHeader(w, r.URL.Query())
+	if format == "json" {
+		j, err := json.Marshal(res.export())
+		if err != nil {
+			httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(j)
+		return
+	}
+
+	err = res.csv(w, r.URL.Query().Get("humanize") == "true")
 	if err != nil {
-		log.WithError(err).Errorf("Unable to write CSV")
-		w.WriteHeader(http.StatusInternalServerError)
+		l.WithError(err).Errorf("Unable to write CSV")
+		httpError(w, r, "Unable to write CSV", http.StatusInternalServerError)
+		return
+	}
+}
+
+// writeXLSX sends the result as XLSX download. It is buffered so failures still result in an error status.
+func (fe *Frontend) writeXLSX(w http.ResponseWriter, r *http.Request, l *logrus.Entry, res *result) {
+	buf := bytes.NewBuffer(nil)
+	err := res.xlsx(buf, r.URL.Query().Get("layout"))
+	if err != nil {
+		l.WithError(err).Errorf("Unable to write XLSX")
+		httpError(w, r, "Unable to write XLSX", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", `attachment; filename="flowhouse.xlsx"`)
+	w.Write(buf.Bytes())
+}
+
+// setAnnotationsHeader adds the annotations applying to the queried time range and agents as JSON to the X-Annotations header
+func (fe *Frontend) setAnnotationsHeader(w http.ResponseWriter, fields url.Values) {
+	if fe.annotations == nil {
+		return
+	}
+
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return
+	}
+
+	annotations := fe.annotations.Get(time.Unix(start, 0), time.Unix(end, 0), fields["agent"])
+	if len(annotations) == 0 {
 		return
 	}
+
+	j, err := json.Marshal(annotations)
+	if err != nil {
+		log.WithError(err).Error("Unable to marshal annotations")
+		return
+	}
+
+	w.Header().Set("X-Annotations", string(j))
+}
+
+// requestLogger returns a logger carrying the request ID and the queried agents
+func requestLogger(r *http.Request) *logrus.Entry {
+	return queryLogger(r.Context(), r.URL.Query())
+}
+
+func queryLogger(ctx context.Context, fields url.Values) *logrus.Entry {
+	return log.WithFields(logrus.Fields{
+		"request_id": requestid.FromContext(ctx),
+		"agent":      strings.Join(fields["agent"], ","),
+	})
+}
+
+// httpError replies with msg and the request ID, which users can report to match the failure to the server logs
+func httpError(w http.ResponseWriter, r *http.Request, msg string, code int) {
+	http.Error(w, withRequestID(r.Context(), msg), code)
+}
+
+func withRequestID(ctx context.Context, msg string) string {
+	id := requestid.FromContext(ctx)
+	if id == "" {
+		return msg
+	}
+
+	return fmt.Sprintf("%s (request ID %s)", msg, id)
+}
+
+// RunQuery runs a query given as parameters of /query. A request ID is generated unless ctx carries one. Queries
+// over long time ranges are routed like those of /query.
+func (fe *Frontend) RunQuery(ctx context.Context, fields url.Values) (*QueryResult, error) {
+	if requestid.FromContext(ctx) == "" {
+		ctx = requestid.NewContext(ctx, requestid.New())
+	}
+
+	if fe.limiter != nil {
+		err := fe.limiter.acquire(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to get a query slot")
+		}
+		defer fe.limiter.release()
+	}
+
+	ctx = fe.route(ctx, fields)
+	res, err := fe.processQuery(ctx, fields, queryLogger(ctx, fields))
+	if err != nil {
+		return nil, err
+	}
+
+	if res == nil {
+		res = newResult()
+	}
+
+	return res.export(), nil
 }
 
-func (fe *Frontend) processQuery(r *http.Request) (*result, error) {
-	if len(r.URL.Query()) == 0 {
+func (fe *Frontend) processQuery(ctx context.Context, fields url.Values, l *logrus.Entry) (*result, error) {
+	if len(fields) == 0 {
 		return nil, nil
 	}
 
-	query, err := fe.fieldsToQuery(r.URL.Query())
+	sm, err := getSmoothing(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	fill, err := getFill(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := fe.getDownsampling(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to generate SQL query")
+	}
+
+	p, err := fe.planQueryRange(fields, start, end)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to generate SQL query")
 	}
 
-	log.Info(query)
+	query := p.sql(fe.chgw.GetDatabaseName(), "ORDER BY mbps DESC LIMIT 10000")
+	l.WithField("sql", query).Debug("Executing query")
 
-	rows, err := fe.chgw.Query(query)
+	rows, err := fe.chgw.QueryContext(ctx, query)
 	defer rows.Close()
 	if err != nil {
 		return nil, errors.Wrap(err, "Query failed")
@@ -257,17 +474,24 @@ func (fe *Frontend) processQuery(r *http.Request) (*result, error) {
 	res := newResult()
 
 	rowLimit := 500 // default limit of processed rows to avoid OOM (too much data hangs the frontend)
-	if topFlowsValues, ok := r.URL.Query()["topFlows"]; ok && len(topFlowsValues) > 0 {
+	if topFlowsValues, ok := fields["topFlows"]; ok && len(topFlowsValues) > 0 {
 		topFlowsInt, err := strconv.Atoi(topFlowsValues[0])
 		if err == nil && topFlowsInt > 0 && topFlowsInt <= 10000 {
 			rowLimit = topFlowsInt
 		} else {
-			log.Errorf("Invalid topFlows value: %v", topFlowsValues[0])
+			l.Errorf("Invalid topFlows value: %v", topFlowsValues[0])
 		}
 	}
-	log.Infof("Top %d rows shown", rowLimit)
+	l.Debugf("Top %d rows shown", rowLimit)
 	othersData := make(map[time.Time]uint64) // remaining rows are aggregated in othersData[timestamp] = mbps
 
+	var sh *shares
+	if ShareRequested(fields) {
+		sh = newShares()
+	}
+
+	catalog := fe.catalog()
+	names := fe.getNames()
 	rowCount := 0
 	for rows.Next() {
 		err := rows.Scan(valuePtrs...)
@@ -284,33 +508,15 @@ func (fe *Frontend) processQuery(r *http.Request) (*result, error) {
 			return nil, fmt.Errorf("expected float64 for the last column")
 		}
 
-		if rowCount < rowLimit { // Process the top flows normally (sorted by mbps descending)
-			keyComponents := make([]string, 0)
-			for i := 1; i < len(columns)-1; i++ {
-				label := getReadableLabel(columns[i])
-
-				switch (*valuePtrs[i].(*interface{})).(type) {
-				case uint8:
-					keyComponents = append(keyComponents, fmt.Sprintf("%s=%d", label, (*valuePtrs[i].(*interface{})).(uint8)))
-				case uint16:
-					keyComponents = append(keyComponents, fmt.Sprintf("%s=%d", label, (*valuePtrs[i].(*interface{})).(uint16)))
-				case uint32:
-					keyComponents = append(keyComponents, fmt.Sprintf("%s=%d", label, (*valuePtrs[i].(*interface{})).(uint32)))
-				case uint64:
-					keyComponents = append(keyComponents, fmt.Sprintf("%s=%d", label, (*valuePtrs[i].(*interface{})).(uint64)))
-				case string:
-					s := (*valuePtrs[i].(*interface{})).(string)
-					if strings.Contains(s, "::ffff:") && strings.Contains(s, "/") {
-						s = formatPrefix(s)
-					}
-
-					keyComponents = append(keyComponents, fmt.Sprintf("%s=%s", label, s))
-				case net.IP:
-					keyComponents = append(keyComponents, fmt.Sprintf("%s=%s", label, (*valuePtrs[i].(*interface{})).(net.IP).String()))
-				}
+		if sh != nil {
+			key := "Others"
+			if rowCount < rowLimit {
+				key = formatKey(columns[1:len(columns)-1], values[1:len(columns)-1], catalog, names)
 			}
 
-			res.add(ts, strings.Join(keyComponents, ";"), uint64(value))
+			sh.add(ts, key, value)
+		} else if rowCount < rowLimit { // Process the top flows normally (sorted by mbps descending)
+			res.add(ts, formatKey(columns[1:len(columns)-1], values[1:len(columns)-1], catalog, names), uint64(value))
 		} else { // Aggregate the remaining flows in "Others"
 			othersData[ts] += uint64(value)
 		}
@@ -318,20 +524,69 @@ func (fe *Frontend) processQuery(r *http.Request) (*result, error) {
 		rowCount++
 	}
 
-	for ts, mbps := range othersData {
-		res.add(ts, "Others", mbps)
+	if sh != nil {
+		res = sh.result()
+	} else {
+		for ts, mbps := range othersData {
+			res.add(ts, "Others", mbps)
+		}
+	}
+
+	res.resolution = p.slot
+	if fill {
+		err := res.fill(start, end, p.slot)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if sm != nil {
+		res.smooth(sm)
+	}
+
+	if ds != nil {
+		res.downsample(ds)
 	}
 
 	return res, nil
 }
 
+// formatKey formats the values of the breakdown columns of a row as key of a series, e.g. Src.AS=64496;IP.Proto=tcp
+func formatKey(columns []string, values []interface{}, catalog []fieldDescription, names *names) string {
+	keyComponents := make([]string, 0, len(columns))
+	for i, column := range columns {
+		label := getReadableLabel(column, catalog)
+
+		switch v := values[i].(type) {
+		case uint8:
+			keyComponents = append(keyComponents, fmt.Sprintf("%s=%s", label, names.label(column, uint64(v))))
+		case uint16:
+			keyComponents = append(keyComponents, fmt.Sprintf("%s=%s", label, names.label(column, uint64(v))))
+		case uint32:
+			keyComponents = append(keyComponents, fmt.Sprintf("%s=%s", label, names.label(column, uint64(v))))
+		case uint64:
+			keyComponents = append(keyComponents, fmt.Sprintf("%s=%s", label, names.label(column, v)))
+		case string:
+			if strings.Contains(v, "::ffff:") && strings.Contains(v, "/") {
+				v = formatPrefix(v)
+			}
+
+			keyComponents = append(keyComponents, fmt.Sprintf("%s=%s", label, v))
+		case net.IP:
+			keyComponents = append(keyComponents, fmt.Sprintf("%s=%s", label, v.String()))
+		}
+	}
+
+	return strings.Join(keyComponents, ";")
+}
+
 func formatPrefix(s string) string {
 	parts := strings.Split(s, "/")
 	addr := net.ParseIP(parts[0])
 	return fmt.Sprintf("%s/%s", addr.String(), parts[1])
 }
 
-func getReadableLabel(label string) string {
+func getReadableLabel(label string, fields []fieldDescription) string {
 	for _, f := range fields {
 		if strings.HasPrefix(label, f.Name) {
 			label = strings.Replace(label, f.Name, f.ShortLabel, 1)
@@ -344,73 +599,243 @@ func getReadableLabel(label string) string {
 	}
 
 	parts := strings.Split(label, "__")
-	return fmt.Sprintf("%s.%s", parts[0], strings.Title(parts[1]))
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.Title(parts[i])
+	}
+
+	return strings.Join(parts, ".")
 }
 
-func (fe *Frontend) fieldsToQuery(fields url.Values) (string, error) {
-	if _, exists := fields["breakdown"]; !exists {
-		return "", fmt.Errorf("No breakdown set")
+// planQuery plans the query of the breakdown given by fields
+func (fe *Frontend) planQuery(fields url.Values) (*queryPlan, error) {
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return nil, err
 	}
 
-	if _, exists := fields["time_start"]; !exists {
-		return "", fmt.Errorf("No start time given")
-	}
+	return fe.planQueryRange(fields, start, end)
+}
 
-	if _, exists := fields["time_end"]; !exists {
-		return "", fmt.Errorf("No end time given")
+// planQueryRange plans the query of the breakdown given by fields between start and end
+func (fe *Frontend) planQueryRange(fields url.Values, start int64, end int64) (*queryPlan, error) {
+	if _, exists := fields["breakdown"]; !exists {
+		return nil, fmt.Errorf("No breakdown set")
 	}
 
-	start, err := timeFieldToTimestamp(fields["time_start"][0])
+	bucket, proportional, err := getAttribution(fields)
 	if err != nil {
-		return "", errors.Wrap(err, "Unable to parse time")
+		return nil, err
 	}
 
-	end, err := timeFieldToTimestamp(fields["time_end"][0])
+	subnetV4, subnetV6, err := getSubnetLengths(fields)
 	if err != nil {
-		return "", errors.Wrap(err, "Unable to parse time")
+		return nil, err
+	}
+
+	p := newQueryPlan()
+	rollups := fe.useRollups(fields, start, time.Now(), proportional)
+	if rollups {
+		p.table = clickhousegw.RollupTableName
+	}
+
+	if proportional {
+		p.column(fmt.Sprintf("arrayJoin(timeSlots(toDateTime(flow_start), toUInt32(intDiv(duration_ms + 999, 1000) + 1), %d))", bucket), "t")
+	} else {
+		p.column("timestamp", "t")
 	}
+	p.group("t")
 
-	selectFieldList := make([]string, 0)
-	selectFieldList = append(selectFieldList, "timestamp as t")
 	for _, fieldName := range fields["breakdown"] {
 		resolvedFieldName := resolveVirtualField(fieldName)
-		statement, err := fe.resolveDictIfNecessary(resolvedFieldName)
+		if isSubnetField(fieldName) {
+			resolvedFieldName = subnetExpr(fieldName, subnetV4, subnetV6)
+		}
+		statement, err := fe.resolveField(p, resolvedFieldName)
 		if err != nil {
 			log.WithError(err).Warning("Unable to resolve dict. Ignoring selection")
 			continue
 		}
 
-		selectFieldList = append(selectFieldList, fmt.Sprintf("%s as %s", statement, fieldName))
+		if p.column(statement, fieldName) {
+			p.group(fieldName)
+		}
 	}
-	selectFieldList = append(selectFieldList, "sum(size * samplerate) * 8 / 10 / 1000000 AS mbps")
 
-	conditions := make([]string, 0)
-	conditions = append(conditions, fmt.Sprintf("t BETWEEN toDateTime(%d) AND toDateTime(%d)", start, end))
-	for fieldName := range fields {
-		if fieldName == "breakdown" || fieldName == "time_start" || fieldName == "time_end" || strings.HasPrefix(fieldName, "filter_field") || fieldName == "topFlows" {
-			continue
+	if proportional {
+		p.slot = bucket
+		p.aggregate(fmt.Sprintf("sum(size * samplerate * %s) * 8 / %d / 1000000", bucketShare(bucket), bucket), "mbps")
+	} else if rollups {
+		p.slot = fe.rollups.Interval
+		p.aggregate(fmt.Sprintf("sum(bytes) * 8 / %d / 1000000", fe.rollups.Interval), "mbps")
+	} else {
+		p.aggregate("sum(size * samplerate) * 8 / 10 / 1000000", "mbps")
+	}
+
+	p.where(fmt.Sprintf("t BETWEEN toDateTime(%d) AND toDateTime(%d)", start, end))
+	if proportional {
+		// flows are exported after they ended, so nothing exported before start can overlap the time range
+		p.where(fmt.Sprintf("timestamp >= toDateTime(%d)", start))
+	}
+
+	durationConditions, err := getDurationConditions(fields)
+	if err != nil {
+		return nil, err
+	}
+	p.where(durationConditions...)
+	p.where(fe.fieldConditions(fields, p)...)
+
+	p.protocols, err = getProtocolPreference(fields, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// getTimeRange returns the time range given by the time_start and time_end parameters as unix timestamps. The times
+// are in the timezone given by the tz parameter (default UTC).
+func getTimeRange(fields url.Values) (int64, int64, error) {
+	if _, exists := fields["time_start"]; !exists {
+		return 0, 0, fmt.Errorf("No start time given")
+	}
+
+	if _, exists := fields["time_end"]; !exists {
+		return 0, 0, fmt.Errorf("No end time given")
+	}
+
+	loc := time.UTC
+	if tz := fields.Get("tz"); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Unknown timezone %q", tz)
 		}
+		loc = l
+	}
+
+	start, err := timeFieldToTimestamp(fields["time_start"][0], loc)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "Unable to parse time")
+	}
 
-		statement, err := fe.resolveDictIfNecessary(fieldName)
+	end, err := timeFieldToTimestamp(fields["time_end"][0], loc)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "Unable to parse time")
+	}
+
+	return start, end, nil
+}
+
+// getFieldConditions returns the filter conditions for all parameters naming a field
+func (fe *Frontend) getFieldConditions(fields url.Values) []string {
+	return fe.fieldConditions(fields, nil)
+}
+
+// fieldConditions returns the filter conditions for all parameters naming a field. Fields selected by p are referred
+// to by their alias instead of computing them (e.g. a dict lookup) again and selective conditions are moved to the
+// PREWHERE clause of p. The conditions are sorted by field, so the same parameters always result in the same query.
+// Protocol and port names are resolved to their numbers.
+func (fe *Frontend) fieldConditions(fields url.Values, p *queryPlan) []string {
+	fields = fe.getNames().resolve(fields)
+	names := conditionFields(fields)
+	conditions := make([]string, 0, len(names))
+	for _, fieldName := range names {
+		statement, err := fe.resolveField(p, fieldName)
 		if err != nil {
 			log.WithError(err).Warning("Unable to resolve dict. Ignoring condition")
 			continue
 		}
 
-		conditions = append(conditions, formatCondition(statement, fields, fieldName))
+		if p != nil && statement == fieldName && isPrewhereField(fieldName) {
+			p.prewhereCondition(formatCondition(statement, fields, fieldName))
+			continue
+		}
+
+		// invalid values (e.g. a malformed prefix or MAC address) result in no condition
+		c := formatCondition(p.ref(statement), fields, fieldName)
+		if c == "" {
+			continue
+		}
+
+		conditions = append(conditions, c)
+	}
+
+	return conditions
+}
+
+// conditionFields gets the sorted names of all parameters naming a field
+func conditionFields(fields url.Values) []string {
+	names := make([]string, 0, len(fields))
+	for fieldName := range fields {
+		if fieldName == "breakdown" || fieldName == "time_start" || fieldName == "time_end" || strings.HasPrefix(fieldName, "filter_field") || fieldName == "topFlows" || isQueryOption(fieldName) {
+			continue
+		}
+
+		names = append(names, fieldName)
 	}
+	sort.Strings(names)
 
-	groupBy := make([]string, 0)
-	groupBy = append(groupBy, "t")
-	if breakdown, ok := fields["breakdown"]; ok {
-		for _, f := range breakdown {
-			//f = resolveVirtualField(f)
-			groupBy = append(groupBy, f)
+	return names
+}
+
+// isQueryOption checks if a parameter controls how the query is built rather than being a field condition
+func isQueryOption(fieldName string) bool {
+	switch fieldName {
+	case "duration_min", "duration_max", "attribution", "bucket", "rows", "columns", "source", "target", "top", "by", "tz", "interval", "limit", "format", "layout", "width", "height", "title", "metric", "bins", "scale", "subnet_v4", "subnet_v6", "humanize", "compare_start", "compare_end", "offset", "prefer_protocol", "normalize", "smooth", "smooth_window", "fill", "downsample", "max_points":
+		return true
+	}
+
+	return false
+}
+
+// getAttribution returns the bucket size in seconds and whether traffic should be attributed
+// proportionally to the time a flow overlaps with each bucket
+func getAttribution(fields url.Values) (uint64, bool, error) {
+	if fields.Get("attribution") != "proportional" {
+		return 0, false, nil
+	}
+
+	bucket := uint64(defaultAttributionBucketSeconds)
+	if b := fields.Get("bucket"); b != "" {
+		var err error
+		bucket, err = strconv.ParseUint(b, 10, 32)
+		if err != nil || bucket == 0 {
+			return 0, false, fmt.Errorf("Invalid bucket size: %q", b)
+		}
+	}
+
+	return bucket, true, nil
+}
+
+// bucketShare returns an expression for the share of a flow that falls into the time slot t
+func bucketShare(bucket uint64) string {
+	overlap := fmt.Sprintf("greatest(least(toUnixTimestamp64Milli(flow_end), (toUnixTimestamp(t) + %d) * 1000) - greatest(toUnixTimestamp64Milli(flow_start), toUnixTimestamp(t) * 1000), 0)", bucket)
+	return fmt.Sprintf("if(duration_ms = 0, toUInt8(toStartOfInterval(toDateTime(flow_start), INTERVAL %d second) = t), %s / duration_ms)", bucket, overlap)
+}
+
+// getDurationConditions returns the conditions for the duration_min and duration_max (milliseconds) filters
+func getDurationConditions(fields url.Values) ([]string, error) {
+	conditions := make([]string, 0)
+	for _, f := range []struct {
+		param string
+		op    string
+	}{
+		{param: "duration_min", op: ">="},
+		{param: "duration_max", op: "<="},
+	} {
+		v := fields.Get(f.param)
+		if v == "" {
+			continue
+		}
+
+		d, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid %s", f.param)
 		}
+
+		conditions = append(conditions, fmt.Sprintf("duration_ms %s %d", f.op, d))
 	}
 
-	q := "SELECT %s FROM %s.flows WHERE %s GROUP BY %s ORDER BY mbps DESC LIMIT 10000"
-	return fmt.Sprintf(q, strings.Join(selectFieldList, ", "), fe.chgw.GetDatabaseName(), strings.Join(conditions, " AND "), strings.Join(groupBy, ", ")), nil
+	return conditions, nil
 }
 
 func formatCondition(statement string, fields url.Values, fieldName string) string {
@@ -427,6 +852,13 @@ func formatConditionSingleValue(statement string, fields url.Values, fieldName s
 	v := fields[fieldName][0]
 	if isIPField(fieldName) {
 		v = formatIPCondition(v)
+	} else if isMACField(fieldName) {
+		var err error
+		v, err = formatMACCondition(v)
+		if err != nil {
+			return ""
+		}
+		statement = fieldName + "_addr"
 	} else if isPrefixField(fieldName) {
 		var err error
 		v, err = formatPrefixCondition(fieldName, v)
@@ -434,6 +866,12 @@ func formatConditionSingleValue(statement string, fields url.Values, fieldName s
 			return ""
 		}
 		return v
+	} else if isSubnetField(fieldName) {
+		v, err := formatSubnetCondition(fieldName, v)
+		if err != nil {
+			return ""
+		}
+		return v
 	} else {
 		v = fmt.Sprintf("'%s'", v)
 	}
@@ -442,7 +880,7 @@ func formatConditionSingleValue(statement string, fields url.Values, fieldName s
 }
 
 func formatConditionMultiValues(statement string, fields url.Values, fieldName string) string {
-	if isPrefixField(fieldName) {
+	if isPrefixField(fieldName) || isSubnetField(fieldName) {
 		return prefixMultiValueCondition(statement, fields, fieldName)
 	}
 
@@ -453,6 +891,16 @@ func formatConditionMultiValues(statement string, fields url.Values, fieldName s
 			continue
 		}
 
+		if isMACField(fieldName) {
+			mac, err := formatMACCondition(v)
+			if err != nil {
+				return ""
+			}
+			statement = fieldName + "_addr"
+			values = append(values, mac)
+			continue
+		}
+
 		values = append(values, fmt.Sprintf("'%s'", v))
 	}
 
@@ -460,10 +908,15 @@ func formatConditionMultiValues(statement string, fields url.Values, fieldName s
 }
 
 func prefixMultiValueCondition(statement string, fields url.Values, fieldName string) string {
+	format := formatPrefixCondition
+	if isSubnetField(fieldName) {
+		format = formatSubnetCondition
+	}
+
 	conditions := make([]string, 0)
 	for _, v := range fields[fieldName] {
 		var err error
-		v, err = formatPrefixCondition(fieldName, v)
+		v, err = format(fieldName, v)
 		if err != nil {
 			return ""
 		}
@@ -474,7 +927,24 @@ func prefixMultiValueCondition(statement string, fields url.Values, fieldName st
 }
 
 func isIPField(fieldName string) bool {
-	return fieldName == "nexthop" || fieldName == "src_ip_addr" || fieldName == "dst_ip_addr" || fieldName == "agent"
+	return fieldName == "nexthop" || fieldName == "src_ip_addr" || fieldName == "dst_ip_addr" || fieldName == "agent" || fieldName == "inner_src_ip_addr" || fieldName == "inner_dst_ip_addr"
+}
+
+func isMACField(fieldName string) bool {
+	return fieldName == "src_mac" || fieldName == "dst_mac"
+}
+
+func formatMACCondition(addr string) (string, error) {
+	hw, err := net.ParseMAC(addr)
+	if err != nil {
+		return "", err
+	}
+
+	if len(hw) != 6 {
+		return "", fmt.Errorf("Invalid MAC address %q", addr)
+	}
+
+	return fmt.Sprintf("MACStringToNum('%s')", hw.String()), nil
 }
 
 func isPrefixField(fieldName string) bool {
@@ -511,12 +981,19 @@ func resolveVirtualField(f string) string {
 		return "concat(IPv6NumToString(dst_ip_pfx_addr), '/', toString(dst_ip_pfx_len))"
 	}
 
+	if f == "src_mac" {
+		return "MACNumToString(src_mac_addr)"
+	}
+
+	if f == "dst_mac" {
+		return "MACNumToString(dst_mac_addr)"
+	}
+
 	return f
 }
 
-func timeFieldToTimestamp(v string) (int64, error) {
-	v += ":00+00:00" // FIXME: Make this configurable
-	t, err := time.Parse(time.RFC3339, v)
+func timeFieldToTimestamp(v string, loc *time.Location) (int64, error) {
+	t, err := time.ParseInLocation("2006-01-02T15:04", v, loc)
 	if err != nil {
 		return 0, errors.Wrapf(err, "Unable to parse %q", v)
 	}
@@ -525,43 +1002,100 @@ func timeFieldToTimestamp(v string) (int64, error) {
 }
 
 // resolveDictIfNecessary maps a fieldname to an dict lookup, if necessary. If not it just returns fieldname.
+// Chained lookups (e.g. int_in__site_id__region) are resolved from left to right.
 func (fe *Frontend) resolveDictIfNecessary(fieldName string) (string, error) {
-	flowsFieldName, relatedFieldsName := parseFieldName(fieldName)
-	if relatedFieldsName == "" {
-		return flowsFieldName, nil
+	flowsFieldName, relatedFieldsNames := parseFieldName(fieldName)
+	expr := fe.fieldExpr(flowsFieldName)
+	prefix := flowsFieldName
+	for _, relatedFieldsName := range relatedFieldsNames {
+		d := fe.getDictForColumn(prefix, relatedFieldsName)
+		if d == nil {
+			return "", fmt.Errorf("Dict for field %s not found", fieldName)
+		}
+
+		keys := make([]dictKey, 0)
+		if len(d.Keys) == 0 {
+			keys = append(keys, dictKey{expr: expr, ip: expr == flowsFieldName && isIPField(flowsFieldName)})
+		} else {
+			for _, k := range d.Keys {
+				keys = append(keys, dictKey{expr: k, ip: isIPField(k)})
+			}
+		}
+
+		attrType := ""
+		if _, exists := d.Defaults[relatedFieldsName]; exists {
+			attrType = fe.dictAttributeType(d.Dict, relatedFieldsName)
+		}
+
+		expr = d.lookup(fe.qualifyDictName(d.Dict), relatedFieldsName, d.keyExpr(keys), attrType)
+		prefix += "__" + relatedFieldsName
 	}
 
-	d := fe.dictCfgs.getDict(flowsFieldName)
-	if d == nil {
-		return "", fmt.Errorf("Dict for field %s not found", fieldName)
+	return expr, nil
+}
+
+// dictAttributeType gets the ClickHouse type of a dict column or an empty string if it is unknown
+func (fe *Frontend) dictAttributeType(dictName string, column string) string {
+	if fe.chgw == nil {
+		return ""
 	}
 
-	params := make([]interface{}, 0)
-	if len(d.Keys) == 0 {
-		params = append(params, flowsFieldName)
-	} else {
-		for _, k := range d.Keys {
-			params = append(params, k)
+	attrs, err := fe.chgw.DescribeDict(dictName)
+	if err != nil {
+		log.WithError(err).Errorf("Unable to get fields of dict %q", dictName)
+		return ""
+	}
+
+	for _, a := range attrs {
+		if a.Name == column {
+			return a.Type
 		}
 	}
 
-	expr := fmt.Sprintf(d.Expr, params...)
+	return ""
+}
 
-	dictName := d.Dict
+func (fe *Frontend) qualifyDictName(dictName string) string {
 	if !strings.Contains(dictName, ".") {
 		dictName = fe.chgw.GetDatabaseName() + "." + dictName
 	}
 
-	return fmt.Sprintf("dictGet('%s', '%s', %s)", dictName, relatedFieldsName, expr), nil
+	return dictName
 }
 
-func parseFieldName(name string) (flowsFieldName, relatedFieldsName string) {
-	parts := strings.Split(name, "__")
-	if len(parts) < 2 {
-		return parts[0], ""
+// getDictForColumn returns the dict bound to field providing column. If only one dict is bound it is returned without
+// checking its columns.
+func (fe *Frontend) getDictForColumn(field string, column string) *Dict {
+	dicts := fe.getDictCfgs().getDicts(field)
+	if len(dicts) <= 1 {
+		if len(dicts) == 0 {
+			return nil
+		}
+
+		return dicts[0]
 	}
 
-	return parts[0], parts[1]
+	for _, d := range dicts {
+		dictFields, err := fe.chgw.GetDictFields(d.Dict)
+		if err != nil {
+			log.WithError(err).Errorf("Unable to get fields of dict %q", d.Dict)
+			continue
+		}
+
+		for _, f := range dictFields {
+			if f == column {
+				return d
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseFieldName splits a field name into the flows field and the chain of dict columns
+func parseFieldName(name string) (flowsFieldName string, relatedFieldsNames []string) {
+	parts := strings.Split(name, "__")
+	return parts[0], parts[1:]
 }
 
 func (fe *Frontend) dissectIndexQuery(values url.Values) map[string][]string {
@@ -577,65 +1111,44 @@ func (fe *Frontend) dissectIndexQuery(values url.Values) map[string][]string {
 	return fields
 }
 
-func (fe *Frontend) getIndexView() (*IndexView, error) {
-	ret := &IndexView{
-		FieldGroups: make([]*FieldGroup, 0),
+// getDerivedFields returns the columns of all dicts bound to a field including chained lookups. Columns provided by
+// more than one dict are only listed once.
+func (fe *Frontend) getDerivedFields(name string, label string, depth int) []*Field {
+	res := make([]*Field, 0)
+	if depth >= maxDictChainDepth {
+		return res
 	}
 
-	for _, field := range fields {
-		fg := &FieldGroup{
-			Name:   field.Name,
-			Label:  field.Label,
-			Fields: make([]*Field, 0),
+	seen := make(map[string]struct{})
+	for _, dictCfg := range fe.getDictCfgs().getDicts(name) {
+		attrs, err := fe.chgw.DescribeDict(dictCfg.Dict)
+		if err != nil {
+			log.Errorf("failed to get dict fields: %v", err)
+			continue
 		}
-		ret.FieldGroups = append(ret.FieldGroups, fg)
-
-		fg.Fields = append(fg.Fields, &Field{
-			Name:  field.Name,
-			Label: field.Label,
-		})
 
-		for _, dictCfg := range fe.dictCfgs {
-			if dictCfg.Field != field.Name {
+		for _, a := range attrs {
+			if _, exists := seen[a.Name]; exists {
 				continue
 			}
+			seen[a.Name] = struct{}{}
 
-			dictFields, err := fe.chgw.GetDictFields(dictCfg.Dict)
-			if err != nil {
-				log.Errorf("failed to get dict fields: %v", err)
-				continue
-			}
-
-			for i := 0; i < len(dictFields); i++ {
-				f := &Field{
-					Name:  fmt.Sprintf("%s__%s", field.Name, dictFields[i]),
-					Label: fmt.Sprintf("%s %s", field.Label, strings.Title(dictFields[i])),
-				}
-				fg.Fields = append(fg.Fields, f)
-
-				ret.BreakDownLen++
+			f := &Field{
+				Name:    fmt.Sprintf("%s__%s", name, a.Name),
+				Label:   fmt.Sprintf("%s %s", label, strings.Title(a.Name)),
+				Type:    a.Type,
+				Numeric: a.IsNumeric(),
 			}
-
+			res = append(res, f)
+			res = append(res, fe.getDerivedFields(f.Name, f.Label, depth+1)...)
 		}
-
-		ret.BreakDownLen += 2
 	}
 
-	return ret, nil
+	return res
 }
 
-func (fe *Frontend) getFieldsDictName(fieldName string) string {
-	for _, d := range fe.dictCfgs {
-		if d.Field == fieldName {
-			return d.Dict
-		}
-	}
-
-	return ""
-}
-
-// GetDictValues gets a dicts columns values
-func (fe *Frontend) GetDictValues(w http.ResponseWriter, r *http.Request) {
+// getDictValues gets a dicts columns values
+func (fe *Frontend) getDictValues(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) != 3 {
 		w.WriteHeader(http.StatusBadRequest)
@@ -648,15 +1161,16 @@ func (fe *Frontend) GetDictValues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dict := fe.getFieldsDictName(fieldName)
-	if dict == "" {
+	dict := fe.getDictForColumn(fieldName, column)
+	if dict == nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	values, err := fe.chgw.GetDictValues(dict, column)
+	values, err := fe.chgw.GetDictValues(dict.Dict, column, scopeFromContext(r.Context()))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		requestLogger(r).WithError(err).Errorf("Unable to get values of dict %s", dict.Dict)
+		httpError(w, r, "Unable to get dict values", http.StatusInternalServerError)
 		return
 	}
 
@@ -680,11 +1194,12 @@ func (fe *Frontend) GetDictValues(w http.ResponseWriter, r *http.Request) {
 	w.Write(j)
 }
 
+// parseDictValueRequest splits field__column into the (possibly derived) field and the dict column
 func parseDictValueRequest(input string) (string, string, error) {
-	parts := strings.Split(input, "__")
-	if len(parts) != 2 {
+	i := strings.LastIndex(input, "__")
+	if i <= 0 || i+2 == len(input) {
 		return "", "", fmt.Errorf("Invalid format")
 	}
 
-	return parts[0], parts[1], nil
+	return input[:i], input[i+2:], nil
 }
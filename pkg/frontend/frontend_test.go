@@ -1,8 +1,12 @@
 package frontend
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
+	"github.com/bio-routing/flowhouse/pkg/models/preferences"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,14 +22,14 @@ func TestFormatPrefixCondition(t *testing.T) {
 			name:      "Test #1",
 			pfx:       "8.8.8.0/24",
 			fieldName: "src_pfx",
-			expected:  "src_pfx_addr = IPv4ToIPv6(IPv4StringToNum('8.8.8.0')) AND src_pfx_len = 24",
+			expected:  "(src_pfx_addr = IPv4ToIPv6(IPv4StringToNum('8.8.8.0')) AND src_pfx_len = 24)",
 			wantFail:  false,
 		},
 		{
 			name:      "Test #2",
 			pfx:       "2001:db8::/48",
 			fieldName: "src_pfx",
-			expected:  "src_pfx_addr = IPv6StringToNum('2001:DB8:0:0:0:0:0:0') AND src_pfx_len = 48",
+			expected:  "(src_pfx_addr = IPv6StringToNum('2001:DB8:0:0:0:0:0:0') AND src_pfx_len = 48)",
 			wantFail:  false,
 		},
 		{
@@ -51,3 +55,352 @@ func TestFormatPrefixCondition(t *testing.T) {
 		assert.Equal(t, test.expected, res, test.name)
 	}
 }
+
+func TestFormatConditionSingleValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		fieldName string
+		value     string
+		expected  string
+	}{
+		{
+			name:      "IP address",
+			fieldName: "src_ip_addr",
+			value:     "192.0.2.1",
+			expected:  "src_ip_addr = IPv4ToIPv6(IPv4StringToNum('192.0.2.1'))",
+		},
+		{
+			name:      "MAC address",
+			fieldName: "src_mac",
+			value:     "80:71:1f:7f:02:94",
+			expected:  "src_mac_addr = MACStringToNum('80:71:1f:7f:02:94')",
+		},
+		{
+			name:      "MAC address in other notation",
+			fieldName: "dst_mac",
+			value:     "8071.1F7F.0294",
+			expected:  "dst_mac_addr = MACStringToNum('80:71:1f:7f:02:94')",
+		},
+		{
+			name:      "Invalid MAC address",
+			fieldName: "src_mac",
+			value:     "80:71:1f:7f:02:94') OR ('1",
+			expected:  "",
+		},
+		{
+			name:      "Plain value",
+			fieldName: "src_vlan",
+			value:     "100",
+			expected:  "src_vlan = '100'",
+		},
+	}
+
+	for _, test := range tests {
+		fields := map[string][]string{
+			test.fieldName: {test.value},
+		}
+
+		assert.Equal(t, test.expected, formatConditionSingleValue(test.fieldName, fields, test.fieldName), test.name)
+	}
+}
+
+func TestGetFieldConditionsInvalidValues(t *testing.T) {
+	fe := &Frontend{}
+	assert.Equal(t, []string{"dst_port = '443'"}, fe.getFieldConditions(url.Values{
+		"dst_port":   {"443"},
+		"src_mac":    {"80:71:1f:7f:02:94' OR '1", "80:71:1f:7f:02:95"},
+		"src_ip_pfx": {"foo"},
+	}))
+}
+
+func TestGetDurationConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   map[string][]string
+		expected []string
+		wantFail bool
+	}{
+		{
+			name:     "No duration filter",
+			fields:   map[string][]string{},
+			expected: []string{},
+		},
+		{
+			name: "Min and max",
+			fields: map[string][]string{
+				"duration_min": {"1000"},
+				"duration_max": {"60000"},
+			},
+			expected: []string{"duration_ms >= 1000", "duration_ms <= 60000"},
+		},
+		{
+			name: "Invalid value",
+			fields: map[string][]string{
+				"duration_min": {"-1"},
+			},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		res, err := getDurationConditions(test.fields)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, res, test.name)
+	}
+}
+
+func TestGetAttribution(t *testing.T) {
+	tests := []struct {
+		name         string
+		fields       map[string][]string
+		bucket       uint64
+		proportional bool
+		wantFail     bool
+	}{
+		{
+			name:   "Default",
+			fields: map[string][]string{},
+		},
+		{
+			name: "Proportional with default bucket",
+			fields: map[string][]string{
+				"attribution": {"proportional"},
+			},
+			bucket:       defaultAttributionBucketSeconds,
+			proportional: true,
+		},
+		{
+			name: "Proportional with custom bucket",
+			fields: map[string][]string{
+				"attribution": {"proportional"},
+				"bucket":      {"300"},
+			},
+			bucket:       300,
+			proportional: true,
+		},
+		{
+			name: "Zero bucket",
+			fields: map[string][]string{
+				"attribution": {"proportional"},
+				"bucket":      {"0"},
+			},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		bucket, proportional, err := getAttribution(test.fields)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.bucket, bucket, test.name)
+		assert.Equal(t, test.proportional, proportional, test.name)
+	}
+}
+
+func TestResolveChainedDicts(t *testing.T) {
+	fe := &Frontend{
+		dictCfgs: Dicts{
+			{Field: "int_in", Dict: "flowhouse.interfaces_dict", Expr: "tuple(IPv6NumToString(%s), %s)", Keys: []string{"agent", "int_in"}},
+			{Field: "int_in__site_id", Dict: "flowhouse.sites", Expr: "tuple(%s)"},
+		},
+	}
+
+	s, err := fe.resolveDictIfNecessary("int_in__site_id")
+	assert.NoError(t, err)
+	assert.Equal(t, "dictGet('flowhouse.interfaces_dict', 'site_id', tuple(IPv6NumToString(agent), int_in))", s)
+
+	s, err = fe.resolveDictIfNecessary("int_in__site_id__region")
+	assert.NoError(t, err)
+	assert.Equal(t, "dictGet('flowhouse.sites', 'region', tuple(dictGet('flowhouse.interfaces_dict', 'site_id', tuple(IPv6NumToString(agent), int_in))))", s)
+
+	_, err = fe.resolveDictIfNecessary("int_out__site_id")
+	assert.Error(t, err)
+
+	assert.Equal(t, "Int.In.Site_id.Region", getReadableLabel("int_in__site_id__region", fields))
+}
+
+func TestResolveDictMisses(t *testing.T) {
+	fe := &Frontend{
+		dictCfgs: Dicts{
+			{Field: "int_in", Dict: "flowhouse.interfaces_dict", Expr: "tuple(IPv6NumToString(%s), %s)", Keys: []string{"agent", "int_in"}, Defaults: map[string]string{"name": "n/a"}},
+			{Field: "src_asn", Dict: "flowhouse.asns", Expr: "tuple(%s)", Unknown: "unknown"},
+		},
+	}
+
+	s, err := fe.resolveDictIfNecessary("int_in__name")
+	assert.NoError(t, err)
+	assert.Equal(t, "dictGetOrDefault('flowhouse.interfaces_dict', 'name', tuple(IPv6NumToString(agent), int_in), 'n/a')", s)
+
+	s, err = fe.resolveDictIfNecessary("int_in__speed")
+	assert.NoError(t, err)
+	assert.Equal(t, "dictGet('flowhouse.interfaces_dict', 'speed', tuple(IPv6NumToString(agent), int_in))", s)
+
+	s, err = fe.resolveDictIfNecessary("src_asn__org")
+	assert.NoError(t, err)
+	assert.Equal(t, "if(dictHas('flowhouse.asns', tuple(src_asn)), toString(dictGet('flowhouse.asns', 'org', tuple(src_asn))), 'unknown')", s)
+
+	d := &Dict{Defaults: map[string]string{"speed": "0"}}
+	assert.Equal(t, "dictGetOrDefault('d', 'speed', tuple(int_in), CAST('0', 'UInt64'))", d.lookup("d", "speed", "tuple(int_in)", "UInt64"))
+}
+
+func TestParseDictValueRequest(t *testing.T) {
+	field, column, err := parseDictValueRequest("int_in__site_id__region")
+	assert.NoError(t, err)
+	assert.Equal(t, "int_in__site_id", field)
+	assert.Equal(t, "region", column)
+
+	for _, input := range []string{"int_in", "__region", "int_in__"} {
+		_, _, err := parseDictValueRequest(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	fe := &Frontend{
+		dictCfgs: Dicts{
+			{Field: "src_asn", Dict: "flowhouse.asns", Expr: "%s"},
+		},
+	}
+
+	_, err := fe.resolveDictIfNecessary("app__owner")
+	assert.Error(t, err)
+
+	fe.Reconfigure(Dicts{
+		{Field: "app", Dict: "flowhouse.apps", Expr: "%s"},
+	}, ComputedFields{
+		{Name: "app", Label: "Application", Expr: "multiIf(dst_port = 443, 'https', 'other')"},
+	})
+
+	s, err := fe.resolveDictIfNecessary("app__owner")
+	assert.NoError(t, err)
+	assert.Equal(t, "dictGet('flowhouse.apps', 'owner', (multiIf(dst_port = 443, 'https', 'other')))", s)
+
+	_, err = fe.resolveDictIfNecessary("src_asn__name")
+	assert.Error(t, err)
+}
+
+func TestDictsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		dicts    Dicts
+		wantFail bool
+	}{
+		{
+			name: "Parent field",
+			dicts: Dicts{
+				{Field: "src_asn", Dict: "asns", Expr: "tuple(%s)"},
+			},
+		},
+		{
+			name: "Keys",
+			dicts: Dicts{
+				{Field: "int_in", Dict: "interfaces", Expr: "tuple(IPv6NumToString(%s), %s)", Keys: []string{"agent", "int_in"}},
+			},
+		},
+		{
+			name: "Placeholders not matching keys",
+			dicts: Dicts{
+				{Field: "int_in", Dict: "interfaces", Expr: "tuple(%s)", Keys: []string{"agent", "int_in"}},
+			},
+			wantFail: true,
+		},
+		{
+			name: "Missing dict",
+			dicts: Dicts{
+				{Field: "src_asn", Expr: "tuple(%s)"},
+			},
+			wantFail: true,
+		},
+		{
+			name: "Quoted default",
+			dicts: Dicts{
+				{Field: "src_asn", Dict: "asns", Expr: "tuple(%s)", Defaults: map[string]string{"name": "it's unknown"}},
+			},
+			wantFail: true,
+		},
+		{
+			name: "Quoted unknown",
+			dicts: Dicts{
+				{Field: "src_asn", Dict: "asns", Expr: "tuple(%s)", Unknown: `\'`},
+			},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.dicts.Validate()
+		assert.Equal(t, test.wantFail, err != nil, test.name)
+	}
+}
+
+func TestGetTimeRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		fields    url.Values
+		wantStart int64
+		wantEnd   int64
+		wantFail  bool
+	}{
+		{
+			name:      "UTC",
+			fields:    url.Values{"time_start": {"2021-03-01T12:00"}, "time_end": {"2021-03-01T13:00"}},
+			wantStart: 1614600000,
+			wantEnd:   1614603600,
+		},
+		{
+			name:      "Timezone",
+			fields:    url.Values{"time_start": {"2021-03-01T13:00"}, "time_end": {"2021-03-01T14:00"}, "tz": {"Europe/Berlin"}},
+			wantStart: 1614600000,
+			wantEnd:   1614603600,
+		},
+		{
+			name:     "Unknown timezone",
+			fields:   url.Values{"time_start": {"2021-03-01T13:00"}, "time_end": {"2021-03-01T14:00"}, "tz": {"Mars/Olympus_Mons"}},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		start, end, err := getTimeRange(test.fields)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.wantStart, start, test.name)
+		assert.Equal(t, test.wantEnd, end, test.name)
+	}
+}
+
+type mockPreferences struct {
+	p *preferences.Preferences
+}
+
+func (m *mockPreferences) Get(r *http.Request) *preferences.Preferences {
+	return m.p
+}
+
+func TestIndexHandlerPreferences(t *testing.T) {
+	fe := New(nil, nil, nil, nil, &mockPreferences{
+		p: &preferences.Preferences{Timezone: "Europe/Berlin", TimeRange: 3600, Breakdowns: []string{"src_asn"}, Theme: preferences.ThemeDark},
+	})
+
+	rec := httptest.NewRecorder()
+	fe.indexHandler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `<body class="theme-dark">`)
+	assert.Contains(t, body, `<input type="hidden" name="tz" value="Europe/Berlin">`)
+	assert.Contains(t, body, `"breakdowns":["src_asn"]`)
+}
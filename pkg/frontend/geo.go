@@ -0,0 +1,81 @@
+package frontend
+
+import "fmt"
+
+const (
+	defaultGeoCountryAttribute   = "country_code"
+	defaultGeoContinentAttribute = "continent_code"
+)
+
+// geoFieldNames are the names of the geo fields, computed fields must not use them
+var geoFieldNames = []string{"src_country", "dst_country", "src_continent", "dst_continent"}
+
+// GeoConfig names the ClickHouse ip_trie dict the geo fields are looked up in. Its attributes are expected to hold ISO
+// 3166 alpha-2 country codes and two letter continent codes.
+type GeoConfig struct {
+	Dict               string `yaml:"dict"`
+	CountryAttribute   string `yaml:"country_attribute"`
+	ContinentAttribute string `yaml:"continent_attribute"`
+}
+
+func (c *GeoConfig) loadDefaults() {
+	if c.CountryAttribute == "" {
+		c.CountryAttribute = defaultGeoCountryAttribute
+	}
+
+	if c.ContinentAttribute == "" {
+		c.ContinentAttribute = defaultGeoContinentAttribute
+	}
+}
+
+// Validate checks that a dict is set
+func (c *GeoConfig) Validate() error {
+	if c.Dict == "" {
+		return fmt.Errorf("No geo dict given")
+	}
+
+	return nil
+}
+
+// fields gets the country and continent of source and destination IPs as computed fields
+func (c *GeoConfig) fields(dict string) ComputedFields {
+	res := make(ComputedFields, 0, len(geoFieldNames))
+	for _, f := range []struct {
+		name       string
+		label      string
+		shortLabel string
+		attribute  string
+		addr       string
+	}{
+		{name: "src_country", label: "Source Country", shortLabel: "Src.Country", attribute: c.CountryAttribute, addr: "src_ip_addr"},
+		{name: "dst_country", label: "Destination Country", shortLabel: "Dst.Country", attribute: c.CountryAttribute, addr: "dst_ip_addr"},
+		{name: "src_continent", label: "Source Continent", shortLabel: "Src.Continent", attribute: c.ContinentAttribute, addr: "src_ip_addr"},
+		{name: "dst_continent", label: "Destination Continent", shortLabel: "Dst.Continent", attribute: c.ContinentAttribute, addr: "dst_ip_addr"},
+	} {
+		res = append(res, &ComputedField{
+			Name:       f.name,
+			Label:      f.label,
+			ShortLabel: f.shortLabel,
+			Expr:       fmt.Sprintf("upper(dictGetString('%s', '%s', tuple(%s)))", dict, f.attribute, f.addr),
+		})
+	}
+
+	return res
+}
+
+// SetGeo adds the country and continent fields looked up in the dict of cfg
+func (fe *Frontend) SetGeo(cfg *GeoConfig) error {
+	err := cfg.Validate()
+	if err != nil {
+		return err
+	}
+	cfg.loadDefaults()
+
+	geoFields := cfg.fields(fe.qualifyDictName(cfg.Dict))
+
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.geoFields = geoFields
+	return nil
+}
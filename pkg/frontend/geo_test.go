@@ -0,0 +1,38 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetGeo(t *testing.T) {
+	fe := &Frontend{
+		computedFields: ComputedFields{
+			{Name: "app", Expr: "multiIf(dst_port = 443, 'https', 'other')"},
+		},
+	}
+	assert.Error(t, fe.SetGeo(&GeoConfig{}))
+
+	assert.NoError(t, fe.SetGeo(&GeoConfig{Dict: "geo.ip_trie", ContinentAttribute: "continent"}))
+
+	s, err := fe.resolveDictIfNecessary("src_country")
+	assert.NoError(t, err)
+	assert.Equal(t, "(upper(dictGetString('geo.ip_trie', 'country_code', tuple(src_ip_addr))))", s)
+
+	s, err = fe.resolveDictIfNecessary("dst_continent")
+	assert.NoError(t, err)
+	assert.Equal(t, "(upper(dictGetString('geo.ip_trie', 'continent', tuple(dst_ip_addr))))", s)
+
+	assert.Equal(t, []string{"(upper(dictGetString('geo.ip_trie', 'country_code', tuple(dst_ip_addr)))) IN ('DE', 'NL')"},
+		fe.getFieldConditions(url.Values{"dst_country": {"DE", "NL"}}))
+
+	catalog := fe.catalog()
+	assert.Equal(t, "Src.Country", getReadableLabel("src_country", catalog))
+	assert.Equal(t, "app", catalog[len(catalog)-1].Name)
+}
+
+func TestComputedFieldsGeoConflict(t *testing.T) {
+	assert.Error(t, ComputedFields{{Name: "src_country", Expr: "'DE'"}}.Validate())
+}
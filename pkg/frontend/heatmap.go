@@ -0,0 +1,271 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	heatmapScaleLog    = "log"
+	heatmapScaleLinear = "linear"
+
+	defaultHeatmapBucketSeconds = 300
+	defaultHeatmapBins          = 20
+	maxHeatmapBins              = 1000
+)
+
+// heatmapMetric is a value the distribution of which a heatmap shows
+type heatmapMetric struct {
+	unit string
+
+	// expr is the value per flow, or per key and time slot if key is set
+	expr string
+	key  string
+}
+
+// heatmapMetrics are the metrics of heatmaps. Values per key are summed up over a time slot, %[1]d is its length in
+// seconds.
+var heatmapMetrics = map[string]heatmapMetric{
+	"flow_size":        {unit: "bytes", expr: "size * samplerate"},
+	"packet_size":      {unit: "bytes", expr: "intDiv(size, greatest(packets, 1))"},
+	"duration":         {unit: "ms", expr: "duration_ms"},
+	"source_rate":      {unit: "bps", expr: "sum(size * samplerate) * 8 / %[1]d", key: "src_ip_addr"},
+	"destination_rate": {unit: "bps", expr: "sum(size * samplerate) * 8 / %[1]d", key: "dst_ip_addr"},
+}
+
+// Heatmap is the distribution of a metric per time slot. Counts has a row per timestamp holding the number of flows
+// (or sources and destinations for rates) per bin.
+type Heatmap struct {
+	Metric     string        `json:"metric"`
+	Unit       string        `json:"unit"`
+	Scale      string        `json:"scale"`
+	Timestamps []time.Time   `json:"timestamps"`
+	Bins       []*HeatmapBin `json:"bins"`
+	Counts     [][]uint64    `json:"counts"`
+}
+
+// HeatmapBin is the range of values of a bin. The lower bound is inclusive, the upper exclusive apart from the largest
+// value which falls into the last linear bin.
+type HeatmapBin struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+type heatmapRow struct {
+	ts    time.Time
+	bin   uint32
+	count uint64
+}
+
+// heatmapHandler serves the distribution of a metric (metric: flow_size, packet_size, duration, source_rate or
+// destination_rate) per time slot of bucket seconds as JSON. Bins double in size (scale=log, default) or split the
+// values up to the largest into the given number of bins of the same size (scale=linear).
+func (fe *Frontend) heatmapHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := fe.processHeatmapQuery(r.Context(), r.URL.Query())
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Unable to process heatmap query")
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func (fe *Frontend) processHeatmapQuery(ctx context.Context, fields url.Values) (*Heatmap, error) {
+	name := fields.Get("metric")
+	metric, ok := heatmapMetrics[name]
+	if !ok {
+		return nil, fmt.Errorf("Invalid metric %q", name)
+	}
+
+	scale := fields.Get("scale")
+	if scale == "" {
+		scale = heatmapScaleLog
+	}
+
+	if scale != heatmapScaleLog && scale != heatmapScaleLinear {
+		return nil, fmt.Errorf("Invalid scale %q", scale)
+	}
+
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if end <= start {
+		return nil, fmt.Errorf("Empty time range")
+	}
+
+	bucket := uint64(defaultHeatmapBucketSeconds)
+	if b := fields.Get("bucket"); b != "" {
+		bucket, err = strconv.ParseUint(b, 10, 32)
+		if err != nil || bucket == 0 {
+			return nil, fmt.Errorf("Invalid bucket size: %q", b)
+		}
+	}
+
+	bins := defaultHeatmapBins
+	if b := fields.Get("bins"); b != "" {
+		bins, err = strconv.Atoi(b)
+		if err != nil || bins <= 0 || bins > maxHeatmapBins {
+			return nil, fmt.Errorf("Invalid bins %q", b)
+		}
+	}
+
+	values := heatmapValuesQuery(fe.chgw.GetDatabaseName(), metric, start, end, bucket, fe.getFieldConditions(fields))
+	width := 0.0
+	if scale == heatmapScaleLinear {
+		width, err = fe.heatmapBinWidth(ctx, values, bins)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := fe.chgw.QueryContext(ctx, heatmapQuery(values, scale, width, bins))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	data := make([]heatmapRow, 0)
+	for rows.Next() {
+		var r heatmapRow
+		err := rows.Scan(&r.ts, &r.bin, &r.count)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		data = append(data, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+
+	if scale == heatmapScaleLog {
+		bins = 0
+	}
+
+	res := buildHeatmap(data, scale, width, bins)
+	res.Metric = name
+	res.Unit = metric.unit
+	return res, nil
+}
+
+// heatmapValuesQuery selects the time slot t and the value v of each flow, or of each key per time slot
+func heatmapValuesQuery(database string, metric heatmapMetric, start int64, end int64, bucket uint64, conditions []string) string {
+	conditions = append([]string{fmt.Sprintf("timestamp BETWEEN toDateTime(%d) AND toDateTime(%d)", start, end)}, conditions...)
+	slot := fmt.Sprintf("toStartOfInterval(timestamp, INTERVAL %d second)", bucket)
+
+	if metric.key == "" {
+		return fmt.Sprintf("SELECT %s AS t, %s AS v FROM %s.flows WHERE %s", slot, metric.expr, database, strings.Join(conditions, " AND "))
+	}
+
+	return fmt.Sprintf("SELECT %s AS t, %s AS v FROM %s.flows WHERE %s GROUP BY t, %s",
+		slot, fmt.Sprintf(metric.expr, bucket), database, strings.Join(conditions, " AND "), metric.key)
+}
+
+// heatmapQuery counts the values per time slot and bin
+func heatmapQuery(values string, scale string, width float64, bins int) string {
+	bin := "toUInt32(floor(log2(greatest(v, 1))))"
+	if scale == heatmapScaleLinear {
+		bin = fmt.Sprintf("toUInt32(least(floor(v / %g), %d))", width, bins-1)
+	}
+
+	return fmt.Sprintf("SELECT t, %s AS bin, count() AS n FROM (%s) GROUP BY t, bin ORDER BY t, bin", bin, values)
+}
+
+// heatmapBinWidth gets the width of bins of the same size covering all values
+func (fe *Frontend) heatmapBinWidth(ctx context.Context, values string, bins int) (float64, error) {
+	rows, err := fe.chgw.QueryContext(ctx, fmt.Sprintf("SELECT toFloat64(max(v)) FROM (%s)", values))
+	if err != nil {
+		return 0, errors.Wrap(err, "Unable to get largest value")
+	}
+	defer rows.Close()
+
+	max := 0.0
+	if rows.Next() {
+		err := rows.Scan(&max)
+		if err != nil {
+			return 0, errors.Wrap(err, "Scan failed")
+		}
+	}
+
+	return binWidth(max, bins), nil
+}
+
+// binWidth gets the width of bins covering 0 to max
+func binWidth(max float64, bins int) float64 {
+	if max <= 0 {
+		return 1
+	}
+
+	return max / float64(bins)
+}
+
+// buildHeatmap builds the grid of counts of rows ordered by time slot. Log scaled bins are added up to the largest bin
+// with values, bins is the number of linear bins.
+func buildHeatmap(data []heatmapRow, scale string, width float64, bins int) *Heatmap {
+	res := &Heatmap{
+		Scale:      scale,
+		Timestamps: make([]time.Time, 0),
+		Bins:       make([]*HeatmapBin, 0),
+		Counts:     make([][]uint64, 0),
+	}
+
+	if scale == heatmapScaleLog {
+		for _, r := range data {
+			if int(r.bin)+1 > bins {
+				bins = int(r.bin) + 1
+			}
+		}
+	}
+
+	for i := 0; i < bins; i++ {
+		b := &HeatmapBin{
+			Lower: float64(i) * width,
+			Upper: float64(i+1) * width,
+		}
+
+		if scale == heatmapScaleLog {
+			b.Lower, b.Upper = math.Exp2(float64(i)), math.Exp2(float64(i+1))
+			if i == 0 {
+				b.Lower = 0
+			}
+		}
+
+		res.Bins = append(res.Bins, b)
+	}
+
+	for _, r := range data {
+		n := len(res.Timestamps)
+		if n == 0 || !res.Timestamps[n-1].Equal(r.ts) {
+			res.Timestamps = append(res.Timestamps, r.ts)
+			res.Counts = append(res.Counts, make([]uint64, bins))
+			n++
+		}
+
+		if int(r.bin) < bins {
+			res.Counts[n-1][r.bin] += r.count
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,103 @@
+package frontend
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeatmapQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		metric     string
+		scale      string
+		width      float64
+		bins       int
+		conditions []string
+		expected   string
+	}{
+		{
+			name:       "Flow sizes",
+			metric:     "flow_size",
+			scale:      heatmapScaleLog,
+			conditions: []string{"ip_protocol = '6'"},
+			expected: "SELECT t, toUInt32(floor(log2(greatest(v, 1)))) AS bin, count() AS n FROM (" +
+				"SELECT toStartOfInterval(timestamp, INTERVAL 60 second) AS t, size * samplerate AS v FROM db.flows WHERE timestamp BETWEEN toDateTime(100) AND toDateTime(400) AND ip_protocol = '6'" +
+				") GROUP BY t, bin ORDER BY t, bin",
+		},
+		{
+			name:   "Source rates",
+			metric: "source_rate",
+			scale:  heatmapScaleLinear,
+			width:  2.5,
+			bins:   10,
+			expected: "SELECT t, toUInt32(least(floor(v / 2.5), 9)) AS bin, count() AS n FROM (" +
+				"SELECT toStartOfInterval(timestamp, INTERVAL 60 second) AS t, sum(size * samplerate) * 8 / 60 AS v FROM db.flows WHERE timestamp BETWEEN toDateTime(100) AND toDateTime(400) GROUP BY t, src_ip_addr" +
+				") GROUP BY t, bin ORDER BY t, bin",
+		},
+	}
+
+	for _, test := range tests {
+		values := heatmapValuesQuery("db", heatmapMetrics[test.metric], 100, 400, 60, test.conditions)
+		assert.Equal(t, test.expected, heatmapQuery(values, test.scale, test.width, test.bins), test.name)
+	}
+}
+
+func TestBuildHeatmap(t *testing.T) {
+	t1 := time.Unix(1700000000, 0).UTC()
+	t2 := time.Unix(1700000060, 0).UTC()
+	data := []heatmapRow{
+		{ts: t1, bin: 0, count: 3},
+		{ts: t1, bin: 2, count: 1},
+		{ts: t2, bin: 1, count: 5},
+	}
+
+	res := buildHeatmap(data, heatmapScaleLog, 0, 0)
+	assert.Equal(t, []time.Time{t1, t2}, res.Timestamps)
+	assert.Equal(t, []*HeatmapBin{
+		{Lower: 0, Upper: 2},
+		{Lower: 2, Upper: 4},
+		{Lower: 4, Upper: 8},
+	}, res.Bins)
+	assert.Equal(t, [][]uint64{{3, 0, 1}, {0, 5, 0}}, res.Counts)
+
+	res = buildHeatmap(data, heatmapScaleLinear, 10, 4)
+	assert.Equal(t, []*HeatmapBin{
+		{Lower: 0, Upper: 10},
+		{Lower: 10, Upper: 20},
+		{Lower: 20, Upper: 30},
+		{Lower: 30, Upper: 40},
+	}, res.Bins)
+	assert.Equal(t, [][]uint64{{3, 0, 1, 0}, {0, 5, 0, 0}}, res.Counts)
+
+	res = buildHeatmap(nil, heatmapScaleLog, 0, 0)
+	assert.Empty(t, res.Bins)
+	assert.Empty(t, res.Counts)
+}
+
+func TestBinWidth(t *testing.T) {
+	assert.Equal(t, 5.0, binWidth(100, 20))
+	assert.Equal(t, 1.0, binWidth(0, 20))
+}
+
+func TestProcessHeatmapQueryValidation(t *testing.T) {
+	fe := &Frontend{}
+	for _, query := range []string{
+		"metric=size&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00",
+		"metric=flow_size&scale=sqrt&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00",
+		"metric=flow_size&bins=0&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00",
+		"metric=flow_size&bucket=0&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00",
+		"metric=flow_size&time_start=2024-01-01T01:00&time_end=2024-01-01T00:00",
+	} {
+		fields, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatalf("Invalid query %q: %v", query, err)
+		}
+
+		_, err = fe.processHeatmapQuery(context.Background(), fields)
+		assert.Error(t, err, query)
+	}
+}
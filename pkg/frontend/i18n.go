@@ -0,0 +1,116 @@
+package frontend
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bio-routing/flowhouse/pkg/models/preferences"
+)
+
+// defaultLanguage is the language of the field labels and templates
+const defaultLanguage = "en"
+
+// I18nConfig holds the translations of the UI. Each locale maps English field labels and template strings to their
+// translation, strings without translation are shown in English.
+type I18nConfig struct {
+	// DefaultLanguage is used if neither the preferences nor the browser ask for a language there is a locale of
+	DefaultLanguage string            `yaml:"default_language"`
+	Locales         map[string]Locale `yaml:"locales"`
+}
+
+// Locale maps English strings to their translation
+type Locale map[string]string
+
+// Validate checks that there is a locale of the default language
+func (c *I18nConfig) Validate() error {
+	if c.DefaultLanguage == "" || c.DefaultLanguage == defaultLanguage {
+		return nil
+	}
+
+	if _, exists := c.Locales[c.DefaultLanguage]; !exists {
+		return fmt.Errorf("No locale of default language %q", c.DefaultLanguage)
+	}
+
+	return nil
+}
+
+func (l Locale) translate(s string) string {
+	if t, exists := l[s]; exists && t != "" {
+		return t
+	}
+
+	return s
+}
+
+// SetI18n sets the translations of the UI
+func (fe *Frontend) SetI18n(cfg *I18nConfig) error {
+	err := cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	locales := make(map[string]Locale, len(cfg.Locales))
+	for lang, l := range cfg.Locales {
+		locales[strings.ToLower(lang)] = l
+	}
+
+	fe.i18n = &I18nConfig{
+		DefaultLanguage: strings.ToLower(cfg.DefaultLanguage),
+		Locales:         locales,
+	}
+	return nil
+}
+
+// language selects the language of a request: the one of the preferences, else the first of the Accept-Language
+// header there is a locale of, else the default language. Tags match their primary language too (de-CH matches de).
+func (fe *Frontend) language(r *http.Request, p *preferences.Preferences) (string, Locale) {
+	if fe.i18n == nil {
+		return defaultLanguage, nil
+	}
+
+	candidates := make([]string, 0)
+	if p != nil && p.Language != "" {
+		candidates = append(candidates, p.Language)
+	}
+
+	for _, tag := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag = strings.TrimSpace(strings.SplitN(tag, ";", 2)[0])
+		if tag != "" && tag != "*" {
+			candidates = append(candidates, tag)
+		}
+	}
+
+	for _, c := range candidates {
+		c = strings.ToLower(c)
+		for _, lang := range []string{c, strings.SplitN(c, "-", 2)[0]} {
+			if lang == defaultLanguage {
+				return lang, nil
+			}
+
+			if l, exists := fe.i18n.Locales[lang]; exists {
+				return lang, l
+			}
+		}
+	}
+
+	if fe.i18n.DefaultLanguage == "" {
+		return defaultLanguage, nil
+	}
+
+	return fe.i18n.DefaultLanguage, fe.i18n.Locales[fe.i18n.DefaultLanguage]
+}
+
+// translateIndexView translates the labels of the fields and field groups of v
+func translateIndexView(v *IndexView, l Locale) {
+	if l == nil {
+		return
+	}
+
+	for _, fg := range v.FieldGroups {
+		fg.Label = l.translate(fg.Label)
+		for _, f := range fg.Fields {
+			f.Label = l.translate(f.Label)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/preferences"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguage(t *testing.T) {
+	fe := &Frontend{}
+	assert.NoError(t, fe.SetI18n(&I18nConfig{
+		DefaultLanguage: "fr",
+		Locales: map[string]Locale{
+			"de":    {"Start": "Beginn"},
+			"fr":    {"Start": "Début"},
+			"pt-BR": {"Start": "Início"},
+		},
+	}))
+
+	tests := []struct {
+		name           string
+		language       string
+		acceptLanguage string
+		expected       string
+	}{
+		{
+			name:     "Preferences",
+			language: "de",
+			expected: "de",
+		},
+		{
+			name:           "Preferences before browser",
+			language:       "de",
+			acceptLanguage: "fr",
+			expected:       "de",
+		},
+		{
+			name:           "Browser",
+			acceptLanguage: "nl, de-CH;q=0.9, en;q=0.8",
+			expected:       "de",
+		},
+		{
+			name:           "Region",
+			acceptLanguage: "pt-BR",
+			expected:       "pt-br",
+		},
+		{
+			name:           "English",
+			acceptLanguage: "en-US, de;q=0.5",
+			expected:       "en",
+		},
+		{
+			name:           "Default",
+			language:       "nl",
+			acceptLanguage: "es",
+			expected:       "fr",
+		},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.acceptLanguage != "" {
+			r.Header.Set("Accept-Language", test.acceptLanguage)
+		}
+
+		lang, _ := fe.language(r, &preferences.Preferences{Language: test.language})
+		assert.Equal(t, test.expected, lang, test.name)
+	}
+
+	lang, l := (&Frontend{}).language(httptest.NewRequest(http.MethodGet, "/", nil), &preferences.Preferences{Language: "de"})
+	assert.Equal(t, "en", lang)
+	assert.Equal(t, "Start", l.translate("Start"))
+}
+
+func TestI18nConfigValidate(t *testing.T) {
+	assert.NoError(t, (&I18nConfig{}).Validate())
+	assert.NoError(t, (&I18nConfig{DefaultLanguage: "de", Locales: map[string]Locale{"de": {}}}).Validate())
+	assert.Error(t, (&I18nConfig{DefaultLanguage: "de"}).Validate())
+}
+
+func TestIndexHandlerI18n(t *testing.T) {
+	fe := New(nil, nil, nil, nil, &mockPreferences{
+		p: &preferences.Preferences{Breakdowns: []string{}, Theme: preferences.ThemeLight, Language: "de"},
+	})
+	assert.NoError(t, fe.SetI18n(&I18nConfig{
+		Locales: map[string]Locale{
+			"de": {"Start": "Beginn", "Run Query": "Abfragen", "Source IP": "Quell-IP"},
+		},
+	}))
+
+	rec := httptest.NewRecorder()
+	fe.indexHandler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `<html lang="de">`)
+	assert.Contains(t, body, `<label for="time_start">Beginn</label>`)
+	assert.Contains(t, body, `<label for="time_end">End</label>`)
+	assert.Contains(t, body, `<input type="submit" value="Abfragen" id="submit">`)
+	assert.Contains(t, body, `<option value="src_ip_addr">Quell-IP</option>`)
+}
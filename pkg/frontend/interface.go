@@ -0,0 +1,125 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	interfaceIn   = "in"
+	interfaceOut  = "out"
+	interfaceBoth = "both"
+)
+
+// InterfaceDrillDown is the top talkers of the traffic of an interface of an agent
+type InterfaceDrillDown struct {
+	Agent     string `json:"agent"`
+	Interface string `json:"interface"`
+	Direction string `json:"direction"`
+	*TopTalkers
+}
+
+// interfaceHandler serves the top hosts, ASNs, ports and protocols of the traffic of an interface as JSON. It takes
+// the agent, the interface as stored in the flows (its name if it was resolved, the ifIndex otherwise), the direction
+// (in for traffic received on the interface, out for traffic sent through it or both, the default), the time range
+// and top like /top_talkers. Other field parameters filter the traffic further.
+func (fe *Frontend) interfaceHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := fe.processInterfaceQuery(r.Context(), r.URL.Query())
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Unable to process interface query")
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func (fe *Frontend) processInterfaceQuery(ctx context.Context, fields url.Values) (*InterfaceDrillDown, error) {
+	agent, err := netip.ParseAddr(fields.Get("agent"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid agent %q", fields.Get("agent"))
+	}
+
+	ifName := fields.Get("interface")
+	if ifName == "" {
+		return nil, fmt.Errorf("No interface given")
+	}
+
+	direction := fields.Get("direction")
+	if direction == "" {
+		direction = interfaceBoth
+	}
+
+	cond, err := interfaceCondition(ifName, direction)
+	if err != nil {
+		return nil, err
+	}
+
+	top := defaultTopTalkersTop
+	if t := fields.Get("top"); t != "" {
+		top, err = strconv.Atoi(t)
+		if err != nil || top <= 0 || top > maxTopTalkersTop {
+			return nil, fmt.Errorf("Invalid top %q", t)
+		}
+	}
+
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if end <= start {
+		return nil, fmt.Errorf("Empty time range")
+	}
+
+	agent = agent.Unmap()
+	conditions := []string{"agent = " + formatIPCondition(agent.String()), cond}
+
+	other := make(url.Values, len(fields))
+	for k, v := range fields {
+		if k != "agent" && k != "interface" && k != "direction" {
+			other[k] = v
+		}
+	}
+	conditions = append(conditions, fe.getFieldConditions(other)...)
+
+	talkers, err := fe.topTalkers(ctx, start, end, conditions, top)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InterfaceDrillDown{
+		Agent:      agent.String(),
+		Interface:  ifName,
+		Direction:  direction,
+		TopTalkers: talkers,
+	}, nil
+}
+
+// interfaceCondition matches the flows of an interface in direction
+func interfaceCondition(ifName string, direction string) (string, error) {
+	v := "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(ifName) + "'"
+	switch direction {
+	case interfaceIn:
+		return "int_in = " + v, nil
+	case interfaceOut:
+		return "int_out = " + v, nil
+	case interfaceBoth:
+		return fmt.Sprintf("(int_in = %s OR int_out = %s)", v, v), nil
+	}
+
+	return "", fmt.Errorf("Invalid direction %q, expected one of %s", direction, strings.Join([]string{interfaceIn, interfaceOut, interfaceBoth}, ", "))
+}
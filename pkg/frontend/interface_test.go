@@ -0,0 +1,74 @@
+package frontend
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterfaceCondition(t *testing.T) {
+	tests := []struct {
+		name      string
+		ifName    string
+		direction string
+		expected  string
+		wantFail  bool
+	}{
+		{
+			name:      "In",
+			ifName:    "et-0/0/0",
+			direction: interfaceIn,
+			expected:  "int_in = 'et-0/0/0'",
+		},
+		{
+			name:      "Out",
+			ifName:    "17",
+			direction: interfaceOut,
+			expected:  "int_out = '17'",
+		},
+		{
+			name:      "Both escaped",
+			ifName:    "uplink 'a'",
+			direction: interfaceBoth,
+			expected:  `(int_in = 'uplink \'a\'' OR int_out = 'uplink \'a\'')`,
+		},
+		{
+			name:      "Invalid direction",
+			ifName:    "17",
+			direction: "sideways",
+			wantFail:  true,
+		},
+	}
+
+	for _, test := range tests {
+		c, err := interfaceCondition(test.ifName, test.direction)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, c, test.name)
+	}
+}
+
+func TestProcessInterfaceQueryValidation(t *testing.T) {
+	fe := &Frontend{}
+	for _, query := range []string{
+		"interface=17&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00",
+		"agent=192.0.2.1&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00",
+		"agent=192.0.2.1&interface=17&direction=up&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00",
+		"agent=192.0.2.1&interface=17&top=0&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00",
+		"agent=192.0.2.1&interface=17&time_start=2024-01-01T01:00&time_end=2024-01-01T01:00",
+	} {
+		fields, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatalf("Invalid query %q: %v", query, err)
+		}
+
+		_, err = fe.processInterfaceQuery(context.Background(), fields)
+		assert.Error(t, err, query)
+	}
+}
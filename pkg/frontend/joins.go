@@ -0,0 +1,141 @@
+package frontend
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	joinColumnRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	joinTableRegex  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+)
+
+// Join binds the columns of a small table to a field of the flows table as an alternative to a dict. field__column
+// is the column of the row whose key equals the field, e.g. src_asn__customer. The table is joined to queries
+// selecting or filtering by its columns.
+type Join struct {
+	Field   string   `yaml:"field"`
+	Table   string   `yaml:"table"`
+	Key     string   `yaml:"key"`
+	Columns []string `yaml:"columns"`
+}
+
+// Joins is a slice of joins
+type Joins []*Join
+
+// Validate checks that every join has a field of the flows table, a table, a key and columns, and that no column is
+// bound to a field twice
+func (j Joins) Validate() error {
+	known := make(map[string]struct{})
+	for _, f := range fields {
+		known[f.Name] = struct{}{}
+	}
+
+	bound := make(map[string]struct{})
+	for _, x := range j {
+		if _, exists := known[x.Field]; !exists {
+			return fmt.Errorf("Join of %q for field %q: unknown field", x.Table, x.Field)
+		}
+
+		if !joinTableRegex.MatchString(x.Table) {
+			return fmt.Errorf("Join of %q for field %q: invalid table", x.Table, x.Field)
+		}
+
+		if !joinColumnRegex.MatchString(x.Key) {
+			return fmt.Errorf("Join of %q for field %q: invalid key %q", x.Table, x.Field, x.Key)
+		}
+
+		if len(x.Columns) == 0 {
+			return fmt.Errorf("Join of %q for field %q: no columns", x.Table, x.Field)
+		}
+
+		for _, c := range x.Columns {
+			if !joinColumnRegex.MatchString(c) {
+				return fmt.Errorf("Join of %q for field %q: invalid column %q", x.Table, x.Field, c)
+			}
+
+			name := x.Field + "__" + c
+			if _, exists := bound[name]; exists {
+				return fmt.Errorf("Join of %q for field %q: column %q bound twice", x.Table, x.Field, c)
+			}
+			bound[name] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+// get gets the join providing fieldName (field__column) and the column
+func (j Joins) get(fieldName string) (*Join, string) {
+	flowsFieldName, related := parseFieldName(fieldName)
+	if len(related) != 1 {
+		return nil, ""
+	}
+
+	for _, x := range j {
+		if x.Field != flowsFieldName {
+			continue
+		}
+
+		for _, c := range x.Columns {
+			if c == related[0] {
+				return x, c
+			}
+		}
+	}
+
+	return nil, ""
+}
+
+// SetJoins sets the tables joined to provide the columns bound to fields
+func (fe *Frontend) SetJoins(j Joins) error {
+	err := j.Validate()
+	if err != nil {
+		return err
+	}
+
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.joins = j
+	return nil
+}
+
+func (fe *Frontend) getJoins() Joins {
+	fe.mu.RLock()
+	defer fe.mu.RUnlock()
+
+	return fe.joins
+}
+
+// resolveField gets the expression of fieldName. Columns of joined tables are added to p as join, other fields are
+// resolved to their dict lookups. Without a plan joined columns can not be resolved.
+func (fe *Frontend) resolveField(p *queryPlan, fieldName string) (string, error) {
+	if p != nil {
+		if j, column := fe.getJoins().get(fieldName); j != nil {
+			return p.join(j, column), nil
+		}
+	}
+
+	return fe.resolveDictIfNecessary(fieldName)
+}
+
+// getJoinedFields gets the joined columns bound to a field
+func (fe *Frontend) getJoinedFields(name string, label string) []*Field {
+	res := make([]*Field, 0)
+	for _, j := range fe.getJoins() {
+		if j.Field != name {
+			continue
+		}
+
+		for _, c := range j.Columns {
+			res = append(res, &Field{
+				Name:  fmt.Sprintf("%s__%s", name, c),
+				Label: fmt.Sprintf("%s %s", label, strings.Title(c)),
+			})
+		}
+	}
+
+	return res
+}
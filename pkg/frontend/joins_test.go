@@ -0,0 +1,104 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		join     *Join
+		wantFail bool
+	}{
+		{
+			name: "Valid",
+			join: &Join{Field: "src_ip_pfx", Table: "prefixes", Key: "prefix", Columns: []string{"customer", "region"}},
+		},
+		{
+			name: "Qualified table",
+			join: &Join{Field: "src_asn", Table: "annotations.asns", Key: "asn", Columns: []string{"customer"}},
+		},
+		{
+			name:     "Unknown field",
+			join:     &Join{Field: "router", Table: "prefixes", Key: "prefix", Columns: []string{"customer"}},
+			wantFail: true,
+		},
+		{
+			name:     "Invalid table",
+			join:     &Join{Field: "src_ip_pfx", Table: "prefixes; DROP TABLE flows", Key: "prefix", Columns: []string{"customer"}},
+			wantFail: true,
+		},
+		{
+			name:     "Invalid key",
+			join:     &Join{Field: "src_ip_pfx", Table: "prefixes", Key: "", Columns: []string{"customer"}},
+			wantFail: true,
+		},
+		{
+			name:     "No columns",
+			join:     &Join{Field: "src_ip_pfx", Table: "prefixes", Key: "prefix"},
+			wantFail: true,
+		},
+		{
+			name:     "Column bound twice",
+			join:     &Join{Field: "src_ip_pfx", Table: "prefixes", Key: "prefix", Columns: []string{"customer", "customer"}},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		err := Joins{test.join}.Validate()
+		assert.Equal(t, test.wantFail, err != nil, test.name)
+	}
+}
+
+func TestPlanQueryJoins(t *testing.T) {
+	fe := &Frontend{
+		joins: Joins{
+			{Field: "src_ip_pfx", Table: "prefixes", Key: "prefix", Columns: []string{"customer", "region"}},
+			{Field: "dst_asn", Table: "annotations.asns", Key: "asn", Columns: []string{"customer"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{
+			name:     "Breakdown by joined column",
+			query:    "breakdown=src_ip_pfx__customer&breakdown=src_ip_pfx__region&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00",
+			expected: "SELECT timestamp as t, j0_customer as src_ip_pfx__customer, j0_region as src_ip_pfx__region, sum(size * samplerate) * 8 / 10 / 1000000 AS mbps FROM db.flows LEFT JOIN (SELECT prefix AS j0_prefix, customer AS j0_customer, region AS j0_region FROM db.prefixes) AS j0 ON concat(IPv6NumToString(src_ip_pfx_addr), '/', toString(src_ip_pfx_len)) = j0_prefix WHERE t BETWEEN toDateTime(1704067200) AND toDateTime(1704070800) GROUP BY t, src_ip_pfx__customer, src_ip_pfx__region ORDER BY mbps DESC LIMIT 10000",
+		},
+		{
+			name:     "Filter on joined column",
+			query:    "breakdown=src_asn&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&dst_asn__customer=acme",
+			expected: "SELECT timestamp as t, src_asn as src_asn, sum(size * samplerate) * 8 / 10 / 1000000 AS mbps FROM db.flows LEFT JOIN (SELECT asn AS j0_asn, customer AS j0_customer FROM annotations.asns) AS j0 ON dst_asn = j0_asn WHERE t BETWEEN toDateTime(1704067200) AND toDateTime(1704070800) AND j0_customer = 'acme' GROUP BY t, src_asn ORDER BY mbps DESC LIMIT 10000",
+		},
+	}
+
+	for _, test := range tests {
+		fields, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatalf("Invalid query for test %q: %v", test.name, err)
+		}
+
+		p, err := fe.planQuery(fields)
+		if err != nil {
+			t.Fatalf("Unexpected error for test %q: %v", test.name, err)
+		}
+
+		assert.Equal(t, test.expected, p.sql("db", "ORDER BY mbps DESC LIMIT 10000"), test.name)
+	}
+}
+
+func TestGetJoinedFields(t *testing.T) {
+	fe := &Frontend{
+		joins: Joins{{Field: "src_asn", Table: "asns", Key: "asn", Columns: []string{"customer"}}},
+	}
+
+	assert.Equal(t, []*Field{{Name: "src_asn__customer", Label: "Source ASN Customer"}}, fe.getJoinedFields("src_asn", "Source ASN"))
+	assert.Empty(t, fe.getJoinedFields("dst_asn", "Destination ASN"))
+}
@@ -0,0 +1,169 @@
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultQueueTimeout = 10
+	defaultRetryAfter   = 5
+)
+
+var (
+	errQueueFull    = fmt.Errorf("Too many queries waiting")
+	errQueueTimeout = fmt.Errorf("Timed out waiting for a query slot")
+
+	queriesRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "flowhouse",
+		Subsystem: "frontend",
+		Name:      "queries_running",
+		Help:      "Number of requests running ClickHouse queries",
+	})
+
+	queriesQueued = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "flowhouse",
+		Subsystem: "frontend",
+		Name:      "queries_queued",
+		Help:      "Number of requests waiting for a query slot",
+	})
+
+	queriesRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "frontend",
+		Name:      "queries_rejected_total",
+		Help:      "Number of requests rejected as too many queries were running",
+	})
+)
+
+// QueryLimitConfig limits how many requests query ClickHouse at the same time
+type QueryLimitConfig struct {
+	MaxConcurrent int `yaml:"max_concurrent"`
+
+	// MaxQueued is the number of requests waiting for a slot, further requests are rejected right away
+	MaxQueued int `yaml:"max_queued"`
+
+	// QueueTimeout is how long requests wait for a slot in seconds (default 10)
+	QueueTimeout int `yaml:"queue_timeout"`
+
+	// RetryAfter is sent to rejected clients in the Retry-After header in seconds (default 5)
+	RetryAfter int `yaml:"retry_after"`
+}
+
+// queryLimiter is a semaphore with a bounded queue
+type queryLimiter struct {
+	slots      chan struct{}
+	queued     int64
+	maxQueued  int64
+	timeout    time.Duration
+	retryAfter int
+}
+
+func newQueryLimiter(cfg *QueryLimitConfig) (*queryLimiter, error) {
+	if cfg.MaxConcurrent < 1 {
+		return nil, fmt.Errorf("max_concurrent must be at least 1")
+	}
+
+	if cfg.MaxQueued < 0 || cfg.QueueTimeout < 0 || cfg.RetryAfter < 0 {
+		return nil, fmt.Errorf("max_queued, queue_timeout and retry_after must not be negative")
+	}
+
+	l := &queryLimiter{
+		slots:      make(chan struct{}, cfg.MaxConcurrent),
+		maxQueued:  int64(cfg.MaxQueued),
+		timeout:    time.Duration(cfg.QueueTimeout) * time.Second,
+		retryAfter: cfg.RetryAfter,
+	}
+
+	if l.timeout == 0 {
+		l.timeout = defaultQueueTimeout * time.Second
+	}
+
+	if l.retryAfter == 0 {
+		l.retryAfter = defaultRetryAfter
+	}
+
+	return l, nil
+}
+
+// acquire waits for a free slot unless the queue is full
+func (l *queryLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		queriesRunning.Inc()
+		return nil
+	default:
+	}
+
+	if atomic.AddInt64(&l.queued, 1) > l.maxQueued {
+		atomic.AddInt64(&l.queued, -1)
+		return errQueueFull
+	}
+	queriesQueued.Inc()
+	defer func() {
+		atomic.AddInt64(&l.queued, -1)
+		queriesQueued.Dec()
+	}()
+
+	t := time.NewTimer(l.timeout)
+	defer t.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		queriesRunning.Inc()
+		return nil
+	case <-t.C:
+		return errQueueTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *queryLimiter) release() {
+	<-l.slots
+	queriesRunning.Dec()
+}
+
+// LimitQueries limits the number of requests querying ClickHouse at the same time. It must be called before Start.
+func (fe *Frontend) LimitQueries(cfg *QueryLimitConfig) error {
+	l, err := newQueryLimiter(cfg)
+	if err != nil {
+		return err
+	}
+
+	fe.limiter = l
+	return nil
+}
+
+// limited lets h run once a query slot is free. Requests which can not get a slot are answered with 429.
+func (fe *Frontend) limited(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if fe.limiter == nil {
+			h(w, r)
+			return
+		}
+
+		err := fe.limiter.acquire(r.Context())
+		if err != nil {
+			if r.Context().Err() != nil {
+				return
+			}
+
+			queriesRejected.Inc()
+			requestLogger(r).WithError(err).Warning("Rejecting request")
+			w.Header().Set("Retry-After", strconv.Itoa(fe.limiter.retryAfter))
+			httpError(w, r, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		defer fe.limiter.release()
+
+		h(w, r)
+	}
+}
@@ -0,0 +1,86 @@
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewQueryLimiter(t *testing.T) {
+	_, err := newQueryLimiter(&QueryLimitConfig{})
+	assert.Error(t, err)
+
+	_, err = newQueryLimiter(&QueryLimitConfig{MaxConcurrent: 1, MaxQueued: -1})
+	assert.Error(t, err)
+
+	l, err := newQueryLimiter(&QueryLimitConfig{MaxConcurrent: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Equal(t, 2, cap(l.slots))
+	assert.Equal(t, defaultQueueTimeout*time.Second, l.timeout)
+	assert.Equal(t, defaultRetryAfter, l.retryAfter)
+}
+
+func TestQueryLimiterAcquire(t *testing.T) {
+	l, err := newQueryLimiter(&QueryLimitConfig{MaxConcurrent: 1, MaxQueued: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	l.timeout = 50 * time.Millisecond
+
+	assert.NoError(t, l.acquire(context.Background()))
+	assert.Equal(t, errQueueTimeout, l.acquire(context.Background()))
+
+	done := make(chan error)
+	go func() {
+		done <- l.acquire(context.Background())
+	}()
+
+	// the queue has room for one request only
+	for {
+		if atomic.LoadInt64(&l.queued) == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, errQueueFull, l.acquire(context.Background()))
+
+	l.release()
+	assert.NoError(t, <-done)
+	l.release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NoError(t, l.acquire(ctx))
+	assert.Equal(t, context.Canceled, l.acquire(ctx))
+}
+
+func TestLimited(t *testing.T) {
+	fe := &Frontend{}
+	err := fe.LimitQueries(&QueryLimitConfig{MaxConcurrent: 1, RetryAfter: 7})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	h := fe.limited(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/query", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", rec.Body.String())
+
+	// with the only slot taken and no queue further requests are rejected
+	fe.limiter.acquire(context.Background())
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/query", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "7", rec.Header().Get("Retry-After"))
+}
@@ -0,0 +1,8 @@
+package frontend
+
+import "github.com/bio-routing/flowhouse/pkg/logging"
+
+var (
+	log   = logging.Component(logging.Frontend)
+	audit = logging.Component(logging.Audit)
+)
@@ -0,0 +1,201 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMatrixTop = 20
+	matrixOthers     = "Others"
+)
+
+// matrixFields are the fields a traffic matrix can be built from
+var matrixFields = map[string]struct{}{
+	"src_asn":  {},
+	"dst_asn":  {},
+	"next_asn": {},
+	"agent":    {},
+	"int_in":   {},
+	"int_out":  {},
+	"customer": {},
+	"service":  {},
+}
+
+// Matrix is a traffic matrix. Values[i][j] is the average rate in Mbps from Rows[i] to Columns[j].
+type Matrix struct {
+	RowField    string      `json:"row_field"`
+	ColumnField string      `json:"column_field"`
+	Rows        []string    `json:"rows"`
+	Columns     []string    `json:"columns"`
+	Values      [][]float64 `json:"values"`
+}
+
+type matrixCell struct {
+	row   string
+	col   string
+	bytes uint64
+}
+
+// matrixHandler serves a traffic matrix (default: source ASN x destination ASN) over a time range as JSON
+func (fe *Frontend) matrixHandler(w http.ResponseWriter, r *http.Request) {
+	m, err := fe.processMatrixQuery(r.Context(), r.URL.Query())
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Unable to process matrix query")
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	j, err := json.Marshal(m)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func (fe *Frontend) processMatrixQuery(ctx context.Context, fields url.Values) (*Matrix, error) {
+	rowField, colField, top, err := getMatrixOptions(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if end <= start {
+		return nil, fmt.Errorf("Empty time range")
+	}
+
+	rows, err := fe.chgw.QueryContext(ctx, fe.matrixQuery(rowField, colField, start, end, fe.getFieldConditions(fields)))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	cells := make([]matrixCell, 0)
+	for rows.Next() {
+		var c matrixCell
+		err := rows.Scan(&c.row, &c.col, &c.bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		cells = append(cells, c)
+	}
+
+	m := buildMatrix(cells, top, end-start)
+	m.RowField = rowField
+	m.ColumnField = colField
+	return m, rows.Err()
+}
+
+func getMatrixOptions(fields url.Values) (string, string, int, error) {
+	rowField := fields.Get("rows")
+	if rowField == "" {
+		rowField = "src_asn"
+	}
+
+	colField := fields.Get("columns")
+	if colField == "" {
+		colField = "dst_asn"
+	}
+
+	for _, f := range []string{rowField, colField} {
+		if _, ok := matrixFields[f]; !ok {
+			return "", "", 0, fmt.Errorf("Unsupported matrix field %q", f)
+		}
+	}
+
+	top := defaultMatrixTop
+	if t := fields.Get("top"); t != "" {
+		var err error
+		top, err = strconv.Atoi(t)
+		if err != nil || top <= 0 {
+			return "", "", 0, fmt.Errorf("Invalid top %q", t)
+		}
+	}
+
+	return rowField, colField, top, nil
+}
+
+func (fe *Frontend) matrixQuery(rowField string, colField string, start int64, end int64, conditions []string) string {
+	conditions = append([]string{fmt.Sprintf("timestamp BETWEEN toDateTime(%d) AND toDateTime(%d)", start, end)}, conditions...)
+
+	return fmt.Sprintf("SELECT toString(%s) AS r, toString(%s) AS c, sum(size * samplerate) AS bytes FROM %s.flows WHERE %s GROUP BY r, c",
+		rowField, colField, fe.chgw.GetDatabaseName(), strings.Join(conditions, " AND "))
+}
+
+// buildMatrix keeps the top rows and columns by volume and sums up everything else in an "Others" row/column
+func buildMatrix(cells []matrixCell, top int, seconds int64) *Matrix {
+	rowTotals := make(map[string]uint64)
+	colTotals := make(map[string]uint64)
+	for _, c := range cells {
+		rowTotals[c.row] += c.bytes
+		colTotals[c.col] += c.bytes
+	}
+
+	rows, rowIdx := topKeys(rowTotals, top)
+	cols, colIdx := topKeys(colTotals, top)
+
+	values := make([][]float64, len(rows))
+	for i := range values {
+		values[i] = make([]float64, len(cols))
+	}
+
+	for _, c := range cells {
+		values[rowIdx(c.row)][colIdx(c.col)] += float64(c.bytes) * 8 / float64(seconds) / 1000000
+	}
+
+	return &Matrix{
+		Rows:    rows,
+		Columns: cols,
+		Values:  values,
+	}
+}
+
+// topKeys returns the top keys by total (followed by "Others" if keys were cut off) and a function mapping keys to their index
+func topKeys(totals map[string]uint64, top int) ([]string, func(string) int) {
+	keys := make([]string, 0, len(totals))
+	for k := range totals {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if totals[keys[i]] != totals[keys[j]] {
+			return totals[keys[i]] > totals[keys[j]]
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	trimmed := len(keys) > top
+	if trimmed {
+		keys = append(keys[:top], matrixOthers)
+	}
+
+	idx := make(map[string]int, len(keys))
+	for i, k := range keys {
+		idx[k] = i
+	}
+
+	return keys, func(k string) int {
+		if i, ok := idx[k]; ok {
+			return i
+		}
+
+		return len(keys) - 1
+	}
+}
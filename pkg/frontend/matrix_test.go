@@ -0,0 +1,51 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMatrix(t *testing.T) {
+	cells := []matrixCell{
+		{row: "1", col: "10", bytes: 1000000},
+		{row: "1", col: "20", bytes: 3000000},
+		{row: "2", col: "10", bytes: 2000000},
+		{row: "3", col: "30", bytes: 500000},
+		{row: "4", col: "10", bytes: 250000},
+	}
+
+	m := buildMatrix(cells, 2, 8)
+	assert.Equal(t, []string{"1", "2", "Others"}, m.Rows)
+	assert.Equal(t, []string{"10", "20", "Others"}, m.Columns)
+	assert.Equal(t, [][]float64{
+		{1, 3, 0},
+		{2, 0, 0},
+		{0.25, 0, 0.5},
+	}, m.Values)
+
+	m = buildMatrix(cells, 10, 8)
+	assert.Equal(t, []string{"1", "2", "3", "4"}, m.Rows)
+	assert.Equal(t, []string{"10", "20", "30"}, m.Columns)
+}
+
+func TestGetMatrixOptions(t *testing.T) {
+	rows, cols, top, err := getMatrixOptions(url.Values{})
+	assert.NoError(t, err)
+	assert.Equal(t, "src_asn", rows)
+	assert.Equal(t, "dst_asn", cols)
+	assert.Equal(t, defaultMatrixTop, top)
+
+	rows, cols, top, err = getMatrixOptions(url.Values{"rows": {"next_asn"}, "columns": {"next_asn"}, "top": {"5"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "next_asn", rows)
+	assert.Equal(t, "next_asn", cols)
+	assert.Equal(t, 5, top)
+
+	_, _, _, err = getMatrixOptions(url.Values{"rows": {"src_ip_addr; DROP TABLE flows"}})
+	assert.Error(t, err)
+
+	_, _, _, err = getMatrixOptions(url.Values{"top": {"0"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,336 @@
+package frontend
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/requestid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// Middleware wraps a handler, e.g. to authenticate or log requests
+type Middleware func(http.Handler) http.Handler
+
+// AdminPrefix is the prefix of the paths only admins may access
+const AdminPrefix = "/admin/"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by route and status code",
+	}, []string{"route", "code"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "flowhouse",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time it took to serve HTTP requests by route",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 4, 8),
+	}, []string{"route"})
+)
+
+// AuthConfig configures HTTP basic authentication
+type AuthConfig struct {
+	Users []*User `yaml:"users"`
+
+	// Exempt are paths served without authentication, e.g. /metrics
+	Exempt []string `yaml:"exempt"`
+}
+
+// User is a user allowed to access the frontend
+type User struct {
+	Name     string `yaml:"name"`
+	Password string `yaml:"password"`
+
+	// Scope limits the dict values listed to the user to the rows whose attributes have one of the given values
+	Scope clickhousegw.ValueScope `yaml:"scope"`
+
+	// Admin allows the user to access the paths below AdminPrefix, e.g. to delete flows or kill queries
+	Admin bool `yaml:"admin"`
+}
+
+type userKey struct{}
+
+// scopeFromContext gets the value scope of the user a request was authenticated as, nil if unrestricted
+func scopeFromContext(ctx context.Context) clickhousegw.ValueScope {
+	u, ok := ctx.Value(userKey{}).(*User)
+	if !ok {
+		return nil
+	}
+
+	return u.Scope
+}
+
+// statusWriter records the status code of a response
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+
+	return w.code
+}
+
+// RequestID tags requests with the X-Request-ID of the client or a new ID. The ID is sent back in the response
+// header and is available to handlers through the request context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
+
+// Recovery answers requests whose handler panicked with an internal server error instead of dropping the connection
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			log.WithFields(logrus.Fields{
+				"path":       r.URL.Path,
+				"request_id": requestid.FromContext(r.Context()),
+			}).Errorf("Handler panicked: %v", err)
+			httpError(w, r, "Internal server error", http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Logging logs every request at debug level
+func Logging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		log.WithFields(logrus.Fields{
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"status":     sw.status(),
+			"duration":   time.Since(start),
+			"remote":     r.RemoteAddr,
+			"request_id": requestid.FromContext(r.Context()),
+		}).Debug("HTTP request")
+	})
+}
+
+// Metrics counts requests and observes their duration per route
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		// the mux sets the pattern of the matched route, which keeps the number of label values bounded
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+
+		httpRequests.WithLabelValues(route, strconv.Itoa(sw.status())).Inc()
+		httpRequestDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// BasicAuth requires the credentials of one of the configured users for all but the exempt paths
+func BasicAuth(cfg *AuthConfig) Middleware {
+	exempt := make(map[string]struct{}, len(cfg.Exempt))
+	for _, p := range cfg.Exempt {
+		exempt[p] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := exempt[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			name, password, ok := r.BasicAuth()
+			u := cfg.authenticate(name, password)
+			if !ok || u == nil {
+				w.Header().Set("WWW-Authenticate", `Basic realm="flowhouse"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, u)))
+		})
+	}
+}
+
+// AdminOnly rejects requests for paths below AdminPrefix unless they were authenticated as an admin. It has to follow
+// BasicAuth in the middleware chain.
+func AdminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, AdminPrefix) {
+			u, ok := r.Context().Value(userKey{}).(*User)
+			if !ok || !u.Admin {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate gets the user with the credentials, nil if there is none
+func (cfg *AuthConfig) authenticate(name string, password string) *User {
+	var res *User
+	for _, u := range cfg.Users {
+		// all users are compared to not leak which exist through timing
+		nameOK := subtle.ConstantTimeCompare([]byte(u.Name), []byte(name)) == 1
+		passwordOK := subtle.ConstantTimeCompare([]byte(u.Password), []byte(password)) == 1
+		if nameOK && passwordOK {
+			res = u
+		}
+	}
+
+	return res
+}
+
+// incompressibleTypes are content types not worth compressing again
+var incompressibleTypes = []string{
+	"image/png",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// gzipWriter compresses the response once the status and content type are known
+type gzipWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if !w.decided {
+		w.decide(code)
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipWriter) decide(code int) {
+	w.decided = true
+
+	h := w.Header()
+	if code == http.StatusNoContent || code == http.StatusNotModified || h.Get("Content-Encoding") != "" {
+		return
+	}
+
+	ct := h.Get("Content-Type")
+	for _, t := range incompressibleTypes {
+		if strings.HasPrefix(ct, t) {
+			return
+		}
+	}
+
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	h.Add("Vary", "Accept-Encoding")
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		// sniff before compressing as the server would sniff the compressed data
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+
+	return w.gz.Write(b)
+}
+
+// Flush sends everything written so far, which keeps server-sent events working
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipWriter) close() {
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
+
+// Gzip compresses responses for clients accepting gzip
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.close()
+
+		next.ServeHTTP(gw, r)
+	})
+}
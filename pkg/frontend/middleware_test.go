@@ -0,0 +1,234 @@
+package frontend
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/requestid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUse(t *testing.T) {
+	fe := &Frontend{mux: http.NewServeMux()}
+	fe.HandleFunc("/x", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("handler"))
+	})
+
+	tag := func(s string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(s + ">"))
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	fe.Use(tag("a"), tag("b"))
+	fe.Use(tag("c"))
+
+	rec := httptest.NewRecorder()
+	fe.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+	assert.Equal(t, "a>b>c>handler", rec.Body.String())
+}
+
+func TestRequestID(t *testing.T) {
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpError(w, r, "Query failed", http.StatusInternalServerError)
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "Client ID", header: "client-42", expected: "client-42"},
+		{name: "Generated ID", header: ""},
+		{name: "Invalid client ID", header: "*/ DROP TABLE flows /*"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/query", nil)
+		if test.header != "" {
+			req.Header.Set(requestid.Header, test.header)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		id := rec.Header().Get(requestid.Header)
+		if test.expected != "" {
+			assert.Equal(t, test.expected, id, test.name)
+		} else {
+			assert.Len(t, id, 16, test.name)
+		}
+		assert.Equal(t, "Query failed (request ID "+id+")\n", rec.Body.String(), test.name)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	h := Recovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestBasicAuth(t *testing.T) {
+	h := BasicAuth(&AuthConfig{
+		Users:  []*User{{Name: "noc", Password: "secret"}},
+		Exempt: []string{"/metrics"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name     string
+		path     string
+		user     string
+		password string
+		expected int
+	}{
+		{name: "No credentials", path: "/query", expected: http.StatusUnauthorized},
+		{name: "Wrong password", path: "/query", user: "noc", password: "wrong", expected: http.StatusUnauthorized},
+		{name: "Unknown user", path: "/query", user: "other", password: "secret", expected: http.StatusUnauthorized},
+		{name: "Valid", path: "/query", user: "noc", password: "secret", expected: http.StatusOK},
+		{name: "Exempt", path: "/metrics", expected: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.user != "" {
+			req.SetBasicAuth(test.user, test.password)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, test.expected, rec.Code, test.name)
+		if test.expected == http.StatusUnauthorized {
+			assert.Equal(t, `Basic realm="flowhouse"`, rec.Header().Get("WWW-Authenticate"), test.name)
+		}
+	}
+}
+
+func TestBasicAuthScope(t *testing.T) {
+	scope := clickhousegw.ValueScope{"customer": {"acme"}}
+
+	var got clickhousegw.ValueScope
+	h := BasicAuth(&AuthConfig{
+		Users: []*User{{Name: "noc", Password: "secret"}, {Name: "acme", Password: "secret", Scope: scope}},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = scopeFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/dict_values/int_in__name", nil)
+	req.SetBasicAuth("acme", "secret")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, scope, got)
+
+	req.SetBasicAuth("noc", "secret")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Nil(t, got)
+}
+
+func TestAdminOnly(t *testing.T) {
+	h := BasicAuth(&AuthConfig{
+		Users:  []*User{{Name: "noc", Password: "secret", Admin: true}, {Name: "acme", Password: "secret"}},
+		Exempt: []string{"/admin/reload"},
+	})(AdminOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	tests := []struct {
+		name     string
+		path     string
+		user     string
+		expected int
+	}{
+		{name: "Admin", path: "/admin/retention/delete", user: "noc", expected: http.StatusOK},
+		{name: "Non-admin", path: "/admin/retention/delete", user: "acme", expected: http.StatusForbidden},
+		{name: "Non-admin other path", path: "/query", user: "acme", expected: http.StatusOK},
+		{name: "Exempt admin path", path: "/admin/reload", expected: http.StatusForbidden},
+		{name: "Unauthenticated", path: "/admin/queries", expected: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, test.path, nil)
+		if test.user != "" {
+			req.SetBasicAuth(test.user, "secret")
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, test.expected, rec.Code, test.name)
+	}
+}
+
+func TestGzip(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		accept      string
+		compressed  bool
+	}{
+		{name: "CSV", contentType: "text/csv", accept: "gzip, deflate", compressed: true},
+		{name: "Sniffed", accept: "gzip", compressed: true},
+		{name: "Not accepted", contentType: "text/csv", compressed: false},
+		{name: "PNG", contentType: "image/png", accept: "gzip", compressed: false},
+	}
+
+	for _, test := range tests {
+		h := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if test.contentType != "" {
+				w.Header().Set("Content-Type", test.contentType)
+			}
+			w.Write([]byte("timestamp,a\n"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("2024-01-01T00:00:00Z,1\n"))
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/query", nil)
+		if test.accept != "" {
+			req.Header.Set("Accept-Encoding", test.accept)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.True(t, rec.Flushed, test.name)
+
+		body := rec.Body.String()
+		if test.compressed {
+			assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"), test.name)
+			zr, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("Unable to read gzip for test %q: %v", test.name, err)
+			}
+
+			b, err := io.ReadAll(zr)
+			assert.NoError(t, err, test.name)
+			body = string(b)
+		} else {
+			assert.Empty(t, rec.Header().Get("Content-Encoding"), test.name)
+		}
+
+		assert.Equal(t, "timestamp,a\n2024-01-01T00:00:00Z,1\n", body, test.name)
+		assert.NotEmpty(t, rec.Header().Get("Content-Type"), test.name)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	fe := &Frontend{mux: http.NewServeMux()}
+	fe.HandleFunc("/agents/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	fe.Use(Metrics)
+
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("/agents/{name}", "418"))
+	fe.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/agents/rtr01", nil))
+	fe.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/agents/rtr02", nil))
+	assert.Equal(t, before+2, testutil.ToFloat64(httpRequests.WithLabelValues("/agents/{name}", "418")))
+
+	fe.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues("unmatched", "404")))
+}
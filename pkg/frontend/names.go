@@ -0,0 +1,269 @@
+package frontend
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// protocolNames are the IANA keywords of commonly seen IP protocols
+var protocolNames = map[uint64]string{
+	0:   "hopopt",
+	1:   "icmp",
+	2:   "igmp",
+	4:   "ipv4",
+	6:   "tcp",
+	8:   "egp",
+	9:   "igp",
+	17:  "udp",
+	27:  "rdp",
+	33:  "dccp",
+	41:  "ipv6",
+	43:  "ipv6-route",
+	44:  "ipv6-frag",
+	46:  "rsvp",
+	47:  "gre",
+	50:  "esp",
+	51:  "ah",
+	58:  "ipv6-icmp",
+	59:  "ipv6-nonxt",
+	60:  "ipv6-opts",
+	88:  "eigrp",
+	89:  "ospf",
+	94:  "ipip",
+	97:  "etherip",
+	103: "pim",
+	108: "ipcomp",
+	112: "vrrp",
+	115: "l2tp",
+	132: "sctp",
+	136: "udplite",
+	137: "mpls-in-ip",
+	143: "ethernet",
+}
+
+// portNames are the IANA service names of well-known ports
+var portNames = map[uint64]string{
+	20:    "ftp-data",
+	21:    "ftp",
+	22:    "ssh",
+	23:    "telnet",
+	25:    "smtp",
+	49:    "tacacs",
+	53:    "domain",
+	67:    "bootps",
+	68:    "bootpc",
+	69:    "tftp",
+	80:    "http",
+	88:    "kerberos",
+	110:   "pop3",
+	111:   "sunrpc",
+	119:   "nntp",
+	123:   "ntp",
+	137:   "netbios-ns",
+	138:   "netbios-dgm",
+	139:   "netbios-ssn",
+	143:   "imap",
+	161:   "snmp",
+	162:   "snmptrap",
+	179:   "bgp",
+	389:   "ldap",
+	443:   "https",
+	445:   "microsoft-ds",
+	465:   "submissions",
+	500:   "isakmp",
+	514:   "syslog",
+	546:   "dhcpv6-client",
+	547:   "dhcpv6-server",
+	587:   "submission",
+	636:   "ldaps",
+	853:   "domain-s",
+	873:   "rsync",
+	989:   "ftps-data",
+	990:   "ftps",
+	993:   "imaps",
+	995:   "pop3s",
+	1194:  "openvpn",
+	1433:  "ms-sql-s",
+	1701:  "l2f",
+	1812:  "radius",
+	1813:  "radius-acct",
+	1883:  "mqtt",
+	2049:  "nfs",
+	3306:  "mysql",
+	3389:  "ms-wbt-server",
+	3478:  "stun",
+	4500:  "ipsec-nat-t",
+	4789:  "vxlan",
+	5060:  "sip",
+	5061:  "sips",
+	5222:  "xmpp-client",
+	5353:  "mdns",
+	5432:  "postgresql",
+	6081:  "geneve",
+	8080:  "http-alt",
+	11211: "memcache",
+}
+
+// NamesConfig overrides the names of IP protocols and ports. An empty name removes a built-in name.
+type NamesConfig struct {
+	Protocols map[uint8]string  `yaml:"protocols"`
+	Ports     map[uint16]string `yaml:"ports"`
+}
+
+// Validate checks that names are unique and no numbers
+func (c *NamesConfig) Validate() error {
+	_, err := newNames(c)
+	return err
+}
+
+// builtinNames are the names used unless configured otherwise
+var builtinNames = mustNames(nil)
+
+// names labels the values of protocol and port fields and resolves names in filters
+type names struct {
+	protocols *nameTable
+	ports     *nameTable
+}
+
+type nameTable struct {
+	names   map[uint64]string
+	numbers map[string]uint64
+}
+
+func mustNames(cfg *NamesConfig) *names {
+	n, err := newNames(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	return n
+}
+
+func newNames(cfg *NamesConfig) (*names, error) {
+	if cfg == nil {
+		cfg = &NamesConfig{}
+	}
+
+	protocols := make(map[uint64]string, len(cfg.Protocols))
+	for k, v := range cfg.Protocols {
+		protocols[uint64(k)] = v
+	}
+
+	ports := make(map[uint64]string, len(cfg.Ports))
+	for k, v := range cfg.Ports {
+		ports[uint64(k)] = v
+	}
+
+	p, err := newNameTable(protocolNames, protocols)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid protocol names: %v", err)
+	}
+
+	q, err := newNameTable(portNames, ports)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid port names: %v", err)
+	}
+
+	return &names{protocols: p, ports: q}, nil
+}
+
+func newNameTable(builtin map[uint64]string, overrides map[uint64]string) (*nameTable, error) {
+	t := &nameTable{
+		names:   make(map[uint64]string, len(builtin)+len(overrides)),
+		numbers: make(map[string]uint64, len(builtin)+len(overrides)),
+	}
+
+	for k, v := range builtin {
+		t.names[k] = v
+	}
+
+	for k, v := range overrides {
+		if v == "" {
+			delete(t.names, k)
+			continue
+		}
+
+		t.names[k] = strings.ToLower(v)
+	}
+
+	for k, v := range t.names {
+		if _, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return nil, fmt.Errorf("Name %q of %d is a number", v, k)
+		}
+
+		if other, exists := t.numbers[v]; exists {
+			return nil, fmt.Errorf("Name %q is used for %d and %d", v, other, k)
+		}
+
+		t.numbers[v] = k
+	}
+
+	return t, nil
+}
+
+// table gets the names of the values of a field or nil if its values have no names
+func (n *names) table(fieldName string) *nameTable {
+	switch fieldName {
+	case "ip_protocol", "inner_ip_protocol":
+		return n.protocols
+	case "src_port", "dst_port", "inner_src_port", "inner_dst_port":
+		return n.ports
+	}
+
+	return nil
+}
+
+// label gets the name of the value v of a field or v itself if it has none
+func (n *names) label(fieldName string, v uint64) string {
+	if t := n.table(fieldName); t != nil {
+		if name, ok := t.names[v]; ok {
+			return name
+		}
+	}
+
+	return strconv.FormatUint(v, 10)
+}
+
+// resolve replaces names in the values of protocol and port fields by their numbers. Names are case insensitive.
+func (n *names) resolve(fields url.Values) url.Values {
+	res := make(url.Values, len(fields))
+	for fieldName, values := range fields {
+		t := n.table(fieldName)
+		if t == nil {
+			res[fieldName] = values
+			continue
+		}
+
+		resolved := make([]string, len(values))
+		for i, v := range values {
+			resolved[i] = v
+			if num, ok := t.numbers[strings.ToLower(v)]; ok {
+				resolved[i] = strconv.FormatUint(num, 10)
+			}
+		}
+		res[fieldName] = resolved
+	}
+
+	return res
+}
+
+// SetNames overrides the built-in names of IP protocols and ports
+func (fe *Frontend) SetNames(cfg *NamesConfig) error {
+	n, err := newNames(cfg)
+	if err != nil {
+		return err
+	}
+
+	fe.names = n
+	return nil
+}
+
+func (fe *Frontend) getNames() *names {
+	if fe.names == nil {
+		return builtinNames
+	}
+
+	return fe.names
+}
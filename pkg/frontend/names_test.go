@@ -0,0 +1,95 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestNamesLabel(t *testing.T) {
+	n := mustNames(&NamesConfig{
+		Ports: map[uint16]string{
+			53:   "DNS",
+			8080: "",
+			9100: "node-exporter",
+		},
+	})
+
+	tests := []struct {
+		field    string
+		value    uint64
+		expected string
+	}{
+		{field: "ip_protocol", value: 6, expected: "tcp"},
+		{field: "inner_ip_protocol", value: 58, expected: "ipv6-icmp"},
+		{field: "ip_protocol", value: 253, expected: "253"},
+		{field: "dst_port", value: 443, expected: "https"},
+		{field: "src_port", value: 53, expected: "dns"},
+		{field: "dst_port", value: 8080, expected: "8080"},
+		{field: "dst_port", value: 9100, expected: "node-exporter"},
+		{field: "dst_asn", value: 443, expected: "443"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, n.label(test.field, test.value), "%s %d", test.field, test.value)
+	}
+}
+
+func TestNamesResolve(t *testing.T) {
+	fields := url.Values{
+		"ip_protocol": {"TCP"},
+		"dst_port":    {"https", "8443", "domain"},
+		"int_in":      {"https"},
+	}
+
+	assert.Equal(t, url.Values{
+		"ip_protocol": {"6"},
+		"dst_port":    {"443", "8443", "53"},
+		"int_in":      {"https"},
+	}, builtinNames.resolve(fields))
+	assert.Equal(t, []string{"TCP"}, fields["ip_protocol"])
+
+	fe := &Frontend{}
+	assert.Equal(t, []string{"dst_port = '443'", "ip_protocol = '6'"}, fe.getFieldConditions(url.Values{
+		"ip_protocol": {"tcp"},
+		"dst_port":    {"https"},
+	}))
+}
+
+func TestNamesConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		wantFail bool
+	}{
+		{
+			name:   "Overrides",
+			config: "protocols:\n  6: TCP\nports:\n  9100: node-exporter\n  8080: \"\"\n",
+		},
+		{
+			name:     "Duplicate",
+			config:   "ports:\n  8443: https\n",
+			wantFail: true,
+		},
+		{
+			name:     "Number",
+			config:   "protocols:\n  253: \"42\"\n",
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		cfg := &NamesConfig{}
+		assert.NoError(t, yaml.Unmarshal([]byte(test.config), cfg), test.name)
+
+		err := cfg.Validate()
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+	}
+}
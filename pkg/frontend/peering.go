@@ -0,0 +1,228 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	peeringByNeighbor = "neighbor"
+	peeringByExit     = "exit"
+
+	defaultPeeringBucketSeconds = 300
+)
+
+// PeeringEntry summarizes the traffic towards a BGP neighbor AS or through an exit interface
+type PeeringEntry struct {
+	NeighborASN uint32 `json:"neighbor_asn,omitempty"`
+	Agent       string `json:"agent,omitempty"`
+	Interface   string `json:"interface,omitempty"`
+
+	AvgMbps  float64 `json:"avg_mbps"`
+	PeakMbps float64 `json:"peak_mbps"`
+
+	// PreviousAvgMbps is the average rate in the preceding time range of the same length
+	PreviousAvgMbps float64 `json:"previous_avg_mbps"`
+
+	// ChangePercent is the change of the average rate compared to the preceding time range (null if there was no traffic)
+	ChangePercent *float64 `json:"change_percent"`
+
+	// Series is the rate per bucket, it allows to spot trends within the time range
+	Series []*PeeringSample `json:"series"`
+}
+
+// PeeringSample is the average rate of one bucket
+type PeeringSample struct {
+	Time time.Time `json:"time"`
+	Mbps float64   `json:"mbps"`
+}
+
+type peeringRow struct {
+	key1  string
+	key2  string
+	ts    time.Time
+	bytes uint64
+}
+
+// peeringHandler serves traffic summaries per BGP neighbor AS (by=neighbor, default) or exit interface (by=exit) as JSON
+func (fe *Frontend) peeringHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := fe.processPeeringQuery(r.Context(), r.URL.Query())
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Unable to process peering query")
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func (fe *Frontend) processPeeringQuery(ctx context.Context, fields url.Values) ([]*PeeringEntry, error) {
+	by := fields.Get("by")
+	if by == "" {
+		by = peeringByNeighbor
+	}
+
+	if by != peeringByNeighbor && by != peeringByExit {
+		return nil, fmt.Errorf("Invalid by %q", by)
+	}
+
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if end <= start {
+		return nil, fmt.Errorf("Empty time range")
+	}
+
+	bucket := uint64(defaultPeeringBucketSeconds)
+	if b := fields.Get("bucket"); b != "" {
+		bucket, err = strconv.ParseUint(b, 10, 32)
+		if err != nil || bucket == 0 {
+			return nil, fmt.Errorf("Invalid bucket size: %q", b)
+		}
+	}
+
+	rows, err := fe.chgw.QueryContext(ctx, fe.peeringQuery(by, start, end, bucket, fe.getFieldConditions(fields)))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	data := make([]peeringRow, 0)
+	for rows.Next() {
+		var r peeringRow
+		err := rows.Scan(&r.key1, &r.key2, &r.ts, &r.bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		data = append(data, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+
+	return buildPeeringReport(by, data, start, end, bucket), nil
+}
+
+func (fe *Frontend) peeringQuery(by string, start int64, end int64, bucket uint64, conditions []string) string {
+	// the preceding time range of the same length is queried as well to compare against
+	prevStart := start - (end - start)
+	conditions = append([]string{fmt.Sprintf("timestamp >= toDateTime(%d) AND timestamp < toDateTime(%d)", prevStart, end)}, conditions...)
+
+	keys, keyCondition := "toString(next_asn) AS k1, '' AS k2", "next_asn != 0"
+	if by == peeringByExit {
+		keys, keyCondition = "IPv6NumToString(agent) AS k1, int_out AS k2", "int_out != ''"
+	}
+	conditions = append(conditions, keyCondition)
+
+	return fmt.Sprintf("SELECT %s, toStartOfInterval(timestamp, INTERVAL %d second) AS t, sum(size * samplerate) AS bytes FROM %s.flows WHERE %s GROUP BY k1, k2, t ORDER BY t",
+		keys, bucket, fe.chgw.GetDatabaseName(), strings.Join(conditions, " AND "))
+}
+
+func buildPeeringReport(by string, data []peeringRow, start int64, end int64, bucket uint64) []*PeeringEntry {
+	type key struct {
+		k1 string
+		k2 string
+	}
+
+	type acc struct {
+		entry     *PeeringEntry
+		bytes     uint64
+		prevBytes uint64
+	}
+
+	entries := make(map[key]*acc)
+	for _, r := range data {
+		k := key{k1: r.key1, k2: r.key2}
+		a, exists := entries[k]
+		if !exists {
+			a = &acc{
+				entry: newPeeringEntry(by, r.key1, r.key2),
+			}
+			entries[k] = a
+		}
+
+		if r.ts.Unix() < start {
+			a.prevBytes += r.bytes
+			continue
+		}
+
+		a.bytes += r.bytes
+		mbps := float64(r.bytes) * 8 / float64(bucket) / 1000000
+		a.entry.Series = append(a.entry.Series, &PeeringSample{
+			Time: r.ts,
+			Mbps: mbps,
+		})
+
+		if mbps > a.entry.PeakMbps {
+			a.entry.PeakMbps = mbps
+		}
+	}
+
+	seconds := float64(end - start)
+	res := make([]*PeeringEntry, 0, len(entries))
+	for _, a := range entries {
+		a.entry.AvgMbps = float64(a.bytes) * 8 / seconds / 1000000
+		a.entry.PreviousAvgMbps = float64(a.prevBytes) * 8 / seconds / 1000000
+		if a.prevBytes > 0 {
+			change := (a.entry.AvgMbps - a.entry.PreviousAvgMbps) / a.entry.PreviousAvgMbps * 100
+			a.entry.ChangePercent = &change
+		}
+
+		if a.entry.Series == nil {
+			a.entry.Series = make([]*PeeringSample, 0)
+		}
+
+		res = append(res, a.entry)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].AvgMbps != res[j].AvgMbps {
+			return res[i].AvgMbps > res[j].AvgMbps
+		}
+
+		return res[i].PreviousAvgMbps > res[j].PreviousAvgMbps
+	})
+
+	return res
+}
+
+func newPeeringEntry(by string, key1 string, key2 string) *PeeringEntry {
+	if by == peeringByNeighbor {
+		asn, _ := strconv.ParseUint(key1, 10, 32)
+		return &PeeringEntry{
+			NeighborASN: uint32(asn),
+		}
+	}
+
+	agent := key1
+	if addr := net.ParseIP(key1); addr != nil {
+		agent = addr.String()
+	}
+
+	return &PeeringEntry{
+		Agent:     agent,
+		Interface: key2,
+	}
+}
@@ -0,0 +1,42 @@
+package frontend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildPeeringReport(t *testing.T) {
+	ts := func(s int64) time.Time {
+		return time.Unix(s, 0)
+	}
+
+	data := []peeringRow{
+		{key1: "::ffff:192.0.2.1", key2: "et-0/0/0", ts: ts(700), bytes: 15000000},
+		{key1: "::ffff:192.0.2.1", key2: "et-0/0/0", ts: ts(1000), bytes: 30000000},
+		{key1: "::ffff:192.0.2.1", key2: "et-0/0/0", ts: ts(1300), bytes: 7500000},
+		{key1: "2001:db8::1", key2: "et-0/0/1", ts: ts(1000), bytes: 3750000},
+	}
+
+	res := buildPeeringReport(peeringByExit, data, 1000, 1600, 300)
+	assert.Len(t, res, 2)
+
+	assert.Equal(t, "192.0.2.1", res[0].Agent)
+	assert.Equal(t, "et-0/0/0", res[0].Interface)
+	assert.Equal(t, float64(0.5), res[0].AvgMbps)
+	assert.Equal(t, float64(0.8), res[0].PeakMbps)
+	assert.Equal(t, float64(0.2), res[0].PreviousAvgMbps)
+	assert.InDelta(t, 150, *res[0].ChangePercent, 0.0001)
+	assert.Equal(t, []*PeeringSample{
+		{Time: ts(1000), Mbps: 0.8},
+		{Time: ts(1300), Mbps: 0.2},
+	}, res[0].Series)
+
+	assert.Equal(t, "2001:db8::1", res[1].Agent)
+	assert.Nil(t, res[1].ChangePercent)
+}
+
+func TestNewPeeringEntry(t *testing.T) {
+	assert.Equal(t, &PeeringEntry{NeighborASN: 64500}, newPeeringEntry(peeringByNeighbor, "64500", ""))
+}
@@ -0,0 +1,152 @@
+package frontend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// queryPlan collects the expressions of a query. Each expression is computed once: selecting it again under another
+// name and filtering on it refer to the alias it was first selected as.
+type queryPlan struct {
+	table      string
+	slot       uint64 // seconds covered by each time slot t, rates are averaged over them
+	selects    []string
+	aliases    map[string]string
+	names      map[string]struct{}
+	prewhere   []string
+	conditions []string
+	groupBy    []string
+	joins      []*Join
+	protocols  *protocolPreference
+}
+
+func newQueryPlan() *queryPlan {
+	return &queryPlan{
+		table:      "flows",
+		slot:       10,
+		selects:    make([]string, 0),
+		aliases:    make(map[string]string),
+		names:      make(map[string]struct{}),
+		prewhere:   make([]string, 0),
+		conditions: make([]string, 0),
+		groupBy:    make([]string, 0),
+	}
+}
+
+// column selects expr as alias. Selecting an alias twice is a no-op, which makes repeated breakdown fields harmless.
+func (p *queryPlan) column(expr string, alias string) bool {
+	if _, exists := p.names[alias]; exists {
+		return false
+	}
+	p.names[alias] = struct{}{}
+
+	if a, exists := p.aliases[expr]; exists {
+		p.selects = append(p.selects, fmt.Sprintf("%s as %s", a, alias))
+		return true
+	}
+
+	p.aliases[expr] = alias
+	p.selects = append(p.selects, fmt.Sprintf("%s as %s", expr, alias))
+	return true
+}
+
+// aggregate selects an aggregate expression, which can not be referred to in conditions
+func (p *queryPlan) aggregate(expr string, alias string) {
+	p.names[alias] = struct{}{}
+	p.selects = append(p.selects, fmt.Sprintf("%s AS %s", expr, alias))
+}
+
+// ref gets the alias of expr if it is selected, expr otherwise
+func (p *queryPlan) ref(expr string) string {
+	if p == nil {
+		return expr
+	}
+
+	if a, exists := p.aliases[expr]; exists {
+		return a
+	}
+
+	return expr
+}
+
+func (p *queryPlan) where(conditions ...string) {
+	for _, c := range conditions {
+		if c != "" {
+			p.conditions = append(p.conditions, c)
+		}
+	}
+}
+
+// prewhereCondition adds a condition which is evaluated before the other columns are read. It must only refer to
+// columns of the flows table.
+func (p *queryPlan) prewhereCondition(c string) {
+	if c != "" {
+		p.prewhere = append(p.prewhere, c)
+	}
+}
+
+// join adds the table of j to the query and gets the alias column is joined as
+func (p *queryPlan) join(j *Join, column string) string {
+	i := 0
+	for i < len(p.joins) && p.joins[i] != j {
+		i++
+	}
+	if i == len(p.joins) {
+		p.joins = append(p.joins, j)
+	}
+
+	return fmt.Sprintf("j%d_%s", i, column)
+}
+
+// joinClauses gets the LEFT JOIN clauses of the joined tables. Only the key and columns of the joined tables are read,
+// so their names can not clash with those of the flows table.
+func (p *queryPlan) joinClauses(db string) string {
+	res := ""
+	for i, j := range p.joins {
+		selects := []string{fmt.Sprintf("%s AS j%d_%s", j.Key, i, j.Key)}
+		for _, c := range j.Columns {
+			if c != j.Key {
+				selects = append(selects, fmt.Sprintf("%s AS j%d_%s", c, i, c))
+			}
+		}
+
+		table := j.Table
+		if !strings.Contains(table, ".") {
+			table = db + "." + table
+		}
+
+		res += fmt.Sprintf(" LEFT JOIN (SELECT %s FROM %s) AS j%d ON %s = j%d_%s", strings.Join(selects, ", "), table, i, resolveVirtualField(j.Field), i, j.Key)
+	}
+
+	return res
+}
+
+func (p *queryPlan) group(alias string) {
+	p.groupBy = append(p.groupBy, alias)
+}
+
+// sql gets the query of the plan on the table (flows unless the rollups are queried) of db
+func (p *queryPlan) sql(db string, suffix string) string {
+	prewhere := ""
+	if len(p.prewhere) > 0 {
+		prewhere = " PREWHERE " + strings.Join(p.prewhere, " AND ")
+	}
+
+	conditions := p.conditions
+	if p.protocols != nil {
+		conditions = append(append(make([]string, 0, len(conditions)+1), conditions...), p.protocols.condition(db, p.table))
+	}
+
+	return fmt.Sprintf("SELECT %s FROM %s.%s%s%s WHERE %s GROUP BY %s %s", strings.Join(p.selects, ", "), db, p.table, p.joinClauses(db), prewhere, strings.Join(conditions, " AND "), strings.Join(p.groupBy, ", "), suffix)
+}
+
+// isPrewhereField checks whether conditions on a field are selective enough to be evaluated in PREWHERE. This holds
+// for addresses and ports matching few flows of a partition, but not e.g. for the agent or the protocol.
+func isPrewhereField(fieldName string) bool {
+	switch fieldName {
+	case "src_ip_addr", "dst_ip_addr", "nexthop", "inner_src_ip_addr", "inner_dst_ip_addr", "src_mac", "dst_mac", "src_port", "dst_port":
+		return true
+	}
+
+	return false
+}
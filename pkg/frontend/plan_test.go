@@ -0,0 +1,71 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanQuery(t *testing.T) {
+	fe := &Frontend{
+		dictCfgs: Dicts{
+			{Field: "int_in", Dict: "flowhouse.interfaces_dict", Expr: "tuple(IPv6NumToString(%s), %s)", Keys: []string{"agent", "int_in"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{
+			name:     "Plain fields",
+			query:    "breakdown=src_asn&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&src_asn=65000&dst_asn=65001",
+			expected: "SELECT timestamp as t, src_asn as src_asn, sum(size * samplerate) * 8 / 10 / 1000000 AS mbps FROM db.flows WHERE t BETWEEN toDateTime(1704067200) AND toDateTime(1704070800) AND dst_asn = '65001' AND src_asn = '65000' GROUP BY t, src_asn ORDER BY mbps DESC LIMIT 10000",
+		},
+		{
+			name:     "Selective conditions",
+			query:    "breakdown=src_ip_addr&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&src_ip_addr=192.0.2.1&src_ip_addr=2001:db8::1&dst_port=443&ip_protocol=6",
+			expected: "SELECT timestamp as t, src_ip_addr as src_ip_addr, sum(size * samplerate) * 8 / 10 / 1000000 AS mbps FROM db.flows PREWHERE dst_port = '443' AND src_ip_addr IN (IPv4ToIPv6(IPv4StringToNum('192.0.2.1')), IPv6StringToNum('2001:db8::1')) WHERE t BETWEEN toDateTime(1704067200) AND toDateTime(1704070800) AND ip_protocol = '6' GROUP BY t, src_ip_addr ORDER BY mbps DESC LIMIT 10000",
+		},
+		{
+			name:     "Duplicate breakdown",
+			query:    "breakdown=src_asn&breakdown=src_asn&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00",
+			expected: "SELECT timestamp as t, src_asn as src_asn, sum(size * samplerate) * 8 / 10 / 1000000 AS mbps FROM db.flows WHERE t BETWEEN toDateTime(1704067200) AND toDateTime(1704070800) GROUP BY t, src_asn ORDER BY mbps DESC LIMIT 10000",
+		},
+		{
+			name:     "Filter on selected dict lookup",
+			query:    "breakdown=int_in__name&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&int_in__name=et-0/0/0",
+			expected: "SELECT timestamp as t, dictGet('flowhouse.interfaces_dict', 'name', tuple(IPv6NumToString(agent), int_in)) as int_in__name, sum(size * samplerate) * 8 / 10 / 1000000 AS mbps FROM db.flows WHERE t BETWEEN toDateTime(1704067200) AND toDateTime(1704070800) AND int_in__name = 'et-0/0/0' GROUP BY t, int_in__name ORDER BY mbps DESC LIMIT 10000",
+		},
+	}
+
+	for _, test := range tests {
+		fields, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatalf("Invalid query for test %q: %v", test.name, err)
+		}
+
+		p, err := fe.planQuery(fields)
+		if err != nil {
+			t.Fatalf("Unexpected error for test %q: %v", test.name, err)
+		}
+
+		assert.Equal(t, test.expected, p.sql("db", "ORDER BY mbps DESC LIMIT 10000"), test.name)
+	}
+}
+
+func TestQueryPlanColumn(t *testing.T) {
+	p := newQueryPlan()
+	assert.True(t, p.column("dictGet('d', 'name', int_in)", "a"))
+	assert.True(t, p.column("dictGet('d', 'name', int_in)", "b"))
+	assert.False(t, p.column("int_out", "a"))
+
+	assert.Equal(t, []string{"dictGet('d', 'name', int_in) as a", "a as b"}, p.selects)
+	assert.Equal(t, "a", p.ref("dictGet('d', 'name', int_in)"))
+	assert.Equal(t, "int_out", p.ref("int_out"))
+
+	var none *queryPlan
+	assert.Equal(t, "int_out", none.ref("int_out"))
+}
@@ -0,0 +1,38 @@
+package frontend
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+)
+
+// protocolPreference drops the flows of agents exporting both sFlow and IPFIX, so their traffic is not counted twice
+type protocolPreference struct {
+	preferred string
+	other     string
+	start     int64
+	end       int64
+}
+
+// getProtocolPreference gets the protocol preference given by the prefer_protocol parameter, nil if not set
+func getProtocolPreference(fields url.Values, start int64, end int64) (*protocolPreference, error) {
+	preferred := fields.Get("prefer_protocol")
+	switch preferred {
+	case "":
+		return nil, nil
+	case flow.ExportProtocolSFlow:
+		return &protocolPreference{preferred: preferred, other: flow.ExportProtocolIPFIX, start: start, end: end}, nil
+	case flow.ExportProtocolIPFIX:
+		return &protocolPreference{preferred: preferred, other: flow.ExportProtocolSFlow, start: start, end: end}, nil
+	}
+
+	return nil, fmt.Errorf("Invalid prefer_protocol %q", preferred)
+}
+
+// condition gets the condition dropping the flows of the other protocol from agents which exported flows with the
+// preferred protocol in the time range
+func (pp *protocolPreference) condition(db string, table string) string {
+	return fmt.Sprintf("(export_protocol != '%s' OR agent NOT IN (SELECT DISTINCT agent FROM %s.%s WHERE timestamp BETWEEN toDateTime(%d) AND toDateTime(%d) AND export_protocol = '%s'))",
+		pp.other, db, table, pp.start, pp.end, pp.preferred)
+}
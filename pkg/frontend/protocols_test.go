@@ -0,0 +1,23 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanQueryPreferProtocol(t *testing.T) {
+	fe := &Frontend{}
+
+	fields, err := url.ParseQuery("breakdown=export_protocol&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&prefer_protocol=ipfix")
+	assert.NoError(t, err)
+
+	p, err := fe.planQuery(fields)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT timestamp as t, export_protocol as export_protocol, sum(size * samplerate) * 8 / 10 / 1000000 AS mbps FROM db.flows WHERE t BETWEEN toDateTime(1704067200) AND toDateTime(1704070800) AND (export_protocol != 'sflow' OR agent NOT IN (SELECT DISTINCT agent FROM db.flows WHERE timestamp BETWEEN toDateTime(1704067200) AND toDateTime(1704070800) AND export_protocol = 'ipfix')) GROUP BY t, export_protocol ORDER BY mbps DESC LIMIT 10000", p.sql("db", "ORDER BY mbps DESC LIMIT 10000"))
+
+	fields.Set("prefer_protocol", "netflow")
+	_, err = fe.planQuery(fields)
+	assert.Error(t, err)
+}
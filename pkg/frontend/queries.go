@@ -0,0 +1,72 @@
+package frontend
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/requestid"
+	"github.com/sirupsen/logrus"
+)
+
+// KillResult is the response to killing queries
+type KillResult struct {
+	Killed int `json:"killed"`
+}
+
+// runningQueriesHandler handles requests for /admin/queries. GET lists the running queries of the frontend, DELETE
+// kills the query given by query_id or all queries of the request given by request_id.
+func (fe *Frontend) runningQueriesHandler(w http.ResponseWriter, r *http.Request) {
+	l := requestLogger(r)
+
+	var res interface{}
+	switch r.Method {
+	case http.MethodGet:
+		queries, err := fe.chgw.RunningQueries()
+		if err != nil {
+			l.WithError(err).Error("Unable to get running queries")
+			httpError(w, r, "Unable to get running queries", http.StatusInternalServerError)
+			return
+		}
+
+		res = queries
+	case http.MethodDelete:
+		var n int
+		var err error
+		queryID, requestID := r.URL.Query().Get("query_id"), r.URL.Query().Get("request_id")
+		switch {
+		case queryID != "" && clickhousegw.ValidQueryID(queryID):
+			n, err = fe.chgw.KillQuery(queryID)
+		case queryID == "" && requestid.Valid(requestID):
+			n, err = fe.chgw.KillRequest(requestID)
+		default:
+			httpError(w, r, "Missing or invalid query_id or request_id", http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			l.WithError(err).Error("Unable to kill queries")
+			httpError(w, r, "Unable to kill queries", http.StatusInternalServerError)
+			return
+		}
+
+		l.WithFields(logrus.Fields{
+			"killed":          n,
+			"kill_query_id":   queryID,
+			"kill_request_id": requestID,
+		}).Info("Killed queries")
+		res = &KillResult{Killed: n}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
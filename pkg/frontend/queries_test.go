@@ -0,0 +1,31 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunningQueriesHandlerInvalid(t *testing.T) {
+	fe := &Frontend{}
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		expected int
+	}{
+		{name: "No ID", method: http.MethodDelete, target: "/admin/queries", expected: http.StatusBadRequest},
+		{name: "Invalid query ID", method: http.MethodDelete, target: "/admin/queries?query_id=abc", expected: http.StatusBadRequest},
+		{name: "Invalid request ID", method: http.MethodDelete, target: "/admin/queries?request_id=a%27b", expected: http.StatusBadRequest},
+		{name: "Wrong method", method: http.MethodPost, target: "/admin/queries", expected: http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		fe.runningQueriesHandler(rec, httptest.NewRequest(test.method, test.target, nil))
+		assert.Equal(t, test.expected, rec.Code, test.name)
+	}
+}
@@ -6,17 +6,56 @@ import (
 	"io"
 	"sort"
 	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/chart"
+	"github.com/bio-routing/flowhouse/pkg/xlsx"
+)
+
+const (
+	// mbpsFormat shows the unit of rates in XLSX exports while keeping cells numeric
+	mbpsFormat = `#,##0" Mbps"`
+
+	// percentFormat shows shares in XLSX exports
+	percentFormat = `0.00"%"`
+
+	// xlsxSeriesLayout writes one sheet per series instead of a single pivoted sheet
+	xlsxSeriesLayout = "series"
 )
 
 type void struct{}
 
+// QueryResult is the result of a query with a series of values per breakdown key
+type QueryResult struct {
+	Unit string `json:"unit"`
+
+	// Resolution is the number of seconds covered by each timestamp
+	Resolution uint64 `json:"resolution,omitempty"`
+
+	// Downsampled is the method time slots were merged with if the result was downsampled
+	Downsampled string `json:"downsampled,omitempty"`
+
+	Timestamps []time.Time    `json:"timestamps"`
+	Series     []*QuerySeries `json:"series"`
+}
+
+// QuerySeries are the values of a breakdown key for each timestamp of a result
+type QuerySeries struct {
+	Name   string      `json:"name"`
+	Values []uint64    `json:"values"`
+	Meta   *SeriesMeta `json:"meta,omitempty"`
+}
+
 type result struct {
-	keys map[string]void
-	data map[time.Time]map[string]uint64 // timestamps -> keys -> values
+	unit        string // a key of resultUnits
+	resolution  uint64 // seconds covered by each timestamp
+	downsampled string // downsampling method
+	keys        map[string]void
+	data        map[time.Time]map[string]uint64 // timestamps -> keys -> values
 }
 
 func newResult() *result {
 	return &result{
+		unit: "Mbps",
 		keys: make(map[string]void),
 		data: make(map[time.Time]map[string]uint64),
 	}
@@ -31,7 +70,9 @@ func (r *result) add(ts time.Time, key string, value uint64) {
 	r.data[ts][key] = value
 }
 
-func (r *result) csv(w io.Writer) error {
+// csv writes the result with a row per timestamp and a column per key. Humanized values carry their unit and a SI
+// prefix (e.g. 1.23 Gbps) instead of being Mbps.
+func (r *result) csv(w io.Writer, humanized bool) error {
 	cw := csv.NewWriter(w)
 	defer cw.Flush()
 
@@ -52,6 +93,12 @@ func (r *result) csv(w io.Writer) error {
 		record = append(record, ts.Format(time.RFC3339))
 
 		for _, k := range keys {
+			if humanized {
+				u := resultUnits[r.unit]
+				record = append(record, humanize(float64(r.data[ts][k])*u.scale, u.base))
+				continue
+			}
+
 			record = append(record, fmt.Sprintf("%d", r.data[ts][k]))
 		}
 
@@ -64,6 +111,115 @@ func (r *result) csv(w io.Writer) error {
 	return nil
 }
 
+// xlsx writes the result as workbook. The default layout is one sheet with a row per timestamp and a column per series,
+// the series layout writes a sheet per series.
+func (r *result) xlsx(w io.Writer, layout string) error {
+	wb := xlsx.New()
+	keys := r.getKeysSorted()
+	timestamps := r.getTimestampsSorted()
+
+	if layout == xlsxSeriesLayout && len(keys) > 0 {
+		for _, k := range keys {
+			s := wb.AddSheet(k)
+			r.xlsxHeader(s, timestamps, []string{k})
+			for _, ts := range timestamps {
+				s.AddRow(xlsx.Time(ts, ""), r.xlsxValue(r.data[ts][k]))
+			}
+		}
+
+		return wb.Write(w)
+	}
+
+	s := wb.AddSheet("Flows")
+	r.xlsxHeader(s, timestamps, keys)
+	for _, ts := range timestamps {
+		row := []xlsx.Cell{xlsx.Time(ts, "")}
+		for _, k := range keys {
+			row = append(row, r.xlsxValue(r.data[ts][k]))
+		}
+
+		s.AddRow(row...)
+	}
+
+	return wb.Write(w)
+}
+
+// xlsxValue gets the cell of a value, shares are written in percent
+func (r *result) xlsxValue(v uint64) xlsx.Cell {
+	if r.unit == shareUnit {
+		return xlsx.Number(float64(v)*resultUnits[shareUnit].scale, percentFormat)
+	}
+
+	return xlsx.Number(float64(v), mbpsFormat)
+}
+
+// xlsxHeader adds the header row naming the time zone of the timestamps and the series
+func (r *result) xlsxHeader(s *xlsx.Sheet, timestamps []time.Time, keys []string) {
+	tz := "UTC"
+	if len(timestamps) > 0 {
+		tz = timestamps[0].Location().String()
+	}
+
+	header := []xlsx.Cell{xlsx.Header(fmt.Sprintf("Time (%s)", tz))}
+	s.SetColumnWidth(0, 20)
+	for i, k := range keys {
+		header = append(header, xlsx.Header(k))
+		s.SetColumnWidth(i+1, float64(max(len(k), 14)))
+	}
+
+	s.AddRow(header...)
+	s.FreezeHeader()
+}
+
+func (r *result) export() *QueryResult {
+	res := &QueryResult{
+		Unit:        r.unit,
+		Resolution:  r.resolution,
+		Downsampled: r.downsampled,
+		Timestamps:  r.getTimestampsSorted(),
+		Series:      make([]*QuerySeries, 0, len(r.keys)),
+	}
+
+	for _, k := range r.getKeysSorted() {
+		s := &QuerySeries{
+			Name:   k,
+			Values: make([]uint64, len(res.Timestamps)),
+		}
+
+		for i, ts := range res.Timestamps {
+			s.Values[i] = r.data[ts][k]
+		}
+
+		res.Series = append(res.Series, s)
+	}
+	res.Describe()
+
+	return res
+}
+
+// toChart adds the timestamps and a series per key to c. Shares are shown in percent.
+func (r *result) toChart(c *chart.Chart) {
+	scale := 1.0
+	if r.unit == shareUnit {
+		c.Unit = "%"
+		scale = resultUnits[shareUnit].scale
+	}
+
+	c.Timestamps = r.getTimestampsSorted()
+	for _, k := range r.getKeysSorted() {
+		s := chart.Series{
+			Name:   k,
+			Values: make([]float64, len(c.Timestamps)),
+		}
+
+		for i, ts := range c.Timestamps {
+			s.Values[i] = float64(r.data[ts][k]) * scale
+		}
+
+		c.Series = append(c.Series, s)
+	}
+}
+
 func (r *result) getKeysSorted() []string {
 	keys := make([]string, len(r.keys))
 	i := 0
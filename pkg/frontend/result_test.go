@@ -0,0 +1,122 @@
+package frontend
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultXLSX(t *testing.T) {
+	loc := time.FixedZone("CET", 3600)
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, loc)
+
+	res := newResult()
+	res.add(ts, "agent=rtr02", 20)
+	res.add(ts, "agent=rtr01", 10)
+	res.add(ts.Add(time.Minute), "agent=rtr01", 1500)
+
+	tests := []struct {
+		name     string
+		layout   string
+		expected map[string][]string
+	}{
+		{
+			name:   "Pivoted",
+			layout: "",
+			expected: map[string][]string{
+				"xl/workbook.xml": {`<sheet name="Flows" sheetId="1" r:id="rId1"/>`},
+				"xl/styles.xml":   {`formatCode="#,##0&#34; Mbps&#34;"`},
+				"xl/worksheets/sheet1.xml": {
+					`<t xml:space="preserve">Time (CET)</t>`,
+					`<c r="B1" s="1" t="inlineStr"><is><t xml:space="preserve">agent=rtr01</t></is></c>`,
+					`<c r="C1" s="1" t="inlineStr"><is><t xml:space="preserve">agent=rtr02</t></is></c>`,
+					`<c r="A2" s="2"><v>45292.5</v></c><c r="B2" s="3"><v>10</v></c><c r="C2" s="3"><v>20</v></c>`,
+					`<c r="B3" s="3"><v>1500</v></c><c r="C3" s="3"><v>0</v></c>`,
+				},
+			},
+		},
+		{
+			name:   "Sheet per series",
+			layout: "series",
+			expected: map[string][]string{
+				"xl/workbook.xml": {
+					`<sheet name="agent=rtr01" sheetId="1" r:id="rId1"/>`,
+					`<sheet name="agent=rtr02" sheetId="2" r:id="rId2"/>`,
+				},
+				"xl/worksheets/sheet1.xml": {
+					`<c r="B2" s="3"><v>10</v></c>`,
+					`<c r="B3" s="3"><v>1500</v></c>`,
+				},
+				"xl/worksheets/sheet2.xml": {
+					`<c r="B2" s="3"><v>20</v></c>`,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		buf := bytes.NewBuffer(nil)
+		err := res.xlsx(buf, test.layout)
+		if err != nil {
+			t.Fatalf("Unexpected error for test %q: %v", test.name, err)
+		}
+
+		files := unzip(t, buf.Bytes())
+		for name, contents := range test.expected {
+			for _, c := range contents {
+				assert.Contains(t, files[name], c, test.name)
+			}
+		}
+	}
+}
+
+func unzip(t *testing.T, b []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("Unable to read zip: %v", err)
+	}
+
+	res := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("Unable to open %s: %v", f.Name, err)
+		}
+
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("Unable to read %s: %v", f.Name, err)
+		}
+
+		res[f.Name] = string(data)
+	}
+
+	return res
+}
+
+func TestResultExport(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	res := newResult()
+	res.add(ts, "agent=rtr02", 20)
+	res.add(ts.Add(time.Minute), "agent=rtr01", 10)
+
+	assert.Equal(t, &QueryResult{
+		Unit:       "Mbps",
+		Timestamps: []time.Time{ts, ts.Add(time.Minute)},
+		Series: []*QuerySeries{
+			{Name: "agent=rtr01", Values: []uint64{0, 10}, Meta: &SeriesMeta{Unit: "bps", Scale: 1000000, Prefix: "M", Decimals: 1}},
+			{Name: "agent=rtr02", Values: []uint64{20, 0}, Meta: &SeriesMeta{Unit: "bps", Scale: 1000000, Prefix: "M", Decimals: 1}},
+		},
+	}, res.export())
+
+	assert.Equal(t, &QueryResult{
+		Unit:       "Mbps",
+		Timestamps: []time.Time{},
+		Series:     []*QuerySeries{},
+	}, newResult().export())
+}
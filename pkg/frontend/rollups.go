@@ -0,0 +1,56 @@
+package frontend
+
+import (
+	"net/url"
+	"time"
+)
+
+// useRollups checks whether a query starting at start has to be answered from the rollups. This is the case if raw
+// flows from start on may have expired and the rollups keep all fields the query breaks down by or filters on.
+// Proportional attribution and duration filters need raw flows.
+func (fe *Frontend) useRollups(fields url.Values, start int64, now time.Time, proportional bool) bool {
+	if fe.rollups == nil || proportional {
+		return false
+	}
+
+	if !fe.rollups.Needed(time.Unix(start, 0), now) {
+		return false
+	}
+
+	if fields.Get("duration_min") != "" || fields.Get("duration_max") != "" {
+		return false
+	}
+
+	for _, fieldName := range append(append([]string{}, fields["breakdown"]...), conditionFields(fields)...) {
+		if !fe.isRollupField(fieldName) {
+			log.WithField("field", fieldName).Debug("Field is not kept in the rollups, querying raw flows")
+			return false
+		}
+	}
+
+	return true
+}
+
+// isRollupField checks whether a field is a column of the rollups or a dict lookup on one, with all dict keys being
+// columns of the rollups
+func (fe *Frontend) isRollupField(fieldName string) bool {
+	flowsFieldName, relatedFieldsNames := parseFieldName(fieldName)
+	if fe.getComputedFields().get(flowsFieldName) != nil || !fe.rollups.HasColumn(flowsFieldName) {
+		return false
+	}
+
+	prefix := flowsFieldName
+	for _, relatedFieldsName := range relatedFieldsNames {
+		for _, d := range fe.getDictCfgs().getDicts(prefix) {
+			for _, k := range d.Keys {
+				if !fe.rollups.HasColumn(k) {
+					return false
+				}
+			}
+		}
+
+		prefix += "__" + relatedFieldsName
+	}
+
+	return true
+}
@@ -0,0 +1,108 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseRollups(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	fe := &Frontend{
+		rollups: &clickhousegw.Rollups{
+			Interval: 300,
+			RawDays:  14,
+		},
+		dictCfgs: Dicts{
+			{Field: "int_in", Dict: "flowhouse.interfaces_dict", Expr: "tuple(IPv6NumToString(%s), %s)", Keys: []string{"agent", "int_in"}},
+			{Field: "src_asn", Dict: "flowhouse.asns", Expr: "tuple(%s, %s)", Keys: []string{"src_asn", "src_ip_addr"}},
+		},
+		computedFields: ComputedFields{
+			{Name: "web", Expr: "dst_port IN (80, 443)"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		proportional bool
+		expected     bool
+	}{
+		{
+			name:     "Expired range",
+			query:    "breakdown=agent&breakdown=int_in__name&time_start=2024-01-01T00:00&time_end=2024-02-01T00:00&customer=acme",
+			expected: true,
+		},
+		{
+			name:     "Recent range",
+			query:    "breakdown=agent&time_start=2024-02-20T00:00&time_end=2024-02-21T00:00",
+			expected: false,
+		},
+		{
+			name:     "Field not kept",
+			query:    "breakdown=agent&time_start=2024-01-01T00:00&time_end=2024-02-01T00:00&dst_port=443",
+			expected: false,
+		},
+		{
+			name:     "Dict key not kept",
+			query:    "breakdown=src_asn__name&time_start=2024-01-01T00:00&time_end=2024-02-01T00:00",
+			expected: false,
+		},
+		{
+			name:     "Computed field",
+			query:    "breakdown=web&time_start=2024-01-01T00:00&time_end=2024-02-01T00:00",
+			expected: false,
+		},
+		{
+			name:     "Duration filter",
+			query:    "breakdown=agent&time_start=2024-01-01T00:00&time_end=2024-02-01T00:00&duration_min=1000",
+			expected: false,
+		},
+		{
+			name:         "Proportional attribution",
+			query:        "breakdown=agent&time_start=2024-01-01T00:00&time_end=2024-02-01T00:00",
+			proportional: true,
+			expected:     false,
+		},
+	}
+
+	for _, test := range tests {
+		fields, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatalf("Invalid query for test %q: %v", test.name, err)
+		}
+
+		start, _, err := getTimeRange(fields)
+		if err != nil {
+			t.Fatalf("Invalid time range for test %q: %v", test.name, err)
+		}
+
+		assert.Equal(t, test.expected, fe.useRollups(fields, start, now, test.proportional), test.name)
+	}
+
+	assert.False(t, (&Frontend{}).useRollups(url.Values{}, 0, now, false))
+}
+
+func TestPlanQueryRollups(t *testing.T) {
+	fe := &Frontend{
+		rollups: &clickhousegw.Rollups{
+			Interval: 300,
+			RawDays:  14,
+		},
+	}
+
+	fields, err := url.ParseQuery("breakdown=src_asn&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&customer=acme")
+	if err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+
+	p, err := fe.planQuery(fields)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assert.Equal(t, "SELECT timestamp as t, src_asn as src_asn, sum(bytes) * 8 / 300 / 1000000 AS mbps FROM db.flows_rollup WHERE t BETWEEN toDateTime(1704067200) AND toDateTime(1704070800) AND customer = 'acme' GROUP BY t, src_asn ORDER BY mbps DESC LIMIT 10000", p.sql("db", "ORDER BY mbps DESC LIMIT 10000"))
+}
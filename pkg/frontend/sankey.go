@@ -0,0 +1,134 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Sankey is traffic between the values of a source and a target field shaped for Sankey diagrams (e.g. d3-sankey).
+// Source and target values are separate nodes even if they are equal, links refer to nodes by index.
+type Sankey struct {
+	SourceField string        `json:"source_field"`
+	TargetField string        `json:"target_field"`
+	Nodes       []*SankeyNode `json:"nodes"`
+	Links       []*SankeyLink `json:"links"`
+}
+
+// SankeyNode is a value of the source or target field
+type SankeyNode struct {
+	Name  string `json:"name"`
+	Field string `json:"field"`
+}
+
+// SankeyLink is the average rate in Mbps from a source to a target node
+type SankeyLink struct {
+	Source int     `json:"source"`
+	Target int     `json:"target"`
+	Value  float64 `json:"value"`
+}
+
+// sankeyHandler serves the traffic from source to target values (default: source ASN to destination ASN) as JSON
+func (fe *Frontend) sankeyHandler(w http.ResponseWriter, r *http.Request) {
+	s, err := fe.processSankeyQuery(r.Context(), r.URL.Query())
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Unable to process sankey query")
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	j, err := json.Marshal(s)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func (fe *Frontend) processSankeyQuery(ctx context.Context, fields url.Values) (*Sankey, error) {
+	// a sankey diagram is a traffic matrix drawn differently, so it takes the same options
+	opts := url.Values{
+		"rows":    {fields.Get("source")},
+		"columns": {fields.Get("target")},
+		"top":     {fields.Get("top")},
+	}
+	sourceField, targetField, top, err := getMatrixOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if end <= start {
+		return nil, fmt.Errorf("Empty time range")
+	}
+
+	rows, err := fe.chgw.QueryContext(ctx, fe.matrixQuery(sourceField, targetField, start, end, fe.getFieldConditions(fields)))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	cells := make([]matrixCell, 0)
+	for rows.Next() {
+		var c matrixCell
+		err := rows.Scan(&c.row, &c.col, &c.bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		cells = append(cells, c)
+	}
+
+	return buildSankey(cells, sourceField, targetField, top, end-start), rows.Err()
+}
+
+// buildSankey keeps the top sources and targets by volume and links everything else to an "Others" node
+func buildSankey(cells []matrixCell, sourceField string, targetField string, top int, seconds int64) *Sankey {
+	m := buildMatrix(cells, top, seconds)
+
+	s := &Sankey{
+		SourceField: sourceField,
+		TargetField: targetField,
+		Nodes:       make([]*SankeyNode, 0, len(m.Rows)+len(m.Columns)),
+		Links:       make([]*SankeyLink, 0),
+	}
+
+	for _, r := range m.Rows {
+		s.Nodes = append(s.Nodes, &SankeyNode{Name: r, Field: sourceField})
+	}
+
+	for _, c := range m.Columns {
+		s.Nodes = append(s.Nodes, &SankeyNode{Name: c, Field: targetField})
+	}
+
+	for i := range m.Rows {
+		for j := range m.Columns {
+			if m.Values[i][j] == 0 {
+				continue
+			}
+
+			s.Links = append(s.Links, &SankeyLink{
+				Source: i,
+				Target: len(m.Rows) + j,
+				Value:  m.Values[i][j],
+			})
+		}
+	}
+
+	sort.SliceStable(s.Links, func(i, j int) bool {
+		return s.Links[i].Value > s.Links[j].Value
+	})
+
+	return s
+}
@@ -0,0 +1,36 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSankey(t *testing.T) {
+	cells := []matrixCell{
+		{row: "et-0/0/1", col: "et-0/0/2", bytes: 1000000},
+		{row: "et-0/0/1", col: "et-0/0/1", bytes: 3000000},
+		{row: "et-0/0/2", col: "et-0/0/2", bytes: 2000000},
+		{row: "et-0/0/3", col: "et-0/0/3", bytes: 500000},
+	}
+
+	s := buildSankey(cells, "int_in", "int_out", 2, 8)
+	assert.Equal(t, &Sankey{
+		SourceField: "int_in",
+		TargetField: "int_out",
+		Nodes: []*SankeyNode{
+			{Name: "et-0/0/1", Field: "int_in"},
+			{Name: "et-0/0/2", Field: "int_in"},
+			{Name: "Others", Field: "int_in"},
+			{Name: "et-0/0/1", Field: "int_out"},
+			{Name: "et-0/0/2", Field: "int_out"},
+			{Name: "Others", Field: "int_out"},
+		},
+		Links: []*SankeyLink{
+			{Source: 0, Target: 3, Value: 3},
+			{Source: 1, Target: 4, Value: 2},
+			{Source: 0, Target: 4, Value: 1},
+			{Source: 2, Target: 5, Value: 0.5},
+		},
+	}, s)
+}
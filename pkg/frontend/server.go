@@ -0,0 +1,120 @@
+package frontend
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// readHeaderTimeout limits slow clients. There is no write timeout as /tail streams for up to an hour.
+const readHeaderTimeout = 10 * time.Second
+
+// routes registers the handlers of the frontend. Handlers running potentially expensive queries are limited and
+// routed, /tail is not as it holds its request open for long and only polls for a few recent flows.
+func (fe *Frontend) routes() {
+	fe.HandleFunc("/", fe.indexHandler)
+	fe.HandleFunc("/flowhouse.js", fe.flowhouseJSHandler)
+	fe.HandleFunc("/query", fe.limited(fe.routed(fe.queryHandler)))
+	fe.HandleFunc("/chart", fe.limited(fe.routed(fe.chartHandler)))
+	fe.HandleFunc("/matrix", fe.limited(fe.routed(fe.matrixHandler)))
+	fe.HandleFunc("/sankey", fe.limited(fe.routed(fe.sankeyHandler)))
+	fe.HandleFunc("/peering", fe.limited(fe.routed(fe.peeringHandler)))
+	fe.HandleFunc("/conversations", fe.limited(fe.routed(fe.conversationsHandler)))
+	fe.HandleFunc("/top_talkers", fe.limited(fe.routed(fe.topTalkersHandler)))
+	fe.HandleFunc("/interface", fe.limited(fe.routed(fe.interfaceHandler)))
+	fe.HandleFunc("/heatmap", fe.limited(fe.routed(fe.heatmapHandler)))
+	fe.HandleFunc("/diff", fe.limited(fe.routed(fe.diffHandler)))
+	fe.HandleFunc("/table", fe.limited(fe.routed(fe.tableHandler)))
+	fe.HandleFunc("/tail", fe.tailHandler)
+	fe.HandleFunc("/dict_values/", fe.getDictValues)
+}
+
+// AdminRoutes registers the admin handlers of the frontend, which kill queries and change dicts. They are only to be
+// registered with authentication and AdminOnly in the middleware chain.
+func (fe *Frontend) AdminRoutes() {
+	fe.HandleFunc(AdminPrefix+"queries", fe.runningQueriesHandler)
+	fe.HandleFunc(AdminPrefix+"dicts", fe.dictsHandler)
+	fe.HandleFunc(AdminPrefix+"dicts/reload", fe.reloadDictsHandler)
+}
+
+// routed lets the queries of h go to an analytics replica if the request spans a long time range or is an export
+func (fe *Frontend) routed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h(w, r.WithContext(fe.route(r.Context(), r.URL.Query())))
+	}
+}
+
+// route marks ctx for the analytics replicas depending on the time range and format of a query
+func (fe *Frontend) route(ctx context.Context, fields url.Values) context.Context {
+	if fe.chgw == nil {
+		return ctx
+	}
+
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return ctx
+	}
+
+	format := fields.Get("format")
+	return fe.chgw.Route(ctx, end-start, format == "csv" || format == "xlsx")
+}
+
+// Handle registers a handler for pattern (see http.ServeMux)
+func (fe *Frontend) Handle(pattern string, h http.Handler) {
+	fe.mux.Handle(pattern, h)
+}
+
+// HandleFunc registers a handler function for pattern (see http.ServeMux)
+func (fe *Frontend) HandleFunc(pattern string, h func(http.ResponseWriter, *http.Request)) {
+	fe.mux.HandleFunc(pattern, h)
+}
+
+// Use appends middleware wrapping all handlers. The middleware added first sees requests first.
+func (fe *Frontend) Use(mw ...Middleware) {
+	fe.middleware = append(fe.middleware, mw...)
+}
+
+// Handler gets the handler serving all routes through the middleware chain
+func (fe *Frontend) Handler() http.Handler {
+	var h http.Handler = fe.mux
+	for i := len(fe.middleware) - 1; i >= 0; i-- {
+		h = fe.middleware[i](h)
+	}
+
+	return h
+}
+
+// Start listens on addr and serves requests in the background. Routes and middleware must be set up before.
+func (fe *Frontend) Start(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "Unable to listen")
+	}
+
+	fe.server = &http.Server{
+		Handler:           fe.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	go func() {
+		err := fe.server.Serve(ln)
+		if err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("HTTP server failed")
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops accepting requests and waits for running requests until ctx is done
+func (fe *Frontend) Stop(ctx context.Context) error {
+	if fe.server == nil {
+		return nil
+	}
+
+	return fe.server.Shutdown(ctx)
+}
@@ -0,0 +1,80 @@
+package frontend
+
+import (
+	"math"
+	"net/url"
+	"time"
+)
+
+const (
+	// shareUnit is the unit of results normalized to the share of each key of the total traffic per time slot
+	shareUnit = "bp"
+
+	// normalizeShare is the normalize option turning rates into shares
+	normalizeShare = "share"
+)
+
+// ShareRequested checks whether a query asks for the share of each key of the total instead of rates (normalize=share)
+func ShareRequested(fields url.Values) bool {
+	return fields.Get("normalize") == normalizeShare
+}
+
+// shares collects the rates of a query to turn them into the share of each key of the total rate per time slot. Rates
+// are kept as floats until the shares are computed, so keys of less than a Mbps still get their share.
+type shares struct {
+	data   map[time.Time]map[string]float64
+	totals map[time.Time]float64
+}
+
+func newShares() *shares {
+	return &shares{
+		data:   make(map[time.Time]map[string]float64),
+		totals: make(map[time.Time]float64),
+	}
+}
+
+func (s *shares) add(ts time.Time, key string, mbps float64) {
+	if _, exists := s.data[ts]; !exists {
+		s.data[ts] = make(map[string]float64)
+	}
+
+	s.data[ts][key] += mbps
+	s.totals[ts] += mbps
+}
+
+// result gets the shares in hundredths of a percent. The shares of each time slot add up to 100% (up to rounding).
+func (s *shares) result() *result {
+	res := newResult()
+	res.unit = shareUnit
+	for ts, keys := range s.data {
+		for k, mbps := range keys {
+			share := uint64(0)
+			if s.totals[ts] > 0 {
+				share = uint64(math.Round(mbps / s.totals[ts] * 10000))
+			}
+
+			res.add(ts, k, share)
+		}
+	}
+
+	return res
+}
+
+// Share turns the rates of r into the share of each series of the total per timestamp, e.g. after merging the results
+// of several collectors. Rates are whole Mbps then, so series of less than a Mbps get no share.
+func (r *QueryResult) Share() {
+	for i := range r.Timestamps {
+		total := uint64(0)
+		for _, s := range r.Series {
+			total += s.Values[i]
+		}
+
+		for _, s := range r.Series {
+			if total > 0 {
+				s.Values[i] = uint64(math.Round(float64(s.Values[i]) / float64(total) * 10000))
+			}
+		}
+	}
+
+	r.Unit = shareUnit
+}
@@ -0,0 +1,63 @@
+package frontend
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/chart"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShares(t *testing.T) {
+	t1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Minute)
+
+	s := newShares()
+	s.add(t1, "agent=rtr01", 0.25)
+	s.add(t1, "agent=rtr02", 0.5)
+	s.add(t1, "Others", 0.25)
+	s.add(t2, "agent=rtr01", 3)
+	s.add(t2, "Others", 0)
+
+	res := s.result().export()
+	assert.Equal(t, shareUnit, res.Unit)
+	assert.Equal(t, []time.Time{t1, t2}, res.Timestamps)
+	assert.Equal(t, []uint64{2500, 0}, res.Series[0].Values)
+	assert.Equal(t, []uint64{2500, 10000}, res.Series[1].Values)
+	assert.Equal(t, []uint64{5000, 0}, res.Series[2].Values)
+	assert.Equal(t, &SeriesMeta{Unit: "%", Scale: 0.01, Prefix: "", Decimals: 1}, res.Series[2].Meta)
+}
+
+func TestShareResultFormats(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := newShares()
+	s.add(ts, "agent=rtr01", 1)
+	s.add(ts, "agent=rtr02", 2)
+	res := s.result()
+
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, res.csv(buf, true))
+	assert.Equal(t, "timestamp,agent=rtr01,agent=rtr02\n2024-01-01T12:00:00Z,33.3 %,66.7 %\n", buf.String())
+
+	c := &chart.Chart{Unit: "Mbps"}
+	res.toChart(c)
+	assert.Equal(t, "%", c.Unit)
+	assert.Equal(t, []float64{33.33}, c.Series[0].Values)
+}
+
+func TestQueryResultShare(t *testing.T) {
+	r := &QueryResult{
+		Unit:       "Mbps",
+		Timestamps: []time.Time{time.Unix(1700000000, 0), time.Unix(1700000060, 0)},
+		Series: []*QuerySeries{
+			{Name: "a", Values: []uint64{1, 0}},
+			{Name: "b", Values: []uint64{3, 0}},
+		},
+	}
+
+	r.Share()
+	assert.Equal(t, shareUnit, r.Unit)
+	assert.Equal(t, []uint64{2500, 0}, r.Series[0].Values)
+	assert.Equal(t, []uint64{7500, 0}, r.Series[1].Values)
+}
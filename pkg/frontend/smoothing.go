@@ -0,0 +1,99 @@
+package frontend
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// smoothSimple averages each value with the preceding values of the window
+	smoothSimple = "sma"
+
+	// smoothExponential weights preceding values exponentially less, with a = 2 / (window + 1)
+	smoothExponential = "ema"
+
+	defaultSmoothWindow = 5
+	maxSmoothWindow     = 100
+)
+
+// smoothing is a moving average applied to the series of a result
+type smoothing struct {
+	kind   string
+	window int
+}
+
+// getSmoothing gets the moving average given by the smooth (sma or ema) and smooth_window (number of time slots,
+// default 5) parameters, nil if not set
+func getSmoothing(fields url.Values) (*smoothing, error) {
+	kind := fields.Get("smooth")
+	if kind == "" {
+		return nil, nil
+	}
+
+	if kind != smoothSimple && kind != smoothExponential {
+		return nil, fmt.Errorf("Invalid smooth %q", kind)
+	}
+
+	s := &smoothing{
+		kind:   kind,
+		window: defaultSmoothWindow,
+	}
+
+	if w := fields.Get("smooth_window"); w != "" {
+		window, err := strconv.Atoi(w)
+		if err != nil || window <= 0 || window > maxSmoothWindow {
+			return nil, fmt.Errorf("Invalid smooth_window %q", w)
+		}
+		s.window = window
+	}
+
+	return s, nil
+}
+
+// apply smooths the values of a series, which are ordered by time. Values are rounded to keep the unit of the series.
+func (s *smoothing) apply(values []float64) []uint64 {
+	res := make([]uint64, len(values))
+	switch s.kind {
+	case smoothSimple:
+		sum := 0.0
+		for i, v := range values {
+			sum += v
+			if i >= s.window {
+				sum -= values[i-s.window]
+			}
+
+			res[i] = uint64(math.Round(sum / float64(min(i+1, s.window))))
+		}
+	case smoothExponential:
+		a := 2 / float64(s.window+1)
+		avg := 0.0
+		for i, v := range values {
+			if i == 0 {
+				avg = v
+			} else {
+				avg = a*v + (1-a)*avg
+			}
+
+			res[i] = uint64(math.Round(avg))
+		}
+	}
+
+	return res
+}
+
+// smooth applies s to every series of r. Time slots a series has no value in count as 0.
+func (r *result) smooth(s *smoothing) {
+	timestamps := r.getTimestampsSorted()
+	for k := range r.keys {
+		values := make([]float64, len(timestamps))
+		for i, ts := range timestamps {
+			values[i] = float64(r.data[ts][k])
+		}
+
+		for i, v := range s.apply(values) {
+			r.data[timestamps[i]][k] = v
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSmoothing(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected *smoothing
+		wantFail bool
+	}{
+		{query: ""},
+		{query: "smooth=sma", expected: &smoothing{kind: smoothSimple, window: 5}},
+		{query: "smooth=ema&smooth_window=3", expected: &smoothing{kind: smoothExponential, window: 3}},
+		{query: "smooth=median", wantFail: true},
+		{query: "smooth=sma&smooth_window=0", wantFail: true},
+		{query: "smooth=sma&smooth_window=101", wantFail: true},
+	}
+
+	for _, test := range tests {
+		fields, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatalf("Invalid query %q: %v", test.query, err)
+		}
+
+		s, err := getSmoothing(fields)
+		assert.Equal(t, test.wantFail, err != nil, test.query)
+		assert.Equal(t, test.expected, s, test.query)
+	}
+}
+
+func TestSmoothingApply(t *testing.T) {
+	values := []float64{10, 20, 30, 0, 0}
+
+	assert.Equal(t, []uint64{10, 15, 20, 17, 10}, (&smoothing{kind: smoothSimple, window: 3}).apply(values))
+	assert.Equal(t, []uint64{10, 15, 23, 11, 6}, (&smoothing{kind: smoothExponential, window: 3}).apply(values))
+	assert.Equal(t, []uint64{10, 20, 30, 0, 0}, (&smoothing{kind: smoothSimple, window: 1}).apply(values))
+}
+
+func TestResultSmooth(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	res := newResult()
+	res.add(ts, "agent=rtr01", 10)
+	res.add(ts.Add(time.Minute), "agent=rtr01", 30)
+	res.add(ts.Add(2*time.Minute), "agent=rtr02", 6)
+
+	res.smooth(&smoothing{kind: smoothSimple, window: 2})
+	r := res.export()
+	assert.Equal(t, []uint64{10, 20, 15}, r.Series[0].Values)
+	assert.Equal(t, []uint64{0, 0, 3}, r.Series[1].Values)
+}
@@ -0,0 +1,78 @@
+package frontend
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSubnetLenV4 = 24
+	defaultSubnetLenV6 = 48
+)
+
+// isSubnetField checks if a field aggregates source or destination addresses to subnets
+func isSubnetField(fieldName string) bool {
+	return fieldName == "src_subnet" || fieldName == "dst_subnet"
+}
+
+// subnetAddrField gets the address field a subnet field aggregates
+func subnetAddrField(fieldName string) string {
+	return strings.TrimSuffix(fieldName, "_subnet") + "_ip_addr"
+}
+
+// getSubnetLengths returns the prefix lengths of IPv4 and IPv6 subnets given by the subnet_v4 (default 24) and
+// subnet_v6 (default 48) parameters
+func getSubnetLengths(fields url.Values) (int, int, error) {
+	v4, v6 := defaultSubnetLenV4, defaultSubnetLenV6
+	for _, f := range []struct {
+		param string
+		max   int
+		len   *int
+	}{
+		{param: "subnet_v4", max: 32, len: &v4},
+		{param: "subnet_v6", max: 128, len: &v6},
+	} {
+		v := fields.Get(f.param)
+		if v == "" {
+			continue
+		}
+
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 0 || l > f.max {
+			return 0, 0, fmt.Errorf("Invalid %s %q", f.param, v)
+		}
+		*f.len = l
+	}
+
+	return v4, v6, nil
+}
+
+// subnetExpr returns an expression for the subnet of the address of a subnet field. IPv4 addresses are stored mapped
+// to IPv6, their subnets are shown with the IPv4 prefix length.
+func subnetExpr(fieldName string, v4 int, v6 int) string {
+	addr := subnetAddrField(fieldName)
+	ipv4 := fmt.Sprintf("%s BETWEEN toIPv6('::ffff:0.0.0.0') AND toIPv6('::ffff:255.255.255.255')", addr)
+
+	return fmt.Sprintf("concat(IPv6NumToString(IPv6CIDRToRange(%s, if(%s, %d, %d)).1), '/', toString(if(%s, %d, %d)))",
+		addr, ipv4, 96+v4, v6, ipv4, v4, v6)
+}
+
+// formatSubnetCondition matches the addresses of a subnet field within the prefix p
+func formatSubnetCondition(fieldName string, p string) (string, error) {
+	pfx, err := netip.ParsePrefix(p)
+	if err != nil {
+		return "", err
+	}
+	pfx = pfx.Masked()
+
+	last := pfx.Addr().AsSlice()
+	for i := pfx.Bits(); i < len(last)*8; i++ {
+		last[i/8] |= 1 << (7 - i%8)
+	}
+	lastAddr, _ := netip.AddrFromSlice(last)
+
+	return fmt.Sprintf("%s BETWEEN %s AND %s", subnetAddrField(fieldName), formatIPCondition(pfx.Addr().String()), formatIPCondition(lastAddr.String())), nil
+}
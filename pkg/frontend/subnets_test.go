@@ -0,0 +1,115 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSubnetLengths(t *testing.T) {
+	tests := []struct {
+		name       string
+		fields     url.Values
+		expectedV4 int
+		expectedV6 int
+		wantFail   bool
+	}{
+		{
+			name:       "Defaults",
+			fields:     url.Values{},
+			expectedV4: 24,
+			expectedV6: 48,
+		},
+		{
+			name:       "Custom",
+			fields:     url.Values{"subnet_v4": {"16"}, "subnet_v6": {"64"}},
+			expectedV4: 16,
+			expectedV6: 64,
+		},
+		{
+			name:     "IPv4 too long",
+			fields:   url.Values{"subnet_v4": {"33"}},
+			wantFail: true,
+		},
+		{
+			name:     "Invalid",
+			fields:   url.Values{"subnet_v6": {"/48"}},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		v4, v6, err := getSubnetLengths(test.fields)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expectedV4, v4, test.name)
+		assert.Equal(t, test.expectedV6, v6, test.name)
+	}
+}
+
+func TestSubnetExpr(t *testing.T) {
+	ipv4 := "src_ip_addr BETWEEN toIPv6('::ffff:0.0.0.0') AND toIPv6('::ffff:255.255.255.255')"
+	assert.Equal(t, "concat(IPv6NumToString(IPv6CIDRToRange(src_ip_addr, if("+ipv4+", 120, 48)).1), '/', toString(if("+ipv4+", 24, 48)))",
+		subnetExpr("src_subnet", 24, 48))
+}
+
+func TestFormatSubnetCondition(t *testing.T) {
+	tests := []struct {
+		name      string
+		fieldName string
+		pfx       string
+		expected  string
+		wantFail  bool
+	}{
+		{
+			name:      "IPv4",
+			fieldName: "src_subnet",
+			pfx:       "192.0.2.0/24",
+			expected:  "src_ip_addr BETWEEN IPv4ToIPv6(IPv4StringToNum('192.0.2.0')) AND IPv4ToIPv6(IPv4StringToNum('192.0.2.255'))",
+		},
+		{
+			name:      "IPv6 with host bits",
+			fieldName: "dst_subnet",
+			pfx:       "2001:db8:1::1/48",
+			expected:  "dst_ip_addr BETWEEN IPv6StringToNum('2001:db8:1::') AND IPv6StringToNum('2001:db8:1:ffff:ffff:ffff:ffff:ffff')",
+		},
+		{
+			name:      "Invalid",
+			fieldName: "dst_subnet",
+			pfx:       "2001:db8::",
+			wantFail:  true,
+		},
+	}
+
+	for _, test := range tests {
+		c, err := formatSubnetCondition(test.fieldName, test.pfx)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, c, test.name)
+	}
+}
+
+func TestPlanQuerySubnets(t *testing.T) {
+	fe := &Frontend{}
+	p, err := fe.planQuery(url.Values{
+		"breakdown":  {"dst_subnet"},
+		"time_start": {"2024-01-01T00:00"},
+		"time_end":   {"2024-01-01T01:00"},
+		"subnet_v6":  {"56"},
+		"src_subnet": {"192.0.2.0/24", "198.51.100.0/24"},
+	})
+	assert.NoError(t, err)
+
+	assert.Contains(t, p.selects, subnetExpr("dst_subnet", 24, 56)+" as dst_subnet")
+	assert.Contains(t, p.conditions, "(src_ip_addr BETWEEN IPv4ToIPv6(IPv4StringToNum('192.0.2.0')) AND IPv4ToIPv6(IPv4StringToNum('192.0.2.255')) OR "+
+		"src_ip_addr BETWEEN IPv4ToIPv6(IPv4StringToNum('198.51.100.0')) AND IPv4ToIPv6(IPv4StringToNum('198.51.100.255')))")
+}
@@ -0,0 +1,226 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTableTop = 20
+	maxTableTop     = 1000
+
+	// maxTableRows limits the number of keys fetched, the share of the total is computed over them
+	maxTableRows = 10000
+)
+
+// QueryTable is the traffic per breakdown key summed up over a time range
+type QueryTable struct {
+	Start time.Time   `json:"start"`
+	End   time.Time   `json:"end"`
+	Total *TableRow   `json:"total"`
+	Rows  []*TableRow `json:"rows"`
+}
+
+// TableRow is the traffic of a breakdown key
+type TableRow struct {
+	Key     string  `json:"key,omitempty"`
+	Bytes   uint64  `json:"bytes"`
+	Packets uint64  `json:"packets"`
+	Flows   uint64  `json:"flows"`
+	Mbps    float64 `json:"mbps"`
+
+	// Percent is the share of the bytes of all keys
+	Percent float64 `json:"percent"`
+}
+
+// tableHandler serves the top breakdown keys of a time range by volume with their bytes, packets, flows, average rate
+// and share of the total as JSON. It takes the parameters of /query.
+func (fe *Frontend) tableHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := fe.processTableQuery(r.Context(), r.URL.Query())
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Unable to process table query")
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func (fe *Frontend) processTableQuery(ctx context.Context, fields url.Values) (*QueryTable, error) {
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if end <= start {
+		return nil, fmt.Errorf("Empty time range")
+	}
+
+	top := defaultTableTop
+	if t := fields.Get("top"); t != "" {
+		top, err = strconv.Atoi(t)
+		if err != nil || top <= 0 || top > maxTableTop {
+			return nil, fmt.Errorf("Invalid top %q", t)
+		}
+	}
+
+	q, err := fe.tableQuery(fields, fe.chgw.GetDatabaseName(), start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := fe.chgw.QueryContext(ctx, q)
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to get columns")
+	}
+
+	keys := len(columns) - 3
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range columns {
+		valuePtrs[i] = &values[i]
+	}
+
+	catalog := fe.catalog()
+	names := fe.getNames()
+	res := make(map[string]*TableRow)
+	for rows.Next() {
+		err := rows.Scan(valuePtrs...)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		counters := make([]uint64, 0, 3)
+		for _, v := range values[keys:] {
+			c, ok := v.(uint64)
+			if !ok {
+				return nil, fmt.Errorf("expected uint64 for the counter columns")
+			}
+			counters = append(counters, c)
+		}
+
+		k := formatKey(columns[:keys], values[:keys], catalog, names)
+		r, exists := res[k]
+		if !exists {
+			r = &TableRow{Key: k}
+			res[k] = r
+		}
+
+		r.Bytes += counters[0]
+		r.Packets += counters[1]
+		r.Flows += counters[2]
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+
+	t := buildTable(res, top, end-start)
+	t.Start = time.Unix(start, 0).UTC()
+	t.End = time.Unix(end, 0).UTC()
+	return t, nil
+}
+
+// tableQuery sums up the bytes, packets and flows per breakdown key between start and end. Flows are attributed to
+// the time slot they were exported in, which is all that matters for the totals of a range.
+func (fe *Frontend) tableQuery(fields url.Values, db string, start int64, end int64) (string, error) {
+	fields = cloneValues(fields)
+	fields.Del("attribution")
+
+	p, err := fe.planQueryRange(fields, start, end)
+	if err != nil {
+		return "", err
+	}
+
+	if len(p.groupBy) < 2 {
+		return "", fmt.Errorf("No breakdown field could be resolved")
+	}
+
+	if p.table == clickhousegw.RollupTableName {
+		p.aggregate("sum(bytes)", "slot_bytes")
+		p.aggregate("sum(packets)", "slot_packets")
+		p.aggregate("sum(flows)", "slot_flows")
+	} else {
+		p.aggregate("sum(size * samplerate)", "slot_bytes")
+		p.aggregate("sum(packets * samplerate)", "slot_packets")
+		p.aggregate("count()", "slot_flows")
+	}
+
+	keys := strings.Join(p.groupBy[1:], ", ")
+	return fmt.Sprintf("SELECT %s, sum(slot_bytes) AS bytes, sum(slot_packets) AS pkts, sum(slot_flows) AS flows FROM (%s) GROUP BY %s ORDER BY bytes DESC LIMIT %d",
+		keys, p.sql(db, ""), keys, maxTableRows), nil
+}
+
+// buildTable gets the top rows by bytes and the total of all rows. Rows with equal bytes are ordered by key.
+func buildTable(data map[string]*TableRow, top int, seconds int64) *QueryTable {
+	total := &TableRow{}
+	rows := make([]*TableRow, 0, len(data))
+	for _, r := range data {
+		total.Bytes += r.Bytes
+		total.Packets += r.Packets
+		total.Flows += r.Flows
+		rows = append(rows, r)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Bytes != rows[j].Bytes {
+			return rows[i].Bytes > rows[j].Bytes
+		}
+
+		return rows[i].Key < rows[j].Key
+	})
+
+	if len(rows) > top {
+		rows = rows[:top]
+	}
+
+	for _, r := range rows {
+		r.Mbps = float64(r.Bytes) * 8 / float64(seconds) / 1000000
+		if total.Bytes > 0 {
+			r.Percent = float64(r.Bytes) / float64(total.Bytes) * 100
+		}
+	}
+
+	total.Mbps = float64(total.Bytes) * 8 / float64(seconds) / 1000000
+	if total.Bytes > 0 {
+		total.Percent = 100
+	}
+
+	return &QueryTable{
+		Total: total,
+		Rows:  rows,
+	}
+}
+
+func cloneValues(v url.Values) url.Values {
+	res := make(url.Values, len(v))
+	for k, x := range v {
+		res[k] = append([]string(nil), x...)
+	}
+
+	return res
+}
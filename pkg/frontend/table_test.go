@@ -0,0 +1,60 @@
+package frontend
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableQuery(t *testing.T) {
+	fe := &Frontend{}
+	fields, err := url.ParseQuery("breakdown=src_asn&breakdown=dst_asn&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&attribution=proportional&ip_protocol=6")
+	if err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+
+	q, err := fe.tableQuery(fields, "db", 1704067200, 1704070800)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT src_asn, dst_asn, sum(slot_bytes) AS bytes, sum(slot_packets) AS pkts, sum(slot_flows) AS flows FROM ("+
+		"SELECT timestamp as t, src_asn as src_asn, dst_asn as dst_asn, sum(size * samplerate) * 8 / 10 / 1000000 AS mbps, sum(size * samplerate) AS slot_bytes, sum(packets * samplerate) AS slot_packets, count() AS slot_flows FROM db.flows WHERE t BETWEEN toDateTime(1704067200) AND toDateTime(1704070800) AND ip_protocol = '6' GROUP BY t, src_asn, dst_asn "+
+		") GROUP BY src_asn, dst_asn ORDER BY bytes DESC LIMIT 10000", q)
+	assert.Equal(t, "proportional", fields.Get("attribution"))
+
+	_, err = fe.tableQuery(url.Values{"breakdown": {"foo__bar"}}, "db", 1704067200, 1704070800)
+	assert.Error(t, err)
+}
+
+func TestBuildTable(t *testing.T) {
+	data := map[string]*TableRow{
+		"a": {Key: "a", Bytes: 4500000, Packets: 3000, Flows: 30},
+		"b": {Key: "b", Bytes: 4500000, Packets: 4000, Flows: 40},
+		"c": {Key: "c", Bytes: 1000000, Packets: 1000, Flows: 10},
+	}
+
+	res := buildTable(data, 2, 10)
+	assert.Equal(t, 2, len(res.Rows))
+	assert.Equal(t, "a", res.Rows[0].Key)
+	assert.Equal(t, "b", res.Rows[1].Key)
+	assert.Equal(t, float64(45), res.Rows[0].Percent)
+	assert.Equal(t, 3.6, res.Rows[0].Mbps)
+	assert.Equal(t, &TableRow{Bytes: 10000000, Packets: 8000, Flows: 80, Mbps: 8, Percent: 100}, res.Total)
+}
+
+func TestProcessTableQueryValidation(t *testing.T) {
+	fe := &Frontend{}
+	for _, query := range []string{
+		"breakdown=src_asn&time_start=2024-01-01T01:00&time_end=2024-01-01T00:00",
+		"breakdown=src_asn&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&top=0",
+		"breakdown=src_asn&time_start=2024-01-01T00:00&time_end=2024-01-01T01:00&top=1001",
+	} {
+		fields, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatalf("Invalid query %q: %v", query, err)
+		}
+
+		_, err = fe.processTableQuery(context.Background(), fields)
+		assert.Error(t, err, query)
+	}
+}
@@ -0,0 +1,225 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTailInterval = 5 * time.Second
+	defaultTailLimit    = 100
+	maxTailLimit        = 1000
+
+	// tailBacklog is how far back the first poll of a tail looks for flows
+	tailBacklog = time.Minute
+
+	// maxTailDuration ends tails that were forgotten in a browser tab
+	maxTailDuration = time.Hour
+)
+
+// TailFlow is a flow streamed by the live tail
+type TailFlow struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Agent      string    `json:"agent"`
+	IntIn      string    `json:"int_in"`
+	IntOut     string    `json:"int_out"`
+	SrcAddr    string    `json:"src_ip_addr"`
+	DstAddr    string    `json:"dst_ip_addr"`
+	Protocol   uint8     `json:"ip_protocol"`
+	SrcPort    uint16    `json:"src_port"`
+	DstPort    uint16    `json:"dst_port"`
+	TCPFlags   uint8     `json:"tcp_flags"`
+	Size       uint64    `json:"size"`
+	Packets    uint64    `json:"packets"`
+	Samplerate uint64    `json:"samplerate"`
+}
+
+type tailOptions struct {
+	interval time.Duration
+	limit    int
+}
+
+// tailHandler streams the newest flows matching the filter parameters as server-sent events, one flow per event. It
+// polls every interval seconds (default 5) for at most limit flows (default 100).
+func (fe *Frontend) tailHandler(w http.ResponseWriter, r *http.Request) {
+	l := requestLogger(r)
+	fields := r.URL.Query()
+	opts, err := parseTailOptions(fields)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpError(w, r, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	conditions := fe.getFieldConditions(fields)
+	cursor := newTailCursor(time.Now().Add(-tailBacklog).Unix())
+	deadline := time.After(maxTailDuration)
+	t := time.NewTicker(opts.interval)
+	defer t.Stop()
+
+	l.Debug("Starting tail")
+	for {
+		flows, err := fe.pollTail(r.Context(), conditions, cursor.second, opts.limit)
+		if err != nil {
+			l.WithError(err).Error("Tail query failed")
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", strings.Replace(withRequestID(r.Context(), err.Error()), "\n", " ", -1))
+			flusher.Flush()
+			return
+		}
+
+		for _, j := range cursor.unsent(flows) {
+			fmt.Fprintf(w, "data: %s\n\n", j)
+		}
+
+		// the comment keeps proxies from closing idle streams and lets us notice disconnected clients
+		_, err = fmt.Fprint(w, ": keepalive\n\n")
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline:
+			fmt.Fprint(w, "event: end\ndata: Maximum tail duration reached\n\n")
+			flusher.Flush()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func parseTailOptions(fields url.Values) (*tailOptions, error) {
+	opts := &tailOptions{
+		interval: defaultTailInterval,
+		limit:    defaultTailLimit,
+	}
+
+	if s := fields.Get("interval"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("Invalid interval %q", s)
+		}
+		opts.interval = time.Duration(n) * time.Second
+	}
+
+	if s := fields.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxTailLimit {
+			return nil, fmt.Errorf("Invalid limit %q (must be between 1 and %d)", s, maxTailLimit)
+		}
+		opts.limit = n
+	}
+
+	return opts, nil
+}
+
+// tailCursor tracks the second (unix timestamp) up to which flows were streamed. As flows of that second can still be
+// inserted after a poll, polls include it and the flows of that second that were already streamed are skipped.
+type tailCursor struct {
+	second int64
+	sent   map[string]int
+}
+
+func newTailCursor(second int64) *tailCursor {
+	return &tailCursor{
+		second: second,
+		sent:   make(map[string]int),
+	}
+}
+
+// unsent gets the encoded flows (in chronological order) that were not streamed yet and advances the cursor
+func (c *tailCursor) unsent(flows []*TailFlow) [][]byte {
+	seen := make(map[string]int)
+	res := make([][]byte, 0, len(flows))
+	for _, fl := range flows {
+		j, err := json.Marshal(fl)
+		if err != nil {
+			continue
+		}
+
+		ts := fl.Timestamp.Unix()
+		if ts < c.second {
+			continue
+		}
+
+		if ts > c.second {
+			c.second = ts
+			c.sent = make(map[string]int)
+			seen = make(map[string]int)
+		}
+
+		// identical flows of the same second are told apart by their count
+		seen[string(j)]++
+		if seen[string(j)] <= c.sent[string(j)] {
+			continue
+		}
+
+		c.sent[string(j)]++
+		res = append(res, j)
+	}
+
+	return res
+}
+
+// pollTail gets the newest flows since cursor (unix timestamp) in chronological order
+func (fe *Frontend) pollTail(ctx context.Context, conditions []string, cursor int64, limit int) ([]*TailFlow, error) {
+	rows, err := fe.chgw.QueryContext(ctx, tailQuery(fe.chgw.GetDatabaseName(), conditions, cursor, limit))
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	res := make([]*TailFlow, 0)
+	for rows.Next() {
+		fl := &TailFlow{}
+		var agent, src, dst net.IP
+		err := rows.Scan(&fl.Timestamp, &agent, &fl.IntIn, &fl.IntOut, &src, &dst, &fl.Protocol, &fl.SrcPort, &fl.DstPort, &fl.TCPFlags, &fl.Size, &fl.Packets, &fl.Samplerate)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		fl.Agent, fl.SrcAddr, fl.DstAddr = agent.String(), src.String(), dst.String()
+		res = append(res, fl)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+
+	// the query gets the newest flows first
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+
+	return res, nil
+}
+
+func tailQuery(database string, conditions []string, cursor int64, limit int) string {
+	conditions = append([]string{fmt.Sprintf("timestamp >= toDateTime(%d)", cursor)}, conditions...)
+
+	return fmt.Sprintf("SELECT timestamp, agent, int_in, int_out, src_ip_addr, dst_ip_addr, ip_protocol, src_port, dst_port, tcp_flags, size, packets, samplerate FROM %s.flows WHERE %s ORDER BY timestamp DESC LIMIT %d",
+		database, strings.Join(conditions, " AND "), limit)
+}
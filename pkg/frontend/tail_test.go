@@ -0,0 +1,71 @@
+package frontend
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTailOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   url.Values
+		expected *tailOptions
+		wantFail bool
+	}{
+		{
+			name:     "Defaults",
+			fields:   url.Values{},
+			expected: &tailOptions{interval: defaultTailInterval, limit: defaultTailLimit},
+		},
+		{
+			name:     "Custom",
+			fields:   url.Values{"interval": {"2"}, "limit": {"10"}},
+			expected: &tailOptions{interval: 2 * time.Second, limit: 10},
+		},
+		{
+			name:     "Limit too high",
+			fields:   url.Values{"limit": {"100000"}},
+			wantFail: true,
+		},
+		{
+			name:     "Invalid interval",
+			fields:   url.Values{"interval": {"0"}},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		opts, err := parseTailOptions(test.fields)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, opts, test.name)
+	}
+}
+
+func TestTailQuery(t *testing.T) {
+	q := tailQuery("flowhouse", []string{"dst_port = 53"}, 1614600000, 100)
+	assert.Equal(t, "SELECT timestamp, agent, int_in, int_out, src_ip_addr, dst_ip_addr, ip_protocol, src_port, dst_port, tcp_flags, size, packets, samplerate FROM flowhouse.flows WHERE timestamp >= toDateTime(1614600000) AND dst_port = 53 ORDER BY timestamp DESC LIMIT 100", q)
+}
+
+func TestTailCursor(t *testing.T) {
+	flow := func(ts int64, port uint16) *TailFlow {
+		return &TailFlow{Timestamp: time.Unix(ts, 0).UTC(), DstPort: port}
+	}
+
+	c := newTailCursor(100)
+	assert.Len(t, c.unsent([]*TailFlow{flow(99, 1), flow(100, 1), flow(100, 2)}), 2, "First poll")
+
+	// flows of the cursor second that were inserted after the first poll are streamed, the others are not again
+	assert.Len(t, c.unsent([]*TailFlow{flow(100, 1), flow(100, 2), flow(100, 2), flow(100, 3)}), 2, "Late flows of cursor second")
+	assert.Len(t, c.unsent([]*TailFlow{flow(100, 1), flow(100, 2), flow(100, 2), flow(100, 3)}), 0, "Nothing new")
+
+	assert.Len(t, c.unsent([]*TailFlow{flow(100, 3), flow(101, 1), flow(101, 1)}), 2, "Next second")
+	assert.Equal(t, int64(101), c.second)
+}
@@ -0,0 +1,168 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTopTalkersTop = 10
+	maxTopTalkersTop     = 1000
+)
+
+// topTalkerDimensions are the expressions of the dimensions of a top talkers summary
+var topTalkerDimensions = map[string]string{
+	"src_ip_addr": "IPv6NumToString(src_ip_addr)",
+	"dst_ip_addr": "IPv6NumToString(dst_ip_addr)",
+	"src_asn":     "toString(src_asn)",
+	"dst_asn":     "toString(dst_asn)",
+	"src_port":    "toString(src_port)",
+	"dst_port":    "toString(dst_port)",
+	"ip_protocol": "toString(ip_protocol)",
+}
+
+// TopTalkers are the top values of each dimension over a time range
+type TopTalkers struct {
+	Start      time.Time               `json:"start"`
+	End        time.Time               `json:"end"`
+	Dimensions map[string][]*TopTalker `json:"dimensions"`
+}
+
+// TopTalker is the traffic of a value of a dimension. Name is the name of protocols and well-known ports.
+type TopTalker struct {
+	Value   string  `json:"value"`
+	Name    string  `json:"name,omitempty"`
+	Bytes   uint64  `json:"bytes"`
+	Packets uint64  `json:"packets"`
+	Mbps    float64 `json:"mbps"`
+}
+
+// topTalkersHandler serves the top source and destination IPs, ASNs, ports and protocols of a time range as JSON
+func (fe *Frontend) topTalkersHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := fe.processTopTalkersQuery(r.Context(), r.URL.Query())
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Unable to process top talkers query")
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		httpError(w, r, "Unable to marshal result", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func (fe *Frontend) processTopTalkersQuery(ctx context.Context, fields url.Values) (*TopTalkers, error) {
+	top := defaultTopTalkersTop
+	if t := fields.Get("top"); t != "" {
+		var err error
+		top, err = strconv.Atoi(t)
+		if err != nil || top <= 0 || top > maxTopTalkersTop {
+			return nil, fmt.Errorf("Invalid top %q", t)
+		}
+	}
+
+	start, end, err := getTimeRange(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if end <= start {
+		return nil, fmt.Errorf("Empty time range")
+	}
+
+	return fe.topTalkers(ctx, start, end, fe.getFieldConditions(fields), top)
+}
+
+// topTalkers gets the top values of each dimension of the flows matching conditions between start and end
+func (fe *Frontend) topTalkers(ctx context.Context, start int64, end int64, conditions []string, top int) (*TopTalkers, error) {
+	res := &TopTalkers{
+		Start:      time.Unix(start, 0).UTC(),
+		End:        time.Unix(end, 0).UTC(),
+		Dimensions: make(map[string][]*TopTalker, len(topTalkerDimensions)),
+	}
+
+	// the queries are independent and small, so they run concurrently
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for dim := range topTalkerDimensions {
+		wg.Add(1)
+		go func(dim string) {
+			defer wg.Done()
+
+			talkers, err := fe.queryTopTalkers(ctx, topTalkersQuery(fe.chgw.GetDatabaseName(), dim, start, end, conditions, top), dim, end-start)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = errors.Wrapf(err, "Unable to get top %s", dim)
+				}
+				return
+			}
+
+			res.Dimensions[dim] = talkers
+		}(dim)
+	}
+
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return res, nil
+}
+
+func (fe *Frontend) queryTopTalkers(ctx context.Context, q string, dim string, seconds int64) ([]*TopTalker, error) {
+	rows, err := fe.chgw.QueryContext(ctx, q)
+	if err != nil {
+		return nil, errors.Wrap(err, "Query failed")
+	}
+	defer rows.Close()
+
+	names := fe.getNames()
+	res := make([]*TopTalker, 0)
+	for rows.Next() {
+		t := &TopTalker{}
+		err := rows.Scan(&t.Value, &t.Bytes, &t.Packets)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan failed")
+		}
+
+		if strings.HasSuffix(dim, "_ip_addr") {
+			t.Value = formatEndpoint(conversationsByIP, t.Value)
+		}
+
+		if v, err := strconv.ParseUint(t.Value, 10, 16); err == nil {
+			if name := names.label(dim, v); name != t.Value {
+				t.Name = name
+			}
+		}
+		t.Mbps = float64(t.Bytes) * 8 / float64(seconds) / 1000000
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+func topTalkersQuery(database string, dim string, start int64, end int64, conditions []string, top int) string {
+	conditions = append([]string{fmt.Sprintf("timestamp BETWEEN toDateTime(%d) AND toDateTime(%d)", start, end)}, conditions...)
+
+	return fmt.Sprintf("SELECT %s AS v, sum(size * samplerate) AS bytes, sum(packets * samplerate) AS pkts FROM %s.flows WHERE %s GROUP BY v ORDER BY bytes DESC, v LIMIT %d",
+		topTalkerDimensions[dim], database, strings.Join(conditions, " AND "), top)
+}
@@ -0,0 +1,32 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopTalkersQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		dim        string
+		conditions []string
+		expected   string
+	}{
+		{
+			name:     "ASN",
+			dim:      "dst_asn",
+			expected: "SELECT toString(dst_asn) AS v, sum(size * samplerate) AS bytes, sum(packets * samplerate) AS pkts FROM flowhouse.flows WHERE timestamp BETWEEN toDateTime(100) AND toDateTime(400) GROUP BY v ORDER BY bytes DESC, v LIMIT 10",
+		},
+		{
+			name:       "IP with filter",
+			dim:        "src_ip_addr",
+			conditions: []string{"(agent = '192.0.2.1')"},
+			expected:   "SELECT IPv6NumToString(src_ip_addr) AS v, sum(size * samplerate) AS bytes, sum(packets * samplerate) AS pkts FROM flowhouse.flows WHERE timestamp BETWEEN toDateTime(100) AND toDateTime(400) AND (agent = '192.0.2.1') GROUP BY v ORDER BY bytes DESC, v LIMIT 10",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, topTalkersQuery("flowhouse", test.dim, 100, 400, test.conditions, 10), test.name)
+	}
+}
@@ -0,0 +1,159 @@
+package frontend
+
+import (
+	"fmt"
+
+	"github.com/bio-routing/flowhouse/pkg/models/preferences"
+)
+
+// UIConfig defines which fields the index view lists, how they are grouped and which are selected by default
+type UIConfig struct {
+	// FieldGroups are listed first, in the given order. Each field is listed with the columns of its dicts.
+	FieldGroups []*UIFieldGroup `yaml:"field_groups"`
+
+	// HiddenFields are not listed. They can still be queried.
+	HiddenFields []string `yaml:"hidden_fields"`
+
+	// HideUngrouped hides all fields not in FieldGroups instead of listing them after the groups
+	HideUngrouped bool `yaml:"hide_ungrouped"`
+
+	// DefaultBreakdowns are selected for users that have not saved breakdowns of their own
+	DefaultBreakdowns []string `yaml:"default_breakdowns"`
+}
+
+// UIFieldGroup is a group of fields of the index view
+type UIFieldGroup struct {
+	Label  string   `yaml:"label"`
+	Fields []string `yaml:"fields"`
+}
+
+// Validate checks that groups are labeled and no field is grouped twice or grouped and hidden
+func (c *UIConfig) Validate() error {
+	seen := make(map[string]struct{})
+	for _, g := range c.FieldGroups {
+		if g.Label == "" {
+			return fmt.Errorf("Field group without label")
+		}
+
+		if len(g.Fields) == 0 {
+			return fmt.Errorf("Field group %q has no fields", g.Label)
+		}
+
+		for _, f := range g.Fields {
+			if _, exists := seen[f]; exists {
+				return fmt.Errorf("Field %q is in more than one group", f)
+			}
+			seen[f] = struct{}{}
+		}
+	}
+
+	for _, f := range c.HiddenFields {
+		if _, exists := seen[f]; exists {
+			return fmt.Errorf("Field %q is grouped and hidden", f)
+		}
+	}
+
+	return nil
+}
+
+// SetUI sets the layout of the index view
+func (fe *Frontend) SetUI(cfg *UIConfig) error {
+	err := cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	fe.ui = cfg
+	return nil
+}
+
+// defaultPreferences applies the default breakdowns to preferences without breakdowns
+func (fe *Frontend) defaultPreferences(p *preferences.Preferences) *preferences.Preferences {
+	if fe.ui == nil || len(fe.ui.DefaultBreakdowns) == 0 || len(p.Breakdowns) != 0 {
+		return p
+	}
+
+	res := *p
+	res.Breakdowns = fe.ui.DefaultBreakdowns
+	return &res
+}
+
+// getIndexView lists the catalog fields and the columns of their dicts in the groups of the UI config. Fields not
+// grouped are listed in a group of their own after the configured groups.
+func (fe *Frontend) getIndexView() (*IndexView, error) {
+	ret := &IndexView{
+		FieldGroups: make([]*FieldGroup, 0),
+	}
+
+	catalog := fe.catalog()
+	listed := make(map[string]struct{})
+	if fe.ui != nil {
+		byName := make(map[string]fieldDescription, len(catalog))
+		for _, field := range catalog {
+			byName[field.Name] = field
+		}
+
+		for _, name := range fe.ui.HiddenFields {
+			listed[name] = struct{}{}
+		}
+
+		for _, g := range fe.ui.FieldGroups {
+			fg := &FieldGroup{
+				Name:   g.Label,
+				Label:  g.Label,
+				Fields: make([]*Field, 0),
+			}
+
+			for _, name := range g.Fields {
+				field, exists := byName[name]
+				if !exists {
+					log.WithField("field", name).Warning("Unknown field in field group")
+					continue
+				}
+				listed[name] = struct{}{}
+
+				fg.Fields = append(fg.Fields, fe.indexFields(field)...)
+			}
+
+			if len(fg.Fields) == 0 {
+				continue
+			}
+
+			ret.FieldGroups = append(ret.FieldGroups, fg)
+			ret.BreakDownLen += len(fg.Fields) + 1
+		}
+
+		if fe.ui.HideUngrouped {
+			return ret, nil
+		}
+	}
+
+	for _, field := range catalog {
+		if _, exists := listed[field.Name]; exists {
+			continue
+		}
+
+		fg := &FieldGroup{
+			Name:   field.Name,
+			Label:  field.Label,
+			Fields: fe.indexFields(field),
+		}
+		ret.FieldGroups = append(ret.FieldGroups, fg)
+		ret.BreakDownLen += len(fg.Fields) + 1
+	}
+
+	return ret, nil
+}
+
+// indexFields gets a field and the columns of its joined tables and dicts
+func (fe *Frontend) indexFields(field fieldDescription) []*Field {
+	res := []*Field{
+		{
+			Name:  field.Name,
+			Label: field.Label,
+		},
+	}
+
+	res = append(res, fe.getJoinedFields(field.Name, field.Label)...)
+	return append(res, fe.getDerivedFields(field.Name, field.Label, 0)...)
+}
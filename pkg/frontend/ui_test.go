@@ -0,0 +1,110 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/preferences"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUIConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *UIConfig
+		wantFail bool
+	}{
+		{
+			name: "Valid",
+			cfg: &UIConfig{
+				FieldGroups:  []*UIFieldGroup{{Label: "Customers", Fields: []string{"customer", "service"}}},
+				HiddenFields: []string{"observation_point_id"},
+			},
+		},
+		{
+			name:     "No label",
+			cfg:      &UIConfig{FieldGroups: []*UIFieldGroup{{Fields: []string{"customer"}}}},
+			wantFail: true,
+		},
+		{
+			name:     "Empty group",
+			cfg:      &UIConfig{FieldGroups: []*UIFieldGroup{{Label: "Customers"}}},
+			wantFail: true,
+		},
+		{
+			name: "Grouped twice",
+			cfg: &UIConfig{FieldGroups: []*UIFieldGroup{
+				{Label: "Customers", Fields: []string{"customer"}},
+				{Label: "Billing", Fields: []string{"customer"}},
+			}},
+			wantFail: true,
+		},
+		{
+			name: "Grouped and hidden",
+			cfg: &UIConfig{
+				FieldGroups:  []*UIFieldGroup{{Label: "Customers", Fields: []string{"customer"}}},
+				HiddenFields: []string{"customer"},
+			},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.cfg.Validate()
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+	}
+}
+
+func TestGetIndexViewUI(t *testing.T) {
+	fe := &Frontend{}
+	v, err := fe.getIndexView()
+	assert.NoError(t, err)
+	assert.Equal(t, len(fields), len(v.FieldGroups))
+	assert.Equal(t, 2*len(fields), v.BreakDownLen)
+
+	assert.NoError(t, fe.SetUI(&UIConfig{
+		FieldGroups: []*UIFieldGroup{
+			{Label: "Customers", Fields: []string{"service", "customer", "unknown"}},
+			{Label: "Unknown", Fields: []string{"unknown2"}},
+		},
+		HiddenFields: []string{"agent"},
+	}))
+
+	v, err = fe.getIndexView()
+	assert.NoError(t, err)
+	assert.Equal(t, "Customers", v.FieldGroups[0].Label)
+	assert.Equal(t, []*Field{{Name: "service", Label: "Service"}, {Name: "customer", Label: "Customer"}}, v.FieldGroups[0].Fields)
+	assert.Equal(t, len(fields)-2, len(v.FieldGroups))
+	assert.Equal(t, 3+2*(len(fields)-3), v.BreakDownLen)
+	for _, fg := range v.FieldGroups[1:] {
+		assert.NotContains(t, []string{"agent", "customer", "service"}, fg.Name)
+	}
+
+	fe.ui.HideUngrouped = true
+	v, err = fe.getIndexView()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(v.FieldGroups))
+}
+
+func TestIndexHandlerDefaultBreakdowns(t *testing.T) {
+	p := &preferences.Preferences{Breakdowns: []string{}, Theme: preferences.ThemeLight}
+	fe := New(nil, nil, nil, nil, &mockPreferences{p: p})
+	assert.NoError(t, fe.SetUI(&UIConfig{DefaultBreakdowns: []string{"customer", "dst_asn"}}))
+
+	rec := httptest.NewRecorder()
+	fe.indexHandler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"breakdowns":["customer","dst_asn"]`)
+	assert.Empty(t, p.Breakdowns)
+
+	p.Breakdowns = []string{"src_asn"}
+	rec = httptest.NewRecorder()
+	fe.indexHandler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Contains(t, rec.Body.String(), `"breakdowns":["src_asn"]`)
+}
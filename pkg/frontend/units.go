@@ -0,0 +1,83 @@
+package frontend
+
+import "strconv"
+
+// SeriesMeta describes the unit of the values of a series and how to show them
+type SeriesMeta struct {
+	// Unit is the base unit, bps or pps
+	Unit string `json:"unit"`
+
+	// Scale converts the values to the base unit (e.g. 1000000 for Mbps)
+	Scale float64 `json:"scale"`
+
+	// Prefix is the SI prefix suited for the largest value of the series (e.g. G to show Gbps)
+	Prefix string `json:"prefix"`
+
+	// Decimals is the number of decimals suggested for values shown with Prefix
+	Decimals int `json:"decimals"`
+}
+
+// resultUnits are the units of query results with their base unit and scale
+var resultUnits = map[string]struct {
+	base  string
+	scale float64
+}{
+	"bps":  {base: "bps", scale: 1},
+	"Mbps": {base: "bps", scale: 1000000},
+	"pps":  {base: "pps", scale: 1},
+
+	// shareUnit are hundredths of a percent, which keeps shares integers like the other units
+	shareUnit: {base: "%", scale: 0.01},
+}
+
+var siPrefixes = []string{"", "k", "M", "G", "T", "P"}
+
+// siPrefix gets the SI prefix suited for v, its factor and the number of decimals to keep three significant digits
+func siPrefix(v float64) (string, float64, int) {
+	i, factor := 0, 1.0
+	for i < len(siPrefixes)-1 && v >= factor*1000 {
+		i++
+		factor *= 1000
+	}
+
+	decimals := 0
+	switch scaled := v / factor; {
+	case scaled < 10:
+		decimals = 2
+	case scaled < 100:
+		decimals = 1
+	}
+
+	return siPrefixes[i], factor, decimals
+}
+
+// humanize formats v given in the base unit with a SI prefix, e.g. 1.23 Gbps
+func humanize(v float64, unit string) string {
+	prefix, factor, decimals := siPrefix(v)
+	return strconv.FormatFloat(v/factor, 'f', decimals, 64) + " " + prefix + unit
+}
+
+// Describe sets the metadata of each series. The prefix is chosen by the largest value of a series.
+func (r *QueryResult) Describe() {
+	u, known := resultUnits[r.Unit]
+	if !known {
+		return
+	}
+
+	for _, s := range r.Series {
+		max := uint64(0)
+		for _, v := range s.Values {
+			if v > max {
+				max = v
+			}
+		}
+
+		prefix, _, decimals := siPrefix(float64(max) * u.scale)
+		s.Meta = &SeriesMeta{
+			Unit:     u.base,
+			Scale:    u.scale,
+			Prefix:   prefix,
+			Decimals: decimals,
+		}
+	}
+}
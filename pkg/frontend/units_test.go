@@ -0,0 +1,61 @@
+package frontend
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHumanize(t *testing.T) {
+	tests := []struct {
+		value    float64
+		unit     string
+		expected string
+	}{
+		{value: 0, unit: "bps", expected: "0.00 bps"},
+		{value: 999, unit: "pps", expected: "999 pps"},
+		{value: 1000, unit: "pps", expected: "1.00 kpps"},
+		{value: 45300000, unit: "bps", expected: "45.3 Mbps"},
+		{value: 1234000000, unit: "bps", expected: "1.23 Gbps"},
+		{value: 2.5e18, unit: "bps", expected: "2500 Pbps"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, humanize(test.value, test.unit))
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	r := &QueryResult{
+		Unit: "Mbps",
+		Series: []*QuerySeries{
+			{Name: "a", Values: []uint64{1500, 12000}},
+			{Name: "b", Values: []uint64{}},
+		},
+	}
+	r.Describe()
+
+	assert.Equal(t, &SeriesMeta{Unit: "bps", Scale: 1000000, Prefix: "G", Decimals: 1}, r.Series[0].Meta)
+	assert.Equal(t, &SeriesMeta{Unit: "bps", Scale: 1000000, Prefix: "", Decimals: 2}, r.Series[1].Meta)
+
+	r = &QueryResult{Unit: "furlongs", Series: []*QuerySeries{{Name: "a"}}}
+	r.Describe()
+	assert.Nil(t, r.Series[0].Meta)
+}
+
+func TestResultCSVHumanized(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	res := newResult()
+	res.add(ts, "agent=rtr01", 1234)
+	res.add(ts, "agent=rtr02", 5)
+
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, res.csv(buf, true))
+	assert.Equal(t, "timestamp,agent=rtr01,agent=rtr02\n2024-01-01T12:00:00Z,1.23 Gbps,5.00 Mbps\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, res.csv(buf, false))
+	assert.Equal(t, "timestamp,agent=rtr01,agent=rtr02\n2024-01-01T12:00:00Z,1234,5\n", buf.String())
+}
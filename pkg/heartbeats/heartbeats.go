@@ -0,0 +1,362 @@
+// Package heartbeats records periodic heartbeats per listener and agent, so no traffic can be told apart from a
+// collector that is down
+package heartbeats
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/models/heartbeat"
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// GapNoHeartbeat is a gap in which a listener sent no heartbeats, e.g. because the collector was down
+	GapNoHeartbeat = "no_heartbeat"
+
+	// GapNoTraffic is a gap in which the listener was up but received no flows from an agent
+	GapNoTraffic = "no_traffic"
+
+	defaultInterval = 60
+
+	// missedHeartbeats is the number of intervals without a heartbeat after which a listener is considered down
+	missedHeartbeats = 2
+)
+
+// Store persists heartbeats
+type Store interface {
+	InsertHeartbeats(heartbeats []*heartbeat.Heartbeat) error
+	GetHeartbeats(start time.Time, end time.Time) ([]*heartbeat.Heartbeat, error)
+}
+
+// Config configures the heartbeats
+type Config struct {
+	// Interval is the time in seconds between heartbeats
+	Interval uint64 `yaml:"interval"`
+
+	// Instance identifies this flowhouse in the heartbeats, the hostname by default
+	Instance string `yaml:"instance"`
+}
+
+func (c *Config) loadDefaults() error {
+	if c.Interval == 0 {
+		c.Interval = defaultInterval
+	}
+
+	if c.Instance == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return errors.Wrap(err, "Unable to get hostname")
+		}
+		c.Instance = hostname
+	}
+
+	return nil
+}
+
+// Gap is a time range without heartbeats of a listener or without traffic from an agent
+type Gap struct {
+	Kind     string    `json:"kind"`
+	Instance string    `json:"instance"`
+	Listener string    `json:"listener"`
+	Agent    string    `json:"agent,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+// Monitor counts the flows of each listener per agent and stores them as heartbeats every interval
+type Monitor struct {
+	cfg       *Config
+	store     Store
+	listeners map[string]map[netip.Addr]uint64
+	mu        sync.Mutex
+	stopCh    chan struct{}
+}
+
+// New creates a monitor and starts sending heartbeats
+func New(cfg *Config, store Store) (*Monitor, error) {
+	err := cfg.loadDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Monitor{
+		cfg:       cfg,
+		store:     store,
+		listeners: make(map[string]map[netip.Addr]uint64),
+		stopCh:    make(chan struct{}),
+	}
+
+	go m.run()
+	return m, nil
+}
+
+// Stop stops sending heartbeats
+func (m *Monitor) Stop() {
+	close(m.stopCh)
+}
+
+// Output registers listener and wraps its output to count its flows per agent
+func (m *Monitor) Output(listener string, output func(flows []*flow.Flow)) func(flows []*flow.Flow) {
+	m.mu.Lock()
+	if _, exists := m.listeners[listener]; !exists {
+		m.listeners[listener] = make(map[netip.Addr]uint64)
+	}
+	m.mu.Unlock()
+
+	return func(flows []*flow.Flow) {
+		m.observe(listener, flows)
+		output(flows)
+	}
+}
+
+func (m *Monitor) observe(listener string, flows []*flow.Flow) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agents := m.listeners[listener]
+	for _, fl := range flows {
+		agents[fl.Agent]++
+	}
+}
+
+func (m *Monitor) run() {
+	t := time.NewTicker(time.Duration(m.cfg.Interval) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case now := <-t.C:
+			err := m.store.InsertHeartbeats(m.beat(now))
+			if err != nil {
+				log.WithError(err).Error("Unable to store heartbeats")
+			}
+		}
+	}
+}
+
+// beat takes the heartbeats of all listeners and their agents and resets the flow counters. Agents seen once keep
+// getting heartbeats, with zero flows while they are silent.
+func (m *Monitor) beat(now time.Time) []*heartbeat.Heartbeat {
+	ts := now.Truncate(time.Second)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make([]*heartbeat.Heartbeat, 0)
+	for listener, agents := range m.listeners {
+		total := uint64(0)
+		for agent, n := range agents {
+			res = append(res, &heartbeat.Heartbeat{
+				Timestamp: ts,
+				Instance:  m.cfg.Instance,
+				Listener:  listener,
+				Agent:     agent,
+				Flows:     n,
+			})
+			total += n
+			agents[agent] = 0
+		}
+
+		res = append(res, &heartbeat.Heartbeat{
+			Timestamp: ts,
+			Instance:  m.cfg.Instance,
+			Listener:  listener,
+			Agent:     netip.IPv6Unspecified(),
+			Flows:     total,
+		})
+	}
+
+	return res
+}
+
+// Gaps gets the gaps from start to end
+func (m *Monitor) Gaps(start time.Time, end time.Time) ([]*Gap, error) {
+	if now := time.Now(); end.After(now) {
+		end = now
+	}
+
+	hbs, err := m.store.GetHeartbeats(start, end)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to get heartbeats")
+	}
+
+	return findGaps(hbs, start, end, time.Duration(m.cfg.Interval)*time.Second), nil
+}
+
+// findGaps finds the gaps in heartbeats ordered by instance, listener, agent and time. A listener without heartbeats
+// for missedHeartbeats intervals has a GapNoHeartbeat, consecutive heartbeats of an agent without flows a GapNoTraffic.
+func findGaps(hbs []*heartbeat.Heartbeat, start time.Time, end time.Time, interval time.Duration) []*Gap {
+	maxDistance := missedHeartbeats * interval
+	res := make([]*Gap, 0)
+
+	for i := 0; i < len(hbs); {
+		j := i
+		for j < len(hbs) && sameSeries(hbs[i], hbs[j]) {
+			j++
+		}
+
+		series := hbs[i:j]
+		if series[0].IsListener() {
+			res = append(res, listenerGaps(series, start, end, maxDistance)...)
+		} else {
+			res = append(res, trafficGaps(series, interval)...)
+		}
+
+		i = j
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Start.Before(res[j].Start)
+	})
+
+	return res
+}
+
+func sameSeries(a *heartbeat.Heartbeat, b *heartbeat.Heartbeat) bool {
+	return a.Instance == b.Instance && a.Listener == b.Listener && a.Agent == b.Agent
+}
+
+func listenerGaps(series []*heartbeat.Heartbeat, start time.Time, end time.Time, maxDistance time.Duration) []*Gap {
+	res := make([]*Gap, 0)
+	prev := start
+	for i := 0; i <= len(series); i++ {
+		next := end
+		if i < len(series) {
+			next = series[i].Timestamp
+		}
+
+		if next.Sub(prev) > maxDistance {
+			res = append(res, &Gap{
+				Kind:     GapNoHeartbeat,
+				Instance: series[0].Instance,
+				Listener: series[0].Listener,
+				Start:    prev,
+				End:      next,
+			})
+		}
+
+		prev = next
+	}
+
+	return res
+}
+
+func trafficGaps(series []*heartbeat.Heartbeat, interval time.Duration) []*Gap {
+	res := make([]*Gap, 0)
+	var g *Gap
+	for _, h := range series {
+		if h.Flows > 0 {
+			g = nil
+			continue
+		}
+
+		if g == nil {
+			g = &Gap{
+				Kind:     GapNoTraffic,
+				Instance: h.Instance,
+				Listener: h.Listener,
+				Agent:    h.Agent.String(),
+				Start:    h.Timestamp.Add(-interval),
+			}
+			res = append(res, g)
+		}
+
+		g.End = h.Timestamp
+	}
+
+	return res
+}
+
+// Handler handles requests for /heartbeats/gaps. GET lists the gaps between start and end as unix timestamps,
+// optionally filtered by listener and agent.
+func (m *Monitor) Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	start, end, err := parseRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gaps, err := m.Gaps(start, end)
+	if err != nil {
+		log.WithError(err).Error("Unable to find heartbeat gaps")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	gaps = filterGaps(gaps, r.URL.Query().Get("listener"), r.URL.Query().Get("agent"))
+
+	j, err := json.Marshal(gaps)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+// filterGaps keeps the gaps of listener and agent if set. Listener gaps are kept for any agent as they affect all of
+// them.
+func filterGaps(gaps []*Gap, listener string, agent string) []*Gap {
+	if a, err := netip.ParseAddr(agent); err == nil {
+		agent = a.String()
+	}
+
+	res := make([]*Gap, 0, len(gaps))
+	for _, g := range gaps {
+		if listener != "" && g.Listener != listener {
+			continue
+		}
+
+		if agent != "" && g.Kind == GapNoTraffic && g.Agent != agent {
+			continue
+		}
+
+		res = append(res, g)
+	}
+
+	return res
+}
+
+func parseRange(r *http.Request) (time.Time, time.Time, error) {
+	start, end := time.Now().Add(-24*time.Hour), time.Unix(math.MaxInt32, 0)
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{
+		{name: "start", t: &start},
+		{name: "end", t: &end},
+	} {
+		s := r.URL.Query().Get(p.name)
+		if s == "" {
+			continue
+		}
+
+		ts, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return start, end, fmt.Errorf("Invalid %s %q", p.name, s)
+		}
+		*p.t = time.Unix(ts, 0)
+	}
+
+	return start, end, nil
+}
@@ -0,0 +1,154 @@
+package heartbeats
+
+import (
+	"net/netip"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/models/heartbeat"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFlows(agent string, n int) []*flow.Flow {
+	res := make([]*flow.Flow, n)
+	for i := range res {
+		res[i] = &flow.Flow{
+			Agent: netip.MustParseAddr(agent),
+		}
+	}
+
+	return res
+}
+
+func TestBeat(t *testing.T) {
+	m := &Monitor{
+		cfg: &Config{
+			Interval: 60,
+			Instance: "fh1",
+		},
+		listeners: make(map[string]map[netip.Addr]uint64),
+	}
+
+	passed := 0
+	sfOut := m.Output("sflow", func(flows []*flow.Flow) {
+		passed += len(flows)
+	})
+	m.Output("ipfix", func(flows []*flow.Flow) {})
+
+	sfOut(testFlows("192.0.2.1", 3))
+	sfOut(testFlows("192.0.2.2", 2))
+	assert.Equal(t, 5, passed)
+
+	now := time.Unix(1700000000, 500)
+	ts := time.Unix(1700000000, 0)
+	expected := []*heartbeat.Heartbeat{
+		{Timestamp: ts, Instance: "fh1", Listener: "ipfix", Agent: netip.IPv6Unspecified()},
+		{Timestamp: ts, Instance: "fh1", Listener: "sflow", Agent: netip.IPv6Unspecified(), Flows: 5},
+		{Timestamp: ts, Instance: "fh1", Listener: "sflow", Agent: netip.MustParseAddr("192.0.2.1"), Flows: 3},
+		{Timestamp: ts, Instance: "fh1", Listener: "sflow", Agent: netip.MustParseAddr("192.0.2.2"), Flows: 2},
+	}
+	assert.Equal(t, expected, sortHeartbeats(m.beat(now)))
+
+	sfOut(testFlows("192.0.2.2", 1))
+	expected = []*heartbeat.Heartbeat{
+		{Timestamp: ts, Instance: "fh1", Listener: "ipfix", Agent: netip.IPv6Unspecified()},
+		{Timestamp: ts, Instance: "fh1", Listener: "sflow", Agent: netip.IPv6Unspecified(), Flows: 1},
+		{Timestamp: ts, Instance: "fh1", Listener: "sflow", Agent: netip.MustParseAddr("192.0.2.1")},
+		{Timestamp: ts, Instance: "fh1", Listener: "sflow", Agent: netip.MustParseAddr("192.0.2.2"), Flows: 1},
+	}
+	assert.Equal(t, expected, sortHeartbeats(m.beat(now)), "counters reset")
+}
+
+func sortHeartbeats(hbs []*heartbeat.Heartbeat) []*heartbeat.Heartbeat {
+	sort.Slice(hbs, func(i, j int) bool {
+		if hbs[i].Listener != hbs[j].Listener {
+			return hbs[i].Listener < hbs[j].Listener
+		}
+
+		if hbs[i].IsListener() != hbs[j].IsListener() {
+			return hbs[i].IsListener()
+		}
+
+		return hbs[i].Agent.Less(hbs[j].Agent)
+	})
+
+	return hbs
+}
+
+func TestFindGaps(t *testing.T) {
+	start := time.Unix(1000, 0)
+	end := time.Unix(2000, 0)
+	at := func(s int64) time.Time {
+		return time.Unix(s, 0)
+	}
+	listener := func(s int64) *heartbeat.Heartbeat {
+		return &heartbeat.Heartbeat{Timestamp: at(s), Instance: "fh1", Listener: "sflow", Agent: netip.IPv6Unspecified()}
+	}
+	agent := func(s int64, flows uint64) *heartbeat.Heartbeat {
+		return &heartbeat.Heartbeat{Timestamp: at(s), Instance: "fh1", Listener: "sflow", Agent: netip.MustParseAddr("192.0.2.1"), Flows: flows}
+	}
+
+	tests := []struct {
+		name     string
+		hbs      []*heartbeat.Heartbeat
+		expected []*Gap
+	}{
+		{
+			name: "No gaps",
+			hbs: []*heartbeat.Heartbeat{
+				listener(1100), listener(1300), listener(1500), listener(1700), listener(1900),
+				agent(1100, 1), agent(1300, 5),
+			},
+			expected: []*Gap{},
+		},
+		{
+			name: "Collector down",
+			hbs: []*heartbeat.Heartbeat{
+				listener(1100), listener(1300), listener(1800),
+			},
+			expected: []*Gap{
+				{Kind: GapNoHeartbeat, Instance: "fh1", Listener: "sflow", Start: at(1300), End: at(1800)},
+			},
+		},
+		{
+			name: "Down at start and end",
+			hbs: []*heartbeat.Heartbeat{
+				listener(1500), listener(1700),
+			},
+			expected: []*Gap{
+				{Kind: GapNoHeartbeat, Instance: "fh1", Listener: "sflow", Start: start, End: at(1500)},
+				{Kind: GapNoHeartbeat, Instance: "fh1", Listener: "sflow", Start: at(1700), End: end},
+			},
+		},
+		{
+			name: "No traffic",
+			hbs: []*heartbeat.Heartbeat{
+				listener(1100), listener(1300), listener(1500), listener(1700), listener(1900),
+				agent(1100, 3), agent(1300, 0), agent(1500, 0), agent(1700, 2), agent(1900, 0),
+			},
+			expected: []*Gap{
+				{Kind: GapNoTraffic, Instance: "fh1", Listener: "sflow", Agent: "192.0.2.1", Start: at(1200), End: at(1500)},
+				{Kind: GapNoTraffic, Instance: "fh1", Listener: "sflow", Agent: "192.0.2.1", Start: at(1800), End: at(1900)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, findGaps(test.hbs, start, end, 100*time.Second), test.name)
+	}
+}
+
+func TestFilterGaps(t *testing.T) {
+	gaps := []*Gap{
+		{Kind: GapNoHeartbeat, Listener: "sflow"},
+		{Kind: GapNoHeartbeat, Listener: "ipfix"},
+		{Kind: GapNoTraffic, Listener: "sflow", Agent: "192.0.2.1"},
+		{Kind: GapNoTraffic, Listener: "sflow", Agent: "2001:db8::1"},
+	}
+
+	assert.Equal(t, gaps, filterGaps(gaps, "", ""))
+	assert.Equal(t, []*Gap{gaps[0], gaps[2], gaps[3]}, filterGaps(gaps, "sflow", ""))
+	assert.Equal(t, []*Gap{gaps[0], gaps[1], gaps[3]}, filterGaps(gaps, "", "2001:0db8::1"))
+}
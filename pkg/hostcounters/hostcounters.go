@@ -0,0 +1,124 @@
+// Package hostcounters stores the host sflow counters (CPU, memory, virtual machines and containers) exported by
+// hsflowd on servers, so server fleets can be observed next to their traffic
+package hostcounters
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/hostcounter"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultInterval    = 10
+	defaultMaxBuffered = 100000
+)
+
+// Store persists host counter samples
+type Store interface {
+	InsertHostCounters(samples []*hostcounter.Sample) error
+}
+
+// Config configures the storage of host counters
+type Config struct {
+	// Interval is the time in seconds between inserts
+	Interval uint64 `yaml:"interval"`
+
+	// MaxBuffered is the maximum number of samples buffered between inserts, further samples are dropped
+	MaxBuffered int `yaml:"max_buffered"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Interval == 0 {
+		c.Interval = defaultInterval
+	}
+
+	if c.MaxBuffered == 0 {
+		c.MaxBuffered = defaultMaxBuffered
+	}
+}
+
+// Collector buffers host counter samples and inserts them every interval
+type Collector struct {
+	cfg     *Config
+	store   Store
+	buffer  []*hostcounter.Sample
+	dropped uint64
+	mu      sync.Mutex
+	stopCh  chan struct{}
+}
+
+// New creates a collector and starts inserting samples
+func New(cfg *Config, store Store) *Collector {
+	cfg.loadDefaults()
+
+	c := &Collector{
+		cfg:    cfg,
+		store:  store,
+		stopCh: make(chan struct{}),
+	}
+
+	go c.run()
+	return c
+}
+
+// Stop inserts the buffered samples and stops inserting
+func (c *Collector) Stop() {
+	close(c.stopCh)
+}
+
+// Add buffers samples for the next insert
+func (c *Collector) Add(samples []*hostcounter.Sample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	free := c.cfg.MaxBuffered - len(c.buffer)
+	if free < 0 {
+		free = 0
+	}
+
+	if len(samples) > free {
+		c.dropped += uint64(len(samples) - free)
+		samples = samples[:free]
+	}
+
+	c.buffer = append(c.buffer, samples...)
+}
+
+func (c *Collector) run() {
+	t := time.NewTicker(time.Duration(c.cfg.Interval) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.stopCh:
+			c.flush()
+			return
+		case <-t.C:
+			c.flush()
+		}
+	}
+}
+
+// flush inserts the buffered samples
+func (c *Collector) flush() {
+	c.mu.Lock()
+	samples, dropped := c.buffer, c.dropped
+	c.buffer, c.dropped = nil, 0
+	c.mu.Unlock()
+
+	if dropped > 0 {
+		log.Warningf("Dropped %d host counter samples exceeding the buffer of %d samples", dropped, c.cfg.MaxBuffered)
+	}
+
+	if len(samples) == 0 {
+		return
+	}
+
+	err := c.store.InsertHostCounters(samples)
+	if err != nil {
+		log.WithError(err).Errorf("Unable to store %d host counter samples", len(samples))
+	}
+}
@@ -0,0 +1,66 @@
+package hostcounters
+
+import (
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/hostcounter"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testStore struct {
+	inserts [][]*hostcounter.Sample
+	err     error
+}
+
+func (s *testStore) InsertHostCounters(samples []*hostcounter.Sample) error {
+	s.inserts = append(s.inserts, samples)
+	return s.err
+}
+
+func testSamples(hostnames ...string) []*hostcounter.Sample {
+	res := make([]*hostcounter.Sample, len(hostnames))
+	for i, h := range hostnames {
+		res[i] = &hostcounter.Sample{Hostname: h}
+	}
+
+	return res
+}
+
+func TestFlush(t *testing.T) {
+	store := &testStore{}
+	c := &Collector{
+		cfg:   &Config{MaxBuffered: 3},
+		store: store,
+	}
+
+	c.flush()
+	assert.Empty(t, store.inserts, "Nothing buffered")
+
+	c.Add(testSamples("web01", "web02"))
+	c.Add(testSamples("web03", "web04"))
+	assert.Equal(t, uint64(1), c.dropped)
+
+	c.flush()
+	assert.Equal(t, [][]*hostcounter.Sample{testSamples("web01", "web02", "web03")}, store.inserts)
+	assert.Empty(t, c.buffer)
+	assert.Equal(t, uint64(0), c.dropped)
+
+	// samples failing to insert are not retried
+	store.inserts = nil
+	store.err = errors.New("Connection refused")
+	c.Add(testSamples("web05"))
+	c.flush()
+	c.flush()
+	assert.Equal(t, [][]*hostcounter.Sample{testSamples("web05")}, store.inserts)
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg := &Config{}
+	cfg.loadDefaults()
+	assert.Equal(t, &Config{Interval: defaultInterval, MaxBuffered: defaultMaxBuffered}, cfg)
+
+	cfg = &Config{Interval: 60, MaxBuffered: 10}
+	cfg.loadDefaults()
+	assert.Equal(t, &Config{Interval: 60, MaxBuffered: 10}, cfg)
+}
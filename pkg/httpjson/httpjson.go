@@ -0,0 +1,31 @@
+// Package httpjson posts JSON documents to webhooks and similar HTTP endpoints
+package httpjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Post sends v as JSON to url. Responses with a status other than 2xx are errors.
+func Post(client *http.Client, url string, v interface{}) error {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "Unable to marshal")
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(j))
+	if err != nil {
+		return errors.Wrapf(err, "POST to %q failed", url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST to %q failed: %s", url, resp.Status)
+	}
+
+	return nil
+}
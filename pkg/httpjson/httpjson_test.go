@@ -0,0 +1,28 @@
+package httpjson
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPost(t *testing.T) {
+	var received map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		json.NewDecoder(r.Body).Decode(&received)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	assert.NoError(t, Post(srv.Client(), srv.URL, map[string]string{"foo": "bar"}))
+	assert.Equal(t, map[string]string{"foo": "bar"}, received)
+
+	assert.Error(t, Post(srv.Client(), srv.URL+"/fail", map[string]string{}))
+	assert.Error(t, Post(srv.Client(), srv.URL, func() {}), "Unmarshalable value")
+}
@@ -15,9 +15,11 @@ import (
 )
 
 const (
-	ifNameOID = "1.3.6.1.2.1.31.1.1.1.1"
-	snmpPort  = 161
-	timeout   = time.Second * 30
+	ifNameOID      = "1.3.6.1.2.1.31.1.1.1.1"
+	ifAliasOID     = "1.3.6.1.2.1.31.1.1.1.18"
+	ifHighSpeedOID = "1.3.6.1.2.1.31.1.1.1.15"
+	snmpPort       = 161
+	timeout        = time.Second * 30
 )
 
 type device struct {
@@ -60,8 +62,22 @@ func (d *device) update(interfaces []*netIf) {
 }
 
 type netIf struct {
-	id   uint32
-	name string
+	id          uint32
+	name        string
+	description string
+	speed       uint64
+}
+
+func (d *device) getInterfaces() []*netIf {
+	d.interfacesMu.RLock()
+	defer d.interfacesMu.RUnlock()
+
+	ret := make([]*netIf, 0, len(d.interfacesByID))
+	for _, ifa := range d.interfacesByID {
+		ret = append(ret, ifa)
+	}
+
+	return ret
 }
 
 func (d *device) startCollector() {
@@ -120,23 +136,21 @@ func (d *device) collect() error {
 
 	defer s.Conn.Close()
 
-	interfaces := make([]*netIf, 0)
+	interfacesByID := make(map[uint32]*netIf)
 	err = s.BulkWalk(ifNameOID, func(pdu gosnmp.SnmpPDU) error {
-		oid := strings.Split(pdu.Name, ".")
-		id, err := strconv.Atoi(oid[len(oid)-1])
+		id, err := pduIfIndex(pdu)
 		if err != nil {
-			return errors.Wrap(err, "Unable to convert interface id")
+			return err
 		}
 
 		if pdu.Type != gosnmp.OctetString {
 			return errors.Errorf("Unexpected PDU type: %d", pdu.Type)
 		}
 
-		name := string(pdu.Value.([]byte))
-		interfaces = append(interfaces, &netIf{
-			id:   uint32(id),
-			name: name,
-		})
+		interfacesByID[id] = &netIf{
+			id:   id,
+			name: string(pdu.Value.([]byte)),
+		}
 
 		return nil
 	})
@@ -144,10 +158,62 @@ func (d *device) collect() error {
 		return errors.Wrap(err, "BulkWalk failed for "+d.addr.String())
 	}
 
+	err = s.BulkWalk(ifAliasOID, func(pdu gosnmp.SnmpPDU) error {
+		id, err := pduIfIndex(pdu)
+		if err != nil {
+			return err
+		}
+
+		if pdu.Type != gosnmp.OctetString {
+			return errors.Errorf("Unexpected PDU type: %d", pdu.Type)
+		}
+
+		if ifa, exists := interfacesByID[id]; exists {
+			ifa.description = string(pdu.Value.([]byte))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "BulkWalk (ifAlias) failed for "+d.addr.String())
+	}
+
+	// ifSpeed saturates at 4.29Gbit/s, so ifHighSpeed (Mbit/s) is used instead
+	err = s.BulkWalk(ifHighSpeedOID, func(pdu gosnmp.SnmpPDU) error {
+		id, err := pduIfIndex(pdu)
+		if err != nil {
+			return err
+		}
+
+		if ifa, exists := interfacesByID[id]; exists {
+			ifa.speed = gosnmp.ToBigInt(pdu.Value).Uint64() * 1000000
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "BulkWalk (ifHighSpeed) failed for "+d.addr.String())
+	}
+
+	interfaces := make([]*netIf, 0, len(interfacesByID))
+	for _, ifa := range interfacesByID {
+		interfaces = append(interfaces, ifa)
+	}
+
 	d.update(interfaces)
 	return nil
 }
 
+func pduIfIndex(pdu gosnmp.SnmpPDU) (uint32, error) {
+	oid := strings.Split(pdu.Name, ".")
+	id, err := strconv.Atoi(oid[len(oid)-1])
+	if err != nil {
+		return 0, errors.Wrap(err, "Unable to convert interface id")
+	}
+
+	return uint32(id), nil
+}
+
 func (d *device) resolve(ifID uint32) string {
 	d.interfacesMu.RLock()
 	defer d.interfacesMu.RUnlock()
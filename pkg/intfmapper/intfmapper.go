@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/bio-routing/flowhouse/cmd/flowhouse/config"
+	"github.com/bio-routing/flowhouse/pkg/models/netif"
 
 	bnet "github.com/bio-routing/bio-rd/net"
 	log "github.com/sirupsen/logrus"
@@ -36,6 +37,27 @@ func (im *IntfMapper) Resolve(agent bnet.IP, ifID uint32) string {
 	return im.devices[agent].resolve(ifID)
 }
 
+// GetInterfaces gets all interfaces of all devices
+func (im *IntfMapper) GetInterfaces() []*netif.Interface {
+	im.devicesMu.RLock()
+	defer im.devicesMu.RUnlock()
+
+	ret := make([]*netif.Interface, 0)
+	for addr, d := range im.devices {
+		for _, ifa := range d.getInterfaces() {
+			ret = append(ret, &netif.Interface{
+				Agent:       addr,
+				Index:       ifa.id,
+				Name:        ifa.name,
+				Description: ifa.description,
+				Speed:       ifa.speed,
+			})
+		}
+	}
+
+	return ret
+}
+
 // AddDevice adds a device
 func (im *IntfMapper) AddDevice(addr bnet.IP, snmpCfg *config.SNMPConfig) error {
 	im.devicesMu.Lock()
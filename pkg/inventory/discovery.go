@@ -0,0 +1,175 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/httpjson"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxDiscoveries = 1000
+
+	// discoveryQueueLength is the number of discoveries waiting for the webhooks, further ones are not delivered
+	discoveryQueueLength = 128
+
+	notifyTimeout = 10 * time.Second
+)
+
+var agentsDiscovered = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "flowhouse",
+	Subsystem: "inventory",
+	Name:      "discovered_agents",
+	Help:      "Agents that sent flows without being in the inventory",
+})
+
+// DiscoveryConfig configures how discoveries of unknown agents are announced
+type DiscoveryConfig struct {
+	Webhooks []*WebhookConfig `yaml:"webhooks"`
+
+	// MaxDiscoveries is the number of discoveries listed, older ones are dropped (default 1000)
+	MaxDiscoveries int `yaml:"max_discoveries"`
+}
+
+// WebhookConfig configures an URL discoveries are POSTed to as JSON
+type WebhookConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+func (c *DiscoveryConfig) loadDefaults() {
+	if c.MaxDiscoveries == 0 {
+		c.MaxDiscoveries = defaultMaxDiscoveries
+	}
+}
+
+// Validate validates the discovery config
+func (c *DiscoveryConfig) Validate() error {
+	if c.MaxDiscoveries < 0 {
+		return fmt.Errorf("max_discoveries must not be negative")
+	}
+
+	for _, wh := range c.Webhooks {
+		if wh.URL == "" {
+			return fmt.Errorf("Webhook %q has no URL", wh.Name)
+		}
+	}
+
+	return nil
+}
+
+// Discovery is the first flow received from an agent that was not in the inventory
+type Discovery struct {
+	Agent     string    `json:"agent"`
+	FirstSeen time.Time `json:"first_seen"`
+
+	// ExportProtocol and Samplerate are those of the first flow
+	ExportProtocol string `json:"export_protocol"`
+	Samplerate     uint64 `json:"samplerate"`
+}
+
+func newDiscovery(fl *flow.Flow, now time.Time) *Discovery {
+	return &Discovery{
+		Agent:          fl.Agent.String(),
+		FirstSeen:      now,
+		ExportProtocol: fl.ExportProtocol,
+		Samplerate:     fl.Samplerate,
+	}
+}
+
+// EnableDiscovery POSTs discoveries to the configured webhooks and keeps the latest ones for listing. Discoveries are
+// logged even if it is not called.
+func (inv *Inventory) EnableDiscovery(cfg *DiscoveryConfig) error {
+	cfg.loadDefaults()
+	err := cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	inv.discoveryCfg = cfg
+	if len(cfg.Webhooks) == 0 {
+		return nil
+	}
+
+	inv.discoveryQueue = make(chan *Discovery, discoveryQueueLength)
+	go inv.notifier(&http.Client{
+		Timeout: notifyTimeout,
+	})
+
+	return nil
+}
+
+// discovered announces discoveries. It has to be called with inv.agentsMu held.
+func (inv *Inventory) discovered(discoveries []*Discovery) {
+	maxDiscoveries := defaultMaxDiscoveries
+	if inv.discoveryCfg != nil {
+		maxDiscoveries = inv.discoveryCfg.MaxDiscoveries
+	}
+
+	for _, d := range discoveries {
+		agentsDiscovered.Inc()
+		log.WithFields(log.Fields{
+			"agent":           d.Agent,
+			"export_protocol": d.ExportProtocol,
+			"samplerate":      d.Samplerate,
+		}).Warning("Discovered agent not in the inventory")
+
+		inv.discoveries = append(inv.discoveries, d)
+		if len(inv.discoveries) > maxDiscoveries {
+			inv.discoveries = inv.discoveries[len(inv.discoveries)-maxDiscoveries:]
+		}
+
+		if inv.discoveryQueue == nil {
+			continue
+		}
+
+		select {
+		case inv.discoveryQueue <- d:
+		default:
+			log.WithField("agent", d.Agent).Error("Discovery queue full, not notifying webhooks")
+		}
+	}
+}
+
+func (inv *Inventory) notifier(client *http.Client) {
+	for d := range inv.discoveryQueue {
+		for _, wh := range inv.discoveryCfg.Webhooks {
+			err := httpjson.Post(client, wh.URL, d)
+			if err != nil {
+				log.WithError(err).WithField("webhook", wh.Name).Error("Unable to notify webhook of discovery")
+			}
+		}
+	}
+}
+
+// Discoveries lists the latest discoveries, the oldest first
+func (inv *Inventory) Discoveries() []*Discovery {
+	inv.agentsMu.RLock()
+	defer inv.agentsMu.RUnlock()
+
+	return append([]*Discovery{}, inv.discoveries...)
+}
+
+// DiscoveriesHandler handles requests for /agents/discovered listing the latest discoveries
+func (inv *Inventory) DiscoveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	j, err := json.Marshal(inv.Discoveries())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
@@ -0,0 +1,84 @@
+package inventory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/agent"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestDiscovery(t *testing.T) {
+	received := make(chan *Discovery, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := &Discovery{}
+		err := json.NewDecoder(r.Body).Decode(d)
+		assert.NoError(t, err)
+		received <- d
+	}))
+	defer srv.Close()
+
+	inv, err := New(&mockStore{
+		agents: []*agent.Agent{
+			{
+				Address: bnet.IPv4FromOctets(192, 0, 2, 1),
+				Name:    "core01.pop01",
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	err = inv.EnableDiscovery(&DiscoveryConfig{
+		MaxDiscoveries: 2,
+		Webhooks: []*WebhookConfig{
+			{Name: "wh", URL: srv.URL},
+		},
+	})
+	assert.NoError(t, err)
+
+	inv.Observe([]*flow.Flow{
+		{Agent: netip.AddrFrom4([4]byte{192, 0, 2, 1}), ExportProtocol: flow.ExportProtocolSFlow, Samplerate: 1024},
+		{Agent: netip.AddrFrom4([4]byte{192, 0, 2, 2}), ExportProtocol: flow.ExportProtocolIPFIX, Samplerate: 1000},
+		{Agent: netip.AddrFrom4([4]byte{192, 0, 2, 2}), ExportProtocol: flow.ExportProtocolIPFIX, Samplerate: 2000},
+	})
+	inv.Observe([]*flow.Flow{
+		{Agent: netip.AddrFrom4([4]byte{192, 0, 2, 2}), ExportProtocol: flow.ExportProtocolIPFIX, Samplerate: 1000},
+		{Agent: netip.AddrFrom4([4]byte{192, 0, 2, 3}), ExportProtocol: flow.ExportProtocolSFlow, Samplerate: 1},
+		{Agent: netip.AddrFrom4([4]byte{192, 0, 2, 4}), ExportProtocol: flow.ExportProtocolCapture, Samplerate: 1},
+	})
+
+	d := inv.Discoveries()
+	if assert.Len(t, d, 2) {
+		assert.Equal(t, "192.0.2.3", d[0].Agent)
+		assert.Equal(t, "192.0.2.4", d[1].Agent)
+		assert.Equal(t, flow.ExportProtocolCapture, d[1].ExportProtocol)
+	}
+
+	for _, expected := range []string{"192.0.2.2", "192.0.2.3", "192.0.2.4"} {
+		select {
+		case d := <-received:
+			assert.Equal(t, expected, d.Agent)
+			if expected == "192.0.2.2" {
+				assert.Equal(t, flow.ExportProtocolIPFIX, d.ExportProtocol)
+				assert.Equal(t, uint64(1000), d.Samplerate)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Webhook not notified of %s", expected)
+		}
+	}
+}
+
+func TestDiscoveryConfigValidate(t *testing.T) {
+	inv, err := New(&mockStore{})
+	assert.NoError(t, err)
+
+	assert.Error(t, inv.EnableDiscovery(&DiscoveryConfig{MaxDiscoveries: -1}))
+	assert.Error(t, inv.EnableDiscovery(&DiscoveryConfig{Webhooks: []*WebhookConfig{{Name: "wh"}}}))
+}
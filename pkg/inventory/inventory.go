@@ -0,0 +1,247 @@
+package inventory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/agent"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/pkg/errors"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// activeTimeout is the time after which an agent that stopped exporting is considered stale
+	activeTimeout = time.Minute * 5
+
+	statusActive    = "active"
+	statusStale     = "stale"
+	statusNeverSeen = "never_seen"
+)
+
+// Store persists agents
+type Store interface {
+	InsertAgents(agents []*agent.Agent) error
+	GetAgents() ([]*agent.Agent, error)
+}
+
+type entry struct {
+	agent    *agent.Agent
+	lastSeen time.Time
+	flows    uint64
+}
+
+// Inventory keeps track of all known and seen agents
+type Inventory struct {
+	store    Store
+	agents   map[netip.Addr]*entry
+	agentsMu sync.RWMutex
+
+	discoveryCfg   *DiscoveryConfig
+	discoveries    []*Discovery
+	discoveryQueue chan *Discovery
+}
+
+// New creates a new inventory and loads previously stored agents
+func New(store Store) (*Inventory, error) {
+	inv := &Inventory{
+		store:  store,
+		agents: make(map[netip.Addr]*entry),
+	}
+
+	agents, err := store.GetAgents()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load agents")
+	}
+
+	for _, a := range agents {
+		inv.agents[flow.AddrFromBNet(a.Address)] = &entry{
+			agent: a,
+		}
+	}
+
+	return inv, nil
+}
+
+// Add adds or updates an agent
+func (inv *Inventory) Add(a *agent.Agent) error {
+	err := inv.store.InsertAgents([]*agent.Agent{a})
+	if err != nil {
+		return errors.Wrap(err, "Unable to store agent")
+	}
+
+	inv.agentsMu.Lock()
+	defer inv.agentsMu.Unlock()
+
+	addr := flow.AddrFromBNet(a.Address)
+	if e, exists := inv.agents[addr]; exists {
+		e.agent = a
+		return nil
+	}
+
+	inv.agents[addr] = &entry{
+		agent: a,
+	}
+
+	return nil
+}
+
+// Observe records that flows have been received from their agents. Agents not in the inventory are announced as
+// discovered.
+func (inv *Inventory) Observe(flows []*flow.Flow) {
+	now := time.Now()
+
+	inv.agentsMu.Lock()
+	defer inv.agentsMu.Unlock()
+
+	var discoveries []*Discovery
+	for _, fl := range flows {
+		e, exists := inv.agents[fl.Agent]
+		if !exists {
+			e = &entry{
+				agent: &agent.Agent{
+					Address: flow.AddrToBNet(fl.Agent),
+				},
+			}
+			inv.agents[fl.Agent] = e
+			discoveries = append(discoveries, newDiscovery(fl, now))
+		}
+
+		e.lastSeen = now
+		e.flows++
+	}
+
+	if len(discoveries) > 0 {
+		inv.discovered(discoveries)
+	}
+}
+
+// Annotate sets the name, site and role of the agent of fl
+func (inv *Inventory) Annotate(fl *flow.Flow) {
+	inv.agentsMu.RLock()
+	defer inv.agentsMu.RUnlock()
+
+	e, exists := inv.agents[fl.Agent]
+	if !exists {
+		return
+	}
+
+	fl.AgentName = e.agent.Name
+	fl.AgentSite = e.agent.Site
+	fl.AgentRole = e.agent.Role
+}
+
+// AgentView is the JSON representation of an agent
+type AgentView struct {
+	Address  string     `json:"address"`
+	Name     string     `json:"name"`
+	Site     string     `json:"site"`
+	Role     string     `json:"role"`
+	Status   string     `json:"status"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+	Flows    uint64     `json:"flows"`
+}
+
+// List lists all agents
+func (inv *Inventory) List() []*AgentView {
+	now := time.Now()
+
+	inv.agentsMu.RLock()
+	defer inv.agentsMu.RUnlock()
+
+	ret := make([]*AgentView, 0, len(inv.agents))
+	for _, e := range inv.agents {
+		ret = append(ret, e.view(now))
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Address < ret[j].Address
+	})
+
+	return ret
+}
+
+// Get gets an agent, nil if it is unknown
+func (inv *Inventory) Get(addr netip.Addr) *AgentView {
+	inv.agentsMu.RLock()
+	defer inv.agentsMu.RUnlock()
+
+	e, exists := inv.agents[addr.Unmap()]
+	if !exists {
+		return nil
+	}
+
+	return e.view(time.Now())
+}
+
+func (e *entry) view(now time.Time) *AgentView {
+	v := &AgentView{
+		Address: e.agent.Address.String(),
+		Name:    e.agent.Name,
+		Site:    e.agent.Site,
+		Role:    e.agent.Role,
+		Status:  statusNeverSeen,
+		Flows:   e.flows,
+	}
+
+	if !e.lastSeen.IsZero() {
+		lastSeen := e.lastSeen
+		v.LastSeen = &lastSeen
+		v.Status = statusStale
+		if now.Sub(e.lastSeen) < activeTimeout {
+			v.Status = statusActive
+		}
+	}
+
+	return v
+}
+
+// Handler handles requests for /agents. GET lists agents, PUT adds or updates an agent.
+func (inv *Inventory) Handler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		j, err := json.Marshal(inv.List())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(j)
+	case http.MethodPut, http.MethodPost:
+		v := &AgentView{}
+		err := json.NewDecoder(r.Body).Decode(v)
+		if err != nil {
+			http.Error(w, "Unable to decode agent", http.StatusBadRequest)
+			return
+		}
+
+		addr, err := bnet.IPFromString(v.Address)
+		if err != nil {
+			http.Error(w, "Invalid address", http.StatusBadRequest)
+			return
+		}
+
+		err = inv.Add(&agent.Agent{
+			Address: addr,
+			Name:    v.Name,
+			Site:    v.Site,
+			Role:    v.Role,
+		})
+		if err != nil {
+			log.WithError(err).Error("Unable to add agent")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,100 @@
+package inventory
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/agent"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+type mockStore struct {
+	agents []*agent.Agent
+}
+
+func (m *mockStore) InsertAgents(agents []*agent.Agent) error {
+	m.agents = append(m.agents, agents...)
+	return nil
+}
+
+func (m *mockStore) GetAgents() ([]*agent.Agent, error) {
+	return m.agents, nil
+}
+
+func TestInventory(t *testing.T) {
+	store := &mockStore{
+		agents: []*agent.Agent{
+			{
+				Address: bnet.IPv4FromOctets(192, 0, 2, 1),
+				Name:    "core01.pop01",
+			},
+		},
+	}
+
+	inv, err := New(store)
+	assert.NoError(t, err)
+
+	err = inv.Add(&agent.Agent{
+		Address: bnet.IPv4FromOctets(192, 0, 2, 2),
+		Name:    "core02.pop02",
+		Site:    "FRA02",
+		Role:    "backbone-router",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(store.agents))
+
+	inv.Observe([]*flow.Flow{
+		{Agent: netip.AddrFrom4([4]byte{192, 0, 2, 2})},
+		{Agent: netip.AddrFrom4([4]byte{192, 0, 2, 2})},
+		{Agent: netip.AddrFrom4([4]byte{192, 0, 2, 3})},
+	})
+
+	l := inv.List()
+	assert.Equal(t, 3, len(l))
+
+	assert.Equal(t, "core01.pop01", l[0].Name)
+	assert.Equal(t, statusNeverSeen, l[0].Status)
+	assert.Nil(t, l[0].LastSeen)
+
+	assert.Equal(t, "FRA02", l[1].Site)
+	assert.Equal(t, statusActive, l[1].Status)
+	assert.Equal(t, uint64(2), l[1].Flows)
+
+	assert.Equal(t, "192.0.2.3", l[2].Address)
+	assert.Equal(t, "", l[2].Name)
+	assert.Equal(t, statusActive, l[2].Status)
+
+	a := inv.Get(netip.MustParseAddr("::ffff:192.0.2.2"))
+	if assert.NotNil(t, a) {
+		assert.Equal(t, "core02.pop02", a.Name)
+		assert.Equal(t, uint64(2), a.Flows)
+	}
+	assert.Nil(t, inv.Get(netip.MustParseAddr("192.0.2.4")))
+}
+
+func TestAnnotate(t *testing.T) {
+	inv, err := New(&mockStore{
+		agents: []*agent.Agent{
+			{
+				Address: bnet.IPv4FromOctets(192, 0, 2, 2),
+				Name:    "core02.pop02",
+				Site:    "FRA02",
+				Role:    "backbone-router",
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	fl := &flow.Flow{Agent: netip.AddrFrom4([4]byte{192, 0, 2, 2})}
+	inv.Annotate(fl)
+	assert.Equal(t, "core02.pop02", fl.AgentName)
+	assert.Equal(t, "FRA02", fl.AgentSite)
+	assert.Equal(t, "backbone-router", fl.AgentRole)
+
+	fl = &flow.Flow{Agent: netip.AddrFrom4([4]byte{192, 0, 2, 3})}
+	inv.Annotate(fl)
+	assert.Empty(t, fl.AgentName)
+}
@@ -19,7 +19,9 @@ func New(rm *routemirror.RouteMirror) *IPAnnotator {
 }
 
 func (ipa *IPAnnotator) Annotate(fl *flow.Flow) error {
-	srt, err := ipa.rm.LPM(fl.Agent.String(), fl.VRFIn, fl.SrcAddr)
+	// the route mirror identifies routers by their address in bio-rd notation
+	agent := flow.AddrToBNet(fl.Agent)
+	srt, err := ipa.rm.LPM(agent.String(), fl.VRFIn, flow.AddrToBNet(fl.SrcAddr))
 	if err != nil {
 		return errors.Wrapf(err, "Unable to get route for source address %s", fl.SrcAddr.String())
 	}
@@ -28,7 +30,7 @@ func (ipa *IPAnnotator) Annotate(fl *flow.Flow) error {
 		return fmt.Errorf("No route found for %s", fl.SrcAddr.String())
 	}
 
-	fl.SrcPfx = *srt.Prefix()
+	fl.SrcPfx = flow.PrefixFromBNet(srt.Prefix())
 	srcFirstASPathSeg := srt.BestPath().BGPPath.ASPath.GetFirstSequenceSegment()
 	if srcFirstASPathSeg != nil {
 		srcASN := srcFirstASPathSeg.GetFirstASN()
@@ -37,7 +39,7 @@ func (ipa *IPAnnotator) Annotate(fl *flow.Flow) error {
 		}
 	}
 
-	drt, err := ipa.rm.LPM(fl.Agent.String(), fl.VRFOut, fl.DstAddr)
+	drt, err := ipa.rm.LPM(agent.String(), fl.VRFOut, flow.AddrToBNet(fl.DstAddr))
 	if err != nil {
 		return errors.Wrapf(err, "Unable to get route for destination address %s", fl.DstAddr.String())
 	}
@@ -46,7 +48,7 @@ func (ipa *IPAnnotator) Annotate(fl *flow.Flow) error {
 		return fmt.Errorf("No route found for %s", fl.DstAddr.String())
 	}
 
-	fl.DstPfx = *drt.Prefix()
+	fl.DstPfx = flow.PrefixFromBNet(drt.Prefix())
 	dstLastASPathSeg := drt.BestPath().BGPPath.ASPath.GetLastSequenceSegment()
 	if dstLastASPathSeg != nil {
 		dstASN := dstLastASPathSeg.GetLastASN()
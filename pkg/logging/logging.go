@@ -0,0 +1,163 @@
+// Package logging configures the log level and format globally and per component
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Ingest are the sFlow, IPFIX and capture servers
+	Ingest = "ingest"
+
+	// Gateway is the ClickHouse gateway
+	Gateway = "gateway"
+
+	// Frontend is the web frontend and query API
+	Frontend = "frontend"
+
+	// Audit records administrative actions like deletions of flows
+	Audit = "audit"
+
+	// FormatText logs human readable lines
+	FormatText = "text"
+
+	// FormatJSON logs one JSON object per line
+	FormatJSON = "json"
+)
+
+var components = []string{Ingest, Gateway, Frontend, Audit}
+
+// Config is the logging configuration
+type Config struct {
+	// Level is the level of all components without a level of their own (default info)
+	Level string `yaml:"level"`
+
+	// Format is text (default) or json
+	Format string `yaml:"format"`
+
+	// Components sets the level per component (ingest, gateway, frontend, audit)
+	Components map[string]string `yaml:"components"`
+}
+
+// Validate checks for unknown levels, formats and components
+func (c *Config) Validate() error {
+	if c.Level != "" {
+		_, err := log.ParseLevel(c.Level)
+		if err != nil {
+			return errors.Wrap(err, "Invalid level")
+		}
+	}
+
+	if c.Format != "" && c.Format != FormatText && c.Format != FormatJSON {
+		return fmt.Errorf("Invalid format %q", c.Format)
+	}
+
+	for name, level := range c.Components {
+		if !isComponent(name) {
+			return fmt.Errorf("Unknown component %q", name)
+		}
+
+		_, err := log.ParseLevel(level)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid level for component %q", name)
+		}
+	}
+
+	return nil
+}
+
+func isComponent(name string) bool {
+	for _, c := range components {
+		if c == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	loggers   = make(map[string]*log.Logger)
+	loggersMu sync.Mutex
+)
+
+// Component returns the logger of a component. Entries carry the component as field and are filtered by the
+// components level.
+func Component(name string) *log.Entry {
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+
+	l, exists := loggers[name]
+	if !exists {
+		l = log.New()
+		l.Out = os.Stderr
+		l.SetFormatter(log.StandardLogger().Formatter)
+		l.SetLevel(log.GetLevel())
+		loggers[name] = l
+	}
+
+	return l.WithField("component", name)
+}
+
+// Configure applies cfg (which may be nil) to the standard logger and all component loggers. If debug is set, all
+// levels are overridden with debug.
+func Configure(cfg *Config, debug bool) error {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	err := cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	level := log.InfoLevel
+	if cfg.Level != "" {
+		level, _ = log.ParseLevel(cfg.Level)
+	}
+
+	var formatter log.Formatter = &log.TextFormatter{}
+	if cfg.Format == FormatJSON {
+		formatter = &log.JSONFormatter{}
+	}
+
+	componentLevel := func(name string) log.Level {
+		if debug {
+			return log.DebugLevel
+		}
+
+		if s, exists := cfg.Components[name]; exists {
+			l, _ := log.ParseLevel(s)
+			return l
+		}
+
+		return level
+	}
+
+	if debug {
+		level = log.DebugLevel
+	}
+
+	log.SetLevel(level)
+	log.SetFormatter(formatter)
+
+	for _, name := range components {
+		Component(name)
+	}
+
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+
+	for name, l := range loggers {
+		l.SetLevel(componentLevel(name))
+		l.SetFormatter(formatter)
+	}
+
+	return nil
+}
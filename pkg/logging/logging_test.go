@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		wantFail bool
+	}{
+		{
+			name: "Empty",
+			cfg:  &Config{},
+		},
+		{
+			name: "Valid",
+			cfg: &Config{
+				Level:  "warning",
+				Format: FormatJSON,
+				Components: map[string]string{
+					Gateway: "debug",
+				},
+			},
+		},
+		{
+			name: "Invalid level",
+			cfg: &Config{
+				Level: "loud",
+			},
+			wantFail: true,
+		},
+		{
+			name: "Invalid format",
+			cfg: &Config{
+				Format: "xml",
+			},
+			wantFail: true,
+		},
+		{
+			name: "Unknown component",
+			cfg: &Config{
+				Components: map[string]string{
+					"billing": "debug",
+				},
+			},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.cfg.Validate()
+		assert.Equal(t, test.wantFail, err != nil, test.name)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(nil, false)
+
+	ingest := Component(Ingest)
+	err := Configure(&Config{
+		Level:  "warning",
+		Format: FormatJSON,
+		Components: map[string]string{
+			Gateway: "debug",
+		},
+	}, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, log.WarnLevel, log.GetLevel())
+	assert.Equal(t, log.WarnLevel, ingest.Logger.GetLevel())
+	assert.Equal(t, log.DebugLevel, Component(Gateway).Logger.GetLevel())
+	assert.Equal(t, Ingest, ingest.Data["component"])
+	assert.IsType(t, &log.JSONFormatter{}, ingest.Logger.Formatter)
+
+	err = Configure(&Config{Level: "error"}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, log.DebugLevel, log.GetLevel())
+	assert.Equal(t, log.DebugLevel, Component(Frontend).Logger.GetLevel())
+
+	err = Configure(&Config{Level: "loud"}, false)
+	assert.Error(t, err)
+}
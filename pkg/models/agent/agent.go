@@ -0,0 +1,13 @@
+package agent
+
+import (
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+// Agent defines a flow exporting device
+type Agent struct {
+	Address bnet.IP
+	Name    string
+	Site    string
+	Role    string
+}
@@ -0,0 +1,39 @@
+package annotation
+
+import (
+	"time"
+)
+
+// Annotation is an event explaining traffic changes, e.g. a maintenance
+type Annotation struct {
+	ID          uint64    `json:"id"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+
+	// Agents are the addresses of the affected agents. An annotation without agents applies to all agents.
+	Agents []string `json:"agents"`
+}
+
+// Overlaps checks if an annotation overlaps with the time range from start to end
+func (a *Annotation) Overlaps(start time.Time, end time.Time) bool {
+	return !a.Start.After(end) && !a.End.Before(start)
+}
+
+// AppliesTo checks if an annotation applies to any of the agents. An empty list matches all annotations.
+func (a *Annotation) AppliesTo(agents []string) bool {
+	if len(a.Agents) == 0 || len(agents) == 0 {
+		return true
+	}
+
+	for _, x := range a.Agents {
+		for _, y := range agents {
+			if x == y {
+				return true
+			}
+		}
+	}
+
+	return false
+}
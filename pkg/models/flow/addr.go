@@ -0,0 +1,68 @@
+package flow
+
+import (
+	"encoding/binary"
+	"net/netip"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+// AddrSize is the size of an address in its binary form
+const AddrSize = 16
+
+// PutAddr writes addr to b in its fixed-size binary form. IPv4 addresses are IPv4-mapped, unset addresses are written
+// as ::ffff:0.0.0.0 like unset prefixes (see pfxAddr in pkg/clickhousegw), so they are grouped with the IPv4 addresses
+// flows stored them as before. b must be at least AddrSize bytes long.
+func PutAddr(b []byte, addr netip.Addr) {
+	if !addr.IsValid() {
+		addr = netip.IPv4Unspecified()
+	}
+
+	a := addr.As16()
+
+	copy(b[:AddrSize], a[:])
+}
+
+// ReadAddr reads an address written by PutAddr. IPv4-mapped addresses are returned as IPv4 addresses.
+func ReadAddr(b []byte) netip.Addr {
+	return netip.AddrFrom16([AddrSize]byte(b[:AddrSize])).Unmap()
+}
+
+// AddrFromBytes gets the address of a 4 or 16 byte long slice, the unset address for any other length
+func AddrFromBytes(b []byte) netip.Addr {
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// AddrFromBNet converts a bio-rd address
+func AddrFromBNet(ip bnet.IP) netip.Addr {
+	var a [AddrSize]byte
+	if ip.IsIPv4() {
+		binary.BigEndian.PutUint32(a[:4], ip.ToUint32())
+		return netip.AddrFrom4([4]byte(a[:4]))
+	}
+
+	binary.BigEndian.PutUint64(a[:8], ip.Higher())
+	binary.BigEndian.PutUint64(a[8:], ip.Lower())
+	return netip.AddrFrom16(a)
+}
+
+// AddrToBNet converts addr to a bio-rd address, e.g. for route lookups
+func AddrToBNet(addr netip.Addr) bnet.IP {
+	if addr.Is4() {
+		a := addr.As4()
+		return bnet.IPv4FromOctets(a[0], a[1], a[2], a[3])
+	}
+
+	a := addr.As16()
+	return bnet.IPv6(binary.BigEndian.Uint64(a[:8]), binary.BigEndian.Uint64(a[8:]))
+}
+
+// PrefixFromBNet converts a bio-rd prefix, the unset prefix if pfx is nil
+func PrefixFromBNet(pfx *bnet.Prefix) netip.Prefix {
+	if pfx == nil || pfx.Addr() == nil {
+		return netip.Prefix{}
+	}
+
+	return netip.PrefixFrom(AddrFromBNet(*pfx.Addr()), int(pfx.Pfxlen()))
+}
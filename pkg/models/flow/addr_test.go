@@ -0,0 +1,76 @@
+package flow
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestPutAddr(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     netip.Addr
+		expected []byte
+		read     netip.Addr
+	}{
+		{
+			name:     "IPv4",
+			addr:     netip.MustParseAddr("192.0.2.1"),
+			expected: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 192, 0, 2, 1},
+			read:     netip.MustParseAddr("192.0.2.1"),
+		},
+		{
+			name:     "IPv6",
+			addr:     netip.MustParseAddr("2001:db8::1"),
+			expected: []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+			read:     netip.MustParseAddr("2001:db8::1"),
+		},
+		{
+			name:     "Unset",
+			addr:     netip.Addr{},
+			expected: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0, 0, 0, 0},
+			read:     netip.IPv4Unspecified(),
+		},
+		{
+			name:     "IPv6 unspecified",
+			addr:     netip.IPv6Unspecified(),
+			expected: make([]byte, AddrSize),
+			read:     netip.IPv6Unspecified(),
+		},
+	}
+
+	for _, test := range tests {
+		b := make([]byte, AddrSize)
+		PutAddr(b, test.addr)
+
+		assert.Equal(t, test.expected, b, test.name)
+		assert.Equal(t, test.read, ReadAddr(b), test.name)
+	}
+}
+
+func TestAddrFromBytes(t *testing.T) {
+	assert.Equal(t, netip.MustParseAddr("192.0.2.1"), AddrFromBytes([]byte{192, 0, 2, 1}))
+	assert.Equal(t, netip.MustParseAddr("2001:db8::1"), AddrFromBytes(netip.MustParseAddr("2001:db8::1").AsSlice()))
+	assert.False(t, AddrFromBytes([]byte{192, 0, 2}).IsValid())
+}
+
+func TestBNet(t *testing.T) {
+	for _, s := range []string{"192.0.2.1", "2001:db8::1", "::ffff:192.0.2.1"} {
+		addr := netip.MustParseAddr(s)
+		ip := AddrToBNet(addr)
+
+		assert.Equal(t, addr, AddrFromBNet(ip), s)
+		assert.Equal(t, addr.Is4(), ip.IsIPv4(), s)
+	}
+
+	pfx, err := bnet.PrefixFromString("198.51.100.0/24")
+	if err != nil {
+		t.Fatalf("Unable to parse prefix: %v", err)
+	}
+
+	assert.Equal(t, netip.MustParsePrefix("198.51.100.0/24"), PrefixFromBNet(pfx))
+	assert.Equal(t, netip.Prefix{}, PrefixFromBNet(nil))
+}
@@ -2,39 +2,137 @@ package flow
 
 import (
 	"fmt"
-
-	bnet "github.com/bio-routing/bio-rd/net"
+	"net/netip"
 )
 
 // Flow defines a network flow
 type Flow struct {
-	Agent      bnet.IP
-	SrcPort    uint16
-	DstPort    uint16
-	SrcAs      uint32
-	DstAs      uint32
-	NextAs     uint32
-	IntIn      string
-	IntOut     string
-	Packets    uint64
-	Protocol   uint8
-	Family     uint8
-	Timestamp  int64
-	Size       uint64
-	Samplerate uint64
-	SrcAddr    bnet.IP
-	DstAddr    bnet.IP
-	NextHop    bnet.IP
-	SrcPfx     bnet.Prefix
-	DstPfx     bnet.Prefix
-	VRFIn      uint64
-	VRFOut     uint64
+	Agent         netip.Addr
+	SrcPort       uint16
+	DstPort       uint16
+	SrcAs         uint32
+	DstAs         uint32
+	NextAs        uint32
+	IntIn         string
+	IntOut        string
+	Packets       uint64
+	Protocol      uint8
+	Family        uint8
+	Timestamp     int64
+	Size          uint64
+	Samplerate    uint64
+	SrcAddr       netip.Addr
+	DstAddr       netip.Addr
+	NextHop       netip.Addr
+	SrcPfx        netip.Prefix
+	DstPfx        netip.Prefix
+	VRFIn         uint64
+	VRFOut        uint64
+	SrcHostname   string
+	DstHostname   string
+	Customer      string
+	Service       string
+	TrafficClass  string
+	TCPFlags      uint8
+	DSCP          uint8
+	ICMPType      uint8
+	ICMPCode      uint8
+	SrcVLAN       uint16
+	DstVLAN       uint16
+	SrcMAC        uint64
+	DstMAC        uint64
+	TunnelType    string
+	TunnelID      uint32
+	InnerSrcAddr  netip.Addr
+	InnerDstAddr  netip.Addr
+	InnerProtocol uint8
+	InnerSrcPort  uint16
+	InnerDstPort  uint16
+
+	// Direction is the direction of the flow at the observation point ("ingress", "egress" or "" if unknown)
+	Direction           string
+	ObservationDomainID uint32
+	ObservationPointID  uint64
+
+	// FlowStart and FlowEnd are the first and last time the flow was seen (unix time in milliseconds)
+	FlowStart int64
+	FlowEnd   int64
+
+	// ReceivedAt is the time the collector received the flow, ExportedAt the export time reported by the exporter
+	// (unix time in seconds, 0 if unknown)
+	ReceivedAt int64
+	ExportedAt int64
+
+	// SrcRPKIState and DstRPKIState are the RPKI origin validation states of SrcPfx and DstPfx
+	SrcRPKIState string
+	DstRPKIState string
+
+	// SrcBogon and DstBogon are set if the address is in bogon/reserved address space
+	SrcBogon bool
+	DstBogon bool
+
+	// SrcThreatFeed and DstThreatFeed are the names of the threat intelligence feeds listing the address
+	SrcThreatFeed string
+	DstThreatFeed string
+
+	// AgentName, AgentSite and AgentRole are the name, site and role of the agent in the inventory
+	AgentName string
+	AgentSite string
+	AgentRole string
+
+	// ExportProtocol is the protocol the flow was exported with (ExportProtocolSFlow, ExportProtocolIPFIX or
+	// ExportProtocolCapture)
+	ExportProtocol string
+
+	// Application is the application identified by the exporter, its name or application ID (<engine>:<selector>)
+	Application string
+
+	// Extra are the values of the extra columns configured for fields of the exporter, in the order of the columns
+	Extra []string
+
+	// Raw are the fields of the exporter not mapped into any column by key, if raw fields are enabled
+	Raw map[string]string
 }
 
+const (
+	// DirectionIngress denotes a flow observed on ingress
+	DirectionIngress = "ingress"
+
+	// DirectionEgress denotes a flow observed on egress
+	DirectionEgress = "egress"
+
+	// ExportProtocolSFlow denotes a flow sampled by an sFlow agent
+	ExportProtocolSFlow = "sflow"
+
+	// ExportProtocolIPFIX denotes a flow exported by an IPFIX (or NetFlow) exporter
+	ExportProtocolIPFIX = "ipfix"
+
+	// ExportProtocolCapture denotes a flow sampled from a local packet capture
+	ExportProtocolCapture = "capture"
+)
+
 // Add adds up to flows
 func (fl *Flow) Add(a *Flow) {
 	fl.Size += a.Size
 	fl.Packets += a.Packets
+	fl.TCPFlags |= a.TCPFlags
+
+	if a.FlowStart != 0 && (fl.FlowStart == 0 || a.FlowStart < fl.FlowStart) {
+		fl.FlowStart = a.FlowStart
+	}
+
+	if a.FlowEnd > fl.FlowEnd {
+		fl.FlowEnd = a.FlowEnd
+	}
+}
+
+// DurationMilliseconds returns the time between the first and the last packet of the flow
+func (fl *Flow) DurationMilliseconds() uint64 {
+	if fl.FlowEnd <= fl.FlowStart {
+		return 0
+	}
+
+	return uint64(fl.FlowEnd - fl.FlowStart)
 }
 
 // Dump dumps the flow
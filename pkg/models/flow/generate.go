@@ -0,0 +1,3 @@
+package flow
+
+//go:generate go run ../../../cmd/flowhouse-schemagen -schema schema.yaml -root ../../..
@@ -0,0 +1,32 @@
+package flow
+
+import "sync"
+
+// Flows are recycled to keep the allocation rate low at high flow rates. A flow is owned by exactly one stage at a
+// time: the server decoding it, the aggregator, the ingest queue, the pipeline enriching its batch, then the writer
+// inserting it. The owner releases it once it is done with it. Nothing may keep a pointer to a flow after passing it on or releasing it,
+// values have to be copied instead.
+var pool = sync.Pool{
+	New: func() interface{} {
+		return &Flow{}
+	},
+}
+
+// New gets a zeroed flow from the pool
+func New() *Flow {
+	return pool.Get().(*Flow)
+}
+
+// Release returns fl to the pool. fl must not be used afterwards.
+func Release(fl *Flow) {
+	*fl = Flow{}
+	pool.Put(fl)
+}
+
+// ReleaseAll returns all flows of a batch to the pool
+func ReleaseAll(flows []*Flow) {
+	for i, fl := range flows {
+		Release(fl)
+		flows[i] = nil
+	}
+}
@@ -0,0 +1,25 @@
+package flow
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelease(t *testing.T) {
+	flows := []*Flow{New(), New()}
+	for _, fl := range flows {
+		fl.SrcAddr = netip.MustParseAddr("192.0.2.1")
+		fl.Size = 1500
+		fl.IntIn = "eth0"
+	}
+
+	ReleaseAll(flows)
+	assert.Equal(t, []*Flow{nil, nil}, flows)
+
+	// flows from the pool have to be zeroed no matter if they were recycled
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, &Flow{}, New())
+	}
+}
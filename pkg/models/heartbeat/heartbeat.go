@@ -0,0 +1,24 @@
+package heartbeat
+
+import (
+	"net/netip"
+	"time"
+)
+
+// Heartbeat is a periodic sign of life of a flowhouse listener, for the listener itself or one of its agents
+type Heartbeat struct {
+	Timestamp time.Time
+	Instance  string
+	Listener  string
+
+	// Agent is the agent the heartbeat is for, unspecified for the heartbeat of the listener
+	Agent netip.Addr
+
+	// Flows is the number of flows received since the previous heartbeat
+	Flows uint64
+}
+
+// IsListener checks if the heartbeat is the one of the listener rather than one of its agents
+func (h *Heartbeat) IsListener() bool {
+	return h.Agent == netip.IPv6Unspecified()
+}
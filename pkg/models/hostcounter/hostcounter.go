@@ -0,0 +1,71 @@
+package hostcounter
+
+import (
+	"net/netip"
+	"time"
+)
+
+// Sample is a host sflow counter sample of a host or of one of its virtual machines or containers
+type Sample struct {
+	Timestamp time.Time
+	Agent     netip.Addr
+
+	// SourceIndex is the index of the data source the sample was taken on
+	SourceIndex uint32
+
+	// ParentIndex is the source index of the host of a virtual machine or container, 0 for a host
+	ParentIndex uint32
+
+	Hostname    string
+	UUID        string
+	MachineType string
+	OSName      string
+	OSRelease   string
+
+	LoadOne     float32
+	LoadFive    float32
+	LoadFifteen float32
+	ProcRun     uint32
+	ProcTotal   uint32
+	CPUNum      uint32
+
+	// CPUSpeed is the speed of the CPUs in MHz
+	CPUSpeed uint32
+
+	// Uptime is the uptime in seconds
+	Uptime uint32
+
+	// CPU times are in milliseconds
+	CPUUser     uint32
+	CPUNice     uint32
+	CPUSystem   uint32
+	CPUIdle     uint32
+	CPUWio      uint32
+	CPUIntr     uint32
+	CPUSoftIntr uint32
+	Interrupts  uint32
+	Contexts    uint32
+
+	// Memory sizes are in bytes
+	MemTotal   uint64
+	MemFree    uint64
+	MemShared  uint64
+	MemBuffers uint64
+	MemCached  uint64
+	SwapTotal  uint64
+	SwapFree   uint64
+	PageIn     uint32
+	PageOut    uint32
+	SwapIn     uint32
+	SwapOut    uint32
+
+	// VirtState is the state of a virtual machine or container, e.g. running
+	VirtState string
+
+	// VirtCPUTime is the CPU time of a virtual machine or container in milliseconds
+	VirtCPUTime uint32
+	VirtCPUs    uint32
+
+	VirtMemory    uint64
+	VirtMaxMemory uint64
+}
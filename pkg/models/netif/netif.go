@@ -0,0 +1,14 @@
+package netif
+
+import (
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+// Interface defines an agents network interface
+type Interface struct {
+	Agent       bnet.IP
+	Index       uint32
+	Name        string
+	Description string
+	Speed       uint64
+}
@@ -0,0 +1,85 @@
+package preferences
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// ThemeLight is the default theme
+	ThemeLight = "light"
+
+	// ThemeDark is the dark theme
+	ThemeDark = "dark"
+
+	// DefaultTimeRange is the default time range of the index view in seconds
+	DefaultTimeRange = 900
+
+	maxTimeRange = 366 * 24 * 3600
+)
+
+var languageRegex = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})?$`)
+
+// Preferences are the defaults of a user for the index view
+type Preferences struct {
+	// Timezone is the IANA name of the timezone times are shown and entered in (empty for UTC)
+	Timezone string `json:"timezone"`
+
+	// TimeRange is the default time range in seconds before now
+	TimeRange int64 `json:"time_range"`
+
+	// Breakdowns are the fields selected by default
+	Breakdowns []string `json:"breakdowns"`
+
+	// Theme is light or dark
+	Theme string `json:"theme"`
+
+	// Language is the language tag of the UI (e.g. de), empty to use the language of the browser
+	Language string `json:"language"`
+}
+
+// Defaults returns the preferences of users that have not set any
+func Defaults() *Preferences {
+	return &Preferences{
+		TimeRange:  DefaultTimeRange,
+		Breakdowns: []string{},
+		Theme:      ThemeLight,
+	}
+}
+
+// Validate checks the preferences and fills in defaults for unset values
+func (p *Preferences) Validate() error {
+	if p.Timezone != "" {
+		_, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			return fmt.Errorf("Unknown timezone %q", p.Timezone)
+		}
+	}
+
+	if p.TimeRange == 0 {
+		p.TimeRange = DefaultTimeRange
+	}
+
+	if p.TimeRange < 0 || p.TimeRange > maxTimeRange {
+		return fmt.Errorf("Time range must be between 1 and %d seconds", maxTimeRange)
+	}
+
+	if p.Breakdowns == nil {
+		p.Breakdowns = []string{}
+	}
+
+	if p.Theme == "" {
+		p.Theme = ThemeLight
+	}
+
+	if p.Theme != ThemeLight && p.Theme != ThemeDark {
+		return fmt.Errorf("Unknown theme %q", p.Theme)
+	}
+
+	if p.Language != "" && !languageRegex.MatchString(p.Language) {
+		return fmt.Errorf("Invalid language %q", p.Language)
+	}
+
+	return nil
+}
@@ -30,34 +30,33 @@ const SetIDTemplateMax = 255
 // TemplateSetID is the set ID reserved for template sets
 const TemplateSetID = 2
 
+// sizeOfEnterpriseNumber is the size of the enterprise number of enterprise-specific fields
+const sizeOfEnterpriseNumber = 4
+
 // errorIncompatibleVersion prints an error message in case the detected version is not supported
 func errorIncompatibleVersion(version uint16) error {
 	return errors.Errorf("IPFIX: Incompatible protocol version v%d, only v10 is supported", version)
 }
 
-// Decode is the main function of this package. It converts raw packet bytes to Packet struct.
+// Decode is the main function of this package. It converts raw packet bytes to Packet struct. Malformed packets fail
+// with an error, no read goes past the packet.
 func Decode(raw []byte) (*Packet, error) {
 	data := convert.Reverse(raw) //TODO: Make it endian aware. This assumes a little endian machine
 
 	pSize := len(data)
-	bufSize := 1500
-	buffer := [1500]byte{}
-
-	if pSize > bufSize {
-		panic("Buffer too small\n")
+	if uintptr(pSize) < sizeOfHeader {
+		return nil, errors.Errorf("IPFIX: Packet of %d bytes is shorter than its header", pSize)
 	}
 
-	// copy data into array as arrays allow us to cast the shit out of it
-	for i := 0; i < pSize; i++ {
-		buffer[bufSize-pSize+i] = data[i]
-	}
+	// the byte past the packet keeps the top of the packet inside the buffer
+	buffer := make([]byte, pSize+1)
+	copy(buffer, data)
 
-	bufferPtr := unsafe.Pointer(&buffer)
-	bufferMinPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(pSize))
-	headerPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(sizeOfHeader))
+	bufferMinPtr := unsafe.Pointer(&buffer[0])
+	headerPtr := unsafe.Pointer(uintptr(unsafe.Pointer(&buffer[pSize])) - sizeOfHeader)
 
 	var packet Packet
-	packet.Buffer = buffer[:]
+	packet.Buffer = buffer[:pSize]
 	packet.Header = (*Header)(headerPtr)
 
 	if packet.Header.Version != 10 {
@@ -68,24 +67,34 @@ func Decode(raw []byte) (*Packet, error) {
 	packet.Templates = make([]*TemplateRecords, 0, numPreAllocRecs)
 
 	for uintptr(headerPtr) > uintptr(bufferMinPtr) {
+		remaining := uintptr(headerPtr) - uintptr(bufferMinPtr)
+		if remaining < sizeOfSetHeader {
+			return nil, errors.Errorf("Set header exceeds the packet, %d bytes left", remaining)
+		}
+
 		ptr := unsafe.Pointer(uintptr(headerPtr) - sizeOfSetHeader)
 
 		fls := &Set{
 			Header: (*SetHeader)(ptr),
 		}
 
+		length := uintptr(fls.Header.Length)
+		if length < sizeOfSetHeader || length > remaining {
+			return nil, errors.Errorf("Invalid length %d of set %d, %d bytes left", length, fls.Header.SetID, remaining)
+		}
+
 		if fls.Header.SetID == TemplateSetID {
 			// Template
-			err := decodeTemplate(&packet, ptr, uintptr(fls.Header.Length)-sizeOfSetHeader)
+			err := decodeTemplate(&packet, ptr, length-sizeOfSetHeader)
 			if err != nil {
 				return nil, errors.Wrap(err, "Unable to decode template")
 			}
 		} else if fls.Header.SetID > SetIDTemplateMax {
 			// Actual data packet
-			decodeData(&packet, ptr, uintptr(fls.Header.Length)-sizeOfSetHeader)
+			decodeData(&packet, ptr, length-sizeOfSetHeader)
 		}
 
-		headerPtr = unsafe.Pointer(uintptr(headerPtr) - uintptr(fls.Header.Length))
+		headerPtr = unsafe.Pointer(uintptr(headerPtr) - length)
 	}
 
 	return &packet, nil
@@ -94,11 +103,11 @@ func Decode(raw []byte) (*Packet, error) {
 // decodeData decodes a flowSet from `packet`
 func decodeData(packet *Packet, headerPtr unsafe.Pointer, size uintptr) {
 	flsh := (*SetHeader)(unsafe.Pointer(headerPtr))
-	data := unsafe.Pointer(uintptr(headerPtr) - uintptr(flsh.Length))
+	data := unsafe.Pointer(uintptr(headerPtr) - size)
 
 	fls := &Set{
 		Header:  flsh,
-		Records: (*(*[1<<31 - 1]byte)(data))[sizeOfSetHeader:flsh.Length],
+		Records: (*(*[1<<31 - 1]byte)(data))[:size:size],
 	}
 
 	packet.FlowSets = append(packet.FlowSets, fls)
@@ -108,6 +117,11 @@ func decodeData(packet *Packet, headerPtr unsafe.Pointer, size uintptr) {
 func decodeTemplate(packet *Packet, end unsafe.Pointer, size uintptr) error {
 	min := uintptr(end) - size
 	for uintptr(end) > min {
+		// less than a template record header left is padding
+		if uintptr(end)-min < sizeOfTemplateRecordHeader {
+			break
+		}
+
 		headerPtr := unsafe.Pointer(uintptr(end) - sizeOfTemplateRecordHeader)
 
 		tmplRecs := &TemplateRecords{}
@@ -115,20 +129,36 @@ func decodeTemplate(packet *Packet, end unsafe.Pointer, size uintptr) error {
 		tmplRecs.Packet = packet
 		tmplRecs.Records = make([]*TemplateRecord, 0, numPreAllocRecs)
 
-		ptr := unsafe.Pointer(uintptr(headerPtr) - sizeOfTemplateRecordHeader)
+		tmplRecs.EnterpriseNumbers = make([]uint32, 0, numPreAllocRecs)
+
+		// next is the top of the next field specifier, it never points below the set
+		next := headerPtr
 		for i := uint16(0); i < tmplRecs.Header.FieldCount; i++ {
-			rec := (*TemplateRecord)(unsafe.Pointer(ptr))
+			if uintptr(next)-min < sizeOfTemplateRecord {
+				return fmt.Errorf("Template %d exceeds its set", tmplRecs.Header.TemplateID)
+			}
+
+			ptr := unsafe.Pointer(uintptr(next) - sizeOfTemplateRecord)
+			rec := (*TemplateRecord)(ptr)
+			enterprise := uint32(0)
 
+			// the enterprise number follows the field specifier of enterprise-specific fields
 			if rec.isEnterprise() {
-				return fmt.Errorf("Enterprise TLV currently not supported")
+				if uintptr(ptr)-min < sizeOfEnterpriseNumber {
+					return fmt.Errorf("Template %d exceeds its set", tmplRecs.Header.TemplateID)
+				}
+
+				ptr = unsafe.Pointer(uintptr(ptr) - sizeOfEnterpriseNumber)
+				enterprise = *(*uint32)(ptr)
 			}
 
 			tmplRecs.Records = append(tmplRecs.Records, rec)
-			ptr = unsafe.Pointer(uintptr(ptr) - sizeOfTemplateRecord)
+			tmplRecs.EnterpriseNumbers = append(tmplRecs.EnterpriseNumbers, enterprise)
+			next = ptr
 		}
 
 		packet.Templates = append(packet.Templates, tmplRecs)
-		end = unsafe.Pointer(uintptr(end) - uintptr(tmplRecs.Header.FieldCount)*sizeOfTemplateRecord - sizeOfTemplateRecordHeader)
+		end = next
 	}
 
 	return nil
@@ -20,6 +20,9 @@ func TestDecodeTemplate(t *testing.T) {
 			pkt: &Packet{
 				Templates: make([]*TemplateRecords, 0),
 			},
+			expected: &Packet{
+				Templates: make([]*TemplateRecords, 0),
+			},
 		},
 	}
 
@@ -28,3 +31,158 @@ func TestDecodeTemplate(t *testing.T) {
 		assert.Equal(t, test.expected, test.pkt, test.name)
 	}
 }
+
+func TestDecodeVariableLengthAndEnterpriseFields(t *testing.T) {
+	raw := testPacket()
+
+	p, err := Decode(raw)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if !assert.Len(t, p.Templates, 1) {
+		return
+	}
+
+	tmpl := p.Templates[0]
+	assert.Equal(t, uint16(256), tmpl.Header.TemplateID)
+	if assert.Len(t, tmpl.Records, 3) {
+		assert.Equal(t, uint16(82), tmpl.Records[1].ElementID())
+		assert.Equal(t, uint16(VariableLength), tmpl.Records[1].Length)
+		assert.Equal(t, uint16(1), tmpl.Records[2].ElementID())
+	}
+	assert.Equal(t, []uint32{0, 0, 9}, tmpl.EnterpriseNumbers)
+
+	if !assert.Len(t, p.FlowSets, 1) {
+		return
+	}
+
+	records := tmpl.DecodeFlowSet(*p.FlowSets[0])
+	if assert.Len(t, records, 2) {
+		// values are reversed like the datagram
+		assert.Equal(t, []byte{1, 2, 0, 192}, records[0].Values[0])
+		assert.Equal(t, []byte("0te"), records[0].Values[1])
+		assert.Equal(t, []byte{42, 0}, records[0].Values[2])
+		assert.Equal(t, []byte("1ex"), records[1].Values[1])
+		assert.Equal(t, []byte{7, 0}, records[1].Values[2])
+	}
+}
+
+// testPacket is a packet with a template of variable length and enterprise fields and a data set of two records
+func testPacket() []byte {
+	return []byte{
+		// header: version 10, length 68, export time, sequence number, domain 1
+		0, 10, 0, 68, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1,
+
+		// template set: template 256 with sourceIPv4Address, interfaceName (variable length) and an enterprise field
+		0, 2, 0, 24,
+		1, 0, 0, 3,
+		0, 8, 0, 4,
+		0, 82, 0xff, 0xff,
+		0x80, 1, 0, 2, 0, 0, 0, 9,
+
+		// data set with two records, the second one with a three byte length
+		1, 0, 0, 28,
+		192, 0, 2, 1, 3, 'e', 't', '0', 0, 42,
+		192, 0, 2, 2, 255, 0, 3, 'x', 'e', '1', 0, 7,
+		0, 0,
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	// the decoder reverses packets in place
+	header := func(sets ...byte) []byte {
+		return append([]byte{0, 10, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1}, sets...)
+	}
+
+	tests := []struct {
+		name    string
+		raw     []byte
+		wantErr bool
+	}{
+		{
+			name:    "Empty",
+			raw:     []byte{},
+			wantErr: true,
+		},
+		{
+			name:    "Truncated header",
+			raw:     header()[:12],
+			wantErr: true,
+		},
+		{
+			name: "Header only",
+			raw:  header(),
+		},
+		{
+			name:    "Truncated set header",
+			raw:     header(1, 0),
+			wantErr: true,
+		},
+		{
+			name:    "Set of zero length",
+			raw:     header(1, 0, 0, 0),
+			wantErr: true,
+		},
+		{
+			name:    "Set exceeding the packet",
+			raw:     header(1, 0, 0, 8, 0, 0),
+			wantErr: true,
+		},
+		{
+			name:    "Template exceeding its set",
+			raw:     header(0, 2, 0, 12, 1, 0, 0, 3, 0, 8, 0, 4),
+			wantErr: true,
+		},
+		{
+			name: "Padded template set",
+			raw:  header(0, 2, 0, 14, 1, 0, 0, 1, 0, 8, 0, 4, 0, 0),
+		},
+		{
+			name: "Packet larger than 1500 bytes",
+			raw:  header(append([]byte{1, 0, 0x07, 0xd4}, make([]byte, 2000)...)...),
+		},
+	}
+
+	for _, test := range tests {
+		_, err := Decode(test.raw)
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+	}
+}
+
+func TestDecodeFlowSetZeroLengthFields(t *testing.T) {
+	tmpl := &TemplateRecords{
+		Header:  &TemplateRecordHeader{TemplateID: 256},
+		Records: []*TemplateRecord{{Type: 8, Length: 0}},
+	}
+	set := Set{
+		Header:  &SetHeader{SetID: 256},
+		Records: make([]byte, 8),
+	}
+
+	assert.Empty(t, tmpl.DecodeFlowSet(set))
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add(testPacket())
+	f.Add([]byte{0, 10, 0, 20, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 1, 0, 0, 4})
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		p, err := Decode(append([]byte{}, raw...))
+		if err != nil {
+			return
+		}
+
+		// data sets are decoded by the templates of the same packet as a collector would after caching them
+		for _, tmpl := range p.Templates {
+			for _, set := range p.FlowSets {
+				tmpl.DecodeFlowSet(*set)
+			}
+		}
+	})
+}
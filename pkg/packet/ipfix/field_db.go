@@ -11,6 +11,10 @@
 
 package ipfix
 
+import (
+	"fmt"
+)
+
 const (
 	InBytes                   = 1
 	InPkts                    = 2
@@ -108,5 +112,128 @@ const (
 	ApplicationDescription    = 94
 	ApplicationTag            = 95
 	ApplicationName           = 96
+	ObservationPointID        = 138
+	IcmpTypeCodeIPv6          = 139
+	FlowStartSeconds          = 150
+	FlowEndSeconds            = 151
+	FlowStartMilliseconds     = 152
+	FlowEndMilliseconds       = 153
+	SysInitTimeMilliseconds   = 160
 	SamplingPacketInterval    = 305
 )
+
+var fieldNames = map[uint16]string{
+	InBytes:                   "InBytes",
+	InPkts:                    "InPkts",
+	Flows:                     "Flows",
+	Protocol:                  "Protocol",
+	SrcTos:                    "SrcTos",
+	TCPFlags:                  "TCPFlags",
+	L4SrcPort:                 "L4SrcPort",
+	IPv4SrcAddr:               "IPv4SrcAddr",
+	SrcMask:                   "SrcMask",
+	InputSnmp:                 "InputSnmp",
+	L4DstPort:                 "L4DstPort",
+	IPv4DstAddr:               "IPv4DstAddr",
+	DstMask:                   "DstMask",
+	OutputSnmp:                "OutputSnmp",
+	IPv4NextHop:               "IPv4NextHop",
+	SrcAs:                     "SrcAs",
+	DstAs:                     "DstAs",
+	BGPIPv4NextHop:            "BGPIPv4NextHop",
+	MulDstPkts:                "MulDstPkts",
+	MulDstBytes:               "MulDstBytes",
+	LastSwitched:              "LastSwitched",
+	FirstSwitched:             "FirstSwitched",
+	OutBytes:                  "OutBytes",
+	OutPkts:                   "OutPkts",
+	MinPktLngth:               "MinPktLngth",
+	MaxPktLngth:               "MaxPktLngth",
+	IPv6SrcAddr:               "IPv6SrcAddr",
+	IPv6DstAddr:               "IPv6DstAddr",
+	IPv6SrcMask:               "IPv6SrcMask",
+	IPv6DstMask:               "IPv6DstMask",
+	IPv6FlowLabel:             "IPv6FlowLabel",
+	IcmpType:                  "IcmpType",
+	MulIgmpType:               "MulIgmpType",
+	SamplingInterval:          "SamplingInterval",
+	SamplingAlgorithm:         "SamplingAlgorithm",
+	FlowActiveTimeout:         "FlowActiveTimeout",
+	FlowInactiveTimeout:       "FlowInactiveTimeout",
+	EngineType:                "EngineType",
+	EngineID:                  "EngineID",
+	TotalBytesExp:             "TotalBytesExp",
+	TotalPktsExp:              "TotalPktsExp",
+	TotalFlowsExp:             "TotalFlowsExp",
+	VendorProprietary43:       "VendorProprietary43",
+	IPv4SrcPrefix:             "IPv4SrcPrefix",
+	IPv4DstPrefix:             "IPv4DstPrefix",
+	MplsTopLabelType:          "MplsTopLabelType",
+	MplsTopLabelIPAddr:        "MplsTopLabelIPAddr",
+	FlowSamplerID:             "FlowSamplerID",
+	FlowSamplerMode:           "FlowSamplerMode",
+	FlowSamplerRandomInterval: "FlowSamplerRandomInterval",
+	VendorProprietary51:       "VendorProprietary51",
+	MinTTL:                    "MinTTL",
+	MaxTTL:                    "MaxTTL",
+	IPv4Ident:                 "IPv4Ident",
+	DstTos:                    "DstTos",
+	InSrcMac:                  "InSrcMac",
+	OutDstMac:                 "OutDstMac",
+	SrcVlan:                   "SrcVlan",
+	DstVlan:                   "DstVlan",
+	IPProtocolVersion:         "IPProtocolVersion",
+	Direction:                 "Direction",
+	IPv6NextHop:               "IPv6NextHop",
+	BgpIPv6NextHop:            "BgpIPv6NextHop",
+	IPv6OptionsHeaders:        "IPv6OptionsHeaders",
+	VendorProprietary65:       "VendorProprietary65",
+	VendorProprietary66:       "VendorProprietary66",
+	VendorProprietary67:       "VendorProprietary67",
+	VendorProprietary68:       "VendorProprietary68",
+	VendorProprietary69:       "VendorProprietary69",
+	MplsLabel1:                "MplsLabel1",
+	MplsLabel2:                "MplsLabel2",
+	MplsLabel3:                "MplsLabel3",
+	MplsLabel4:                "MplsLabel4",
+	MplsLabel5:                "MplsLabel5",
+	MplsLabel6:                "MplsLabel6",
+	MplsLabel7:                "MplsLabel7",
+	MplsLabel8:                "MplsLabel8",
+	MplsLabel9:                "MplsLabel9",
+	MplsLabel10:               "MplsLabel10",
+	InDstMac:                  "InDstMac",
+	OutSrcMac:                 "OutSrcMac",
+	IfName:                    "IfName",
+	IfDesc:                    "IfDesc",
+	SamplerName:               "SamplerName",
+	InPermanentBytes:          "InPermanentBytes",
+	InPermanentPkts:           "InPermanentPkts",
+	VendorProprietary87:       "VendorProprietary87",
+	FragmentOffset:            "FragmentOffset",
+	ForwardingStatus:          "ForwardingStatus",
+	MplsPalRd:                 "MplsPalRd",
+	MplsPrefixLen:             "MplsPrefixLen",
+	SrcTrafficIndex:           "SrcTrafficIndex",
+	DstTrafficIndex:           "DstTrafficIndex",
+	ApplicationDescription:    "ApplicationDescription",
+	ApplicationTag:            "ApplicationTag",
+	ApplicationName:           "ApplicationName",
+	ObservationPointID:        "ObservationPointID",
+	IcmpTypeCodeIPv6:          "IcmpTypeCodeIPv6",
+	FlowStartSeconds:          "FlowStartSeconds",
+	FlowEndSeconds:            "FlowEndSeconds",
+	FlowStartMilliseconds:     "FlowStartMilliseconds",
+	FlowEndMilliseconds:       "FlowEndMilliseconds",
+	SysInitTimeMilliseconds:   "SysInitTimeMilliseconds",
+	SamplingPacketInterval:    "SamplingPacketInterval",
+}
+
+// FieldName returns the name of an information element
+func FieldName(id uint16) string {
+	if name, ok := fieldNames[id]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Unknown%d", id)
+}
@@ -18,6 +18,9 @@ import (
 const (
 	// numPreAllocFlowDataRecs is number of elements to pre allocate in DataRecs slice
 	numPreAllocFlowDataRecs = 20
+
+	// VariableLength is the length of fields whose length is given in each record (RFC 7011 section 7)
+	VariableLength = 65535
 )
 
 // TemplateRecordHeader represents the header of a template record
@@ -45,6 +48,9 @@ type TemplateRecords struct {
 	// List of fields in this Template Record.
 	Records []*TemplateRecord
 
+	// EnterpriseNumbers are the enterprise numbers of the fields, 0 for IANA fields
+	EnterpriseNumbers []uint32
+
 	Packet *Packet
 
 	Values [][]byte
@@ -63,6 +69,11 @@ func (tmpl *TemplateRecord) isEnterprise() bool {
 	return tmpl.Type&0x8000 == 0x8000
 }
 
+// ElementID is the information element identifier of the field without the enterprise bit
+func (tmpl *TemplateRecord) ElementID() uint16 {
+	return tmpl.Type & 0x7fff
+}
+
 // FlowDataRecord is actual NetFlow data. This structure does not contain any
 // information about the actual data meaning. It must be combined with
 // corresponding TemplateRecord to be decoded to a single NetFlow data row.
@@ -97,6 +108,11 @@ func (dtpl *TemplateRecords) DecodeFlowSet(set Set) (list []FlowDataRecord) {
 			return
 		}
 
+		// records of templates without fields or of zero length fields take no space
+		if count == 0 {
+			return list
+		}
+
 		list = append(list, record)
 		n = n - count
 	}
@@ -111,13 +127,33 @@ func parseFieldValues(data []byte, fields []*TemplateRecord) ([][]byte, int) {
 	values := make([][]byte, len(fields))
 
 	for i, f := range fields {
-		if n < int(f.Length) {
+		l := int(f.Length)
+		if f.Length == VariableLength {
+			// the length precedes the value, in one byte or as 255 followed by two bytes
+			if n < 1 {
+				return nil, 0
+			}
+			l = int(data[n-1])
+			count++
+			n--
+
+			if l == 255 {
+				if n < 2 {
+					return nil, 0
+				}
+				l = int(data[n-1])<<8 | int(data[n-2])
+				count += 2
+				n -= 2
+			}
+		}
+
+		if n < l {
 			return nil, 0
 		}
 
-		values[i] = data[n-int(f.Length) : n]
-		count += int(f.Length)
-		n -= int(f.Length)
+		values[i] = data[n-l : n]
+		count += l
+		n -= l
 	}
 
 	return values, count
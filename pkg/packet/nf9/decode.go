@@ -39,29 +39,25 @@ func errorIncompatibleVersion(version uint16) error {
 	return errors.Errorf("NF9: Incompatible protocol version v%d, only v9 is supported", version)
 }
 
-// Decode is the main function of this package. It converts raw packet bytes to Packet struct.
+// Decode is the main function of this package. It converts raw packet bytes to Packet struct. Malformed packets fail
+// with an error, no read goes past the packet.
 func Decode(raw []byte, remote net.IP) (*Packet, error) {
 	data := convert.Reverse(raw) //TODO: Make it endian aware. This assumes a little endian machine
 
 	pSize := len(data)
-	bufSize := 1500
-	buffer := [1500]byte{}
-
-	if pSize > bufSize {
-		panic("Buffer too small\n")
+	if uintptr(pSize) < sizeOfHeader {
+		return nil, errors.Errorf("NF9: Packet of %d bytes is shorter than its header", pSize)
 	}
 
-	// copy data into array as arrays allow us to cast the shit out of it
-	for i := 0; i < pSize; i++ {
-		buffer[bufSize-pSize+i] = data[i]
-	}
+	// the byte past the packet keeps the top of the packet inside the buffer
+	buffer := make([]byte, pSize+1)
+	copy(buffer, data)
 
-	bufferPtr := unsafe.Pointer(&buffer)
-	bufferMinPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(pSize))
-	headerPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(sizeOfHeader))
+	bufferMinPtr := unsafe.Pointer(&buffer[0])
+	headerPtr := unsafe.Pointer(uintptr(unsafe.Pointer(&buffer[pSize])) - sizeOfHeader)
 
 	var packet Packet
-	packet.Buffer = buffer[:]
+	packet.Buffer = buffer[:pSize]
 	packet.Header = (*Header)(headerPtr)
 
 	if packet.Header.Version != 9 {
@@ -72,55 +68,79 @@ func Decode(raw []byte, remote net.IP) (*Packet, error) {
 	packet.Templates = make([]*TemplateRecords, 0, numPreAllocRecs)
 
 	for uintptr(headerPtr) > uintptr(bufferMinPtr) {
+		remaining := uintptr(headerPtr) - uintptr(bufferMinPtr)
+		if remaining < sizeOfFlowSetHeader {
+			return nil, errors.Errorf("FlowSet header exceeds the packet, %d bytes left", remaining)
+		}
+
 		ptr := unsafe.Pointer(uintptr(headerPtr) - sizeOfFlowSetHeader)
 
 		fls := &FlowSet{
 			Header: (*FlowSetHeader)(ptr),
 		}
 
+		length := uintptr(fls.Header.Length)
+		if length < sizeOfFlowSetHeader || length > remaining {
+			return nil, errors.Errorf("Invalid length %d of FlowSet %d, %d bytes left", length, fls.Header.FlowSetID, remaining)
+		}
+
 		if fls.Header.FlowSetID == TemplateFlowSetID {
 			// Template
-			decodeTemplate(&packet, ptr, uintptr(fls.Header.Length)-sizeOfFlowSetHeader, remote)
+			err := decodeTemplate(&packet, ptr, length-sizeOfFlowSetHeader, remote)
+			if err != nil {
+				return nil, errors.Wrap(err, "Unable to decode template")
+			}
 		} else if fls.Header.FlowSetID == OptionTemplateFlowSetID {
 			// Option Template
-			decodeOption(&packet, ptr, uintptr(fls.Header.Length)-sizeOfFlowSetHeader, remote)
+			err := decodeOption(&packet, ptr, length-sizeOfFlowSetHeader, remote)
+			if err != nil {
+				return nil, errors.Wrap(err, "Unable to decode option template")
+			}
 		} else if fls.Header.FlowSetID > FlowSetIDTemplateMax {
 			// Actual data packet
-			decodeData(&packet, ptr, uintptr(fls.Header.Length)-sizeOfFlowSetHeader)
+			decodeData(&packet, ptr, length-sizeOfFlowSetHeader)
 		}
 
-		headerPtr = unsafe.Pointer(uintptr(headerPtr) - uintptr(fls.Header.Length))
+		headerPtr = unsafe.Pointer(uintptr(headerPtr) - length)
 	}
 
 	return &packet, nil
 }
 
 // decodeOption decodes an option template from `packet`
-func decodeOption(packet *Packet, end unsafe.Pointer, size uintptr, remote net.IP) {
+func decodeOption(packet *Packet, end unsafe.Pointer, size uintptr, remote net.IP) error {
 	min := uintptr(end) - size
 
 	for uintptr(end) > min {
+		// less than an options template record header left is padding
+		if uintptr(end)-min < sizeOfOptionsTemplateRecordHeader {
+			break
+		}
+
 		headerPtr := unsafe.Pointer(uintptr(end) - sizeOfOptionsTemplateRecordHeader)
 
 		tmplRecs := &TemplateRecords{}
 		hdr := (*OptionsTemplateRecordHeader)(unsafe.Pointer(headerPtr))
+		if uintptr(headerPtr)-min < uintptr(hdr.OptionScopeLength)+uintptr(hdr.OptionLength) {
+			return fmt.Errorf("Option template %d exceeds its FlowSet", hdr.TemplateID)
+		}
+
 		tmplRecs.Header = &TemplateRecordHeader{TemplateID: hdr.TemplateID}
 		tmplRecs.Packet = packet
 		tmplRecs.Records = make([]*TemplateRecord, 0, numPreAllocRecs)
 
-		ptr := headerPtr
-		// Process option scopes
-		for i := uint16(0); i < hdr.OptionScopeLength/uint16(sizeOfOptionScope); i++ {
-			optScope := (*OptionScope)(ptr)
+		// Process option scopes, the first one follows the header
+		numScopes := hdr.OptionScopeLength / uint16(sizeOfOptionScope)
+		for i := uint16(0); i < numScopes; i++ {
+			optScope := (*OptionScope)(unsafe.Pointer(uintptr(headerPtr) - uintptr(i+1)*sizeOfOptionScope))
 			tmplRecs.OptionScopes = append(tmplRecs.OptionScopes, optScope)
-			ptr = unsafe.Pointer(uintptr(ptr) - sizeOfOptionScope)
 		}
 
 		// Process option fields
+		fieldsPtr := unsafe.Pointer(uintptr(headerPtr) - uintptr(numScopes)*sizeOfOptionScope)
 		for i := uint16(0); i < hdr.OptionLength/uint16(sizeOfTemplateRecord); i++ {
-			opt := (*TemplateRecord)(ptr)
+			opt := (*TemplateRecord)(unsafe.Pointer(uintptr(fieldsPtr) - uintptr(i+1)*sizeOfTemplateRecord))
 			tmplRecs.Records = append(tmplRecs.Records, opt)
-			ptr = unsafe.Pointer(uintptr(ptr) - sizeOfTemplateRecord)
 		}
 
 		//packet.OptionsTemplates = append(packet.OptionsTemplates, tmplRecs)
@@ -128,40 +148,51 @@ func decodeOption(packet *Packet, end unsafe.Pointer, size uintptr, remote net.I
 
 		end = unsafe.Pointer(uintptr(end) - uintptr(hdr.OptionScopeLength) - uintptr(hdr.OptionLength) - sizeOfOptionsTemplateRecordHeader)
 	}
+
+	return nil
 }
 
 // decodeTemplate decodes a template from `packet`
-func decodeTemplate(packet *Packet, end unsafe.Pointer, size uintptr, remote net.IP) {
+func decodeTemplate(packet *Packet, end unsafe.Pointer, size uintptr, remote net.IP) error {
 	min := uintptr(end) - size
 	for uintptr(end) > min {
+		// less than a template record header left is padding
+		if uintptr(end)-min < sizeOfTemplateRecordHeader {
+			break
+		}
+
 		headerPtr := unsafe.Pointer(uintptr(end) - sizeOfTemplateRecordHeader)
 
 		tmplRecs := &TemplateRecords{}
 		tmplRecs.Header = (*TemplateRecordHeader)(unsafe.Pointer(headerPtr))
+		if uintptr(headerPtr)-min < uintptr(tmplRecs.Header.FieldCount)*sizeOfTemplateRecord {
+			return fmt.Errorf("Template %d exceeds its FlowSet", tmplRecs.Header.TemplateID)
+		}
+
 		tmplRecs.Packet = packet
 		tmplRecs.Records = make([]*TemplateRecord, 0, numPreAllocRecs)
 
-		ptr := unsafe.Pointer(uintptr(headerPtr) - sizeOfTemplateRecordHeader)
 		var i uint16
 		for i = 0; i < tmplRecs.Header.FieldCount; i++ {
-			rec := (*TemplateRecord)(unsafe.Pointer(ptr))
+			rec := (*TemplateRecord)(unsafe.Pointer(uintptr(headerPtr) - uintptr(i+1)*sizeOfTemplateRecord))
 			tmplRecs.Records = append(tmplRecs.Records, rec)
-			ptr = unsafe.Pointer(uintptr(ptr) - sizeOfTemplateRecord)
 		}
 
 		packet.Templates = append(packet.Templates, tmplRecs)
 		end = unsafe.Pointer(uintptr(end) - uintptr(tmplRecs.Header.FieldCount)*sizeOfTemplateRecord - sizeOfTemplateRecordHeader)
 	}
+
+	return nil
 }
 
 // decodeData decodes a flowSet from `packet`
 func decodeData(packet *Packet, headerPtr unsafe.Pointer, size uintptr) {
 	flsh := (*FlowSetHeader)(unsafe.Pointer(headerPtr))
-	data := unsafe.Pointer(uintptr(headerPtr) - uintptr(flsh.Length))
+	data := unsafe.Pointer(uintptr(headerPtr) - size)
 
 	fls := &FlowSet{
 		Header: flsh,
-		Flows:  (*(*[1<<31 - 1]byte)(data))[sizeOfFlowSetHeader:flsh.Length],
+		Flows:  (*(*[1<<31 - 1]byte)(data))[:size:size],
 	}
 
 	packet.FlowSets = append(packet.FlowSets, fls)
@@ -16,6 +16,7 @@ import (
 	"testing"
 
 	"github.com/bio-routing/tflow2/convert"
+	"github.com/stretchr/testify/assert"
 )
 
 /*func TestDecode(t *testing.T) {
@@ -35,6 +36,40 @@ import (
 }*/
 
 func TestDecode2(t *testing.T) {
+	s := optionTemplatePacket()
+
+	_, err := Decode(s, net.IP([]byte{1, 1, 1, 1}))
+	if err != nil {
+		t.Errorf("Decoding packet failed: %v\n", err)
+	}
+
+}
+
+func testEq(a, b []byte) bool {
+
+	if a == nil && b == nil {
+		return true
+	}
+
+	if a == nil || b == nil {
+		return false
+	}
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// optionTemplatePacket is a packet of an option template with one scope and four fields
+func optionTemplatePacket() []byte {
 	s := []byte{
 		8, 0, // Length
 		44, 0, // Type
@@ -64,34 +99,109 @@ func TestDecode2(t *testing.T) {
 		75, 91, 213, 103, // sysUpTime
 		1, 0, // Count
 		9, 0} // Version
-	s = convert.Reverse(s)
 
-	_, err := Decode(s, net.IP([]byte{1, 1, 1, 1}))
-	if err != nil {
-		t.Errorf("Decoding packet failed: %v\n", err)
+	return convert.Reverse(s)
+}
+
+func TestDecodeOptionTemplate(t *testing.T) {
+	p, err := Decode(optionTemplatePacket(), net.IP([]byte{1, 1, 1, 1}))
+	if !assert.NoError(t, err) {
+		return
 	}
 
-}
+	if !assert.Len(t, p.Templates, 1) {
+		return
+	}
 
-func testEq(a, b []byte) bool {
+	tmpl := p.Templates[0]
+	assert.Equal(t, uint16(266), tmpl.Header.TemplateID)
+	if assert.Len(t, tmpl.OptionScopes, 1) {
+		assert.Equal(t, uint16(1), tmpl.OptionScopes[0].ScopeFieldType)
+		assert.Equal(t, uint16(4), tmpl.OptionScopes[0].ScopeFieldLength)
+	}
 
-	if a == nil && b == nil {
-		return true
+	if assert.Len(t, tmpl.Records, 4) {
+		assert.Equal(t, uint16(41), tmpl.Records[0].Type)
+		assert.Equal(t, uint16(44), tmpl.Records[3].Type)
 	}
+}
 
-	if a == nil || b == nil {
-		return false
+func TestDecodeMalformed(t *testing.T) {
+	// the decoder reverses packets in place
+	header := func(flowSets ...byte) []byte {
+		return append([]byte{0, 9, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0}, flowSets...)
 	}
 
-	if len(a) != len(b) {
-		return false
+	tests := []struct {
+		name    string
+		raw     []byte
+		wantErr bool
+	}{
+		{
+			name:    "Empty",
+			raw:     []byte{},
+			wantErr: true,
+		},
+		{
+			name:    "Truncated header",
+			raw:     header()[:12],
+			wantErr: true,
+		},
+		{
+			name: "Header only",
+			raw:  header(),
+		},
+		{
+			name:    "FlowSet of zero length",
+			raw:     header(1, 0, 0, 0),
+			wantErr: true,
+		},
+		{
+			name:    "FlowSet exceeding the packet",
+			raw:     header(1, 0, 0, 8, 0, 0),
+			wantErr: true,
+		},
+		{
+			name:    "Template exceeding its FlowSet",
+			raw:     header(0, 0, 0, 12, 1, 0, 0, 3, 0, 8, 0, 4),
+			wantErr: true,
+		},
+		{
+			name:    "Option template exceeding its FlowSet",
+			raw:     header(0, 1, 0, 14, 1, 0, 0, 4, 0, 8, 0, 1, 0, 4),
+			wantErr: true,
+		},
+		{
+			name: "Packet larger than 1500 bytes",
+			raw:  header(append([]byte{1, 0, 0x07, 0xd4}, make([]byte, 2000)...)...),
+		},
 	}
 
-	for i := range a {
-		if a[i] != b[i] {
-			return false
+	for _, test := range tests {
+		_, err := Decode(test.raw, net.IP([]byte{1, 1, 1, 1}))
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
 		}
+
+		assert.NoError(t, err, test.name)
 	}
+}
 
-	return true
+func FuzzDecode(f *testing.F) {
+	f.Add(optionTemplatePacket())
+	f.Add([]byte{0, 9, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 12, 1, 0, 0, 1, 0, 8, 0, 4, 1, 0, 0, 8, 1, 2, 3, 4})
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		p, err := Decode(append([]byte{}, raw...), net.IP([]byte{1, 1, 1, 1}))
+		if err != nil {
+			return
+		}
+
+		for _, tmpl := range p.Templates {
+			for _, set := range p.FlowSets {
+				DecodeFlowSet(tmpl.Records, *set)
+			}
+		}
+	})
 }
@@ -154,6 +154,11 @@ func DecodeFlowSet(templateRecords []*TemplateRecord, set FlowSet) (list []FlowD
 		if record.Values == nil {
 			return
 		}
+
+		// records of templates without fields or of zero length fields take no space
+		if count == 0 {
+			return
+		}
 		list = append(list, record)
 		n = n - count
 	}
@@ -0,0 +1,118 @@
+package packet
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// EtherTypeERSPANTypeII is the GRE protocol type of ERSPAN type I and II
+	EtherTypeERSPANTypeII = 0x88be
+
+	// EtherTypeERSPANTypeIII is the GRE protocol type of ERSPAN type III
+	EtherTypeERSPANTypeIII = 0x22eb
+
+	// ERSPANFrameTypeEthernet is the frame type of ERSPAN type III mirroring ethernet frames
+	ERSPANFrameTypeEthernet = 0
+
+	erspanSessionIDMask = 0x03ff
+	erspanVLANMask      = 0x0fff
+
+	// sizeOfERSPANPlatformHeader is the size of the platform specific subheader of ERSPAN type III
+	sizeOfERSPANPlatformHeader = 8
+)
+
+var (
+	// SizeOfERSPANTypeIIHeader is the size of an ERSPAN type II header in bytes
+	SizeOfERSPANTypeIIHeader = unsafe.Sizeof(erspanTypeIIHeader{})
+
+	// SizeOfERSPANTypeIIIHeader is the size of an ERSPAN type III header (without platform specific subheader) in bytes
+	SizeOfERSPANTypeIIIHeader = unsafe.Sizeof(erspanTypeIIIHeader{})
+)
+
+// ERSPANHeader is an ERSPAN header of any type
+type ERSPANHeader struct {
+	// Type is the ERSPAN type (1, 2 or 3)
+	Type uint8
+
+	// SessionID identifies the mirroring session
+	SessionID uint16
+
+	// VLAN is the VLAN the mirrored frame was received on (0 if not given)
+	VLAN uint16
+
+	// Egress is set for frames mirrored on egress, only type III tells the direction
+	Egress bool
+
+	// FrameType is the type of the mirrored frame, only type III mirrors other frames than ethernet frames
+	FrameType uint8
+
+	// Length is the length of the header including the platform specific subheader in bytes
+	Length uint32
+}
+
+type erspanTypeIIHeader struct {
+	ReservedIndex uint32
+	COSSessionID  uint16
+	VersionVLAN   uint16
+}
+
+type erspanTypeIIIHeader struct {
+	Flags        uint16
+	SGT          uint16
+	Timestamp    uint32
+	COSSessionID uint16
+	VersionVLAN  uint16
+}
+
+// DecodeERSPAN decodes the ERSPAN header following gre. Type I has no header, it is told apart from type II by the
+// missing GRE sequence number.
+func DecodeERSPAN(raw unsafe.Pointer, length uint32, gre *GREHeader) (*ERSPANHeader, error) {
+	switch gre.ProtocolType {
+	case EtherTypeERSPANTypeII:
+		if gre.FlagsVersion&greFlagSequence == 0 {
+			return &ERSPANHeader{Type: 1}, nil
+		}
+
+		if SizeOfERSPANTypeIIHeader > uintptr(length) {
+			return nil, errors.Errorf("Frame is too short: %d", length)
+		}
+
+		h := (*erspanTypeIIHeader)(unsafe.Pointer(uintptr(raw) - SizeOfERSPANTypeIIHeader))
+		return &ERSPANHeader{
+			Type:      2,
+			SessionID: h.COSSessionID & erspanSessionIDMask,
+			VLAN:      h.VersionVLAN & erspanVLANMask,
+			Length:    uint32(SizeOfERSPANTypeIIHeader),
+		}, nil
+
+	case EtherTypeERSPANTypeIII:
+		if SizeOfERSPANTypeIIIHeader > uintptr(length) {
+			return nil, errors.Errorf("Frame is too short: %d", length)
+		}
+
+		h := (*erspanTypeIIIHeader)(unsafe.Pointer(uintptr(raw) - SizeOfERSPANTypeIIIHeader))
+		res := &ERSPANHeader{
+			Type:      3,
+			SessionID: h.COSSessionID & erspanSessionIDMask,
+			VLAN:      h.VersionVLAN & erspanVLANMask,
+			Egress:    h.Flags&0x0008 != 0,
+			FrameType: uint8(h.Flags>>10) & 0x1f,
+			Length:    uint32(SizeOfERSPANTypeIIIHeader),
+		}
+
+		// the optional platform specific subheader follows
+		if h.Flags&0x0001 != 0 {
+			res.Length += sizeOfERSPANPlatformHeader
+		}
+
+		if res.Length > length {
+			return nil, errors.Errorf("Frame is too short: %d", length)
+		}
+
+		return res, nil
+	}
+
+	return nil, errors.Errorf("Unsupported GRE protocol type 0x%x", gre.ProtocolType)
+}
@@ -0,0 +1,48 @@
+package packet
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// GenevePort is the IANA assigned UDP port of Geneve
+	GenevePort = 6081
+)
+
+var (
+	// SizeOfGeneveHeader is the size of a Geneve header (without options) in bytes
+	SizeOfGeneveHeader = unsafe.Sizeof(GeneveHeader{})
+)
+
+type GeneveHeader struct {
+	Reserved      uint8
+	VNI           [3]byte
+	ProtocolType  uint16
+	Flags         uint8
+	VersionOptLen uint8
+}
+
+// GetVNI gets the Geneve virtual network identifier
+func (h *GeneveHeader) GetVNI() uint32 {
+	return uint32(h.VNI[0]) | uint32(h.VNI[1])<<8 | uint32(h.VNI[2])<<16
+}
+
+// OptionsLength gets the length of the variable options in bytes
+func (h *GeneveHeader) OptionsLength() uint32 {
+	return uint32(h.VersionOptLen&0x3f) * 4
+}
+
+func DecodeGeneve(raw unsafe.Pointer, length uint32) (*GeneveHeader, error) {
+	if SizeOfGeneveHeader > uintptr(length) {
+		return nil, errors.Errorf("Frame is too short: %d", length)
+	}
+
+	h := (*GeneveHeader)(unsafe.Pointer(uintptr(raw) - SizeOfGeneveHeader))
+	if uint32(SizeOfGeneveHeader)+h.OptionsLength() > length {
+		return nil, errors.Errorf("Frame is too short for options: %d", length)
+	}
+
+	return h, nil
+}
@@ -0,0 +1,76 @@
+package packet
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// GRE IP protocol number
+	GRE = 47
+
+	// EtherTypeTransparentEthernetBridging is the GRE protocol type of encapsulated ethernet frames
+	EtherTypeTransparentEthernetBridging = 0x6558
+
+	greFlagChecksum = 0x8000
+	greFlagKey      = 0x2000
+	greFlagSequence = 0x1000
+)
+
+var (
+	// SizeOfGREHeader is the size of a GRE header (without optional fields) in bytes
+	SizeOfGREHeader = unsafe.Sizeof(greHeader{})
+)
+
+type GREHeader struct {
+	ProtocolType uint16
+	FlagsVersion uint16
+
+	// Key is the optional GRE key (0 if not present)
+	Key uint32
+
+	// Length is the length of the header including optional fields in bytes
+	Length uint32
+}
+
+type greHeader struct {
+	ProtocolType uint16
+	FlagsVersion uint16
+}
+
+func DecodeGRE(raw unsafe.Pointer, length uint32) (*GREHeader, error) {
+	if SizeOfGREHeader > uintptr(length) {
+		return nil, errors.Errorf("Frame is too short: %d", length)
+	}
+
+	gh := (*greHeader)(unsafe.Pointer(uintptr(raw) - SizeOfGREHeader))
+	h := &GREHeader{
+		ProtocolType: gh.ProtocolType,
+		FlagsVersion: gh.FlagsVersion,
+		Length:       uint32(SizeOfGREHeader),
+	}
+
+	if h.FlagsVersion&greFlagChecksum != 0 {
+		h.Length += 4
+	}
+
+	if h.FlagsVersion&greFlagKey != 0 {
+		if h.Length+4 > length {
+			return nil, errors.Errorf("Frame is too short for key: %d", length)
+		}
+
+		h.Key = *(*uint32)(unsafe.Pointer(uintptr(raw) - uintptr(h.Length) - 4))
+		h.Length += 4
+	}
+
+	if h.FlagsVersion&greFlagSequence != 0 {
+		h.Length += 4
+	}
+
+	if h.Length > length {
+		return nil, errors.Errorf("Frame is too short: %d", length)
+	}
+
+	return h, nil
+}
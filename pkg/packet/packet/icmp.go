@@ -0,0 +1,34 @@
+package packet
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ICMP IP protocol number
+	ICMP = 1
+
+	// ICMPv6 IP protocol number
+	ICMPv6 = 58
+)
+
+var (
+	// SizeOfICMPHeader is the size of an ICMP header in bytes
+	SizeOfICMPHeader = unsafe.Sizeof(ICMPHeader{})
+)
+
+type ICMPHeader struct {
+	Checksum uint16
+	Code     uint8
+	Type     uint8
+}
+
+func DecodeICMP(raw unsafe.Pointer, length uint32) (*ICMPHeader, error) {
+	if SizeOfICMPHeader > uintptr(length) {
+		return nil, errors.Errorf("Frame is too short: %d", length)
+	}
+
+	return (*ICMPHeader)(unsafe.Pointer(uintptr(raw) - SizeOfICMPHeader)), nil
+}
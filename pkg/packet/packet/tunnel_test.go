@@ -0,0 +1,150 @@
+package packet
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// toBuffer copies reversed header bytes into a buffer and returns a pointer to its end
+func toBuffer(data []byte) (unsafe.Pointer, []byte) {
+	buffer := make([]byte, 64)
+	copy(buffer[len(buffer)-len(data):], data)
+
+	return unsafe.Pointer(uintptr(unsafe.Pointer(&buffer[0])) + uintptr(len(buffer))), buffer
+}
+
+func TestDecodeVXLAN(t *testing.T) {
+	ptr, buf := toBuffer([]byte{
+		0,             // Reserved
+		0x39, 0x30, 0, // VNI 12345
+		0, 0, 0, // Reserved
+		0x08, // Flags
+	})
+
+	h, err := DecodeVXLAN(ptr, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(12345), h.GetVNI())
+	assert.Equal(t, uint8(0x08), h.Flags)
+
+	_, err = DecodeVXLAN(ptr, 4)
+	assert.Error(t, err)
+	_ = buf
+}
+
+func TestDecodeGeneve(t *testing.T) {
+	ptr, buf := toBuffer([]byte{
+		0,       // Reserved
+		1, 0, 0, // VNI 1
+		0x58, 0x65, // Protocol Type
+		0,    // Flags
+		0x01, // Version 0, options length 1 (4 bytes)
+	})
+
+	h, err := DecodeGeneve(ptr, 12)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), h.GetVNI())
+	assert.Equal(t, uint16(EtherTypeTransparentEthernetBridging), h.ProtocolType)
+	assert.Equal(t, uint32(4), h.OptionsLength())
+
+	_, err = DecodeGeneve(ptr, 8)
+	assert.Error(t, err, "options exceed length")
+	_ = buf
+}
+
+func TestDecodeGRE(t *testing.T) {
+	ptr, buf := toBuffer([]byte{
+		0x2a, 0, 0, 0, // Key 42
+		0x00, 0x08, // Protocol Type IPv4
+		0x00, 0x20, // Flags: Key present
+	})
+
+	h, err := DecodeGRE(ptr, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(EtherTypeIPv4), h.ProtocolType)
+	assert.Equal(t, uint32(42), h.Key)
+	assert.Equal(t, uint32(8), h.Length)
+
+	_, err = DecodeGRE(ptr, 6)
+	assert.Error(t, err)
+	_ = buf
+}
+
+func TestDecodeERSPAN(t *testing.T) {
+	typeII, buf := toBuffer([]byte{
+		0x05, 0, 0, 0, // Index 5
+		0x07, 0x00, // Session ID 7
+		0x64, 0x10, // Version 1, VLAN 100
+	})
+	_ = buf
+
+	typeIII, buf := toBuffer([]byte{
+		0, 0, 0, 0, 0, 0, 0, 0, // Platform specific subheader
+		0x09, 0x00, // Frame type 0, direction egress, subheader present
+		0, 0, // SGT
+		0, 0, 0, 0, // Timestamp
+		0x2a, 0x00, // Session ID 42
+		0x00, 0x20, // Version 2, VLAN 0
+	})
+	_ = buf
+
+	tests := []struct {
+		name     string
+		raw      unsafe.Pointer
+		length   uint32
+		gre      *GREHeader
+		expected *ERSPANHeader
+		wantErr  bool
+	}{
+		{
+			name:     "Type I",
+			gre:      &GREHeader{ProtocolType: EtherTypeERSPANTypeII},
+			expected: &ERSPANHeader{Type: 1},
+		},
+		{
+			name:     "Type II",
+			raw:      typeII,
+			length:   8,
+			gre:      &GREHeader{ProtocolType: EtherTypeERSPANTypeII, FlagsVersion: greFlagSequence},
+			expected: &ERSPANHeader{Type: 2, SessionID: 7, VLAN: 100, Length: 8},
+		},
+		{
+			name:    "Type II truncated",
+			raw:     typeII,
+			length:  4,
+			gre:     &GREHeader{ProtocolType: EtherTypeERSPANTypeII, FlagsVersion: greFlagSequence},
+			wantErr: true,
+		},
+		{
+			name:     "Type III with platform specific subheader",
+			raw:      typeIII,
+			length:   20,
+			gre:      &GREHeader{ProtocolType: EtherTypeERSPANTypeIII},
+			expected: &ERSPANHeader{Type: 3, SessionID: 42, Egress: true, Length: 20},
+		},
+		{
+			name:    "Type III truncated subheader",
+			raw:     typeIII,
+			length:  16,
+			gre:     &GREHeader{ProtocolType: EtherTypeERSPANTypeIII},
+			wantErr: true,
+		},
+		{
+			name:    "No ERSPAN",
+			gre:     &GREHeader{ProtocolType: EtherTypeIPv4},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		h, err := DecodeERSPAN(test.raw, test.length, test.gre)
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, h, test.name)
+	}
+}
@@ -0,0 +1,37 @@
+package packet
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// VXLANPort is the IANA assigned UDP port of VXLAN
+	VXLANPort = 4789
+)
+
+var (
+	// SizeOfVXLANHeader is the size of a VXLAN header in bytes
+	SizeOfVXLANHeader = unsafe.Sizeof(VXLANHeader{})
+)
+
+type VXLANHeader struct {
+	Reserved2 uint8
+	VNI       [3]byte
+	Reserved1 [3]byte
+	Flags     uint8
+}
+
+// GetVNI gets the VXLAN network identifier
+func (h *VXLANHeader) GetVNI() uint32 {
+	return uint32(h.VNI[0]) | uint32(h.VNI[1])<<8 | uint32(h.VNI[2])<<16
+}
+
+func DecodeVXLAN(raw unsafe.Pointer, length uint32) (*VXLANHeader, error) {
+	if SizeOfVXLANHeader > uintptr(length) {
+		return nil, errors.Errorf("Frame is too short: %d", length)
+	}
+
+	return (*VXLANHeader)(unsafe.Pointer(uintptr(raw) - SizeOfVXLANHeader)), nil
+}
@@ -0,0 +1,157 @@
+package sflow
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// decodeCounterSample decodes a counter sample, expanded tells the format of its header
+func decodeCounterSample(w window, expanded bool) (*CounterSample, error) {
+	cs := &CounterSample{}
+	if expanded {
+		ptr, err := w.next(sizeOfExpandedCounterSampleHeader)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to decode expanded counter sample header")
+		}
+
+		ecsh := *(*ExpandedCounterSampleHeader)(ptr)
+		cs.ExpandedCounterSampleHeader = &ecsh
+		cs.CounterSampleHeader = ecsh.toCounterSampleHeader()
+	} else {
+		ptr, err := w.next(sizeOfCounterSampleHeader)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to decode counter sample header")
+		}
+
+		csh := *(*CounterSampleHeader)(ptr)
+		cs.CounterSampleHeader = &csh
+	}
+
+	for i := uint32(0); i < cs.CounterSampleHeader.CounterRecords; i++ {
+		record, sfType, err := w.nextTypeLength()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to decode counter record %d", i)
+		}
+
+		sfTypeEnterprise, sfTypeFormat := extractEnterpriseFormat(sfType)
+		if sfTypeEnterprise != standardSflow {
+			continue
+		}
+
+		err = decodeCounterRecord(cs, record, sfTypeFormat)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to decode counter record %d of format %d", i, sfTypeFormat)
+		}
+	}
+
+	return cs, nil
+}
+
+// decodeCounterRecord decodes the generic interface counters and host sflow structures into cs, records of other
+// structures are skipped
+func decodeCounterRecord(cs *CounterSample, w window, sfTypeFormat uint32) error {
+	_, err := w.next(sizeOfTypeLength)
+	if err != nil {
+		return err
+	}
+
+	switch sfTypeFormat {
+	case genericInterfaceCounters:
+		ptr, err := w.next(sizeOfGenericInterfaceCounters)
+		if err != nil {
+			return err
+		}
+		gic := *(*GenericInterfaceCounters)(ptr)
+		cs.GenericInterfaceCounters = &gic
+	case hostDescr:
+		cs.HostDescription, err = decodeHostDescription(w)
+		return err
+	case hostParent:
+		ptr, err := w.next(sizeOfHostParent)
+		if err != nil {
+			return err
+		}
+		hp := *(*HostParent)(ptr)
+		cs.HostParent = &hp
+	case hostCPU:
+		ptr, err := w.next(sizeOfHostCPU)
+		if err != nil {
+			return err
+		}
+		hc := *(*HostCPU)(ptr)
+		cs.HostCPU = &hc
+	case hostMemory:
+		ptr, err := w.next(sizeOfHostMemory)
+		if err != nil {
+			return err
+		}
+		hm := *(*HostMemory)(ptr)
+		cs.HostMemory = &hm
+	case virtCPU:
+		ptr, err := w.next(sizeOfVirtCPU)
+		if err != nil {
+			return err
+		}
+		vc := *(*VirtCPU)(ptr)
+		cs.VirtCPU = &vc
+	case virtMemory:
+		ptr, err := w.next(sizeOfVirtMemory)
+		if err != nil {
+			return err
+		}
+		vm := *(*VirtMemory)(ptr)
+		cs.VirtMemory = &vm
+	}
+
+	return nil
+}
+
+func decodeHostDescription(w window) (*HostDescription, error) {
+	hostname, err := w.nextString()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode hostname")
+	}
+
+	hd := &HostDescription{
+		Hostname: hostname,
+	}
+
+	uuidTop := w.ptr
+	_, err = w.next(sizeOfUUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode UUID")
+	}
+	copy(hd.UUID[:], getBytes(uuidTop, sizeOfUUID))
+
+	ptr, err := w.next(8)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode machine type")
+	}
+	hd.MachineType = *(*uint32)(unsafe.Pointer(uintptr(ptr) + 4))
+	hd.OSName = *(*uint32)(ptr)
+
+	hd.OSRelease, err = w.nextString()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode OS release")
+	}
+
+	return hd, nil
+}
+
+// nextString gets the next string and moves w past it and its padding to a multiple of 4 bytes
+func (w *window) nextString() (string, error) {
+	lengthPtr, err := w.next(4)
+	if err != nil {
+		return "", err
+	}
+
+	length := uintptr(*(*uint32)(lengthPtr))
+	top := w.ptr
+	_, err = w.next((length + 3) &^ 3)
+	if err != nil {
+		return "", err
+	}
+
+	return string(getBytes(top, length)), nil
+}
@@ -0,0 +1,202 @@
+package sflow
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// xdrString encodes s with its length and padding to a multiple of 4 bytes
+func xdrString(s string) []byte {
+	res := append(words(uint32(len(s))), s...)
+	for len(res)%4 != 0 {
+		res = append(res, 0)
+	}
+
+	return res
+}
+
+func hyper(values ...uint64) []byte {
+	res := make([]byte, 0, len(values)*8)
+	for _, v := range values {
+		res = binary.BigEndian.AppendUint64(res, v)
+	}
+
+	return res
+}
+
+func hostDescrRecord() []byte {
+	uuid := []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 1, 2, 3, 4, 5, 6, 7, 8}
+	return record(hostDescr, xdrString("web01"), uuid, words(3, 2), xdrString("6.1.0-13-amd64"))
+}
+
+func hostCPURecord() []byte {
+	loads := words(math.Float32bits(0.5), math.Float32bits(1.25), math.Float32bits(2))
+	counters := words(3, 400, 8, 2400, 86400, 1000, 10, 500, 90000, 20, 30, 40, 123456, 654321)
+
+	// the cpu_steal, cpu_guest and cpu_guest_nice additions are not decoded
+	return record(hostCPU, loads, counters, words(1, 2, 3))
+}
+
+// ifCountersRecord encodes generic interface counters of a 10G interface
+func ifCountersRecord(ifIndex uint32, inOctets uint64, inUcastPkts uint32, outOctets uint64, outUcastPkts uint32) []byte {
+	return record(genericInterfaceCounters,
+		words(ifIndex, 6), hyper(10000000000), words(1, 3),
+		hyper(inOctets), words(inUcastPkts, 2, 3, 4, 5, 6),
+		hyper(outOctets), words(outUcastPkts, 7, 8, 9, 10, 0))
+}
+
+func TestDecodeCounterSamples(t *testing.T) {
+	genericInterface := ifCountersRecord(7, 1<<40, 1000, 1<<41, 2000)
+
+	compact := record(dataCounterSample,
+		words(42, 0<<24|7, 4),
+		hostDescrRecord(),
+		genericInterface,
+		hostCPURecord(),
+		record(hostMemory, hyper(16<<30, 8<<30, 1<<20, 2<<20, 4<<30, 2<<30, 1<<30), words(10, 20, 30, 40)),
+	)
+	expanded := record(expandedCounterSample,
+		words(43, 3, 100005, 4),
+		record(hostDescr, xdrString("vm1"), make([]byte, 16), words(3, 2), xdrString("")),
+		record(hostParent, words(2, 1)),
+		record(virtCPU, words(1, 5000, 2)),
+		record(virtMemory, hyper(1<<30, 2<<30)),
+	)
+
+	p, err := Decode(testDatagram(compact, expanded))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Empty(t, p.Errors)
+	assert.Empty(t, p.FlowSamples)
+	if !assert.Len(t, p.CounterSamples, 2) {
+		return
+	}
+
+	cs := p.CounterSamples[0]
+	assert.Equal(t, &CounterSampleHeader{
+		CounterRecords:     4,
+		SourceIDClassIndex: 7,
+		SequenceNumber:     42,
+		SampleLength:       cs.CounterSampleHeader.SampleLength,
+		EnterpriseType:     dataCounterSample,
+	}, cs.CounterSampleHeader)
+	assert.Nil(t, cs.ExpandedCounterSampleHeader)
+	assert.True(t, cs.HasHostStructures())
+	assert.Equal(t, &HostDescription{
+		Hostname:    "web01",
+		UUID:        [16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 1, 2, 3, 4, 5, 6, 7, 8},
+		MachineType: 3,
+		OSName:      2,
+		OSRelease:   "6.1.0-13-amd64",
+	}, cs.HostDescription)
+	assert.Equal(t, &HostCPU{
+		LoadOne:     0.5,
+		LoadFive:    1.25,
+		LoadFifteen: 2,
+		ProcRun:     3,
+		ProcTotal:   400,
+		CPUNum:      8,
+		CPUSpeed:    2400,
+		Uptime:      86400,
+		CPUUser:     1000,
+		CPUNice:     10,
+		CPUSystem:   500,
+		CPUIdle:     90000,
+		CPUWio:      20,
+		CPUIntr:     30,
+		CPUSoftIntr: 40,
+		Interrupts:  123456,
+		Contexts:    654321,
+	}, cs.HostCPU)
+	assert.Equal(t, &HostMemory{
+		MemTotal:   16 << 30,
+		MemFree:    8 << 30,
+		MemShared:  1 << 20,
+		MemBuffers: 2 << 20,
+		MemCached:  4 << 30,
+		SwapTotal:  2 << 30,
+		SwapFree:   1 << 30,
+		PageIn:     10,
+		PageOut:    20,
+		SwapIn:     30,
+		SwapOut:    40,
+	}, cs.HostMemory)
+	assert.Nil(t, cs.HostParent)
+	assert.Equal(t, &GenericInterfaceCounters{
+		IfIndex:            7,
+		IfType:             6,
+		IfSpeed:            10000000000,
+		IfDirection:        1,
+		IfStatus:           3,
+		IfInOctets:         1 << 40,
+		IfInUcastPkts:      1000,
+		IfInMulticastPkts:  2,
+		IfInBroadcastPkts:  3,
+		IfInDiscards:       4,
+		IfInErrors:         5,
+		IfInUnknownProtos:  6,
+		IfOutOctets:        1 << 41,
+		IfOutUcastPkts:     2000,
+		IfOutMulticastPkts: 7,
+		IfOutBroadcastPkts: 8,
+		IfOutDiscards:      9,
+		IfOutErrors:        10,
+	}, cs.GenericInterfaceCounters)
+
+	cs = p.CounterSamples[1]
+	if assert.NotNil(t, cs.ExpandedCounterSampleHeader) {
+		assert.Equal(t, uint32(3<<24|100005), cs.CounterSampleHeader.SourceIDClassIndex)
+	}
+	assert.Equal(t, "vm1", cs.HostDescription.Hostname)
+	assert.Equal(t, "", cs.HostDescription.OSRelease)
+	assert.Equal(t, &HostParent{ContainerType: 2, ContainerIndex: 1}, cs.HostParent)
+	assert.Equal(t, &VirtCPU{State: 1, CPUTime: 5000, NrVirtCPU: 2}, cs.VirtCPU)
+	assert.Equal(t, &VirtMemory{Memory: 1 << 30, MaxMemory: 2 << 30}, cs.VirtMemory)
+	assert.Nil(t, cs.HostCPU)
+}
+
+func TestDecodeCounterSamplesBroken(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample []byte
+	}{
+		{
+			name:   "Short host_cpu",
+			sample: record(dataCounterSample, words(1, 7, 1), record(hostCPU, words(1, 2, 3))),
+		},
+		{
+			name:   "Hostname exceeding its record",
+			sample: record(dataCounterSample, words(1, 7, 1), record(hostDescr, words(200), []byte("web01"))),
+		},
+		{
+			name:   "Missing records",
+			sample: record(dataCounterSample, words(1, 7, 2), hostCPURecord()),
+		},
+		{
+			name:   "Short generic interface counters",
+			sample: record(dataCounterSample, words(1, 7, 1), record(genericInterfaceCounters, words(7, 6))),
+		},
+		{
+			name:   "Short header",
+			sample: record(expandedCounterSample, words(1, 0, 7)),
+		},
+	}
+
+	for _, test := range tests {
+		// the broken sample is skipped, the following one is still decoded
+		p, err := Decode(testDatagram(test.sample, record(dataCounterSample, words(2, 8, 1), hostCPURecord())))
+		if !assert.NoError(t, err, test.name) {
+			continue
+		}
+
+		assert.Len(t, p.Errors, 1, test.name)
+		if assert.Len(t, p.CounterSamples, 1, test.name) {
+			assert.Equal(t, uint32(2), p.CounterSamples[0].CounterSampleHeader.SequenceNumber, test.name)
+		}
+	}
+}
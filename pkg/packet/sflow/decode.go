@@ -22,60 +22,128 @@ import (
 )
 
 const (
-	dataFlowSample     = 1
-	expandedFlowSample = 3
-	dataCounterSample  = 2
-	standardSflow      = 0
-	rawPacketHeader    = 1
-	extendedSwitchData = 1001
-	extendedRouterData = 1002
+	dataFlowSample        = 1
+	expandedFlowSample    = 3
+	dataCounterSample     = 2
+	expandedCounterSample = 4
+	standardSflow         = 0
+	rawPacketHeader       = 1
+	extendedSwitchData    = 1001
+	extendedRouterData    = 1002
+
+	genericInterfaceCounters = 1
+
+	// host sflow counter structures
+	hostDescr  = 2000
+	hostParent = 2002
+	hostCPU    = 2003
+	hostMemory = 2004
+	virtCPU    = 2101
+	virtMemory = 2102
+
+	// sizeOfUUID is the size of the UUID in host descriptions
+	sizeOfUUID = 16
+
+	// sizeOfTypeLength is the size of the data format and length preceding each sample and record
+	sizeOfTypeLength = 8
+
+	// ifIndexMask masks the ifIndex of an interface given in the compact format, the upper two bits are the format
+	ifIndexMask = 0x3fffffff
 )
 
 // errorIncompatibleVersion prints an error message in case the detected version is not supported
 func errorIncompatibleVersion(version uint32) error {
-	return errors.Errorf("Sflow: Incompatible protocol version v%d, only v5 is supported", version)
+	return errorf(ErrorUnsupportedVersion, "Sflow: Incompatible protocol version v%d, only v5 is supported", version)
 }
 
-// Decode is the main function of this package. It converts raw packet bytes to Packet struct.
-func Decode(raw []byte) (*Packet, error) {
-	data := convert.Reverse(raw) //TODO: Make it endian aware. This assumes a little endian machine
+// window is a part of the reversed datagram, starting at ptr and extending n bytes towards lower addresses. Reading
+// it from the top down reads the datagram in order. All reads go through a window, so no sample or record is read
+// beyond its declared length or the end of the datagram.
+type window struct {
+	ptr unsafe.Pointer
+	n   uintptr
+}
 
-	pSize := len(data)
-	bufSize := 1500
-	buffer := [1500]byte{}
+// next moves w past the next size bytes and returns a pointer to them
+func (w *window) next(size uintptr) (unsafe.Pointer, error) {
+	if size > w.n {
+		return nil, errorf(ErrorTruncated, "%d bytes needed, %d left", size, w.n)
+	}
+
+	w.ptr = unsafe.Pointer(uintptr(w.ptr) - size)
+	w.n -= size
+	return w.ptr, nil
+}
+
+// split gets the next size bytes as a window of their own and moves w past them
+func (w *window) split(size uintptr) (window, error) {
+	top := w.ptr
+	_, err := w.next(size)
+	if err != nil {
+		return window{}, err
+	}
+
+	return window{ptr: top, n: size}, nil
+}
 
-	if pSize > bufSize {
-		panic("Buffer too small\n")
+// nextTypeLength gets the next sample or record as a window including its data format and length
+func (w *window) nextTypeLength() (window, uint32, error) {
+	if w.n < sizeOfTypeLength {
+		return window{}, 0, errorf(ErrorTruncated, "%d bytes needed, %d left", sizeOfTypeLength, w.n)
 	}
 
-	// copy data into array as arrays allow us to cast the shit out of it
-	for i := 0; i < pSize; i++ {
-		buffer[bufSize-pSize+i] = data[i]
+	sfType := *(*uint32)(unsafe.Pointer(uintptr(w.ptr) - uintptr(4)))
+	length := *(*uint32)(unsafe.Pointer(uintptr(w.ptr) - uintptr(8)))
+
+	res, err := w.split(sizeOfTypeLength + uintptr(length))
+	if err != nil {
+		return window{}, 0, err
 	}
 
-	bufferPtr := unsafe.Pointer(&buffer)
-	//bufferMinPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(pSize))
-	headerPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(sizeOfHeaderTop))
+	return res, sfType, nil
+}
+
+// Decode is the main function of this package. It converts raw packet bytes to Packet struct. Samples failing to
+// decode are skipped and their errors are listed in the packets Errors, only an invalid header fails the datagram.
+func Decode(raw []byte) (*Packet, error) {
+	data := convert.Reverse(raw) //TODO: Make it endian aware. This assumes a little endian machine
+
+	// the byte past the datagram keeps the top of the window inside the buffer
+	pSize := len(data)
+	buffer := make([]byte, pSize+1)
+	copy(buffer, data)
 
 	var p Packet
-	p.Buffer = buffer[:]
+	p.Buffer = buffer[:pSize]
+	w := window{
+		ptr: unsafe.Pointer(&buffer[pSize]),
+		n:   uintptr(pSize),
+	}
+
+	headerPtr, err := w.next(sizeOfHeaderTop)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode header")
+	}
 	p.headerTop = (*headerTop)(headerPtr)
 
 	if p.headerTop.Version != 5 {
-		return nil, errorIncompatibleVersion(p.Header.Version)
+		return nil, errorIncompatibleVersion(p.headerTop.Version)
 	}
 
-	agentAddressLen := uint64(0)
-	switch p.headerTop.AgentAddressType {
-	default:
-		return nil, errors.Errorf("Unknown AgentAddressType %d", p.headerTop.AgentAddressType)
-	case 1:
-		agentAddressLen = 4
-	case 2:
-		agentAddressLen = 16
+	agentAddressLen, err := addressLength(p.headerTop.AgentAddressType)
+	if err != nil {
+		return nil, err
 	}
 
-	headerBottomPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(sizeOfHeaderTop) - uintptr(agentAddressLen) - uintptr(sizeOfHeaderBottom))
+	_, err = w.next(agentAddressLen)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode agent address")
+	}
+
+	headerBottomPtr, err := w.next(sizeOfHeaderBottom)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode header")
+	}
 	p.headerBottom = (*headerBottom)(headerBottomPtr)
 
 	h := Header{
@@ -89,162 +157,226 @@ func Decode(raw []byte) (*Packet, error) {
 	}
 	p.Header = &h
 
-	flowSamples, err := decodeFlowSamples(headerBottomPtr, h.NumSamples)
-	if err != nil {
-		return nil, errors.Wrap(err, "Unable to dissect flows")
-	}
-	p.FlowSamples = flowSamples
+	p.FlowSamples, p.CounterSamples, p.Errors = decodeSamples(&w, h.NumSamples)
 
 	return &p, nil
 }
 
+func addressLength(addressType uint32) (uintptr, error) {
+	switch addressType {
+	case 1:
+		return 4, nil
+	case 2:
+		return 16, nil
+	}
+
+	return 0, errorf(ErrorUnknownAddressType, "Unknown AddressType %d", addressType)
+}
+
 func extractEnterpriseFormat(sfType uint32) (sfTypeEnterprise uint32, sfTypeFormat uint32) {
 	return sfType >> 12, sfType & 0xfff
 }
 
-func decodeFlowSamples(samplesPtr unsafe.Pointer, NumSamples uint32) ([]*FlowSample, error) {
+// decodeSamples decodes the flow and counter samples of a datagram. A sample failing to decode is skipped, the
+// remaining samples are still decoded as long as the sample lengths are intact.
+func decodeSamples(w *window, numSamples uint32) ([]*FlowSample, []*CounterSample, []error) {
 	flowSamples := make([]*FlowSample, 0)
-	for i := uint32(0); i < NumSamples; i++ {
-		sfTypeEnterprise, sfTypeFormat := extractEnterpriseFormat(*(*uint32)(unsafe.Pointer(uintptr(samplesPtr) - uintptr(4))))
-
-		if sfTypeEnterprise != 0 {
-			return nil, errors.Errorf("Unknown Enterprise: %d", sfTypeEnterprise)
+	var counterSamples []*CounterSample
+	var errs []error
+	for i := uint32(0); i < numSamples; i++ {
+		sample, sfType, err := w.nextTypeLength()
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "Unable to decode sample %d", i))
+			break
 		}
 
-		sampleLengthPtr := unsafe.Pointer(uintptr(samplesPtr) - uintptr(8))
-		sampleLength := *(*uint32)(sampleLengthPtr)
+		sfTypeEnterprise, sfTypeFormat := extractEnterpriseFormat(sfType)
+		if sfTypeEnterprise != standardSflow {
+			errs = append(errs, errorf(ErrorUnknownEnterprise, "Unknown Enterprise: %d", sfTypeEnterprise))
+			continue
+		}
 
-		if sfTypeFormat == dataFlowSample {
-			fs, err := decodeFlowSample(samplesPtr)
-			if err != nil {
-				return nil, errors.Wrap(err, "Unable to decode flow sample")
-			}
-			flowSamples = append(flowSamples, fs)
-		} else if sfTypeFormat == expandedFlowSample {
-			fs, err := decodeExpandedFlowSample(samplesPtr)
+		var fs *FlowSample
+		switch sfTypeFormat {
+		case dataFlowSample:
+			fs, err = decodeFlowSample(sample)
+		case expandedFlowSample:
+			fs, err = decodeExpandedFlowSample(sample)
+		case dataCounterSample, expandedCounterSample:
+			cs, err := decodeCounterSample(sample, sfTypeFormat == expandedCounterSample)
 			if err != nil {
-				return nil, errors.Wrap(err, "Unable to decode flow sample")
+				errs = append(errs, errors.Wrapf(err, "Unable to decode counter sample %d", i))
+				continue
 			}
-			flowSamples = append(flowSamples, fs)
+
+			counterSamples = append(counterSamples, cs)
+			continue
+		default:
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "Unable to decode flow sample %d", i))
+			continue
 		}
 
-		samplesPtr = unsafe.Pointer(uintptr(samplesPtr) - uintptr(sampleLength+8))
+		flowSamples = append(flowSamples, fs)
 	}
 
-	return flowSamples, nil
+	return flowSamples, counterSamples, errs
 }
 
-func decodeFlowSample(flowSamplePtr unsafe.Pointer) (*FlowSample, error) {
-	flowSamplePtr = unsafe.Pointer(uintptr(flowSamplePtr) - uintptr(sizeOfFlowSampleHeader))
-	fsh := (*FlowSampleHeader)(flowSamplePtr)
+func decodeFlowSample(w window) (*FlowSample, error) {
+	ptr, err := w.next(sizeOfFlowSampleHeader)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode flow sample header")
+	}
 
-	return _decodeFlowSample(flowSamplePtr, fsh)
+	fsh := *(*FlowSampleHeader)(ptr)
+	return _decodeFlowSample(w, &fsh)
 }
 
-func decodeExpandedFlowSample(flowSamplePtr unsafe.Pointer) (*FlowSample, error) {
-	flowSamplePtr = unsafe.Pointer(uintptr(flowSamplePtr) - uintptr(sizeOfExpandedFlowSampleHeader))
-	fsh := (*ExpandedFlowSampleHeader)(flowSamplePtr).toFlowSampleHeader()
+func decodeExpandedFlowSample(w window) (*FlowSample, error) {
+	ptr, err := w.next(sizeOfExpandedFlowSampleHeader)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode expanded flow sample header")
+	}
 
-	return _decodeFlowSample(flowSamplePtr, fsh)
+	efsh := (*ExpandedFlowSampleHeader)(ptr)
+	fs, err := _decodeFlowSample(w, efsh.toFlowSampleHeader())
+	if err != nil {
+		return nil, err
+	}
+
+	efshCopy := *efsh
+	fs.ExpandedFlowSampleHeader = &efshCopy
+	return fs, nil
 }
 
-func _decodeFlowSample(flowSamplePtr unsafe.Pointer, fsh *FlowSampleHeader) (*FlowSample, error) {
+func _decodeFlowSample(w window, fsh *FlowSampleHeader) (*FlowSample, error) {
 	var rph *RawPacketHeader
 	var rphd unsafe.Pointer
 	var erd *ExtendedRouterData
 	var esd *ExtendedSwitchData
 
 	for i := uint32(0); i < fsh.FlowRecord; i++ {
-		sfTypeEnterprise, sfTypeFormat := extractEnterpriseFormat(*(*uint32)(unsafe.Pointer(uintptr(flowSamplePtr) - uintptr(4))))
-		flowDataLength := *(*uint32)(unsafe.Pointer(uintptr(flowSamplePtr) - uintptr(8)))
-
-		if sfTypeEnterprise == standardSflow {
-			var err error
-			switch sfTypeFormat {
-			case rawPacketHeader:
-				rph = decodeRawPacketHeader(flowSamplePtr)
-				rphd = unsafe.Pointer(uintptr(flowSamplePtr) - sizeOfRawPacketHeader)
-
-			case extendedRouterData:
-				erd, err = decodeExtendRouterData(flowSamplePtr)
-				if err != nil {
-					return nil, errors.Wrap(err, "Unable to decide extended router data")
-				}
-
-			case extendedSwitchData:
-				esd, err = decodeExtendedSwitchData(flowSamplePtr)
-				if err != nil {
-					return nil, errors.Wrap(err, "Unable to decide extended switch data")
-				}
-
-			default:
-				log.Infof("Unknown sfTypeFormat %d\n", sfTypeFormat)
-			}
+		record, sfType, err := w.nextTypeLength()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to decode flow record %d", i)
+		}
 
+		sfTypeEnterprise, sfTypeFormat := extractEnterpriseFormat(sfType)
+		if sfTypeEnterprise != standardSflow {
+			continue
 		}
 
-		flowSamplePtr = unsafe.Pointer(uintptr(flowSamplePtr) - uintptr(8) - uintptr(flowDataLength))
+		switch sfTypeFormat {
+		case rawPacketHeader:
+			rph, rphd, err = decodeRawPacketHeader(record)
+			if err != nil {
+				return nil, errors.Wrap(err, "Unable to decode raw packet header")
+			}
+
+		case extendedRouterData:
+			erd, err = decodeExtendRouterData(record)
+			if err != nil {
+				return nil, errors.Wrap(err, "Unable to decide extended router data")
+			}
+
+		case extendedSwitchData:
+			esd, err = decodeExtendedSwitchData(record)
+			if err != nil {
+				return nil, errors.Wrap(err, "Unable to decide extended switch data")
+			}
+
+		default:
+			log.Debugf("Unknown sfTypeFormat %d\n", sfTypeFormat)
+		}
 	}
 
 	fs := &FlowSample{
 		FlowSampleHeader:   fsh,
 		RawPacketHeader:    rph,
 		Data:               rphd,
-		DataLen:            rph.OriginalPacketLength,
 		ExtendedSwitchData: esd,
 		ExtendedRouterData: erd,
 	}
 
+	if rph != nil {
+		fs.DataLen = rph.OriginalPacketLength
+	}
+
 	return fs, nil
 }
 
-func decodeRawPacketHeader(rphPtr unsafe.Pointer) *RawPacketHeader {
-	rphPtr = unsafe.Pointer(uintptr(rphPtr) - uintptr(sizeOfRawPacketHeader))
+// decodeRawPacketHeader decodes a raw packet header record and returns a pointer to the sampled header
+func decodeRawPacketHeader(w window) (*RawPacketHeader, unsafe.Pointer, error) {
+	rphPtr, err := w.next(sizeOfRawPacketHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	rph := (*RawPacketHeader)(rphPtr)
-	return rph
+	if uintptr(rph.OriginalPacketLength) > w.n {
+		return nil, nil, errorf(ErrorTruncated, "Header of %d bytes exceeds its record of %d bytes", rph.OriginalPacketLength, w.n)
+	}
+
+	return rph, w.ptr, nil
 }
 
-func decodeExtendRouterData(erhPtr unsafe.Pointer) (*ExtendedRouterData, error) {
-	erhTopPtr := unsafe.Pointer(uintptr(erhPtr) - uintptr(sizeOfextendedRouterDataTop))
+func decodeExtendRouterData(w window) (*ExtendedRouterData, error) {
+	erhTopPtr, err := w.next(sizeOfextendedRouterDataTop)
+	if err != nil {
+		return nil, err
+	}
 	erhTop := (*extendedRouterDataTop)(erhTopPtr)
 
-	addressLen := uint64(0)
-	switch erhTop.AddressType {
-	default:
-		return nil, errors.Errorf("Unknown AgentAddressType %d", erhTop.AddressType)
-	case 1:
-		addressLen = 4
-	case 2:
-		addressLen = 16
+	addressLen, err := addressLength(erhTop.AddressType)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.next(addressLen)
+	if err != nil {
+		return nil, err
 	}
 
-	erhBottomPtr := unsafe.Pointer(uintptr(erhTopPtr) - uintptr(sizeOfextendedRouterDataBottom) - uintptr(addressLen) - uintptr(sizeOfextendedRouterDataBottom))
+	erhBottomPtr, err := w.next(sizeOfextendedRouterDataBottom)
+	if err != nil {
+		return nil, err
+	}
 	erhBottom := (*extendedRouterDataBottom)(erhBottomPtr)
 
 	return &ExtendedRouterData{
 		EnterpriseType:         erhTop.EnterpriseType,
 		FlowDataLength:         erhTop.FlowDataLength,
 		AddressType:            erhTop.AddressType,
-		NextHop:                getNetIP(unsafe.Pointer(uintptr(erhTopPtr)), addressLen),
+		NextHop:                getNetIP(erhTopPtr, addressLen),
 		NextHopSourceMask:      erhBottom.NextHopSourceMask,
 		NextHopDestinationMask: erhBottom.NextHopDestinationMask,
 	}, nil
 }
 
-func decodeExtendedSwitchData(eshPtr unsafe.Pointer) (*ExtendedSwitchData, error) {
-	eshPtr = unsafe.Pointer(uintptr(eshPtr) - uintptr(sizeOfExtendedSwitchData))
-	esh := (*ExtendedSwitchData)(eshPtr)
+func decodeExtendedSwitchData(w window) (*ExtendedSwitchData, error) {
+	eshPtr, err := w.next(sizeOfExtendedSwitchData)
+	if err != nil {
+		return nil, err
+	}
 
-	eshCopy := *esh
+	eshCopy := *(*ExtendedSwitchData)(eshPtr)
 	return &eshCopy, nil
 }
 
-func getNetIP(headerPtr unsafe.Pointer, addressLen uint64) net.IP {
-	ptr := unsafe.Pointer(uintptr(headerPtr) - uintptr(1))
-	addr := make([]byte, addressLen)
-	for i := uint64(0); i < addressLen; i++ {
-		addr[i] = *(*byte)(unsafe.Pointer(uintptr(ptr) - uintptr(i)))
+func getNetIP(headerPtr unsafe.Pointer, addressLen uintptr) net.IP {
+	return net.IP(getBytes(headerPtr, addressLen))
+}
+
+// getBytes gets the n bytes below top in datagram order
+func getBytes(top unsafe.Pointer, n uintptr) []byte {
+	res := make([]byte, n)
+	for i := uintptr(0); i < n; i++ {
+		res[i] = *(*byte)(unsafe.Pointer(uintptr(top) - (i + 1)))
 	}
 
-	return net.IP(addr)
+	return res
 }
@@ -11,10 +11,12 @@
 package sflow
 
 import (
+	"encoding/binary"
 	"fmt"
 	"testing"
 
 	"github.com/bio-routing/tflow2/convert"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDecode(t *testing.T) {
@@ -129,3 +131,162 @@ func testEq(a, b []byte) bool {
 
 	return true
 }
+
+// words encodes values in network byte order
+func words(values ...uint32) []byte {
+	res := make([]byte, 0, len(values)*4)
+	for _, v := range values {
+		res = binary.BigEndian.AppendUint32(res, v)
+	}
+
+	return res
+}
+
+// record encodes a sample or flow record of data format sfType
+func record(sfType uint32, data ...[]byte) []byte {
+	body := []byte{}
+	for _, d := range data {
+		body = append(body, d...)
+	}
+
+	return append(words(sfType, uint32(len(body))), body...)
+}
+
+// testDatagram encodes a datagram of agent 192.0.2.1 with samples
+func testDatagram(samples ...[]byte) []byte {
+	res := append(words(5, 1), 192, 0, 2, 1)
+	res = append(res, words(0, 1, 100, uint32(len(samples)))...)
+	for _, s := range samples {
+		res = append(res, s...)
+	}
+
+	return res
+}
+
+// rawHeaderRecord encodes a raw packet header record of headerLength bytes of which only header are present
+func rawHeaderRecord(headerLength uint32, header []byte) []byte {
+	return record(rawPacketHeader, words(1, 1500, 0, headerLength), header)
+}
+
+func TestDecodeSkipsBrokenSamples(t *testing.T) {
+	raw := testDatagram(
+		// vendor specific sample
+		record(4300<<12|1, words(1, 2, 3)),
+
+		// flow sample with a header longer than its record
+		record(dataFlowSample, words(1, 3, 1000, 0, 0, 3, 4, 1), rawHeaderRecord(200, make([]byte, 4))),
+
+		// expanded flow sample of a packet sent out of three interfaces
+		record(expandedFlowSample, words(2, 0, 7, 2000, 0, 0, 0, 7, 2, 3, 1), rawHeaderRecord(4, []byte{1, 2, 3, 4})),
+	)
+
+	p, err := Decode(raw)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Len(t, p.Errors, 2) {
+		assert.Equal(t, ErrorUnknownEnterprise, ErrorKind(p.Errors[0]))
+		assert.Equal(t, ErrorTruncated, ErrorKind(p.Errors[1]))
+	}
+
+	if !assert.Len(t, p.FlowSamples, 1) {
+		return
+	}
+
+	fs := p.FlowSamples[0]
+	assert.Equal(t, uint32(2000), fs.FlowSampleHeader.SamplingRate)
+	assert.Equal(t, uint32(7), fs.FlowSampleHeader.InputIf)
+	assert.Equal(t, uint32(2<<30|3), fs.FlowSampleHeader.OutputIf)
+	assert.Equal(t, uint32(7), fs.FlowSampleHeader.SourceIDClassIndex)
+	assert.Equal(t, uint32(4), fs.DataLen)
+	assert.NotNil(t, fs.ExpandedFlowSampleHeader)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      []byte
+		expected string
+	}{
+		{
+			name:     "Empty",
+			raw:      []byte{},
+			expected: ErrorTruncated,
+		},
+		{
+			name:     "Truncated header",
+			raw:      words(5, 1, 0xc0000201),
+			expected: ErrorTruncated,
+		},
+		{
+			name:     "Version 4",
+			raw:      words(4, 1, 0xc0000201, 0, 0, 0, 0),
+			expected: ErrorUnsupportedVersion,
+		},
+		{
+			name:     "Unknown agent address type",
+			raw:      words(5, 3, 0xc0000201, 0, 0, 0, 0),
+			expected: ErrorUnknownAddressType,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := Decode(test.raw)
+		if assert.Error(t, err, test.name) {
+			assert.Equal(t, test.expected, ErrorKind(err), test.name)
+		}
+	}
+}
+
+func TestDecodeJumboDatagram(t *testing.T) {
+	raw := testDatagram(record(dataFlowSample, words(1, 3, 1000, 0, 0, 3, 4, 1), rawHeaderRecord(8000, make([]byte, 8000))))
+
+	p, err := Decode(raw)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Empty(t, p.Errors)
+	if assert.Len(t, p.FlowSamples, 1) {
+		assert.Equal(t, uint32(8000), p.FlowSamples[0].DataLen)
+	}
+}
+
+func TestDecodeMissingSamples(t *testing.T) {
+	raw := testDatagram(record(dataFlowSample, words(1, 3, 1000, 0, 0, 3, 4, 0)))
+
+	// the datagram claims a second sample
+	raw[27] = 2
+
+	p, err := Decode(raw)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Len(t, p.FlowSamples, 1)
+	if assert.Len(t, p.Errors, 1) {
+		assert.Equal(t, ErrorTruncated, ErrorKind(p.Errors[0]))
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add(testDatagram(record(dataFlowSample, words(1, 3, 1000, 0, 0, 3, 4, 1), rawHeaderRecord(4, []byte{1, 2, 3, 4}))))
+	f.Add(testDatagram(record(expandedFlowSample, words(2, 0, 7, 2000, 0, 0, 0, 7, 2, 3, 2), rawHeaderRecord(4, []byte{1, 2, 3, 4}),
+		record(extendedRouterData, words(1, 0xc0000201, 24, 24)), record(extendedSwitchData, words(10, 0, 20, 0)))))
+	f.Add(testDatagram(record(4300<<12|1, words(1, 2, 3))))
+	f.Add(testDatagram(record(dataCounterSample, words(1, 7, 2), hostDescrRecord(), hostCPURecord())))
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		p, err := Decode(append([]byte{}, raw...))
+		if err != nil {
+			return
+		}
+
+		for _, fs := range p.FlowSamples {
+			if fs.RawPacketHeader != nil && uintptr(fs.DataLen) > uintptr(len(p.Buffer)) {
+				t.Fatalf("Header of %d bytes exceeds the datagram of %d bytes", fs.DataLen, len(p.Buffer))
+			}
+		}
+	})
+}
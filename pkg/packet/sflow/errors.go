@@ -0,0 +1,50 @@
+package sflow
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrorTruncated denotes a datagram, sample or record ending before its declared length
+	ErrorTruncated = "truncated"
+
+	// ErrorUnsupportedVersion denotes a datagram of another version than 5
+	ErrorUnsupportedVersion = "unsupported_version"
+
+	// ErrorUnknownAddressType denotes an address that is neither IPv4 nor IPv6
+	ErrorUnknownAddressType = "unknown_address_type"
+
+	// ErrorUnknownEnterprise denotes a sample of a vendor specific format
+	ErrorUnknownEnterprise = "unknown_enterprise"
+
+	// ErrorOther denotes errors not caused by a DecodeError
+	ErrorOther = "other"
+)
+
+// DecodeError is an error decoding a datagram or one of its samples. Kind classifies it for counting.
+type DecodeError struct {
+	Kind string
+	msg  string
+}
+
+func (e *DecodeError) Error() string {
+	return e.msg
+}
+
+func errorf(kind string, format string, args ...interface{}) error {
+	return &DecodeError{
+		Kind: kind,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// ErrorKind gets the kind of the DecodeError causing err, ErrorOther if it was not caused by one
+func ErrorKind(err error) string {
+	if e, ok := errors.Cause(err).(*DecodeError); ok {
+		return e.Kind
+	}
+
+	return ErrorOther
+}
@@ -26,6 +26,13 @@ type Packet struct {
 	// A slice of pointers to FlowSet. Each element is instance of (Data)FlowSet
 	FlowSamples []*FlowSample
 
+	// CounterSamples are the counter samples, only the generic interface counters and host sflow structures of them are
+	// decoded
+	CounterSamples []*CounterSample
+
+	// Errors are the errors of the samples that were skipped, see ErrorKind
+	Errors []error
+
 	// Buffer is a slice pointing to the original byte array that this packet was decoded from.
 	// This field is only populated if debug level is at least 2
 	Buffer []byte
@@ -41,6 +48,15 @@ var (
 	sizeOfextendedRouterDataTop    = unsafe.Sizeof(extendedRouterDataTop{})
 	sizeOfextendedRouterDataBottom = unsafe.Sizeof(extendedRouterDataBottom{})
 	sizeOfExtendedSwitchData       = unsafe.Sizeof(ExtendedSwitchData{})
+
+	sizeOfCounterSampleHeader         = unsafe.Sizeof(CounterSampleHeader{})
+	sizeOfExpandedCounterSampleHeader = unsafe.Sizeof(ExpandedCounterSampleHeader{})
+	sizeOfGenericInterfaceCounters    = unsafe.Sizeof(GenericInterfaceCounters{})
+	sizeOfHostParent                  = unsafe.Sizeof(HostParent{})
+	sizeOfHostCPU                     = unsafe.Sizeof(HostCPU{})
+	sizeOfHostMemory                  = unsafe.Sizeof(HostMemory{})
+	sizeOfVirtCPU                     = unsafe.Sizeof(VirtCPU{})
+	sizeOfVirtMemory                  = unsafe.Sizeof(VirtMemory{})
 )
 
 // Header is an sflow version 5 header
@@ -95,28 +111,30 @@ type FlowSampleHeader struct {
 type ExpandedFlowSampleHeader struct {
 	FlowRecord         uint32
 	OutputIf           uint32
-	_                  uint32
+	OutputIfFormat     uint32
 	InputIf            uint32
-	_                  uint32
+	InputIfFormat      uint32
 	DroppedPackets     uint32
 	SamplePool         uint32
 	SamplingRate       uint32
 	SourceIDClassIndex uint32
-	_                  uint32
+	SourceIDType       uint32
 	SequenceNumber     uint32
 	SampleLength       uint32
 	EnterpriseType     uint32
 }
 
+// toFlowSampleHeader converts the header to the compact header. Interfaces and the source ID keep their format in
+// the upper bits like in compact headers, so both are interpreted the same way.
 func (e *ExpandedFlowSampleHeader) toFlowSampleHeader() *FlowSampleHeader {
 	return &FlowSampleHeader{
 		FlowRecord:         e.FlowRecord,
-		OutputIf:           e.OutputIf,
-		InputIf:            e.InputIf,
+		OutputIf:           e.OutputIfFormat<<30 | e.OutputIf&ifIndexMask,
+		InputIf:            e.InputIfFormat<<30 | e.InputIf&ifIndexMask,
 		DroppedPackets:     e.DroppedPackets,
 		SamplePool:         e.SamplePool,
 		SamplingRate:       e.SamplingRate,
-		SourceIDClassIndex: e.SourceIDClassIndex,
+		SourceIDClassIndex: e.SourceIDType<<24 | e.SourceIDClassIndex&0xffffff,
 		SequenceNumber:     e.SequenceNumber,
 		SampleLength:       e.SampleLength,
 		EnterpriseType:     e.EnterpriseType,
@@ -163,3 +181,140 @@ type ExtendedSwitchData struct {
 	FlowDataLength   uint32
 	EnterpriseType   uint32
 }
+
+// CounterSample is an sflow version 5 counter sample
+type CounterSample struct {
+	CounterSampleHeader         *CounterSampleHeader
+	ExpandedCounterSampleHeader *ExpandedCounterSampleHeader
+	GenericInterfaceCounters    *GenericInterfaceCounters
+	HostDescription             *HostDescription
+	HostParent                  *HostParent
+	HostCPU                     *HostCPU
+	HostMemory                  *HostMemory
+	VirtCPU                     *VirtCPU
+	VirtMemory                  *VirtMemory
+}
+
+// HasHostStructures checks if the sample carries any host sflow structure
+func (c *CounterSample) HasHostStructures() bool {
+	return c.HostDescription != nil || c.HostParent != nil || c.HostCPU != nil || c.HostMemory != nil ||
+		c.VirtCPU != nil || c.VirtMemory != nil
+}
+
+// CounterSampleHeader is an sflow version 5 counter sample header
+type CounterSampleHeader struct {
+	CounterRecords     uint32
+	SourceIDClassIndex uint32
+	SequenceNumber     uint32
+	SampleLength       uint32
+	EnterpriseType     uint32
+}
+
+// ExpandedCounterSampleHeader is an sflow version 5 expanded counter sample header
+type ExpandedCounterSampleHeader struct {
+	CounterRecords uint32
+	SourceIDIndex  uint32
+	SourceIDType   uint32
+	SequenceNumber uint32
+	SampleLength   uint32
+	EnterpriseType uint32
+}
+
+// toCounterSampleHeader converts the header to the compact header with the source ID type in the upper 8 bits
+func (e *ExpandedCounterSampleHeader) toCounterSampleHeader() *CounterSampleHeader {
+	return &CounterSampleHeader{
+		CounterRecords:     e.CounterRecords,
+		SourceIDClassIndex: e.SourceIDType<<24 | e.SourceIDIndex&0xffffff,
+		SequenceNumber:     e.SequenceNumber,
+		SampleLength:       e.SampleLength,
+		EnterpriseType:     e.EnterpriseType,
+	}
+}
+
+// GenericInterfaceCounters are the generic interface counters of an interface
+type GenericInterfaceCounters struct {
+	IfPromiscuousMode  uint32
+	IfOutErrors        uint32
+	IfOutDiscards      uint32
+	IfOutBroadcastPkts uint32
+	IfOutMulticastPkts uint32
+	IfOutUcastPkts     uint32
+	IfOutOctets        uint64
+	IfInUnknownProtos  uint32
+	IfInErrors         uint32
+	IfInDiscards       uint32
+	IfInBroadcastPkts  uint32
+	IfInMulticastPkts  uint32
+	IfInUcastPkts      uint32
+	IfInOctets         uint64
+	IfStatus           uint32
+	IfDirection        uint32
+	IfSpeed            uint64
+	IfType             uint32
+	IfIndex            uint32
+}
+
+// HostDescription is the host sflow host_descr structure
+type HostDescription struct {
+	Hostname    string
+	UUID        [16]byte
+	MachineType uint32
+	OSName      uint32
+	OSRelease   string
+}
+
+// HostParent is the host sflow host_parent structure, it names the host of a virtual machine or container
+type HostParent struct {
+	ContainerIndex uint32
+	ContainerType  uint32
+}
+
+// HostCPU is the host sflow host_cpu structure. CPU times are in milliseconds, later additions to the structure are
+// not decoded.
+type HostCPU struct {
+	Contexts    uint32
+	Interrupts  uint32
+	CPUSoftIntr uint32
+	CPUIntr     uint32
+	CPUWio      uint32
+	CPUIdle     uint32
+	CPUSystem   uint32
+	CPUNice     uint32
+	CPUUser     uint32
+	Uptime      uint32
+	CPUSpeed    uint32
+	CPUNum      uint32
+	ProcTotal   uint32
+	ProcRun     uint32
+	LoadFifteen float32
+	LoadFive    float32
+	LoadOne     float32
+}
+
+// HostMemory is the host sflow host_memory structure, sizes are in bytes
+type HostMemory struct {
+	SwapOut    uint32
+	SwapIn     uint32
+	PageOut    uint32
+	PageIn     uint32
+	SwapFree   uint64
+	SwapTotal  uint64
+	MemCached  uint64
+	MemBuffers uint64
+	MemShared  uint64
+	MemFree    uint64
+	MemTotal   uint64
+}
+
+// VirtCPU is the host sflow virt_cpu structure of a virtual machine or container
+type VirtCPU struct {
+	NrVirtCPU uint32
+	CPUTime   uint32
+	State     uint32
+}
+
+// VirtMemory is the host sflow virt_memory structure of a virtual machine or container, sizes are in bytes
+type VirtMemory struct {
+	MaxMemory uint64
+	Memory    uint64
+}
@@ -0,0 +1,77 @@
+package prefixset
+
+import (
+	"net/netip"
+	"sort"
+)
+
+// Set is a set of prefixes supporting fast lookups of addresses
+type Set struct {
+	prefixes  map[netip.Prefix]struct{}
+	lengthsV4 []uint8
+	lengthsV6 []uint8
+}
+
+// New creates a new empty set
+func New() *Set {
+	return &Set{
+		prefixes: make(map[netip.Prefix]struct{}),
+	}
+}
+
+// Add adds a prefix to the set
+func (s *Set) Add(pfx netip.Prefix) {
+	k := pfx.Masked()
+	if _, exists := s.prefixes[k]; exists {
+		return
+	}
+	s.prefixes[k] = struct{}{}
+
+	if k.Addr().Is4() {
+		s.lengthsV4 = addLength(s.lengthsV4, uint8(k.Bits()))
+		return
+	}
+
+	s.lengthsV6 = addLength(s.lengthsV6, uint8(k.Bits()))
+}
+
+func addLength(lengths []uint8, l uint8) []uint8 {
+	for _, x := range lengths {
+		if x == l {
+			return lengths
+		}
+	}
+
+	lengths = append(lengths, l)
+	sort.Slice(lengths, func(i, j int) bool {
+		return lengths[i] > lengths[j]
+	})
+
+	return lengths
+}
+
+// Len returns the number of prefixes in the set
+func (s *Set) Len() int {
+	return len(s.prefixes)
+}
+
+// Contains checks if addr is covered by any prefix of the set
+func (s *Set) Contains(addr netip.Addr) bool {
+	lengths := s.lengthsV6
+	if addr.Is4() {
+		lengths = s.lengthsV4
+	}
+
+	for _, l := range lengths {
+		k, err := addr.Prefix(int(l))
+		if err != nil {
+			return false
+		}
+
+		if _, exists := s.prefixes[k]; exists {
+			return true
+		}
+	}
+
+	return false
+}
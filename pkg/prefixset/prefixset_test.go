@@ -0,0 +1,61 @@
+package prefixset
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContains(t *testing.T) {
+	s := New()
+	for _, p := range []string{"10.0.0.0/8", "192.0.2.128/25", "2001:db8::/32", "0.0.0.0/0"} {
+		pfx, err := netip.ParsePrefix(p)
+		if err != nil {
+			t.Fatalf("Unable to parse prefix %q: %v", p, err)
+		}
+
+		s.Add(pfx)
+	}
+
+	tests := []struct {
+		name     string
+		addr     string
+		expected bool
+	}{
+		{
+			name:     "IPv4 default route",
+			addr:     "198.51.100.1",
+			expected: true,
+		},
+		{
+			name:     "IPv6 covered",
+			addr:     "2001:db8::1",
+			expected: true,
+		},
+		{
+			name:     "IPv6 not covered",
+			addr:     "2001:db9::1",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		addr, err := netip.ParseAddr(test.addr)
+		if err != nil {
+			t.Fatalf("Unable to parse address %q: %v", test.addr, err)
+		}
+
+		assert.Equal(t, test.expected, s.Contains(addr), test.name)
+	}
+
+	assert.Equal(t, 4, s.Len())
+}
+
+func TestContainsMoreSpecific(t *testing.T) {
+	s := New()
+	s.Add(netip.MustParsePrefix("192.0.2.128/25"))
+
+	assert.True(t, s.Contains(netip.AddrFrom4([4]byte{192, 0, 2, 200})))
+	assert.False(t, s.Contains(netip.AddrFrom4([4]byte{192, 0, 2, 100})))
+}
@@ -0,0 +1,267 @@
+// Package ratelimit caps the datagrams and flows accepted per agent, so a misconfigured exporter (e.g. one exporting
+// unsampled traffic) can not overload the pipeline
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBurstSeconds = 1
+
+	// logInterval is the minimum time between two log events of a limited agent
+	logInterval = time.Minute
+)
+
+var (
+	datagramsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "ratelimit",
+		Name:      "dropped_datagrams",
+		Help:      "Datagrams dropped as their agent exceeded its datagrams per second",
+	}, []string{"agent"})
+	flowsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "ratelimit",
+		Name:      "dropped_flows",
+		Help:      "Flows dropped as their agent exceeded its flows per second",
+	}, []string{"agent"})
+)
+
+// Config configures the limits applying to every agent. 0 means no limit.
+type Config struct {
+	DatagramsPerSecond float64 `yaml:"datagrams_per_second"`
+	FlowsPerSecond     float64 `yaml:"flows_per_second"`
+
+	// BurstSeconds is the number of seconds of traffic at the limit an agent may send at once (default 1)
+	BurstSeconds float64 `yaml:"burst_seconds"`
+
+	// Agents overrides the limits of single agents
+	Agents []*AgentConfig `yaml:"agents"`
+}
+
+// AgentConfig are the limits of an agent. 0 means no limit.
+type AgentConfig struct {
+	Agent              string  `yaml:"agent"`
+	DatagramsPerSecond float64 `yaml:"datagrams_per_second"`
+	FlowsPerSecond     float64 `yaml:"flows_per_second"`
+	agent              netip.Addr
+}
+
+func (c *Config) loadDefaults() {
+	if c.BurstSeconds == 0 {
+		c.BurstSeconds = defaultBurstSeconds
+	}
+}
+
+// Validate validates the config
+func (c *Config) Validate() error {
+	if c.DatagramsPerSecond < 0 || c.FlowsPerSecond < 0 || c.BurstSeconds < 0 {
+		return fmt.Errorf("datagrams_per_second, flows_per_second and burst_seconds must not be negative")
+	}
+
+	seen := make(map[netip.Addr]struct{}, len(c.Agents))
+	for _, a := range c.Agents {
+		addr, err := netip.ParseAddr(a.Agent)
+		if err != nil {
+			return fmt.Errorf("Invalid agent %q", a.Agent)
+		}
+
+		if a.DatagramsPerSecond < 0 || a.FlowsPerSecond < 0 {
+			return fmt.Errorf("Limits of agent %s must not be negative", a.Agent)
+		}
+
+		if _, exists := seen[addr]; exists {
+			return fmt.Errorf("Agent %s is configured twice", a.Agent)
+		}
+		seen[addr] = struct{}{}
+		a.agent = addr
+	}
+
+	return nil
+}
+
+// Limiter keeps a token bucket for the datagrams and flows of each agent
+type Limiter struct {
+	cfg    *Config
+	agents map[netip.Addr]*AgentConfig
+	now    func() time.Time
+
+	mu        sync.Mutex
+	datagrams map[netip.Addr]*bucket
+	flows     map[netip.Addr]*bucket
+}
+
+// bucket is a token bucket refilled at rate tokens per second up to burst tokens
+type bucket struct {
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	dropped  uint64
+	loggedAt time.Time
+}
+
+// New creates a new limiter
+func New(cfg *Config) (*Limiter, error) {
+	cfg.loadDefaults()
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Limiter{
+		cfg:       cfg,
+		agents:    make(map[netip.Addr]*AgentConfig, len(cfg.Agents)),
+		now:       time.Now,
+		datagrams: make(map[netip.Addr]*bucket),
+		flows:     make(map[netip.Addr]*bucket),
+	}
+
+	for _, a := range cfg.Agents {
+		l.agents[a.agent] = a
+	}
+
+	return l, nil
+}
+
+// limits gets the datagrams and flows per second of agent
+func (l *Limiter) limits(agent netip.Addr) (float64, float64) {
+	if a, exists := l.agents[agent]; exists {
+		return a.DatagramsPerSecond, a.FlowsPerSecond
+	}
+
+	return l.cfg.DatagramsPerSecond, l.cfg.FlowsPerSecond
+}
+
+// AllowDatagram checks whether a datagram of agent is within its limit and counts it as dropped otherwise
+func (l *Limiter) AllowDatagram(agent netip.Addr) bool {
+	rate, _ := l.limits(agent)
+	if rate == 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.take(l.datagrams, agent, rate, 1) == 1 {
+		return true
+	}
+
+	datagramsDropped.WithLabelValues(agent.String()).Inc()
+	l.dropped(l.datagrams[agent], agent, "datagrams", rate, 1)
+	return false
+}
+
+// Output wraps output to drop the flows of agents beyond their limit
+func (l *Limiter) Output(output func(flows []*flow.Flow)) func(flows []*flow.Flow) {
+	return func(flows []*flow.Flow) {
+		flows = l.filter(flows)
+		if len(flows) > 0 {
+			output(flows)
+		}
+	}
+}
+
+// filter gets the flows within the limits of their agents. Flows of the same agent are dropped from the end of the batch.
+func (l *Limiter) filter(flows []*flow.Flow) []*flow.Flow {
+	counts := make(map[netip.Addr]int)
+	for _, fl := range flows {
+		counts[fl.Agent]++
+	}
+
+	l.mu.Lock()
+	allowed := make(map[netip.Addr]int, len(counts))
+	limited := false
+	for agent, n := range counts {
+		_, rate := l.limits(agent)
+		if rate == 0 {
+			allowed[agent] = n
+			continue
+		}
+
+		allowed[agent] = l.take(l.flows, agent, rate, n)
+		if allowed[agent] < n {
+			limited = true
+			flowsDropped.WithLabelValues(agent.String()).Add(float64(n - allowed[agent]))
+			l.dropped(l.flows[agent], agent, "flows", rate, uint64(n-allowed[agent]))
+		}
+	}
+	l.mu.Unlock()
+
+	if !limited {
+		return flows
+	}
+
+	res := flows[:0]
+	for _, fl := range flows {
+		if allowed[fl.Agent] > 0 {
+			allowed[fl.Agent]--
+			res = append(res, fl)
+			continue
+		}
+
+		flow.Release(fl)
+	}
+
+	return res
+}
+
+// take takes up to n tokens from the bucket of agent and returns how many it got. It has to be called with l.mu held.
+func (l *Limiter) take(buckets map[netip.Addr]*bucket, agent netip.Addr, rate float64, n int) int {
+	now := l.now()
+	b, exists := buckets[agent]
+	if !exists || b.rate != rate {
+		// a bucket holding less than a token would never let anything pass
+		burst := math.Max(rate*l.cfg.BurstSeconds, 1)
+		b = &bucket{
+			rate:   rate,
+			burst:  burst,
+			tokens: burst,
+			last:   now,
+		}
+		buckets[agent] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	taken := n
+	if float64(n) > b.tokens {
+		taken = int(b.tokens)
+	}
+	b.tokens -= float64(taken)
+
+	return taken
+}
+
+// dropped counts dropped datagrams or flows of an agent and logs them at most once per logInterval. It has to be
+// called with l.mu held.
+func (l *Limiter) dropped(b *bucket, agent netip.Addr, kind string, rate float64, n uint64) {
+	b.dropped += n
+	now := l.now()
+	if now.Sub(b.loggedAt) < logInterval {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"agent":   agent.String(),
+		"limit":   rate,
+		"dropped": b.dropped,
+	}).Warningf("Agent exceeds its limit of %s per second, dropping %s", kind, kind)
+	b.dropped = 0
+	b.loggedAt = now
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		wantFail bool
+	}{
+		{
+			name: "Valid",
+			cfg: &Config{
+				FlowsPerSecond: 1000,
+				Agents: []*AgentConfig{
+					{Agent: "192.0.2.1", FlowsPerSecond: 10},
+				},
+			},
+		},
+		{
+			name:     "Negative limit",
+			cfg:      &Config{DatagramsPerSecond: -1},
+			wantFail: true,
+		},
+		{
+			name: "Invalid agent",
+			cfg: &Config{
+				Agents: []*AgentConfig{
+					{Agent: "rtr01"},
+				},
+			},
+			wantFail: true,
+		},
+		{
+			name: "Agent configured twice",
+			cfg: &Config{
+				Agents: []*AgentConfig{
+					{Agent: "192.0.2.1", FlowsPerSecond: 10},
+					{Agent: "192.0.2.1", FlowsPerSecond: 20},
+				},
+			},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := New(test.cfg)
+		assert.Equal(t, test.wantFail, err != nil, test.name)
+	}
+}
+
+func TestAllowDatagram(t *testing.T) {
+	l, err := New(&Config{
+		DatagramsPerSecond: 2,
+		Agents: []*AgentConfig{
+			{Agent: "192.0.2.2"},
+		},
+	})
+	assert.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	limited := netip.MustParseAddr("192.0.2.1")
+	unlimited := netip.MustParseAddr("192.0.2.2")
+
+	assert.True(t, l.AllowDatagram(limited))
+	assert.True(t, l.AllowDatagram(limited))
+	assert.False(t, l.AllowDatagram(limited))
+	for i := 0; i < 10; i++ {
+		assert.True(t, l.AllowDatagram(unlimited))
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, l.AllowDatagram(limited))
+	assert.False(t, l.AllowDatagram(limited))
+}
+
+func TestOutput(t *testing.T) {
+	l, err := New(&Config{
+		FlowsPerSecond: 3,
+		BurstSeconds:   1,
+	})
+	assert.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	rtr01 := netip.MustParseAddr("192.0.2.1")
+	rtr02 := netip.MustParseAddr("192.0.2.2")
+	newFlows := func(agents ...netip.Addr) []*flow.Flow {
+		flows := make([]*flow.Flow, 0, len(agents))
+		for i, a := range agents {
+			flows = append(flows, &flow.Flow{Agent: a, Packets: uint64(i)})
+		}
+
+		return flows
+	}
+
+	var got []*flow.Flow
+	output := l.Output(func(flows []*flow.Flow) {
+		got = flows
+	})
+
+	output(newFlows(rtr01, rtr02, rtr01, rtr01, rtr01, rtr01))
+	assert.Len(t, got, 4)
+	for i, p := range []uint64{0, 1, 2, 3} {
+		assert.Equal(t, p, got[i].Packets)
+	}
+
+	got = nil
+	output(newFlows(rtr01))
+	assert.Nil(t, got)
+
+	now = now.Add(time.Second)
+	output(newFlows(rtr01, rtr01))
+	assert.Len(t, got, 2)
+}
@@ -0,0 +1,233 @@
+package rdns
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultWorkers     = 16
+	defaultQueueLength = 4096
+	defaultCacheTTL    = 3600
+	defaultNegativeTTL = 300
+	defaultTimeout     = 2
+)
+
+// Config is a reverse DNS resolvers configuration
+type Config struct {
+	Workers     int `yaml:"workers"`
+	QueueLength int `yaml:"queue_length"`
+
+	// CacheTTL is the fixed time host names are cached for in seconds. The TTLs of the PTR records are not known to
+	// the resolver of the standard library, so they are not taken into account.
+	CacheTTL uint64 `yaml:"cache_ttl"`
+
+	// TTL is the former name of CacheTTL
+	TTL uint64 `yaml:"ttl"`
+
+	NegativeTTL uint64 `yaml:"negative_ttl"`
+	Timeout     uint64 `yaml:"timeout"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Workers == 0 {
+		c.Workers = defaultWorkers
+	}
+
+	if c.QueueLength == 0 {
+		c.QueueLength = defaultQueueLength
+	}
+
+	if c.CacheTTL == 0 && c.TTL != 0 {
+		log.Warningf("rdns: ttl is deprecated, use cache_ttl")
+		c.CacheTTL = c.TTL
+	}
+
+	if c.CacheTTL == 0 {
+		c.CacheTTL = defaultCacheTTL
+	}
+
+	if c.NegativeTTL == 0 {
+		c.NegativeTTL = defaultNegativeTTL
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = defaultTimeout
+	}
+}
+
+type lookupFunc func(ctx context.Context, addr string) ([]string, error)
+
+type entry struct {
+	name    string
+	expires time.Time
+}
+
+// Resolver resolves IP addresses into host names. Lookups are carried out
+// asynchronously by a bounded number of workers so ingestion never blocks on DNS.
+type Resolver struct {
+	cfg     *Config
+	lookup  lookupFunc
+	cache   map[netip.Addr]*entry
+	pending map[netip.Addr]struct{}
+	cacheMu sync.RWMutex
+	queue   chan netip.Addr
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New creates and starts a new Resolver
+func New(cfg *Config) *Resolver {
+	cfg.loadDefaults()
+
+	r := newResolver(cfg, net.DefaultResolver.LookupAddr)
+	r.start()
+	return r
+}
+
+func newResolver(cfg *Config, lookup lookupFunc) *Resolver {
+	return &Resolver{
+		cfg:     cfg,
+		lookup:  lookup,
+		cache:   make(map[netip.Addr]*entry),
+		pending: make(map[netip.Addr]struct{}),
+		queue:   make(chan netip.Addr, cfg.QueueLength),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+func (r *Resolver) start() {
+	for i := 0; i < r.cfg.Workers; i++ {
+		r.wg.Add(1)
+		go r.worker()
+	}
+
+	r.wg.Add(1)
+	go r.janitor()
+}
+
+// Stop stops the resolvers workers
+func (r *Resolver) Stop() {
+	close(r.stopCh)
+	r.wg.Wait()
+}
+
+// Annotate sets the source and destination host names of a flow
+func (r *Resolver) Annotate(fl *flow.Flow) error {
+	fl.SrcHostname = r.Resolve(fl.SrcAddr)
+	fl.DstHostname = r.Resolve(fl.DstAddr)
+
+	return nil
+}
+
+// Resolve gets the cached host name of addr. If addr is not cached (or the
+// cache entry expired) a lookup is scheduled and the stale or empty name is returned.
+func (r *Resolver) Resolve(addr netip.Addr) string {
+	if !addr.IsValid() {
+		return ""
+	}
+
+	now := time.Now()
+
+	r.cacheMu.RLock()
+	e, exists := r.cache[addr]
+	r.cacheMu.RUnlock()
+
+	if exists && now.Before(e.expires) {
+		return e.name
+	}
+
+	r.schedule(addr)
+
+	if exists {
+		return e.name
+	}
+
+	return ""
+}
+
+func (r *Resolver) schedule(addr netip.Addr) {
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+
+	if _, exists := r.pending[addr]; exists {
+		return
+	}
+
+	select {
+	case r.queue <- addr:
+		r.pending[addr] = struct{}{}
+	default:
+		log.Debugf("rDNS queue full. Dropping lookup for %s", addr.String())
+	}
+}
+
+func (r *Resolver) worker() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case addr := <-r.queue:
+			r.resolve(addr)
+		}
+	}
+}
+
+func (r *Resolver) resolve(addr netip.Addr) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Timeout)*time.Second)
+	defer cancel()
+
+	e := &entry{
+		expires: time.Now().Add(time.Duration(r.cfg.NegativeTTL) * time.Second),
+	}
+
+	names, err := r.lookup(ctx, addr.String())
+	if err == nil && len(names) > 0 {
+		e.name = strings.TrimSuffix(names[0], ".")
+		e.expires = time.Now().Add(time.Duration(r.cfg.CacheTTL) * time.Second)
+	}
+
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+
+	r.cache[addr] = e
+	delete(r.pending, addr)
+}
+
+// janitor removes entries that have not been renewed for a full cache TTL after they expired
+func (r *Resolver) janitor() {
+	defer r.wg.Done()
+
+	t := time.NewTicker(time.Duration(r.cfg.CacheTTL) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-t.C:
+			r.purge(time.Now().Add(-time.Duration(r.cfg.CacheTTL) * time.Second))
+		}
+	}
+}
+
+func (r *Resolver) purge(before time.Time) {
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+
+	for addr, e := range r.cache {
+		if e.expires.Before(before) {
+			delete(r.cache, addr)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package rdns
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	lookups := 0
+	lookup := func(ctx context.Context, addr string) ([]string, error) {
+		lookups++
+		if addr == "192.0.2.1" {
+			return []string{"core01.pop01.example.com."}, nil
+		}
+
+		return nil, fmt.Errorf("no such host")
+	}
+
+	cfg := &Config{}
+	cfg.loadDefaults()
+	r := newResolver(cfg, lookup)
+
+	known := netip.AddrFrom4([4]byte{192, 0, 2, 1})
+	unknown := netip.AddrFrom4([4]byte{192, 0, 2, 2})
+
+	assert.Equal(t, "", r.Resolve(known), "cache miss")
+	assert.Equal(t, 1, len(r.queue), "lookup scheduled")
+
+	r.Resolve(known)
+	assert.Equal(t, 1, len(r.queue), "lookup is scheduled only once")
+
+	r.resolve(<-r.queue)
+	assert.Equal(t, "core01.pop01.example.com", r.Resolve(known), "cache hit")
+	assert.Equal(t, 0, len(r.queue), "no lookup on cache hit")
+
+	r.Resolve(unknown)
+	r.resolve(<-r.queue)
+	assert.Equal(t, "", r.Resolve(unknown), "negative cache hit")
+	assert.Equal(t, 0, len(r.queue), "no lookup on negative cache hit")
+	assert.Equal(t, 2, lookups)
+
+	r.cache[known].expires = time.Now().Add(-time.Second)
+	assert.Equal(t, "core01.pop01.example.com", r.Resolve(known), "stale name is returned while refreshing")
+	assert.Equal(t, 1, len(r.queue), "refresh scheduled")
+
+	r.purge(time.Now())
+	_, exists := r.cache[known]
+	assert.False(t, exists, "expired entry purged")
+}
+
+func TestLoadDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected uint64
+	}{
+		{
+			name:     "Default",
+			expected: defaultCacheTTL,
+		},
+		{
+			name:     "Cache TTL",
+			cfg:      Config{CacheTTL: 60},
+			expected: 60,
+		},
+		{
+			name:     "Former TTL",
+			cfg:      Config{TTL: 120},
+			expected: 120,
+		},
+		{
+			name:     "Cache TTL wins",
+			cfg:      Config{CacheTTL: 60, TTL: 120},
+			expected: 60,
+		},
+	}
+
+	for _, test := range tests {
+		test.cfg.loadDefaults()
+		assert.Equal(t, test.expected, test.cfg.CacheTTL, test.name)
+	}
+}
@@ -0,0 +1,216 @@
+// Package relay forwards received export datagrams to downstream collectors
+package relay
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultQueueLength  = 1024
+	defaultMaxExporters = 4096
+
+	// datagramSize is the initial capacity of pooled datagrams, the size of the receive buffers of the servers
+	datagramSize = 8960
+)
+
+var (
+	datagramsForwarded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "relay",
+		Name:      "forwarded_datagrams",
+		Help:      "Datagrams forwarded to downstream collectors",
+	}, []string{"protocol", "target"})
+	datagramsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "relay",
+		Name:      "dropped_datagrams",
+		Help:      "Datagrams dropped due to a full queue or send errors",
+	}, []string{"protocol", "target"})
+)
+
+// Config is the relays configuration. It lists the downstream collectors (host:port) per protocol.
+type Config struct {
+	SFlow       []string `yaml:"sflow"`
+	IPFIX       []string `yaml:"ipfix"`
+	QueueLength int      `yaml:"queue_length"`
+
+	// MaxExporters limits the number of exporters whose datagrams are forwarded, each takes a socket per target
+	MaxExporters int `yaml:"max_exporters"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.QueueLength == 0 {
+		c.QueueLength = defaultQueueLength
+	}
+
+	if c.MaxExporters == 0 {
+		c.MaxExporters = defaultMaxExporters
+	}
+}
+
+// Relay forwards datagrams to a set of targets. Forwarding never blocks the caller,
+// datagrams are dropped if a target can not keep up.
+type Relay struct {
+	protocol     string
+	maxExporters int
+	targets      []*target
+	wg           sync.WaitGroup
+}
+
+// target is a downstream collector. Datagrams of each exporter are sent from a socket of their own, so collectors
+// keeping templates per transport session (source address and port, RFC 7011) keep the templates of the exporters
+// apart.
+type target struct {
+	addr    string
+	udpAddr *net.UDPAddr
+
+	// conns are the sockets by exporter, used by the sender of the target only
+	conns map[netip.Addr]*net.UDPConn
+	queue chan *datagram
+}
+
+// datagram is a copy of a received datagram shared by all targets. The last target done with it returns it to the pool.
+type datagram struct {
+	exporter netip.Addr
+	b        []byte
+	refs     int32
+}
+
+var datagrams = sync.Pool{
+	New: func() interface{} {
+		return &datagram{
+			b: make([]byte, 0, datagramSize),
+		}
+	},
+}
+
+func (d *datagram) release() {
+	if atomic.AddInt32(&d.refs, -1) == 0 {
+		datagrams.Put(d)
+	}
+}
+
+// New creates a new relay for protocol using the configured targets of that protocol.
+// It returns nil if no targets are configured for protocol.
+func New(cfg *Config, protocol string, targets []string) (*Relay, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	cfg.loadDefaults()
+
+	r := &Relay{
+		protocol:     protocol,
+		maxExporters: cfg.MaxExporters,
+		targets:      make([]*target, 0, len(targets)),
+	}
+
+	for _, t := range targets {
+		addr, err := net.ResolveUDPAddr("udp", t)
+		if err != nil {
+			r.Stop()
+			return nil, errors.Wrapf(err, "Unable to resolve %q", t)
+		}
+
+		// sockets are opened per exporter on demand, this one only checks the target can be connected to
+		conn, err := net.DialUDP("udp", nil, addr)
+		if err != nil {
+			r.Stop()
+			return nil, errors.Wrapf(err, "Unable to connect to %q", t)
+		}
+		conn.Close()
+
+		r.targets = append(r.targets, &target{
+			addr:    t,
+			udpAddr: addr,
+			conns:   make(map[netip.Addr]*net.UDPConn),
+			queue:   make(chan *datagram, cfg.QueueLength),
+		})
+	}
+
+	for _, t := range r.targets {
+		r.wg.Add(1)
+		go r.sender(t)
+	}
+
+	return r, nil
+}
+
+// Forward queues a copy of pkt received from exporter for all targets
+func (r *Relay) Forward(exporter netip.Addr, pkt []byte) {
+	d := datagrams.Get().(*datagram)
+	d.exporter = exporter
+	d.b = append(d.b[:0], pkt...)
+	d.refs = int32(len(r.targets))
+
+	for _, t := range r.targets {
+		select {
+		case t.queue <- d:
+		default:
+			datagramsDropped.WithLabelValues(r.protocol, t.addr).Inc()
+			d.release()
+		}
+	}
+}
+
+func (r *Relay) sender(t *target) {
+	defer r.wg.Done()
+
+	for d := range t.queue {
+		conn, err := t.conn(d.exporter, r.maxExporters)
+		if err == nil {
+			_, err = conn.Write(d.b)
+		}
+		d.release()
+		if err != nil {
+			datagramsDropped.WithLabelValues(r.protocol, t.addr).Inc()
+			log.WithError(err).Debugf("Unable to forward %s datagram to %s", r.protocol, t.addr)
+			continue
+		}
+
+		datagramsForwarded.WithLabelValues(r.protocol, t.addr).Inc()
+	}
+}
+
+// conn gets the socket datagrams of exporter are sent from, opening it if there is none yet
+func (t *target) conn(exporter netip.Addr, maxExporters int) (*net.UDPConn, error) {
+	if conn, exists := t.conns[exporter]; exists {
+		return conn, nil
+	}
+
+	if len(t.conns) >= maxExporters {
+		return nil, errors.Errorf("Exceeded the limit of %d exporters", maxExporters)
+	}
+
+	conn, err := net.DialUDP("udp", nil, t.udpAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to connect")
+	}
+
+	t.conns[exporter] = conn
+	return conn, nil
+}
+
+// Stop stops forwarding and closes all connections
+func (r *Relay) Stop() {
+	for _, t := range r.targets {
+		close(t.queue)
+	}
+
+	r.wg.Wait()
+
+	for _, t := range r.targets {
+		for _, conn := range t.conns {
+			conn.Close()
+		}
+	}
+}
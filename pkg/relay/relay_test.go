@@ -0,0 +1,89 @@
+package relay
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForward(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	defer conn.Close()
+
+	r, err := New(&Config{}, "sflow", []string{conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("Unable to create relay: %v", err)
+	}
+	defer r.Stop()
+
+	pkt := []byte{1, 2, 3, 4}
+	r.Forward(netip.MustParseAddr("192.0.2.1"), pkt)
+
+	// the relay has to work on a copy as the caller reuses its buffer
+	pkt[0] = 42
+
+	buf := make([]byte, 16)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	assert.Equal(t, []byte{1, 2, 3, 4}, buf[:n])
+}
+
+func TestForwardPerExporter(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	defer conn.Close()
+
+	r, err := New(&Config{MaxExporters: 2}, "ipfix", []string{conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("Unable to create relay: %v", err)
+	}
+	defer r.Stop()
+
+	a := netip.MustParseAddr("192.0.2.1")
+	b := netip.MustParseAddr("192.0.2.2")
+	read := func() (byte, *net.UDPAddr) {
+		buf := make([]byte, 16)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil || n != 1 {
+			t.Fatalf("Read failed: %v", err)
+		}
+
+		return buf[0], src
+	}
+
+	sources := make(map[byte]*net.UDPAddr)
+	for i, exporter := range []netip.Addr{a, b, a} {
+		r.Forward(exporter, []byte{byte(i)})
+		i, src := read()
+		sources[i] = src
+	}
+
+	assert.Equal(t, sources[0].Port, sources[2].Port, "datagrams of an exporter are sent from the same socket")
+	assert.NotEqual(t, sources[0].Port, sources[1].Port, "datagrams of exporters are sent from sockets of their own")
+
+	// the datagram of a third exporter exceeds the limit and is dropped
+	r.Forward(netip.MustParseAddr("192.0.2.3"), []byte{3})
+	r.Forward(b, []byte{4})
+	i, src := read()
+	assert.Equal(t, byte(4), i)
+	assert.Equal(t, sources[1].Port, src.Port)
+}
+
+func TestNewWithoutTargets(t *testing.T) {
+	r, err := New(&Config{}, "ipfix", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+}
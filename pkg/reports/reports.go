@@ -0,0 +1,332 @@
+// Package reports runs saved queries on a schedule and delivers their results to webhooks
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/bio-routing/flowhouse/pkg/requestid"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// timeFormat is the format of time_start and time_end of /query
+const timeFormat = "2006-01-02T15:04"
+
+// Runner runs queries given as parameters of /query
+type Runner interface {
+	RunQuery(ctx context.Context, fields url.Values) (*frontend.QueryResult, error)
+}
+
+// Config configures the scheduled queries and the webhooks their results are sent to
+type Config struct {
+	Queries  []*Query         `yaml:"queries"`
+	Webhooks []*WebhookConfig `yaml:"webhooks"`
+}
+
+// Query is a saved query run on a schedule
+type Query struct {
+	Name string `yaml:"name"`
+
+	// Parameters are the parameters of /query without the time range, e.g. breakdown=dst_asn&topFlows=10
+	Parameters string `yaml:"parameters"`
+
+	// Interval is the time between two runs in seconds
+	Interval uint64 `yaml:"interval"`
+
+	// Range is the time range in seconds ending at the minute the query runs (default: the interval)
+	Range uint64 `yaml:"range"`
+
+	// Webhooks lists the names of the webhooks to deliver the result to. All webhooks are used if empty.
+	Webhooks []string `yaml:"webhooks"`
+
+	fields url.Values
+}
+
+func (q *Query) validate() error {
+	if q.Name == "" {
+		return fmt.Errorf("Query without name")
+	}
+
+	if q.Interval == 0 {
+		return fmt.Errorf("Query %q: interval not set", q.Name)
+	}
+
+	if q.Range == 0 {
+		q.Range = q.Interval
+	}
+
+	fields, err := url.ParseQuery(q.Parameters)
+	if err != nil {
+		return fmt.Errorf("Query %q: invalid parameters: %v", q.Name, err)
+	}
+
+	if len(fields) == 0 {
+		return fmt.Errorf("Query %q: parameters not set", q.Name)
+	}
+
+	for _, f := range []string{"time_start", "time_end", "tz"} {
+		if _, exists := fields[f]; exists {
+			return fmt.Errorf("Query %q: %s is set by the schedule", q.Name, f)
+		}
+	}
+
+	q.fields = fields
+	return nil
+}
+
+// timeRange gets the fields of q for the range ending at the minute now is in
+func (q *Query) timeRange(now time.Time) (url.Values, time.Time, time.Time) {
+	end := now.UTC().Truncate(time.Minute)
+	start := end.Add(-time.Duration(q.Range) * time.Second)
+
+	fields := make(url.Values)
+	for k, v := range q.fields {
+		fields[k] = v
+	}
+	fields.Set("time_start", start.Format(timeFormat))
+	fields.Set("time_end", end.Format(timeFormat))
+
+	return fields, start, end
+}
+
+// Delivery is the body POSTed to webhooks
+type Delivery struct {
+	Query      string                `json:"query"`
+	Parameters string                `json:"parameters"`
+	Start      time.Time             `json:"start"`
+	End        time.Time             `json:"end"`
+	Result     *frontend.QueryResult `json:"result"`
+}
+
+// QueryStatus is the state of a scheduled query as reported by the API
+type QueryStatus struct {
+	Name      string    `json:"name"`
+	LastRun   time.Time `json:"last_run"`
+	NextRun   time.Time `json:"next_run"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+type queryState struct {
+	query    *Query
+	webhooks []*webhook
+	lastRun  time.Time
+	nextRun  time.Time
+	lastErr  error
+	trigger  chan struct{}
+}
+
+// Manager runs the scheduled queries
+type Manager struct {
+	runner  Runner
+	queries []*queryState
+	mu      sync.RWMutex
+	stopCh  chan struct{}
+}
+
+// New creates a new manager and starts running the queries
+func New(cfg *Config, runner Runner) (*Manager, error) {
+	m := &Manager{
+		runner: runner,
+		stopCh: make(chan struct{}),
+	}
+
+	webhooks := make(map[string]*webhook)
+	for _, wh := range cfg.Webhooks {
+		if _, exists := webhooks[wh.Name]; exists {
+			return nil, fmt.Errorf("Duplicate webhook %q", wh.Name)
+		}
+
+		webhooks[wh.Name] = newWebhook(wh)
+	}
+
+	for _, q := range cfg.Queries {
+		err := q.validate()
+		if err != nil {
+			return nil, err
+		}
+
+		if m.getQuery(q.Name) != nil {
+			return nil, fmt.Errorf("Duplicate query %q", q.Name)
+		}
+
+		qs := &queryState{
+			query:   q,
+			trigger: make(chan struct{}, 1),
+		}
+
+		for _, name := range q.Webhooks {
+			wh, exists := webhooks[name]
+			if !exists {
+				return nil, fmt.Errorf("Query %q: unknown webhook %q", q.Name, name)
+			}
+
+			qs.webhooks = append(qs.webhooks, wh)
+		}
+
+		if len(q.Webhooks) == 0 {
+			for _, wh := range cfg.Webhooks {
+				qs.webhooks = append(qs.webhooks, webhooks[wh.Name])
+			}
+		}
+
+		if len(qs.webhooks) == 0 {
+			return nil, fmt.Errorf("Query %q: no webhooks configured", q.Name)
+		}
+
+		m.queries = append(m.queries, qs)
+	}
+
+	for _, qs := range m.queries {
+		go m.scheduler(qs)
+	}
+
+	return m, nil
+}
+
+// Stop stops running queries
+func (m *Manager) Stop() {
+	close(m.stopCh)
+}
+
+func (m *Manager) getQuery(name string) *queryState {
+	for _, qs := range m.queries {
+		if qs.query.Name == name {
+			return qs
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) scheduler(qs *queryState) {
+	interval := time.Duration(qs.query.Interval) * time.Second
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	m.mu.Lock()
+	qs.nextRun = time.Now().Add(interval)
+	m.mu.Unlock()
+
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case now := <-t.C:
+			m.run(qs, now)
+		case <-qs.trigger:
+			m.run(qs, time.Now())
+		}
+
+		m.mu.Lock()
+		qs.nextRun = time.Now().Add(interval)
+		m.mu.Unlock()
+	}
+}
+
+// run runs a query and delivers the result to its webhooks
+func (m *Manager) run(qs *queryState, now time.Time) {
+	id := requestid.New()
+	err := m.deliver(requestid.NewContext(context.Background(), id), qs, now)
+	if err != nil {
+		log.WithError(err).WithField("request_id", id).Errorf("Scheduled query %q failed", qs.query.Name)
+	}
+
+	m.mu.Lock()
+	qs.lastRun = now
+	qs.lastErr = err
+	m.mu.Unlock()
+}
+
+func (m *Manager) deliver(ctx context.Context, qs *queryState, now time.Time) error {
+	fields, start, end := qs.query.timeRange(now)
+	res, err := m.runner.RunQuery(ctx, fields)
+	if err != nil {
+		return err
+	}
+
+	d := &Delivery{
+		Query:      qs.query.Name,
+		Parameters: qs.query.Parameters,
+		Start:      start,
+		End:        end,
+		Result:     res,
+	}
+
+	var firstErr error
+	for _, wh := range qs.webhooks {
+		err := wh.deliver(d)
+		if err != nil {
+			log.WithError(err).Errorf("Unable to deliver scheduled query %q to webhook %q", qs.query.Name, wh.cfg.Name)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// Status returns the state of all queries
+func (m *Manager) Status() []*QueryStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make([]*QueryStatus, 0, len(m.queries))
+	for _, qs := range m.queries {
+		s := &QueryStatus{
+			Name:    qs.query.Name,
+			LastRun: qs.lastRun,
+			NextRun: qs.nextRun,
+		}
+
+		if qs.lastErr != nil {
+			s.LastError = qs.lastErr.Error()
+		}
+
+		res = append(res, s)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
+
+// Handler serves the state of all queries as JSON. POST with parameter name runs a query right away.
+func (m *Manager) Handler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		j, err := json.Marshal(m.Status())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(j)
+	case http.MethodPost:
+		qs := m.getQuery(r.URL.Query().Get("name"))
+		if qs == nil {
+			http.Error(w, "Unknown query", http.StatusNotFound)
+			return
+		}
+
+		select {
+		case qs.trigger <- struct{}{}:
+		default:
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
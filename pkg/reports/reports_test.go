@@ -0,0 +1,185 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/bio-routing/flowhouse/pkg/requestid"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRunner struct {
+	fields    url.Values
+	requestID string
+	err       error
+}
+
+func (r *fakeRunner) RunQuery(ctx context.Context, fields url.Values) (*frontend.QueryResult, error) {
+	r.fields = fields
+	r.requestID = requestid.FromContext(ctx)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return &frontend.QueryResult{
+		Unit:       "Mbps",
+		Timestamps: []time.Time{time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		Series: []*frontend.QuerySeries{
+			{Name: "dst_asn=64500", Values: []uint64{42}},
+		},
+	}, nil
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		wantFail bool
+	}{
+		{
+			name: "Valid",
+			cfg: &Config{
+				Queries:  []*Query{{Name: "q", Parameters: "breakdown=dst_asn", Interval: 3600}},
+				Webhooks: []*WebhookConfig{{Name: "tickets", URL: "http://localhost"}},
+			},
+		},
+		{
+			name: "No interval",
+			cfg: &Config{
+				Queries:  []*Query{{Name: "q", Parameters: "breakdown=dst_asn"}},
+				Webhooks: []*WebhookConfig{{Name: "tickets", URL: "http://localhost"}},
+			},
+			wantFail: true,
+		},
+		{
+			name: "Time range in parameters",
+			cfg: &Config{
+				Queries:  []*Query{{Name: "q", Parameters: "breakdown=dst_asn&time_start=2024-01-01T00:00", Interval: 3600}},
+				Webhooks: []*WebhookConfig{{Name: "tickets", URL: "http://localhost"}},
+			},
+			wantFail: true,
+		},
+		{
+			name: "Unknown webhook",
+			cfg: &Config{
+				Queries:  []*Query{{Name: "q", Parameters: "breakdown=dst_asn", Interval: 3600, Webhooks: []string{"chat"}}},
+				Webhooks: []*WebhookConfig{{Name: "tickets", URL: "http://localhost"}},
+			},
+			wantFail: true,
+		},
+		{
+			name: "No webhooks",
+			cfg: &Config{
+				Queries: []*Query{{Name: "q", Parameters: "breakdown=dst_asn", Interval: 3600}},
+			},
+			wantFail: true,
+		},
+		{
+			name: "Duplicate query",
+			cfg: &Config{
+				Queries: []*Query{
+					{Name: "q", Parameters: "breakdown=dst_asn", Interval: 3600},
+					{Name: "q", Parameters: "breakdown=src_asn", Interval: 3600},
+				},
+				Webhooks: []*WebhookConfig{{Name: "tickets", URL: "http://localhost"}},
+			},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		m, err := New(test.cfg, &fakeRunner{})
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error for test %q: %v", test.name, err)
+			continue
+		}
+
+		m.Stop()
+	}
+}
+
+func TestRun(t *testing.T) {
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+	}))
+	defer srv.Close()
+
+	runner := &fakeRunner{}
+	m, err := New(&Config{
+		Queries:  []*Query{{Name: "transit", Parameters: "breakdown=dst_asn&topFlows=5", Interval: 86400, Range: 3600}},
+		Webhooks: []*WebhookConfig{{Name: "tickets", URL: srv.URL, Secret: "s3cret"}},
+	}, runner)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer m.Stop()
+
+	now := time.Date(2024, 1, 1, 12, 0, 42, 0, time.UTC)
+	m.run(m.queries[0], now)
+
+	assert.Equal(t, url.Values{
+		"breakdown":  {"dst_asn"},
+		"topFlows":   {"5"},
+		"time_start": {"2024-01-01T11:00"},
+		"time_end":   {"2024-01-01T12:00"},
+	}, runner.fields)
+	assert.True(t, requestid.Valid(runner.requestID), "request ID %q", runner.requestID)
+
+	assert.True(t, Verify("s3cret", body, signature), "signature %q", signature)
+	assert.False(t, Verify("other", body, signature))
+
+	d := &Delivery{}
+	err = json.Unmarshal(body, d)
+	if err != nil {
+		t.Fatalf("Unable to unmarshal delivery: %v", err)
+	}
+
+	assert.Equal(t, "transit", d.Query)
+	assert.Equal(t, time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), d.Start)
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), d.End)
+	assert.Equal(t, []uint64{42}, d.Result.Series[0].Values)
+
+	status := m.Status()
+	assert.Equal(t, now, status[0].LastRun)
+	assert.Empty(t, status[0].LastError)
+
+	runner.err = fmt.Errorf("Query failed")
+	m.run(m.queries[0], now)
+	assert.Equal(t, "Query failed", m.Status()[0].LastError)
+}
+
+func TestHandlerTrigger(t *testing.T) {
+	m, err := New(&Config{
+		Queries:  []*Query{{Name: "transit", Parameters: "breakdown=dst_asn", Interval: 86400}},
+		Webhooks: []*WebhookConfig{{Name: "tickets", URL: "http://localhost"}},
+	}, &fakeRunner{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer m.Stop()
+
+	rec := httptest.NewRecorder()
+	m.Handler(rec, httptest.NewRequest(http.MethodPost, "/scheduled_queries?name=unknown", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	m.Handler(rec, httptest.NewRequest(http.MethodGet, "/scheduled_queries", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"transit"`)
+}
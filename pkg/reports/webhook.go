@@ -0,0 +1,92 @@
+package reports
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	deliveryTimeout = 10 * time.Second
+
+	// SignatureHeader carries the HMAC-SHA256 of the body as sha256=<hex>
+	SignatureHeader = "X-Flowhouse-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+// WebhookConfig configures an URL results are POSTed to as JSON
+type WebhookConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+
+	// Secret is the key the body is signed with. Requests are not signed if empty.
+	Secret string `yaml:"secret"`
+}
+
+type webhook struct {
+	cfg    *WebhookConfig
+	client *http.Client
+}
+
+func newWebhook(cfg *WebhookConfig) *webhook {
+	return &webhook{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: deliveryTimeout,
+		},
+	}
+}
+
+func (wh *webhook) deliver(d *Delivery) error {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return errors.Wrap(err, "Unable to marshal")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, wh.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "Unable to create request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if wh.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(wh.cfg.Secret, body))
+	}
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "POST to %q failed", wh.cfg.URL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST to %q failed: %s", wh.cfg.URL, resp.Status)
+	}
+
+	return nil
+}
+
+// Sign gets the signature of body as sent in the SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a body received by a webhook
+func Verify(secret string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
@@ -0,0 +1,44 @@
+// Package requestid correlates an API call with its log entries, error responses and ClickHouse queries
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+// Header is the HTTP header carrying the request ID in both directions
+const Header = "X-Request-ID"
+
+// maxLength limits IDs passed in by clients as they end up in logs and SQL comments
+const maxLength = 64
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+type contextKey struct{}
+
+// New generates a random request ID
+func New() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+// Valid checks whether id may be used as request ID. Only a restricted set of characters is allowed so IDs can be
+// embedded into SQL comments and log lines safely.
+func Valid(id string) bool {
+	return len(id) <= maxLength && validID.MatchString(id)
+}
+
+// NewContext returns a copy of ctx carrying id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext gets the request ID of ctx, empty if there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,41 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	id := New()
+	assert.Len(t, id, 16)
+	assert.True(t, Valid(id))
+	assert.NotEqual(t, id, New())
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id       string
+		expected bool
+	}{
+		{id: "0123456789abcdef", expected: true},
+		{id: "client-42.retry_1", expected: true},
+		{id: "", expected: false},
+		{id: "a b", expected: false},
+		{id: "*/ DROP TABLE flows /*", expected: false},
+		{id: "line\nbreak", expected: false},
+		{id: strings.Repeat("a", 64), expected: true},
+		{id: strings.Repeat("a", 65), expected: false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Valid(test.id), test.id)
+	}
+}
+
+func TestContext(t *testing.T) {
+	assert.Equal(t, "", FromContext(context.Background()))
+	assert.Equal(t, "abc", FromContext(NewContext(context.Background(), "abc")))
+}
@@ -0,0 +1,196 @@
+// Package retention deletes flows by agent, customer or time range, e.g. for erasure requests or after devices are
+// decommissioned. Every deletion is written to the audit log.
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/bio-routing/flowhouse/pkg/logging"
+	"github.com/bio-routing/flowhouse/pkg/requestid"
+	"github.com/pkg/errors"
+)
+
+var audit = logging.Component(logging.Audit)
+
+// Deleter deletes flows, e.g. the ClickHouse gateway
+type Deleter interface {
+	CountFlows(ctx context.Context, d *clickhousegw.FlowDeletion) (uint64, error)
+	DeleteFlows(ctx context.Context, d *clickhousegw.FlowDeletion) (*clickhousegw.FlowDeletionResult, error)
+}
+
+// Request selects the flows to delete. All given criteria have to match.
+type Request struct {
+	Start     *time.Time `json:"start,omitempty"`
+	End       *time.Time `json:"end,omitempty"`
+	Agents    []string   `json:"agents,omitempty"`
+	Customers []string   `json:"customers,omitempty"`
+
+	// DryRun only counts the matching flows
+	DryRun bool `json:"dry_run"`
+}
+
+// Result is the outcome of a request
+type Result struct {
+	*clickhousegw.FlowDeletionResult
+	DryRun bool `json:"dry_run"`
+}
+
+// deletion validates r and converts it for the gateway
+func (r *Request) deletion() (*clickhousegw.FlowDeletion, error) {
+	d := &clickhousegw.FlowDeletion{
+		Agents:    make([]netip.Addr, 0, len(r.Agents)),
+		Customers: make([]string, 0, len(r.Customers)),
+	}
+
+	if r.Start != nil {
+		d.Start = *r.Start
+	}
+
+	if r.End != nil {
+		d.End = *r.End
+	}
+
+	if !d.Start.IsZero() && !d.End.IsZero() && !d.Start.Before(d.End) {
+		return nil, fmt.Errorf("Start must be before end")
+	}
+
+	for _, a := range r.Agents {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid agent %q", a)
+		}
+
+		d.Agents = append(d.Agents, addr)
+	}
+
+	for _, c := range r.Customers {
+		if c == "" {
+			return nil, fmt.Errorf("Empty customer")
+		}
+
+		d.Customers = append(d.Customers, c)
+	}
+
+	if d.Start.IsZero() && d.End.IsZero() && len(d.Agents) == 0 && len(d.Customers) == 0 {
+		return nil, fmt.Errorf("At least one of start, end, agents and customers is required")
+	}
+
+	return d, nil
+}
+
+// String describes the flows selected by r for the audit log
+func (r *Request) String() string {
+	parts := make([]string, 0)
+	if r.Start != nil {
+		parts = append(parts, "start="+r.Start.UTC().Format(time.RFC3339))
+	}
+
+	if r.End != nil {
+		parts = append(parts, "end="+r.End.UTC().Format(time.RFC3339))
+	}
+
+	if len(r.Agents) > 0 {
+		parts = append(parts, "agents="+strings.Join(r.Agents, ","))
+	}
+
+	if len(r.Customers) > 0 {
+		parts = append(parts, "customers="+strings.Join(r.Customers, ","))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Delete deletes the flows selected by req, or only counts them on a dry run. actor is who asked for the deletion and
+// is written to the audit log along with the request and its outcome.
+func Delete(ctx context.Context, d Deleter, req *Request, actor string) (*Result, error) {
+	fd, err := req.deletion()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := audit.WithField("actor", actor).WithField("filter", req.String()).WithField("dry_run", req.DryRun)
+	if id := requestid.FromContext(ctx); id != "" {
+		entry = entry.WithField("request_id", id)
+	}
+
+	res := &Result{
+		DryRun: req.DryRun,
+	}
+	if req.DryRun {
+		n, err := d.CountFlows(ctx, fd)
+		if err != nil {
+			entry.WithError(err).Error("Counting flows to delete failed")
+			return nil, errors.Wrap(err, "Unable to count flows")
+		}
+
+		res.FlowDeletionResult = &clickhousegw.FlowDeletionResult{
+			Rows: n,
+		}
+		entry.WithField("rows", n).Info("Counted flows to delete")
+		return res, nil
+	}
+
+	entry.Info("Deleting flows")
+	res.FlowDeletionResult, err = d.DeleteFlows(ctx, fd)
+	if err != nil {
+		entry.WithError(err).Error("Deleting flows failed")
+		return nil, errors.Wrap(err, "Unable to delete flows")
+	}
+
+	entry.WithField("rows", res.Rows).
+		WithField("dropped_partitions", res.DroppedPartitions).
+		WithField("mutation", res.Mutation).
+		Info("Deleted flows")
+
+	return res, nil
+}
+
+// Handler returns a handler for /admin/retention/delete. It takes a Request as JSON body and returns the Result. The
+// user of the request (if authenticated) is the actor in the audit log.
+func Handler(d Deleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		req := &Request{}
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := req.deletion(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		actor, _, _ := r.BasicAuth()
+		if actor == "" {
+			actor = r.RemoteAddr
+		}
+
+		res, err := Delete(r.Context(), d, req, actor)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		j, err := json.Marshal(res)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(j)
+	}
+}
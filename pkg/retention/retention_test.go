@@ -0,0 +1,155 @@
+package retention
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockDeleter struct {
+	rows    uint64
+	deleted *clickhousegw.FlowDeletion
+	counted *clickhousegw.FlowDeletion
+}
+
+func (m *mockDeleter) CountFlows(ctx context.Context, d *clickhousegw.FlowDeletion) (uint64, error) {
+	m.counted = d
+	return m.rows, nil
+}
+
+func (m *mockDeleter) DeleteFlows(ctx context.Context, d *clickhousegw.FlowDeletion) (*clickhousegw.FlowDeletionResult, error) {
+	m.deleted = d
+	return &clickhousegw.FlowDeletionResult{
+		Rows:     m.rows,
+		Mutation: true,
+	}, nil
+}
+
+func TestDeletion(t *testing.T) {
+	start := time.Unix(1700000000, 0).UTC()
+	end := start.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		req      *Request
+		expected *clickhousegw.FlowDeletion
+		wantFail bool
+	}{
+		{
+			name: "Agent and customer",
+			req: &Request{
+				Agents:    []string{"192.0.2.1"},
+				Customers: []string{"acme"},
+			},
+			expected: &clickhousegw.FlowDeletion{
+				Agents:    []netip.Addr{netip.MustParseAddr("192.0.2.1")},
+				Customers: []string{"acme"},
+			},
+		},
+		{
+			name: "Time range",
+			req: &Request{
+				Start: &start,
+				End:   &end,
+			},
+			expected: &clickhousegw.FlowDeletion{
+				Start:     start,
+				End:       end,
+				Agents:    []netip.Addr{},
+				Customers: []string{},
+			},
+		},
+		{
+			name:     "No criteria",
+			req:      &Request{},
+			wantFail: true,
+		},
+		{
+			name: "End before start",
+			req: &Request{
+				Start: &end,
+				End:   &start,
+			},
+			wantFail: true,
+		},
+		{
+			name: "Invalid agent",
+			req: &Request{
+				Agents: []string{"rtr01"},
+			},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		d, err := test.req.deletion()
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected failure for %q: %v", test.name, err)
+			continue
+		}
+
+		assert.Equal(t, test.expected, d, test.name)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+		deleted        bool
+	}{
+		{
+			name:           "Delete",
+			body:           `{"agents": ["192.0.2.1"]}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"rows":42,"dropped_partitions":0,"mutation":true,"dry_run":false}`,
+			deleted:        true,
+		},
+		{
+			name:           "Dry run",
+			body:           `{"customers": ["acme"], "dry_run": true}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"rows":42,"dropped_partitions":0,"mutation":false,"dry_run":true}`,
+		},
+		{
+			name:           "No criteria",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid JSON",
+			body:           `{`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		d := &mockDeleter{rows: 42}
+		w := httptest.NewRecorder()
+		Handler(d)(w, httptest.NewRequest(http.MethodPost, "/admin/retention/delete", strings.NewReader(test.body)))
+
+		assert.Equal(t, test.expectedStatus, w.Code, test.name)
+		assert.Equal(t, test.deleted, d.deleted != nil, test.name)
+		if test.expectedBody != "" {
+			assert.Equal(t, test.expectedBody, w.Body.String(), test.name)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	Handler(&mockDeleter{})(w, httptest.NewRequest(http.MethodGet, "/admin/retention/delete", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,175 @@
+// Package ringbuffer queues batches of flows between decoding and inserting in a fixed amount of memory
+package ringbuffer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// DropOldest drops the oldest queued batches to make room for a new one
+	DropOldest = "drop-oldest"
+
+	// DropNewest drops a new batch if it does not fit
+	DropNewest = "drop-newest"
+
+	defaultBatches = 1024
+	defaultFlows   = 1000000
+)
+
+var (
+	droppedFlows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "queue_dropped_flows",
+		Help:      "Flows dropped because the queue was full",
+	}, []string{"policy"})
+	droppedBatches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "queue_dropped_batches",
+		Help:      "Batches dropped because the queue was full",
+	}, []string{"policy"})
+)
+
+// Config configures the ring buffer
+type Config struct {
+	// Batches is the number of batches the buffer holds
+	Batches int `yaml:"batches"`
+
+	// Flows is the number of flows the buffer holds over all batches
+	Flows int `yaml:"flows"`
+
+	// OverflowPolicy selects what is dropped once the buffer is full, drop-oldest (default) or drop-newest
+	OverflowPolicy string `yaml:"overflow_policy"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Batches == 0 {
+		c.Batches = defaultBatches
+	}
+
+	if c.Flows == 0 {
+		c.Flows = defaultFlows
+	}
+
+	if c.OverflowPolicy == "" {
+		c.OverflowPolicy = DropOldest
+	}
+}
+
+func (c *Config) validate() error {
+	if c.Batches < 1 || c.Flows < 1 {
+		return fmt.Errorf("batches and flows must be positive")
+	}
+
+	if c.OverflowPolicy != DropOldest && c.OverflowPolicy != DropNewest {
+		return fmt.Errorf("Unknown overflow_policy %q, expected %s or %s", c.OverflowPolicy, DropOldest, DropNewest)
+	}
+
+	return nil
+}
+
+// RingBuffer is a bounded FIFO of flow batches. Put never blocks, batches that do not fit are dropped according to the
+// overflow policy.
+type RingBuffer struct {
+	cfg      *Config
+	mu       sync.Mutex
+	nonEmpty *sync.Cond
+	slots    [][]*flow.Flow
+	head     int
+	batches  int
+	flows    int
+}
+
+// New creates a ring buffer
+func New(cfg *Config) (*RingBuffer, error) {
+	cfg.loadDefaults()
+	err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	rb := &RingBuffer{
+		cfg:   cfg,
+		slots: make([][]*flow.Flow, cfg.Batches),
+	}
+	rb.nonEmpty = sync.NewCond(&rb.mu)
+
+	return rb, nil
+}
+
+// Put queues a batch and takes ownership of its flows
+func (rb *RingBuffer) Put(flows []*flow.Flow) {
+	if len(flows) == 0 {
+		return
+	}
+
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	// a batch larger than the whole buffer can never fit
+	if len(flows) > rb.cfg.Flows {
+		rb.drop(flows)
+		return
+	}
+
+	for rb.batches == len(rb.slots) || rb.flows+len(flows) > rb.cfg.Flows {
+		if rb.cfg.OverflowPolicy == DropNewest {
+			rb.drop(flows)
+			return
+		}
+
+		rb.drop(rb.pop())
+	}
+
+	rb.slots[(rb.head+rb.batches)%len(rb.slots)] = flows
+	rb.batches++
+	rb.flows += len(flows)
+	rb.nonEmpty.Signal()
+}
+
+// Get takes the oldest batch, waiting for one if the buffer is empty. The caller takes ownership of its flows.
+func (rb *RingBuffer) Get() []*flow.Flow {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	for rb.batches == 0 {
+		rb.nonEmpty.Wait()
+	}
+
+	return rb.pop()
+}
+
+func (rb *RingBuffer) pop() []*flow.Flow {
+	flows := rb.slots[rb.head]
+	rb.slots[rb.head] = nil
+	rb.head = (rb.head + 1) % len(rb.slots)
+	rb.batches--
+	rb.flows -= len(flows)
+
+	return flows
+}
+
+func (rb *RingBuffer) drop(flows []*flow.Flow) {
+	droppedBatches.WithLabelValues(rb.cfg.OverflowPolicy).Inc()
+	droppedFlows.WithLabelValues(rb.cfg.OverflowPolicy).Add(float64(len(flows)))
+	flow.ReleaseAll(flows)
+}
+
+// Len gets the number of queued batches and flows
+func (rb *RingBuffer) Len() (batches int, flows int) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	return rb.batches, rb.flows
+}
+
+// Capacity gets the number of batches and flows the buffer holds
+func (rb *RingBuffer) Capacity() (batches int, flows int) {
+	return rb.cfg.Batches, rb.cfg.Flows
+}
@@ -0,0 +1,187 @@
+package ringbuffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// testBatch creates a batch of n flows marked with id in their sample rate
+func testBatch(id uint64, n int) []*flow.Flow {
+	res := make([]*flow.Flow, n)
+	for i := range res {
+		res[i] = flow.New()
+		res[i].Samplerate = id
+	}
+
+	return res
+}
+
+func batchIDs(rb *RingBuffer) []uint64 {
+	res := make([]uint64, 0)
+	for {
+		batches, _ := rb.Len()
+		if batches == 0 {
+			return res
+		}
+
+		res = append(res, rb.Get()[0].Samplerate)
+	}
+}
+
+func TestPut(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            *Config
+		batchSizes     []int
+		expected       []uint64
+		droppedBatches float64
+		droppedFlows   float64
+	}{
+		{
+			name:       "Fits",
+			cfg:        &Config{Batches: 4, Flows: 100},
+			batchSizes: []int{10, 10, 10},
+			expected:   []uint64{0, 1, 2},
+		},
+		{
+			name:           "Drop oldest on batches",
+			cfg:            &Config{Batches: 2, Flows: 100},
+			batchSizes:     []int{10, 20, 30},
+			expected:       []uint64{1, 2},
+			droppedBatches: 1,
+			droppedFlows:   10,
+		},
+		{
+			name:           "Drop oldest on flows",
+			cfg:            &Config{Batches: 4, Flows: 50},
+			batchSizes:     []int{10, 20, 30},
+			expected:       []uint64{1, 2},
+			droppedBatches: 1,
+			droppedFlows:   10,
+		},
+		{
+			name:           "Drop newest on batches",
+			cfg:            &Config{Batches: 2, Flows: 100, OverflowPolicy: DropNewest},
+			batchSizes:     []int{10, 20, 30},
+			expected:       []uint64{0, 1},
+			droppedBatches: 1,
+			droppedFlows:   30,
+		},
+		{
+			name:           "Drop newest on flows",
+			cfg:            &Config{Batches: 4, Flows: 50, OverflowPolicy: DropNewest},
+			batchSizes:     []int{10, 20, 30, 20},
+			expected:       []uint64{0, 1, 3},
+			droppedBatches: 1,
+			droppedFlows:   30,
+		},
+		{
+			name:           "Batch larger than buffer",
+			cfg:            &Config{Batches: 4, Flows: 50},
+			batchSizes:     []int{10, 60},
+			expected:       []uint64{0},
+			droppedBatches: 1,
+			droppedFlows:   60,
+		},
+	}
+
+	for _, test := range tests {
+		rb, err := New(test.cfg)
+		if err != nil {
+			t.Errorf("Unexpected failure for %q: %v", test.name, err)
+			continue
+		}
+
+		policy := rb.cfg.OverflowPolicy
+		batchesBefore := testutil.ToFloat64(droppedBatches.WithLabelValues(policy))
+		flowsBefore := testutil.ToFloat64(droppedFlows.WithLabelValues(policy))
+
+		for i, n := range test.batchSizes {
+			rb.Put(testBatch(uint64(i), n))
+		}
+
+		_, flows := rb.Len()
+		assert.LessOrEqual(t, flows, test.cfg.Flows, test.name)
+		assert.Equal(t, test.expected, batchIDs(rb), test.name)
+		assert.Equal(t, test.droppedBatches, testutil.ToFloat64(droppedBatches.WithLabelValues(policy))-batchesBefore, test.name)
+		assert.Equal(t, test.droppedFlows, testutil.ToFloat64(droppedFlows.WithLabelValues(policy))-flowsBefore, test.name)
+
+		_, flows = rb.Len()
+		assert.Equal(t, 0, flows, test.name)
+	}
+}
+
+func TestGetWaits(t *testing.T) {
+	rb, err := New(&Config{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	res := make(chan []*flow.Flow)
+	go func() {
+		res <- rb.Get()
+	}()
+
+	select {
+	case <-res:
+		t.Fatalf("Get returned on an empty buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	rb.Put(testBatch(7, 3))
+	select {
+	case flows := <-res:
+		assert.Len(t, flows, 3)
+		assert.Equal(t, uint64(7), flows[0].Samplerate)
+	case <-time.After(time.Second):
+		t.Fatalf("Get did not return after Put")
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		expected *Config
+		wantFail bool
+	}{
+		{
+			name: "Defaults",
+			cfg:  &Config{},
+			expected: &Config{
+				Batches:        defaultBatches,
+				Flows:          defaultFlows,
+				OverflowPolicy: DropOldest,
+			},
+		},
+		{
+			name:     "Unknown policy",
+			cfg:      &Config{OverflowPolicy: "block"},
+			wantFail: true,
+		},
+		{
+			name:     "Negative flows",
+			cfg:      &Config{Flows: -1},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		rb, err := New(test.cfg)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected failure for %q: %v", test.name, err)
+			continue
+		}
+
+		assert.Equal(t, test.expected, rb.cfg, test.name)
+	}
+}
@@ -0,0 +1,235 @@
+package rpki
+
+import (
+	"encoding/json"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/source"
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// StateValid means a VRP covering the prefix matches origin AS and length
+	StateValid = "valid"
+
+	// StateInvalid means covering VRPs exist but none matches origin AS and length
+	StateInvalid = "invalid"
+
+	// StateUnknown means no VRP covers the prefix
+	StateUnknown = "unknown"
+
+	defaultRefreshInterval = 600
+)
+
+// Config is the RPKI validators configuration
+type Config struct {
+	// VRPSource is a file path or a HTTP(S) URL of a VRP export in JSON format (as generated by rpki-client, routinator, etc.)
+	VRPSource       string `yaml:"vrp_source"`
+	RefreshInterval uint64 `yaml:"refresh_interval"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.RefreshInterval == 0 {
+		c.RefreshInterval = defaultRefreshInterval
+	}
+}
+
+type vrp struct {
+	asn       uint32
+	maxLength uint8
+}
+
+type vrpTable struct {
+	vrps  map[netip.Prefix][]vrp
+	count int
+}
+
+// Validator annotates flows with the RPKI origin validation state of their source and destination prefix
+type Validator struct {
+	cfg    *Config
+	table  *vrpTable
+	mu     sync.RWMutex
+	stopCh chan struct{}
+}
+
+// New creates a new validator, loads the VRPs and refreshes them periodically
+func New(cfg *Config) (*Validator, error) {
+	cfg.loadDefaults()
+
+	v := &Validator{
+		cfg:    cfg,
+		table:  newVRPTable(),
+		stopCh: make(chan struct{}),
+	}
+
+	err := v.load()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load VRPs")
+	}
+
+	go v.refresher()
+	return v, nil
+}
+
+// Stop stops the periodic refresh
+func (v *Validator) Stop() {
+	close(v.stopCh)
+}
+
+func (v *Validator) refresher() {
+	t := time.NewTicker(time.Duration(v.cfg.RefreshInterval) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-v.stopCh:
+			return
+		case <-t.C:
+			err := v.load()
+			if err != nil {
+				log.WithError(err).Error("Unable to refresh VRPs")
+			}
+		}
+	}
+}
+
+func (v *Validator) load() error {
+	rc, err := source.Open(v.cfg.VRPSource)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	t, err := parseVRPs(rc)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to parse VRPs from %q", v.cfg.VRPSource)
+	}
+
+	v.mu.Lock()
+	v.table = t
+	v.mu.Unlock()
+
+	log.Infof("Loaded %d VRPs from %s", t.count, v.cfg.VRPSource)
+	return nil
+}
+
+type vrpFile struct {
+	ROAs []struct {
+		Prefix    string          `json:"prefix"`
+		MaxLength uint8           `json:"maxLength"`
+		ASN       json.RawMessage `json:"asn"`
+	} `json:"roas"`
+}
+
+func parseVRPs(r io.Reader) (*vrpTable, error) {
+	var f vrpFile
+	err := json.NewDecoder(r).Decode(&f)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode JSON")
+	}
+
+	t := newVRPTable()
+	for _, roa := range f.ROAs {
+		pfx, err := netip.ParsePrefix(roa.Prefix)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid prefix %q", roa.Prefix)
+		}
+
+		asn, err := parseASN(roa.ASN)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid ASN for %q", roa.Prefix)
+		}
+
+		maxLength := roa.MaxLength
+		if maxLength < uint8(pfx.Bits()) {
+			maxLength = uint8(pfx.Bits())
+		}
+
+		t.add(pfx, vrp{
+			asn:       asn,
+			maxLength: maxLength,
+		})
+	}
+
+	return t, nil
+}
+
+// parseASN parses an ASN given either as number or as string ("AS65000" or "65000")
+func parseASN(raw json.RawMessage) (uint32, error) {
+	s := strings.Trim(string(raw), "\"")
+	s = strings.TrimPrefix(strings.ToUpper(s), "AS")
+
+	asn, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint32(asn), nil
+}
+
+func newVRPTable() *vrpTable {
+	return &vrpTable{
+		vrps: make(map[netip.Prefix][]vrp),
+	}
+}
+
+func (t *vrpTable) add(pfx netip.Prefix, v vrp) {
+	k := pfx.Masked()
+	t.vrps[k] = append(t.vrps[k], v)
+	t.count++
+}
+
+// validate carries out origin validation as described in RFC6811
+func (t *vrpTable) validate(pfx netip.Prefix, origin uint32) string {
+	state := StateUnknown
+	for l := pfx.Bits(); l >= 0; l-- {
+		k, err := pfx.Addr().Prefix(l)
+		if err != nil {
+			return state
+		}
+
+		for _, v := range t.vrps[k] {
+			state = StateInvalid
+			if v.asn != 0 && v.asn == origin && pfx.Bits() <= int(v.maxLength) {
+				return StateValid
+			}
+		}
+	}
+
+	return state
+}
+
+// Validate returns the validation state of a prefix originated by the given AS
+func (v *Validator) Validate(pfx netip.Prefix, origin uint32) string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.table.validate(pfx, origin)
+}
+
+// Annotate sets the RPKI validation state of the flows source and destination prefix
+func (v *Validator) Annotate(fl *flow.Flow) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if hasPrefix(fl.SrcPfx) {
+		fl.SrcRPKIState = v.table.validate(fl.SrcPfx, fl.SrcAs)
+	}
+
+	if hasPrefix(fl.DstPfx) {
+		fl.DstRPKIState = v.table.validate(fl.DstPfx, fl.DstAs)
+	}
+}
+
+// hasPrefix checks if a flow was annotated with a prefix by the routing information
+func hasPrefix(pfx netip.Prefix) bool {
+	return pfx.IsValid() && pfx.Bits() > 0
+}
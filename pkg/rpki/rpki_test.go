@@ -0,0 +1,116 @@
+package rpki
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+const testVRPs = `{
+	"roas": [
+		{ "asn": "AS65000", "prefix": "192.0.2.0/24", "maxLength": 24, "ta": "test" },
+		{ "asn": 65001, "prefix": "198.51.100.0/22", "maxLength": 24, "ta": "test" },
+		{ "asn": "AS0", "prefix": "203.0.113.0/24", "maxLength": 24, "ta": "test" },
+		{ "asn": "AS65002", "prefix": "2001:db8::/32", "maxLength": 48, "ta": "test" }
+	]
+}`
+
+func TestValidate(t *testing.T) {
+	table, err := parseVRPs(strings.NewReader(testVRPs))
+	if err != nil {
+		t.Fatalf("Unable to parse VRPs: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		pfx      string
+		origin   uint32
+		expected string
+	}{
+		{
+			name:     "Exact match",
+			pfx:      "192.0.2.0/24",
+			origin:   65000,
+			expected: StateValid,
+		},
+		{
+			name:     "Wrong origin",
+			pfx:      "192.0.2.0/24",
+			origin:   65001,
+			expected: StateInvalid,
+		},
+		{
+			name:     "More specific within max length",
+			pfx:      "198.51.101.0/24",
+			origin:   65001,
+			expected: StateValid,
+		},
+		{
+			name:     "More specific exceeding max length",
+			pfx:      "198.51.101.0/25",
+			origin:   65001,
+			expected: StateInvalid,
+		},
+		{
+			name:     "AS0 VRP",
+			pfx:      "203.0.113.0/24",
+			origin:   0,
+			expected: StateInvalid,
+		},
+		{
+			name:     "Not covered",
+			pfx:      "100.64.0.0/10",
+			origin:   65000,
+			expected: StateUnknown,
+		},
+		{
+			name:     "IPv6 valid",
+			pfx:      "2001:db8:1::/48",
+			origin:   65002,
+			expected: StateValid,
+		},
+		{
+			name:     "IPv6 too specific",
+			pfx:      "2001:db8:1::/64",
+			origin:   65002,
+			expected: StateInvalid,
+		},
+	}
+
+	for _, test := range tests {
+		pfx, err := netip.ParsePrefix(test.pfx)
+		if err != nil {
+			t.Fatalf("Unable to parse prefix %q: %v", test.pfx, err)
+		}
+
+		assert.Equal(t, test.expected, table.validate(pfx, test.origin), test.name)
+	}
+}
+
+func TestParseVRPsInvalid(t *testing.T) {
+	_, err := parseVRPs(strings.NewReader(`{"roas": [{"asn": "ASfoo", "prefix": "192.0.2.0/24", "maxLength": 24}]}`))
+	assert.Error(t, err)
+}
+
+func TestAnnotate(t *testing.T) {
+	table, err := parseVRPs(strings.NewReader(testVRPs))
+	if err != nil {
+		t.Fatalf("Unable to parse VRPs: %v", err)
+	}
+
+	v := &Validator{
+		table: table,
+	}
+
+	fl := &flow.Flow{
+		SrcPfx: netip.MustParsePrefix("192.0.2.0/24"),
+		SrcAs:  65000,
+	}
+	v.Annotate(fl)
+
+	assert.Equal(t, StateValid, fl.SrcRPKIState)
+	assert.Equal(t, "", fl.DstRPKIState)
+}
@@ -0,0 +1,315 @@
+// Package samplingreport compares the traffic of interfaces estimated from sFlow flow samples with the traffic counted
+// by their interface counters, so the sampling error of each agent and interface can be quantified
+package samplingreport
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+)
+
+const (
+	// DefaultIntervals is the default number of counter intervals a report covers
+	DefaultIntervals = 30
+
+	// confidence is the z-score of the 95% confidence interval of the expected error
+	confidence = 1.96
+)
+
+// Counters are the interface counters of an interface. Packet counters are 32 bit counters that may wrap.
+type Counters struct {
+	IfIndex    uint32
+	Name       string
+	InOctets   uint64
+	InPackets  uint32
+	OutOctets  uint64
+	OutPackets uint32
+}
+
+// traffic is the counted and estimated traffic in one direction
+type traffic struct {
+	countedBytes     uint64
+	countedPackets   uint64
+	estimatedBytes   uint64
+	estimatedPackets uint64
+	samples          uint64
+}
+
+func (t *traffic) add(o traffic) {
+	t.countedBytes += o.countedBytes
+	t.countedPackets += o.countedPackets
+	t.estimatedBytes += o.estimatedBytes
+	t.estimatedPackets += o.estimatedPackets
+	t.samples += o.samples
+}
+
+// interval is the traffic between two counter samples
+type interval struct {
+	duration time.Duration
+	in       traffic
+	out      traffic
+}
+
+type iface struct {
+	name         string
+	counters     *Counters
+	countersTime time.Time
+
+	// in and out are the estimates since the latest counter sample
+	in  traffic
+	out traffic
+
+	inSamplingRate  uint32
+	outSamplingRate uint32
+
+	intervals []interval
+	next      int
+}
+
+func (i *iface) addInterval(iv interval) {
+	if len(i.intervals) < cap(i.intervals) {
+		i.intervals = append(i.intervals, iv)
+		return
+	}
+
+	i.intervals[i.next] = iv
+	i.next = (i.next + 1) % len(i.intervals)
+}
+
+type ifaceKey struct {
+	agent   netip.Addr
+	ifIndex uint32
+}
+
+// Report accounts the samples and interface counters of the latest intervals per agent and interface
+type Report struct {
+	intervals int
+	ifaces    map[ifaceKey]*iface
+	mu        sync.Mutex
+}
+
+// New creates a report covering the latest intervals counter intervals of each interface
+func New(intervals int) *Report {
+	if intervals <= 0 {
+		intervals = DefaultIntervals
+	}
+
+	return &Report{
+		intervals: intervals,
+		ifaces:    make(map[ifaceKey]*iface),
+	}
+}
+
+func (r *Report) getIface(agent netip.Addr, ifIndex uint32) *iface {
+	k := ifaceKey{agent: agent, ifIndex: ifIndex}
+	i, exists := r.ifaces[k]
+	if !exists {
+		i = &iface{
+			intervals: make([]interval, 0, r.intervals),
+		}
+		r.ifaces[k] = i
+	}
+
+	return i
+}
+
+// AddSample accounts a packet of frameLength bytes sampled at samplingRate on interface ifIndex of agent in direction
+func (r *Report) AddSample(agent netip.Addr, ifIndex uint32, direction string, samplingRate uint32, frameLength uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.getIface(agent, ifIndex)
+	t, rate := &i.in, &i.inSamplingRate
+	if direction == flow.DirectionEgress {
+		t, rate = &i.out, &i.outSamplingRate
+	}
+
+	t.estimatedBytes += uint64(frameLength) * uint64(samplingRate)
+	t.estimatedPackets += uint64(samplingRate)
+	t.samples++
+	*rate = samplingRate
+}
+
+// AddCounters accounts the interface counters of agent received at now. The traffic counted since the previous
+// counters is compared to the traffic estimated from the samples in between. The first counters and counters that
+// were reset only start a new interval.
+func (r *Report) AddCounters(agent netip.Addr, c *Counters, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.getIface(agent, c.IfIndex)
+	prev, prevTime := i.counters, i.countersTime
+	in, out := i.in, i.out
+
+	i.name = c.Name
+	i.counters, i.countersTime = c, now
+	i.in, i.out = traffic{}, traffic{}
+
+	if prev == nil || c.InOctets < prev.InOctets || c.OutOctets < prev.OutOctets || !now.After(prevTime) {
+		return
+	}
+
+	in.countedBytes = c.InOctets - prev.InOctets
+	in.countedPackets = uint64(c.InPackets - prev.InPackets)
+	out.countedBytes = c.OutOctets - prev.OutOctets
+	out.countedPackets = uint64(c.OutPackets - prev.OutPackets)
+
+	i.addInterval(interval{
+		duration: now.Sub(prevTime),
+		in:       in,
+		out:      out,
+	})
+}
+
+// Direction compares the counted and estimated traffic of an interface in one direction. Errors are 0 without
+// counted traffic.
+type Direction struct {
+	CountedBytes     uint64 `json:"counted_bytes"`
+	CountedPackets   uint64 `json:"counted_packets"`
+	EstimatedBytes   uint64 `json:"estimated_bytes"`
+	EstimatedPackets uint64 `json:"estimated_packets"`
+	Samples          uint64 `json:"samples"`
+
+	// CountedRate and EstimatedRate are the average rates in bits per second
+	CountedRate   float64 `json:"counted_rate"`
+	EstimatedRate float64 `json:"estimated_rate"`
+
+	// SamplingRate is the sampling rate of the latest sample, EffectiveSamplingRate the counted packets per sample
+	SamplingRate          uint32  `json:"sampling_rate"`
+	EffectiveSamplingRate float64 `json:"effective_sampling_rate"`
+
+	// ByteError and PacketError are the relative errors of the estimates, positive for overestimated traffic
+	ByteError   float64 `json:"byte_error"`
+	PacketError float64 `json:"packet_error"`
+
+	// ExpectedError is the relative error of the packet estimate expected from sampling at 95% confidence
+	ExpectedError float64 `json:"expected_error"`
+}
+
+func newDirection(t traffic, samplingRate uint32, seconds float64) Direction {
+	d := Direction{
+		CountedBytes:     t.countedBytes,
+		CountedPackets:   t.countedPackets,
+		EstimatedBytes:   t.estimatedBytes,
+		EstimatedPackets: t.estimatedPackets,
+		Samples:          t.samples,
+		SamplingRate:     samplingRate,
+	}
+
+	if seconds > 0 {
+		d.CountedRate = float64(t.countedBytes) * 8 / seconds
+		d.EstimatedRate = float64(t.estimatedBytes) * 8 / seconds
+	}
+
+	if t.samples > 0 {
+		d.EffectiveSamplingRate = float64(t.countedPackets) / float64(t.samples)
+		d.ExpectedError = confidence / math.Sqrt(float64(t.samples))
+	}
+
+	d.ByteError = relativeError(t.estimatedBytes, t.countedBytes)
+	d.PacketError = relativeError(t.estimatedPackets, t.countedPackets)
+	return d
+}
+
+func relativeError(estimated uint64, counted uint64) float64 {
+	if counted == 0 {
+		return 0
+	}
+
+	return (float64(estimated) - float64(counted)) / float64(counted)
+}
+
+// Interface is the report of an interface of an agent over its latest intervals
+type Interface struct {
+	Agent     string    `json:"agent"`
+	IfIndex   uint32    `json:"if_index"`
+	Name      string    `json:"name"`
+	Intervals int       `json:"intervals"`
+	Seconds   float64   `json:"seconds"`
+	In        Direction `json:"in"`
+	Out       Direction `json:"out"`
+}
+
+// Interfaces reports the interfaces with at least one interval (optionally only those of agent) ordered by agent and
+// interface index
+func (r *Report) Interfaces(agent *netip.Addr) []*Interface {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]ifaceKey, 0, len(r.ifaces))
+	for k, i := range r.ifaces {
+		if len(i.intervals) == 0 || (agent != nil && k.agent != *agent) {
+			continue
+		}
+
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a].agent != keys[b].agent {
+			return keys[a].agent.Less(keys[b].agent)
+		}
+
+		return keys[a].ifIndex < keys[b].ifIndex
+	})
+
+	res := make([]*Interface, 0, len(keys))
+	for _, k := range keys {
+		i := r.ifaces[k]
+
+		var d time.Duration
+		var in, out traffic
+		for _, iv := range i.intervals {
+			d += iv.duration
+			in.add(iv.in)
+			out.add(iv.out)
+		}
+
+		res = append(res, &Interface{
+			Agent:     k.agent.String(),
+			IfIndex:   k.ifIndex,
+			Name:      i.name,
+			Intervals: len(i.intervals),
+			Seconds:   d.Seconds(),
+			In:        newDirection(in, i.inSamplingRate, d.Seconds()),
+			Out:       newDirection(out, i.outSamplingRate, d.Seconds()),
+		})
+	}
+
+	return res
+}
+
+// Handler handles requests for /agents/sampling. GET reports the interfaces, the agent parameter restricts the report
+// to a single agent.
+func (r *Report) Handler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var agent *netip.Addr
+	if a := req.URL.Query().Get("agent"); a != "" {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			http.Error(w, "Invalid agent", http.StatusBadRequest)
+			return
+		}
+		addr = addr.Unmap()
+		agent = &addr
+	}
+
+	j, err := json.Marshal(r.Interfaces(agent))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
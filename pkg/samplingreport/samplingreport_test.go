@@ -0,0 +1,141 @@
+package samplingreport
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	r := New(2)
+	agent := netip.MustParseAddr("192.0.2.1")
+	start := time.Unix(1700000000, 0)
+
+	// samples before the first counters are not compared to anything
+	r.AddSample(agent, 7, flow.DirectionIngress, 1000, 1500)
+	r.AddCounters(agent, &Counters{IfIndex: 7, Name: "et7", InOctets: 1000, InPackets: 0xfffffff0, OutOctets: 500}, start)
+	assert.Empty(t, r.Interfaces(nil))
+
+	for i := 0; i < 90; i++ {
+		r.AddSample(agent, 7, flow.DirectionIngress, 1000, 1500)
+	}
+	for i := 0; i < 4; i++ {
+		r.AddSample(agent, 7, flow.DirectionEgress, 100, 100)
+	}
+
+	// the packet counter wraps
+	r.AddCounters(agent, &Counters{IfIndex: 7, Name: "et7", InOctets: 1000 + 150000000, InPackets: 99984, OutOctets: 500 + 50000, OutPackets: 500}, start.Add(10*time.Second))
+
+	res := r.Interfaces(nil)
+	if !assert.Len(t, res, 1) {
+		return
+	}
+
+	i := res[0]
+	assert.Equal(t, "192.0.2.1", i.Agent)
+	assert.Equal(t, uint32(7), i.IfIndex)
+	assert.Equal(t, "et7", i.Name)
+	assert.Equal(t, 1, i.Intervals)
+	assert.Equal(t, 10.0, i.Seconds)
+
+	assert.Equal(t, uint64(150000000), i.In.CountedBytes)
+	assert.Equal(t, uint64(100000), i.In.CountedPackets)
+	assert.Equal(t, uint64(135000000), i.In.EstimatedBytes)
+	assert.Equal(t, uint64(90000), i.In.EstimatedPackets)
+	assert.Equal(t, uint64(90), i.In.Samples)
+	assert.Equal(t, uint32(1000), i.In.SamplingRate)
+	assert.InDelta(t, 1111.1, i.In.EffectiveSamplingRate, 0.1)
+	assert.InDelta(t, 120000000, i.In.CountedRate, 0.1)
+	assert.InDelta(t, 108000000, i.In.EstimatedRate, 0.1)
+	assert.InDelta(t, -0.1, i.In.ByteError, 0.0001)
+	assert.InDelta(t, -0.1, i.In.PacketError, 0.0001)
+	assert.InDelta(t, 0.2066, i.In.ExpectedError, 0.0001)
+
+	assert.Equal(t, uint64(50000), i.Out.CountedBytes)
+	assert.Equal(t, uint64(40000), i.Out.EstimatedBytes)
+	assert.InDelta(t, -0.2, i.Out.ByteError, 0.0001)
+	assert.InDelta(t, -0.2, i.Out.PacketError, 0.0001)
+
+	// counters going backwards were reset, the samples in between are dropped
+	r.AddSample(agent, 7, flow.DirectionIngress, 1000, 1500)
+	r.AddCounters(agent, &Counters{IfIndex: 7, InOctets: 100}, start.Add(20*time.Second))
+	assert.Equal(t, 1, r.Interfaces(nil)[0].Intervals)
+
+	// only the latest intervals are reported
+	for n := 3; n <= 5; n++ {
+		r.AddCounters(agent, &Counters{IfIndex: 7, InOctets: uint64(n) * 100}, start.Add(time.Duration(n)*10*time.Second))
+	}
+	i = r.Interfaces(nil)[0]
+	assert.Equal(t, 2, i.Intervals)
+	assert.Equal(t, 20.0, i.Seconds)
+	assert.Equal(t, uint64(200), i.In.CountedBytes)
+	assert.Equal(t, 0.0, i.In.ExpectedError)
+	assert.Equal(t, -1.0, i.In.ByteError)
+	assert.Equal(t, 0.0, i.Out.ByteError, "No counted traffic")
+}
+
+func TestInterfacesOrder(t *testing.T) {
+	r := New(0)
+	start := time.Unix(1700000000, 0)
+	for _, a := range []string{"192.0.2.2", "2001:db8::1", "192.0.2.1"} {
+		for _, ifIndex := range []uint32{8, 7} {
+			r.AddCounters(netip.MustParseAddr(a), &Counters{IfIndex: ifIndex}, start)
+			r.AddCounters(netip.MustParseAddr(a), &Counters{IfIndex: ifIndex}, start.Add(time.Second))
+		}
+	}
+
+	var got []string
+	for _, i := range r.Interfaces(nil) {
+		got = append(got, fmt.Sprintf("%s/%d", i.Agent, i.IfIndex))
+	}
+	assert.Equal(t, []string{"192.0.2.1/7", "192.0.2.1/8", "192.0.2.2/7", "192.0.2.2/8", "2001:db8::1/7", "2001:db8::1/8"}, got)
+
+	agent := netip.MustParseAddr("192.0.2.2")
+	assert.Len(t, r.Interfaces(&agent), 2)
+}
+
+func TestHandler(t *testing.T) {
+	r := New(0)
+	agent := netip.MustParseAddr("192.0.2.1")
+	r.AddCounters(agent, &Counters{IfIndex: 7, Name: "et7"}, time.Unix(1700000000, 0))
+	r.AddCounters(agent, &Counters{IfIndex: 7, Name: "et7", InOctets: 100}, time.Unix(1700000010, 0))
+
+	tests := []struct {
+		name     string
+		url      string
+		expected int
+		contains string
+	}{
+		{
+			name:     "All agents",
+			url:      "/agents/sampling",
+			expected: http.StatusOK,
+			contains: `"name":"et7"`,
+		},
+		{
+			name:     "Other agent",
+			url:      "/agents/sampling?agent=192.0.2.2",
+			expected: http.StatusOK,
+			contains: "[]",
+		},
+		{
+			name:     "Invalid agent",
+			url:      "/agents/sampling?agent=foo",
+			expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		r.Handler(rec, httptest.NewRequest(http.MethodGet, test.url, nil))
+		assert.Equal(t, test.expected, rec.Code, test.name)
+		assert.True(t, strings.Contains(rec.Body.String(), test.contains), test.name)
+	}
+}
@@ -0,0 +1,390 @@
+// Package scandetect detects scanners and SYN floods by rolling up the ingested flows per source address
+package scandetect
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/packet"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// TypeHostScan is a source contacting many distinct destinations (horizontal scan)
+	TypeHostScan = "host_scan"
+
+	// TypePortScan is a source contacting many distinct destination ports (vertical scan)
+	TypePortScan = "port_scan"
+
+	// TypeSYNFlood is a source sending mostly TCP packets with SYN but without ACK set
+	TypeSYNFlood = "syn_flood"
+
+	// SeverityLow, SeverityMedium and SeverityHigh rate an events score
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+
+	defaultWindow          = 60
+	defaultExpireWindows   = 5
+	defaultMaxEvents       = 1000
+	defaultMinDestinations = 100
+	defaultMinPorts        = 100
+	defaultMinSYNPackets   = 10000
+	defaultSYNRatio        = 0.9
+
+	// mediumScore and highScore are the scores (multiples of the threshold) from which on an event is rated medium or high
+	mediumScore = 2
+	highScore   = 10
+
+	// maxTracked limits the memory used for counting distinct destinations and ports per source and window
+	maxTracked = 65536
+
+	tcpFlagSYN = 0x02
+	tcpFlagACK = 0x10
+)
+
+var severities = map[string]int{
+	SeverityLow:    0,
+	SeverityMedium: 1,
+	SeverityHigh:   2,
+}
+
+// Config is the scan detectors configuration. Distinct destinations and ports are counted on sampled flows and are
+// therefore lower than the real numbers with high sample rates.
+type Config struct {
+	// Window is the interval in seconds distinct destinations and ports are counted in
+	Window uint64 `yaml:"window"`
+
+	// ExpireWindows is the number of windows without exceeded threshold after which an event ends
+	ExpireWindows uint64 `yaml:"expire_windows"`
+
+	// MaxEvents is the number of events kept for the events API
+	MaxEvents int `yaml:"max_events"`
+
+	// MinDestinations is the number of distinct destination addresses per window considered a host scan
+	MinDestinations int `yaml:"min_destinations"`
+
+	// MinPorts is the number of distinct destination ports per window considered a port scan
+	MinPorts int `yaml:"min_ports"`
+
+	// MinSYNPackets is the number of SYN without ACK packets (multiplied by the sample rate) per window from which on
+	// the SYN ratio is checked
+	MinSYNPackets uint64 `yaml:"min_syn_packets"`
+
+	// SYNRatio is the share of SYN without ACK packets of all TCP packets considered a SYN flood
+	SYNRatio float64 `yaml:"syn_ratio"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Window == 0 {
+		c.Window = defaultWindow
+	}
+
+	if c.ExpireWindows == 0 {
+		c.ExpireWindows = defaultExpireWindows
+	}
+
+	if c.MaxEvents == 0 {
+		c.MaxEvents = defaultMaxEvents
+	}
+
+	if c.MinDestinations == 0 {
+		c.MinDestinations = defaultMinDestinations
+	}
+
+	if c.MinPorts == 0 {
+		c.MinPorts = defaultMinPorts
+	}
+
+	if c.MinSYNPackets == 0 {
+		c.MinSYNPackets = defaultMinSYNPackets
+	}
+
+	if c.SYNRatio == 0 {
+		c.SYNRatio = defaultSYNRatio
+	}
+}
+
+// Event is a detected scanner or SYN flood source
+type Event struct {
+	ID       uint64     `json:"id"`
+	Source   string     `json:"source"`
+	Type     string     `json:"type"`
+	Start    time.Time  `json:"start"`
+	LastSeen time.Time  `json:"last_seen"`
+	End      *time.Time `json:"end,omitempty"`
+
+	// Score is the peak of the observed value relative to the threshold
+	Score    float64 `json:"score"`
+	Severity string  `json:"severity"`
+
+	PeakDestinations int    `json:"peak_destinations"`
+	PeakPorts        int    `json:"peak_ports"`
+	PeakSYNPackets   uint64 `json:"peak_syn_packets"`
+}
+
+// Active checks if an event is still ongoing
+func (e *Event) Active() bool {
+	return e.End == nil
+}
+
+type counters struct {
+	destinations map[netip.Addr]struct{}
+	ports        map[uint16]struct{}
+	tcpPackets   uint64
+	synPackets   uint64
+}
+
+func newCounters() *counters {
+	return &counters{
+		destinations: make(map[netip.Addr]struct{}),
+		ports:        make(map[uint16]struct{}),
+	}
+}
+
+func (c *counters) add(fl *flow.Flow) {
+	if len(c.destinations) < maxTracked {
+		c.destinations[fl.DstAddr] = struct{}{}
+	}
+
+	if fl.Protocol != packet.TCP && fl.Protocol != packet.UDP {
+		return
+	}
+
+	if len(c.ports) < maxTracked {
+		c.ports[fl.DstPort] = struct{}{}
+	}
+
+	// flows without any TCP flag set come from exporters not reporting flags
+	if fl.Protocol != packet.TCP || fl.TCPFlags == 0 {
+		return
+	}
+
+	c.tcpPackets += fl.Packets * fl.Samplerate
+	if fl.TCPFlags&tcpFlagSYN != 0 && fl.TCPFlags&tcpFlagACK == 0 {
+		c.synPackets += fl.Packets * fl.Samplerate
+	}
+}
+
+type eventKey struct {
+	src       netip.Addr
+	eventType string
+}
+
+// Detector rolls up flows per source and window and raises events when thresholds are exceeded
+type Detector struct {
+	cfg         *Config
+	windowStart int64
+	rollup      map[netip.Addr]*counters
+	active      map[eventKey]*Event
+	events      []*Event
+	lastID      uint64
+	mu          sync.RWMutex
+}
+
+// New creates a new scan detector
+func New(cfg *Config) *Detector {
+	cfg.loadDefaults()
+
+	return &Detector{
+		cfg:    cfg,
+		rollup: make(map[netip.Addr]*counters),
+		active: make(map[eventKey]*Event),
+	}
+}
+
+// Observe adds flows to the current window
+func (d *Detector) Observe(flows []*flow.Flow) {
+	d.observe(flows, time.Now())
+}
+
+func (d *Detector) observe(flows []*flow.Flow, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	windowStart := now.Unix() - now.Unix()%int64(d.cfg.Window)
+	if windowStart > d.windowStart {
+		if d.windowStart != 0 {
+			d.evaluate(time.Unix(d.windowStart+int64(d.cfg.Window), 0))
+		}
+
+		d.windowStart = windowStart
+		d.rollup = make(map[netip.Addr]*counters)
+	}
+
+	for _, fl := range flows {
+		c, exists := d.rollup[fl.SrcAddr]
+		if !exists {
+			c = newCounters()
+			d.rollup[fl.SrcAddr] = c
+		}
+
+		c.add(fl)
+	}
+}
+
+// scores returns the score per exceeded threshold
+func (d *Detector) scores(c *counters) map[string]float64 {
+	res := make(map[string]float64)
+	if len(c.destinations) >= d.cfg.MinDestinations {
+		res[TypeHostScan] = float64(len(c.destinations)) / float64(d.cfg.MinDestinations)
+	}
+
+	if len(c.ports) >= d.cfg.MinPorts {
+		res[TypePortScan] = float64(len(c.ports)) / float64(d.cfg.MinPorts)
+	}
+
+	if c.synPackets >= d.cfg.MinSYNPackets && float64(c.synPackets)/float64(c.tcpPackets) >= d.cfg.SYNRatio {
+		res[TypeSYNFlood] = float64(c.synPackets) / float64(d.cfg.MinSYNPackets)
+	}
+
+	return res
+}
+
+// evaluate checks the finished window against the thresholds. It has to be called with d.mu held.
+func (d *Detector) evaluate(windowEnd time.Time) {
+	for src, c := range d.rollup {
+		for t, score := range d.scores(c) {
+			k := eventKey{
+				src:       src,
+				eventType: t,
+			}
+
+			ev, exists := d.active[k]
+			if !exists {
+				d.lastID++
+				ev = &Event{
+					ID:     d.lastID,
+					Source: src.String(),
+					Type:   t,
+					Start:  windowEnd.Add(-time.Duration(d.cfg.Window) * time.Second),
+				}
+				d.active[k] = ev
+				d.addEvent(ev)
+
+				log.WithFields(log.Fields{
+					"event":        ev.ID,
+					"source":       ev.Source,
+					"type":         ev.Type,
+					"destinations": len(c.destinations),
+					"ports":        len(c.ports),
+					"syn_packets":  c.synPackets,
+				}).Warning("Scan event started")
+			}
+
+			ev.LastSeen = windowEnd
+			if score > ev.Score {
+				ev.Score = score
+				ev.Severity = severity(score)
+			}
+
+			if len(c.destinations) > ev.PeakDestinations {
+				ev.PeakDestinations = len(c.destinations)
+			}
+
+			if len(c.ports) > ev.PeakPorts {
+				ev.PeakPorts = len(c.ports)
+			}
+
+			if c.synPackets > ev.PeakSYNPackets {
+				ev.PeakSYNPackets = c.synPackets
+			}
+		}
+	}
+
+	expiry := time.Duration(d.cfg.ExpireWindows*d.cfg.Window) * time.Second
+	for k, ev := range d.active {
+		if windowEnd.Sub(ev.LastSeen) < expiry {
+			continue
+		}
+
+		end := ev.LastSeen
+		ev.End = &end
+		delete(d.active, k)
+
+		log.WithFields(log.Fields{
+			"event":  ev.ID,
+			"source": ev.Source,
+			"type":   ev.Type,
+		}).Info("Scan event ended")
+	}
+}
+
+func severity(score float64) string {
+	if score >= highScore {
+		return SeverityHigh
+	}
+
+	if score >= mediumScore {
+		return SeverityMedium
+	}
+
+	return SeverityLow
+}
+
+func (d *Detector) addEvent(ev *Event) {
+	d.events = append(d.events, ev)
+	if len(d.events) <= d.cfg.MaxEvents {
+		return
+	}
+
+	// drop the oldest finished event
+	for i, x := range d.events {
+		if !x.Active() {
+			d.events = append(d.events[:i], d.events[i+1:]...)
+			return
+		}
+	}
+}
+
+// Events returns copies of all known events with at least the given severity, most recent first. If activeOnly is set,
+// finished events are omitted.
+func (d *Detector) Events(activeOnly bool, minSeverity string) []*Event {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	res := make([]*Event, 0, len(d.events))
+	for _, ev := range d.events {
+		if activeOnly && !ev.Active() {
+			continue
+		}
+
+		if severities[ev.Severity] < severities[minSeverity] {
+			continue
+		}
+
+		c := *ev
+		res = append(res, &c)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID > res[j].ID
+	})
+
+	return res
+}
+
+// Handler serves the events as JSON. With ?active=true only ongoing events are returned, ?severity=medium or
+// ?severity=high omits less severe events.
+func (d *Detector) Handler(w http.ResponseWriter, r *http.Request) {
+	minSeverity := r.URL.Query().Get("severity")
+	if _, ok := severities[minSeverity]; minSeverity != "" && !ok {
+		http.Error(w, "Invalid severity", http.StatusBadRequest)
+		return
+	}
+
+	j, err := json.Marshal(d.Events(r.URL.Query().Get("active") == "true", minSeverity))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
@@ -0,0 +1,119 @@
+package scandetect
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScores(t *testing.T) {
+	d := New(&Config{
+		MinDestinations: 10,
+		MinPorts:        10,
+		MinSYNPackets:   100,
+	})
+
+	src := netip.AddrFrom4([4]byte{198, 51, 100, 1})
+	tests := []struct {
+		name     string
+		flows    func() []*flow.Flow
+		expected map[string]float64
+	}{
+		{
+			name: "Host scan",
+			flows: func() []*flow.Flow {
+				res := make([]*flow.Flow, 0)
+				for i := 0; i < 20; i++ {
+					res = append(res, &flow.Flow{SrcAddr: src, DstAddr: netip.AddrFrom4([4]byte{192, 0, 2, uint8(i)}), Protocol: packet.TCP, DstPort: 22, TCPFlags: tcpFlagSYN | tcpFlagACK, Packets: 1, Samplerate: 1})
+				}
+				return res
+			},
+			expected: map[string]float64{TypeHostScan: 2},
+		},
+		{
+			name: "Port scan with SYNs",
+			flows: func() []*flow.Flow {
+				res := make([]*flow.Flow, 0)
+				for i := 0; i < 100; i++ {
+					res = append(res, &flow.Flow{SrcAddr: src, DstAddr: netip.AddrFrom4([4]byte{192, 0, 2, 1}), Protocol: packet.TCP, DstPort: uint16(i), TCPFlags: tcpFlagSYN, Packets: 1, Samplerate: 10})
+				}
+				return res
+			},
+			expected: map[string]float64{TypePortScan: 10, TypeSYNFlood: 10},
+		},
+		{
+			name: "No TCP flags",
+			flows: func() []*flow.Flow {
+				return []*flow.Flow{
+					{SrcAddr: src, DstAddr: netip.AddrFrom4([4]byte{192, 0, 2, 1}), Protocol: packet.TCP, DstPort: 443, Packets: 1000, Samplerate: 1000},
+				}
+			},
+			expected: map[string]float64{},
+		},
+	}
+
+	for _, test := range tests {
+		c := newCounters()
+		for _, fl := range test.flows() {
+			c.add(fl)
+		}
+
+		assert.Equal(t, test.expected, d.scores(c), test.name)
+	}
+}
+
+func TestEventLifecycle(t *testing.T) {
+	d := New(&Config{
+		Window:          10,
+		ExpireWindows:   2,
+		MinDestinations: 2,
+	})
+
+	src := netip.AddrFrom4([4]byte{198, 51, 100, 1})
+	scan := func(destinations int) []*flow.Flow {
+		res := make([]*flow.Flow, 0, destinations)
+		for i := 0; i < destinations; i++ {
+			res = append(res, &flow.Flow{
+				SrcAddr:    src,
+				DstAddr:    netip.AddrFrom4([4]byte{192, 0, 2, uint8(i)}),
+				Protocol:   packet.UDP,
+				DstPort:    161,
+				Packets:    1,
+				Samplerate: 1,
+			})
+		}
+		return res
+	}
+
+	start := time.Unix(1000, 0)
+	d.observe(scan(4), start)
+	assert.Len(t, d.Events(false, ""), 0)
+
+	// the first window is evaluated when the next one starts
+	d.observe(scan(20), start.Add(10*time.Second))
+	events := d.Events(true, "")
+	assert.Len(t, events, 1)
+	assert.Equal(t, "198.51.100.1", events[0].Source)
+	assert.Equal(t, TypeHostScan, events[0].Type)
+	assert.Equal(t, SeverityMedium, events[0].Severity)
+	assert.Equal(t, float64(2), events[0].Score)
+	assert.Equal(t, start, events[0].Start)
+
+	// the score of the second window raises the severity
+	d.observe(nil, start.Add(20*time.Second))
+	events = d.Events(true, SeverityHigh)
+	assert.Len(t, events, 1)
+	assert.Equal(t, 20, events[0].PeakDestinations)
+
+	d.observe(nil, start.Add(30*time.Second))
+	d.observe(nil, start.Add(40*time.Second))
+	assert.Len(t, d.Events(true, ""), 0)
+
+	events = d.Events(false, "")
+	assert.Len(t, events, 1)
+	assert.Equal(t, start.Add(20*time.Second), *events[0].End)
+}
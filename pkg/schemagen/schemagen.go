@@ -0,0 +1,279 @@
+// Package schemagen generates the code depending on the fields of flows from the flow schema
+// (pkg/models/flow/schema.yaml): the DDL and insert columns of the flows table, the fields of the flow sink, the
+// field catalog of the frontend and the IPFIX information elements decoded into the fields. Adding a field to the schema
+// and running go generate keeps them in sync. Decoding the elements into the fields is not generated.
+package schemagen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"regexp"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// TypeAddr is an IP address, stored as IPv6
+	TypeAddr = "addr"
+
+	// TypePrefix is an IP prefix, stored as <name>_addr (IPv6) and <name>_len (UInt8)
+	TypePrefix = "prefix"
+
+	// TypeMAC is a MAC address as number, stored as <name>_addr (UInt64)
+	TypeMAC = "mac"
+
+	// TypeString is a string
+	TypeString = "string"
+
+	// TypeLowCardinality is a string with few distinct values
+	TypeLowCardinality = "low_cardinality"
+
+	// TypeBool is a boolean, stored as UInt8
+	TypeBool = "bool"
+
+	// TypeDateTime is unix time in seconds
+	TypeDateTime = "datetime"
+
+	// TypeDateTime64 is unix time in milliseconds
+	TypeDateTime64 = "datetime64"
+)
+
+var (
+	nameRegexp    = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	fieldRegexp   = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*(\(\))?$`)
+	elementRegexp = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+)
+
+// column is a column a type is stored in
+type column struct {
+	// suffix is appended to the name of the field
+	suffix string
+	ddl    string
+
+	// constructor creates the flowColumn taking values of goType, wrap converts the field to them
+	constructor string
+	goType      string
+	wrap        string
+}
+
+// fieldType is how values of a type are stored and written to the flow sink
+type fieldType struct {
+	columns []column
+
+	// sink converts the field for the flow sink if set
+	sink string
+}
+
+var fieldTypes = map[string]fieldType{
+	TypeAddr: {
+		columns: []column{{ddl: "IPv6", constructor: "addrColumn", goType: "netip.Addr"}},
+		sink:    "ipString",
+	},
+	TypePrefix: {
+		columns: []column{
+			{suffix: "_addr", ddl: "IPv6", constructor: "addrColumn", goType: "netip.Addr", wrap: "pfxAddr"},
+			{suffix: "_len", ddl: "UInt8", constructor: "uint8Column", goType: "uint8", wrap: "pfxlen"},
+		},
+		sink: "pfxString",
+	},
+	TypeMAC: {
+		columns: []column{{suffix: "_addr", ddl: "UInt64", constructor: "uint64Column", goType: "uint64"}},
+		sink:    "macString",
+	},
+	TypeString: {
+		columns: []column{{ddl: "String", constructor: "stringColumn", goType: "string"}},
+	},
+	TypeLowCardinality: {
+		columns: []column{{ddl: "LowCardinality(String)", constructor: "stringColumn", goType: "string"}},
+	},
+	"uint8": {
+		columns: []column{{ddl: "UInt8", constructor: "uint8Column", goType: "uint8"}},
+	},
+	"uint16": {
+		columns: []column{{ddl: "UInt16", constructor: "uint16Column", goType: "uint16"}},
+	},
+	"uint32": {
+		columns: []column{{ddl: "UInt32", constructor: "uint32Column", goType: "uint32"}},
+	},
+	"uint64": {
+		columns: []column{{ddl: "UInt64", constructor: "uint64Column", goType: "uint64"}},
+	},
+	TypeBool: {
+		columns: []column{{ddl: "UInt8", constructor: "boolColumn", goType: "bool"}},
+	},
+	TypeDateTime: {
+		columns: []column{{ddl: "DateTime", constructor: "dateTimeColumn", goType: "int64"}},
+	},
+	TypeDateTime64: {
+		columns: []column{{ddl: "DateTime64(3)", constructor: "dateTime64Column", goType: "int64"}},
+	},
+}
+
+// Field is a field of flows
+type Field struct {
+	// Name is the name of the field in the frontend and the flow sink and the name of its column(s)
+	Name string `yaml:"name"`
+
+	// Field is the field or method of flow.Flow taking the value, e.g. SrcAddr. Fields the frontend computes, e.g.
+	// subnets, have none.
+	Field string `yaml:"field"`
+
+	// Type is the type of the value (addr, prefix, mac, string, low_cardinality, uint8, uint16, uint32, uint64, bool,
+	// datetime or datetime64)
+	Type string `yaml:"type"`
+
+	// Column is false for fields not stored in the flows table (default true for fields with a Field)
+	Column *bool `yaml:"column"`
+
+	// Enrichment columns are appended to the flows table and added to existing tables on startup
+	Enrichment bool `yaml:"enrichment"`
+
+	// Label and ShortLabel name the field in the frontend, fields without label are not listed by it
+	Label      string `yaml:"label"`
+	ShortLabel string `yaml:"short_label"`
+
+	// IPFIXElements are the information elements of pkg/packet/ipfix the IPFIX server decodes into the field, e.g.
+	// IPv4SrcAddr. The decoding itself is written by hand in pkg/servers/ipfix.
+	IPFIXElements []string `yaml:"ipfix_elements"`
+}
+
+// HasColumn tells if the field is stored in the flows table
+func (f *Field) HasColumn() bool {
+	if f.Field == "" {
+		return false
+	}
+
+	return f.Column == nil || *f.Column
+}
+
+// Schema are the fields of flows
+type Schema []*Field
+
+// Load loads a schema from a YAML file
+func Load(path string) (Schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read schema")
+	}
+
+	var s Schema
+	err = yaml.UnmarshalStrict(b, &s)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to unmarshal schema")
+	}
+
+	err = s.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid schema")
+	}
+
+	return s, nil
+}
+
+// Validate validates the schema
+func (s Schema) Validate() error {
+	names := make(map[string]struct{}, len(s))
+	columns := make(map[string]struct{}, len(s))
+	for _, f := range s {
+		if !nameRegexp.MatchString(f.Name) {
+			return fmt.Errorf("Invalid name %q", f.Name)
+		}
+
+		if _, exists := names[f.Name]; exists {
+			return fmt.Errorf("Field %s is defined twice", f.Name)
+		}
+		names[f.Name] = struct{}{}
+
+		if f.Field == "" {
+			if f.Type != "" || f.Column != nil || f.Enrichment || len(f.IPFIXElements) > 0 {
+				return fmt.Errorf("Field %s has no field of flows but a type or column", f.Name)
+			}
+
+			if f.Label == "" {
+				return fmt.Errorf("Field %s has neither a field of flows nor a label", f.Name)
+			}
+
+			continue
+		}
+
+		if !fieldRegexp.MatchString(f.Field) {
+			return fmt.Errorf("Invalid field %q of %s", f.Field, f.Name)
+		}
+
+		for _, e := range f.IPFIXElements {
+			if !elementRegexp.MatchString(e) {
+				return fmt.Errorf("Invalid IPFIX element %q of %s", e, f.Name)
+			}
+		}
+
+		t, exists := fieldTypes[f.Type]
+		if !exists {
+			return fmt.Errorf("Unknown type %q of %s", f.Type, f.Name)
+		}
+
+		if f.Enrichment && (f.Type != TypeLowCardinality || !f.HasColumn()) {
+			return fmt.Errorf("Enrichment field %s has to be a low_cardinality column", f.Name)
+		}
+
+		if !f.HasColumn() {
+			continue
+		}
+
+		for _, c := range t.columns {
+			if _, exists := columns[f.Name+c.suffix]; exists {
+				return fmt.Errorf("Column %s of %s is defined twice", f.Name+c.suffix, f.Name)
+			}
+			columns[f.Name+c.suffix] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+// File is a generated file
+type File struct {
+	// Path is relative to the root of the repository
+	Path    string
+	Content []byte
+}
+
+type target struct {
+	path     string
+	template *template.Template
+}
+
+var targets = []target{
+	{path: "pkg/clickhousegw/schema_gen.go", template: clickhousegwTemplate},
+	{path: "pkg/flowsink/fields_gen.go", template: flowsinkTemplate},
+	{path: "pkg/frontend/fields_gen.go", template: frontendTemplate},
+	{path: "pkg/servers/ipfix/elements_gen.go", template: ipfixTemplate},
+}
+
+// Generate generates the files depending on the schema
+func (s Schema) Generate() ([]*File, error) {
+	data := s.templateData()
+	res := make([]*File, 0, len(targets))
+	for _, t := range targets {
+		buf := &bytes.Buffer{}
+		err := t.template.Execute(buf, data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to generate %s", t.path)
+		}
+
+		b, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to format %s", t.path)
+		}
+
+		res = append(res, &File{
+			Path:    t.path,
+			Content: b,
+		})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,163 @@
+package schemagen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGeneratedFiles fails if the generated files differ from the schema, run go generate ./pkg/models/flow to fix it
+func TestGeneratedFiles(t *testing.T) {
+	s, err := Load("../models/flow/schema.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	files, err := s.Generate()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, f := range files {
+		b, err := os.ReadFile(filepath.Join("../..", f.Path))
+		if !assert.NoError(t, err, f.Path) {
+			continue
+		}
+
+		assert.Equal(t, string(f.Content), string(b), "%s is out of date, run go generate ./pkg/models/flow", f.Path)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	noColumn := false
+
+	tests := []struct {
+		name    string
+		schema  Schema
+		wantErr bool
+	}{
+		{
+			name: "Valid",
+			schema: Schema{
+				{Name: "src_ip_pfx", Field: "SrcPfx", Type: TypePrefix, Label: "Source IP Prefix"},
+				{Name: "duration_ms", Field: "DurationMilliseconds()", Type: "uint64"},
+				{Name: "vrf_in", Field: "VRFIn", Type: "uint64", Column: &noColumn},
+				{Name: "agent_name", Field: "AgentName", Type: TypeLowCardinality, Enrichment: true},
+				{Name: "src_subnet", Label: "Source Subnet"},
+				{Name: "src_port", Field: "SrcPort", Type: "uint16", IPFIXElements: []string{"L4SrcPort"}},
+			},
+		},
+		{
+			name:    "Invalid name",
+			schema:  Schema{{Name: "Src IP", Field: "SrcAddr", Type: TypeAddr}},
+			wantErr: true,
+		},
+		{
+			name: "Duplicate name",
+			schema: Schema{
+				{Name: "src_ip_addr", Field: "SrcAddr", Type: TypeAddr},
+				{Name: "src_ip_addr", Field: "DstAddr", Type: TypeAddr},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Duplicate column",
+			schema: Schema{
+				{Name: "src_mac", Field: "SrcMAC", Type: TypeMAC},
+				{Name: "src_mac_addr", Field: "SrcMAC", Type: "uint64"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown type",
+			schema:  Schema{{Name: "src_ip_addr", Field: "SrcAddr", Type: "ip"}},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid field",
+			schema:  Schema{{Name: "src_ip_addr", Field: "SrcAddr.String()", Type: TypeAddr}},
+			wantErr: true,
+		},
+		{
+			name:    "Enrichment of other type",
+			schema:  Schema{{Name: "tunnel_id", Field: "TunnelID", Type: "uint32", Enrichment: true}},
+			wantErr: true,
+		},
+		{
+			name:    "Type without field",
+			schema:  Schema{{Name: "src_subnet", Type: TypeString, Label: "Source Subnet"}},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid IPFIX element",
+			schema:  Schema{{Name: "src_port", Field: "SrcPort", Type: "uint16", IPFIXElements: []string{"7"}}},
+			wantErr: true,
+		},
+		{
+			name:    "IPFIX element without field",
+			schema:  Schema{{Name: "src_subnet", Label: "Source Subnet", IPFIXElements: []string{"IPv4SrcAddr"}}},
+			wantErr: true,
+		},
+		{
+			name:    "Neither field nor label",
+			schema:  Schema{{Name: "src_subnet"}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.schema.Validate()
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	noColumn := false
+	s := Schema{
+		{Name: "agent", Field: "Agent", Type: TypeAddr, Label: "Agent", ShortLabel: "A."},
+		{Name: "agent_name", Field: "AgentName", Type: TypeLowCardinality, Enrichment: true, Label: "Agent Name"},
+		{Name: "src_ip_pfx", Field: "SrcPfx", Type: TypePrefix},
+		{Name: "family", Field: "Family", Type: "uint8", Column: &noColumn, IPFIXElements: []string{"IPv4SrcAddr", "IPv6SrcAddr"}},
+		{Name: "vrf_in", Field: "VRFIn", Type: "uint64", Column: &noColumn},
+		{Name: "src_subnet", Label: "Source Subnet", ShortLabel: "Src.Net"},
+	}
+
+	files, err := s.Generate()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	content := make(map[string]string, len(files))
+	for _, f := range files {
+		content[f.Path] = string(f.Content)
+	}
+
+	ch := content["pkg/clickhousegw/schema_gen.go"]
+	assert.Contains(t, ch, "`\t\t\tagent           IPv6,\n\t\t\tsrc_ip_pfx_addr IPv6,\n\t\t\tsrc_ip_pfx_len  UInt8`")
+	assert.Contains(t, ch, "var enrichmentColumns = []string{\n\t\"agent_name\",\n}")
+	assert.Contains(t, ch, `uint8Column("src_ip_pfx_len", func(fl *flow.Flow) uint8 { return pfxlen(fl.SrcPfx) }),`)
+	assert.True(t, strings.Index(ch, `"src_ip_pfx_len"`) < strings.Index(ch, `stringColumn("agent_name"`), "enrichment columns go last")
+	assert.NotContains(t, ch, "vrf_in")
+
+	sink := content["pkg/flowsink/fields_gen.go"]
+	assert.Contains(t, sink, `"src_ip_pfx": func(fl *flow.Flow) interface{} { return pfxString(fl.SrcPfx) },`)
+	assert.Contains(t, sink, `"vrf_in":     func(fl *flow.Flow) interface{} { return fl.VRFIn },`)
+	assert.NotContains(t, sink, "src_subnet")
+
+	frontend := content["pkg/frontend/fields_gen.go"]
+	assert.Contains(t, frontend, "Name:       \"agent_name\",\n\t\tLabel:      \"Agent Name\",\n\t\tShortLabel: \"Agent Name\",")
+	assert.Contains(t, frontend, `"src_subnet"`)
+	assert.NotContains(t, frontend, "vrf_in")
+
+	elements := content["pkg/servers/ipfix/elements_gen.go"]
+	assert.Contains(t, elements, `"family": {ipfix.IPv4SrcAddr, ipfix.IPv6SrcAddr},`)
+	assert.NotContains(t, elements, "src_ip_pfx")
+}
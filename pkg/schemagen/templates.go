@@ -0,0 +1,199 @@
+package schemagen
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const header = `// Code generated by flowhouse-schemagen from pkg/models/flow/schema.yaml. DO NOT EDIT.
+`
+
+var clickhousegwTemplate = template.Must(template.New("clickhousegw").Parse(header + `
+package clickhousegw
+
+import (
+	"net/netip"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+)
+
+// flowColumnsDDL are the definitions of the columns of the flows table preceding the enrichment columns
+const flowColumnsDDL = ` + "`{{.DDL}}`" + `
+
+// flowColumnTypes are the columns of flowColumnsDDL and their types. Columns missing in existing flows tables are
+// added on startup.
+var flowColumnTypes = []columnType{
+{{- range .ColumnTypes}}
+	{ {{- printf "%q" .Name}}, {{printf "%q" .Type -}} },
+{{- end}}
+}
+
+// enrichmentColumns are the string columns of the flows table filled by decoders and enrichment stages. They have few
+// distinct values, so they are LowCardinality which keeps them small and makes grouping by them cheap. New enrichment
+// columns are appended to the flows table and added to existing tables on startup.
+var enrichmentColumns = []string{
+{{- range .Enrichment}}
+	{{printf "%q" .}},
+{{- end}}
+}
+
+// flowColumns are the columns inserts write, in the order of the INSERT statement. Batches are written column by
+// column with typed writes, so no value is boxed or reflected upon.
+var flowColumns = []flowColumn{
+{{- range .Columns}}
+	{{.Constructor}}({{printf "%q" .Name}}, func(fl *flow.Flow) {{.GoType}} { return {{.Expr}} }),
+{{- end}}
+}
+`))
+
+var flowsinkTemplate = template.Must(template.New("flowsink").Parse(header + `
+package flowsink
+
+import (
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+)
+
+// fields are the fields of flows by name. Field names match the frontend's where a field exists there.
+var fields = map[string]field{
+{{- range .Sink}}
+	{{printf "%q" .Name}}: func(fl *flow.Flow) interface{} { return {{.Expr}} },
+{{- end}}
+}
+`))
+
+var frontendTemplate = template.Must(template.New("frontend").Parse(header + `
+package frontend
+
+// fields are the built in fields of the field catalog
+var fields = []fieldDescription{
+{{- range .Catalog}}
+	{
+		Name:       {{printf "%q" .Name}},
+		Label:      {{printf "%q" .Label}},
+		ShortLabel: {{printf "%q" .ShortLabel}},
+	},
+{{- end}}
+}
+`))
+
+var ipfixTemplate = template.Must(template.New("ipfix").Parse(header + `
+package ipfix
+
+import (
+	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+)
+
+// schemaElements are the information elements decoded into the fields of flows by name of the field
+var schemaElements = map[string][]uint16{
+{{- range .Elements}}
+	{{printf "%q" .Name}}: { {{- range $i, $e := .Elements}}{{if $i}}, {{end}}ipfix.{{$e}}{{end -}} },
+{{- end}}
+}
+`))
+
+type columnData struct {
+	Name        string
+	Constructor string
+	GoType      string
+	Expr        string
+}
+
+type columnTypeData struct {
+	Name string
+	Type string
+}
+
+type sinkData struct {
+	Name string
+	Expr string
+}
+
+type catalogData struct {
+	Name       string
+	Label      string
+	ShortLabel string
+}
+
+type elementData struct {
+	Name     string
+	Elements []string
+}
+
+type templateData struct {
+	DDL         string
+	ColumnTypes []columnTypeData
+	Enrichment  []string
+	Columns     []columnData
+	Sink        []sinkData
+	Catalog     []catalogData
+	Elements    []elementData
+}
+
+// templateData gets the data of the templates. Enrichment columns follow the other columns, so they are appended to
+// the flows table.
+func (s Schema) templateData() *templateData {
+	res := &templateData{}
+	var ddl []string
+	var enrichment []columnData
+	for _, f := range s {
+		if f.Label != "" {
+			shortLabel := f.ShortLabel
+			if shortLabel == "" {
+				shortLabel = f.Label
+			}
+
+			res.Catalog = append(res.Catalog, catalogData{
+				Name:       f.Name,
+				Label:      f.Label,
+				ShortLabel: shortLabel,
+			})
+		}
+
+		if f.Field == "" {
+			continue
+		}
+
+		if len(f.IPFIXElements) > 0 {
+			res.Elements = append(res.Elements, elementData{Name: f.Name, Elements: f.IPFIXElements})
+		}
+
+		t := fieldTypes[f.Type]
+		expr := "fl." + f.Field
+		if t.sink != "" {
+			res.Sink = append(res.Sink, sinkData{Name: f.Name, Expr: t.sink + "(" + expr + ")"})
+		} else {
+			res.Sink = append(res.Sink, sinkData{Name: f.Name, Expr: expr})
+		}
+
+		if !f.HasColumn() {
+			continue
+		}
+
+		for _, c := range t.columns {
+			cd := columnData{
+				Name:        f.Name + c.suffix,
+				Constructor: c.constructor,
+				GoType:      c.goType,
+				Expr:        expr,
+			}
+			if c.wrap != "" {
+				cd.Expr = c.wrap + "(" + expr + ")"
+			}
+
+			if f.Enrichment {
+				enrichment = append(enrichment, cd)
+				res.Enrichment = append(res.Enrichment, cd.Name)
+				continue
+			}
+
+			res.Columns = append(res.Columns, cd)
+			res.ColumnTypes = append(res.ColumnTypes, columnTypeData{Name: cd.Name, Type: c.ddl})
+			ddl = append(ddl, fmt.Sprintf("\t\t\t%-15s %s", cd.Name, c.ddl))
+		}
+	}
+
+	res.DDL = strings.Join(ddl, ",\n")
+	res.Columns = append(res.Columns, enrichment...)
+	return res
+}
@@ -0,0 +1,71 @@
+// Package sdnotify implements the systemd notification protocol (sd_notify) for Type=notify services and the watchdog
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Ready tells systemd that startup is finished
+	Ready = "READY=1"
+
+	// Watchdog resets the watchdog timer
+	Watchdog = "WATCHDOG=1"
+
+	// Reloading tells systemd that the configuration is being reloaded. It has to be followed by Ready.
+	Reloading = "RELOADING=1"
+
+	// Stopping tells systemd that the service is shutting down
+	Stopping = "STOPPING=1"
+)
+
+// Enabled returns whether the process was started with a notification socket
+func Enabled() bool {
+	return os.Getenv("NOTIFY_SOCKET") != ""
+}
+
+// Notify sends state to the notification socket. It does nothing if there is no socket.
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// a leading @ denotes a socket in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return errors.Wrap(err, "Unable to connect to notification socket")
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	if err != nil {
+		return errors.Wrap(err, "Write failed")
+	}
+
+	return nil
+}
+
+// WatchdogTimeout returns the watchdog timeout configured for this process (WatchdogSec) or 0 if there is none.
+// Watchdog has to be sent more often than that, systemd recommends every half of the timeout.
+func WatchdogTimeout() time.Duration {
+	usec, err := strconv.ParseUint(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec == 0 {
+		return 0
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond
+}
@@ -0,0 +1,77 @@
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotify(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	assert.True(t, Enabled())
+	assert.NoError(t, Notify(Ready))
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, Ready, string(buf[:n]))
+
+	t.Setenv("NOTIFY_SOCKET", "")
+	assert.False(t, Enabled())
+	assert.NoError(t, Notify(Ready))
+}
+
+func TestWatchdogTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		usec   string
+		pid    string
+		wanted time.Duration
+	}{
+		{
+			name:   "Not set",
+			wanted: 0,
+		},
+		{
+			name:   "Set",
+			usec:   "30000000",
+			wanted: 30 * time.Second,
+		},
+		{
+			name:   "Own PID",
+			usec:   "30000000",
+			pid:    strconv.Itoa(os.Getpid()),
+			wanted: 30 * time.Second,
+		},
+		{
+			name:   "Other PID",
+			usec:   "30000000",
+			pid:    "1",
+			wanted: 0,
+		},
+		{
+			name:   "Invalid",
+			usec:   "foo",
+			wanted: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Setenv("WATCHDOG_USEC", test.usec)
+		t.Setenv("WATCHDOG_PID", test.pid)
+		assert.Equal(t, test.wanted, WatchdogTimeout(), test.name)
+	}
+}
@@ -0,0 +1,230 @@
+// Package secrets resolves references to secrets kept outside of the config file
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SchemeFile references the content of a file (without trailing newlines), e.g. ${file:/run/secrets/password}
+	SchemeFile = "file"
+
+	// SchemeEnv references an environment variable, e.g. ${env:CLICKHOUSE_PASSWORD}
+	SchemeEnv = "env"
+
+	// SchemeVault references a key of a HashiCorp Vault secret (KV version 1 or 2), e.g.
+	// ${vault:secret/data/flowhouse#password}
+	SchemeVault = "vault"
+
+	defaultVaultTimeout = 10
+)
+
+// Config configures how secrets are resolved
+type Config struct {
+	Vault *VaultConfig `yaml:"vault"`
+
+	// RefreshInterval is the interval in seconds in which referenced secrets are resolved again to pick up rotated
+	// secrets. 0 disables refreshing.
+	RefreshInterval uint64 `yaml:"refresh_interval"`
+}
+
+// VaultConfig is the configuration of the Vault client. Address and token default to the VAULT_ADDR and VAULT_TOKEN
+// environment variables. The token may be a file or env reference itself.
+type VaultConfig struct {
+	Address string `yaml:"address"`
+	Token   string `yaml:"token"`
+	Timeout uint64 `yaml:"timeout"`
+}
+
+func (c *VaultConfig) loadDefaults() {
+	if c.Address == "" {
+		c.Address = os.Getenv("VAULT_ADDR")
+	}
+
+	if c.Token == "" {
+		c.Token = os.Getenv("VAULT_TOKEN")
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = defaultVaultTimeout
+	}
+}
+
+// IsReference checks if s is a secret reference
+func IsReference(s string) bool {
+	return strings.HasPrefix(s, "${") && strings.HasSuffix(s, "}") && strings.Contains(s, ":")
+}
+
+// Resolver resolves secret references and remembers them to detect rotated secrets
+type Resolver struct {
+	cfg      *Config
+	client   *http.Client
+	resolved map[string]string
+	mu       sync.Mutex
+}
+
+// New creates a new resolver. cfg may be nil if Vault is not used.
+func New(cfg *Config) *Resolver {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	r := &Resolver{
+		cfg:      cfg,
+		resolved: make(map[string]string),
+	}
+
+	if cfg.Vault != nil {
+		cfg.Vault.loadDefaults()
+		r.client = &http.Client{
+			Timeout: time.Duration(cfg.Vault.Timeout) * time.Second,
+		}
+	}
+
+	return r
+}
+
+// Resolve returns the secret referenced by s or s itself if it is not a reference
+func (r *Resolver) Resolve(s string) (string, error) {
+	if !IsReference(s) {
+		return s, nil
+	}
+
+	v, err := r.lookup(s)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resolved[s] = v
+	return v, nil
+}
+
+// Changed resolves all previously resolved references again and checks if any of them changed
+func (r *Resolver) Changed() (bool, error) {
+	r.mu.Lock()
+	resolved := make(map[string]string, len(r.resolved))
+	for ref, v := range r.resolved {
+		resolved[ref] = v
+	}
+	r.mu.Unlock()
+
+	for ref, old := range resolved {
+		v, err := r.lookup(ref)
+		if err != nil {
+			return false, err
+		}
+
+		if v != old {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *Resolver) lookup(ref string) (string, error) {
+	parts := strings.SplitN(strings.TrimSuffix(strings.TrimPrefix(ref, "${"), "}"), ":", 2)
+	scheme, name := parts[0], parts[1]
+
+	switch scheme {
+	case SchemeFile:
+		c, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", errors.Wrapf(err, "Unable to read secret file %q", name)
+		}
+
+		return strings.TrimRight(string(c), "\r\n"), nil
+	case SchemeEnv:
+		v, exists := os.LookupEnv(name)
+		if !exists {
+			return "", fmt.Errorf("Environment variable %q is not set", name)
+		}
+
+		return v, nil
+	case SchemeVault:
+		return r.lookupVault(name)
+	}
+
+	return "", fmt.Errorf("Unknown secret scheme %q in %q", scheme, ref)
+}
+
+// lookupVault reads a key of a Vault secret given as path#key
+func (r *Resolver) lookupVault(name string) (string, error) {
+	if r.cfg.Vault == nil || r.cfg.Vault.Address == "" {
+		return "", fmt.Errorf("Vault is not configured")
+	}
+
+	parts := strings.SplitN(name, "#", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("Invalid Vault reference %q, expected path#key", name)
+	}
+
+	token := r.cfg.Vault.Token
+	if IsReference(token) {
+		if strings.HasPrefix(token, "${"+SchemeVault+":") {
+			return "", fmt.Errorf("Vault token can not be a Vault reference")
+		}
+
+		t, err := r.lookup(token)
+		if err != nil {
+			return "", errors.Wrap(err, "Unable to resolve Vault token")
+		}
+		token = t
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(r.cfg.Vault.Address, "/")+"/v1/"+strings.TrimPrefix(parts[0], "/"), nil)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to create Vault request")
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to read %q from Vault", parts[0])
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Unable to read %q from Vault: %s", parts[0], resp.Status)
+	}
+
+	secret := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&secret)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to decode Vault secret %q", parts[0])
+	}
+
+	data := secret.Data
+	// KV version 2 nests the secret in data.data next to data.metadata
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+
+	v, exists := data[parts[1]]
+	if !exists {
+		return "", fmt.Errorf("Key %q not found in Vault secret %q", parts[1], parts[0])
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("Key %q of Vault secret %q is not a string", parts[1], parts[0])
+	}
+
+	return s, nil
+}
@@ -0,0 +1,139 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	passwordFile := filepath.Join(dir, "password")
+	assert.NoError(t, ioutil.WriteFile(passwordFile, []byte("from-file\n"), 0600))
+	os.Setenv("FLOWHOUSE_TEST_SECRET", "from-env")
+	defer os.Unsetenv("FLOWHOUSE_TEST_SECRET")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "from-file" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/flowhouse":
+			w.Write([]byte(`{"data": {"data": {"password": "from-vault-v2"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/flowhouse":
+			w.Write([]byte(`{"data": {"password": "from-vault-v1", "port": 9000}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	r := New(&Config{
+		Vault: &VaultConfig{
+			Address: srv.URL,
+			Token:   "${file:" + passwordFile + "}",
+		},
+	})
+
+	tests := []struct {
+		name     string
+		ref      string
+		expected string
+		wantFail bool
+	}{
+		{
+			name:     "No reference",
+			ref:      "plain",
+			expected: "plain",
+		},
+		{
+			name:     "File",
+			ref:      "${file:" + passwordFile + "}",
+			expected: "from-file",
+		},
+		{
+			name:     "Missing file",
+			ref:      "${file:" + filepath.Join(dir, "missing") + "}",
+			wantFail: true,
+		},
+		{
+			name:     "Env",
+			ref:      "${env:FLOWHOUSE_TEST_SECRET}",
+			expected: "from-env",
+		},
+		{
+			name:     "Unset env",
+			ref:      "${env:FLOWHOUSE_TEST_UNSET}",
+			wantFail: true,
+		},
+		{
+			name:     "Vault KV v2",
+			ref:      "${vault:secret/data/flowhouse#password}",
+			expected: "from-vault-v2",
+		},
+		{
+			name:     "Vault KV v1",
+			ref:      "${vault:kv/flowhouse#password}",
+			expected: "from-vault-v1",
+		},
+		{
+			name:     "Vault key not a string",
+			ref:      "${vault:kv/flowhouse#port}",
+			wantFail: true,
+		},
+		{
+			name:     "Vault missing key",
+			ref:      "${vault:kv/flowhouse}",
+			wantFail: true,
+		},
+		{
+			name:     "Vault unknown path",
+			ref:      "${vault:kv/other#password}",
+			wantFail: true,
+		},
+		{
+			name:     "Unknown scheme",
+			ref:      "${foo:bar}",
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		v, err := r.Resolve(test.ref)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, v, test.name)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	os.Setenv("FLOWHOUSE_TEST_SECRET", "old")
+	defer os.Unsetenv("FLOWHOUSE_TEST_SECRET")
+
+	r := New(nil)
+	_, err := r.Resolve("${env:FLOWHOUSE_TEST_SECRET}")
+	assert.NoError(t, err)
+
+	changed, err := r.Changed()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	os.Setenv("FLOWHOUSE_TEST_SECRET", "new")
+	changed, err = r.Changed()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+}
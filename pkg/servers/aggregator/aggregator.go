@@ -0,0 +1,157 @@
+// Package aggregator aggregates flows with identical keys over a short time window
+package aggregator
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+)
+
+const (
+	aggregationWindowSeconds = 10
+)
+
+// Aggregator sums up flows with identical keys over aggregationWindowSeconds and passes them on in batches
+type Aggregator struct {
+	data                   map[key]*flow.Flow
+	stopCh                 chan struct{}
+	ingress                chan *flow.Flow
+	output                 func(flows []*flow.Flow)
+	currentUnixTimeSeconds int64
+}
+
+// New creates and starts a new aggregator passing its batches to output, which takes ownership of them
+func New(output func(flows []*flow.Flow)) *Aggregator {
+	a := &Aggregator{
+		data:    make(map[key]*flow.Flow),
+		stopCh:  make(chan struct{}),
+		ingress: make(chan *flow.Flow),
+		output:  output,
+	}
+
+	go a.service()
+	return a
+}
+
+// Ingest passes a flow to the aggregator, which takes ownership of it
+func (a *Aggregator) Ingest(fl *flow.Flow) {
+	a.ingress <- fl
+}
+
+// Stop stops the aggregator
+func (a *Aggregator) Stop() {
+	close(a.stopCh)
+}
+
+type key struct {
+	agent         netip.Addr
+	src           netip.Addr
+	dst           netip.Addr
+	sport         uint16
+	dport         uint16
+	protocol      uint8
+	dscp          uint8
+	icmpType      uint8
+	icmpCode      uint8
+	srcVLAN       uint16
+	dstVLAN       uint16
+	srcMAC        uint64
+	dstMAC        uint64
+	tunnelID      uint32
+	innerSrc      netip.Addr
+	innerDst      netip.Addr
+	innerSport    uint16
+	innerDport    uint16
+	innerProtocol uint8
+	direction     string
+	obsDomainID   uint32
+	obsPointID    uint64
+	exportProto   string
+}
+
+func flowToKey(fl *flow.Flow) key {
+	return key{
+		agent:         fl.Agent,
+		src:           fl.SrcAddr,
+		dst:           fl.DstAddr,
+		sport:         fl.SrcPort,
+		dport:         fl.DstPort,
+		protocol:      fl.Protocol,
+		dscp:          fl.DSCP,
+		icmpType:      fl.ICMPType,
+		icmpCode:      fl.ICMPCode,
+		srcVLAN:       fl.SrcVLAN,
+		dstVLAN:       fl.DstVLAN,
+		srcMAC:        fl.SrcMAC,
+		dstMAC:        fl.DstMAC,
+		tunnelID:      fl.TunnelID,
+		innerSrc:      fl.InnerSrcAddr,
+		innerDst:      fl.InnerDstAddr,
+		innerSport:    fl.InnerSrcPort,
+		innerDport:    fl.InnerDstPort,
+		innerProtocol: fl.InnerProtocol,
+		direction:     fl.Direction,
+		obsDomainID:   fl.ObservationDomainID,
+		obsPointID:    fl.ObservationPointID,
+		exportProto:   fl.ExportProtocol,
+	}
+}
+
+func (a *Aggregator) isStopped() bool {
+	select {
+	case <-a.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func (a *Aggregator) service() {
+	for {
+		if a.isStopped() {
+			return
+		}
+		fl := <-a.ingress
+		a.ingest(fl)
+	}
+}
+
+func (a *Aggregator) ingest(fl *flow.Flow) {
+	currentUnixTimeSeconds := time.Now().Unix()
+	currentUnixTimeSeconds -= currentUnixTimeSeconds % aggregationWindowSeconds
+	if a.currentUnixTimeSeconds < currentUnixTimeSeconds {
+		a.flush()
+		a.currentUnixTimeSeconds = currentUnixTimeSeconds
+	}
+
+	fl.Timestamp = currentUnixTimeSeconds
+	fl.ReceivedAt = currentUnixTimeSeconds
+	a.add(fl)
+}
+
+func (a *Aggregator) add(fl *flow.Flow) {
+	k := flowToKey(fl)
+
+	e, exists := a.data[k]
+	if !exists {
+		a.data[k] = fl
+		return
+	}
+
+	e.Add(fl)
+	flow.Release(fl)
+}
+
+func (a *Aggregator) flush() {
+	s := make([]*flow.Flow, len(a.data))
+
+	i := 0
+	for _, fl := range a.data {
+		s[i] = fl
+		i++
+	}
+
+	a.output(s)
+	clear(a.data)
+}
@@ -0,0 +1,405 @@
+// Package capture builds flows from packets captured on local interfaces
+package capture
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/packet"
+	"github.com/bio-routing/flowhouse/pkg/servers/aggregator"
+	"github.com/bio-routing/tflow2/convert"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultSnapLength = 256
+	defaultAgent      = "127.0.0.1"
+
+	tunnelTypeGRE    = "gre"
+	tunnelTypeERSPAN = "erspan"
+)
+
+// Config is the capture servers configuration
+type Config struct {
+	Interfaces []string `yaml:"interfaces"`
+
+	// SampleRate makes only every n-th packet to be processed
+	SampleRate uint64 `yaml:"sample_rate"`
+
+	// SnapLength is the number of bytes of each packet to be decoded
+	SnapLength int `yaml:"snap_length"`
+
+	// Agent is the address flows are attributed to
+	Agent string `yaml:"agent"`
+
+	// Decapsulate makes flows of GRE and ERSPAN encapsulated packets, e.g. of traffic mirrored to this host, to be
+	// built from the inner headers
+	Decapsulate bool `yaml:"decapsulate"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.SampleRate == 0 {
+		c.SampleRate = 1
+	}
+
+	if c.SnapLength == 0 {
+		c.SnapLength = defaultSnapLength
+	}
+
+	if c.Agent == "" {
+		c.Agent = defaultAgent
+	}
+}
+
+// frameSource is a source of captured ethernet frames
+type frameSource interface {
+	// read reads a frame into buf and returns the original length of the frame and whether it was sent by this host
+	read(buf []byte) (length int, outgoing bool, err error)
+	close() error
+}
+
+// Server captures packets on local interfaces and turns them into flows
+type Server struct {
+	cfg               *Config
+	agent             netip.Addr
+	aggregator        *aggregator.Aggregator
+	sources           map[string]frameSource
+	wg                sync.WaitGroup
+	stopCh            chan struct{}
+	packetsReceived   *prometheus.CounterVec
+	packetsSampled    *prometheus.CounterVec
+	frameDecodeErrors *prometheus.CounterVec
+}
+
+// New creates a new capture server and starts capturing on all configured interfaces
+func New(cfg *Config, output func(flows []*flow.Flow)) (*Server, error) {
+	cfg.loadDefaults()
+
+	agent, err := netip.ParseAddr(cfg.Agent)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid agent address %q", cfg.Agent)
+	}
+
+	s := &Server{
+		cfg:        cfg,
+		agent:      agent,
+		aggregator: aggregator.New(output),
+		sources:    make(map[string]frameSource),
+		stopCh:     make(chan struct{}),
+		packetsReceived: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "capture",
+			Name:      "received_packets",
+			Help:      "Captured packets",
+		}, []string{"interface"}),
+		packetsSampled: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "capture",
+			Name:      "sampled_packets",
+			Help:      "Captured packets selected for processing",
+		}, []string{"interface"}),
+		frameDecodeErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "capture",
+			Name:      "decode_errors",
+			Help:      "Captured packets that could not be decoded",
+		}, []string{"interface"}),
+	}
+
+	for _, ifName := range cfg.Interfaces {
+		src, err := openSource(ifName)
+		if err != nil {
+			s.closeSources()
+			return nil, errors.Wrapf(err, "Unable to capture on %q", ifName)
+		}
+
+		s.sources[ifName] = src
+	}
+
+	for ifName, src := range s.sources {
+		s.wg.Add(1)
+		go s.captureWorker(ifName, src)
+	}
+
+	return s, nil
+}
+
+// Stop stops capturing
+func (s *Server) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+	s.closeSources()
+	s.aggregator.Stop()
+}
+
+func (s *Server) closeSources() {
+	for ifName, src := range s.sources {
+		err := src.close()
+		if err != nil {
+			log.WithError(err).Warningf("Unable to close capture on %q", ifName)
+		}
+	}
+}
+
+func (s *Server) stopped() bool {
+	select {
+	case <-s.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Server) captureWorker(ifName string, src frameSource) {
+	defer s.wg.Done()
+
+	buf := make([]byte, s.cfg.SnapLength)
+	reversed := make([]byte, s.cfg.SnapLength+1)
+	count := uint64(0)
+	for {
+		if s.stopped() {
+			return
+		}
+
+		length, outgoing, err := src.read(buf)
+		if err == errTimeout {
+			continue
+		}
+
+		if err != nil {
+			log.WithError(err).Errorf("Capture on %q failed", ifName)
+			return
+		}
+
+		s.packetsReceived.WithLabelValues(ifName).Inc()
+		count++
+		if count%s.cfg.SampleRate != 0 {
+			continue
+		}
+		s.packetsSampled.WithLabelValues(ifName).Inc()
+
+		fl, err := s.processFrame(ifName, outgoing, buf, reversed, length)
+		if err != nil {
+			s.frameDecodeErrors.WithLabelValues(ifName).Inc()
+			log.WithError(err).Debug("Unable to decode captured packet")
+			continue
+		}
+
+		s.aggregator.Ingest(fl)
+	}
+}
+
+// processFrame decodes a captured frame into a flow. The packet decoders work on byte reversed data,
+// so the captured part of the frame is reversed into rev first. The decoders take a pointer to the end of the data,
+// rev is one byte longer than the captured part so that pointer stays within it.
+func (s *Server) processFrame(ifName string, outgoing bool, frame []byte, rev []byte, length int) (*flow.Flow, error) {
+	captured := min(length, len(frame), len(rev)-1)
+
+	for i := 0; i < captured; i++ {
+		rev[captured-1-i] = frame[i]
+	}
+
+	now := time.Now()
+	fl := flow.New()
+	*fl = flow.Flow{
+		Agent:          s.agent,
+		Size:           uint64(length),
+		Packets:        1,
+		Timestamp:      now.Unix(),
+		Samplerate:     s.cfg.SampleRate,
+		FlowStart:      now.UnixMilli(),
+		FlowEnd:        now.UnixMilli(),
+		Direction:      flow.DirectionIngress,
+		IntIn:          ifName,
+		ExportProtocol: flow.ExportProtocolCapture,
+	}
+
+	if outgoing {
+		fl.Direction = flow.DirectionEgress
+		fl.IntIn = ""
+		fl.IntOut = ifName
+	}
+
+	err := decodeFrame(unsafe.Pointer(&rev[captured]), uint32(captured), fl, s.cfg.Decapsulate)
+	if err != nil {
+		flow.Release(fl)
+		return nil, err
+	}
+
+	return fl, nil
+}
+
+// decodeFrame decodes an ethernet frame into fl. If decapsulate is set, GRE and ERSPAN encapsulated packets are
+// decoded from their inner headers.
+func decodeFrame(ptr unsafe.Pointer, length uint32, fl *flow.Flow, decapsulate bool) error {
+	ether, err := packet.DecodeEthernet(ptr, length)
+	if err != nil {
+		return errors.Wrap(err, "Unable to decode ethernet frame")
+	}
+	ptr = unsafe.Pointer(uintptr(ptr) - packet.SizeOfEthernetII)
+	length -= uint32(packet.SizeOfEthernetII)
+
+	fl.SrcMAC = macToUint64(ether.SrcMAC)
+	fl.DstMAC = macToUint64(ether.DstMAC)
+
+	ethType := ether.EtherType
+	if ethType == packet.EtherTypeIEEE8021Q {
+		dot1q, err := packet.DecodeDot1Q(ptr, length)
+		if err != nil {
+			return errors.Wrap(err, "Unable to decode dot1q header")
+		}
+		ptr = unsafe.Pointer(uintptr(ptr) - packet.SizeOfDot1Q)
+		length -= uint32(packet.SizeOfDot1Q)
+
+		fl.SrcVLAN = dot1q.TCI & 0x0fff
+		ethType = dot1q.EtherType
+	}
+
+	return decodePayload(ptr, length, ethType, fl, decapsulate)
+}
+
+func decodePayload(ptr unsafe.Pointer, length uint32, ethType uint16, fl *flow.Flow, decapsulate bool) error {
+	// payloadLength is the length of the IP payload of the original packet, the capture may be shorter
+	var payloadLength uint32
+	switch ethType {
+	case packet.EtherTypeIPv4:
+		ipv4, err := packet.DecodeIPv4(ptr, length)
+		if err != nil {
+			return errors.Wrap(err, "Unable to decode IPv4 packet")
+		}
+		ptr = unsafe.Pointer(uintptr(ptr) - packet.SizeOfIPv4Header)
+		length -= uint32(packet.SizeOfIPv4Header)
+
+		fl.Family = 4
+		payloadLength = uint32(ipv4.TotalLength) - min(uint32(ipv4.TotalLength), uint32(packet.SizeOfIPv4Header))
+		fl.SrcAddr = flow.AddrFromBytes(convert.Reverse(ipv4.SrcAddr[:]))
+		fl.DstAddr = flow.AddrFromBytes(convert.Reverse(ipv4.DstAddr[:]))
+		fl.Protocol = uint8(ipv4.Protocol)
+		fl.DSCP = ipv4.DSCP >> 2
+	case packet.EtherTypeIPv6:
+		ipv6, err := packet.DecodeIPv6(ptr, length)
+		if err != nil {
+			return errors.Wrap(err, "Unable to decode IPv6 packet")
+		}
+		ptr = unsafe.Pointer(uintptr(ptr) - packet.SizeOfIPv6Header)
+		length -= uint32(packet.SizeOfIPv6Header)
+
+		fl.Family = 6
+		payloadLength = uint32(ipv6.PayloadLength)
+		fl.SrcAddr = flow.AddrFromBytes(convert.Reverse(ipv6.SrcAddr[:]))
+		fl.DstAddr = flow.AddrFromBytes(convert.Reverse(ipv6.DstAddr[:]))
+		fl.Protocol = uint8(ipv6.NextHeader)
+		fl.DSCP = uint8(ipv6.VersionTrafficClassFlowLabel>>20) >> 2
+	default:
+		return errors.Errorf("Unsupported EtherType 0x%x", ethType)
+	}
+
+	if decapsulate && fl.Protocol == packet.GRE {
+		return decodeGRE(ptr, length, payloadLength, fl)
+	}
+
+	return decodeTransport(ptr, length, fl)
+}
+
+// decodeGRE decodes the inner headers of a GRE packet of payloadLength bytes into fl, replacing the outer ones. The
+// flows size becomes the size of the inner packet. Packets of other GRE protocol types keep their outer headers.
+func decodeGRE(ptr unsafe.Pointer, length uint32, payloadLength uint32, fl *flow.Flow) error {
+	gre, err := packet.DecodeGRE(ptr, length)
+	if err != nil {
+		return errors.Wrap(err, "Unable to decode GRE header")
+	}
+	ptr = unsafe.Pointer(uintptr(ptr) - uintptr(gre.Length))
+	length -= gre.Length
+	innerLength := payloadLength - min(gre.Length, payloadLength)
+
+	switch gre.ProtocolType {
+	case packet.EtherTypeERSPANTypeII, packet.EtherTypeERSPANTypeIII:
+		erspan, err := packet.DecodeERSPAN(ptr, length, gre)
+		if err != nil {
+			return errors.Wrap(err, "Unable to decode ERSPAN header")
+		}
+		ptr = unsafe.Pointer(uintptr(ptr) - uintptr(erspan.Length))
+		length -= erspan.Length
+		innerLength -= min(erspan.Length, innerLength)
+
+		if erspan.FrameType != packet.ERSPANFrameTypeEthernet {
+			return errors.Errorf("Unsupported ERSPAN frame type %d", erspan.FrameType)
+		}
+
+		fl.TunnelType = tunnelTypeERSPAN
+		fl.TunnelID = uint32(erspan.SessionID)
+		fl.Size = uint64(innerLength)
+
+		// the VLAN of the mirrored frame is given by ERSPAN if the frame is untagged
+		resetLinkLayer(fl)
+		fl.SrcVLAN = erspan.VLAN
+		return decodeFrame(ptr, length, fl, false)
+
+	case packet.EtherTypeTransparentEthernetBridging:
+		fl.TunnelType = tunnelTypeGRE
+		fl.TunnelID = gre.Key
+		fl.Size = uint64(innerLength)
+		resetLinkLayer(fl)
+		return decodeFrame(ptr, length, fl, false)
+
+	case packet.EtherTypeIPv4, packet.EtherTypeIPv6:
+		fl.TunnelType = tunnelTypeGRE
+		fl.TunnelID = gre.Key
+		fl.Size = uint64(innerLength)
+		return decodePayload(ptr, length, gre.ProtocolType, fl, false)
+	}
+
+	return nil
+}
+
+func resetLinkLayer(fl *flow.Flow) {
+	fl.SrcMAC = 0
+	fl.DstMAC = 0
+	fl.SrcVLAN = 0
+}
+
+func decodeTransport(ptr unsafe.Pointer, length uint32, fl *flow.Flow) error {
+	switch fl.Protocol {
+	case packet.TCP:
+		tcp, err := packet.DecodeTCP(ptr, length)
+		if err != nil {
+			return errors.Wrap(err, "Unable to decode TCP segment")
+		}
+
+		fl.SrcPort = tcp.SrcPort
+		fl.DstPort = tcp.DstPort
+		fl.TCPFlags = tcp.Flags
+	case packet.UDP:
+		udp, err := packet.DecodeUDP(ptr, length)
+		if err != nil {
+			return errors.Wrap(err, "Unable to decode UDP datagram")
+		}
+
+		fl.SrcPort = udp.SrcPort
+		fl.DstPort = udp.DstPort
+	case packet.ICMP, packet.ICMPv6:
+		icmp, err := packet.DecodeICMP(ptr, length)
+		if err != nil {
+			return errors.Wrap(err, "Unable to decode ICMP message")
+		}
+
+		fl.ICMPType = icmp.Type
+		fl.ICMPCode = icmp.Code
+	}
+
+	return nil
+}
+
+func macToUint64(mac []byte) uint64 {
+	ret := uint64(0)
+	for _, b := range mac {
+		ret = ret<<8 | uint64(b)
+	}
+
+	return ret
+}
@@ -0,0 +1,167 @@
+package capture
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessFrame(t *testing.T) {
+	frame := []byte{
+		0x80, 0x71, 0x1f, 0x7f, 0x02, 0x94, // Destination MAC
+		0x20, 0x4e, 0x71, 0x04, 0x1c, 0xb9, // Source MAC
+		0x08, 0x00, // EtherType
+
+		0x45,       // Version + Length
+		0x28,       // TOS
+		0x00, 0x28, // Total Length
+		0x00, 0x00, // Identifier
+		0x40, 0x00, // Flags + Fragment offset
+		0x40,       // TTL
+		0x06,       // Protocol
+		0x00, 0x00, // Header Checksum
+		192, 0, 2, 1, // SRC IP
+		198, 51, 100, 2, // DST IP
+
+		0xc3, 0x50, // SRC port
+		0x01, 0xbb, // DST port
+		0x00, 0x00, 0x00, 0x01, // Sequence Number
+		0x00, 0x00, 0x00, 0x00, // ACK Number
+		0x50, 0x02, // Header Length + Flags (SYN)
+		0xff, 0xff, // Window
+		0x00, 0x00, // Checksum
+		0x00, 0x00, // Urgent Pointer
+	}
+
+	s := &Server{
+		cfg: &Config{
+			SampleRate: 10,
+		},
+		agent: netip.AddrFrom4([4]byte{127, 0, 0, 1}),
+	}
+
+	buf := make([]byte, defaultSnapLength)
+	copy(buf, frame)
+
+	fl, err := s.processFrame("eth0", true, buf, make([]byte, defaultSnapLength+1), 1514)
+	if err != nil {
+		t.Fatalf("Unable to process frame: %v", err)
+	}
+
+	assert.Equal(t, netip.AddrFrom4([4]byte{192, 0, 2, 1}), fl.SrcAddr)
+	assert.Equal(t, netip.AddrFrom4([4]byte{198, 51, 100, 2}), fl.DstAddr)
+	assert.Equal(t, uint8(4), fl.Family)
+	assert.Equal(t, uint8(6), fl.Protocol)
+	assert.Equal(t, uint16(50000), fl.SrcPort)
+	assert.Equal(t, uint16(443), fl.DstPort)
+	assert.Equal(t, uint8(0x02), fl.TCPFlags)
+	assert.Equal(t, uint8(10), fl.DSCP)
+	assert.Equal(t, uint64(0x204e71041cb9), fl.SrcMAC)
+	assert.Equal(t, uint64(1514), fl.Size)
+	assert.Equal(t, uint64(10), fl.Samplerate)
+	assert.Equal(t, flow.DirectionEgress, fl.Direction)
+	assert.Equal(t, "eth0", fl.IntOut)
+	assert.Equal(t, "", fl.IntIn)
+}
+
+func TestProcessFrameTooShort(t *testing.T) {
+	s := &Server{
+		cfg:   &Config{SampleRate: 1},
+		agent: netip.AddrFrom4([4]byte{127, 0, 0, 1}),
+	}
+
+	_, err := s.processFrame("eth0", false, []byte{0x80, 0x71}, make([]byte, 3), 2)
+	assert.Error(t, err)
+}
+
+func TestProcessFrameERSPAN(t *testing.T) {
+	frame := []byte{
+		0x80, 0x71, 0x1f, 0x7f, 0x02, 0x94, // Destination MAC
+		0x20, 0x4e, 0x71, 0x04, 0x1c, 0xb9, // Source MAC
+		0x08, 0x00, // EtherType
+
+		0x45, 0x00, 0x00, 0x4e, 0x00, 0x00, 0x40, 0x00, 0x40,
+		0x2f,       // Protocol GRE
+		0x00, 0x00, // Header Checksum
+		10, 0, 0, 1, // SRC IP
+		10, 0, 0, 2, // DST IP
+
+		0x10, 0x00, // GRE flags: sequence number present
+		0x88, 0xbe, // Protocol Type ERSPAN type II
+		0x00, 0x00, 0x00, 0x01, // Sequence Number
+
+		0x10, 0x64, // Version 1, VLAN 100
+		0x00, 0x07, // Session ID 7
+		0x00, 0x00, 0x00, 0x05, // Index
+
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x02, // Destination MAC
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x01, // Source MAC
+		0x08, 0x00, // EtherType
+
+		0x45, 0x00, 0x00, 0x1c, 0x00, 0x00, 0x40, 0x00, 0x40,
+		0x11,       // Protocol UDP
+		0x00, 0x00, // Header Checksum
+		192, 0, 2, 1, // SRC IP
+		198, 51, 100, 2, // DST IP
+
+		0x04, 0xd2, // SRC port
+		0x00, 0x35, // DST port
+		0x00, 0x08, 0x00, 0x00, // Length + Checksum
+	}
+
+	tests := []struct {
+		name             string
+		decapsulate      bool
+		expectedSrc      netip.Addr
+		expectedProtocol uint8
+		expectedDstPort  uint16
+		expectedVLAN     uint16
+		expectedTunnel   string
+		expectedSize     uint64
+	}{
+		{
+			name:             "Decapsulated",
+			decapsulate:      true,
+			expectedSrc:      netip.AddrFrom4([4]byte{192, 0, 2, 1}),
+			expectedProtocol: 17,
+			expectedDstPort:  53,
+			expectedVLAN:     100,
+			expectedTunnel:   "erspan",
+			expectedSize:     42,
+		},
+		{
+			name:             "Not decapsulated",
+			expectedSrc:      netip.AddrFrom4([4]byte{10, 0, 0, 1}),
+			expectedProtocol: 47,
+			expectedSize:     92,
+		},
+	}
+
+	for _, test := range tests {
+		s := &Server{
+			cfg:   &Config{SampleRate: 1, Decapsulate: test.decapsulate},
+			agent: netip.AddrFrom4([4]byte{127, 0, 0, 1}),
+		}
+
+		buf := make([]byte, defaultSnapLength)
+		copy(buf, frame)
+
+		fl, err := s.processFrame("eth0", false, buf, make([]byte, defaultSnapLength+1), len(frame))
+		if !assert.NoError(t, err, test.name) {
+			continue
+		}
+
+		assert.Equal(t, test.expectedSrc, fl.SrcAddr, test.name)
+		assert.Equal(t, test.expectedProtocol, fl.Protocol, test.name)
+		assert.Equal(t, test.expectedDstPort, fl.DstPort, test.name)
+		assert.Equal(t, test.expectedVLAN, fl.SrcVLAN, test.name)
+		assert.Equal(t, test.expectedTunnel, fl.TunnelType, test.name)
+		assert.Equal(t, test.expectedSize, fl.Size, test.name)
+		if test.decapsulate {
+			assert.Equal(t, uint32(7), fl.TunnelID, test.name)
+			assert.Equal(t, uint64(0x020000000001), fl.SrcMAC, test.name)
+		}
+	}
+}
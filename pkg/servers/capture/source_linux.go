@@ -0,0 +1,79 @@
+package capture
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ethPAll      = 0x0003
+	readTimeoutS = 1
+)
+
+var errTimeout = errors.New("timeout")
+
+// afPacketSource captures frames on a single interface using an AF_PACKET socket
+type afPacketSource struct {
+	fd int
+}
+
+func openSource(ifName string) (frameSource, error) {
+	iface, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to find interface")
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(ethPAll)))
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create AF_PACKET socket")
+	}
+
+	err = syscall.Bind(fd, &syscall.SockaddrLinklayer{
+		Protocol: htons(ethPAll),
+		Ifindex:  iface.Index,
+	})
+	if err != nil {
+		syscall.Close(fd)
+		return nil, errors.Wrap(err, "Bind failed")
+	}
+
+	// a read timeout allows the workers to notice when they are stopped
+	err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &syscall.Timeval{Sec: readTimeoutS})
+	if err != nil {
+		syscall.Close(fd)
+		return nil, errors.Wrap(err, "Unable to set read timeout")
+	}
+
+	return &afPacketSource{
+		fd: fd,
+	}, nil
+}
+
+func (s *afPacketSource) read(buf []byte) (int, bool, error) {
+	// MSG_TRUNC makes recvfrom return the real length of frames exceeding buf
+	n, from, err := syscall.Recvfrom(s.fd, buf, syscall.MSG_TRUNC)
+	if err == syscall.EAGAIN || err == syscall.EINTR {
+		return 0, false, errTimeout
+	}
+
+	if err != nil {
+		return 0, false, errors.Wrap(err, "Recvfrom failed")
+	}
+
+	outgoing := false
+	if ll, ok := from.(*syscall.SockaddrLinklayer); ok {
+		outgoing = ll.Pkttype == syscall.PACKET_OUTGOING
+	}
+
+	return n, outgoing, nil
+}
+
+func (s *afPacketSource) close() error {
+	return syscall.Close(s.fd)
+}
+
+func htons(x uint16) uint16 {
+	return x<<8 | x>>8
+}
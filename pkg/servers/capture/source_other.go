@@ -0,0 +1,13 @@
+//go:build !linux
+
+package capture
+
+import (
+	"github.com/pkg/errors"
+)
+
+var errTimeout = errors.New("timeout")
+
+func openSource(ifName string) (frameSource, error) {
+	return nil, errors.New("Packet capture is only supported on Linux")
+}
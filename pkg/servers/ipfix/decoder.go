@@ -0,0 +1,28 @@
+package ipfix
+
+import (
+	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+)
+
+// Decoder decodes IPFIX datagrams that were received elsewhere, e.g. to benchmark the decoder. Each decoder keeps
+// its own templates.
+type Decoder struct {
+	ipf *IPFIXServer
+}
+
+// NewDecoder creates a decoder passing the flows of each decoded flow set to output, which takes ownership of them
+func NewDecoder(ifResolver InterfaceResolver, output func(flows []*flow.Flow)) *Decoder {
+	return &Decoder{
+		ipf: &IPFIXServer{
+			tmplCache:  newTemplateCache(),
+			ifResolver: ifResolver,
+			output:     output,
+		},
+	}
+}
+
+// Decode decodes a datagram sent by agent. The datagram is modified in place.
+func (d *Decoder) Decode(agent bnet.IP, datagram []byte) {
+	d.ipf.processPacket(agent, 0, datagram)
+}
@@ -0,0 +1,189 @@
+package ipfix
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+	"github.com/bio-routing/tflow2/convert"
+)
+
+const (
+	// FormatString takes the value as text, e.g. interfaceName (82) or httpRequestTarget (461)
+	FormatString = "string"
+
+	// FormatUint takes the value as unsigned integer in decimal
+	FormatUint = "uint"
+
+	// FormatHex takes the value as hex string
+	FormatHex = "hex"
+)
+
+var columnNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// FieldConfig maps an information element, e.g. a variable-length string, into a column of the flows table
+type FieldConfig struct {
+	Element uint16 `yaml:"element"`
+
+	// Enterprise is the private enterprise number of the element, 0 for IANA elements
+	Enterprise uint32 `yaml:"enterprise"`
+
+	Column string `yaml:"column"`
+
+	// Format is how the value is written to the column (string (default), uint or hex)
+	Format string `yaml:"format"`
+}
+
+// Fields are the information elements mapped into columns. Fields may share a column, e.g. to take names of
+// exporters using different elements. A column then takes the value of the first field present in a record.
+type Fields []*FieldConfig
+
+// Validate validates the fields
+func (f Fields) Validate() error {
+	seen := make(map[fieldKey]struct{}, len(f))
+	for _, fc := range f {
+		if fc.Format == "" {
+			fc.Format = FormatString
+		}
+
+		if !columnNameRegexp.MatchString(fc.Column) {
+			return fmt.Errorf("Invalid column %q", fc.Column)
+		}
+
+		if fc.Format != FormatString && fc.Format != FormatUint && fc.Format != FormatHex {
+			return fmt.Errorf("Invalid format %q of column %s", fc.Format, fc.Column)
+		}
+
+		if fc.Element == 0 || fc.Element > 0x7fff {
+			return fmt.Errorf("Invalid element %d of column %s", fc.Element, fc.Column)
+		}
+
+		k := fieldKey{enterprise: fc.Enterprise, element: fc.Element}
+		if _, exists := seen[k]; exists {
+			return fmt.Errorf("Element %d of enterprise %d is configured twice", fc.Element, fc.Enterprise)
+		}
+		seen[k] = struct{}{}
+	}
+
+	return nil
+}
+
+// Columns gets the distinct columns of the fields in order of their first appearance
+func (f Fields) Columns() []string {
+	res := make([]string, 0, len(f))
+	seen := make(map[string]struct{}, len(f))
+	for _, fc := range f {
+		if _, exists := seen[fc.Column]; exists {
+			continue
+		}
+
+		seen[fc.Column] = struct{}{}
+		res = append(res, fc.Column)
+	}
+
+	return res
+}
+
+type fieldKey struct {
+	enterprise uint32
+	element    uint16
+}
+
+// extraField is where a configured field goes to
+type extraField struct {
+	column int
+	format string
+}
+
+// extraFields gets the configured fields by element
+func (f Fields) extraFields() map[fieldKey]extraField {
+	if len(f) == 0 {
+		return nil
+	}
+
+	columns := make(map[string]int)
+	for i, c := range f.Columns() {
+		columns[c] = i
+	}
+
+	res := make(map[fieldKey]extraField, len(f))
+	for _, fc := range f {
+		res[fieldKey{enterprise: fc.Enterprise, element: fc.Element}] = extraField{
+			column: columns[fc.Column],
+			format: fc.Format,
+		}
+	}
+
+	return res
+}
+
+// extraIndex is the index of a value in the records of a template and the extra field it goes to
+type extraIndex struct {
+	value int
+	extraField
+}
+
+// getExtraIndexes gets the configured fields contained in template
+func (ipf *IPFIXServer) getExtraIndexes(template *ipfix.TemplateRecords) []extraIndex {
+	if len(ipf.extraFields) == 0 {
+		return nil
+	}
+
+	var res []extraIndex
+	for i, f := range template.Records {
+		enterprise := uint32(0)
+		if i < len(template.EnterpriseNumbers) {
+			enterprise = template.EnterpriseNumbers[i]
+		}
+
+		ef, exists := ipf.extraFields[fieldKey{enterprise: enterprise, element: f.ElementID()}]
+		if exists {
+			res = append(res, extraIndex{value: i, extraField: ef})
+		}
+	}
+
+	return res
+}
+
+// extraValues gets the values of the extra columns of a record. The first field present of each column wins.
+func (ipf *IPFIXServer) extraValues(indexes []extraIndex, values [][]byte) []string {
+	res := make([]string, ipf.extraColumns)
+	for _, idx := range indexes {
+		if res[idx.column] != "" || idx.value >= len(values) {
+			continue
+		}
+
+		res[idx.column] = formatValue(values[idx.value], idx.format)
+	}
+
+	return res
+}
+
+// formatValue formats a value as decoded, which is byte reversed
+func formatValue(v []byte, format string) string {
+	if len(v) == 0 {
+		return ""
+	}
+
+	if format == FormatUint {
+		if len(v) > 8 {
+			v = v[:8]
+		}
+
+		return strconv.FormatUint(convert.Uint64(v), 10)
+	}
+
+	// the values alias the datagram, reversing them has to happen on a copy
+	b := make([]byte, len(v))
+	copy(b, v)
+
+	if format == FormatHex {
+		return hex.EncodeToString(convert.Reverse(b))
+	}
+
+	// strings may be padded with NUL bytes up to the length of the field
+	return string(bytes.TrimRight(convert.Reverse(b), "\x00"))
+}
@@ -0,0 +1,151 @@
+package ipfix
+
+import (
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestFieldsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  Fields
+		wantErr bool
+	}{
+		{
+			name: "Valid",
+			fields: Fields{
+				{Element: 82, Column: "interface_name"},
+				{Element: 1, Enterprise: 9, Column: "app_id", Format: FormatUint},
+				{Element: 2, Enterprise: 9, Column: "app_id", Format: FormatHex},
+			},
+		},
+		{
+			name:    "Invalid column",
+			fields:  Fields{{Element: 82, Column: "Interface-Name"}},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid format",
+			fields:  Fields{{Element: 82, Column: "interface_name", Format: "bytes"}},
+			wantErr: true,
+		},
+		{
+			name:    "Element with enterprise bit",
+			fields:  Fields{{Element: 0x8001, Column: "app_id"}},
+			wantErr: true,
+		},
+		{
+			name: "Element configured twice",
+			fields: Fields{
+				{Element: 82, Column: "interface_name"},
+				{Element: 82, Column: "if_name"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.fields.Validate()
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+	}
+}
+
+func TestFieldsColumns(t *testing.T) {
+	f := Fields{
+		{Element: 82, Column: "interface_name"},
+		{Element: 1, Enterprise: 9, Column: "app_id"},
+		{Element: 83, Column: "interface_name"},
+	}
+
+	assert.Equal(t, []string{"interface_name", "app_id"}, f.Columns())
+}
+
+func TestProcessPacketExtraFields(t *testing.T) {
+	fields := Fields{
+		{Element: 82, Column: "interface_name"},
+		{Element: 1, Enterprise: 9, Column: "app_id", Format: FormatUint},
+	}
+	assert.NoError(t, fields.Validate())
+
+	var flows []*flow.Flow
+	ipf := &IPFIXServer{
+		tmplCache: newTemplateCache(),
+		output: func(fls []*flow.Flow) {
+			flows = append(flows, fls...)
+		},
+		extraFields:  fields.extraFields(),
+		extraColumns: len(fields.Columns()),
+	}
+
+	datagram := []byte{
+		0, 10, 0, 68, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1,
+
+		// template 256 with sourceIPv4Address, interfaceName (variable length) and an enterprise field
+		0, 2, 0, 24,
+		1, 0, 0, 3,
+		0, 8, 0, 4,
+		0, 82, 0xff, 0xff,
+		0x80, 1, 0, 2, 0, 0, 0, 9,
+
+		1, 0, 0, 28,
+		192, 0, 2, 1, 3, 'e', 't', '0', 0, 42,
+		192, 0, 2, 2, 255, 0, 3, 'e', 'x', '1', 0, 7,
+		0, 0,
+	}
+
+	ipf.processPacket(bnet.IPv4FromOctets(192, 0, 2, 254), 0, datagram)
+
+	if !assert.Len(t, flows, 2) {
+		return
+	}
+
+	assert.Equal(t, "192.0.2.1", flows[0].SrcAddr.String())
+	assert.Equal(t, []string{"et0", "42"}, flows[0].Extra)
+	assert.Equal(t, []string{"ex1", "7"}, flows[1].Extra)
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    []byte
+		format   string
+		expected string
+	}{
+		{
+			name:     "String padded with NUL bytes",
+			value:    []byte{0, 0, '0', 't', 'e'},
+			format:   FormatString,
+			expected: "et0",
+		},
+		{
+			name:     "Uint",
+			value:    []byte{0x01, 0x02},
+			format:   FormatUint,
+			expected: "513",
+		},
+		{
+			name:     "Hex",
+			value:    []byte{0x01, 0x02},
+			format:   FormatHex,
+			expected: "0201",
+		},
+		{
+			name:     "Empty",
+			format:   FormatString,
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, formatValue(test.value, test.format), test.name)
+	}
+}
@@ -1,20 +1,25 @@
 package ipfix
 
 import (
+	"encoding/json"
 	"io"
 	"net"
+	"net/http"
 	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/appid"
+	"github.com/bio-routing/flowhouse/pkg/decodelog"
 	"github.com/bio-routing/flowhouse/pkg/models/flow"
 	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+	"github.com/bio-routing/flowhouse/pkg/ratelimit"
+	"github.com/bio-routing/flowhouse/pkg/relay"
 	"github.com/bio-routing/tflow2/convert"
 	"github.com/pkg/errors"
-
-	log "github.com/sirupsen/logrus"
 )
 
 type InterfaceResolver interface {
@@ -40,6 +45,26 @@ type fieldMap struct {
 	srcPort                int
 	dstPort                int
 	samplingPacketInterval int
+	tcpFlags               int
+	tos                    int
+	icmpTypeCode           int
+	srcMac                 int
+	dstMac                 int
+	dstVlan                int
+	direction              int
+	observationPointID     int
+	flowStartSeconds       int
+	flowEndSeconds         int
+	flowStartMilliseconds  int
+	flowEndMilliseconds    int
+	flowStartSysUpTime     int
+	flowEndSysUpTime       int
+	systemInitTime         int
+	applicationID          int
+	applicationName        int
+
+	// unmapped are the indexes of the fields not mapped into the flow
+	unmapped []int
 }
 
 type IPFIXServer struct {
@@ -48,18 +73,36 @@ type IPFIXServer struct {
 	tmplCache  *templateCache
 	conn       *net.UDPConn
 	ifResolver InterfaceResolver
-	output     chan []*flow.Flow
+	output     func(flows []*flow.Flow)
+	relay      *relay.Relay
+	decodeLog  *decodelog.Log
+	limiter    *ratelimit.Limiter
 	wg         sync.WaitGroup
 	stopCh     chan struct{}
+
+	// extraFields are the fields mapped into the extraColumns extra columns
+	extraFields  map[fieldKey]extraField
+	extraColumns int
+
+	// raw keeps the fields mapped neither into the flow nor into an extra column in flow.Flow.Raw
+	raw bool
 }
 
-// New creates and starts a new `IPFIXServer` instance
-func New(listen string, numReaders int, output chan []*flow.Flow, ifResolver InterfaceResolver) (*IPFIXServer, error) {
+// New creates and starts a new `IPFIXServer` instance. If r is not nil received datagrams are forwarded to it.
+// Datagrams failing to decode are recorded in dl. If lim is not nil datagrams of agents beyond their limit are dropped.
+// The values of fields go to the extra columns of the flows, with raw the values of all other fields go to their raw map.
+func New(listen string, numReaders int, output func(flows []*flow.Flow), ifResolver InterfaceResolver, r *relay.Relay, dl *decodelog.Log, lim *ratelimit.Limiter, fields Fields, raw bool) (*IPFIXServer, error) {
 	ipf := &IPFIXServer{
-		tmplCache:  newTemplateCache(),
-		ifResolver: ifResolver,
-		stopCh:     make(chan struct{}),
-		output:     output,
+		tmplCache:    newTemplateCache(),
+		ifResolver:   ifResolver,
+		stopCh:       make(chan struct{}),
+		relay:        r,
+		decodeLog:    dl,
+		limiter:      lim,
+		output:       output,
+		extraFields:  fields.extraFields(),
+		extraColumns: len(fields.Columns()),
+		raw:          raw,
 	}
 
 	addr, err := net.ResolveUDPAddr("udp", listen)
@@ -126,10 +169,30 @@ func (ipf *IPFIXServer) packetWorker() error {
 			return errors.Wrapf(err, "Unable to convert net.IP to bnet.IP: %q", remote)
 		}
 
-		ipf.processPacket(remoteAddr, buffer[:length])
+		if ipf.relay != nil {
+			ipf.relay.Forward(flow.AddrFromBNet(remoteAddr), buffer[:length])
+		}
+
+		if ipf.limiter != nil && !ipf.limiter.AllowDatagram(flow.AddrFromBNet(remoteAddr)) {
+			continue
+		}
+
+		ipf.processPacketSafely(remoteAddr, uint16(remote.Port), buffer[:length])
 	}
 }
 
+// processPacketSafely processes a packet and recovers from panics on malformed packets, so they cannot stop the worker
+func (ipf *IPFIXServer) processPacketSafely(agent bnet.IP, srcPort uint16, buffer []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			decodePanics.WithLabelValues(agent.String()).Inc()
+			log.WithField("agent", agent.String()).Errorf("Recovered from panic processing IPFIX packet: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	ipf.processPacket(agent, srcPort, buffer)
+}
+
 func (ipf *IPFIXServer) stopped() bool {
 	select {
 	case <-ipf.stopCh:
@@ -139,10 +202,15 @@ func (ipf *IPFIXServer) stopped() bool {
 	}
 }
 
-func (ipf *IPFIXServer) processPacket(agent bnet.IP, buffer []byte) {
+func (ipf *IPFIXServer) processPacket(agent bnet.IP, srcPort uint16, buffer []byte) {
+	packetsReceived.WithLabelValues(agent.String()).Inc()
 	pkt, err := ipfix.Decode(buffer)
 	if err != nil {
-		log.WithError(err).Error("Unable to decode IPFIX packet")
+		log.WithError(err).WithField("agent", agent.String()).Error("Unable to decode IPFIX packet")
+		if ipf.decodeLog != nil {
+			// the decoder reverses the buffer in place
+			ipf.decodeLog.Add(decodelog.ProtocolIPFIX, agent, srcPort, convert.Reverse(buffer), err)
+		}
 		return
 	}
 
@@ -159,14 +227,16 @@ func (ipf *IPFIXServer) processFlowSets(remote bnet.IP, domainID uint32, flowSet
 
 		if template == nil {
 			templateKey := makeTemplateKey(addr, domainID, set.Header.SetID, keyParts)
-			log.Debugf("Template for given FlowSet not found: %s", templateKey)
+			log.WithField("agent", addr).Debugf("Template for given FlowSet not found: %s", templateKey)
+			flowSetsMissingTemplate.WithLabelValues(addr).Inc()
 
 			continue
 		}
 
 		records := template.DecodeFlowSet(*set)
 		if records == nil {
-			log.Warning("Error decoding FlowSet")
+			log.WithField("agent", addr).Warning("Error decoding FlowSet")
+			flowSetDecodeErrors.WithLabelValues(addr).Inc()
 			continue
 		}
 
@@ -177,6 +247,14 @@ func (ipf *IPFIXServer) processFlowSets(remote bnet.IP, domainID uint32, flowSet
 // process generates Flow elements from records and pushes them into the `receiver` channel
 func (ipf *IPFIXServer) processFlowSet(template *ipfix.TemplateRecords, records []ipfix.FlowDataRecord, agent bnet.IP, ts int64, packet *ipfix.Packet) {
 	fm := generateFieldMap(template)
+	extras := ipf.getExtraIndexes(template)
+	raws := ipf.getRawIndexes(template, fm)
+	receivedAt := time.Now().Unix()
+	agentAddr := flow.AddrFromBNet(agent)
+
+	if fm.lacksSystemInitTime() {
+		flowTimesWithoutInitTime.WithLabelValues(agent.String()).Add(float64(len(records)))
+	}
 
 	flows := make([]*flow.Flow, 0, len(records))
 	for _, r := range records {
@@ -187,11 +265,29 @@ func (ipf *IPFIXServer) processFlowSet(template *ipfix.TemplateRecords, records
 			continue
 		}*/
 
-		fl := &flow.Flow{
-			Agent:     agent,
-			Timestamp: ts,
+		fl := flow.New()
+		*fl = flow.Flow{
+			Agent:               agentAddr,
+			Timestamp:           ts,
+			ReceivedAt:          receivedAt,
+			ExportedAt:          ts,
+			ObservationDomainID: packet.Header.DomainID,
+			ExportProtocol:      flow.ExportProtocolIPFIX,
+		}
+
+		if fm.direction >= 0 {
+			fl.Direction = flow.DirectionIngress
+			if convert.Uint16(r.Values[fm.direction]) == 1 {
+				fl.Direction = flow.DirectionEgress
+			}
+		}
+
+		if fm.observationPointID >= 0 {
+			fl.ObservationPointID = convert.Uint64(r.Values[fm.observationPointID])
 		}
 
+		fl.FlowStart, fl.FlowEnd = getFlowTimes(fm, r, ts)
+
 		if fm.family >= 0 {
 			fl.Family = uint8(fm.family)
 		}
@@ -224,16 +320,56 @@ func (ipf *IPFIXServer) processFlowSet(template *ipfix.TemplateRecords, records
 			fl.DstPort = convert.Uint16(r.Values[fm.dstPort])
 		}
 
+		if fm.tcpFlags >= 0 {
+			fl.TCPFlags = uint8(convert.Uint16(r.Values[fm.tcpFlags]))
+		}
+
+		if fm.tos >= 0 {
+			fl.DSCP = uint8(convert.Uint16(r.Values[fm.tos])) >> 2
+		}
+
+		if fm.icmpTypeCode >= 0 {
+			typeCode := convert.Uint16(r.Values[fm.icmpTypeCode])
+			fl.ICMPType = uint8(typeCode >> 8)
+			fl.ICMPCode = uint8(typeCode)
+		}
+
+		if fm.srcMac >= 0 {
+			fl.SrcMAC = convert.Uint64(r.Values[fm.srcMac])
+		}
+
+		if fm.dstMac >= 0 {
+			fl.DstMAC = convert.Uint64(r.Values[fm.dstMac])
+		}
+
+		if fm.vlan >= 0 {
+			fl.SrcVLAN = convert.Uint16(r.Values[fm.vlan])
+		}
+
+		if fm.dstVlan >= 0 {
+			fl.DstVLAN = convert.Uint16(r.Values[fm.dstVlan])
+		}
+
 		if fm.srcAddr >= 0 {
-			fl.SrcAddr = bnet.IPv4FromBytes(convert.Reverse(r.Values[fm.srcAddr]))
+			fl.SrcAddr = flow.AddrFromBytes(convert.Reverse(r.Values[fm.srcAddr]))
 		}
 
 		if fm.dstAddr >= 0 {
-			fl.DstAddr = bnet.IPv4FromBytes(convert.Reverse(r.Values[fm.dstAddr]))
+			fl.DstAddr = flow.AddrFromBytes(convert.Reverse(r.Values[fm.dstAddr]))
 		}
 
 		if fm.nextHop >= 0 {
-			fl.NextHop = bnet.IPv4FromBytes(convert.Reverse(r.Values[fm.nextHop]))
+			fl.NextHop = flow.AddrFromBytes(convert.Reverse(r.Values[fm.nextHop]))
+		}
+
+		fl.Application = getApplication(fm, r)
+
+		if ipf.extraColumns > 0 {
+			fl.Extra = ipf.extraValues(extras, r.Values)
+		}
+
+		if len(raws) > 0 {
+			fl.Raw = rawValues(raws, r.Values)
 		}
 
 		fl.Samplerate = 1000
@@ -242,7 +378,72 @@ func (ipf *IPFIXServer) processFlowSet(template *ipfix.TemplateRecords, records
 		flows = append(flows, fl)
 	}
 
-	ipf.output <- flows
+	flowsDecoded.WithLabelValues(agent.String()).Add(float64(len(flows)))
+	ipf.output(flows)
+}
+
+// getFlowTimes returns start and end of a flow in unix milliseconds. Times relative to the system uptime
+// (flowStartSysUpTime, flowEndSysUpTime) are only used along with systemInitTimeMilliseconds in the same record.
+// Without usable timestamps in the record the export time is used.
+func getFlowTimes(fm *fieldMap, r ipfix.FlowDataRecord, exportTime int64) (int64, int64) {
+	start := exportTime * 1000
+	end := exportTime * 1000
+
+	initTime := int64(-1)
+	if fm.systemInitTime >= 0 {
+		initTime = int64(convert.Uint64(r.Values[fm.systemInitTime]))
+	}
+
+	if fm.flowStartMilliseconds >= 0 {
+		start = int64(convert.Uint64(r.Values[fm.flowStartMilliseconds]))
+	} else if fm.flowStartSeconds >= 0 {
+		start = int64(convert.Uint32(r.Values[fm.flowStartSeconds])) * 1000
+	} else if fm.flowStartSysUpTime >= 0 && initTime >= 0 {
+		start = initTime + int64(convert.Uint32(r.Values[fm.flowStartSysUpTime]))
+	}
+
+	if fm.flowEndMilliseconds >= 0 {
+		end = int64(convert.Uint64(r.Values[fm.flowEndMilliseconds]))
+	} else if fm.flowEndSeconds >= 0 {
+		end = int64(convert.Uint32(r.Values[fm.flowEndSeconds])) * 1000
+	} else if fm.flowEndSysUpTime >= 0 && initTime >= 0 {
+		end = initTime + int64(convert.Uint32(r.Values[fm.flowEndSysUpTime]))
+	}
+
+	if end < start {
+		end = start
+	}
+
+	return start, end
+}
+
+// lacksSystemInitTime tells if the flow times of records are relative to the system uptime only, so the export time has
+// to be used instead
+func (fm *fieldMap) lacksSystemInitTime() bool {
+	if fm.systemInitTime >= 0 {
+		return false
+	}
+
+	return (fm.flowStartSysUpTime >= 0 && fm.flowStartMilliseconds < 0 && fm.flowStartSeconds < 0) ||
+		(fm.flowEndSysUpTime >= 0 && fm.flowEndMilliseconds < 0 && fm.flowEndSeconds < 0)
+}
+
+// getApplication returns the application name of a record, its application ID if it has no name
+func getApplication(fm *fieldMap, r ipfix.FlowDataRecord) string {
+	if fm.applicationName >= 0 {
+		name := formatValue(r.Values[fm.applicationName], FormatString)
+		if name != "" {
+			return name
+		}
+	}
+
+	if fm.applicationID >= 0 {
+		id := make([]byte, len(r.Values[fm.applicationID]))
+		copy(id, r.Values[fm.applicationID])
+		return appid.FormatID(convert.Reverse(id))
+	}
+
+	return ""
 }
 
 // generateFieldMap processes a TemplateRecord and populates a fieldMap accordingly
@@ -265,6 +466,23 @@ func generateFieldMap(template *ipfix.TemplateRecords) *fieldMap {
 		srcPort:                -1,
 		dstPort:                -1,
 		samplingPacketInterval: -1,
+		tcpFlags:               -1,
+		tos:                    -1,
+		icmpTypeCode:           -1,
+		srcMac:                 -1,
+		dstMac:                 -1,
+		dstVlan:                -1,
+		direction:              -1,
+		observationPointID:     -1,
+		flowStartSeconds:       -1,
+		flowEndSeconds:         -1,
+		flowStartMilliseconds:  -1,
+		flowEndMilliseconds:    -1,
+		flowStartSysUpTime:     -1,
+		flowEndSysUpTime:       -1,
+		systemInitTime:         -1,
+		applicationID:          -1,
+		applicationName:        -1,
 	}
 
 	i := -1
@@ -306,6 +524,52 @@ func generateFieldMap(template *ipfix.TemplateRecords) *fieldMap {
 			fm.dstAsn = i
 		case ipfix.SamplingPacketInterval:
 			fm.samplingPacketInterval = i
+		case ipfix.TCPFlags:
+			fm.tcpFlags = i
+		case ipfix.SrcTos:
+			fm.tos = i
+		case ipfix.IcmpType:
+			fm.icmpTypeCode = i
+		case ipfix.IcmpTypeCodeIPv6:
+			fm.icmpTypeCode = i
+		case ipfix.InSrcMac:
+			fm.srcMac = i
+		case ipfix.OutDstMac:
+			fm.dstMac = i
+		case ipfix.InDstMac:
+			if fm.dstMac < 0 {
+				fm.dstMac = i
+			}
+		case ipfix.SrcVlan:
+			fm.vlan = i
+		case ipfix.DstVlan:
+			fm.dstVlan = i
+		case ipfix.Direction:
+			fm.direction = i
+		case ipfix.ObservationPointID:
+			fm.observationPointID = i
+		case ipfix.FlowStartSeconds:
+			fm.flowStartSeconds = i
+		case ipfix.FlowEndSeconds:
+			fm.flowEndSeconds = i
+		case ipfix.FlowStartMilliseconds:
+			fm.flowStartMilliseconds = i
+		case ipfix.FlowEndMilliseconds:
+			fm.flowEndMilliseconds = i
+		case ipfix.FirstSwitched:
+			fm.flowStartSysUpTime = i
+		case ipfix.LastSwitched:
+			fm.flowEndSysUpTime = i
+		case ipfix.SysInitTimeMilliseconds:
+			fm.systemInitTime = i
+		case ipfix.ApplicationTag:
+			fm.applicationID = i
+		case ipfix.ApplicationName:
+			fm.applicationName = i
+		default:
+			if f.Type != paddingOctets {
+				fm.unmapped = append(fm.unmapped, i)
+			}
 		}
 	}
 
@@ -320,6 +584,28 @@ func (ipf *IPFIXServer) updateTemplateCache(remote bnet.IP, p *ipfix.Packet) {
 	}
 }
 
+// TemplatesHandler serves the currently known templates as JSON. The exporter parameter restricts the result to a single exporter.
+func (ipf *IPFIXServer) TemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	var exporter *bnet.IP
+	if e := r.URL.Query().Get("exporter"); e != "" {
+		addr, err := bnet.IPFromString(e)
+		if err != nil {
+			http.Error(w, "Invalid exporter", http.StatusBadRequest)
+			return
+		}
+		exporter = &addr
+	}
+
+	j, err := json.Marshal(ipf.tmplCache.list(exporter))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
 // makeTemplateKey creates a string of the 3 tuple router address, source id and template id
 func makeTemplateKey(addr string, sourceID uint32, templateID uint16, keyParts []string) string {
 	keyParts[0] = addr
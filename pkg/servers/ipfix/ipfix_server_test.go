@@ -0,0 +1,178 @@
+package ipfix
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestGetApplication(t *testing.T) {
+	tmpl := &ipfix.TemplateRecords{
+		Records: []*ipfix.TemplateRecord{
+			{Type: ipfix.ApplicationTag, Length: 4},
+			{Type: ipfix.ApplicationName, Length: ipfix.VariableLength},
+		},
+	}
+	fm := generateFieldMap(tmpl)
+
+	tests := []struct {
+		name     string
+		values   [][]byte
+		expected string
+	}{
+		{
+			name: "Name",
+			// values are reversed like the datagram
+			values:   [][]byte{{0xc5, 0x01, 0, 13}, []byte("sptth")},
+			expected: "https",
+		},
+		{
+			name:     "ID without name",
+			values:   [][]byte{{0xc5, 0x01, 0, 13}, {}},
+			expected: "13:453",
+		},
+	}
+
+	for _, test := range tests {
+		r := ipfix.FlowDataRecord{Values: test.values}
+		assert.Equal(t, test.expected, getApplication(fm, r), test.name)
+		assert.Equal(t, []byte{0xc5, 0x01, 0, 13}, r.Values[0], test.name)
+	}
+}
+
+func TestGetFlowTimes(t *testing.T) {
+	// values are reversed like the datagram, i.e. little endian
+	u32 := func(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+	u64 := func(v uint64) []byte { return binary.LittleEndian.AppendUint64(nil, v) }
+
+	tests := []struct {
+		name          string
+		records       []*ipfix.TemplateRecord
+		values        [][]byte
+		expectedStart int64
+		expectedEnd   int64
+		lacksInitTime bool
+	}{
+		{
+			name: "Milliseconds",
+			records: []*ipfix.TemplateRecord{
+				{Type: ipfix.FlowStartMilliseconds, Length: 8},
+				{Type: ipfix.FlowEndMilliseconds, Length: 8},
+			},
+			values:        [][]byte{u64(1614600000500), u64(1614600001500)},
+			expectedStart: 1614600000500,
+			expectedEnd:   1614600001500,
+		},
+		{
+			name: "System uptime with init time",
+			records: []*ipfix.TemplateRecord{
+				{Type: ipfix.SysInitTimeMilliseconds, Length: 8},
+				{Type: ipfix.FirstSwitched, Length: 4},
+				{Type: ipfix.LastSwitched, Length: 4},
+			},
+			values:        [][]byte{u64(1614500000000), u32(100000500), u32(100001500)},
+			expectedStart: 1614600000500,
+			expectedEnd:   1614600001500,
+		},
+		{
+			name: "System uptime without init time",
+			records: []*ipfix.TemplateRecord{
+				{Type: ipfix.FirstSwitched, Length: 4},
+				{Type: ipfix.LastSwitched, Length: 4},
+			},
+			values:        [][]byte{u32(100000500), u32(100001500)},
+			expectedStart: 1614600010000,
+			expectedEnd:   1614600010000,
+			lacksInitTime: true,
+		},
+		{
+			name:          "No times",
+			records:       []*ipfix.TemplateRecord{{Type: ipfix.L4SrcPort, Length: 2}},
+			values:        [][]byte{{53, 0}},
+			expectedStart: 1614600010000,
+			expectedEnd:   1614600010000,
+		},
+	}
+
+	for _, test := range tests {
+		fm := generateFieldMap(&ipfix.TemplateRecords{Records: test.records})
+		start, end := getFlowTimes(fm, ipfix.FlowDataRecord{Values: test.values}, 1614600010)
+		assert.Equal(t, test.expectedStart, start, test.name)
+		assert.Equal(t, test.expectedEnd, end, test.name)
+		assert.Equal(t, test.lacksInitTime, fm.lacksSystemInitTime(), test.name)
+	}
+}
+
+// TestSchemaElements fails if the flow schema lists an element generateFieldMap does not map into the flow
+func TestSchemaElements(t *testing.T) {
+	for name, elements := range schemaElements {
+		for _, e := range elements {
+			fm := generateFieldMap(&ipfix.TemplateRecords{
+				Records: []*ipfix.TemplateRecord{{Type: e, Length: 4}},
+			})
+
+			assert.Empty(t, fm.unmapped, "element %d of %s", e, name)
+		}
+	}
+}
+
+type testResolver struct{}
+
+func (r testResolver) Resolve(agent bnet.IP, ifID uint32) string {
+	return ""
+}
+
+// testPacket is a packet of a template and a data set of one flow from 192.0.2.1:1234 to 198.51.100.1:53
+func testPacket() []byte {
+	return []byte{
+		0, 10, 0, 56, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1,
+
+		// template 256 with sourceIPv4Address, destinationIPv4Address, sourceTransportPort, destinationTransportPort, ingressInterface
+		0, 2, 0, 28,
+		1, 0, 0, 5,
+		0, 8, 0, 4,
+		0, 12, 0, 4,
+		0, 7, 0, 2,
+		0, 11, 0, 2,
+		0, 10, 0, 4,
+
+		1, 0, 0, 20,
+		192, 0, 2, 1, 198, 51, 100, 1, 0x04, 0xd2, 0, 53, 0, 0, 0, 7,
+	}
+}
+
+func TestProcessPacketSafely(t *testing.T) {
+	var flows []*flow.Flow
+	ipf := &IPFIXServer{
+		tmplCache:  newTemplateCache(),
+		ifResolver: testResolver{},
+		output: func(fls []*flow.Flow) {
+			flows = fls
+			panic("output failed")
+		},
+	}
+
+	assert.NotPanics(t, func() {
+		ipf.processPacketSafely(bnet.IPv4FromOctets(192, 0, 2, 254), 0, testPacket())
+	})
+
+	if assert.Len(t, flows, 1) {
+		assert.Equal(t, "192.0.2.1", flows[0].SrcAddr.String())
+		assert.Equal(t, uint16(53), flows[0].DstPort)
+	}
+}
+
+func FuzzDecoder(f *testing.F) {
+	f.Add(testPacket())
+
+	f.Fuzz(func(t *testing.T, datagram []byte) {
+		// a fresh decoder keeps templates of earlier inputs from changing the outcome
+		d := NewDecoder(testResolver{}, func(flows []*flow.Flow) {})
+		d.Decode(bnet.IPv4FromOctets(192, 0, 2, 254), append([]byte{}, datagram...))
+	})
+}
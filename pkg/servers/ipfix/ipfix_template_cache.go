@@ -1,20 +1,43 @@
 package ipfix
 
 import (
+	"sort"
 	"sync"
+	"time"
 
 	bnet "github.com/bio-routing/bio-rd/net"
 	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
 )
 
 type templateCache struct {
-	cache map[bnet.IP]map[uint32]map[uint16]ipfix.TemplateRecords
+	cache map[bnet.IP]map[uint32]map[uint16]*templateEntry
 	lock  sync.RWMutex
 }
 
+type templateEntry struct {
+	records ipfix.TemplateRecords
+	updated time.Time
+}
+
+// TemplateInfo describes a template known to the collector
+type TemplateInfo struct {
+	Exporter    string              `json:"exporter"`
+	DomainID    uint32              `json:"domain_id"`
+	TemplateID  uint16              `json:"template_id"`
+	Fields      []TemplateFieldInfo `json:"fields"`
+	LastRefresh time.Time           `json:"last_refresh"`
+}
+
+// TemplateFieldInfo describes a field of a template
+type TemplateFieldInfo struct {
+	ID     uint16 `json:"id"`
+	Name   string `json:"name"`
+	Length uint16 `json:"length"`
+}
+
 // newTemplateCache creates and initializes a new `templateCache` instance
 func newTemplateCache() *templateCache {
-	return &templateCache{cache: make(map[bnet.IP]map[uint32]map[uint16]ipfix.TemplateRecords)}
+	return &templateCache{cache: make(map[bnet.IP]map[uint32]map[uint16]*templateEntry)}
 }
 
 func (c *templateCache) set(rtr bnet.IP, domainID uint32, templateID uint16, records ipfix.TemplateRecords) {
@@ -22,14 +45,17 @@ func (c *templateCache) set(rtr bnet.IP, domainID uint32, templateID uint16, rec
 	defer c.lock.Unlock()
 
 	if _, ok := c.cache[rtr]; !ok {
-		c.cache[rtr] = make(map[uint32]map[uint16]ipfix.TemplateRecords)
+		c.cache[rtr] = make(map[uint32]map[uint16]*templateEntry)
 	}
 
 	if _, ok := c.cache[rtr][domainID]; !ok {
-		c.cache[rtr][domainID] = make(map[uint16]ipfix.TemplateRecords)
+		c.cache[rtr][domainID] = make(map[uint16]*templateEntry)
 	}
 
-	c.cache[rtr][domainID][templateID] = records
+	c.cache[rtr][domainID][templateID] = &templateEntry{
+		records: records,
+		updated: time.Now(),
+	}
 }
 
 func (c *templateCache) get(rtr bnet.IP, domainID uint32, templateID uint16) *ipfix.TemplateRecords {
@@ -48,6 +74,55 @@ func (c *templateCache) get(rtr bnet.IP, domainID uint32, templateID uint16) *ip
 		return nil
 	}
 
-	ret := c.cache[rtr][domainID][templateID]
+	ret := c.cache[rtr][domainID][templateID].records
 	return &ret
 }
+
+// list returns all known templates (optionally only those of exporter) sorted by exporter, domain and template ID
+func (c *templateCache) list(exporter *bnet.IP) []*TemplateInfo {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	res := make([]*TemplateInfo, 0)
+	for rtr, domains := range c.cache {
+		if exporter != nil && rtr != *exporter {
+			continue
+		}
+
+		for domainID, templates := range domains {
+			for templateID, e := range templates {
+				ti := &TemplateInfo{
+					Exporter:    rtr.String(),
+					DomainID:    domainID,
+					TemplateID:  templateID,
+					Fields:      make([]TemplateFieldInfo, 0, len(e.records.Records)),
+					LastRefresh: e.updated,
+				}
+
+				for _, r := range e.records.Records {
+					ti.Fields = append(ti.Fields, TemplateFieldInfo{
+						ID:     r.Type,
+						Name:   ipfix.FieldName(r.Type),
+						Length: r.Length,
+					})
+				}
+
+				res = append(res, ti)
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Exporter != res[j].Exporter {
+			return res[i].Exporter < res[j].Exporter
+		}
+
+		if res[i].DomainID != res[j].DomainID {
+			return res[i].DomainID < res[j].DomainID
+		}
+
+		return res[i].TemplateID < res[j].TemplateID
+	})
+
+	return res
+}
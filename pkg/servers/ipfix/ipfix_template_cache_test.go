@@ -0,0 +1,42 @@
+package ipfix
+
+import (
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestTemplateCacheList(t *testing.T) {
+	c := newTemplateCache()
+	a := bnet.IPv4FromOctets(192, 0, 2, 2)
+	b := bnet.IPv4FromOctets(192, 0, 2, 1)
+
+	c.set(a, 0, 256, ipfix.TemplateRecords{
+		Header: &ipfix.TemplateRecordHeader{TemplateID: 256, FieldCount: 2},
+		Records: []*ipfix.TemplateRecord{
+			{Type: ipfix.IPv4SrcAddr, Length: 4},
+			{Type: 40000, Length: 2},
+		},
+	})
+	c.set(b, 1, 300, ipfix.TemplateRecords{
+		Header: &ipfix.TemplateRecordHeader{TemplateID: 300, FieldCount: 0},
+	})
+
+	res := c.list(nil)
+	assert.Len(t, res, 2)
+	assert.Equal(t, "192.0.2.1", res[0].Exporter)
+	assert.Equal(t, uint16(300), res[0].TemplateID)
+	assert.Equal(t, "192.0.2.2", res[1].Exporter)
+	assert.Equal(t, []TemplateFieldInfo{
+		{ID: ipfix.IPv4SrcAddr, Name: "IPv4SrcAddr", Length: 4},
+		{ID: 40000, Name: "Unknown40000", Length: 2},
+	}, res[1].Fields)
+	assert.False(t, res[1].LastRefresh.IsZero())
+
+	res = c.list(&a)
+	assert.Len(t, res, 1)
+	assert.Equal(t, uint16(256), res[0].TemplateID)
+}
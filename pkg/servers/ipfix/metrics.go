@@ -0,0 +1,48 @@
+package ipfix
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics are shared by all servers so a server can be replaced on reload without registering them again
+var labels = []string{"agent"}
+
+var (
+	packetsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "ipfix",
+		Name:      "received_packets",
+		Help:      "Received IPFIX packets",
+	}, labels)
+	flowSetsMissingTemplate = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "ipfix",
+		Name:      "flow_sets_missing_template",
+		Help:      "Flow sets dropped as their template is not known (yet)",
+	}, labels)
+	flowSetDecodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "ipfix",
+		Name:      "flow_set_decode_errors",
+		Help:      "Flow sets that failed to decode",
+	}, labels)
+	flowsDecoded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "ipfix",
+		Name:      "decoded_flows",
+		Help:      "Flows decoded from data records",
+	}, labels)
+	flowTimesWithoutInitTime = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "ipfix",
+		Name:      "flow_times_without_init_time",
+		Help:      "Flows with times relative to the system uptime but no system init time, the export time is used instead",
+	}, labels)
+	decodePanics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "ipfix",
+		Name:      "decode_panics",
+		Help:      "Packets dropped as processing them panicked",
+	}, labels)
+)
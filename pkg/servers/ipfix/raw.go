@@ -0,0 +1,111 @@
+package ipfix
+
+import (
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+)
+
+// paddingOctets is the information element padding records (RFC 7011 section 3.3.1), it carries no information
+const paddingOctets = 210
+
+// rawIndex is the index of a value in the records of a template, its key in the raw map and how it is formatted
+type rawIndex struct {
+	value  int
+	key    string
+	format string
+}
+
+// getRawIndexes gets the fields of template mapped neither into the flow nor into an extra column. Fields of IANA
+// elements are keyed by their element ID, e.g. 461, fields of enterprise elements by <enterprise>/<element>.
+func (ipf *IPFIXServer) getRawIndexes(template *ipfix.TemplateRecords, fm *fieldMap) []rawIndex {
+	if !ipf.raw || len(fm.unmapped) == 0 {
+		return nil
+	}
+
+	res := make([]rawIndex, 0, len(fm.unmapped))
+	for _, i := range fm.unmapped {
+		f := template.Records[i]
+		enterprise := uint32(0)
+		if i < len(template.EnterpriseNumbers) {
+			enterprise = template.EnterpriseNumbers[i]
+		}
+
+		k := fieldKey{enterprise: enterprise, element: f.ElementID()}
+		if _, exists := ipf.extraFields[k]; exists {
+			continue
+		}
+
+		res = append(res, rawIndex{
+			value:  i,
+			key:    k.String(),
+			format: rawFormat(f),
+		})
+	}
+
+	return res
+}
+
+// String formats k as key of the raw map
+func (k fieldKey) String() string {
+	if k.enterprise == 0 {
+		return strconv.Itoa(int(k.element))
+	}
+
+	return strconv.FormatUint(uint64(k.enterprise), 10) + "/" + strconv.Itoa(int(k.element))
+}
+
+// rawFormat guesses the format of a field not knowing its information element. Fixed length fields of up to 8 bytes
+// are taken as numbers, all others are formatted by their value (see rawValue).
+func rawFormat(f *ipfix.TemplateRecord) string {
+	if f.Length != ipfix.VariableLength && f.Length <= 8 {
+		return FormatUint
+	}
+
+	return ""
+}
+
+// rawValues gets the raw map of a record. Empty values are left out.
+func rawValues(indexes []rawIndex, values [][]byte) map[string]string {
+	res := make(map[string]string, len(indexes))
+	for _, idx := range indexes {
+		if idx.value >= len(values) || len(values[idx.value]) == 0 {
+			continue
+		}
+
+		res[idx.key] = rawValue(values[idx.value], idx.format)
+	}
+
+	return res
+}
+
+// rawValue formats a value as decoded, which is byte reversed. Values without format are taken as string if they are
+// printable text and as hex string otherwise.
+func rawValue(v []byte, format string) string {
+	if format != "" {
+		return formatValue(v, format)
+	}
+
+	s := formatValue(v, FormatString)
+	if isPrintable(s) {
+		return s
+	}
+
+	return formatValue(v, FormatHex)
+}
+
+func isPrintable(s string) bool {
+	if s == "" || !utf8.ValidString(s) {
+		return false
+	}
+
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+
+	return true
+}
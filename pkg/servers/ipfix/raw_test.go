@@ -0,0 +1,106 @@
+package ipfix
+
+import (
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestGetRawIndexes(t *testing.T) {
+	tmpl := &ipfix.TemplateRecords{
+		Records: []*ipfix.TemplateRecord{
+			{Type: ipfix.L4SrcPort, Length: 2},
+			{Type: 461, Length: ipfix.VariableLength},
+			{Type: 82, Length: ipfix.VariableLength},
+			{Type: paddingOctets, Length: 2},
+			{Type: 0x8000 | 12235, Length: 4},
+			{Type: 0x8000 | 12236, Length: 16},
+		},
+		EnterpriseNumbers: []uint32{0, 0, 0, 0, 9, 9},
+	}
+
+	ipf := &IPFIXServer{
+		raw:         true,
+		extraFields: Fields{{Element: 82, Column: "interface_name", Format: FormatString}}.extraFields(),
+	}
+
+	expected := []rawIndex{
+		{value: 1, key: "461"},
+		{value: 4, key: "9/12235", format: FormatUint},
+		{value: 5, key: "9/12236"},
+	}
+	assert.Equal(t, expected, ipf.getRawIndexes(tmpl, generateFieldMap(tmpl)))
+
+	ipf.raw = false
+	assert.Nil(t, ipf.getRawIndexes(tmpl, generateFieldMap(tmpl)))
+}
+
+func TestRawValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    []byte
+		format   string
+		expected string
+	}{
+		{
+			name: "Number",
+			// values are reversed like the datagram
+			value:    []byte{0x2a, 0x01, 0, 0},
+			format:   FormatUint,
+			expected: "298",
+		},
+		{
+			name:     "Text",
+			value:    []byte("lmth.xedni/"),
+			expected: "/index.html",
+		},
+		{
+			name:     "NUL padded text",
+			value:    []byte("\x00\x00lmth.xedni/"),
+			expected: "/index.html",
+		},
+		{
+			name:     "Binary",
+			value:    []byte{0x02, 0x01, 0x00, 0xff},
+			expected: "ff000102",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, rawValue(test.value, test.format), test.name)
+	}
+}
+
+func TestProcessPacketRaw(t *testing.T) {
+	var flows []*flow.Flow
+	ipf := &IPFIXServer{
+		tmplCache:  newTemplateCache(),
+		ifResolver: testResolver{},
+		raw:        true,
+		output: func(fls []*flow.Flow) {
+			flows = fls
+		},
+	}
+
+	ipf.processPacket(bnet.IPv4FromOctets(192, 0, 2, 254), 0, []byte{
+		0, 10, 0, 42, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1,
+
+		// template 256 with destinationTransportPort and flowActiveTimeout
+		0, 2, 0, 16,
+		1, 0, 0, 2,
+		0, 11, 0, 2,
+		0, 36, 0, 2,
+
+		1, 0, 0, 10,
+		0, 53, 0, 60, 0, 0,
+	})
+
+	if assert.Len(t, flows, 1) {
+		assert.Equal(t, uint16(53), flows[0].DstPort)
+		assert.Equal(t, map[string]string{"36": "60"}, flows[0].Raw)
+	}
+}
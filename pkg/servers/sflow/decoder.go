@@ -0,0 +1,28 @@
+package sflow
+
+import (
+	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+)
+
+// Decoder decodes sflow datagrams that were received elsewhere, e.g. to benchmark the decoder
+type Decoder struct {
+	sfs *SflowServer
+}
+
+// NewDecoder creates a decoder passing each decoded flow to output, which takes ownership of it. Unlike the server
+// it does not aggregate flows.
+func NewDecoder(ifResolver InterfaceResolver, decodeTunnels bool, output func(fl *flow.Flow)) *Decoder {
+	return &Decoder{
+		sfs: &SflowServer{
+			ifResolver:    ifResolver,
+			decodeTunnels: decodeTunnels,
+			ingest:        output,
+		},
+	}
+}
+
+// Decode decodes a datagram sent by agent. The datagram is modified in place.
+func (d *Decoder) Decode(agent bnet.IP, datagram []byte) {
+	d.sfs.processPacket(agent, 0, datagram)
+}
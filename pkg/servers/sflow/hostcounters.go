@@ -0,0 +1,119 @@
+package sflow
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/hostcounter"
+	"github.com/bio-routing/flowhouse/pkg/packet/sflow"
+)
+
+// names of the enumerations of the host sflow structures
+var (
+	machineTypes = []string{"unknown", "other", "x86", "x86_64", "ia64", "sparc", "alpha", "powerpc", "m68k", "mips", "arm", "hppa", "s390"}
+	osNames      = []string{"unknown", "other", "linux", "windows", "darwin", "hpux", "aix", "dragonfly", "freebsd", "netbsd", "openbsd", "osf", "solaris", "java"}
+	virtStates   = []string{"nostate", "running", "blocked", "paused", "shutdown", "shutoff", "crashed"}
+)
+
+// processCounterSamples passes the counter samples carrying host sflow structures to the host output
+func (sfs *SflowServer) processCounterSamples(agentStr string, agent netip.Addr, counterSamples []*sflow.CounterSample, now time.Time) {
+	samples := make([]*hostcounter.Sample, 0, len(counterSamples))
+	for _, cs := range counterSamples {
+		if !cs.HasHostStructures() {
+			continue
+		}
+
+		hostCounterSamplesReceived.WithLabelValues(agentStr).Inc()
+		samples = append(samples, convertCounterSample(agent, cs, now))
+	}
+
+	if len(samples) > 0 {
+		sfs.hostOutput(samples)
+	}
+}
+
+func convertCounterSample(agent netip.Addr, cs *sflow.CounterSample, now time.Time) *hostcounter.Sample {
+	s := &hostcounter.Sample{
+		Timestamp:   now.Truncate(time.Second),
+		Agent:       agent,
+		SourceIndex: cs.CounterSampleHeader.SourceIDClassIndex & sourceIDIndexMask,
+	}
+
+	if hd := cs.HostDescription; hd != nil {
+		s.Hostname = hd.Hostname
+		s.UUID = formatUUID(hd.UUID)
+		s.MachineType = enumName(machineTypes, hd.MachineType)
+		s.OSName = enumName(osNames, hd.OSName)
+		s.OSRelease = hd.OSRelease
+	}
+
+	if hp := cs.HostParent; hp != nil {
+		s.ParentIndex = hp.ContainerIndex
+	}
+
+	if hc := cs.HostCPU; hc != nil {
+		s.LoadOne = hc.LoadOne
+		s.LoadFive = hc.LoadFive
+		s.LoadFifteen = hc.LoadFifteen
+		s.ProcRun = hc.ProcRun
+		s.ProcTotal = hc.ProcTotal
+		s.CPUNum = hc.CPUNum
+		s.CPUSpeed = hc.CPUSpeed
+		s.Uptime = hc.Uptime
+		s.CPUUser = hc.CPUUser
+		s.CPUNice = hc.CPUNice
+		s.CPUSystem = hc.CPUSystem
+		s.CPUIdle = hc.CPUIdle
+		s.CPUWio = hc.CPUWio
+		s.CPUIntr = hc.CPUIntr
+		s.CPUSoftIntr = hc.CPUSoftIntr
+		s.Interrupts = hc.Interrupts
+		s.Contexts = hc.Contexts
+	}
+
+	if hm := cs.HostMemory; hm != nil {
+		s.MemTotal = hm.MemTotal
+		s.MemFree = hm.MemFree
+		s.MemShared = hm.MemShared
+		s.MemBuffers = hm.MemBuffers
+		s.MemCached = hm.MemCached
+		s.SwapTotal = hm.SwapTotal
+		s.SwapFree = hm.SwapFree
+		s.PageIn = hm.PageIn
+		s.PageOut = hm.PageOut
+		s.SwapIn = hm.SwapIn
+		s.SwapOut = hm.SwapOut
+	}
+
+	if vc := cs.VirtCPU; vc != nil {
+		s.VirtState = enumName(virtStates, vc.State)
+		s.VirtCPUTime = vc.CPUTime
+		s.VirtCPUs = vc.NrVirtCPU
+	}
+
+	if vm := cs.VirtMemory; vm != nil {
+		s.VirtMemory = vm.Memory
+		s.VirtMaxMemory = vm.MaxMemory
+	}
+
+	return s
+}
+
+// enumName names value of an enumeration, values without a name are formatted as number
+func enumName(names []string, value uint32) string {
+	if value < uint32(len(names)) {
+		return names[value]
+	}
+
+	return fmt.Sprintf("%d", value)
+}
+
+// formatUUID formats a UUID in its canonical form, the unset UUID as empty string
+func formatUUID(uuid [16]byte) string {
+	if uuid == [16]byte{} {
+		return ""
+	}
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
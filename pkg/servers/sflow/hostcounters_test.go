@@ -0,0 +1,144 @@
+package sflow
+
+import (
+	"encoding/binary"
+	"math"
+	"net/netip"
+	"testing"
+	"time"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/models/hostcounter"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCounterDatagram encodes a v5 datagram of a host counter sample of web01 and a virtual machine counter sample
+// of vm1 on it, followed by an interface counter sample of et7
+func testCounterDatagram() []byte {
+	words := func(values ...uint32) []byte {
+		var b []byte
+		for _, v := range values {
+			b = binary.BigEndian.AppendUint32(b, v)
+		}
+		return b
+	}
+	record := func(sfType uint32, data ...[]byte) []byte {
+		var body []byte
+		for _, d := range data {
+			body = append(body, d...)
+		}
+		return append(words(sfType, uint32(len(body))), body...)
+	}
+
+	uuid := []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 1, 2, 3, 4, 5, 6, 7, 8}
+	host := record(2,
+		words(1, 1, 3),
+		record(2000, words(5), []byte("web01\x00\x00\x00"), uuid, words(3, 2, 3), []byte("6.1\x00")),
+		record(2003, words(math.Float32bits(0.5), math.Float32bits(1), math.Float32bits(2), 3, 400, 8, 2400, 86400,
+			1000, 10, 500, 90000, 20, 30, 40, 123456, 654321)),
+		record(2004, words(4, 0, 2, 0, 0, 1<<20, 0, 2<<20, 1, 0, 1, 0, 0, 1<<30, 10, 20, 30, 40)),
+	)
+	vm := record(4,
+		words(2, 3, 100005, 4),
+		record(2000, words(3), []byte("vm1\x00"), make([]byte, 16), words(3, 2, 0)),
+		record(2002, words(2, 1)),
+		record(2101, words(1, 5000, 2)),
+		record(2102, words(0, 1<<30, 0, 2<<30)),
+	)
+	ifCounters := record(2, words(3, 7, 1), ifCountersRecord(7, 0, 0))
+
+	res := words(5, 1, 0xc0000201, 0, 1, 100, 3)
+	res = append(res, host...)
+	res = append(res, vm...)
+	return append(res, ifCounters...)
+}
+
+func TestProcessPacketHostCounters(t *testing.T) {
+	var samples []*hostcounter.Sample
+	sfs := &SflowServer{
+		ifResolver: testResolver{},
+		ingest:     func(fl *flow.Flow) {},
+		hostOutput: func(s []*hostcounter.Sample) {
+			samples = append(samples, s...)
+		},
+	}
+
+	sfs.processPacket(bnet.IPv4FromOctets(192, 0, 2, 254), 0, testCounterDatagram())
+	if !assert.Len(t, samples, 2) {
+		return
+	}
+
+	agent := netip.MustParseAddr("192.0.2.254")
+	assert.WithinDuration(t, time.Now(), samples[0].Timestamp, time.Minute)
+	assert.Equal(t, &hostcounter.Sample{
+		Timestamp:   samples[0].Timestamp,
+		Agent:       agent,
+		SourceIndex: 1,
+		Hostname:    "web01",
+		UUID:        "12345678-9abc-def0-0102-030405060708",
+		MachineType: "x86_64",
+		OSName:      "linux",
+		OSRelease:   "6.1",
+		LoadOne:     0.5,
+		LoadFive:    1,
+		LoadFifteen: 2,
+		ProcRun:     3,
+		ProcTotal:   400,
+		CPUNum:      8,
+		CPUSpeed:    2400,
+		Uptime:      86400,
+		CPUUser:     1000,
+		CPUNice:     10,
+		CPUSystem:   500,
+		CPUIdle:     90000,
+		CPUWio:      20,
+		CPUIntr:     30,
+		CPUSoftIntr: 40,
+		Interrupts:  123456,
+		Contexts:    654321,
+		MemTotal:    4 << 32,
+		MemFree:     2 << 32,
+		MemShared:   1 << 20,
+		MemBuffers:  2 << 20,
+		MemCached:   1 << 32,
+		SwapTotal:   1 << 32,
+		SwapFree:    1 << 30,
+		PageIn:      10,
+		PageOut:     20,
+		SwapIn:      30,
+		SwapOut:     40,
+	}, samples[0])
+
+	assert.Equal(t, &hostcounter.Sample{
+		Timestamp:     samples[1].Timestamp,
+		Agent:         agent,
+		SourceIndex:   100005,
+		ParentIndex:   1,
+		Hostname:      "vm1",
+		MachineType:   "x86_64",
+		OSName:        "linux",
+		VirtState:     "running",
+		VirtCPUTime:   5000,
+		VirtCPUs:      2,
+		VirtMemory:    1 << 30,
+		VirtMaxMemory: 2 << 30,
+	}, samples[1])
+}
+
+func TestProcessPacketHostCountersDisabled(t *testing.T) {
+	sfs := &SflowServer{
+		ifResolver: testResolver{},
+		ingest:     func(fl *flow.Flow) {},
+	}
+
+	assert.NotPanics(t, func() {
+		sfs.processPacket(bnet.IPv4FromOctets(192, 0, 2, 254), 0, testCounterDatagram())
+	})
+}
+
+func TestEnumName(t *testing.T) {
+	assert.Equal(t, "linux", enumName(osNames, 2))
+	assert.Equal(t, "java", enumName(osNames, 13))
+	assert.Equal(t, "14", enumName(osNames, 14))
+}
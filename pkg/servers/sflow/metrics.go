@@ -0,0 +1,120 @@
+package sflow
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metrics are shared by all servers so a server can be replaced on reload without registering them again
+var labels = []string{"agent"}
+
+var (
+	packetsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "received_packets",
+		Help:      "Received sflow packets",
+	}, labels)
+	decodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "decode_errors",
+		Help:      "Datagrams and samples failing to decode by kind of error",
+	}, []string{"agent", "kind"})
+	flowSamplesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_received",
+		Help:      "Flow samples received",
+	}, labels)
+	flowNoRawPktHeader = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_no_raw_pkt_header",
+		Help:      "Flow samples without raw packet header",
+	}, labels)
+	flowNoData = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_no_data",
+		Help:      "Flow samples without data",
+	}, labels)
+	flowUnknownProtocol = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_unknown_protocol",
+		Help:      "Flow samples unknown protocol",
+	}, labels)
+	flowEthernetDecodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_ethernet_decode_errors",
+		Help:      "Flow samples ethernet decode errors",
+	}, labels)
+	flowUnknownEtherType = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_unknown_ether_type",
+		Help:      "Flow samples unknown ether type",
+	}, labels)
+	flowDot1qDecodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_dot1q_decode_errors",
+		Help:      "Flow samples Dot1Q decode errors",
+	}, labels)
+	flowIPv4DecodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_ipv4_decode_errors",
+		Help:      "Flow samples IPv4 decode errors",
+	}, labels)
+	flowIPv6DecodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_ipv6_decode_errors",
+		Help:      "Flow samples IPv6 decode errors",
+	}, labels)
+	flowTCPDecodeErros = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_tcp_decode_errors",
+		Help:      "Flow samples TCP decode errors",
+	}, labels)
+	flowUDPDecodeErros = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_udp_decode_errors",
+		Help:      "Flow samples UDP decode errors",
+	}, labels)
+	flowICMPDecodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_icmp_decode_errors",
+		Help:      "Flow samples ICMP decode errors",
+	}, labels)
+	flowTunnelDecodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "flow_samples_tunnel_decode_errors",
+		Help:      "Flow samples tunnel (VXLAN, Geneve, GRE) decode errors",
+	}, labels)
+	flowsDecoded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "decoded_flows",
+		Help:      "Flows decoded from flow samples",
+	}, labels)
+	decodePanics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "decode_panics",
+		Help:      "Packets dropped as processing them panicked",
+	}, labels)
+	hostCounterSamplesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "host_counter_samples_received",
+		Help:      "Counter samples with host sflow structures received",
+	}, labels)
+)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/netip"
 	"runtime/debug"
 	"sync"
 	"time"
@@ -12,23 +13,32 @@ import (
 
 	"github.com/bio-routing/tflow2/convert"
 	"github.com/pkg/errors"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/decodelog"
 	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/models/hostcounter"
 	"github.com/bio-routing/flowhouse/pkg/packet/packet"
 	"github.com/bio-routing/flowhouse/pkg/packet/sflow"
-	log "github.com/sirupsen/logrus"
+	"github.com/bio-routing/flowhouse/pkg/ratelimit"
+	"github.com/bio-routing/flowhouse/pkg/relay"
+	"github.com/bio-routing/flowhouse/pkg/samplingreport"
+	"github.com/bio-routing/flowhouse/pkg/servers/aggregator"
 )
 
-var labels []string
+const (
+	// sourceIDIndexMask masks the index part of an sflow data source ID
+	sourceIDIndexMask = 0x00ffffff
 
-func init() {
-	labels = []string{
-		"agent",
-	}
-}
+	// header protocols of sampled headers
+	headerProtocolEthernet = 1
+	headerProtocolIPv4     = 11
+	headerProtocolIPv6     = 12
+
+	// interface formats in the upper two bits of interfaces given by flow samples
+	ifFormatDiscarded = 1
+	ifFormatMultiple  = 2
+)
 
 type InterfaceResolver interface {
 	Resolve(agent bnet.IP, ifID uint32) string
@@ -36,104 +46,37 @@ type InterfaceResolver interface {
 
 // SflowServer represents a sflow Collector instance
 type SflowServer struct {
-	aggregator               *aggregator
-	conn                     *net.UDPConn
-	ifResolver               InterfaceResolver
-	wg                       sync.WaitGroup
-	stopCh                   chan struct{}
-	packetsReceived          *prometheus.CounterVec
-	flowSamplesReceived      *prometheus.CounterVec
-	flowNoRawPktHeader       *prometheus.CounterVec
-	flowNoData               *prometheus.CounterVec
-	flowUnknownProtocol      *prometheus.CounterVec
-	flowEthernetDecodeErrors *prometheus.CounterVec
-	flowUnknownEtherType     *prometheus.CounterVec
-	flowDot1qDecodeErrors    *prometheus.CounterVec
-	flowIPv4DecodeErrors     *prometheus.CounterVec
-	flowIPv6DecodeErrors     *prometheus.CounterVec
-	flowTCPDecodeErros       *prometheus.CounterVec
-	flowUDPDecodeErros       *prometheus.CounterVec
+	aggregator    *aggregator.Aggregator
+	ingest        func(fl *flow.Flow)
+	hostOutput    func(samples []*hostcounter.Sample)
+	conn          *net.UDPConn
+	ifResolver    InterfaceResolver
+	decodeTunnels bool
+	relay         *relay.Relay
+	decodeLog     *decodelog.Log
+	sampling      *samplingreport.Report
+	limiter       *ratelimit.Limiter
+	wg            sync.WaitGroup
+	stopCh        chan struct{}
 }
 
-// New creates and starts a new `SflowServer` instance
-func New(listen string, numReaders int, output chan []*flow.Flow, ifResolver InterfaceResolver) (*SflowServer, error) {
+// New creates and starts a new `SflowServer` instance. If hostOutput is not nil host sflow counter samples are passed
+// to it. If r is not nil received datagrams are forwarded to it. Datagrams failing to decode are recorded in dl. If sr
+// is not nil flow samples and interface counters are accounted in it. If lim is not nil datagrams of agents beyond
+// their limit are dropped.
+func New(listen string, numReaders int, output func(flows []*flow.Flow), hostOutput func(samples []*hostcounter.Sample), ifResolver InterfaceResolver, decodeTunnels bool, r *relay.Relay, dl *decodelog.Log, sr *samplingreport.Report, lim *ratelimit.Limiter) (*SflowServer, error) {
 	sfs := &SflowServer{
-		aggregator: newAggregator(output),
-		ifResolver: ifResolver,
-		packetsReceived: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "received_packets",
-			Help:      "Received sflow packets",
-		}, labels),
-		flowSamplesReceived: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "flow_samples_received",
-			Help:      "Flow samples received",
-		}, labels),
-		flowNoRawPktHeader: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "flow_samples_no_raw_pkt_header",
-			Help:      "Flow samples without raw packet header",
-		}, labels),
-		flowNoData: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "flow_no_data",
-			Help:      "Flow samples without data",
-		}, labels),
-		flowUnknownProtocol: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "flow_samples_unknown_protocol",
-			Help:      "Flow samples unknown protocol",
-		}, labels),
-		flowEthernetDecodeErrors: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "flow_samples_ethernet_decode_errors",
-			Help:      "Flow samples ethernet decode errors",
-		}, labels),
-		flowUnknownEtherType: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "flow_samples_unknown_ether_type",
-			Help:      "Flow samples unknown ether type",
-		}, labels),
-		flowDot1qDecodeErrors: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "flow_samples_dot1q_decode_errors",
-			Help:      "Flow samples Dot1Q decode errors",
-		}, labels),
-		flowIPv4DecodeErrors: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "flow_samples_ipv4_decode_errors",
-			Help:      "Flow samples IPv4 decode errors",
-		}, labels),
-		flowIPv6DecodeErrors: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "flow_samples_ipv6_decode_errors",
-			Help:      "Flow samples IPv6 decode errors",
-		}, labels),
-		flowTCPDecodeErros: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "flow_samples_tcp_decode_errors",
-			Help:      "Flow samples TCP decode errors",
-		}, labels),
-		flowUDPDecodeErros: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: "flowhouse",
-			Subsystem: "sflow",
-			Name:      "flow_samples_udp_decode_errors",
-			Help:      "Flow samples UDP decode errors",
-		}, labels),
-		stopCh: make(chan struct{}),
+		aggregator:    aggregator.New(output),
+		hostOutput:    hostOutput,
+		ifResolver:    ifResolver,
+		decodeTunnels: decodeTunnels,
+		relay:         r,
+		decodeLog:     dl,
+		sampling:      sr,
+		limiter:       lim,
+		stopCh:        make(chan struct{}),
 	}
+	sfs.ingest = sfs.aggregator.Ingest
 
 	addr, err := net.ResolveUDPAddr("udp", listen)
 	if err != nil {
@@ -168,7 +111,7 @@ func (sfs *SflowServer) Stop() {
 	log.Info("Stopping SflowServer")
 	debug.PrintStack()
 	close(sfs.stopCh)
-	sfs.aggregator.stop()
+	sfs.aggregator.Stop()
 	sfs.conn.Close()
 	sfs.wg.Wait()
 }
@@ -200,11 +143,33 @@ func (sfs *SflowServer) packetWorker() error {
 			return errors.Wrapf(err, "Unable to convert net.IP to bnet.IP: %q", remote)
 		}
 
-		sfs.packetsReceived.WithLabelValues(remoteAddr.String()).Inc()
-		sfs.processPacket(remoteAddr, buffer[:length])
+		packetsReceived.WithLabelValues(remoteAddr.String()).Inc()
+
+		// the decoder works in place, so datagrams have to be forwarded before they are processed
+		if sfs.relay != nil {
+			sfs.relay.Forward(flow.AddrFromBNet(remoteAddr), buffer[:length])
+		}
+
+		if sfs.limiter != nil && !sfs.limiter.AllowDatagram(flow.AddrFromBNet(remoteAddr)) {
+			continue
+		}
+
+		sfs.processPacketSafely(remoteAddr, uint16(remote.Port), buffer[:length])
 	}
 }
 
+// processPacketSafely processes a packet and recovers from panics on malformed packets, so they cannot stop the worker
+func (sfs *SflowServer) processPacketSafely(agent bnet.IP, srcPort uint16, buffer []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			decodePanics.WithLabelValues(agent.String()).Inc()
+			log.WithField("agent", agent.String()).Errorf("Recovered from panic processing sflow packet: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	sfs.processPacket(agent, srcPort, buffer)
+}
+
 func (sfs *SflowServer) stopped() bool {
 	select {
 	case <-sfs.stopCh:
@@ -215,158 +180,301 @@ func (sfs *SflowServer) stopped() bool {
 }
 
 // processPacket takes a raw sflow packet, send it to the decoder and passes the decoded packet to the aggregator
-func (sfs *SflowServer) processPacket(agent bnet.IP, buffer []byte) {
+func (sfs *SflowServer) processPacket(agent bnet.IP, srcPort uint16, buffer []byte) {
 	agentStr := agent.String()
+	agentAddr := flow.AddrFromBNet(agent)
 
 	p, err := sflow.Decode(buffer)
 	if err != nil {
-		log.WithError(err).Error("Unable to decode sflow packet")
+		decodeErrors.WithLabelValues(agentStr, sflow.ErrorKind(err)).Inc()
+		log.WithError(err).WithField("agent", agentStr).Error("Unable to decode sflow packet")
+		if sfs.decodeLog != nil {
+			// the decoder reverses the buffer in place
+			sfs.decodeLog.Add(decodelog.ProtocolSFlow, agent, srcPort, convert.Reverse(buffer), err)
+		}
 		return
 	}
 
+	for _, err := range p.Errors {
+		decodeErrors.WithLabelValues(agentStr, sflow.ErrorKind(err)).Inc()
+		log.WithError(err).WithField("agent", agentStr).Debug("Skipped sflow sample")
+	}
+
+	now := time.Now()
+	if sfs.hostOutput != nil {
+		sfs.processCounterSamples(agentStr, agentAddr, p.CounterSamples, now)
+	}
+
+	if sfs.sampling != nil {
+		sfs.reportInterfaceCounters(agent, agentAddr, p.CounterSamples, now)
+	}
+
 	for _, fs := range p.FlowSamples {
-		sfs.flowSamplesReceived.WithLabelValues(agentStr).Inc()
+		flowSamplesReceived.WithLabelValues(agentStr).Inc()
+		if sfs.sampling != nil {
+			sfs.reportSample(agentAddr, fs)
+		}
 
 		if fs.RawPacketHeader == nil {
-			sfs.flowNoRawPktHeader.WithLabelValues(agentStr).Inc()
+			flowNoRawPktHeader.WithLabelValues(agentStr).Inc()
 			continue
 		}
 
 		if fs.Data == nil {
-			sfs.flowNoData.WithLabelValues(agentStr).Inc()
+			flowNoData.WithLabelValues(agentStr).Inc()
 			continue
 		}
 
-		if fs.RawPacketHeader.HeaderProtocol != 1 {
-			sfs.flowUnknownProtocol.WithLabelValues(agentStr).Inc()
+		headerProtocol := fs.RawPacketHeader.HeaderProtocol
+		if headerProtocol != headerProtocolEthernet && headerProtocol != headerProtocolIPv4 && headerProtocol != headerProtocolIPv6 {
+			flowUnknownProtocol.WithLabelValues(agentStr).Inc()
 			continue
 		}
 
-		ether, err := packet.DecodeEthernet(fs.Data, fs.RawPacketHeader.OriginalPacketLength)
-		if err != nil {
-			sfs.flowEthernetDecodeErrors.WithLabelValues(agentStr).Inc()
-			log.WithError(err).Debug("Unable to decode ethernet packet")
-			continue
+		// routers may sample headers starting at the IP header
+		var ether *packet.EthernetHeader
+		if headerProtocol == headerProtocolEthernet {
+			ether, err = packet.DecodeEthernet(fs.Data, fs.DataLen)
+			if err != nil {
+				flowEthernetDecodeErrors.WithLabelValues(agentStr).Inc()
+				log.WithError(err).Debug("Unable to decode ethernet packet")
+				continue
+			}
+			fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfEthernetII)
+			fs.DataLen -= uint32(packet.SizeOfEthernetII)
 		}
-		fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfEthernetII)
-		fs.DataLen -= uint32(packet.SizeOfEthernetII)
 
-		fl := &flow.Flow{
-			Agent:      agent,
-			IntIn:      sfs.ifResolver.Resolve(agent, fs.FlowSampleHeader.InputIf),
-			IntOut:     sfs.ifResolver.Resolve(agent, fs.FlowSampleHeader.OutputIf),
+		fl := flow.New()
+		*fl = flow.Flow{
+			Agent:      agentAddr,
+			IntIn:      sfs.interfaceName(agent, fs.FlowSampleHeader.InputIf),
+			IntOut:     sfs.interfaceName(agent, fs.FlowSampleHeader.OutputIf),
 			Size:       uint64(fs.RawPacketHeader.FrameLength),
 			Packets:    1,
-			Timestamp:  time.Now().Unix(),
+			Timestamp:  now.Unix(),
 			Samplerate: uint64(fs.FlowSampleHeader.SamplingRate),
-		}
+			FlowStart:  now.UnixMilli(),
+			FlowEnd:    now.UnixMilli(),
 
-		if fl.IntIn == "" {
-			fl.IntIn += fmt.Sprintf("%d", fs.FlowSampleHeader.InputIf)
+			Direction:           getDirection(fs.FlowSampleHeader),
+			ObservationDomainID: p.Header.SubAgentID,
+			ObservationPointID:  uint64(fs.FlowSampleHeader.SourceIDClassIndex & sourceIDIndexMask),
+			ExportProtocol:      flow.ExportProtocolSFlow,
 		}
 
-		if fl.IntOut == "" {
-			fl.IntOut += fmt.Sprintf("%d", fs.FlowSampleHeader.OutputIf)
+		if ether != nil {
+			fl.SrcMAC = macToUint64(ether.SrcMAC)
+			fl.DstMAC = macToUint64(ether.DstMAC)
 		}
 
 		if fs.ExtendedRouterData != nil {
-			nh, err := bnet.IPFromBytes([]byte(fs.ExtendedRouterData.NextHop))
-			if err == nil {
-				fl.NextHop = nh
-			}
+			fl.NextHop = flow.AddrFromBytes([]byte(fs.ExtendedRouterData.NextHop))
 		}
 
 		if fs.ExtendedSwitchData != nil {
+			fl.SrcVLAN = uint16(fs.ExtendedSwitchData.IncomingVLAN)
+			fl.DstVLAN = uint16(fs.ExtendedSwitchData.OutgoingVLAN)
 			fl.IntIn += fmt.Sprintf(".%d", fs.ExtendedSwitchData.IncomingVLAN)
 			fl.IntOut += fmt.Sprintf(".%d", fs.ExtendedSwitchData.OutgoingVLAN)
 		}
 
-		sfs.processEthernet(agentStr, ether.EtherType, fs, fl)
-		sfs.aggregator.ingress <- fl
+		switch headerProtocol {
+		case headerProtocolEthernet:
+			sfs.processEthernet(agentStr, ether.EtherType, fs, fl, sfs.decodeTunnels)
+		case headerProtocolIPv4:
+			sfs.processIPv4Packet(agentStr, fs, fl, sfs.decodeTunnels)
+		case headerProtocolIPv6:
+			sfs.processIPv6Packet(agentStr, fs, fl, sfs.decodeTunnels)
+		}
+		flowsDecoded.WithLabelValues(agentStr).Inc()
+		sfs.ingest(fl)
 	}
 }
 
-func (sfs *SflowServer) processEthernet(agentStr string, ethType uint16, fs *sflow.FlowSample, fl *flow.Flow) {
+// processEthernet dispatches the payload by ethType. If decapsulate is set, tunneled packets are decoded as well.
+func (sfs *SflowServer) processEthernet(agentStr string, ethType uint16, fs *sflow.FlowSample, fl *flow.Flow, decapsulate bool) {
 	if ethType == packet.EtherTypeIPv4 {
-		sfs.processIPv4Packet(agentStr, fs, fl)
+		sfs.processIPv4Packet(agentStr, fs, fl, decapsulate)
 	} else if ethType == packet.EtherTypeIPv6 {
-		sfs.processIPv6Packet(agentStr, fs, fl)
+		sfs.processIPv6Packet(agentStr, fs, fl, decapsulate)
 	} else if ethType == packet.EtherTypeARP || ethType == packet.EtherTypeLACP {
 		return
 	} else if ethType == packet.EtherTypeIEEE8021Q {
-		sfs.processDot1QPacket(agentStr, fs, fl)
+		sfs.processDot1QPacket(agentStr, fs, fl, decapsulate)
 	} else {
-		sfs.flowUnknownEtherType.WithLabelValues(agentStr).Inc()
+		flowUnknownEtherType.WithLabelValues(agentStr).Inc()
 		log.Debugf("Unknown EtherType: 0x%x", ethType)
 	}
 }
 
-func (sfs *SflowServer) processDot1QPacket(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) {
+func (sfs *SflowServer) processDot1QPacket(agentStr string, fs *sflow.FlowSample, fl *flow.Flow, decapsulate bool) {
 	dot1q, err := packet.DecodeDot1Q(fs.Data, fs.DataLen)
 	if err != nil {
-		sfs.flowDot1qDecodeErrors.WithLabelValues(agentStr).Inc()
+		flowDot1qDecodeErrors.WithLabelValues(agentStr).Inc()
 		log.WithError(err).Debug("Unable to decode dot1q header")
+		return
 	}
 	fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfDot1Q)
 	fs.DataLen -= uint32(packet.SizeOfDot1Q)
 
-	sfs.processEthernet(agentStr, dot1q.EtherType, fs, fl)
+	// Without extended switch data the outer most VLAN tag is the best guess for the ingress VLAN
+	if fl.SrcVLAN == 0 {
+		fl.SrcVLAN = dot1q.TCI & 0x0fff
+	}
+
+	sfs.processEthernet(agentStr, dot1q.EtherType, fs, fl, decapsulate)
 }
 
-func (sfs *SflowServer) processIPv4Packet(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) {
+func (sfs *SflowServer) processIPv4Packet(agentStr string, fs *sflow.FlowSample, fl *flow.Flow, decapsulate bool) {
 	fl.Family = 4
 	ipv4, err := packet.DecodeIPv4(fs.Data, fs.DataLen)
 	if err != nil {
-		sfs.flowIPv4DecodeErrors.WithLabelValues(agentStr).Inc()
+		flowIPv4DecodeErrors.WithLabelValues(agentStr).Inc()
 		log.WithError(err).Debug("Unable to decode IPv4 packet")
+		return
 	}
-	fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfIPv4Header)
-	fs.DataLen -= uint32(packet.SizeOfIPv4Header)
 
-	fl.SrcAddr, _ = bnet.IPFromBytes(convert.Reverse(ipv4.SrcAddr[:]))
-	fl.DstAddr, _ = bnet.IPFromBytes(convert.Reverse(ipv4.DstAddr[:]))
+	fl.SrcAddr = flow.AddrFromBytes(convert.Reverse(ipv4.SrcAddr[:]))
+	fl.DstAddr = flow.AddrFromBytes(convert.Reverse(ipv4.DstAddr[:]))
 	fl.Protocol = uint8(ipv4.Protocol)
-	switch ipv4.Protocol {
-	case packet.TCP:
-		if err := getTCP(fs.Data, fs.DataLen, fl); err != nil {
-			sfs.flowTCPDecodeErros.WithLabelValues(agentStr).Inc()
-			log.WithError(err).Debug("Unable to decode TCP")
-		}
-	case packet.UDP:
-		if err := getUDP(fs.Data, fs.DataLen, fl); err != nil {
-			sfs.flowUDPDecodeErros.WithLabelValues(agentStr).Inc()
-			log.WithError(err).Debug("Unable to decode UDP")
-		}
+	fl.DSCP = ipv4.DSCP >> 2
+
+	// the transport header follows the options and is only in the first fragment
+	headerLength := uint32(ipv4.VersionHeaderLength&0x0f) * 4
+	if headerLength < uint32(packet.SizeOfIPv4Header) || skip(fs, headerLength) != nil {
+		flowIPv4DecodeErrors.WithLabelValues(agentStr).Inc()
+		log.Debugf("Invalid IPv4 header length %d", headerLength)
+		return
+	}
+
+	if ipv4.FlagsFragmentOffset&0x1fff != 0 {
+		return
 	}
+
+	sfs.processTransport(agentStr, fs, fl, decapsulate)
 }
 
-func (sfs *SflowServer) processIPv6Packet(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) {
+func (sfs *SflowServer) processIPv6Packet(agentStr string, fs *sflow.FlowSample, fl *flow.Flow, decapsulate bool) {
 	fl.Family = 6
 	ipv6, err := packet.DecodeIPv6(fs.Data, fs.DataLen)
 	if err != nil {
-		sfs.flowIPv6DecodeErrors.WithLabelValues(agentStr).Inc()
+		flowIPv6DecodeErrors.WithLabelValues(agentStr).Inc()
 		log.WithError(err).Debug("Unable to decode IPv6 packet")
+		return
 	}
 	fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfIPv6Header)
 	fs.DataLen -= uint32(packet.SizeOfIPv6Header)
 
-	fl.SrcAddr, _ = bnet.IPFromBytes(convert.Reverse(ipv6.SrcAddr[:]))
-	fl.DstAddr, _ = bnet.IPFromBytes(convert.Reverse(ipv6.DstAddr[:]))
+	fl.SrcAddr = flow.AddrFromBytes(convert.Reverse(ipv6.SrcAddr[:]))
+	fl.DstAddr = flow.AddrFromBytes(convert.Reverse(ipv6.DstAddr[:]))
 	fl.Protocol = uint8(ipv6.NextHeader)
-	switch ipv6.NextHeader {
+	fl.DSCP = uint8(ipv6.VersionTrafficClassFlowLabel>>20) >> 2
+	sfs.processTransport(agentStr, fs, fl, decapsulate)
+}
+
+func (sfs *SflowServer) processTransport(agentStr string, fs *sflow.FlowSample, fl *flow.Flow, decapsulate bool) {
+	switch fl.Protocol {
 	case packet.TCP:
 		if err := getTCP(fs.Data, fs.DataLen, fl); err != nil {
-			sfs.flowTCPDecodeErros.WithLabelValues(agentStr).Inc()
+			flowTCPDecodeErros.WithLabelValues(agentStr).Inc()
 			log.WithError(err).Debug("Unable to decode TCP")
 		}
 	case packet.UDP:
 		if err := getUDP(fs.Data, fs.DataLen, fl); err != nil {
-			sfs.flowUDPDecodeErros.WithLabelValues(agentStr).Inc()
+			flowUDPDecodeErros.WithLabelValues(agentStr).Inc()
 			log.WithError(err).Debug("Unable to decode UDP")
 		}
+	case packet.ICMP, packet.ICMPv6:
+		if err := getICMP(fs.Data, fs.DataLen, fl); err != nil {
+			flowICMPDecodeErrors.WithLabelValues(agentStr).Inc()
+			log.WithError(err).Debug("Unable to decode ICMP")
+		}
+	}
+
+	if decapsulate {
+		if err := sfs.processTunnel(agentStr, fs, fl); err != nil {
+			flowTunnelDecodeErrors.WithLabelValues(agentStr).Inc()
+			log.WithError(err).Debug("Unable to decode tunnel")
+		}
 	}
 }
 
+// interfaceName resolves an interface of a flow sample. Samples of discarded packets or packets sent out of multiple
+// interfaces give no interface but the reason or number of interfaces, they are named by their format instead.
+func (sfs *SflowServer) interfaceName(agent bnet.IP, ifValue uint32) string {
+	switch ifValue >> 30 {
+	case ifFormatDiscarded:
+		return "discarded"
+	case ifFormatMultiple:
+		return "multiple"
+	}
+
+	name := sfs.ifResolver.Resolve(agent, ifValue)
+	if name == "" {
+		return fmt.Sprintf("%d", ifValue)
+	}
+
+	return name
+}
+
+// reportSample accounts a flow sample taken on an interface of the sampled packet in the sampling report
+func (sfs *SflowServer) reportSample(agent netip.Addr, fs *sflow.FlowSample) {
+	direction := getDirection(fs.FlowSampleHeader)
+	if direction == "" {
+		return
+	}
+
+	frameLength := uint32(0)
+	if fs.RawPacketHeader != nil {
+		frameLength = fs.RawPacketHeader.FrameLength
+	}
+
+	sourceIndex := fs.FlowSampleHeader.SourceIDClassIndex & sourceIDIndexMask
+	sfs.sampling.AddSample(agent, sourceIndex, direction, fs.FlowSampleHeader.SamplingRate, frameLength)
+}
+
+// reportInterfaceCounters accounts the generic interface counters of counter samples in the sampling report
+func (sfs *SflowServer) reportInterfaceCounters(agent bnet.IP, agentAddr netip.Addr, counterSamples []*sflow.CounterSample, now time.Time) {
+	for _, cs := range counterSamples {
+		gic := cs.GenericInterfaceCounters
+		if gic == nil {
+			continue
+		}
+
+		sfs.sampling.AddCounters(agentAddr, &samplingreport.Counters{
+			IfIndex:    gic.IfIndex,
+			Name:       sfs.interfaceName(agent, gic.IfIndex),
+			InOctets:   gic.IfInOctets,
+			InPackets:  gic.IfInUcastPkts + gic.IfInMulticastPkts + gic.IfInBroadcastPkts,
+			OutOctets:  gic.IfOutOctets,
+			OutPackets: gic.IfOutUcastPkts + gic.IfOutMulticastPkts + gic.IfOutBroadcastPkts,
+		}, now)
+	}
+}
+
+// getDirection derives the flows direction from the data source the sample was taken on
+func getDirection(fsh *sflow.FlowSampleHeader) string {
+	sourceIndex := fsh.SourceIDClassIndex & sourceIDIndexMask
+	if sourceIndex == fsh.InputIf {
+		return flow.DirectionIngress
+	}
+
+	if sourceIndex == fsh.OutputIf {
+		return flow.DirectionEgress
+	}
+
+	return ""
+}
+
+func macToUint64(mac net.HardwareAddr) uint64 {
+	ret := uint64(0)
+	for _, b := range mac {
+		ret = ret<<8 | uint64(b)
+	}
+
+	return ret
+}
+
 func getUDP(udpPtr unsafe.Pointer, length uint32, fl *flow.Flow) error {
 	udp, err := packet.DecodeUDP(udpPtr, length)
 	if err != nil {
@@ -387,6 +495,19 @@ func getTCP(tcpPtr unsafe.Pointer, length uint32, fl *flow.Flow) error {
 
 	fl.SrcPort = tcp.SrcPort
 	fl.DstPort = tcp.DstPort
+	fl.TCPFlags = tcp.Flags
+
+	return nil
+}
+
+func getICMP(icmpPtr unsafe.Pointer, length uint32, fl *flow.Flow) error {
+	icmp, err := packet.DecodeICMP(icmpPtr, length)
+	if err != nil {
+		return errors.Wrap(err, "Unable to decode ICMP message")
+	}
+
+	fl.ICMPType = icmp.Type
+	fl.ICMPCode = icmp.Code
 
 	return nil
 }
@@ -395,14 +516,14 @@ func getTCP(tcpPtr unsafe.Pointer, length uint32, fl *flow.Flow) error {
 func Dump(fl *flow.Flow) {
 	fmt.Printf("--------------------------------\n")
 	fmt.Printf("Flow dump:\n")
-	fmt.Printf("Agent: %d\n", fl.Agent)
+	fmt.Printf("Agent: %s\n", fl.Agent.String())
 	fmt.Printf("Family: %d\n", fl.Family)
 	fmt.Printf("SrcAddr: %s\n", fl.SrcAddr.String())
 	fmt.Printf("DstAddr: %s\n", fl.DstAddr.String())
 	fmt.Printf("Protocol: %d\n", fl.Protocol)
 	fmt.Printf("NextHop: %s\n", fl.NextHop.String())
-	fmt.Printf("IntIn: %d\n", fl.IntIn)
-	fmt.Printf("IntOut: %d\n", fl.IntOut)
+	fmt.Printf("IntIn: %s\n", fl.IntIn)
+	fmt.Printf("IntOut: %s\n", fl.IntOut)
 	fmt.Printf("Packets: %d\n", fl.Packets)
 	fmt.Printf("Bytes: %d\n", fl.Size)
 	fmt.Printf("--------------------------------\n")
@@ -0,0 +1,178 @@
+package sflow
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/models/hostcounter"
+	"github.com/bio-routing/flowhouse/pkg/samplingreport"
+	"github.com/stretchr/testify/assert"
+)
+
+type testResolver struct{}
+
+func (r testResolver) Resolve(agent bnet.IP, ifID uint32) string {
+	if ifID == 7 {
+		return "et7"
+	}
+
+	return ""
+}
+
+// testDatagram encodes a v5 datagram of a single expanded flow sample with a raw packet header record
+func testDatagram(headerProtocol uint32, inputIf uint32, outputIf uint32, header []byte) []byte {
+	words := func(b []byte, values ...uint32) []byte {
+		for _, v := range values {
+			b = binary.BigEndian.AppendUint32(b, v)
+		}
+		return b
+	}
+
+	rec := words(nil, 1, uint32(16+len(header)), headerProtocol, 1500, 0, uint32(len(header)))
+	rec = append(rec, header...)
+
+	sample := words(nil, 2, 0, 7, 1000, 0, 0, inputIf>>30, inputIf&0x3fffffff, outputIf>>30, outputIf&0x3fffffff, 1)
+	sample = append(sample, rec...)
+
+	res := words(nil, 5, 1, 0xc0000201, 0, 1, 100, 1, 3, uint32(len(sample)))
+	return append(res, sample...)
+}
+
+// ifCountersRecord encodes generic interface counters of interface ifIndex
+func ifCountersRecord(ifIndex uint32, inOctets uint64, inUcastPkts uint32) []byte {
+	var b []byte
+	for _, v := range []uint32{1, 88, ifIndex, 6, 0, 1000000000, 1, 3} {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	b = binary.BigEndian.AppendUint64(b, inOctets)
+	b = binary.BigEndian.AppendUint32(b, inUcastPkts)
+	return append(b, make([]byte, 52)...)
+}
+
+// testIfCountersDatagram encodes a v5 datagram of a single counter sample of interface ifIndex
+func testIfCountersDatagram(ifIndex uint32, inOctets uint64, inUcastPkts uint32) []byte {
+	res := binary.BigEndian.AppendUint32(nil, 5)
+	for _, v := range []uint32{1, 0xc0000201, 0, 1, 100, 1, 2, 12 + 96, 1, ifIndex, 1} {
+		res = binary.BigEndian.AppendUint32(res, v)
+	}
+
+	return append(res, ifCountersRecord(ifIndex, inOctets, inUcastPkts)...)
+}
+
+// ipv4UDP is an IPv4 header with options followed by the UDP header of 192.0.2.1:1234 -> 198.51.100.1:53
+var ipv4UDP = []byte{
+	0x46, 0, 0, 60, 0, 0, 0, 0, 64, 17, 0, 0,
+	192, 0, 2, 1,
+	198, 51, 100, 1,
+	1, 1, 0, 0,
+	0x04, 0xd2, 0, 53, 0, 8, 0, 0,
+}
+
+func TestProcessPacketHeaderProtocols(t *testing.T) {
+	tests := []struct {
+		name        string
+		datagram    []byte
+		expected    int
+		expectedIn  string
+		expectedOut string
+	}{
+		{
+			name:        "IPv4 header",
+			datagram:    testDatagram(headerProtocolIPv4, 7, 8, ipv4UDP),
+			expected:    1,
+			expectedIn:  "et7",
+			expectedOut: "8",
+		},
+		{
+			name:        "Discarded packet",
+			datagram:    testDatagram(headerProtocolIPv4, 7, ifFormatDiscarded<<30|3, ipv4UDP),
+			expected:    1,
+			expectedIn:  "et7",
+			expectedOut: "discarded",
+		},
+		{
+			name:     "Unsupported header protocol",
+			datagram: testDatagram(2, 7, 8, ipv4UDP),
+		},
+	}
+
+	for _, test := range tests {
+		var flows []*flow.Flow
+		d := NewDecoder(testResolver{}, false, func(fl *flow.Flow) {
+			flows = append(flows, fl)
+		})
+		d.Decode(bnet.IPv4FromOctets(192, 0, 2, 254), test.datagram)
+
+		if !assert.Len(t, flows, test.expected, test.name) || test.expected == 0 {
+			continue
+		}
+
+		fl := flows[0]
+		assert.Equal(t, "192.0.2.1", fl.SrcAddr.String(), test.name)
+		assert.Equal(t, "198.51.100.1", fl.DstAddr.String(), test.name)
+		assert.Equal(t, uint16(1234), fl.SrcPort, test.name)
+		assert.Equal(t, uint16(53), fl.DstPort, test.name)
+		assert.Equal(t, test.expectedIn, fl.IntIn, test.name)
+		assert.Equal(t, test.expectedOut, fl.IntOut, test.name)
+		assert.Equal(t, flow.DirectionIngress, fl.Direction, test.name)
+	}
+}
+
+func TestProcessPacketSamplingReport(t *testing.T) {
+	sr := samplingreport.New(0)
+	sfs := &SflowServer{
+		ifResolver: testResolver{},
+		ingest:     func(fl *flow.Flow) {},
+		sampling:   sr,
+	}
+	agent := bnet.IPv4FromOctets(192, 0, 2, 254)
+
+	sfs.processPacket(agent, 0, testIfCountersDatagram(7, 1000, 10))
+	for i := 0; i < 3; i++ {
+		sfs.processPacket(agent, 0, testDatagram(headerProtocolIPv4, 7, 8, ipv4UDP))
+	}
+
+	// samples taken on egress of interface 8 and counters of other interfaces are accounted separately
+	sfs.processPacket(agent, 0, testIfCountersDatagram(8, 0, 0))
+	sfs.processPacket(agent, 0, testIfCountersDatagram(8, 1000, 10))
+
+	time.Sleep(10 * time.Millisecond)
+	sfs.processPacket(agent, 0, testIfCountersDatagram(7, 1000+4000000, 3010))
+
+	res := sr.Interfaces(nil)
+	if !assert.Len(t, res, 2) {
+		return
+	}
+
+	i := res[0]
+	assert.Equal(t, "et7", i.Name)
+	assert.Equal(t, uint64(4000000), i.In.CountedBytes)
+	assert.Equal(t, uint64(3000), i.In.CountedPackets)
+	assert.Equal(t, uint64(3), i.In.Samples)
+	assert.Equal(t, uint64(3000), i.In.EstimatedPackets)
+	assert.Equal(t, uint64(4500000), i.In.EstimatedBytes)
+	assert.Equal(t, uint32(1000), i.In.SamplingRate)
+	assert.InDelta(t, 0.125, i.In.ByteError, 0.0001)
+	assert.Equal(t, 0.0, i.In.PacketError)
+
+	assert.Equal(t, "8", res[1].Name)
+	assert.Equal(t, uint64(0), res[1].Out.Samples)
+}
+
+func FuzzDecoder(f *testing.F) {
+	f.Add(testDatagram(headerProtocolIPv4, 7, 8, ipv4UDP))
+	f.Add(testDatagram(headerProtocolIPv6, 7, 8, make([]byte, 48)))
+	f.Add(testDatagram(headerProtocolEthernet, 7, 8, append([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 0x08, 0}, ipv4UDP...)))
+	f.Add(testCounterDatagram())
+	f.Add(testIfCountersDatagram(7, 1000, 10))
+
+	d := NewDecoder(testResolver{}, true, func(fl *flow.Flow) {})
+	d.sfs.hostOutput = func(samples []*hostcounter.Sample) {}
+	d.sfs.sampling = samplingreport.New(0)
+	f.Fuzz(func(t *testing.T, datagram []byte) {
+		d.Decode(bnet.IPv4FromOctets(192, 0, 2, 254), append([]byte{}, datagram...))
+	})
+}
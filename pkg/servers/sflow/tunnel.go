@@ -0,0 +1,127 @@
+package sflow
+
+import (
+	"unsafe"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/packet"
+	"github.com/bio-routing/flowhouse/pkg/packet/sflow"
+	"github.com/pkg/errors"
+)
+
+const (
+	tunnelTypeVXLAN  = "vxlan"
+	tunnelTypeGeneve = "geneve"
+	tunnelTypeGRE    = "gre"
+)
+
+// processTunnel decodes the inner headers of VXLAN, Geneve and GRE encapsulated packets.
+// fs.Data is expected to point at the outer transport header.
+func (sfs *SflowServer) processTunnel(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) error {
+	inner := flow.New()
+	defer flow.Release(inner)
+
+	switch {
+	case fl.Protocol == packet.UDP && fl.DstPort == packet.VXLANPort:
+		if err := skip(fs, uint32(packet.SizeOfUDPHeader)); err != nil {
+			return err
+		}
+
+		vxlan, err := packet.DecodeVXLAN(fs.Data, fs.DataLen)
+		if err != nil {
+			return errors.Wrap(err, "Unable to decode VXLAN header")
+		}
+
+		fl.TunnelType = tunnelTypeVXLAN
+		fl.TunnelID = vxlan.GetVNI()
+		if err := skip(fs, uint32(packet.SizeOfVXLANHeader)); err != nil {
+			return err
+		}
+
+		if err := sfs.processInnerEthernet(agentStr, fs, inner); err != nil {
+			return err
+		}
+
+	case fl.Protocol == packet.UDP && fl.DstPort == packet.GenevePort:
+		if err := skip(fs, uint32(packet.SizeOfUDPHeader)); err != nil {
+			return err
+		}
+
+		geneve, err := packet.DecodeGeneve(fs.Data, fs.DataLen)
+		if err != nil {
+			return errors.Wrap(err, "Unable to decode Geneve header")
+		}
+
+		fl.TunnelType = tunnelTypeGeneve
+		fl.TunnelID = geneve.GetVNI()
+		if err := skip(fs, uint32(packet.SizeOfGeneveHeader)+geneve.OptionsLength()); err != nil {
+			return err
+		}
+
+		if err := sfs.processInnerPayload(agentStr, geneve.ProtocolType, fs, inner); err != nil {
+			return err
+		}
+
+	case fl.Protocol == packet.GRE:
+		gre, err := packet.DecodeGRE(fs.Data, fs.DataLen)
+		if err != nil {
+			return errors.Wrap(err, "Unable to decode GRE header")
+		}
+
+		fl.TunnelType = tunnelTypeGRE
+		fl.TunnelID = gre.Key
+		if err := skip(fs, gre.Length); err != nil {
+			return err
+		}
+
+		if err := sfs.processInnerPayload(agentStr, gre.ProtocolType, fs, inner); err != nil {
+			return err
+		}
+
+	default:
+		return nil
+	}
+
+	fl.InnerSrcAddr = inner.SrcAddr
+	fl.InnerDstAddr = inner.DstAddr
+	fl.InnerProtocol = inner.Protocol
+	fl.InnerSrcPort = inner.SrcPort
+	fl.InnerDstPort = inner.DstPort
+
+	return nil
+}
+
+func (sfs *SflowServer) processInnerPayload(agentStr string, protocolType uint16, fs *sflow.FlowSample, inner *flow.Flow) error {
+	if protocolType == packet.EtherTypeTransparentEthernetBridging {
+		return sfs.processInnerEthernet(agentStr, fs, inner)
+	}
+
+	sfs.processEthernet(agentStr, protocolType, fs, inner, false)
+	return nil
+}
+
+func (sfs *SflowServer) processInnerEthernet(agentStr string, fs *sflow.FlowSample, inner *flow.Flow) error {
+	ether, err := packet.DecodeEthernet(fs.Data, fs.DataLen)
+	if err != nil {
+		return errors.Wrap(err, "Unable to decode inner ethernet header")
+	}
+
+	if err := skip(fs, uint32(packet.SizeOfEthernetII)); err != nil {
+		return err
+	}
+
+	sfs.processEthernet(agentStr, ether.EtherType, fs, inner, false)
+	return nil
+}
+
+// skip advances the flow samples data pointer by n bytes
+func skip(fs *sflow.FlowSample, n uint32) error {
+	if n > fs.DataLen {
+		return errors.Errorf("Frame is too short: %d", fs.DataLen)
+	}
+
+	fs.Data = unsafe.Pointer(uintptr(fs.Data) - uintptr(n))
+	fs.DataLen -= n
+
+	return nil
+}
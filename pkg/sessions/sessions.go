@@ -0,0 +1,160 @@
+// Package sessions keeps the preferences of frontend users in sessions identified by a cookie
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/bio-routing/flowhouse/pkg/models/preferences"
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CookieName is the name of the session cookie
+	CookieName = "flowhouse_session"
+
+	cookieMaxAge = 365 * 24 * 3600
+)
+
+// Store persists sessions
+type Store interface {
+	InsertSession(id string, p *preferences.Preferences) error
+	GetSessions() (map[string]*preferences.Preferences, error)
+}
+
+// Manager keeps track of all sessions
+type Manager struct {
+	store    Store
+	sessions map[string]*preferences.Preferences
+	mu       sync.RWMutex
+}
+
+// New creates a new session manager and loads previously stored sessions
+func New(store Store) (*Manager, error) {
+	sessions, err := store.GetSessions()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load sessions")
+	}
+
+	return &Manager{
+		store:    store,
+		sessions: sessions,
+	}, nil
+}
+
+// Get returns the preferences of the session of a request or the defaults if there is none
+func (m *Manager) Get(r *http.Request) *preferences.Preferences {
+	c, err := r.Cookie(CookieName)
+	if err != nil {
+		return preferences.Defaults()
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, exists := m.sessions[c.Value]
+	if !exists {
+		return preferences.Defaults()
+	}
+
+	return p
+}
+
+// set validates and stores the preferences of a session
+func (m *Manager) set(id string, p *preferences.Preferences) error {
+	err := p.Validate()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err = m.store.InsertSession(id, p)
+	if err != nil {
+		return errors.Wrap(err, "Unable to store session")
+	}
+
+	m.sessions[id] = p
+	return nil
+}
+
+// Handler handles requests for /preferences. GET returns the preferences of the session, PUT replaces them and starts a
+// session if the request has none.
+func (m *Manager) Handler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, m.Get(r))
+	case http.MethodPut:
+		p := &preferences.Preferences{}
+		err := json.NewDecoder(r.Body).Decode(p)
+		if err != nil {
+			http.Error(w, "Unable to decode preferences", http.StatusBadRequest)
+			return
+		}
+
+		id, err := sessionID(r)
+		if err != nil {
+			log.WithError(err).Error("Unable to create session")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = m.set(id, p)
+		if err != nil {
+			log.WithError(err).Error("Unable to set preferences")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     CookieName,
+			Value:    id,
+			Path:     "/",
+			MaxAge:   cookieMaxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		writeJSON(w, http.StatusOK, p)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// sessionID returns the session ID of a request or a new one if it has none
+func sessionID(r *http.Request) (string, error) {
+	c, err := r.Cookie(CookieName)
+	if err == nil && isValidID(c.Value) {
+		return c.Value, nil
+	}
+
+	b := make([]byte, 16)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to generate session ID")
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func isValidID(id string) bool {
+	b, err := hex.DecodeString(id)
+	return err == nil && len(b) == 16
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	j, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(j)
+}
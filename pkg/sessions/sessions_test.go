@@ -0,0 +1,88 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/preferences"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockStore struct {
+	sessions map[string]*preferences.Preferences
+}
+
+func (m *mockStore) InsertSession(id string, p *preferences.Preferences) error {
+	m.sessions[id] = p
+	return nil
+}
+
+func (m *mockStore) GetSessions() (map[string]*preferences.Preferences, error) {
+	res := make(map[string]*preferences.Preferences)
+	for id, p := range m.sessions {
+		res[id] = p
+	}
+
+	return res, nil
+}
+
+func TestHandler(t *testing.T) {
+	store := &mockStore{
+		sessions: map[string]*preferences.Preferences{
+			"00112233445566778899aabbccddeeff": {Timezone: "Europe/Berlin", TimeRange: 3600, Breakdowns: []string{"src_asn"}, Theme: preferences.ThemeDark},
+		},
+	}
+
+	m, err := New(store)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	get := func(cookie *http.Cookie) *preferences.Preferences {
+		req := httptest.NewRequest(http.MethodGet, "/preferences", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+
+		rec := httptest.NewRecorder()
+		m.Handler(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		p := &preferences.Preferences{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), p))
+		return p
+	}
+
+	assert.Equal(t, preferences.Defaults(), get(nil))
+	assert.Equal(t, "Europe/Berlin", get(&http.Cookie{Name: CookieName, Value: "00112233445566778899aabbccddeeff"}).Timezone)
+
+	// a new session is started by the first PUT
+	req := httptest.NewRequest(http.MethodPut, "/preferences", bytes.NewBufferString(`{"timezone":"UTC","breakdowns":["dst_port"]}`))
+	rec := httptest.NewRecorder()
+	m.Handler(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	cookies := rec.Result().Cookies()
+	if !assert.Len(t, cookies, 1) {
+		return
+	}
+	assert.Equal(t, CookieName, cookies[0].Name)
+	assert.True(t, isValidID(cookies[0].Value))
+	assert.Len(t, store.sessions, 2)
+
+	p := get(cookies[0])
+	assert.Equal(t, []string{"dst_port"}, p.Breakdowns)
+	assert.Equal(t, int64(preferences.DefaultTimeRange), p.TimeRange)
+	assert.Equal(t, preferences.ThemeLight, p.Theme)
+
+	// invalid preferences are rejected
+	req = httptest.NewRequest(http.MethodPut, "/preferences", bytes.NewBufferString(`{"timezone":"Mars/Olympus_Mons"}`))
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	m.Handler(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, []string{"dst_port"}, get(cookies[0]).Breakdowns)
+}
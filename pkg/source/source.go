@@ -0,0 +1,47 @@
+package source
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultFetchTimeout = 30
+
+// Open opens a data source. src is either a local file path or a HTTP(S) URL.
+func Open(src string) (io.ReadCloser, error) {
+	if !IsURL(src) {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to open %q", src)
+		}
+
+		return f, nil
+	}
+
+	c := &http.Client{
+		Timeout: defaultFetchTimeout * time.Second,
+	}
+
+	resp, err := c.Get(src)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to fetch %q", src)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Unable to fetch %q: %s", src, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// IsURL checks if src is a HTTP(S) URL
+func IsURL(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
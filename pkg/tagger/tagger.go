@@ -0,0 +1,221 @@
+package tagger
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TagCustomer is the customer tag
+	TagCustomer = "customer"
+
+	// TagService is the service tag
+	TagService = "service"
+
+	// TagTrafficClass is the traffic class tag
+	TagTrafficClass = "traffic_class"
+
+	directionAny = "any"
+	directionSrc = "src"
+	directionDst = "dst"
+)
+
+// Config is the tagging engines configuration
+type Config struct {
+	Rules []*RuleConfig `yaml:"rules"`
+}
+
+// RuleConfig defines a tagging rule. A rule matches if all given criteria match.
+// Within a criterion it is sufficient if any of the given values matches.
+type RuleConfig struct {
+	Name       string            `yaml:"name"`
+	Direction  string            `yaml:"direction"`
+	Prefixes   []string          `yaml:"prefixes"`
+	ASNs       []uint32          `yaml:"asns"`
+	Interfaces []string          `yaml:"interfaces"`
+	Tags       map[string]string `yaml:"tags"`
+}
+
+type rule struct {
+	name       string
+	direction  string
+	prefixes   []netip.Prefix
+	asns       map[uint32]struct{}
+	interfaces map[string]struct{}
+	tags       map[string]string
+}
+
+// Tagger tags flows according to a set of rules
+type Tagger struct {
+	rules []*rule
+}
+
+// New creates a new tagger
+func New(cfg *Config) (*Tagger, error) {
+	t := &Tagger{
+		rules: make([]*rule, 0, len(cfg.Rules)),
+	}
+
+	for _, rc := range cfg.Rules {
+		r, err := newRule(rc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid rule %q", rc.Name)
+		}
+
+		t.rules = append(t.rules, r)
+	}
+
+	return t, nil
+}
+
+func newRule(rc *RuleConfig) (*rule, error) {
+	r := &rule{
+		name:      rc.Name,
+		direction: rc.Direction,
+		tags:      rc.Tags,
+	}
+
+	if r.direction == "" {
+		r.direction = directionAny
+	}
+
+	if r.direction != directionAny && r.direction != directionSrc && r.direction != directionDst {
+		return nil, fmt.Errorf("Invalid direction %q", r.direction)
+	}
+
+	for k := range rc.Tags {
+		if k != TagCustomer && k != TagService && k != TagTrafficClass {
+			return nil, fmt.Errorf("Unknown tag %q", k)
+		}
+	}
+
+	for _, p := range rc.Prefixes {
+		pfx, err := netip.ParsePrefix(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to parse prefix %q", p)
+		}
+
+		r.prefixes = append(r.prefixes, pfx)
+	}
+
+	if len(rc.ASNs) > 0 {
+		r.asns = make(map[uint32]struct{})
+		for _, asn := range rc.ASNs {
+			r.asns[asn] = struct{}{}
+		}
+	}
+
+	if len(rc.Interfaces) > 0 {
+		r.interfaces = make(map[string]struct{})
+		for _, ifName := range rc.Interfaces {
+			r.interfaces[ifName] = struct{}{}
+		}
+	}
+
+	return r, nil
+}
+
+// Annotate sets the tags of a flow. Rules are evaluated in order, the first rule setting a tag wins.
+func (t *Tagger) Annotate(fl *flow.Flow) error {
+	for _, r := range t.rules {
+		if !r.matches(fl) {
+			continue
+		}
+
+		for k, v := range r.tags {
+			setTag(fl, k, v)
+		}
+	}
+
+	return nil
+}
+
+func setTag(fl *flow.Flow, tag string, value string) {
+	switch tag {
+	case TagCustomer:
+		if fl.Customer == "" {
+			fl.Customer = value
+		}
+	case TagService:
+		if fl.Service == "" {
+			fl.Service = value
+		}
+	case TagTrafficClass:
+		if fl.TrafficClass == "" {
+			fl.TrafficClass = value
+		}
+	}
+}
+
+func (r *rule) matches(fl *flow.Flow) bool {
+	if len(r.prefixes) > 0 && !r.matchesPrefixes(fl) {
+		return false
+	}
+
+	if r.asns != nil && !r.matchesASNs(fl) {
+		return false
+	}
+
+	if r.interfaces != nil && !r.matchesInterfaces(fl) {
+		return false
+	}
+
+	return true
+}
+
+func (r *rule) matchesPrefixes(fl *flow.Flow) bool {
+	if r.direction != directionDst && containsAddr(r.prefixes, fl.SrcAddr) {
+		return true
+	}
+
+	if r.direction != directionSrc && containsAddr(r.prefixes, fl.DstAddr) {
+		return true
+	}
+
+	return false
+}
+
+func (r *rule) matchesASNs(fl *flow.Flow) bool {
+	if r.direction != directionDst {
+		if _, exists := r.asns[fl.SrcAs]; exists {
+			return true
+		}
+	}
+
+	if r.direction != directionSrc {
+		if _, exists := r.asns[fl.DstAs]; exists {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *rule) matchesInterfaces(fl *flow.Flow) bool {
+	if r.direction != directionDst {
+		if _, exists := r.interfaces[fl.IntIn]; exists {
+			return true
+		}
+	}
+
+	if r.direction != directionSrc {
+		if _, exists := r.interfaces[fl.IntOut]; exists {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, pfx := range prefixes {
+		if pfx.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
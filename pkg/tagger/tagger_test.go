@@ -0,0 +1,113 @@
+package tagger
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotate(t *testing.T) {
+	tg, err := New(&Config{
+		Rules: []*RuleConfig{
+			{
+				Name:      "customer-a",
+				Direction: "dst",
+				Prefixes:  []string{"198.51.100.0/24", "2001:db8:1::/48"},
+				Tags: map[string]string{
+					TagCustomer: "customer-a",
+				},
+			},
+			{
+				Name: "transit",
+				ASNs: []uint32{64500},
+				Tags: map[string]string{
+					TagCustomer:     "transit",
+					TagTrafficClass: "transit",
+				},
+			},
+			{
+				Name:       "cdn",
+				Direction:  "src",
+				Interfaces: []string{"et-0/0/1.0"},
+				Prefixes:   []string{"203.0.113.0/24"},
+				Tags: map[string]string{
+					TagService: "cdn",
+				},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		fl       *flow.Flow
+		expected *flow.Flow
+	}{
+		{
+			name: "Destination prefix match",
+			fl: &flow.Flow{
+				DstAddr: netip.AddrFrom4([4]byte{198, 51, 100, 10}),
+				DstAs:   64500,
+			},
+			expected: &flow.Flow{
+				DstAddr:      netip.AddrFrom4([4]byte{198, 51, 100, 10}),
+				DstAs:        64500,
+				Customer:     "customer-a",
+				TrafficClass: "transit",
+			},
+		},
+		{
+			name: "Direction mismatch",
+			fl: &flow.Flow{
+				SrcAddr: netip.AddrFrom4([4]byte{198, 51, 100, 10}),
+			},
+			expected: &flow.Flow{
+				SrcAddr: netip.AddrFrom4([4]byte{198, 51, 100, 10}),
+			},
+		},
+		{
+			name: "All criteria must match",
+			fl: &flow.Flow{
+				SrcAddr: netip.AddrFrom4([4]byte{203, 0, 113, 1}),
+				IntIn:   "et-0/0/2.0",
+			},
+			expected: &flow.Flow{
+				SrcAddr: netip.AddrFrom4([4]byte{203, 0, 113, 1}),
+				IntIn:   "et-0/0/2.0",
+			},
+		},
+		{
+			name: "Interface and prefix match",
+			fl: &flow.Flow{
+				SrcAddr: netip.AddrFrom4([4]byte{203, 0, 113, 1}),
+				IntIn:   "et-0/0/1.0",
+			},
+			expected: &flow.Flow{
+				SrcAddr: netip.AddrFrom4([4]byte{203, 0, 113, 1}),
+				IntIn:   "et-0/0/1.0",
+				Service: "cdn",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tg.Annotate(test.fl)
+		assert.Equal(t, test.expected, test.fl, test.name)
+	}
+}
+
+func TestNewInvalidTag(t *testing.T) {
+	_, err := New(&Config{
+		Rules: []*RuleConfig{
+			{
+				Name: "foo",
+				Tags: map[string]string{
+					"foo": "bar",
+				},
+			},
+		},
+	})
+	assert.Error(t, err)
+}
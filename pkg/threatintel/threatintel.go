@@ -0,0 +1,329 @@
+package threatintel
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/prefixset"
+	"github.com/bio-routing/flowhouse/pkg/source"
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// FormatPlain is a list with one address or prefix per line. Everything after '#' or ';' is ignored.
+	FormatPlain = "plain"
+
+	// FormatCSV is a CSV file with the address or prefix in column `column`
+	FormatCSV = "csv"
+
+	defaultRefreshInterval = 3600
+)
+
+// Config is the threat intelligence subsystems configuration
+type Config struct {
+	Feeds []*FeedConfig `yaml:"feeds"`
+}
+
+// FeedConfig describes a block list
+type FeedConfig struct {
+	Name            string `yaml:"name"`
+	Source          string `yaml:"source"`
+	Format          string `yaml:"format"`
+	Column          int    `yaml:"column"`
+	RefreshInterval uint64 `yaml:"refresh_interval"`
+}
+
+func (fc *FeedConfig) loadDefaults() {
+	if fc.Format == "" {
+		fc.Format = FormatPlain
+	}
+
+	if fc.RefreshInterval == 0 {
+		fc.RefreshInterval = defaultRefreshInterval
+	}
+}
+
+type feed struct {
+	hits     uint64 // accessed atomically, keep 64 bit aligned
+	cfg      *FeedConfig
+	set      *prefixset.Set
+	version  string
+	loadedAt time.Time
+	lastErr  error
+	mu       sync.RWMutex
+}
+
+// FeedStatus is the state of a feed as reported by the API
+type FeedStatus struct {
+	Name      string    `json:"name"`
+	Source    string    `json:"source"`
+	Version   string    `json:"version"`
+	LoadedAt  time.Time `json:"loaded_at"`
+	Prefixes  int       `json:"prefixes"`
+	Hits      uint64    `json:"hits"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Matcher tags flows whose source or destination is listed in a threat intelligence feed
+type Matcher struct {
+	feeds  []*feed
+	stopCh chan struct{}
+}
+
+// New creates a new matcher and starts loading the feeds
+func New(cfg *Config) (*Matcher, error) {
+	m := &Matcher{
+		feeds:  make([]*feed, 0, len(cfg.Feeds)),
+		stopCh: make(chan struct{}),
+	}
+
+	for _, fc := range cfg.Feeds {
+		fc.loadDefaults()
+
+		if fc.Name == "" {
+			return nil, fmt.Errorf("Feed without name")
+		}
+
+		if fc.Format != FormatPlain && fc.Format != FormatCSV {
+			return nil, fmt.Errorf("Feed %q: unknown format %q", fc.Name, fc.Format)
+		}
+
+		m.feeds = append(m.feeds, &feed{
+			cfg: fc,
+			set: prefixset.New(),
+		})
+	}
+
+	for _, f := range m.feeds {
+		go f.refresher(m.stopCh)
+	}
+
+	return m, nil
+}
+
+// Stop stops refreshing the feeds
+func (m *Matcher) Stop() {
+	close(m.stopCh)
+}
+
+func (f *feed) refresher(stopCh chan struct{}) {
+	f.refresh()
+
+	t := time.NewTicker(time.Duration(f.cfg.RefreshInterval) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+			f.refresh()
+		}
+	}
+}
+
+func (f *feed) refresh() {
+	err := f.load()
+	if err != nil {
+		log.WithError(err).Errorf("Unable to load threat intelligence feed %q", f.cfg.Name)
+	}
+
+	f.mu.Lock()
+	f.lastErr = err
+	f.mu.Unlock()
+}
+
+func (f *feed) load() error {
+	rc, err := source.Open(f.cfg.Source)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return errors.Wrap(err, "Read failed")
+	}
+
+	sum := sha256.Sum256(data)
+	version := hex.EncodeToString(sum[:])
+
+	f.mu.RLock()
+	unchanged := version == f.version
+	f.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	s, err := parseFeed(f.cfg, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "Unable to parse feed")
+	}
+
+	f.mu.Lock()
+	f.set = s
+	f.version = version
+	f.loadedAt = time.Now()
+	f.mu.Unlock()
+
+	log.Infof("Loaded %d prefixes from threat intelligence feed %q", s.Len(), f.cfg.Name)
+	return nil
+}
+
+func parseFeed(cfg *FeedConfig, r io.Reader) (*prefixset.Set, error) {
+	entries, err := readEntries(cfg, r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := prefixset.New()
+	for _, e := range entries {
+		pfx, err := parseEntry(e)
+		if err != nil {
+			log.WithError(err).Debugf("Feed %q: ignoring invalid entry %q", cfg.Name, e)
+			continue
+		}
+
+		s.Add(pfx)
+	}
+
+	return s, nil
+}
+
+func readEntries(cfg *FeedConfig, r io.Reader) ([]string, error) {
+	entries := make([]string, 0)
+	if cfg.Format == FormatCSV {
+		cr := csv.NewReader(r)
+		cr.Comment = '#'
+		cr.FieldsPerRecord = -1
+		for {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return nil, errors.Wrap(err, "Unable to read CSV")
+			}
+
+			if cfg.Column < len(rec) {
+				entries = append(entries, strings.TrimSpace(rec[cfg.Column]))
+			}
+		}
+
+		return entries, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		entries = append(entries, strings.Fields(line)[0])
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "Read failed")
+	}
+
+	return entries, nil
+}
+
+// parseEntry parses a prefix or a single address
+func parseEntry(e string) (netip.Prefix, error) {
+	if strings.Contains(e, "/") {
+		return netip.ParsePrefix(e)
+	}
+
+	addr, err := netip.ParseAddr(e)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func (f *feed) contains(addr netip.Addr) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.set.Contains(addr)
+}
+
+// match returns the name of the first feed listing addr
+func (m *Matcher) match(addr netip.Addr) string {
+	for _, f := range m.feeds {
+		if f.contains(addr) {
+			atomic.AddUint64(&f.hits, 1)
+			return f.cfg.Name
+		}
+	}
+
+	return ""
+}
+
+// Annotate sets the name of the feed listing the flows source or destination address
+func (m *Matcher) Annotate(fl *flow.Flow) {
+	fl.SrcThreatFeed = m.match(fl.SrcAddr)
+	fl.DstThreatFeed = m.match(fl.DstAddr)
+}
+
+// Status returns the state of all feeds
+func (m *Matcher) Status() []*FeedStatus {
+	res := make([]*FeedStatus, 0, len(m.feeds))
+	for _, f := range m.feeds {
+		f.mu.RLock()
+		s := &FeedStatus{
+			Name:     f.cfg.Name,
+			Source:   f.cfg.Source,
+			Version:  f.version,
+			LoadedAt: f.loadedAt,
+			Prefixes: f.set.Len(),
+			Hits:     atomic.LoadUint64(&f.hits),
+		}
+
+		if f.lastErr != nil {
+			s.LastError = f.lastErr.Error()
+		}
+		f.mu.RUnlock()
+
+		res = append(res, s)
+	}
+
+	return res
+}
+
+// Handler serves the state of all feeds as JSON
+func (m *Matcher) Handler(w http.ResponseWriter, r *http.Request) {
+	j, err := json.Marshal(m.Status())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
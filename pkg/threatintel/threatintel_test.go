@@ -0,0 +1,114 @@
+package threatintel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *FeedConfig
+		data     string
+		listed   []string
+		unlisted []string
+	}{
+		{
+			name: "Plain",
+			cfg: &FeedConfig{
+				Name:   "drop",
+				Format: FormatPlain,
+			},
+			data:     "; Spamhaus DROP List\n192.0.2.0/24 ; SBL1\n198.51.100.7\n# comment\n2001:db8::/32\n",
+			listed:   []string{"192.0.2.1", "198.51.100.7", "2001:db8::1"},
+			unlisted: []string{"198.51.100.8", "203.0.113.1"},
+		},
+		{
+			name: "CSV",
+			cfg: &FeedConfig{
+				Name:   "c2",
+				Format: FormatCSV,
+				Column: 1,
+			},
+			data:     "# first_seen,ip,port\n2020-01-01,203.0.113.5,443\n2020-01-02,not-an-ip,80\n",
+			listed:   []string{"203.0.113.5"},
+			unlisted: []string{"203.0.113.6"},
+		},
+	}
+
+	for _, test := range tests {
+		s, err := parseFeed(test.cfg, strings.NewReader(test.data))
+		if err != nil {
+			t.Fatalf("Unable to parse feed %s: %v", test.name, err)
+		}
+
+		for _, a := range test.listed {
+			assert.True(t, s.Contains(mustIP(t, a)), "%s: %s", test.name, a)
+		}
+
+		for _, a := range test.unlisted {
+			assert.False(t, s.Contains(mustIP(t, a)), "%s: %s", test.name, a)
+		}
+	}
+}
+
+func TestAnnotateAndStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("192.0.2.0/24\n"))
+	}))
+	defer srv.Close()
+
+	f := &feed{
+		cfg: &FeedConfig{
+			Name:   "test",
+			Source: srv.URL,
+			Format: FormatPlain,
+		},
+	}
+	f.refresh()
+
+	m := &Matcher{
+		feeds: []*feed{f},
+	}
+
+	fl := &flow.Flow{
+		SrcAddr: netip.AddrFrom4([4]byte{198, 51, 100, 1}),
+		DstAddr: netip.AddrFrom4([4]byte{192, 0, 2, 1}),
+	}
+	m.Annotate(fl)
+
+	assert.Equal(t, "", fl.SrcThreatFeed)
+	assert.Equal(t, "test", fl.DstThreatFeed)
+
+	rec := httptest.NewRecorder()
+	m.Handler(rec, httptest.NewRequest(http.MethodGet, "/threat_intel/feeds", nil))
+
+	status := make([]*FeedStatus, 0)
+	err := json.Unmarshal(rec.Body.Bytes(), &status)
+	if err != nil {
+		t.Fatalf("Unable to decode status: %v", err)
+	}
+
+	assert.Len(t, status, 1)
+	assert.Equal(t, "test", status[0].Name)
+	assert.Equal(t, 1, status[0].Prefixes)
+	assert.Equal(t, uint64(1), status[0].Hits)
+	assert.NotEmpty(t, status[0].Version)
+	assert.Empty(t, status[0].LastError)
+}
+
+func mustIP(t *testing.T, s string) netip.Addr {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		t.Fatalf("Unable to parse address %q: %v", s, err)
+	}
+
+	return addr
+}
@@ -0,0 +1,69 @@
+// Package timestamps selects which of the times known about a flow is stored as its timestamp
+package timestamps
+
+import (
+	"fmt"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+)
+
+const (
+	// SourceExport uses the time reported by the exporter (IPFIX export time). Protocols without an exporter
+	// reported time (sFlow, local capture) fall back to the receive time.
+	SourceExport = "export"
+
+	// SourceReceived uses the time the collector received the flow
+	SourceReceived = "received"
+)
+
+// Config is the timestamp policy
+type Config struct {
+	// Source selects the time stored in the `timestamp` column
+	Source string `yaml:"source"`
+
+	// StoreBoth additionally stores the receive and the export time in the `received_at` and `exported_at` columns
+	StoreBoth bool `yaml:"store_both"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Source == "" {
+		c.Source = SourceExport
+	}
+}
+
+// Policy applies the configured timestamp policy to flows
+type Policy struct {
+	cfg *Config
+}
+
+// New creates a new timestamp policy
+func New(cfg *Config) (*Policy, error) {
+	cfg.loadDefaults()
+
+	if cfg.Source != SourceExport && cfg.Source != SourceReceived {
+		return nil, fmt.Errorf("Unknown timestamp source %q", cfg.Source)
+	}
+
+	return &Policy{
+		cfg: cfg,
+	}, nil
+}
+
+// Annotate sets the flows timestamp according to the policy
+func (p *Policy) Annotate(fl *flow.Flow) {
+	switch p.cfg.Source {
+	case SourceExport:
+		if fl.ExportedAt != 0 {
+			fl.Timestamp = fl.ExportedAt
+		}
+	case SourceReceived:
+		if fl.ReceivedAt != 0 {
+			fl.Timestamp = fl.ReceivedAt
+		}
+	}
+
+	if !p.cfg.StoreBoth {
+		fl.ReceivedAt = 0
+		fl.ExportedAt = 0
+	}
+}
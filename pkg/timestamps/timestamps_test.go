@@ -0,0 +1,92 @@
+package timestamps
+
+import (
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		input    *flow.Flow
+		expected *flow.Flow
+	}{
+		{
+			name: "Default: export time",
+			cfg:  &Config{},
+			input: &flow.Flow{
+				Timestamp:  100,
+				ReceivedAt: 105,
+				ExportedAt: 100,
+			},
+			expected: &flow.Flow{
+				Timestamp: 100,
+			},
+		},
+		{
+			name: "Received time",
+			cfg: &Config{
+				Source: SourceReceived,
+			},
+			input: &flow.Flow{
+				Timestamp:  100,
+				ReceivedAt: 105,
+				ExportedAt: 100,
+			},
+			expected: &flow.Flow{
+				Timestamp: 105,
+			},
+		},
+		{
+			name: "Export time without exporter time",
+			cfg: &Config{
+				Source:    SourceExport,
+				StoreBoth: true,
+			},
+			input: &flow.Flow{
+				Timestamp:  110,
+				ReceivedAt: 110,
+			},
+			expected: &flow.Flow{
+				Timestamp:  110,
+				ReceivedAt: 110,
+			},
+		},
+		{
+			name: "Store both",
+			cfg: &Config{
+				Source:    SourceReceived,
+				StoreBoth: true,
+			},
+			input: &flow.Flow{
+				Timestamp:  100,
+				ReceivedAt: 105,
+				ExportedAt: 100,
+			},
+			expected: &flow.Flow{
+				Timestamp:  105,
+				ReceivedAt: 105,
+				ExportedAt: 100,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		p, err := New(test.cfg)
+		if err != nil {
+			t.Errorf("Unexpected error for test %q: %v", test.name, err)
+			continue
+		}
+
+		p.Annotate(test.input)
+		assert.Equal(t, test.expected, test.input, test.name)
+	}
+}
+
+func TestNewInvalidSource(t *testing.T) {
+	_, err := New(&Config{Source: "foo"})
+	assert.Error(t, err)
+}
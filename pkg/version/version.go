@@ -0,0 +1,87 @@
+// Package version holds the version and build information embedded at link time, e.g.
+//
+//	go build -ldflags "-X github.com/bio-routing/flowhouse/pkg/version.Version=v1.2.3"
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Version, Commit and BuildDate are set with -ldflags -X. Commit and BuildDate default to the VCS information Go
+// embeds when building from a checkout.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info is the build information
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	for _, s := range bi.Settings {
+		switch {
+		case s.Key == "vcs.revision" && info.Commit == "":
+			info.Commit = s.Value
+		case s.Key == "vcs.time" && info.BuildDate == "":
+			info.BuildDate = s.Value
+		}
+	}
+
+	return info
+}
+
+// String formats the build information for humans
+func (i Info) String() string {
+	s := "flowhouse " + i.Version
+	if i.Commit != "" {
+		s += " (commit " + i.Commit
+		if i.BuildDate != "" {
+			s += ", built " + i.BuildDate
+		}
+		s += ")"
+	}
+
+	return s + " " + i.GoVersion
+}
+
+// RegisterMetric registers the flowhouse_build_info metric which is always 1 and carries the build information as
+// labels
+func RegisterMetric() {
+	info := Get()
+	promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "flowhouse",
+		Name:      "build_info",
+		Help:      "Build information, always 1",
+	}, []string{"version", "commit", "build_date", "go_version"}).WithLabelValues(info.Version, info.Commit, info.BuildDate, info.GoVersion).Set(1)
+}
+
+// Handler serves the build information as JSON
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Get())
+}
@@ -0,0 +1,30 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     Info
+		expected string
+	}{
+		{
+			name:     "Version only",
+			info:     Info{Version: "dev", GoVersion: "go1.23.4"},
+			expected: "flowhouse dev go1.23.4",
+		},
+		{
+			name:     "Full",
+			info:     Info{Version: "v1.2.3", Commit: "abc123", BuildDate: "2021-03-01T00:00:00Z", GoVersion: "go1.23.4"},
+			expected: "flowhouse v1.2.3 (commit abc123, built 2021-03-01T00:00:00Z) go1.23.4",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.info.String(), test.name)
+	}
+}
@@ -0,0 +1,273 @@
+// Package writers inserts flows through parallel writers, each with its own connection and batch
+package writers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ShardByAgent keeps the flows of an agent on one writer
+	ShardByAgent = "agent"
+
+	// ShardByHash spreads flows over all writers by their addresses, ports and protocol
+	ShardByHash = "hash"
+
+	defaultWriters       = 1
+	defaultBatchSize     = 10000
+	defaultFlushInterval = 1
+	defaultQueueLength   = 16
+)
+
+var queuedBatches = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "flowhouse",
+	Subsystem: "writers",
+	Name:      "queued_batches",
+	Help:      "Batches waiting to be added to the batch of a writer",
+}, []string{"writer"})
+
+// Config configures the writers
+type Config struct {
+	// Writers is the number of writers
+	Writers int `yaml:"writers"`
+
+	// ShardBy selects the writer of a flow, agent (default) or hash
+	ShardBy string `yaml:"shard_by"`
+
+	// BatchSize is the number of flows a writer inserts at once
+	BatchSize int `yaml:"batch_size"`
+
+	// FlushInterval is the time in seconds after which a writer inserts an incomplete batch
+	FlushInterval uint64 `yaml:"flush_interval"`
+
+	// QueueLength is the number of batches queued per writer before Write blocks
+	QueueLength int `yaml:"queue_length"`
+
+	// Addresses are the ClickHouse servers the writers connect to in turns, the server of the clickhouse section if empty
+	Addresses []string `yaml:"addresses"`
+}
+
+func (c *Config) loadDefaults() {
+	if c.Writers == 0 {
+		c.Writers = defaultWriters
+	}
+
+	if c.ShardBy == "" {
+		c.ShardBy = ShardByAgent
+	}
+
+	if c.BatchSize == 0 {
+		c.BatchSize = defaultBatchSize
+	}
+
+	if c.FlushInterval == 0 {
+		c.FlushInterval = defaultFlushInterval
+	}
+
+	if c.QueueLength == 0 {
+		c.QueueLength = defaultQueueLength
+	}
+}
+
+func (c *Config) validate() error {
+	if c.Writers < 1 || c.BatchSize < 1 || c.QueueLength < 1 {
+		return fmt.Errorf("writers, batch_size and queue_length must be positive")
+	}
+
+	if c.ShardBy != ShardByAgent && c.ShardBy != ShardByHash {
+		return fmt.Errorf("Unknown shard_by %q, expected %s or %s", c.ShardBy, ShardByAgent, ShardByHash)
+	}
+
+	return nil
+}
+
+// Inserter inserts flows, e.g. a ClickHouse connection
+type Inserter interface {
+	InsertFlows(flows []*flow.Flow) error
+	Close()
+}
+
+// ConnectFunc connects an inserter to address, the default server if address is empty
+type ConnectFunc func(address string) (Inserter, error)
+
+// ObserveFunc is called with the flows of each insert, how long it took and whether it failed
+type ObserveFunc func(flows []*flow.Flow, d time.Duration, err error)
+
+// Pool distributes flows over its writers
+type Pool struct {
+	cfg     *Config
+	writers []*writer
+	seed    maphash.Seed
+	observe ObserveFunc
+	wg      sync.WaitGroup
+}
+
+type writer struct {
+	name     string
+	inserter Inserter
+	queue    chan []*flow.Flow
+	batch    []*flow.Flow
+}
+
+// New connects and starts the writers
+func New(cfg *Config, connect ConnectFunc, observe ObserveFunc) (*Pool, error) {
+	cfg.loadDefaults()
+	err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pool{
+		cfg:     cfg,
+		writers: make([]*writer, 0, cfg.Writers),
+		seed:    maphash.MakeSeed(),
+		observe: observe,
+	}
+
+	for i := 0; i < cfg.Writers; i++ {
+		addr := ""
+		if len(cfg.Addresses) > 0 {
+			addr = cfg.Addresses[i%len(cfg.Addresses)]
+		}
+
+		ins, err := connect(addr)
+		if err != nil {
+			p.close()
+			return nil, errors.Wrapf(err, "Unable to connect writer %d", i)
+		}
+
+		p.writers = append(p.writers, &writer{
+			name:     strconv.Itoa(i),
+			inserter: ins,
+			queue:    make(chan []*flow.Flow, cfg.QueueLength),
+			batch:    make([]*flow.Flow, 0, cfg.BatchSize),
+		})
+	}
+
+	for _, w := range p.writers {
+		p.wg.Add(1)
+		go p.run(w)
+	}
+
+	return p, nil
+}
+
+// Write passes flows to their writers, which take ownership of them. It blocks while the queue of a writer is full.
+func (p *Pool) Write(flows []*flow.Flow) {
+	if len(p.writers) == 1 {
+		p.enqueue(p.writers[0], flows)
+		return
+	}
+
+	shards := make([][]*flow.Flow, len(p.writers))
+	for _, fl := range flows {
+		i := p.shard(fl)
+		shards[i] = append(shards[i], fl)
+	}
+
+	for i, s := range shards {
+		if len(s) > 0 {
+			p.enqueue(p.writers[i], s)
+		}
+	}
+}
+
+func (p *Pool) enqueue(w *writer, flows []*flow.Flow) {
+	w.queue <- flows
+	queuedBatches.WithLabelValues(w.name).Set(float64(len(w.queue)))
+}
+
+// Stop inserts all queued flows and closes the connections
+func (p *Pool) Stop() {
+	for _, w := range p.writers {
+		close(w.queue)
+	}
+
+	p.wg.Wait()
+	p.close()
+}
+
+func (p *Pool) close() {
+	for _, w := range p.writers {
+		w.inserter.Close()
+	}
+}
+
+// shard gets the index of the writer of fl
+func (p *Pool) shard(fl *flow.Flow) int {
+	var b [2*flow.AddrSize + 5]byte
+	n := flow.AddrSize
+	if p.cfg.ShardBy == ShardByHash {
+		flow.PutAddr(b[:], fl.SrcAddr)
+		flow.PutAddr(b[flow.AddrSize:], fl.DstAddr)
+		binary.BigEndian.PutUint16(b[2*flow.AddrSize:], fl.SrcPort)
+		binary.BigEndian.PutUint16(b[2*flow.AddrSize+2:], fl.DstPort)
+		b[2*flow.AddrSize+4] = fl.Protocol
+		n = len(b)
+	} else {
+		flow.PutAddr(b[:], fl.Agent)
+	}
+
+	return int(maphash.Bytes(p.seed, b[:n]) % uint64(len(p.writers)))
+}
+
+// run adds the queued flows of w to its batch, which is inserted once it is full or the flush interval passed
+func (p *Pool) run(w *writer) {
+	defer p.wg.Done()
+
+	t := time.NewTicker(time.Duration(p.cfg.FlushInterval) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case flows, ok := <-w.queue:
+			if !ok {
+				p.flush(w)
+				return
+			}
+
+			queuedBatches.WithLabelValues(w.name).Set(float64(len(w.queue)))
+			for len(flows) > 0 {
+				n := min(p.cfg.BatchSize-len(w.batch), len(flows))
+				w.batch = append(w.batch, flows[:n]...)
+				flows = flows[n:]
+				if len(w.batch) == p.cfg.BatchSize {
+					p.flush(w)
+				}
+			}
+		case <-t.C:
+			p.flush(w)
+		}
+	}
+}
+
+func (p *Pool) flush(w *writer) {
+	if len(w.batch) == 0 {
+		return
+	}
+
+	start := time.Now()
+	err := w.inserter.InsertFlows(w.batch)
+	if err != nil {
+		log.WithError(err).WithField("writer", w.name).Error("Insert failed")
+	}
+
+	if p.observe != nil {
+		p.observe(w.batch, time.Since(start), err)
+	}
+
+	// nothing keeps references to the flows of a batch past this point
+	flow.ReleaseAll(w.batch)
+	w.batch = w.batch[:0]
+}
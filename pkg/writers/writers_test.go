@@ -0,0 +1,203 @@
+package writers
+
+import (
+	"fmt"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockInserter struct {
+	address string
+	mu      sync.Mutex
+	inserts [][]netip.Addr
+	closed  bool
+}
+
+func (m *mockInserter) InsertFlows(flows []*flow.Flow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agents := make([]netip.Addr, 0, len(flows))
+	for _, fl := range flows {
+		agents = append(agents, fl.Agent)
+	}
+	m.inserts = append(m.inserts, agents)
+	return nil
+}
+
+func (m *mockInserter) Close() {
+	m.closed = true
+}
+
+func (m *mockInserter) agents() map[netip.Addr]int {
+	res := make(map[netip.Addr]int)
+	for _, ins := range m.inserts {
+		for _, a := range ins {
+			res[a]++
+		}
+	}
+
+	return res
+}
+
+func newMockPool(t *testing.T, cfg *Config) (*Pool, []*mockInserter) {
+	inserters := make([]*mockInserter, 0)
+	p, err := New(cfg, func(address string) (Inserter, error) {
+		m := &mockInserter{address: address}
+		inserters = append(inserters, m)
+		return m, nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	return p, inserters
+}
+
+func testFlows(agents int, perAgent int) []*flow.Flow {
+	res := make([]*flow.Flow, 0, agents*perAgent)
+	for a := 0; a < agents; a++ {
+		for i := 0; i < perAgent; i++ {
+			fl := flow.New()
+			fl.Agent = netip.AddrFrom4([4]byte{192, 0, 2, byte(a)})
+			fl.SrcAddr = netip.AddrFrom4([4]byte{198, 51, 100, byte(i)})
+			fl.SrcPort = uint16(i)
+			res = append(res, fl)
+		}
+	}
+
+	return res
+}
+
+func TestShardByAgent(t *testing.T) {
+	p, inserters := newMockPool(t, &Config{Writers: 4})
+	p.Write(testFlows(16, 10))
+	p.Stop()
+
+	seen := make(map[netip.Addr]int)
+	total := 0
+	for i, m := range inserters {
+		assert.True(t, m.closed, "writer %d not closed", i)
+		for a, n := range m.agents() {
+			_, dup := seen[a]
+			assert.False(t, dup, "agent %s on more than one writer", a)
+			seen[a] = i
+			assert.Equal(t, 10, n)
+			total += n
+		}
+	}
+
+	assert.Equal(t, 160, total)
+	assert.Len(t, seen, 16)
+}
+
+func TestShardByHash(t *testing.T) {
+	p, inserters := newMockPool(t, &Config{Writers: 4, ShardBy: ShardByHash})
+	p.Write(testFlows(1, 200))
+	p.Stop()
+
+	total := 0
+	for i, m := range inserters {
+		n := m.agents()[netip.AddrFrom4([4]byte{192, 0, 2, 0})]
+		assert.Greater(t, n, 0, "writer %d got no flows", i)
+		total += n
+	}
+
+	assert.Equal(t, 200, total)
+}
+
+func TestBatchSize(t *testing.T) {
+	p, inserters := newMockPool(t, &Config{BatchSize: 30})
+	p.Write(testFlows(1, 45))
+	p.Write(testFlows(1, 20))
+	p.Stop()
+
+	sizes := make([]int, 0)
+	for _, ins := range inserters[0].inserts {
+		sizes = append(sizes, len(ins))
+	}
+
+	assert.Equal(t, []int{30, 30, 5}, sizes)
+}
+
+func TestFlushInterval(t *testing.T) {
+	p, inserters := newMockPool(t, &Config{FlushInterval: 1})
+	defer p.Stop()
+
+	p.Write(testFlows(1, 5))
+	assert.Eventually(t, func() bool {
+		m := inserters[0]
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return len(m.inserts) == 1
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       *Config
+		connErr   error
+		addresses []string
+		wantFail  bool
+	}{
+		{
+			name:      "Defaults",
+			cfg:       &Config{},
+			addresses: []string{""},
+		},
+		{
+			name: "Addresses in turns",
+			cfg: &Config{
+				Writers:   3,
+				Addresses: []string{"ch1:9000", "ch2:9000"},
+			},
+			addresses: []string{"ch1:9000", "ch2:9000", "ch1:9000"},
+		},
+		{
+			name:     "Unknown sharding",
+			cfg:      &Config{ShardBy: "random"},
+			wantFail: true,
+		},
+		{
+			name:     "Negative writers",
+			cfg:      &Config{Writers: -1},
+			wantFail: true,
+		},
+		{
+			name:     "Connect fails",
+			cfg:      &Config{Writers: 2},
+			connErr:  fmt.Errorf("connection refused"),
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		addresses := make([]string, 0)
+		p, err := New(test.cfg, func(address string) (Inserter, error) {
+			if test.connErr != nil {
+				return nil, test.connErr
+			}
+
+			addresses = append(addresses, address)
+			return &mockInserter{}, nil
+		}, nil)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected failure for %q: %v", test.name, err)
+			continue
+		}
+
+		p.Stop()
+		assert.Equal(t, test.addresses, addresses, test.name)
+	}
+}
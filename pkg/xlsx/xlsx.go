@@ -0,0 +1,400 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxSheetNameLength = 31
+
+	// DefaultTimeFormat is the number format of time cells without a format
+	DefaultTimeFormat = "yyyy-mm-dd hh:mm"
+
+	// first ID of custom number formats, lower IDs are built into Excel
+	firstCustomNumFmtID = 164
+
+	// cell style indices fixed independent of the cell formats used
+	defaultStyle = 0
+	headerStyle  = 1
+)
+
+// excelEpoch is day 0 of the 1900 date system (including the bogus 29 Feb 1900)
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+type cellKind int
+
+const (
+	kindText cellKind = iota
+	kindHeader
+	kindNumber
+	kindTime
+)
+
+// Cell is a cell of a sheet
+type Cell struct {
+	kind   cellKind
+	text   string
+	number float64
+	time   time.Time
+	format string
+}
+
+// Text creates a text cell
+func Text(s string) Cell {
+	return Cell{kind: kindText, text: s}
+}
+
+// Header creates a bold text cell
+func Header(s string) Cell {
+	return Cell{kind: kindHeader, text: s}
+}
+
+// Number creates a numeric cell displayed with format (an Excel number format, e.g. `#,##0" Mbps"`). Empty format uses General.
+func Number(v float64, format string) Cell {
+	return Cell{kind: kindNumber, number: v, format: format}
+}
+
+// Time creates a date cell showing the wall clock time of t (Excel has no time zones). Empty format uses DefaultTimeFormat.
+func Time(t time.Time, format string) Cell {
+	if format == "" {
+		format = DefaultTimeFormat
+	}
+
+	return Cell{kind: kindTime, time: t, format: format}
+}
+
+// Sheet is a worksheet
+type Sheet struct {
+	name   string
+	widths map[int]float64
+	rows   [][]Cell
+	frozen bool
+}
+
+// SetColumnWidth sets the width of column col (0 based) in characters
+func (s *Sheet) SetColumnWidth(col int, width float64) {
+	s.widths[col] = width
+}
+
+// FreezeHeader keeps the first row visible while scrolling
+func (s *Sheet) FreezeHeader() {
+	s.frozen = true
+}
+
+// AddRow appends a row
+func (s *Sheet) AddRow(cells ...Cell) {
+	s.rows = append(s.rows, cells)
+}
+
+// Name gets the name of the sheet as written to the workbook
+func (s *Sheet) Name() string {
+	return s.name
+}
+
+// Workbook is a set of sheets written as Office Open XML spreadsheet
+type Workbook struct {
+	sheets []*Sheet
+}
+
+// New creates an empty workbook
+func New() *Workbook {
+	return &Workbook{}
+}
+
+// AddSheet appends a sheet. Characters Excel does not allow in sheet names are replaced, long names are truncated and
+// duplicates get a number appended.
+func (wb *Workbook) AddSheet(name string) *Sheet {
+	s := &Sheet{
+		name:   wb.uniqueSheetName(sanitizeSheetName(name)),
+		widths: make(map[int]float64),
+	}
+
+	wb.sheets = append(wb.sheets, s)
+	return s
+}
+
+func sanitizeSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	name = strings.Trim(name, "'")
+	if name == "" {
+		return "Sheet"
+	}
+
+	return truncate(name, maxSheetNameLength)
+}
+
+func (wb *Workbook) uniqueSheetName(name string) string {
+	res := name
+	for i := 2; wb.hasSheet(res); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		res = truncate(name, maxSheetNameLength-len(suffix)) + suffix
+	}
+
+	return res
+}
+
+func (wb *Workbook) hasSheet(name string) bool {
+	for _, s := range wb.sheets {
+		if strings.EqualFold(s.name, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n])
+}
+
+// Write writes the workbook as XLSX file
+func (wb *Workbook) Write(w io.Writer) error {
+	if len(wb.sheets) == 0 {
+		return fmt.Errorf("Workbook has no sheets")
+	}
+
+	styles := wb.collectStyles()
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name  string
+		write func(w *bufio.Writer)
+	}{
+		{"[Content_Types].xml", wb.writeContentTypes},
+		{"_rels/.rels", writeRootRels},
+		{"xl/workbook.xml", wb.writeWorkbook},
+		{"xl/_rels/workbook.xml.rels", wb.writeWorkbookRels},
+		{"xl/styles.xml", styles.write},
+	}
+
+	for _, f := range files {
+		err := writeFile(zw, f.name, f.write)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, s := range wb.sheets {
+		err := writeFile(zw, fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), func(w *bufio.Writer) {
+			s.write(w, styles)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(zw.Close(), "Unable to finish zip file")
+}
+
+func writeFile(zw *zip.Writer, name string, write func(w *bufio.Writer)) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to create %s", name)
+	}
+
+	bw := bufio.NewWriter(f)
+	bw.WriteString(xml.Header)
+	write(bw)
+
+	return errors.Wrapf(bw.Flush(), "Unable to write %s", name)
+}
+
+func (wb *Workbook) writeContentTypes(w *bufio.Writer) {
+	w.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	w.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	w.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	w.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	w.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.sheets {
+		fmt.Fprintf(w, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	w.WriteString(`</Types>`)
+}
+
+func writeRootRels(w *bufio.Writer) {
+	w.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	w.WriteString(`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>`)
+	w.WriteString(`</Relationships>`)
+}
+
+func (wb *Workbook) writeWorkbook(w *bufio.Writer) {
+	w.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, s := range wb.sheets {
+		fmt.Fprintf(w, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(s.name), i+1, i+1)
+	}
+	w.WriteString(`</sheets></workbook>`)
+}
+
+func (wb *Workbook) writeWorkbookRels(w *bufio.Writer) {
+	w.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range wb.sheets {
+		fmt.Fprintf(w, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(w, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(wb.sheets)+1)
+	w.WriteString(`</Relationships>`)
+}
+
+// styles maps the number formats used by cells to cell style indices
+type styles struct {
+	formats []string
+	index   map[string]int
+}
+
+func (wb *Workbook) collectStyles() *styles {
+	st := &styles{
+		index: make(map[string]int),
+	}
+
+	for _, s := range wb.sheets {
+		for _, row := range s.rows {
+			for _, c := range row {
+				if c.format == "" {
+					continue
+				}
+
+				if _, exists := st.index[c.format]; !exists {
+					st.index[c.format] = len(st.formats)
+					st.formats = append(st.formats, c.format)
+				}
+			}
+		}
+	}
+
+	return st
+}
+
+func (st *styles) styleOf(c Cell) int {
+	if c.kind == kindHeader {
+		return headerStyle
+	}
+
+	if c.format == "" {
+		return defaultStyle
+	}
+
+	return headerStyle + 1 + st.index[c.format]
+}
+
+func (st *styles) write(w *bufio.Writer) {
+	w.WriteString(`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(st.formats) > 0 {
+		fmt.Fprintf(w, `<numFmts count="%d">`, len(st.formats))
+		for i, f := range st.formats {
+			fmt.Fprintf(w, `<numFmt numFmtId="%d" formatCode="%s"/>`, firstCustomNumFmtID+i, escape(f))
+		}
+		w.WriteString(`</numFmts>`)
+	}
+
+	w.WriteString(`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>`)
+	w.WriteString(`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>`)
+	w.WriteString(`<fill><patternFill patternType="solid"><fgColor rgb="FFD9D9D9"/><bgColor indexed="64"/></patternFill></fill></fills>`)
+	w.WriteString(`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>`)
+	w.WriteString(`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`)
+
+	fmt.Fprintf(w, `<cellXfs count="%d">`, headerStyle+1+len(st.formats))
+	w.WriteString(`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>`)
+	w.WriteString(`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>`)
+	for i := range st.formats {
+		fmt.Fprintf(w, `<xf numFmtId="%d" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`, firstCustomNumFmtID+i)
+	}
+	w.WriteString(`</cellXfs>`)
+
+	w.WriteString(`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>`)
+	w.WriteString(`</styleSheet>`)
+}
+
+func (s *Sheet) write(w *bufio.Writer, st *styles) {
+	w.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if s.frozen {
+		w.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+
+	if len(s.widths) > 0 {
+		w.WriteString(`<cols>`)
+		for col := 0; col <= maxKey(s.widths); col++ {
+			width, ok := s.widths[col]
+			if !ok {
+				continue
+			}
+
+			fmt.Fprintf(w, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, col+1, col+1, width)
+		}
+		w.WriteString(`</cols>`)
+	}
+
+	w.WriteString(`<sheetData>`)
+	for i, row := range s.rows {
+		fmt.Fprintf(w, `<row r="%d">`, i+1)
+		for j, c := range row {
+			ref := ColumnName(j) + fmt.Sprint(i+1)
+			style := ""
+			if n := st.styleOf(c); n != defaultStyle {
+				style = fmt.Sprintf(` s="%d"`, n)
+			}
+
+			switch c.kind {
+			case kindNumber:
+				fmt.Fprintf(w, `<c r="%s"%s><v>%g</v></c>`, ref, style, c.number)
+			case kindTime:
+				fmt.Fprintf(w, `<c r="%s"%s><v>%g</v></c>`, ref, style, serialDate(c.time))
+			default:
+				fmt.Fprintf(w, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(c.text))
+			}
+		}
+		w.WriteString(`</row>`)
+	}
+	w.WriteString(`</sheetData></worksheet>`)
+}
+
+func maxKey(m map[int]float64) int {
+	res := 0
+	for k := range m {
+		if k > res {
+			res = k
+		}
+	}
+
+	return res
+}
+
+// ColumnName gets the letters of column col (0 based), e.g. A for 0 and AA for 26
+func ColumnName(col int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+
+	return name
+}
+
+// serialDate converts the wall clock time of t to days since the Excel epoch
+func serialDate(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Sub(excelEpoch).Hours() / 24
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
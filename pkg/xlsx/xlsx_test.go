@@ -0,0 +1,138 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		col      int
+		expected string
+	}{
+		{col: 0, expected: "A"},
+		{col: 25, expected: "Z"},
+		{col: 26, expected: "AA"},
+		{col: 51, expected: "AZ"},
+		{col: 52, expected: "BA"},
+		{col: 701, expected: "ZZ"},
+		{col: 702, expected: "AAA"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ColumnName(test.col))
+	}
+}
+
+func TestSerialDate(t *testing.T) {
+	assert.Equal(t, 45292.5, serialDate(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+
+	// the wall clock time is used as Excel has no time zones
+	loc := time.FixedZone("UTC+2", 2*3600)
+	assert.Equal(t, 45292.5, serialDate(time.Date(2024, 1, 1, 12, 0, 0, 0, loc)))
+}
+
+func TestAddSheet(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		expected []string
+	}{
+		{
+			name:     "Valid names",
+			names:    []string{"Flows", "agent=rtr01"},
+			expected: []string{"Flows", "agent=rtr01"},
+		},
+		{
+			name:     "Invalid characters",
+			names:    []string{"src_pfx=10.0.0.0/8", "a[1]:b?*", "'quoted'", ""},
+			expected: []string{"src_pfx=10.0.0.0_8", "a_1__b__", "quoted", "Sheet"},
+		},
+		{
+			name:     "Long names",
+			names:    []string{"src_asn=65000;dst_asn=65001;proto=6"},
+			expected: []string{"src_asn=65000;dst_asn=65001;pro"},
+		},
+		{
+			name:     "Duplicates",
+			names:    []string{"Flows", "flows", "src_asn=65000;dst_asn=65001;proto=6", "src_asn=65000;dst_asn=65001;proto=17"},
+			expected: []string{"Flows", "flows (2)", "src_asn=65000;dst_asn=65001;pro", "src_asn=65000;dst_asn=65001 (2)"},
+		},
+	}
+
+	for _, test := range tests {
+		wb := New()
+		res := make([]string, 0)
+		for _, n := range test.names {
+			res = append(res, wb.AddSheet(n).Name())
+		}
+
+		assert.Equal(t, test.expected, res, test.name)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	wb := New()
+	s := wb.AddSheet("Flows")
+	s.FreezeHeader()
+	s.SetColumnWidth(0, 18)
+	s.AddRow(Header("Time"), Header("a<b"))
+	s.AddRow(Time(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), ""), Number(1500, `#,##0" Mbps"`))
+	s.AddRow(Text("total"), Number(2.5, ""))
+
+	buf := bytes.NewBuffer(nil)
+	err := wb.Write(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Unable to read zip: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("Unable to open %s: %v", f.Name, err)
+		}
+
+		b, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("Unable to read %s: %v", f.Name, err)
+		}
+
+		files[f.Name] = string(b)
+		assert.NoError(t, xml.Unmarshal(b, new(interface{})), f.Name)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		assert.Contains(t, files, name)
+	}
+
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="Flows" sheetId="1" r:id="rId1"/>`)
+	assert.Contains(t, files["xl/styles.xml"], `<numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm"/>`)
+	assert.Contains(t, files["xl/styles.xml"], `<numFmt numFmtId="165" formatCode="#,##0&#34; Mbps&#34;"/>`)
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `state="frozen"`)
+	assert.Contains(t, sheet, `<col min="1" max="1" width="18" customWidth="1"/>`)
+	assert.Contains(t, sheet, `<c r="B1" s="1" t="inlineStr"><is><t xml:space="preserve">a&lt;b</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="A2" s="2"><v>45292.5</v></c>`)
+	assert.Contains(t, sheet, `<c r="B2" s="3"><v>1500</v></c>`)
+	assert.Contains(t, sheet, `<c r="A3" t="inlineStr"><is><t xml:space="preserve">total</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B3"><v>2.5</v></c>`)
+}
+
+func TestWriteEmpty(t *testing.T) {
+	err := New().Write(io.Discard)
+	assert.Error(t, err)
+}